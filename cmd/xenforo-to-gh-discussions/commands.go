@@ -0,0 +1,127 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// command is a subcommand with its own flag set.
+type command struct {
+	name    string
+	aliases []string
+	args    string // Positional arguments shown in the usage
+	summary string
+	action  string // Names the command in fatal errors, e.g. "Migration"
+	run     func(args []string) error
+}
+
+// defaultCommand runs when the first argument is a flag or missing, so plain
+// invocations like "xenforo-to-gh-discussions --dry-run" keep working.
+const defaultCommand = "migrate"
+
+var commands = []command{
+	{name: "migrate", summary: "Migrate threads to GitHub Discussions (default)", action: "Migration", run: runMigrate},
+	{name: "dry-run", summary: "Preview a migration without writing to GitHub", action: "Migration", run: runDryRun},
+	{name: "resume", args: "[thread-id]", summary: "Continue an interrupted migration, optionally from a thread ID", action: "Migration", run: runResume},
+	{name: "upload", args: "DIR", summary: "Post the threads of a migrate --export-only run", action: "Upload", run: runUpload},
+	{name: "sync", summary: "Mirror new threads and posts of a migrated node, once or every interval", action: "Sync", run: runSync},
+	{name: "serve", summary: "Mirror the threads and posts announced by XenForo webhooks", action: "Serve", run: runServe},
+	{name: "verify", summary: "Compare migrated discussions against their source threads", action: "Verification", run: runVerify},
+	{name: "fixup", summary: "Re-render migrated threads and patch the discussions that changed", action: "Fixup", run: runFixup},
+	{name: "relink", summary: "Point links to forum threads at their migrated discussions", action: "Relink", run: runRelink},
+	{name: "rollback", summary: "Delete the discussions recorded in a progress file", action: "Rollback", run: runRollback},
+	{name: "stats", summary: "Summarize a progress file", action: "Stats", run: runStats},
+	{name: "export", aliases: []string{"inventory"}, summary: "Export the forum structure as JSON", action: "Export", run: runInventory},
+	{name: "config", args: "init", summary: "Write a commented config file template", action: "Config", run: runConfig},
+}
+
+// runCommand dispatches the command line to its command.
+func runCommand(args []string) int {
+	args, err := loadConfigFile(args)
+	if err != nil {
+		log.Printf("Config failed: %v", err)
+		return 1
+	}
+
+	name := defaultCommand
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name, args = args[0], args[1:]
+	}
+
+	if name == "help" {
+		printUsage(os.Stdout)
+		return 0
+	}
+
+	cmd, ok := lookupCommand(name)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "unknown command %q\n\n", name)
+		printUsage(os.Stderr)
+		return 2
+	}
+
+	if err := cmd.run(args); err != nil {
+		log.Printf("%s failed: %v", cmd.action, err)
+		return 1
+	}
+	return 0
+}
+
+func lookupCommand(name string) (command, bool) {
+	for _, cmd := range commands {
+		if cmd.name == name {
+			return cmd, true
+		}
+		for _, alias := range cmd.aliases {
+			if alias == name {
+				return cmd, true
+			}
+		}
+	}
+	return command{}, false
+}
+
+// printUsage lists the commands. Each command prints its flags with -h.
+func printUsage(w io.Writer) {
+	_, _ = fmt.Fprintln(w, "Usage: xenforo-to-gh-discussions [command] [flags]")
+	_, _ = fmt.Fprintln(w, "\nCommands:")
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, cmd := range commands {
+		_, _ = fmt.Fprintf(tw, "  %s\t%s\n", strings.TrimSpace(cmd.name+" "+cmd.args), cmd.summary)
+	}
+	_ = tw.Flush()
+	_, _ = fmt.Fprintln(w, "\nRun \"xenforo-to-gh-discussions <command> -h\" for the flags of a command.")
+	_, _ = fmt.Fprintln(w, "Every command accepts --config <file> (or CONFIG_FILE) to read settings from a config file;")
+	_, _ = fmt.Fprintln(w, "environment variables override the file, and flags override both.")
+}
+
+// runDryRun implements the "dry-run" command, a migration with --dry-run.
+func runDryRun(args []string) error {
+	return runMigrate(append([]string{"--dry-run"}, args...))
+}
+
+// runResume implements the "resume" command. Migrations always skip completed
+// threads and continue interrupted ones from their checkpoint; a thread ID
+// additionally skips every thread before it, like --resume-from.
+func runResume(args []string) error {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		threadID, err := strconv.Atoi(args[0])
+		if err != nil || threadID <= 0 {
+			return fmt.Errorf("resume needs a positive thread ID, got: %q", args[0])
+		}
+		args = append([]string{"--resume-from", args[0]}, args[1:]...)
+	}
+	return runMigrate(args)
+}
+
+// logFormatFlag registers the --log-format flag of the commands that log
+// their progress.
+func logFormatFlag(fs *flag.FlagSet) *string {
+	return fs.String("log-format", os.Getenv("LOG_FORMAT"), "Log format: text, or json with thread ID, post ID, phase and duration fields (overrides LOG_FORMAT)")
+}
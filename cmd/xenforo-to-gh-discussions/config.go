@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+)
+
+// runConfig implements the "config" command. "config init" writes a commented
+// config file template with every setting at its default.
+func runConfig(args []string) error {
+	if len(args) == 0 || args[0] != "init" {
+		return fmt.Errorf("usage: xenforo-to-gh-discussions config init [--output %s] [--force]", config.DefaultConfigFile)
+	}
+
+	fs := flag.NewFlagSet("config init", flag.ExitOnError)
+	output := fs.String("output", config.DefaultConfigFile, "Write the template to this file")
+	force := fs.Bool("force", false, "Overwrite an existing file")
+	if err := fs.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	if *force {
+		flags = os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	}
+	file, err := os.OpenFile(*output, flags, 0o600)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists (use --force to overwrite)", *output)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create config file: %w", err)
+	}
+
+	if err := config.WriteTemplate(file); err != nil {
+		_ = file.Close()
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	log.Printf("✓ Config template written to %s", *output)
+	return nil
+}
+
+// loadConfigFile loads the config file named by the --config flag, which any
+// command accepts, or by CONFIG_FILE. Returns the arguments without the flag.
+func loadConfigFile(args []string) ([]string, error) {
+	path := os.Getenv(config.EnvConfigFile)
+
+	rest := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			rest = append(rest, arg)
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return nil, fmt.Errorf("flag needs an argument: %s", arg)
+			}
+			i++
+			value = args[i]
+		}
+		path = value
+	}
+
+	if path != "" {
+		if err := config.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	return rest, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"context"
+	"os"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/migration"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// runShowConversion implements --show-conversion, which prints how each post
+// of a thread converts using the XenForo and conversion settings from the
+// environment. Output is colored when written to a terminal unless NO_COLOR
+// is set.
+func runShowConversion(cfg *config.Config, threadID int, sideBySide bool) error {
+	client := xenforo.NewClient(cfg.XenForo.APIURL, cfg.XenForo.APIKey, cfg.XenForo.APIUser, cfg.Migration.MaxRetries)
+
+	opts := migration.ConversionOptions{SideBySide: sideBySide}
+	if info, err := os.Stdout.Stat(); err == nil && info.Mode()&os.ModeCharDevice != 0 && os.Getenv("NO_COLOR") == "" {
+		opts.Color = true
+	}
+
+	return migration.ShowConversion(context.Background(), cfg, client, threadID, os.Stdout, opts)
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/migration"
+)
+
+// runFixup implements the "fixup" command, which renders the threads
+// recorded in the progress file again with the current settings and patches
+// the discussions whose posts changed, e.g. after a converter fix.
+func runFixup(args []string) error {
+	cfg := config.New()
+
+	fs := flag.NewFlagSet("fixup", flag.ExitOnError)
+	progressFile := fs.String("progress-file", defaultProgressFile(cfg), "Progress file recording the discussions to patch")
+	threads := fs.String("threads", "", "Comma-separated thread IDs to fix up instead of every recorded thread")
+	dryRun := fs.Bool("dry-run", false, "List the posts that would be updated without editing them")
+	tokenFile := fs.String("github-token-file", os.Getenv("GITHUB_TOKEN_FILE"), "Read the GitHub token from this file, or stdin with \"-\" (overrides GITHUB_TOKEN)")
+	keyFile := fs.String("xenforo-key-file", os.Getenv("XENFORO_API_KEY_FILE"), "Read the XenForo API key from this file, or stdin with \"-\" (overrides XENFORO_API_KEY)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	logFormat := logFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := logging.Setup(os.Stderr, *logFormat, *verbose); err != nil {
+		return err
+	}
+
+	var threadIDs []int
+	for _, field := range strings.Split(*threads, ",") {
+		if field = strings.TrimSpace(field); field == "" {
+			continue
+		}
+		threadID, err := strconv.Atoi(field)
+		if err != nil || threadID <= 0 {
+			return fmt.Errorf("threads must be positive thread IDs, got: %q", field)
+		}
+		threadIDs = append(threadIDs, threadID)
+	}
+
+	creds, err := config.ReadCredentials(*tokenFile, *keyFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+	cfg.ApplyCredentials(creds)
+	cfg.Migration.ProgressFile = *progressFile
+
+	updated, err := migration.NewMigrator(cfg).Fixup(context.Background(), threadIDs, *dryRun)
+	if err != nil {
+		return fmt.Errorf("fixup stopped after %d updated posts: %w", updated, err)
+	}
+
+	if !*dryRun {
+		log.Printf("✓ Updated %d posts", updated)
+	}
+	return nil
+}
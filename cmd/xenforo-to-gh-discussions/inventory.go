@@ -0,0 +1,57 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/inventory"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// runInventory implements the "export" command (also "inventory"), which
+// prints the forum's node tree and optionally a node's threads as JSON using
+// the XenForo settings from the environment.
+func runInventory(args []string) error {
+	fs := flag.NewFlagSet("export", flag.ExitOnError)
+	nodeID := fs.Int("node", 0, "Also list the threads of this node")
+	output := fs.String("output", "", "Write the inventory to this file instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if *nodeID < 0 {
+		return fmt.Errorf("node must be a positive value, got: %d", *nodeID)
+	}
+
+	cfg := config.New()
+	client := xenforo.NewClient(cfg.XenForo.APIURL, cfg.XenForo.APIKey, cfg.XenForo.APIUser, cfg.Migration.MaxRetries)
+
+	inv, err := inventory.Build(context.Background(), client, *nodeID)
+	if err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+		w = file
+	}
+
+	if err := inventory.Write(w, inv); err != nil {
+		return err
+	}
+
+	if *output != "" {
+		log.Printf("✓ Inventory written to %s", *output)
+	}
+	return nil
+}
@@ -2,38 +2,185 @@ package main
 
 import (
 	"flag"
-	"log"
+	"fmt"
+	"os"
 
 	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/migration"
 )
 
 func main() {
+	os.Exit(runCommand(os.Args[1:]))
+}
+
+// runMigrate implements the "migrate" command, the default when no command is
+// given, which migrates threads interactively or from the environment.
+func runMigrate(args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ExitOnError)
 	var (
-		dryRun         = flag.Bool("dry-run", false, "Run in dry-run mode (no actual API calls)")
-		resumeFrom     = flag.Int("resume-from", 0, "Resume from specific thread ID")
-		verbose        = flag.Bool("verbose", false, "Enable verbose logging")
-		nonInteractive = flag.Bool("non-interactive", false, "Run in non-interactive mode using environment variables")
+		dryRun         = fs.Bool("dry-run", false, "Run in dry-run mode (no actual API calls)")
+		dryRunOutput   = fs.String("dry-run-output", "", "Write each would-be discussion and comment as Markdown under this directory (implies --dry-run)")
+		exportOnly     = fs.String("export-only", "", "Render every thread to discussions.ndjson under this directory for the upload command instead of posting it (implies --dry-run)")
+		resumeFrom     = fs.Int("resume-from", 0, "Resume from specific thread ID")
+		verbose        = fs.Bool("verbose", false, "Enable verbose logging")
+		nonInteractive = fs.Bool("non-interactive", false, "Run in non-interactive mode using environment variables")
+		workers        = fs.Int("workers", 0, "Number of threads to migrate in parallel (overrides MIGRATION_CONCURRENCY)")
+		attachWorkers  = fs.Int("workers-attachments", 0, "Number of parallel attachment downloads (overrides ATTACHMENT_WORKERS)")
+		sinceLastRun   = fs.Bool("since-last-run", false, "Only migrate threads created since the last successful run")
+		since          = fs.String("since", "", "Only migrate threads created on or after this date, YYYY-MM-DD or RFC 3339 (overrides THREAD_SINCE)")
+		until          = fs.String("until", "", "Only migrate threads created on or before this date (overrides THREAD_UNTIL)")
+		minReplies     = fs.Int("min-replies", 0, "Only migrate threads with at least this many replies (overrides THREAD_MIN_REPLIES)")
+		prefix         = fs.String("prefix", "", "Only migrate threads with one of these comma-separated prefix titles or IDs (overrides THREAD_PREFIXES)")
+		author         = fs.String("author", "", "Only migrate threads started by one of these comma-separated usernames (overrides THREAD_AUTHORS)")
+		state          = fs.String("state", "", "Only migrate open or closed threads (overrides THREAD_STATE)")
+		threads        = fs.String("threads", "", "Only migrate these thread IDs and ranges, e.g. 100,105,200-300, or those listed in this file (overrides THREAD_IDS)")
+		order          = fs.String("order", "", "Migrate threads in listing, oldest-first, newest-first or last-activity order (overrides MIGRATION_ORDER)")
+		excludeThreads = fs.String("exclude-threads", "", "Never migrate these thread IDs and ranges, or those listed in this file (overrides EXCLUDE_THREAD_IDS)")
+		webhookURL     = fs.String("webhook-url", "", "POST a JSON run summary to this URL (overrides WEBHOOK_URL)")
+		allowNonEmpty  = fs.Bool("allow-nonempty", false, "Migrate even if the target category already has discussions (with REQUIRE_EMPTY_CATEGORY)")
+		tokenFile      = fs.String("github-token-file", os.Getenv("GITHUB_TOKEN_FILE"), "Read the GitHub token from this file, or stdin with \"-\" (overrides GITHUB_TOKEN)")
+		keyFile        = fs.String("xenforo-key-file", os.Getenv("XENFORO_API_KEY_FILE"), "Read the XenForo API key from this file, or stdin with \"-\" (overrides XENFORO_API_KEY)")
+		autoRetry      = fs.Bool("auto-retry-failed", false, "Retry failed threads once more after the run (wait set by AUTO_RETRY_WAIT)")
+		retryFailed    = fs.Bool("retry-failed", false, "Only migrate the threads recorded as failed in the progress file")
+		locale         = fs.String("locale", "", "Language of the post frontmatter labels, e.g. \"de\" (overrides LOCALE)")
+		showConversion = fs.Int("show-conversion", 0, "Print the BB-code and converted Markdown of each post of this thread, then exit")
+		sideBySide     = fs.Bool("side-by-side", false, "Show --show-conversion output in two columns instead of a diff")
+		legacyConvert  = fs.Bool("legacy-converter", false, "Convert BB-code with the previous regular-expression converter (overrides LEGACY_CONVERTER)")
+		estimate       = fs.Bool("estimate", false, "Show the estimated API calls and duration and ask for confirmation before migrating")
+		estimateMode   = fs.String("estimate-mode", "", "Count attachments for the estimate from 10% of posts (quick), sampled threads (sample) or every post (full) (overrides ESTIMATE_MODE)")
+		logFormat      = logFormatFlag(fs)
 	)
-	flag.Parse()
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := logging.Setup(os.Stderr, *logFormat, *verbose); err != nil {
+		return err
+	}
 
 	if *resumeFrom < 0 {
-		log.Fatalf("resume-from must be a positive value, got: %d", *resumeFrom)
+		return fmt.Errorf("resume-from must be a positive value, got: %d", *resumeFrom)
+	}
+
+	if *minReplies < 0 {
+		return fmt.Errorf("min-replies must be a positive value, got: %d", *minReplies)
+	}
+
+	if *workers < 0 || *attachWorkers < 0 {
+		return fmt.Errorf("worker counts must be positive values, got: workers=%d, workers-attachments=%d", *workers, *attachWorkers)
+	}
+
+	if *showConversion < 0 {
+		return fmt.Errorf("show-conversion must be a positive thread ID, got: %d", *showConversion)
+	}
+
+	if !*nonInteractive && *showConversion == 0 && (*tokenFile == config.StdinSecret || *keyFile == config.StdinSecret) {
+		return fmt.Errorf("reading secrets from stdin requires --non-interactive")
+	}
+
+	creds, err := config.ReadCredentials(*tokenFile, *keyFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+
+	if *showConversion > 0 {
+		cfg := config.New()
+		cfg.ApplyCredentials(creds)
+		if *legacyConvert {
+			cfg.Migration.LegacyConverter = true
+		}
+		if err := runShowConversion(cfg, *showConversion, *sideBySide); err != nil {
+			return fmt.Errorf("show conversion failed: %w", err)
+		}
+		return nil
 	}
 
 	var cfg *config.Config
 	if *nonInteractive {
 		cfg = config.New()
+		cfg.ApplyCredentials(creds)
 	} else {
-		cfg = config.InteractiveConfig()
+		cfg = config.InteractiveConfigWithCredentials(creds)
 	}
 
-	cfg.Migration.DryRun = *dryRun
+	cfg.Migration.DryRun = *dryRun || *dryRunOutput != "" || *exportOnly != ""
+	cfg.Migration.DryRunOutput = *dryRunOutput
+	cfg.Migration.ExportOnly = *exportOnly
 	cfg.Migration.Verbose = *verbose
 	cfg.Migration.ResumeFrom = *resumeFrom
 
-	runner := migration.NewInteractiveRunner(*nonInteractive)
-	if err := runner.Run(cfg); err != nil {
-		log.Fatalf("Migration failed: %v", err)
+	if *sinceLastRun {
+		cfg.Migration.SinceLastRun = true
+	}
+
+	if *since != "" {
+		cfg.Migration.FilterSince = *since
+	}
+	if *until != "" {
+		cfg.Migration.FilterUntil = *until
+	}
+	if *minReplies > 0 {
+		cfg.Migration.FilterMinReplies = *minReplies
+	}
+	if *prefix != "" {
+		cfg.Migration.FilterPrefixes = config.SplitList(*prefix)
+	}
+	if *author != "" {
+		cfg.Migration.FilterAuthors = config.SplitList(*author)
+	}
+	if *state != "" {
+		cfg.Migration.FilterState = *state
 	}
+	if *threads != "" {
+		cfg.Migration.FilterThreads = *threads
+	}
+	if *excludeThreads != "" {
+		cfg.Migration.ExcludeThreads = *excludeThreads
+	}
+	if *order != "" {
+		cfg.Migration.MigrationOrder = *order
+	}
+
+	if *webhookURL != "" {
+		cfg.Migration.WebhookURL = *webhookURL
+	}
+
+	if *allowNonEmpty {
+		cfg.Migration.AllowNonEmptyCategory = true
+	}
+
+	if *autoRetry {
+		cfg.Migration.AutoRetryFailed = true
+	}
+
+	if *retryFailed {
+		cfg.Migration.RetryFailed = true
+	}
+
+	if *legacyConvert {
+		cfg.Migration.LegacyConverter = true
+	}
+
+	if *estimate {
+		cfg.Migration.ConfirmEstimate = true
+	}
+
+	if *estimateMode != "" {
+		cfg.Migration.EstimateMode = *estimateMode
+	}
+
+	if *locale != "" {
+		cfg.Migration.Locale = *locale
+	}
+
+	if *workers > 0 {
+		cfg.Migration.MigrationConcurrency = *workers
+	}
+	if *attachWorkers > 0 {
+		cfg.Migration.AttachmentWorkers = *attachWorkers
+	}
+
+	runner := migration.NewInteractiveRunner(*nonInteractive)
+	return runner.Run(cfg)
 }
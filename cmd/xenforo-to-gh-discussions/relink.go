@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/migration"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+)
+
+// runRelink implements the "relink" command, which rewrites links to forum
+// threads in the discussions recorded in the progress file to the discussions
+// those threads were migrated to. Run it once the migration is complete.
+func runRelink(args []string) error {
+	cfg := config.New()
+
+	fs := flag.NewFlagSet("relink", flag.ExitOnError)
+	progressFile := fs.String("progress-file", defaultProgressFile(cfg), "Progress file recording the migrated discussions")
+	dryRun := fs.Bool("dry-run", false, "List the posts whose links would be rewritten without editing them")
+	tokenFile := fs.String("github-token-file", os.Getenv("GITHUB_TOKEN_FILE"), "Read the GitHub token from this file, or stdin with \"-\" (overrides GITHUB_TOKEN)")
+	logFormat := logFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := logging.Setup(os.Stderr, *logFormat, false); err != nil {
+		return err
+	}
+
+	if cfg.XenForo.WebURL == "" {
+		return fmt.Errorf("XENFORO_WEB_URL is required to recognize links to forum threads")
+	}
+
+	creds, err := config.ReadCredentials(*tokenFile, "", os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+	cfg.ApplyCredentials(creds)
+
+	persist, err := progress.OpenPersistence(*progressFile, cfg.Migration.ProgressBackend, cfg.Migration.ProgressBucketSize)
+	if err != nil {
+		return fmt.Errorf("failed to open progress: %w", err)
+	}
+	tracker, err := progress.NewTrackerWithPersistence(persist, true)
+	if err != nil {
+		return fmt.Errorf("failed to initialize progress tracker: %w", err)
+	}
+	recorded := tracker.Discussions()
+	if len(recorded) == 0 {
+		log.Printf("No discussions recorded in %s", *progressFile)
+		return nil
+	}
+
+	client, err := cfg.NewGitHubClient()
+	if err != nil {
+		return fmt.Errorf("failed to initialize GitHub client: %w", err)
+	}
+
+	edited, err := migration.RewriteThreadLinks(context.Background(), cfg, client, recorded, *dryRun)
+	if err != nil {
+		return fmt.Errorf("relink stopped after %d edited posts: %w", edited, err)
+	}
+
+	if !*dryRun {
+		log.Printf("✓ Rewrote thread links in %d posts", edited)
+	}
+	return nil
+}
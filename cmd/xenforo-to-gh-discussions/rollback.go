@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/migration"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+)
+
+// runRollback implements the "rollback" command, which deletes the
+// discussions recorded in the progress file and forgets their threads, so a
+// failed or test run can be undone and migrated again.
+func runRollback(args []string) error {
+	cfg := config.New()
+
+	fs := flag.NewFlagSet("rollback", flag.ExitOnError)
+	progressFile := fs.String("progress-file", defaultProgressFile(cfg), "Progress file recording the discussions to delete")
+	dryRun := fs.Bool("dry-run", false, "List the discussions that would be deleted without deleting them")
+	yes := fs.Bool("yes", false, "Delete without asking for confirmation")
+	threads := fs.String("threads", "", "Only roll back these thread IDs and ranges (e.g. 100,105,200-300), or those listed in this file")
+	tokenFile := fs.String("github-token-file", os.Getenv("GITHUB_TOKEN_FILE"), "Read the GitHub token from this file, or stdin with \"-\" (overrides GITHUB_TOKEN)")
+	logFormat := logFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := logging.Setup(os.Stderr, *logFormat, false); err != nil {
+		return err
+	}
+
+	threadIDs, err := config.ReadThreadIDs(*threads)
+	if err != nil {
+		return fmt.Errorf("invalid threads: %w", err)
+	}
+
+	if *tokenFile == config.StdinSecret && !*yes {
+		return fmt.Errorf("reading the GitHub token from stdin requires --yes")
+	}
+
+	creds, err := config.ReadCredentials(*tokenFile, "", os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+	cfg.ApplyCredentials(creds)
+
+	persist, err := progress.OpenPersistence(*progressFile, cfg.Migration.ProgressBackend, cfg.Migration.ProgressBucketSize)
+	if err != nil {
+		return fmt.Errorf("failed to open progress: %w", err)
+	}
+	tracker, err := progress.NewTrackerWithPersistence(persist, *dryRun)
+	if err != nil {
+		return fmt.Errorf("failed to initialize progress tracker: %w", err)
+	}
+
+	targets := migration.RollbackTargets(tracker)
+	if len(threadIDs) > 0 {
+		var selected []migration.RollbackTarget
+		for _, target := range targets {
+			if threadIDs.Contains(target.ThreadID) {
+				selected = append(selected, target)
+			}
+		}
+		targets = selected
+	}
+	count := 0
+	for _, target := range targets {
+		count += len(target.Discussions)
+	}
+	if count == 0 {
+		log.Printf("No discussions recorded in %s", *progressFile)
+		return nil
+	}
+
+	var client *github.Client
+	if !*dryRun {
+		client, err = cfg.NewGitHubClient()
+		if err != nil {
+			return fmt.Errorf("failed to initialize GitHub client: %w", err)
+		}
+
+		if !*yes && !config.PromptBool(fmt.Sprintf("Delete %d discussions of %d threads from %s?", count, len(targets), cfg.GitHub.Repository), false) {
+			log.Println("Rollback cancelled")
+			return nil
+		}
+	}
+
+	deleted, err := migration.Rollback(context.Background(), client, tracker, targets, *dryRun)
+	if err != nil {
+		return fmt.Errorf("rollback stopped after %d deleted discussions: %w", deleted, err)
+	}
+
+	if !*dryRun {
+		log.Printf("✓ Deleted %d of %d discussions", deleted, count)
+	}
+	return nil
+}
+
+// defaultProgressFile returns the progress file migrations of the configured
+// node write to.
+func defaultProgressFile(cfg *config.Config) string {
+	return fmt.Sprintf("migration_progress_node%d.json", cfg.GitHub.XenForoNodeID)
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/migration"
+)
+
+// runServe implements the "serve" command, which keeps a migrated node
+// mirrored on GitHub as XenForo announces new threads and posts through
+// webhooks, using the settings from the environment. It runs until
+// interrupted.
+func runServe(args []string) error {
+	cfg := config.New()
+
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	listen := fs.String("listen", cfg.Migration.ServeAddr, "Address to receive webhooks on, e.g. :8080 (overrides SERVE_ADDR)")
+	progressFile := fs.String("progress-file", defaultProgressFile(cfg), "Progress file recording the migrated discussions")
+	dryRun := fs.Bool("dry-run", false, "Log the posts and threads that would be added without writing to GitHub")
+	insecure := fs.Bool("insecure", false, "Accept webhooks without a secret when XENFORO_WEBHOOK_SECRET is not set")
+	tokenFile := fs.String("github-token-file", os.Getenv("GITHUB_TOKEN_FILE"), "Read the GitHub token from this file, or stdin with \"-\" (overrides GITHUB_TOKEN)")
+	keyFile := fs.String("xenforo-key-file", os.Getenv("XENFORO_API_KEY_FILE"), "Read the XenForo API key from this file, or stdin with \"-\" (overrides XENFORO_API_KEY)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	logFormat := logFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := logging.Setup(os.Stderr, *logFormat, *verbose); err != nil {
+		return err
+	}
+
+	if *listen == "" {
+		return fmt.Errorf("listen address cannot be empty")
+	}
+
+	creds, err := config.ReadCredentials(*tokenFile, *keyFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+	cfg.ApplyCredentials(creds)
+	cfg.Migration.ServeAddr = *listen
+	cfg.Migration.ProgressFile = *progressFile
+	cfg.Migration.DryRun = *dryRun
+	cfg.Migration.Verbose = *verbose
+
+	// An interrupt stops receiving webhooks after the event being mirrored
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return migration.NewMigrator(cfg).Serve(ctx, *insecure)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+)
+
+// runStats implements the "stats" command, which summarizes a progress file
+// without contacting XenForo or GitHub.
+func runStats(args []string) error {
+	cfg := config.New()
+
+	fs := flag.NewFlagSet("stats", flag.ExitOnError)
+	progressFile := fs.String("progress-file", defaultProgressFile(cfg), "Progress file to summarize")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	persist, err := progress.OpenPersistence(*progressFile, cfg.Migration.ProgressBackend, cfg.Migration.ProgressBucketSize)
+	if err != nil {
+		return fmt.Errorf("failed to open progress: %w", err)
+	}
+	tracker, err := progress.NewTrackerWithPersistence(persist, false)
+	if err != nil {
+		return fmt.Errorf("failed to initialize progress tracker: %w", err)
+	}
+
+	prog := tracker.GetProgress()
+	if prog.LastUpdated == 0 {
+		return fmt.Errorf("no progress recorded in %s", *progressFile)
+	}
+
+	discussions, splitThreads := 0, 0
+	for _, ref := range prog.Discussions {
+		discussions += 1 + len(ref.Parts)
+		if len(ref.Parts) > 0 {
+			splitThreads++
+		}
+	}
+
+	tracker.PrintSummary()
+
+	fmt.Printf("\nProgress file: %s\n", *progressFile)
+	fmt.Printf("Last updated: %s\n", time.Unix(prog.LastUpdated, 0).Format(time.RFC3339))
+	fmt.Printf("Last thread ID: %d\n", prog.LastThreadID)
+	fmt.Printf("Interrupted threads: %d\n", len(prog.Checkpoints))
+	fmt.Printf("Recorded discussions: %d (%d split threads)\n", discussions, splitThreads)
+
+	if len(prog.Metadata.LastRuns) > 0 {
+		keys := make([]string, 0, len(prog.Metadata.LastRuns))
+		for key := range prog.Metadata.LastRuns {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		fmt.Println("\nLast successful runs:")
+		for _, key := range keys {
+			fmt.Printf("  - %s: %s\n", key, time.Unix(prog.Metadata.LastRuns[key], 0).Format(time.RFC3339))
+		}
+	}
+
+	return nil
+}
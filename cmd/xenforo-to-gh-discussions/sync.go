@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/migration"
+)
+
+// runSync implements the "sync" command, which keeps a migrated node mirrored
+// on GitHub: posts added to migrated threads are appended to their
+// discussions and new threads are migrated, using the settings from the
+// environment. With an interval it keeps running until interrupted.
+func runSync(args []string) error {
+	cfg := config.New()
+
+	fs := flag.NewFlagSet("sync", flag.ExitOnError)
+	progressFile := fs.String("progress-file", defaultProgressFile(cfg), "Progress file recording the migrated discussions")
+	interval := fs.Duration("interval", cfg.Migration.SyncInterval, "Sync again after this pause until interrupted, e.g. 15m (0 = a single pass) (overrides SYNC_INTERVAL)")
+	dryRun := fs.Bool("dry-run", false, "Log the posts and threads that would be added without writing to GitHub")
+	tokenFile := fs.String("github-token-file", os.Getenv("GITHUB_TOKEN_FILE"), "Read the GitHub token from this file, or stdin with \"-\" (overrides GITHUB_TOKEN)")
+	keyFile := fs.String("xenforo-key-file", os.Getenv("XENFORO_API_KEY_FILE"), "Read the XenForo API key from this file, or stdin with \"-\" (overrides XENFORO_API_KEY)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	logFormat := logFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := logging.Setup(os.Stderr, *logFormat, *verbose); err != nil {
+		return err
+	}
+
+	if *interval < 0 {
+		return fmt.Errorf("interval cannot be negative, got: %s", *interval)
+	}
+
+	creds, err := config.ReadCredentials(*tokenFile, *keyFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+	cfg.ApplyCredentials(creds)
+	cfg.Migration.ProgressFile = *progressFile
+	cfg.Migration.SyncInterval = *interval
+	cfg.Migration.DryRun = *dryRun
+	cfg.Migration.Verbose = *verbose
+
+	// An interrupt ends the running pass after the post being written
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	return migration.NewMigrator(cfg).Sync(ctx)
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/migration"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+)
+
+// runUpload implements the "upload" command, the second phase of a two-phase
+// migration: it posts the threads a "migrate --export-only DIR" run rendered
+// to DIR, using the GitHub settings from the environment. Attachments are
+// hosted at ATTACHMENT_TARGET, or in ATTACHMENT_UPLOAD_REPO when it is set.
+func runUpload(args []string) error {
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		return fmt.Errorf("usage: xenforo-to-gh-discussions upload DIR [flags]")
+	}
+	dir, args := args[0], args[1:]
+
+	cfg := config.New()
+
+	fs := flag.NewFlagSet("upload", flag.ExitOnError)
+	progressFile := fs.String("progress-file", defaultProgressFile(cfg), "Progress file recording the uploaded threads")
+	tokenFile := fs.String("github-token-file", os.Getenv("GITHUB_TOKEN_FILE"), "Read the GitHub token from this file, or stdin with \"-\" (overrides GITHUB_TOKEN)")
+	verbose := fs.Bool("verbose", false, "Enable verbose logging")
+	logFormat := logFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := logging.Setup(os.Stderr, *logFormat, *verbose); err != nil {
+		return err
+	}
+
+	creds, err := config.ReadCredentials(*tokenFile, "", os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+	cfg.ApplyCredentials(creds)
+
+	persist, err := progress.OpenPersistence(*progressFile, cfg.Migration.ProgressBackend, cfg.Migration.ProgressBucketSize)
+	if err != nil {
+		return fmt.Errorf("failed to open progress: %w", err)
+	}
+	tracker, err := progress.NewTrackerWithPersistence(persist, false)
+	if err != nil {
+		return fmt.Errorf("failed to initialize progress tracker: %w", err)
+	}
+
+	client, err := cfg.NewGitHubClient()
+	if err != nil {
+		return fmt.Errorf("failed to initialize GitHub client: %w", err)
+	}
+
+	ctx := context.Background()
+	info, err := client.GetRepositoryInfo(ctx, cfg.GitHub.Repository)
+	if err != nil {
+		return fmt.Errorf("GitHub API check failed: %w", err)
+	}
+	if info.NameWithOwner != "" {
+		cfg.GitHub.Repository = info.NameWithOwner
+	}
+
+	runner := migration.NewRunner(cfg, nil, client, tracker, nil)
+	uploader, err := migration.NewAttachmentUploader(ctx, cfg, client)
+	if err != nil {
+		return err
+	}
+	if uploader != nil {
+		runner.SetUploader(uploader)
+	}
+	uploaded, err := runner.UploadExport(ctx, filepath.Join(dir, migration.ExportFileName))
+	if err != nil {
+		return fmt.Errorf("upload stopped after %d threads: %w", uploaded, err)
+	}
+
+	tracker.PrintSummary()
+	log.Printf("✓ Uploaded %d threads", uploaded)
+	return nil
+}
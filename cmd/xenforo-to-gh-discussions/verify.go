@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/migration"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// runVerify implements the "verify" command, which compares the discussions
+// recorded in the progress file against their source threads and reports
+// the threads that differ, using the settings from the environment.
+func runVerify(args []string) error {
+	cfg := config.New()
+
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	progressFile := fs.String("progress-file", defaultProgressFile(cfg), "Progress file recording the discussions to verify")
+	output := fs.String("output", "", "Write the report to this file instead of stdout")
+	tokenFile := fs.String("github-token-file", os.Getenv("GITHUB_TOKEN_FILE"), "Read the GitHub token from this file, or stdin with \"-\" (overrides GITHUB_TOKEN)")
+	keyFile := fs.String("xenforo-key-file", os.Getenv("XENFORO_API_KEY_FILE"), "Read the XenForo API key from this file, or stdin with \"-\" (overrides XENFORO_API_KEY)")
+	logFormat := logFormatFlag(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := logging.Setup(os.Stderr, *logFormat, false); err != nil {
+		return err
+	}
+
+	creds, err := config.ReadCredentials(*tokenFile, *keyFile, os.Stdin)
+	if err != nil {
+		return fmt.Errorf("failed to read credentials: %w", err)
+	}
+	cfg.ApplyCredentials(creds)
+
+	persist, err := progress.OpenPersistence(*progressFile, cfg.Migration.ProgressBackend, cfg.Migration.ProgressBucketSize)
+	if err != nil {
+		return fmt.Errorf("failed to open progress: %w", err)
+	}
+	tracker, err := progress.NewTrackerWithPersistence(persist, true)
+	if err != nil {
+		return fmt.Errorf("failed to initialize progress tracker: %w", err)
+	}
+	recorded := tracker.Discussions()
+	if len(recorded) == 0 {
+		log.Printf("No discussions recorded in %s", *progressFile)
+		return nil
+	}
+
+	xenforoClient := xenforo.NewClient(cfg.XenForo.APIURL, cfg.XenForo.APIKey, cfg.XenForo.APIUser, cfg.Migration.MaxRetries)
+	githubClient, err := cfg.NewGitHubClient()
+	if err != nil {
+		return fmt.Errorf("failed to initialize GitHub client: %w", err)
+	}
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		file, err := os.Create(*output)
+		if err != nil {
+			return fmt.Errorf("failed to create output file: %w", err)
+		}
+		defer func() { _ = file.Close() }()
+		w = file
+	}
+
+	differing, err := migration.Verify(context.Background(), cfg, xenforoClient, githubClient, recorded, w)
+	if err != nil {
+		return err
+	}
+
+	if *output != "" {
+		log.Printf("✓ Report written to %s", *output)
+	}
+	if differing > 0 {
+		return fmt.Errorf("%d threads differ from the source", differing)
+	}
+	return nil
+}
@@ -1,10 +1,24 @@
 package attachments
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/exileum/xenforo-to-gh-discussions/internal/bbcode"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/concurrency"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/testutil"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
 )
 
@@ -55,10 +69,32 @@ func TestFileSanitizer(t *testing.T) {
 
 type mockXenForoClient struct {
 	downloadError error
+	delay         time.Duration
+	probe         testutil.ConcurrencyProbe
+	truncate      int32 // Downloads that write only half of the content
+	fail          int32 // Downloads that fail before writing anything
+	downloads     int32
 }
 
-func (m *mockXenForoClient) DownloadAttachment(url, filepath string) error {
-	return m.downloadError
+// mockAttachmentContent is the content of every downloaded mock attachment.
+const mockAttachmentContent = "attachment data"
+
+func (m *mockXenForoClient) DownloadAttachment(ctx context.Context, url, filepath string) error {
+	defer m.probe.Enter()()
+	time.Sleep(m.delay)
+	atomic.AddInt32(&m.downloads, 1)
+	if m.downloadError != nil {
+		return m.downloadError
+	}
+	if atomic.AddInt32(&m.fail, -1) >= 0 {
+		return fmt.Errorf("connection reset")
+	}
+
+	content := mockAttachmentContent
+	if atomic.AddInt32(&m.truncate, -1) >= 0 {
+		content = content[:len(content)/2]
+	}
+	return os.WriteFile(filepath, []byte(content), 0644)
 }
 
 func TestDownloader(t *testing.T) {
@@ -75,12 +111,196 @@ func TestDownloader(t *testing.T) {
 	}
 
 	// Test in dry-run mode (should not download)
-	err := downloader.DownloadAttachments(attachments)
+	err := downloader.DownloadAttachments(context.Background(), attachments)
 	if err != nil {
 		t.Errorf("Dry run should not return error: %v", err)
 	}
 }
 
+func TestDownloaderVerification(t *testing.T) {
+	attachment := xenforo.Attachment{
+		AttachmentID: 1,
+		Filename:     "test.png",
+		DirectURL:    "https://example.com/1",
+		FileSize:     int64(len(mockAttachmentContent)),
+	}
+	tempDir := t.TempDir()
+	filePath := filepath.Join(tempDir, "png", "attachment_1_test.png")
+	manifestPath := filepath.Join(tempDir, ManifestFileName)
+
+	readManifest := func(t *testing.T) map[int]ManifestEntry {
+		t.Helper()
+		data, err := os.ReadFile(manifestPath)
+		if err != nil {
+			t.Fatalf("Failed to read manifest: %v", err)
+		}
+		var entries map[int]ManifestEntry
+		if err := json.Unmarshal(data, &entries); err != nil {
+			t.Fatalf("Failed to parse manifest: %v", err)
+		}
+		return entries
+	}
+
+	t.Run("Truncated download is downloaded again", func(t *testing.T) {
+		mockClient := &mockXenForoClient{truncate: 1}
+		if err := NewDownloader(tempDir, false, mockClient, 0).DownloadAttachments(context.Background(), []xenforo.Attachment{attachment}); err != nil {
+			t.Fatalf("DownloadAttachments returned error: %v", err)
+		}
+
+		if mockClient.downloads != 2 {
+			t.Errorf("Expected 2 downloads, got %d", mockClient.downloads)
+		}
+		sum := sha256.Sum256([]byte(mockAttachmentContent))
+		expected := ManifestEntry{Path: "png/attachment_1_test.png", Size: attachment.FileSize, SHA256: hex.EncodeToString(sum[:])}
+		if entry := readManifest(t)[1]; entry != expected {
+			t.Errorf("Expected manifest entry %+v, got %+v", expected, entry)
+		}
+	})
+
+	t.Run("Verified file is kept", func(t *testing.T) {
+		mockClient := &mockXenForoClient{}
+		if err := NewDownloader(tempDir, false, mockClient, 0).DownloadAttachments(context.Background(), []xenforo.Attachment{attachment}); err != nil {
+			t.Fatalf("DownloadAttachments returned error: %v", err)
+		}
+		if mockClient.downloads != 0 {
+			t.Errorf("Expected no download, got %d", mockClient.downloads)
+		}
+	})
+
+	t.Run("File changed on disk is downloaded again", func(t *testing.T) {
+		if err := os.WriteFile(filePath, []byte("attachment DATA"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		mockClient := &mockXenForoClient{}
+		if err := NewDownloader(tempDir, false, mockClient, 0).DownloadAttachments(context.Background(), []xenforo.Attachment{attachment}); err != nil {
+			t.Fatalf("DownloadAttachments returned error: %v", err)
+		}
+		if data, _ := os.ReadFile(filePath); mockClient.downloads != 1 || string(data) != mockAttachmentContent {
+			t.Errorf("Expected the file downloaded again, got %d downloads and %q", mockClient.downloads, data)
+		}
+	})
+
+	t.Run("File that keeps failing verification is removed", func(t *testing.T) {
+		mockClient := &mockXenForoClient{truncate: maxDownloadAttempts}
+		if err := os.Remove(filePath); err != nil {
+			t.Fatal(err)
+		}
+		if err := NewDownloader(tempDir, false, mockClient, 0).DownloadAttachments(context.Background(), []xenforo.Attachment{attachment}); err != nil {
+			t.Fatalf("DownloadAttachments returned error: %v", err)
+		}
+		if mockClient.downloads != maxDownloadAttempts {
+			t.Errorf("Expected %d downloads, got %d", maxDownloadAttempts, mockClient.downloads)
+		}
+		if _, err := os.Stat(filePath); !os.IsNotExist(err) {
+			t.Errorf("Expected the truncated file to be removed, got %v", err)
+		}
+	})
+}
+
+func TestDownloaderRetries(t *testing.T) {
+	attachments := []xenforo.Attachment{{AttachmentID: 1, Filename: "test.png", DirectURL: "https://example.com/1"}}
+
+	tests := []struct {
+		name       string
+		fail       int32
+		downloads  int32
+		downloaded bool
+	}{
+		{name: "Transient failure is retried", fail: 2, downloads: 3, downloaded: true},
+		{name: "Attempts are bounded", fail: 5, downloads: maxDownloadAttempts, downloaded: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockXenForoClient{fail: tt.fail}
+			downloader := NewDownloader(t.TempDir(), false, mockClient, 0)
+			if err := downloader.DownloadAttachments(context.Background(), attachments); err != nil {
+				t.Fatalf("DownloadAttachments returned error: %v", err)
+			}
+
+			if mockClient.downloads != tt.downloads {
+				t.Errorf("Expected %d downloads, got %d", tt.downloads, mockClient.downloads)
+			}
+			if _, err := os.Stat(downloader.LocalPath(attachments[0])); (err == nil) != tt.downloaded {
+				t.Errorf("Expected downloaded=%v, got %v", tt.downloaded, err)
+			}
+		})
+	}
+}
+
+func TestAttachmentPolicy(t *testing.T) {
+	attachments := []xenforo.Attachment{
+		{AttachmentID: 1, Filename: "photo.PNG", DirectURL: "https://forum.example.com/attachments/photo.1/", FileSize: int64(len(mockAttachmentContent))},
+		{AttachmentID: 2, Filename: "huge.png", DirectURL: "https://forum.example.com/attachments/huge.2/", FileSize: 3 << 20},
+		{AttachmentID: 3, Filename: "setup.exe", DirectURL: "https://forum.example.com/attachments/setup.3/"},
+		{AttachmentID: 4, Filename: "notes.txt", DirectURL: "https://forum.example.com/attachments/notes.4/"},
+	}
+	mockClient := &mockXenForoClient{}
+	downloader := NewDownloader(t.TempDir(), false, mockClient, 0).
+		SetForumBaseURL("https://forum.example.com").
+		SetPolicy(2<<20, []string{".png", "EXE"}, []string{"exe"})
+
+	expected := []string{"", "larger than 2.0 MiB", "exe files are blocked", "txt files are not allowed"}
+	for i, attachment := range attachments {
+		if reason := downloader.SkipReason(attachment); reason != expected[i] {
+			t.Errorf("Expected skip reason %q for %s, got %q", expected[i], attachment.Filename, reason)
+		}
+	}
+
+	if err := downloader.DownloadAttachments(context.Background(), attachments); err != nil {
+		t.Fatalf("DownloadAttachments returned error: %v", err)
+	}
+	if mockClient.downloads != 1 {
+		t.Errorf("Expected only the allowed attachment to be downloaded, got %d downloads", mockClient.downloads)
+	}
+
+	message := downloader.ReplaceAttachmentLinks("[ATTACH]1[/ATTACH] [ATTACH]3[/ATTACH] [file](https://forum.example.com/attachments/setup.3/)", attachments)
+	want := "![photo.PNG](./png/attachment_1_photo.PNG) " +
+		"[setup.exe](https://forum.example.com/attachments/setup.3/) *(not migrated: exe files are blocked)* " +
+		"[file](https://forum.example.com/attachments/setup.3/)"
+	if message != want {
+		t.Errorf("Expected %q, got %q", want, message)
+	}
+}
+
+func TestDownloaderWorkers(t *testing.T) {
+	attachments := make([]xenforo.Attachment, 8)
+	for i := range attachments {
+		attachments[i] = xenforo.Attachment{
+			AttachmentID: i + 1,
+			Filename:     fmt.Sprintf("file%d.png", i+1),
+			DirectURL:    fmt.Sprintf("https://example.com/%d", i+1),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		workers   int
+		limit     int
+		maxActive int32
+	}{
+		{name: "Sequential by default", workers: 1, limit: 0, maxActive: 1},
+		{name: "Parallel workers", workers: 3, limit: 0, maxActive: 3},
+		{name: "Bounded by global limiter", workers: 4, limit: 2, maxActive: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := &mockXenForoClient{delay: 20 * time.Millisecond}
+			downloader := NewDownloader(t.TempDir(), false, mockClient, 0).
+				SetConcurrency(tt.workers, concurrency.NewSemaphore(tt.limit))
+
+			if err := downloader.DownloadAttachments(context.Background(), attachments); err != nil {
+				t.Fatalf("DownloadAttachments returned error: %v", err)
+			}
+
+			if mockClient.probe.Max() != tt.maxActive {
+				t.Errorf("Expected %d concurrent downloads, got %d", tt.maxActive, mockClient.probe.Max())
+			}
+		})
+	}
+}
+
 func TestReplaceAttachmentLinks(t *testing.T) {
 	mockClient := &mockXenForoClient{}
 	tempDir := t.TempDir()
@@ -113,6 +333,303 @@ func TestReplaceAttachmentLinks(t *testing.T) {
 	}
 }
 
+func TestReplaceAttachmentLinksInsideQuotes(t *testing.T) {
+	downloader := NewDownloader(t.TempDir(), true, &mockXenForoClient{}, 0)
+	processor := bbcode.NewMessageProcessor()
+
+	attachments := []xenforo.Attachment{
+		{AttachmentID: 1, Filename: "image.png", DirectURL: "https://example.com/1"},
+		{AttachmentID: 2, Filename: "document.pdf", DirectURL: "https://example.com/2"},
+	}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Inline attachment in attributed quote",
+			input:    `[quote="John, post: 12, member: 3"]Look at this: [ATTACH=full]1[/ATTACH][/quote]`,
+			expected: "> **John said:**\n> Look at this: ![image.png](./png/attachment_1_image.png)\n",
+		},
+		{
+			name:     "Attachment on its own line in quote",
+			input:    "[quote]First line\n[ATTACH]1[/ATTACH]\nLast line[/quote]",
+			expected: "> First line\n> ![image.png](./png/attachment_1_image.png)\n> Last line\n",
+		},
+		{
+			name:     "Lowercase attach tag in quote",
+			input:    "[quote]See [attach type=\"full\"]2[/attach][/quote]",
+			expected: "> See [document.pdf](./pdf/attachment_2_document.pdf)\n",
+		},
+		{
+			name:     "Unknown attachment is left untouched",
+			input:    "[quote][ATTACH]99[/ATTACH][/quote]",
+			expected: "> [ATTACH]99[/ATTACH]\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			markdown := processor.ProcessContent(tt.input)
+			result := downloader.ReplaceAttachmentLinks(markdown, attachments)
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestReplaceAttachmentShortLinks(t *testing.T) {
+	downloader := NewDownloader(t.TempDir(), true, &mockXenForoClient{}, 0).
+		SetForumBaseURL("https://forum.example.com/")
+
+	attachments := []xenforo.Attachment{
+		{AttachmentID: 123, Filename: "screenshot.png"},
+		{AttachmentID: 124, Filename: "manual.pdf"},
+	}
+	hostedURLs := map[int]string{124: "https://raw.githubusercontent.com/owner/repo/main/manual.pdf"}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Link target",
+			input:    "See [the screenshot](https://forum.example.com/attachments/screenshot-png.123/)",
+			expected: "See [the screenshot](./png/attachment_123_screenshot.png)",
+		},
+		{
+			name:     "Image target",
+			input:    "![](http://forum.example.com/attachments/screenshot-png.123/?hash=abc)",
+			expected: "![](./png/attachment_123_screenshot.png)",
+		},
+		{
+			name:     "Bare URL of a hosted attachment",
+			input:    "Manual: https://forum.example.com/attachments/124/",
+			expected: "Manual: [manual.pdf](https://raw.githubusercontent.com/owner/repo/main/manual.pdf)",
+		},
+		{
+			name:     "Unknown attachment is left unchanged",
+			input:    "Old: https://forum.example.com/attachments/other-zip.999/",
+			expected: "Old: https://forum.example.com/attachments/other-zip.999/",
+		},
+		{
+			name:     "Other hosts are left unchanged",
+			input:    "Mirror: https://mirror.example.com/attachments/screenshot-png.123/",
+			expected: "Mirror: https://mirror.example.com/attachments/screenshot-png.123/",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := downloader.ReplaceAttachmentLinksWithURLs(tt.input, attachments, hostedURLs)
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// Without a forum base URL short links are not rewritten
+	plain := NewDownloader(t.TempDir(), true, &mockXenForoClient{}, 0)
+	input := "https://forum.example.com/attachments/screenshot-png.123/"
+	if result := plain.ReplaceAttachmentLinks(input, attachments); result != input {
+		t.Errorf("Expected unchanged link, got %q", result)
+	}
+}
+
+func TestSaveInlineImages(t *testing.T) {
+	tempDir := t.TempDir()
+	downloader := NewDownloader(tempDir, false, &mockXenForoClient{}, 0)
+
+	png := []byte("\x89PNG\r\n\x1a\nfake image data")
+	message := "Look: [IMG]data:image/png;base64," + base64.StdEncoding.EncodeToString(png) + "[/IMG] and [img]https://example.com/a.png[/img]"
+
+	result, saved := downloader.SaveInlineImages(5, message, 1024)
+	if len(saved) != 1 {
+		t.Fatalf("Expected 1 inline image, got %d", len(saved))
+	}
+	if saved[0].Filename != "inline_5_1.png" {
+		t.Errorf("Expected generated filename, got %q", saved[0].Filename)
+	}
+	if expected := inlineImageIDBase + 5*maxInlineImagesPer + 1; saved[0].AttachmentID != expected {
+		t.Errorf("Expected synthetic attachment ID %d, got %d", expected, saved[0].AttachmentID)
+	}
+
+	data, err := os.ReadFile(downloader.LocalPath(saved[0]))
+	if err != nil {
+		t.Fatalf("Inline image was not saved: %v", err)
+	}
+	if !bytes.Equal(data, png) {
+		t.Error("Saved inline image does not match the decoded data")
+	}
+
+	linked := downloader.ReplaceAttachmentLinks(result, saved)
+	expected := fmt.Sprintf("Look: ![inline_5_1.png](./png/attachment_%d_inline_5_1.png) and [img]https://example.com/a.png[/img]", saved[0].AttachmentID)
+	if linked != expected {
+		t.Errorf("Expected %q, got %q", expected, linked)
+	}
+
+	t.Run("Oversized image is omitted", func(t *testing.T) {
+		result, saved := downloader.SaveInlineImages(6, message, 8)
+		if len(saved) != 0 {
+			t.Errorf("Oversized image should not be saved, got %d", len(saved))
+		}
+		if strings.Contains(result, "data:image") || !strings.Contains(result, "*[inline image omitted]*") {
+			t.Errorf("Oversized data URI should be replaced with a note, got %q", result)
+		}
+	})
+
+	t.Run("Invalid data is omitted", func(t *testing.T) {
+		result, saved := downloader.SaveInlineImages(7, "[img]data:image/png;base64,!!![/img]", 0)
+		if len(saved) != 0 || result != "*[inline image omitted]*" {
+			t.Errorf("Invalid data URI should be replaced with a note, got %q (%d saved)", result, len(saved))
+		}
+	})
+}
+
+func TestFilenameTemplate(t *testing.T) {
+	tmpl, err := ParseFilenameTemplate("{{.Name}}-{{.ID}}{{.Ext}}")
+	if err != nil {
+		t.Fatalf("ParseFilenameTemplate returned error: %v", err)
+	}
+
+	tempDir := t.TempDir()
+	downloader := NewDownloader(tempDir, true, &mockXenForoClient{}, 0).SetFilenameTemplate(tmpl)
+	attachment := xenforo.Attachment{AttachmentID: 7, Filename: "photo.png"}
+
+	localPath := downloader.LocalPath(attachment)
+	if expected := filepath.Join(tempDir, "png", "photo-7.png"); localPath != expected {
+		t.Errorf("Expected local path %q, got %q", expected, localPath)
+	}
+
+	link := downloader.ReplaceAttachmentLinks("[ATTACH=full]7[/ATTACH]", []xenforo.Attachment{attachment})
+	if link != "![photo.png](./png/photo-7.png)" {
+		t.Errorf("Link should point at the templated file name, got %q", link)
+	}
+
+	uploader := NewUploader(&mockCommitter{}, "owner/repo", "forum-assets", "attachments", false).SetFilenameTemplate(tmpl)
+	if repoPath := uploader.RepoPath(3, attachment); repoPath != "attachments/thread-3/photo-7.png" {
+		t.Errorf("Uploaded file should keep the templated file name, got %q", repoPath)
+	}
+
+	// The default template keeps the historical names
+	defaultTmpl, err := ParseFilenameTemplate("attachment_{{.ID}}_{{.Name}}{{.Ext}}")
+	if err != nil {
+		t.Fatalf("Default template should parse: %v", err)
+	}
+	downloader.SetFilenameTemplate(defaultTmpl)
+	if base := filepath.Base(downloader.LocalPath(attachment)); base != "attachment_7_photo.png" {
+		t.Errorf("Expected default file name, got %q", base)
+	}
+
+	for _, invalid := range []string{"", "{{.Name}}/{{.ID}}{{.Ext}}", "{{.Missing}}", "{{.Name", "{{.ID}}:{{.Ext}}", "../{{.ID}}{{.Ext}}", "{{.Name}}{{.Ext}}"} {
+		if _, err := ParseFilenameTemplate(invalid); err == nil {
+			t.Errorf("Expected template %q to be rejected", invalid)
+		}
+	}
+}
+
+// fakeAvatarSource serves users with avatar URLs and counts requests.
+type fakeAvatarSource struct {
+	avatars   map[int]string // User ID -> avatar URL
+	size      int
+	delay     time.Duration // Per user lookup
+	probe     testutil.ConcurrencyProbe
+	mu        sync.Mutex
+	lookups   int
+	downloads int
+}
+
+func (f *fakeAvatarSource) GetUser(ctx context.Context, userID int) (*xenforo.User, error) {
+	defer f.probe.Enter()()
+	time.Sleep(f.delay)
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.lookups++
+	return &xenforo.User{UserID: userID, AvatarURLs: map[string]string{"s": f.avatars[userID]}}, nil
+}
+
+func (f *fakeAvatarSource) DownloadAttachment(ctx context.Context, url, filepath string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.downloads++
+	return os.WriteFile(filepath, bytes.Repeat([]byte("a"), f.size), 0644)
+}
+
+func TestAvatarCache(t *testing.T) {
+	source := &fakeAvatarSource{
+		avatars: map[int]string{
+			1: "https://forum.example.com/data/avatars/s/0/1.jpg?1700000000",
+			2: "https://forum.example.com/data/avatars/s/0/1.jpg?1700000000", // Same image as user 1
+			3: "",
+		},
+		size: 100,
+	}
+	tempDir := t.TempDir()
+	cache := NewAvatarCache(tempDir, false, source).SetMaxSize(1024)
+
+	for i := 0; i < 3; i++ {
+		if link := cache.Avatar(context.Background(), 1); link != "./avatars/avatar_1.jpg" {
+			t.Fatalf("Expected local avatar link, got %q", link)
+		}
+	}
+	if source.lookups != 1 || source.downloads != 1 {
+		t.Errorf("Avatar should be looked up and downloaded once, got %d lookups and %d downloads", source.lookups, source.downloads)
+	}
+	if _, err := os.Stat(filepath.Join(tempDir, "avatars", "avatar_1.jpg")); err != nil {
+		t.Errorf("Avatar file should exist: %v", err)
+	}
+
+	if link := cache.Avatar(context.Background(), 2); link != "./avatars/avatar_1.jpg" || source.downloads != 1 {
+		t.Errorf("Shared avatar URL should reuse the file, got %q after %d downloads", link, source.downloads)
+	}
+	if link := cache.Avatar(context.Background(), 3); link != "" {
+		t.Errorf("User without avatar should get no link, got %q", link)
+	}
+	if link := cache.Avatar(context.Background(), 0); link != "" || source.lookups != 3 {
+		t.Errorf("Guests should not be looked up, got %q after %d lookups", link, source.lookups)
+	}
+
+	t.Run("Oversized avatar is skipped", func(t *testing.T) {
+		source := &fakeAvatarSource{avatars: map[int]string{5: "https://forum.example.com/5.png"}, size: 2048}
+		cache := NewAvatarCache(t.TempDir(), false, source).SetMaxSize(1024)
+		if link := cache.Avatar(context.Background(), 5); link != "" {
+			t.Errorf("Oversized avatar should be skipped, got %q", link)
+		}
+	})
+
+	t.Run("Users are looked up concurrently and once", func(t *testing.T) {
+		source := &fakeAvatarSource{
+			avatars: map[int]string{1: "https://forum.example.com/1.png", 2: "https://forum.example.com/2.png"},
+			size:    10,
+			delay:   20 * time.Millisecond,
+		}
+		cache := NewAvatarCache(t.TempDir(), false, source)
+
+		var wg sync.WaitGroup
+		for _, userID := range []int{1, 2, 1, 2} {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if link := cache.Avatar(context.Background(), userID); link != fmt.Sprintf("./avatars/avatar_%d.png", userID) {
+					t.Errorf("Expected the avatar of user %d, got %q", userID, link)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if source.lookups != 2 {
+			t.Errorf("Expected one lookup per user, got %d", source.lookups)
+		}
+		if source.probe.Max() < 2 {
+			t.Error("Expected users to be looked up concurrently")
+		}
+	})
+}
+
 func TestValidatePath(t *testing.T) {
 	sanitizer := NewFileSanitizer()
 
@@ -288,7 +805,7 @@ func TestDownloaderRateLimiting(t *testing.T) {
 
 			// Measure execution time
 			start := time.Now()
-			err := downloader.DownloadAttachments(attachments)
+			err := downloader.DownloadAttachments(context.Background(), attachments)
 			elapsed := time.Since(start)
 
 			if err != nil {
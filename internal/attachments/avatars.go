@@ -0,0 +1,210 @@
+package attachments
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// avatarsDir is the attachments subdirectory holding downloaded avatars.
+const avatarsDir = "avatars"
+
+// AvatarSource looks up forum members and downloads their avatars.
+type AvatarSource interface {
+	GetUser(ctx context.Context, userID int) (*xenforo.User, error)
+	DownloadAttachment(ctx context.Context, url, filepath string) error
+}
+
+// AvatarCache downloads each author's avatar once and returns the link to
+// use in post headers. Users sharing an avatar URL share the file, and
+// avatars already on disk from an earlier run are not downloaded again.
+type AvatarCache struct {
+	source         AvatarSource
+	sanitizer      *FileSanitizer
+	uploader       *Uploader
+	attachmentsDir string
+	dryRun         bool
+	maxSize        int64
+
+	// The lock only guards the maps: lookups, downloads and uploads of
+	// different users run concurrently
+	mu       sync.Mutex
+	links    map[int]*avatarLookup // User ID -> lookup of the user's avatar
+	urlLinks map[string]string     // Avatar URL -> avatar link
+}
+
+// avatarLookup is the lookup of a user's avatar. Callers asking for the same
+// user while it runs wait for its link.
+type avatarLookup struct {
+	done chan struct{} // Closed once link is set
+	link string        // Avatar link ("" = no avatar)
+}
+
+// NewAvatarCache creates a cache storing avatars in the "avatars"
+// subdirectory of attachmentsDir.
+func NewAvatarCache(attachmentsDir string, dryRun bool, source AvatarSource) *AvatarCache {
+	return &AvatarCache{
+		source:         source,
+		sanitizer:      NewFileSanitizer(),
+		attachmentsDir: attachmentsDir,
+		dryRun:         dryRun,
+		links:          make(map[int]*avatarLookup),
+		urlLinks:       make(map[string]string),
+	}
+}
+
+// SetMaxSize skips avatars larger than maxSize bytes (0 = no limit).
+func (a *AvatarCache) SetMaxSize(maxSize int64) *AvatarCache {
+	a.maxSize = maxSize
+	return a
+}
+
+// SetUploader uploads avatars to GitHub so post headers link to hosted URLs
+// instead of local paths.
+func (a *AvatarCache) SetUploader(uploader *Uploader) *AvatarCache {
+	a.uploader = uploader
+	return a
+}
+
+// Avatar returns the avatar link for a user, or an empty string when the user
+// has no avatar or it could not be stored. Lookups and downloads happen once
+// per user; failures are logged and not retried. Concurrent calls for the
+// same user wait for the first one.
+func (a *AvatarCache) Avatar(ctx context.Context, userID int) string {
+	if userID <= 0 {
+		return ""
+	}
+
+	a.mu.Lock()
+	if lookup, ok := a.links[userID]; ok {
+		a.mu.Unlock()
+		select {
+		case <-lookup.done:
+			return lookup.link
+		case <-ctx.Done():
+			return ""
+		}
+	}
+	lookup := &avatarLookup{done: make(chan struct{})}
+	a.links[userID] = lookup
+	a.mu.Unlock()
+
+	link, err := a.fetch(ctx, userID)
+	if err != nil {
+		logging.Warnf(ctx, "    ⚠ Skipping avatar of user %d: %v", userID, err)
+	}
+	lookup.link = link
+	close(lookup.done)
+	return link
+}
+
+func (a *AvatarCache) fetch(ctx context.Context, userID int) (string, error) {
+	user, err := a.source.GetUser(ctx, userID)
+	if err != nil {
+		return "", err
+	}
+
+	avatarURL := user.AvatarURL()
+	if avatarURL == "" {
+		return "", nil
+	}
+	if link, ok := a.sharedLink(avatarURL); ok {
+		return link, nil
+	}
+
+	filename := a.sanitizer.SanitizeFilename(fmt.Sprintf("avatar_%d%s", userID, avatarExtension(avatarURL)))
+	link := "./" + avatarsDir + "/" + filename
+
+	if a.dryRun {
+		logging.Infof(ctx, "    [DRY-RUN] Would download avatar: %s", filename)
+		a.shareLink(avatarURL, link)
+		return link, nil
+	}
+
+	filePath, err := a.download(ctx, avatarURL, filename)
+	if err != nil {
+		return "", err
+	}
+
+	if a.uploader != nil {
+		content, err := os.ReadFile(filePath)
+		if err != nil {
+			return "", fmt.Errorf("failed to read avatar: %w", err)
+		}
+		if link, err = a.uploader.UploadAvatar(ctx, filename, content); err != nil {
+			return "", err
+		}
+	}
+
+	a.shareLink(avatarURL, link)
+	return link, nil
+}
+
+// sharedLink returns the link of an avatar URL already stored for another user.
+func (a *AvatarCache) sharedLink(avatarURL string) (string, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	link, ok := a.urlLinks[avatarURL]
+	return link, ok
+}
+
+// shareLink records the link of an avatar URL for the other users sharing it.
+func (a *AvatarCache) shareLink(avatarURL, link string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.urlLinks[avatarURL] = link
+}
+
+// download stores the avatar unless it already exists on disk and enforces
+// the size limit.
+func (a *AvatarCache) download(ctx context.Context, avatarURL, filename string) (string, error) {
+	dir := filepath.Join(a.attachmentsDir, avatarsDir)
+	filePath := filepath.Join(dir, filename)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	if err := a.sanitizer.ValidatePath(filePath, dir); err != nil {
+		return "", fmt.Errorf("security violation: file path escapes directory")
+	}
+
+	if _, err := os.Stat(filePath); err != nil {
+		if err := a.source.DownloadAttachment(ctx, avatarURL, filePath); err != nil {
+			_ = os.Remove(filePath)
+			return "", err
+		}
+		logging.Infof(ctx, "    ✓ Downloaded avatar: %s", filename)
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to stat avatar: %w", err)
+	}
+	if a.maxSize > 0 && info.Size() > a.maxSize {
+		_ = os.Remove(filePath)
+		return "", fmt.Errorf("avatar exceeds the %d byte limit", a.maxSize)
+	}
+
+	return filePath, nil
+}
+
+// avatarExtension returns the image extension of an avatar URL, defaulting
+// to ".jpg" (XenForo's avatar format).
+func avatarExtension(avatarURL string) string {
+	ext := ".jpg"
+	if parsed, err := url.Parse(avatarURL); err == nil {
+		switch candidate := strings.ToLower(path.Ext(parsed.Path)); candidate {
+		case ".jpg", ".jpeg", ".png", ".gif", ".webp":
+			ext = candidate
+		}
+	}
+	return ext
+}
@@ -4,14 +4,19 @@
 package attachments
 
 import (
+	"context"
 	"fmt"
-	"log"
 	"os"
 	"path/filepath"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"text/template"
 	"time"
 
+	"github.com/exileum/xenforo-to-gh-discussions/internal/concurrency"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
 )
 
@@ -21,10 +26,17 @@ type Downloader struct {
 	dryRun         bool
 	client         XenForoDownloader
 	rateLimitDelay time.Duration
+	workers        int
+	limiter        *concurrency.Semaphore
+	manifest       *manifest
+	policy         *attachmentPolicy // Attachments skipped by size or extension (nil = none)
+
+	filenameTemplate *template.Template
+	shortLinkPattern *regexp.Regexp
 }
 
 type XenForoDownloader interface {
-	DownloadAttachment(url, filepath string) error
+	DownloadAttachment(ctx context.Context, url, filepath string) error
 }
 
 func NewDownloader(attachmentsDir string, dryRun bool, client XenForoDownloader, rateLimitDelay time.Duration) *Downloader {
@@ -34,37 +46,100 @@ func NewDownloader(attachmentsDir string, dryRun bool, client XenForoDownloader,
 		dryRun:         dryRun,
 		client:         client,
 		rateLimitDelay: rateLimitDelay,
+		workers:        1,
+		manifest:       newManifest(attachmentsDir),
+	}
+}
+
+// SetConcurrency configures how many attachments are downloaded in parallel.
+// Every download also holds a slot of the shared limiter, which bounds the
+// combined thread and attachment work across the whole migration.
+func (d *Downloader) SetConcurrency(workers int, limiter *concurrency.Semaphore) *Downloader {
+	if workers < 1 {
+		workers = 1
 	}
+	d.workers = workers
+	d.limiter = limiter
+	return d
 }
 
-func (d *Downloader) DownloadAttachments(attachments []xenforo.Attachment) error {
+// DownloadAttachments downloads the attachments that are not on disk yet and
+// verifies every file against the size reported by the forum and the
+// attachments manifest, downloading mismatching files again. Failed downloads
+// are retried and then logged; only a failure to save the manifest is
+// returned. A cancelled ctx stops starting and retrying downloads.
+func (d *Downloader) DownloadAttachments(ctx context.Context, attachments []xenforo.Attachment) error {
 	for _, attachment := range attachments {
-		if d.dryRun {
-			log.Printf("    [DRY-RUN] Would download: %s", attachment.Filename)
-			continue
+		if reason := d.SkipReason(attachment); reason != "" {
+			d.skip(ctx, attachment, reason)
 		}
+	}
+	attachments = d.Allowed(attachments)
 
-		if err := d.downloadSingle(attachment); err != nil {
-			log.Printf("    ✗ Failed to download %s: %v", attachment.Filename, err)
-			continue
+	if d.dryRun {
+		for _, attachment := range attachments {
+			logging.Infof(ctx, "    [DRY-RUN] Would download: %s", attachment.Filename)
 		}
+		return nil
 	}
-	return nil
+
+	jobs := make(chan xenforo.Attachment)
+	var wg sync.WaitGroup
+
+	for i := 0; i < min(d.workers, len(attachments)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for attachment := range jobs {
+				d.downloadWithLimit(ctx, attachment)
+			}
+		}()
+	}
+
+	for _, attachment := range attachments {
+		if ctx.Err() != nil {
+			break
+		}
+		jobs <- attachment
+	}
+	close(jobs)
+	wg.Wait()
+
+	return d.manifest.save()
 }
 
-func (d *Downloader) downloadSingle(attachment xenforo.Attachment) error {
-	// Determine file extension and create directory
-	ext := d.getFileExtension(attachment.Filename)
-	dir := filepath.Join(d.attachmentsDir, ext)
+func (d *Downloader) downloadWithLimit(ctx context.Context, attachment xenforo.Attachment) {
+	if err := d.limiter.Acquire(ctx); err != nil {
+		logging.Errorf(ctx, "    ✗ Failed to download %s: %v", attachment.Filename, err)
+		return
+	}
+	defer d.limiter.Release()
 
-	if err := os.MkdirAll(dir, 0755); err != nil {
-		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	if err := d.downloadSingle(ctx, attachment); err != nil {
+		logging.Errorf(ctx, "    ✗ Failed to download %s: %v", attachment.Filename, err)
 	}
+}
 
-	// Generate safe filename
+// LocalPath returns the on-disk location of a downloaded attachment.
+func (d *Downloader) LocalPath(attachment xenforo.Attachment) string {
 	sanitizedFilename := d.sanitizer.SanitizeFilename(attachment.Filename)
-	filename := fmt.Sprintf("attachment_%d_%s", attachment.AttachmentID, sanitizedFilename)
-	filePath := filepath.Join(dir, filename)
+	ext := d.getFileExtension(sanitizedFilename)
+	return filepath.Join(d.attachmentsDir, ext, d.localFilename(attachment))
+}
+
+// maxDownloadAttempts bounds the downloads of an attachment that keep failing
+// or whose file keeps failing verification. An interrupted download is
+// resumed by the next attempt when the client supports it.
+const maxDownloadAttempts = 3
+
+func (d *Downloader) downloadSingle(ctx context.Context, attachment xenforo.Attachment) error {
+	filePath := d.LocalPath(attachment)
+	dir := filepath.Dir(filePath)
+	filename := filepath.Base(filePath)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
 
 	// Validate path security
 	if err := d.sanitizer.ValidatePath(filePath, dir); err != nil {
@@ -73,22 +148,63 @@ func (d *Downloader) downloadSingle(attachment xenforo.Attachment) error {
 
 	// Check if file already exists
 	if _, err := os.Stat(filePath); err == nil {
-		log.Printf("    ⏭ Skipped (already exists): %s", filename)
-		return nil
+		err := d.verify(attachment, filePath, true)
+		if err == nil {
+			logging.Infof(ctx, "    ⏭ Skipped (already exists): %s", filename)
+			return nil
+		}
+		logging.Warnf(ctx, "    ⚠ Downloading %s again: %v", filename, err)
 	}
 
-	// Download file
-	if err := d.client.DownloadAttachment(attachment.DirectURL, filePath); err != nil {
-		return err
-	}
+	for attempt := 1; ; attempt++ {
+		err := d.client.DownloadAttachment(ctx, attachment.DirectURL, filePath)
+		if err == nil {
+			if err = d.verify(attachment, filePath, false); err != nil {
+				_ = os.Remove(filePath) // Never link a file that failed verification
+			}
+		}
+
+		// Configurable rate limiting
+		if d.rateLimitDelay > 0 {
+			select {
+			case <-ctx.Done():
+			case <-time.After(d.rateLimitDelay):
+			}
+		}
 
-	log.Printf("    ✓ Downloaded: %s", filename)
+		if err == nil {
+			logging.Infof(ctx, "    ✓ Downloaded: %s", filename)
+			return nil
+		}
+		if attempt == maxDownloadAttempts || ctx.Err() != nil {
+			return fmt.Errorf("%w (after %d attempts)", err, attempt)
+		}
+		logging.Warnf(ctx, "    ⚠ Downloading %s again: %v", filename, err)
+	}
+}
 
-	// Configurable rate limiting
-	if d.rateLimitDelay > 0 {
-		time.Sleep(d.rateLimitDelay)
+// verify checks a downloaded file against the size the forum reports for the
+// attachment, when known, and records its size and checksum in the manifest.
+// A file from an earlier run must also match its manifest entry, if any.
+func (d *Downloader) verify(attachment xenforo.Attachment, filePath string, existing bool) error {
+	size, sum, err := fileChecksum(filePath)
+	if err != nil {
+		return fmt.Errorf("failed to verify download: %w", err)
+	}
+	if attachment.FileSize > 0 && size != attachment.FileSize {
+		return fmt.Errorf("size mismatch: %d bytes instead of %d", size, attachment.FileSize)
+	}
+	if existing {
+		if entry, ok := d.manifest.entry(attachment.AttachmentID); ok && (entry.Size != size || entry.SHA256 != sum) {
+			return fmt.Errorf("checksum mismatch: file differs from the manifest")
+		}
 	}
 
+	relPath, err := filepath.Rel(d.attachmentsDir, filePath)
+	if err != nil {
+		relPath = filePath
+	}
+	d.manifest.record(attachment.AttachmentID, ManifestEntry{Path: filepath.ToSlash(relPath), Size: size, SHA256: sum})
 	return nil
 }
 
@@ -100,41 +216,65 @@ func (d *Downloader) getFileExtension(filename string) string {
 	return strings.TrimPrefix(ext, ".")
 }
 
-func (d *Downloader) ReplaceAttachmentLinks(message string, attachments []xenforo.Attachment) string {
-	for _, attachment := range attachments {
-		sanitizedFilename := d.sanitizer.SanitizeFilename(attachment.Filename)
-		ext := d.getFileExtension(sanitizedFilename)
+// attachTagPattern matches XenForo attachment BB-codes in any letter case:
+// [ATTACH]123[/ATTACH], [ATTACH=full]123[/ATTACH], [ATTACH type="full"]123[/ATTACH]
+// and the short [ATTACH=123] form.
+var attachTagPattern = regexp.MustCompile(`(?i)\[attach(?:=[a-z]+|\s[^\]]*)?\](\d+)\[/attach\]|\[attach=(\d+)\]`)
 
-		filename := fmt.Sprintf("attachment_%d_%s", attachment.AttachmentID, sanitizedFilename)
-		relativePath := fmt.Sprintf("./%s/%s", ext, filename)
-
-		// Determine if it's an image
-		isImage := d.isImageFile(ext)
+// ReplaceAttachmentLinks replaces attachment BB-codes with Markdown links to
+// the downloaded files. Replacements are single-line, so tags that sit inside
+// already-converted "> " quote lines stay within the blockquote.
+func (d *Downloader) ReplaceAttachmentLinks(message string, attachments []xenforo.Attachment) string {
+	return d.ReplaceAttachmentLinksWithURLs(message, attachments, nil)
+}
 
-		// Replace BB-code with appropriate markdown
-		bbCode := fmt.Sprintf("[ATTACH=%d]", attachment.AttachmentID)
-		bbCodeFull := fmt.Sprintf("[ATTACH=full]%d[/ATTACH]", attachment.AttachmentID)
+// ReplaceAttachmentLinksWithURLs works like ReplaceAttachmentLinks but links
+// attachments present in hostedURLs (keyed by attachment ID) to their hosted
+// location instead of the local relative path. Attachment short URLs are
+// rewritten as well when a forum base URL is set.
+func (d *Downloader) ReplaceAttachmentLinksWithURLs(message string, attachments []xenforo.Attachment, hostedURLs map[int]string) string {
+	links := make(map[string]string, len(attachments))
+	for _, attachment := range attachments {
+		links[strconv.Itoa(attachment.AttachmentID)] = d.markdownLink(attachment, hostedURLs[attachment.AttachmentID])
+	}
 
-		var markdownLink string
-		if isImage {
-			markdownLink = fmt.Sprintf("![%s](%s)", sanitizedFilename, relativePath)
-		} else {
-			markdownLink = fmt.Sprintf("[%s](%s)", sanitizedFilename, relativePath)
+	message = attachTagPattern.ReplaceAllStringFunc(message, func(match string) string {
+		parts := attachTagPattern.FindStringSubmatch(match)
+		id := parts[1]
+		if id == "" {
+			id = parts[2]
+		}
+		if link, ok := links[id]; ok {
+			return link
 		}
+		return match
+	})
 
-		message = strings.ReplaceAll(message, bbCode, markdownLink)
-		message = strings.ReplaceAll(message, bbCodeFull, markdownLink)
-	}
+	message = d.replaceShortLinks(message, attachments, hostedURLs)
 
 	// Log any remaining unhandled attach codes
-	remaining := regexp.MustCompile(`\[ATTACH[^]]*\]`).FindAllString(message, -1)
+	remaining := regexp.MustCompile(`(?i)\[ATTACH[^]]*\]`).FindAllString(message, -1)
 	for _, code := range remaining {
-		log.Printf("    ⚠ Unhandled attachment code: %s", code)
+		logging.Warnf(context.Background(), "    ⚠ Unhandled attachment code: %s", code)
 	}
 
 	return message
 }
 
+func (d *Downloader) markdownLink(attachment xenforo.Attachment, hostedURL string) string {
+	sanitizedFilename := d.sanitizer.SanitizeFilename(attachment.Filename)
+	if reason := d.SkipReason(attachment); reason != "" {
+		return fmt.Sprintf("[%s](%s) *(not migrated: %s)*", sanitizedFilename, attachment.DirectURL, reason)
+	}
+	ext := d.getFileExtension(sanitizedFilename)
+	target := d.linkTarget(attachment, hostedURL)
+
+	if d.isImageFile(ext) {
+		return fmt.Sprintf("![%s](%s)", sanitizedFilename, target)
+	}
+	return fmt.Sprintf("[%s](%s)", sanitizedFilename, target)
+}
+
 func (d *Downloader) isImageFile(ext string) bool {
 	imageExtensions := map[string]bool{
 		"png":  true,
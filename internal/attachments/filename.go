@@ -0,0 +1,79 @@
+package attachments
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+	"text/template"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// FilenameData is the data available to attachment filename templates.
+type FilenameData struct {
+	ID   int    // XenForo attachment ID
+	Name string // Sanitized original filename without its extension
+	Ext  string // Extension of the original filename including the dot, empty if none
+}
+
+// ParseFilenameTemplate parses an attachment filename template and checks that
+// it renders a safe, non-empty filename: no directory separators or characters
+// the sanitizer would replace. The filename must include the attachment ID,
+// which keeps attachments with the same name apart.
+func ParseFilenameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("filename").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename template: %w", err)
+	}
+
+	sample, err := renderFilename(tmpl, FilenameData{ID: 123, Name: "image", Ext: ".png"})
+	if err != nil {
+		return nil, fmt.Errorf("invalid filename template: %w", err)
+	}
+	if sample == "" || sample == "." || sample == ".." {
+		return nil, fmt.Errorf("filename template renders an empty filename")
+	}
+	if sanitized := NewFileSanitizer().SanitizeFilename(sample); sanitized != sample {
+		return nil, fmt.Errorf("filename template renders unsafe filename %q (sanitized to %q)", sample, sanitized)
+	}
+	if other, err := renderFilename(tmpl, FilenameData{ID: 456, Name: "image", Ext: ".png"}); err != nil || other == sample {
+		return nil, fmt.Errorf("filename template must include the attachment ID ({{.ID}})")
+	}
+
+	return tmpl, nil
+}
+
+// SetFilenameTemplate sets the template naming downloaded attachments. The same
+// name is used on disk and in the Markdown links.
+func (d *Downloader) SetFilenameTemplate(tmpl *template.Template) *Downloader {
+	d.filenameTemplate = tmpl
+	return d
+}
+
+// localFilename returns the on-disk filename of an attachment.
+func (d *Downloader) localFilename(attachment xenforo.Attachment) string {
+	return attachmentFilename(d.filenameTemplate, d.sanitizer, attachment)
+}
+
+// attachmentFilename names an attachment with tmpl, or "attachment_<id>_<filename>"
+// without one. Rendered names are sanitized again since they include the
+// forum-provided filename.
+func attachmentFilename(tmpl *template.Template, sanitizer *FileSanitizer, attachment xenforo.Attachment) string {
+	sanitizedFilename := sanitizer.SanitizeFilename(attachment.Filename)
+	if tmpl != nil {
+		ext := filepath.Ext(sanitizedFilename)
+		data := FilenameData{ID: attachment.AttachmentID, Name: strings.TrimSuffix(sanitizedFilename, ext), Ext: ext}
+		if filename, err := renderFilename(tmpl, data); err == nil && filename != "" {
+			return sanitizer.SanitizeFilename(filename)
+		}
+	}
+	return fmt.Sprintf("attachment_%d_%s", attachment.AttachmentID, sanitizedFilename)
+}
+
+func renderFilename(tmpl *template.Template, data FilenameData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
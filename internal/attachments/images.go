@@ -0,0 +1,115 @@
+package attachments
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+)
+
+// imageProcessor downsizes images wider than maxWidth before they are
+// uploaded, so large forum screenshots do not bloat the upload repository.
+type imageProcessor struct {
+	maxWidth int // Widest image uploaded unchanged, in pixels
+	quality  int // JPEG quality of downsized images (1-100)
+}
+
+// SetImageProcessing downsizes JPEG and PNG attachments wider than maxWidth
+// pixels (0 = disabled) to that width before uploading them, keeping their
+// aspect ratio and format. JPEGs are encoded at quality (1-100); PNGs stay
+// lossless. Other formats, including GIF and WebP, are uploaded unchanged, as
+// is any image whose downsized encoding is not smaller. Local files are never
+// modified.
+func (u *Uploader) SetImageProcessing(maxWidth, quality int) *Uploader {
+	u.images = nil
+	if maxWidth > 0 {
+		u.images = &imageProcessor{maxWidth: maxWidth, quality: quality}
+	}
+	return u
+}
+
+// resizedImage describes an image downsized by the processor.
+type resizedImage struct {
+	content               []byte
+	fromWidth, fromHeight int
+	toWidth, toHeight     int
+}
+
+// process returns the downsized content of an image, or nil when the content
+// is uploaded unchanged: it is not a JPEG or PNG, is narrow enough, or would
+// not get smaller. Undecodable images return an error.
+func (p *imageProcessor) process(content []byte) (*resizedImage, error) {
+	config, format, err := image.DecodeConfig(bytes.NewReader(content))
+	if err != nil || (format != "jpeg" && format != "png") || config.Width <= p.maxWidth {
+		return nil, nil
+	}
+
+	src, _, err := image.Decode(bytes.NewReader(content))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s image: %w", format, err)
+	}
+
+	width := p.maxWidth
+	height := max(1, config.Height*width/config.Width)
+	dst := downscale(src, width, height)
+
+	var buf bytes.Buffer
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: p.quality})
+	} else {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s image: %w", format, err)
+	}
+	if buf.Len() >= len(content) {
+		return nil, nil
+	}
+
+	return &resizedImage{
+		content:    buf.Bytes(),
+		fromWidth:  config.Width,
+		fromHeight: config.Height,
+		toWidth:    width,
+		toHeight:   height,
+	}, nil
+}
+
+// downscale resizes src to width x height by averaging the source pixels
+// each destination pixel covers, which keeps text in screenshots legible.
+func downscale(src image.Image, width, height int) *image.RGBA {
+	bounds := src.Bounds()
+	rgba := image.NewRGBA(image.Rect(0, 0, bounds.Dx(), bounds.Dy()))
+	draw.Draw(rgba, rgba.Bounds(), src, bounds.Min, draw.Src)
+
+	srcWidth, srcHeight := bounds.Dx(), bounds.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := y * srcHeight / height
+		y1 := max((y+1)*srcHeight/height, y0+1)
+		for x := 0; x < width; x++ {
+			x0 := x * srcWidth / width
+			x1 := max((x+1)*srcWidth/width, x0+1)
+
+			var sum [4]int
+			for sy := y0; sy < y1; sy++ {
+				row := rgba.Pix[sy*rgba.Stride+x0*4 : sy*rgba.Stride+x1*4]
+				for i := 0; i < len(row); i += 4 {
+					sum[0] += int(row[i])
+					sum[1] += int(row[i+1])
+					sum[2] += int(row[i+2])
+					sum[3] += int(row[i+3])
+				}
+			}
+
+			n := (y1 - y0) * (x1 - x0)
+			offset := y*dst.Stride + x*4
+			for c := range sum {
+				dst.Pix[offset+c] = uint8((sum[c] + n/2) / n)
+			}
+		}
+	}
+	return dst
+}
@@ -0,0 +1,116 @@
+package attachments
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// Inline images get synthetic attachment IDs far above real XenForo IDs so
+// they flow through the regular attachment links and uploads. IDs are
+// derived from the post ID, keeping file names stable across runs. 32-bit
+// builds start at 1<<30 instead, as high as an int allows.
+const (
+	inlineImageIDBase  = min(1<<40, math.MaxInt/2+1)
+	maxInlineImagesPer = 1000
+)
+
+// dataURIImagePattern matches [img] tags embedding a base64 data URI.
+var dataURIImagePattern = regexp.MustCompile(`(?is)\[img\]\s*data:(image/[a-z0-9.+-]+);base64,([^\[\]]*?)\s*\[/img\]`)
+
+// inlineImageExtensions maps supported data URI image types to file extensions.
+var inlineImageExtensions = map[string]string{
+	"image/png":  "png",
+	"image/jpeg": "jpg",
+	"image/jpg":  "jpg",
+	"image/gif":  "gif",
+	"image/webp": "webp",
+}
+
+// SaveInlineImages decodes base64 data URI images embedded in a post's [img]
+// tags, saves them as attachment files and replaces each tag with an [ATTACH]
+// code for the returned attachments. Images larger than maxSize bytes (0 = no
+// limit), with unsupported types or invalid data are replaced with a short
+// note instead of keeping the data URI, which GitHub rejects.
+func (d *Downloader) SaveInlineImages(postID int, message string, maxSize int64) (string, []xenforo.Attachment) {
+	var saved []xenforo.Attachment
+	index := 0
+
+	result := dataURIImagePattern.ReplaceAllStringFunc(message, func(match string) string {
+		parts := dataURIImagePattern.FindStringSubmatch(match)
+		index++
+		if index > maxInlineImagesPer {
+			logging.Warnf(context.Background(), "    ⚠ Post %d: too many inline images, omitting image %d", postID, index)
+			return "*[inline image omitted]*"
+		}
+
+		attachment, err := d.saveInlineImage(postID, index, strings.ToLower(parts[1]), parts[2], maxSize)
+		if err != nil {
+			logging.Warnf(context.Background(), "    ⚠ Post %d: omitting inline image %d: %v", postID, index, err)
+			return "*[inline image omitted]*"
+		}
+
+		saved = append(saved, attachment)
+		return fmt.Sprintf("[ATTACH=full]%d[/ATTACH]", attachment.AttachmentID)
+	})
+
+	return result, saved
+}
+
+func (d *Downloader) saveInlineImage(postID, index int, mimeType, encoded string, maxSize int64) (xenforo.Attachment, error) {
+	ext, ok := inlineImageExtensions[mimeType]
+	if !ok {
+		return xenforo.Attachment{}, fmt.Errorf("unsupported image type %s", mimeType)
+	}
+
+	encoded = strings.Join(strings.Fields(encoded), "")
+	if maxSize > 0 && int64(base64.StdEncoding.DecodedLen(len(encoded))) > maxSize+2 {
+		return xenforo.Attachment{}, fmt.Errorf("image exceeds the %d byte limit", maxSize)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return xenforo.Attachment{}, fmt.Errorf("invalid base64 data: %w", err)
+	}
+	if maxSize > 0 && int64(len(data)) > maxSize {
+		return xenforo.Attachment{}, fmt.Errorf("image exceeds the %d byte limit", maxSize)
+	}
+
+	id := int64(inlineImageIDBase) + int64(postID)*maxInlineImagesPer + int64(index)
+	if id > math.MaxInt {
+		return xenforo.Attachment{}, fmt.Errorf("post ID %d is too large for inline image IDs in a 32-bit build", postID)
+	}
+
+	attachment := xenforo.Attachment{
+		AttachmentID: int(id),
+		Filename:     fmt.Sprintf("inline_%d_%d.%s", postID, index, ext),
+	}
+
+	if d.dryRun {
+		logging.Infof(context.Background(), "    [DRY-RUN] Would save inline image: %s (%d bytes)", attachment.Filename, len(data))
+		return attachment, nil
+	}
+
+	filePath := d.LocalPath(attachment)
+	dir := filepath.Dir(filePath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return xenforo.Attachment{}, fmt.Errorf("failed to create directory %s: %w", dir, err)
+	}
+	if err := d.sanitizer.ValidatePath(filePath, dir); err != nil {
+		return xenforo.Attachment{}, fmt.Errorf("security violation: file path escapes directory")
+	}
+	if err := os.WriteFile(filePath, data, 0644); err != nil {
+		return xenforo.Attachment{}, fmt.Errorf("failed to save inline image: %w", err)
+	}
+
+	logging.Infof(context.Background(), "    ✓ Saved inline image: %s", filepath.Base(filePath))
+	return attachment, nil
+}
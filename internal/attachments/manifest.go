@@ -0,0 +1,126 @@
+package attachments
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+)
+
+// ManifestFileName is the file in the attachments directory recording the
+// size and checksum of every verified download.
+const ManifestFileName = "attachments_manifest.json"
+
+// ManifestEntry describes a verified attachment file.
+type ManifestEntry struct {
+	Path   string `json:"path"` // Relative to the attachments directory
+	Size   int64  `json:"size"`
+	SHA256 string `json:"sha256"`
+}
+
+// manifest holds the entries of the attachments manifest, keyed by
+// attachment ID. It is loaded on first use and saved after each batch of
+// downloads.
+type manifest struct {
+	mu      sync.Mutex
+	path    string
+	changed bool
+	entries map[int]ManifestEntry
+}
+
+func newManifest(attachmentsDir string) *manifest {
+	return &manifest{path: filepath.Join(attachmentsDir, ManifestFileName)}
+}
+
+// load reads the manifest file once. A missing file starts an empty manifest,
+// and so does an unreadable one, whose entries are recorded again as their
+// files are verified.
+func (m *manifest) load() {
+	if m.entries != nil {
+		return
+	}
+	m.entries = make(map[int]ManifestEntry)
+
+	data, err := os.ReadFile(m.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return
+	}
+	if err == nil {
+		err = json.Unmarshal(data, &m.entries)
+	}
+	if err != nil {
+		logging.Warnf(context.Background(), "    ⚠ Ignoring attachments manifest %s: %v", m.path, err)
+		m.entries = make(map[int]ManifestEntry)
+	}
+}
+
+// entry returns the recorded entry of an attachment.
+func (m *manifest) entry(attachmentID int) (ManifestEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load()
+	entry, ok := m.entries[attachmentID]
+	return entry, ok
+}
+
+// record stores the entry of a verified attachment.
+func (m *manifest) record(attachmentID int, entry ManifestEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.load()
+	if m.entries[attachmentID] != entry {
+		m.entries[attachmentID] = entry
+		m.changed = true
+	}
+}
+
+// save writes the manifest when entries changed since the last save, through
+// a temporary file so an interrupted write keeps the previous manifest.
+func (m *manifest) save() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.changed {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(m.entries, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode attachments manifest: %w", err)
+	}
+	tmpPath := m.path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return fmt.Errorf("failed to write attachments manifest: %w", err)
+	}
+	if err := os.Rename(tmpPath, m.path); err != nil {
+		return fmt.Errorf("failed to replace attachments manifest: %w", err)
+	}
+	m.changed = false
+	return nil
+}
+
+// fileChecksum returns the size and hex SHA-256 hash of a file's content.
+func fileChecksum(path string) (int64, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, "", err
+	}
+	defer func() { _ = file.Close() }()
+
+	hash := sha256.New()
+	size, err := io.Copy(hash, file)
+	if err != nil {
+		return 0, "", err
+	}
+	return size, hex.EncodeToString(hash.Sum(nil)), nil
+}
@@ -0,0 +1,137 @@
+package attachments
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// attachmentPolicy decides which attachments are downloaded by their size
+// and extension, and remembers the attachments it skipped for the summary.
+type attachmentPolicy struct {
+	maxSize int64           // Largest attachment downloaded, in bytes (0 = unlimited)
+	allowed map[string]bool // Extensions downloaded (empty = all)
+	blocked map[string]bool // Extensions never downloaded
+
+	mu      sync.Mutex
+	skipped map[int]string // Attachment ID -> reason
+}
+
+// SetPolicy skips attachments larger than maxSize bytes (0 = unlimited) and
+// attachments whose extension is blocked, or not allowed when allowed
+// extensions are given. Extensions match case-insensitively, with or without
+// a leading dot; attachments of unknown size are downloaded. Posts link
+// skipped attachments to their original URL with a note.
+func (d *Downloader) SetPolicy(maxSize int64, allowed, blocked []string) *Downloader {
+	d.policy = &attachmentPolicy{
+		maxSize: maxSize,
+		allowed: extensionSet(allowed),
+		blocked: extensionSet(blocked),
+		skipped: make(map[int]string),
+	}
+	return d
+}
+
+func extensionSet(extensions []string) map[string]bool {
+	set := make(map[string]bool, len(extensions))
+	for _, ext := range extensions {
+		if ext = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(ext), ".")); ext != "" {
+			set[ext] = true
+		}
+	}
+	return set
+}
+
+// SkipReason returns why the policy skips an attachment, e.g. "exe files are
+// blocked", or an empty string when the attachment is downloaded.
+func (d *Downloader) SkipReason(attachment xenforo.Attachment) string {
+	p := d.policy
+	if p == nil {
+		return ""
+	}
+
+	ext := d.getFileExtension(d.sanitizer.SanitizeFilename(attachment.Filename))
+	switch {
+	case p.blocked[ext]:
+		return fmt.Sprintf("%s files are blocked", ext)
+	case len(p.allowed) > 0 && !p.allowed[ext]:
+		return fmt.Sprintf("%s files are not allowed", ext)
+	case p.maxSize > 0 && attachment.FileSize > p.maxSize:
+		return fmt.Sprintf("larger than %s", FormatSize(p.maxSize))
+	}
+	return ""
+}
+
+// Allowed returns the attachments the policy does not skip.
+func (d *Downloader) Allowed(attachments []xenforo.Attachment) []xenforo.Attachment {
+	if d.policy == nil {
+		return attachments
+	}
+
+	var allowed []xenforo.Attachment
+	for _, attachment := range attachments {
+		if d.SkipReason(attachment) == "" {
+			allowed = append(allowed, attachment)
+		}
+	}
+	return allowed
+}
+
+// skip records an attachment skipped by the policy and logs it once.
+func (d *Downloader) skip(ctx context.Context, attachment xenforo.Attachment, reason string) {
+	d.policy.mu.Lock()
+	defer d.policy.mu.Unlock()
+
+	if _, ok := d.policy.skipped[attachment.AttachmentID]; ok {
+		return
+	}
+	d.policy.skipped[attachment.AttachmentID] = reason
+	logging.Infof(ctx, "    ⏭ Skipped %s: %s", attachment.Filename, reason)
+}
+
+// LogSkipped logs how many attachments the policy skipped, by reason.
+func (d *Downloader) LogSkipped(ctx context.Context) {
+	if d.policy == nil {
+		return
+	}
+
+	d.policy.mu.Lock()
+	defer d.policy.mu.Unlock()
+
+	if len(d.policy.skipped) == 0 {
+		return
+	}
+	counts := make(map[string]int)
+	for _, reason := range d.policy.skipped {
+		counts[reason]++
+	}
+	reasons := make([]string, 0, len(counts))
+	for reason := range counts {
+		reasons = append(reasons, reason)
+	}
+	sort.Strings(reasons)
+
+	logging.Warnf(ctx, "⚠ Skipped %d attachments by the attachment policy, linked to the forum instead:", len(d.policy.skipped))
+	for _, reason := range reasons {
+		logging.Warnf(ctx, "  - %d: %s", counts[reason], reason)
+	}
+}
+
+// FormatSize formats a size in bytes with a binary unit, e.g. "3.0 MiB".
+func FormatSize(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,78 @@
+package attachments
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// SetForumBaseURL enables rewriting of the forum's attachment short URLs
+// (baseURL/attachments/<name>.<id>/) to the migrated attachment links. An
+// empty URL disables the rewriting.
+func (d *Downloader) SetForumBaseURL(baseURL string) *Downloader {
+	d.shortLinkPattern = nil
+
+	parsed := strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	if parsed == "" {
+		return d
+	}
+	// Links may use either scheme regardless of the configured one
+	host := parsed
+	if i := strings.Index(host, "://"); i >= 0 {
+		host = host[i+3:]
+	}
+
+	d.shortLinkPattern = regexp.MustCompile(`(?i)(\]\()?https?://` + regexp.QuoteMeta(host) +
+		`/attachments/(?:[^\s/()\[\]<>"]*\.)?(\d+)/?(?:\?[^\s()\[\]<>"]*)?`)
+	return d
+}
+
+// replaceShortLinks rewrites attachment short URLs pointing to known
+// attachments. URLs used as Markdown link or image targets are replaced by the
+// migrated target; bare URLs become full Markdown links. Unknown attachments
+// are left untouched.
+func (d *Downloader) replaceShortLinks(message string, attachments []xenforo.Attachment, hostedURLs map[int]string) string {
+	if d.shortLinkPattern == nil || len(attachments) == 0 {
+		return message
+	}
+
+	known := make(map[int]xenforo.Attachment, len(attachments))
+	for _, attachment := range attachments {
+		known[attachment.AttachmentID] = attachment
+	}
+
+	return d.shortLinkPattern.ReplaceAllStringFunc(message, func(match string) string {
+		parts := d.shortLinkPattern.FindStringSubmatch(match)
+		id, err := strconv.Atoi(parts[2])
+		if err != nil {
+			return match
+		}
+		attachment, ok := known[id]
+		if !ok || (parts[1] != "" && d.SkipReason(attachment) != "") {
+			return match // Skipped attachments keep their forum link
+		}
+		if parts[1] != "" {
+			return parts[1] + d.linkTarget(attachment, hostedURLs[id])
+		}
+		return d.markdownLink(attachment, hostedURLs[id])
+	})
+}
+
+// LocalLink returns the relative local path posts link an attachment with
+// when it is not hosted.
+func (d *Downloader) LocalLink(attachment xenforo.Attachment) string {
+	return d.linkTarget(attachment, "")
+}
+
+// linkTarget returns the hosted URL of an attachment, or its relative local
+// path when it is not hosted.
+func (d *Downloader) linkTarget(attachment xenforo.Attachment, hostedURL string) string {
+	if hostedURL != "" {
+		return hostedURL
+	}
+	ext := d.getFileExtension(d.sanitizer.SanitizeFilename(attachment.Filename))
+	return fmt.Sprintf("./%s/%s", ext, d.localFilename(attachment))
+}
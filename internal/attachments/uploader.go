@@ -0,0 +1,278 @@
+package attachments
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// GitHubCommitter commits files to a GitHub repository branch.
+type GitHubCommitter interface {
+	CommitFiles(ctx context.Context, repo, branch, message string, files []github.TreeFile) (*github.CommitResult, error)
+}
+
+// LFSStore stores file contents in a repository's Git LFS storage.
+type LFSStore interface {
+	UploadLFSObjects(ctx context.Context, repo string, contents [][]byte) error
+}
+
+// Uploader pushes downloaded attachments to a GitHub repository so migrated
+// posts can link to hosted URLs instead of local files.
+type Uploader struct {
+	committer GitHubCommitter
+	sanitizer *FileSanitizer
+	repo      string
+	branch    string
+	basePath  string
+	batch     bool
+	images    *imageProcessor
+	lfs       LFSStore
+
+	filenameTemplate *template.Template // Names uploaded files (nil = default naming)
+
+	// Commits build on the branch head and move it without force, so
+	// concurrent commits to the branch would reject each other
+	commitMu sync.Mutex
+}
+
+// NewUploader creates an uploader committing to repo/branch under basePath.
+// With batch enabled all attachments of a thread go into a single commit,
+// otherwise every file gets its own commit.
+func NewUploader(committer GitHubCommitter, repo, branch, basePath string, batch bool) *Uploader {
+	return &Uploader{
+		committer: committer,
+		sanitizer: NewFileSanitizer(),
+		repo:      repo,
+		branch:    branch,
+		basePath:  basePath,
+		batch:     batch,
+	}
+}
+
+// SetLFS stores uploaded files in Git LFS: their content goes to the LFS
+// storage of the repository and the branch receives pointer files, with a
+// .gitattributes tracking the upload path. Posts link to the LFS media URLs.
+func (u *Uploader) SetLFS(store LFSStore) *Uploader {
+	u.lfs = store
+	return u
+}
+
+// SetFilenameTemplate sets the template naming uploaded attachments. It should
+// match the downloader's, so hosted files keep their local names.
+func (u *Uploader) SetFilenameTemplate(tmpl *template.Template) *Uploader {
+	u.filenameTemplate = tmpl
+	return u
+}
+
+// UploadThreadAttachments uploads the thread's downloaded attachments and
+// returns their hosted URLs keyed by attachment ID. Attachments whose local
+// file is missing are skipped. localPath resolves an attachment's file on disk.
+func (u *Uploader) UploadThreadAttachments(ctx context.Context, threadID int, attachments []xenforo.Attachment, localPath func(xenforo.Attachment) string) (map[int]string, error) {
+	urls := make(map[int]string, len(attachments))
+
+	var files []github.TreeFile
+	var ids []int
+	for _, attachment := range attachments {
+		content, err := os.ReadFile(localPath(attachment))
+		if err != nil {
+			logging.Warnf(ctx, "    ⚠ Skipping upload of %s: %v", attachment.Filename, err)
+			continue
+		}
+		if u.images != nil {
+			content = u.processImage(ctx, attachment, content)
+		}
+
+		ids = append(ids, attachment.AttachmentID)
+		files = append(files, github.TreeFile{Path: u.RepoPath(threadID, attachment), Content: content})
+	}
+
+	if len(files) == 0 {
+		return urls, nil
+	}
+
+	if u.batch {
+		message := fmt.Sprintf("Add %d attachments for thread %d", len(files), threadID)
+		result, err := u.commit(ctx, message, files)
+		if err != nil {
+			return urls, fmt.Errorf("failed to upload attachments for thread %d: %w", threadID, err)
+		}
+		for i, file := range files {
+			urls[ids[i]] = result.URLs[file.Path]
+		}
+		logging.Infof(ctx, "  ✓ Uploaded %d attachments in commit %s", len(files), result.SHA)
+		return urls, nil
+	}
+
+	for i, file := range files {
+		message := fmt.Sprintf("Add attachment %s for thread %d", path.Base(file.Path), threadID)
+		result, err := u.commit(ctx, message, []github.TreeFile{file})
+		if err != nil {
+			return urls, fmt.Errorf("failed to upload %s: %w", file.Path, err)
+		}
+		urls[ids[i]] = result.URLs[file.Path]
+	}
+	logging.Infof(ctx, "  ✓ Uploaded %d attachments", len(urls))
+
+	return urls, nil
+}
+
+// processImage returns the content to upload for an attachment, downsized
+// when it is an image wider than the configured width. Images that fail to
+// process are uploaded unchanged.
+func (u *Uploader) processImage(ctx context.Context, attachment xenforo.Attachment, content []byte) []byte {
+	resized, err := u.images.process(content)
+	if err != nil {
+		logging.Warnf(ctx, "    ⚠ Uploading %s unchanged: %v", attachment.Filename, err)
+		return content
+	}
+	if resized == nil {
+		return content
+	}
+
+	logging.Infof(ctx, "    ✓ Downsized %s from %dx%d to %dx%d (%s -> %s)", attachment.Filename,
+		resized.fromWidth, resized.fromHeight, resized.toWidth, resized.toHeight,
+		FormatSize(int64(len(content))), FormatSize(int64(len(resized.content))))
+	return resized.content
+}
+
+// RepoPath returns the repository path an attachment is uploaded to.
+func (u *Uploader) RepoPath(threadID int, attachment xenforo.Attachment) string {
+	filename := attachmentFilename(u.filenameTemplate, u.sanitizer, attachment)
+	return path.Join(u.basePath, fmt.Sprintf("thread-%d", threadID), filename)
+}
+
+// HostedURL returns the URL an attachment of the thread is served from once
+// uploaded.
+func (u *Uploader) HostedURL(threadID int, attachment xenforo.Attachment) string {
+	return u.fileURL(u.RepoPath(threadID, attachment))
+}
+
+// fileURL returns the URL an uploaded file is served from.
+func (u *Uploader) fileURL(repoPath string) string {
+	if u.lfs != nil {
+		return github.MediaFileURL(u.repo, u.branch, repoPath)
+	}
+	return github.RawFileURL(u.repo, u.branch, repoPath)
+}
+
+// UploadAvatar uploads an author avatar shared by all threads and returns its
+// hosted URL.
+func (u *Uploader) UploadAvatar(ctx context.Context, filename string, content []byte) (string, error) {
+	file := github.TreeFile{Path: path.Join(u.basePath, avatarsDir, filename), Content: content}
+	result, err := u.commit(ctx, "Add avatar "+filename, []github.TreeFile{file})
+	if err != nil {
+		return "", fmt.Errorf("failed to upload %s: %w", file.Path, err)
+	}
+	return result.URLs[file.Path], nil
+}
+
+// maxUploadAttempts bounds how often a file whose stored blob does not match
+// the local content is uploaded.
+const maxUploadAttempts = 3
+
+// commit commits files and verifies that GitHub stored each file unchanged by
+// comparing the returned blob SHA with the local Git blob hash. Mismatched
+// files (e.g., truncated uploads) are uploaded again. Files without a reported
+// blob SHA are not verified. With LFS, the committed files are the pointers
+// to their uploaded content. Commits of all workers are serialized.
+func (u *Uploader) commit(ctx context.Context, message string, files []github.TreeFile) (*github.CommitResult, error) {
+	if u.lfs != nil {
+		var err error
+		if files, err = u.lfsPointers(ctx, files); err != nil {
+			return nil, err
+		}
+	}
+
+	u.commitMu.Lock()
+	defer u.commitMu.Unlock()
+
+	result, err := u.committer.CommitFiles(ctx, u.repo, u.branch, message, files)
+	if err != nil {
+		return nil, err
+	}
+	u.lfsURLs(result, files)
+
+	for attempt := 1; ; attempt++ {
+		mismatched := mismatchedBlobs(files, result.BlobSHAs)
+		if len(mismatched) == 0 {
+			return result, nil
+		}
+		if attempt >= maxUploadAttempts {
+			return nil, fmt.Errorf("checksum mismatch for %s after %d uploads", mismatched[0].Path, attempt)
+		}
+
+		for _, file := range mismatched {
+			logging.Warnf(ctx, "    ⚠ Checksum mismatch for %s, uploading again", path.Base(file.Path))
+		}
+		retry, err := u.committer.CommitFiles(ctx, u.repo, u.branch, "Re-upload: "+message, mismatched)
+		if err != nil {
+			return nil, err
+		}
+
+		result.SHA = retry.SHA
+		for _, file := range mismatched {
+			result.URLs[file.Path] = retry.URLs[file.Path]
+			result.BlobSHAs[file.Path] = retry.BlobSHAs[file.Path]
+		}
+		u.lfsURLs(result, mismatched)
+		files = mismatched
+	}
+}
+
+// lfsAttributesPath is the repository file marking the LFS-tracked paths.
+const lfsAttributesPath = ".gitattributes"
+
+// lfsPointers uploads the content of files to Git LFS and returns the pointer
+// files committed in their place, along with the .gitattributes tracking the
+// upload path. The unchanged .gitattributes adds nothing to later commits.
+func (u *Uploader) lfsPointers(ctx context.Context, files []github.TreeFile) ([]github.TreeFile, error) {
+	contents := make([][]byte, len(files))
+	for i, file := range files {
+		contents[i] = file.Content
+	}
+	if err := u.lfs.UploadLFSObjects(ctx, u.repo, contents); err != nil {
+		return nil, err
+	}
+
+	pointers := make([]github.TreeFile, 0, len(files)+1)
+	for _, file := range files {
+		pointers = append(pointers, github.TreeFile{Path: file.Path, Content: github.LFSPointer(file.Content)})
+	}
+	pattern := path.Join(u.basePath, "**")
+	if u.basePath == "" {
+		pattern = "**"
+	}
+	attributes := strings.ReplaceAll(pattern, " ", "[[:space:]]") + " filter=lfs diff=lfs merge=lfs -text\n"
+	return append(pointers, github.TreeFile{Path: lfsAttributesPath, Content: []byte(attributes)}), nil
+}
+
+// lfsURLs replaces the raw URLs of committed LFS pointers, which serve the
+// pointer itself, with the media URLs serving their content.
+func (u *Uploader) lfsURLs(result *github.CommitResult, files []github.TreeFile) {
+	if u.lfs == nil {
+		return
+	}
+	for _, file := range files {
+		result.URLs[file.Path] = u.fileURL(file.Path)
+	}
+}
+
+// mismatchedBlobs returns the files whose stored blob SHA differs from the
+// hash of their content.
+func mismatchedBlobs(files []github.TreeFile, blobSHAs map[string]string) []github.TreeFile {
+	var mismatched []github.TreeFile
+	for _, file := range files {
+		if stored := blobSHAs[file.Path]; stored != "" && stored != github.BlobSHA(file.Content) {
+			mismatched = append(mismatched, file)
+		}
+	}
+	return mismatched
+}
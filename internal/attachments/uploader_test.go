@@ -0,0 +1,308 @@
+package attachments
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/testutil"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+type mockCommitter struct {
+	commits [][]github.TreeFile
+	corrupt map[string]int // Uploads of a path that are stored truncated
+	delay   time.Duration
+	probe   testutil.ConcurrencyProbe
+}
+
+func (m *mockCommitter) CommitFiles(ctx context.Context, repo, branch, message string, files []github.TreeFile) (*github.CommitResult, error) {
+	defer m.probe.Enter()()
+	time.Sleep(m.delay)
+	m.commits = append(m.commits, files)
+	result := &github.CommitResult{SHA: "sha", URLs: make(map[string]string), BlobSHAs: make(map[string]string)}
+	for _, file := range files {
+		result.URLs[file.Path] = github.RawFileURL(repo, branch, file.Path)
+		stored := file.Content
+		if m.corrupt[file.Path] > 0 {
+			m.corrupt[file.Path]--
+			stored = stored[:len(stored)/2]
+		}
+		result.BlobSHAs[file.Path] = github.BlobSHA(stored)
+	}
+	return result, nil
+}
+
+func TestUploaderBatching(t *testing.T) {
+	tempDir := t.TempDir()
+	downloader := NewDownloader(tempDir, false, &mockXenForoClient{}, 0)
+
+	attachments := []xenforo.Attachment{
+		{AttachmentID: 1, Filename: "image.png", DirectURL: "https://example.com/1"},
+		{AttachmentID: 2, Filename: "document.pdf", DirectURL: "https://example.com/2"},
+		{AttachmentID: 3, Filename: "missing.txt", DirectURL: "https://example.com/3"},
+	}
+	for _, attachment := range attachments[:2] {
+		path := downloader.LocalPath(attachment)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name    string
+		batch   bool
+		commits int
+	}{
+		{name: "Batched into a single commit", batch: true, commits: 1},
+		{name: "One commit per file", batch: false, commits: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			committer := &mockCommitter{}
+			uploader := NewUploader(committer, "owner/repo", "forum-assets", "attachments", tt.batch)
+
+			urls, err := uploader.UploadThreadAttachments(context.Background(), 7, attachments, downloader.LocalPath)
+			if err != nil {
+				t.Fatalf("UploadThreadAttachments returned error: %v", err)
+			}
+
+			if len(committer.commits) != tt.commits {
+				t.Errorf("Expected %d commits, got %d", tt.commits, len(committer.commits))
+			}
+
+			expected := map[int]string{
+				1: "https://raw.githubusercontent.com/owner/repo/forum-assets/attachments/thread-7/attachment_1_image.png",
+				2: "https://raw.githubusercontent.com/owner/repo/forum-assets/attachments/thread-7/attachment_2_document.pdf",
+			}
+			if len(urls) != len(expected) {
+				t.Errorf("Expected %d URLs, got %d", len(expected), len(urls))
+			}
+			for id, url := range expected {
+				if urls[id] != url {
+					t.Errorf("Expected URL %q for attachment %d, got %q", url, id, urls[id])
+				}
+			}
+
+			message := downloader.ReplaceAttachmentLinksWithURLs("[ATTACH=full]1[/ATTACH]", attachments, urls)
+			if message != "![image.png]("+expected[1]+")" {
+				t.Errorf("Expected hosted image link, got %q", message)
+			}
+		})
+	}
+}
+
+func TestUploaderVerifiesChecksums(t *testing.T) {
+	tempDir := t.TempDir()
+	downloader := NewDownloader(tempDir, false, &mockXenForoClient{}, 0)
+
+	attachment := xenforo.Attachment{AttachmentID: 1, Filename: "image.png", DirectURL: "https://example.com/1"}
+	localPath := downloader.LocalPath(attachment)
+	if err := os.MkdirAll(filepath.Dir(localPath), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(localPath, []byte("image data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	repoPath := "attachments/thread-7/attachment_1_image.png"
+
+	tests := []struct {
+		name     string
+		corrupt  int
+		commits  int
+		expected bool
+	}{
+		{name: "Matching checksum", corrupt: 0, commits: 1, expected: true},
+		{name: "Mismatch is uploaded again", corrupt: 1, commits: 2, expected: true},
+		{name: "Persistent mismatch fails", corrupt: maxUploadAttempts, commits: maxUploadAttempts, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			committer := &mockCommitter{corrupt: map[string]int{repoPath: tt.corrupt}}
+			uploader := NewUploader(committer, "owner/repo", "forum-assets", "attachments", true)
+
+			urls, err := uploader.UploadThreadAttachments(context.Background(), 7, []xenforo.Attachment{attachment}, downloader.LocalPath)
+			if tt.expected && (err != nil || urls[1] == "") {
+				t.Errorf("Expected a verified upload, got URLs %v and error %v", urls, err)
+			}
+			if !tt.expected && (err == nil || urls[1] != "") {
+				t.Errorf("Expected the upload to fail verification, got URLs %v and error %v", urls, err)
+			}
+			if len(committer.commits) != tt.commits {
+				t.Errorf("Expected %d commits, got %d", tt.commits, len(committer.commits))
+			}
+		})
+	}
+}
+
+func TestUploaderDownsizesImages(t *testing.T) {
+	gradient := image.NewRGBA(image.Rect(0, 0, 400, 200))
+	for y := 0; y < 200; y++ {
+		for x := 0; x < 400; x++ {
+			gradient.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: uint8(x ^ y), A: 255})
+		}
+	}
+	var pngData, jpegData bytes.Buffer
+	if err := png.Encode(&pngData, gradient); err != nil {
+		t.Fatal(err)
+	}
+	if err := jpeg.Encode(&jpegData, gradient, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		filename string
+		content  []byte
+		maxWidth int
+		width    int // Expected uploaded width, 0 for unchanged content
+	}{
+		{name: "Wide PNG is downsized", filename: "screenshot.png", content: pngData.Bytes(), maxWidth: 100, width: 100},
+		{name: "Wide JPEG is downsized", filename: "photo.jpg", content: jpegData.Bytes(), maxWidth: 100, width: 100},
+		{name: "Narrow image is unchanged", filename: "screenshot.png", content: pngData.Bytes(), maxWidth: 400},
+		{name: "Other files are unchanged", filename: "document.pdf", content: []byte("%PDF-1.4"), maxWidth: 100},
+		{name: "Processing disabled", filename: "screenshot.png", content: pngData.Bytes(), maxWidth: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			localPath := filepath.Join(t.TempDir(), tt.filename)
+			if err := os.WriteFile(localPath, tt.content, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			committer := &mockCommitter{}
+			uploader := NewUploader(committer, "owner/repo", "forum-assets", "attachments", true).
+				SetImageProcessing(tt.maxWidth, 85)
+			attachment := xenforo.Attachment{AttachmentID: 1, Filename: tt.filename}
+			if _, err := uploader.UploadThreadAttachments(context.Background(), 7, []xenforo.Attachment{attachment}, func(xenforo.Attachment) string {
+				return localPath
+			}); err != nil {
+				t.Fatalf("UploadThreadAttachments returned error: %v", err)
+			}
+
+			uploaded := committer.commits[0][0].Content
+			if tt.width == 0 {
+				if !bytes.Equal(uploaded, tt.content) {
+					t.Errorf("Expected unchanged content, got %d bytes instead of %d", len(uploaded), len(tt.content))
+				}
+				return
+			}
+
+			config, _, err := image.DecodeConfig(bytes.NewReader(uploaded))
+			if err != nil {
+				t.Fatalf("Failed to decode uploaded image: %v", err)
+			}
+			if config.Width != tt.width || config.Height != tt.width/2 {
+				t.Errorf("Expected %dx%d image, got %dx%d", tt.width, tt.width/2, config.Width, config.Height)
+			}
+			if len(uploaded) >= len(tt.content) {
+				t.Errorf("Expected smaller content, got %d bytes from %d", len(uploaded), len(tt.content))
+			}
+			if local, _ := os.ReadFile(localPath); !bytes.Equal(local, tt.content) {
+				t.Error("Expected the local file to be unchanged")
+			}
+		})
+	}
+}
+
+type mockLFSStore struct {
+	contents [][]byte
+}
+
+func (m *mockLFSStore) UploadLFSObjects(ctx context.Context, repo string, contents [][]byte) error {
+	m.contents = append(m.contents, contents...)
+	return nil
+}
+
+func TestUploaderLFS(t *testing.T) {
+	localPath := filepath.Join(t.TempDir(), "image.png")
+	if err := os.WriteFile(localPath, []byte("image data"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	committer := &mockCommitter{}
+	store := &mockLFSStore{}
+	uploader := NewUploader(committer, "owner/repo", "forum-assets", "forum files", true).SetLFS(store)
+
+	attachment := xenforo.Attachment{AttachmentID: 1, Filename: "image.png"}
+	urls, err := uploader.UploadThreadAttachments(context.Background(), 7, []xenforo.Attachment{attachment}, func(xenforo.Attachment) string {
+		return localPath
+	})
+	if err != nil {
+		t.Fatalf("UploadThreadAttachments returned error: %v", err)
+	}
+
+	if len(store.contents) != 1 || string(store.contents[0]) != "image data" {
+		t.Errorf("Expected the file content in LFS, got %q", store.contents)
+	}
+
+	committed := make(map[string]string)
+	for _, file := range committer.commits[0] {
+		committed[file.Path] = string(file.Content)
+	}
+	repoPath := "forum files/thread-7/attachment_1_image.png"
+	if pointer := string(github.LFSPointer([]byte("image data"))); committed[repoPath] != pointer {
+		t.Errorf("Expected pointer %q to be committed, got %q", pointer, committed[repoPath])
+	}
+	if attributes := "forum[[:space:]]files/** filter=lfs diff=lfs merge=lfs -text\n"; committed[".gitattributes"] != attributes {
+		t.Errorf("Expected .gitattributes %q, got %q", attributes, committed[".gitattributes"])
+	}
+
+	expected := "https://media.githubusercontent.com/media/owner/repo/forum-assets/forum%20files/thread-7/attachment_1_image.png"
+	if urls[1] != expected {
+		t.Errorf("Expected URL %q, got %q", expected, urls[1])
+	}
+	if url := uploader.HostedURL(7, attachment); url != expected {
+		t.Errorf("Expected hosted URL %q, got %q", expected, url)
+	}
+}
+
+func TestUploaderSerializesCommits(t *testing.T) {
+	tempDir := t.TempDir()
+	downloader := NewDownloader(tempDir, false, &mockXenForoClient{}, 0)
+	committer := &mockCommitter{delay: 10 * time.Millisecond}
+	uploader := NewUploader(committer, "owner/repo", "forum-assets", "attachments", true)
+
+	var wg sync.WaitGroup
+	for threadID := 1; threadID <= 4; threadID++ {
+		attachment := xenforo.Attachment{AttachmentID: threadID, Filename: "image.png"}
+		path := downloader.LocalPath(attachment)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("data"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := uploader.UploadThreadAttachments(context.Background(), threadID, []xenforo.Attachment{attachment}, downloader.LocalPath); err != nil {
+				t.Errorf("UploadThreadAttachments returned error: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(committer.commits) != 4 {
+		t.Errorf("Expected 4 commits, got %d", len(committer.commits))
+	}
+	if committer.probe.Max() != 1 {
+		t.Errorf("Expected commits to the branch one at a time, got %d at once", committer.probe.Max())
+	}
+}
@@ -1,7 +1,11 @@
 package bbcode
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"regexp"
 	"strings"
 	"testing"
 	"time"
@@ -67,6 +71,175 @@ func TestBBCodeConverter(t *testing.T) {
 	}
 }
 
+func TestAlignmentTags(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		preserve bool
+		expected string
+	}{
+		{
+			name:     "Left alignment",
+			input:    "[left]Left text[/left]",
+			preserve: true,
+			expected: "<div align=\"left\">\n\nLeft text\n\n</div>",
+		},
+		{
+			name:     "Right alignment with formatting",
+			input:    "[RIGHT][b]Signature[/b][/RIGHT]",
+			preserve: true,
+			expected: "<div align=\"right\">\n\n**Signature**\n\n</div>",
+		},
+		{
+			name:     "Justify alignment across lines",
+			input:    "[justify]First line\nSecond line[/justify]",
+			preserve: true,
+			expected: "<div align=\"justify\">\n\nFirst line\nSecond line\n\n</div>",
+		},
+		{
+			name:     "Empty alignment block is removed",
+			input:    "[right] [/right]",
+			preserve: true,
+			expected: "",
+		},
+		{
+			name:     "Stripped when alignment is not preserved",
+			input:    "[left]Left[/left] [right]Right[/right] [justify]Justified[/justify]",
+			preserve: false,
+			expected: "Left Right Justified",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := NewConverter().SetPreserveAlignment(tt.preserve)
+			result := converter.ToMarkdown(tt.input)
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSignatureStripping(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		enabled  bool
+		pattern  string
+		expected string
+	}{
+		{
+			name:     "Standard delimiter",
+			input:    "Here is my answer.\nSecond line.\n-- \nJohn Doe\nhttps://example.com",
+			enabled:  true,
+			expected: "Here is my answer.\nSecond line.",
+		},
+		{
+			name:     "Last delimiter wins",
+			input:    "Body\n-- \nstill body\n-- \nSignature",
+			enabled:  true,
+			expected: "Body\n-- \nstill body",
+		},
+		{
+			name:     "Double dash inside text is kept",
+			input:    "Use the --verbose flag -- it helps.",
+			enabled:  true,
+			expected: "Use the --verbose flag -- it helps.",
+		},
+		{
+			name:     "Delimiter inside code block is kept",
+			input:    "[code]diff\n-- \nline[/code]",
+			enabled:  true,
+			expected: "```\ndiff\n-- \nline\n```",
+		},
+		{
+			name:     "Sig tag",
+			input:    "Body text [sig]My signature[/sig]",
+			enabled:  true,
+			expected: "Body text",
+		},
+		{
+			name:     "Custom pattern",
+			input:    "Body text\n~~~~~\nSent from my phone",
+			enabled:  true,
+			pattern:  `(?m)^~{3,}$`,
+			expected: "Body text",
+		},
+		{
+			name:     "Disabled keeps signature",
+			input:    "Body\n-- \nSignature",
+			enabled:  false,
+			expected: "Body\n-- \nSignature",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var pattern *regexp.Regexp
+			if tt.pattern != "" {
+				pattern = regexp.MustCompile(tt.pattern)
+			}
+			converter := NewConverter().SetSignatureStripping(tt.enabled, pattern)
+			result := strings.TrimSpace(converter.ToMarkdown(tt.input))
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestConversionBudget(t *testing.T) {
+	nested := strings.Repeat("[quote]", 50) + "[b]deep[/b]" + strings.Repeat("[/quote]", 50)
+
+	t.Run("Normal input stays within budget", func(t *testing.T) {
+		result, err := NewConverter().ToMarkdownContext(context.Background(), "[b]bold[/b]")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if result != "**bold**" {
+			t.Errorf("Expected %q, got %q", "**bold**", result)
+		}
+	})
+
+	t.Run("Deeply nested input exhausts the step budget", func(t *testing.T) {
+		converter := NewConverter().SetBudget(5, 0)
+
+		result, err := converter.ToMarkdownContext(context.Background(), nested)
+		if !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+		}
+		if result == "" {
+			t.Error("Expected a best-effort result")
+		}
+		if !strings.Contains(result, "> ") {
+			t.Errorf("Expected quotes processed before the budget ran out, got %q", result)
+		}
+	})
+
+	t.Run("Expired context stops conversion", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		result, err := NewConverter().ToMarkdownContext(ctx, nested)
+		if !errors.Is(err, ErrBudgetExceeded) {
+			t.Fatalf("Expected ErrBudgetExceeded, got %v", err)
+		}
+		if result != nested {
+			t.Errorf("Expected unconverted input as best-effort result, got %q", result)
+		}
+	})
+
+	t.Run("Processor reports budget warnings", func(t *testing.T) {
+		processor := NewMessageProcessor()
+		processor.converter.SetBudget(3, 0)
+
+		if _, err := processor.ProcessContentContext(context.Background(), nested); !errors.Is(err, ErrBudgetExceeded) {
+			t.Errorf("Expected ErrBudgetExceeded, got %v", err)
+		}
+	})
+}
+
 func TestMessageProcessor(t *testing.T) {
 	processor := NewMessageProcessor()
 
@@ -139,6 +312,204 @@ func TestAtMentionConversion(t *testing.T) {
 	}
 }
 
+func TestUserMentions(t *testing.T) {
+	logins := map[int]string{12: "octocat"}
+
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Mapped member",
+			input:    "Thanks [USER=12]@Alice[/USER]!",
+			expected: "Thanks @octocat!",
+		},
+		{
+			name:     "Unmapped member",
+			input:    "Thanks [USER=34]@Bob Smith[/USER]!",
+			expected: "Thanks **Bob Smith**!",
+		},
+		{
+			name:     "Quoted ID and plain mention",
+			input:    `[user="12"]Alice[/user] and @carol`,
+			expected: "@octocat and **carol**",
+		},
+	}
+
+	for _, legacy := range []bool{false, true} {
+		processor := NewMessageProcessor().SetUserMentions(logins).SetLegacyConverter(legacy)
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s (legacy: %t)", tt.name, legacy), func(t *testing.T) {
+				if result := processor.ProcessContent(tt.input); result != tt.expected {
+					t.Errorf("Expected %q, got %q", tt.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestLists(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Ordered list",
+			input:    "[list=1]\n[*]One\n[*]Two\n[/list]",
+			expected: "1. One\n2. Two\n",
+		},
+		{
+			name:     "Nested lists",
+			input:    "Before\n[list]\n[*]A\n[list=1]\n[*]A.1\n[*]A.2\n[/list]\n[*]B\n[/list]",
+			expected: "Before\n- A\n  1. A.1\n  2. A.2\n- B\n",
+		},
+		{
+			name:     "Item spanning lines",
+			input:    "[list]\n[*]Ünïcode first line\nsecond line\n[*]Next\n[/list]",
+			expected: "- Ünïcode first line\n  second line\n- Next\n",
+		},
+	}
+
+	for _, legacy := range []bool{false, true} {
+		converter := NewConverter().SetLegacy(legacy)
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s (legacy: %t)", tt.name, legacy), func(t *testing.T) {
+				if result := converter.ToMarkdown(tt.input); result != tt.expected {
+					t.Errorf("Expected %q, got %q", tt.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestCodeLanguages(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"Language option", "[code=javascript]let a = 1;[/code]", "\n```javascript\nlet a = 1;\n```\n"},
+		{"Upper-case quoted language", `[CODE="PHP"]echo 1;[/CODE]`, "\n```php\necho 1;\n```\n"},
+		{"Rich code block", "[code=rich]Plain[/code]", "\n```\nPlain\n```\n"},
+		{"Malformed language", "[code=a b]x[/code]", "\n```\nx\n```\n"},
+		{"PHP tag", "[php]<?php echo 1; ?>[/php]", "\n```php\n<?php echo 1; ?>\n```\n"},
+		{"HTML tag", "[HTML]<b>Hi</b>[/HTML]", "\n```html\n<b>Hi</b>\n```\n"},
+		{"SQL tag", "[sql]SELECT 1;[/sql]", "\n```sql\nSELECT 1;\n```\n"},
+	}
+
+	for _, legacy := range []bool{false, true} {
+		converter := NewConverter().SetLegacy(legacy)
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s (legacy: %t)", tt.name, legacy), func(t *testing.T) {
+				if result := converter.ToMarkdown(tt.input); result != tt.expected {
+					t.Errorf("Expected %q, got %q", tt.expected, result)
+				}
+			})
+		}
+	}
+}
+
+func TestHTMLCleanup(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		convert  bool
+		expected string
+	}{
+		{
+			name:     "Entities are decoded",
+			input:    "Tom &amp; Jerry say &quot;hi&quot;",
+			expected: "Tom & Jerry say \"hi\"",
+		},
+		{
+			name:     "Entities in code are decoded",
+			input:    "[code]a &amp;&amp; b &lt; c[/code]",
+			expected: "\n```\na && b < c\n```\n",
+		},
+		{
+			name:     "Scripts are stripped, also when encoded",
+			input:    "Hi<script>alert(1)</script> &lt;script&gt;alert(2)&lt;/script&gt;there",
+			expected: "Hi there",
+		},
+		{
+			name:     "Frames and event handlers are stripped",
+			input:    `<iframe src="https://evil.example"></iframe><img src="x.png" onerror="alert(1)"><a href="javascript:alert(1)">x</a>`,
+			expected: `<img src="x.png"><a>x</a>`,
+		},
+		{
+			name:     "HTML in code is kept",
+			input:    "[html]<script>go()</script>[/html]",
+			expected: "\n```html\n<script>go()</script>\n```\n",
+		},
+		{
+			name:     "Simple HTML is kept without conversion",
+			input:    "<b>Bold</b><br>line",
+			expected: "<b>Bold</b><br>line",
+		},
+		{
+			name:     "Simple HTML is converted",
+			input:    `<strong>Bold</strong> and <em>italic</em><br/>See <a href="https://example.com/?a=1&amp;b=2" title="x">this</a>`,
+			convert:  true,
+			expected: "**Bold** and *italic*\nSee [this](https://example.com/?a=1&b=2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			processor := NewMessageProcessor().SetHTMLConversion(tt.convert)
+			if result := processor.ProcessContent(tt.input); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestMentionModes(t *testing.T) {
+	logins := map[int]string{12: "octocat"}
+	input := "[USER=12]@Alice[/USER], [USER=34]@Bob[/USER] and @carol"
+
+	tests := []struct {
+		mode     MentionMode
+		expected string
+		header   string
+	}{
+		{MentionBold, "@octocat, **Bob** and **carol**", "**Alice** (@octocat)"},
+		{MentionEscape, "@\u200boctocat, @\u200bBob and @\u200bcarol", "**Alice** (@\u200boctocat)"},
+		{MentionGitHub, "@octocat, @Bob and @carol", "**Alice** (@octocat)"},
+		{MentionStrip, "octocat, Bob and carol", "**Alice** (octocat)"},
+	}
+
+	for _, legacy := range []bool{false, true} {
+		for _, tt := range tests {
+			t.Run(fmt.Sprintf("%s (legacy: %t)", tt.mode, legacy), func(t *testing.T) {
+				processor := NewMessageProcessor().
+					SetMentionMode(tt.mode).
+					SetUserMentions(logins).
+					SetHeaderStyle(HeaderNone).
+					SetLegacyConverter(legacy)
+				if result := processor.ProcessContent(input); result != tt.expected {
+					t.Errorf("Expected %q, got %q", tt.expected, result)
+				}
+
+				processor.SetHeaderStyle(HeaderFrontmatter)
+				formatted, err := processor.FormatMessageWithAuthor(Author{Username: "Alice", GitHubLogin: "octocat"}, 1700000000, 1, "Hi")
+				if err != nil {
+					t.Fatalf("FormatMessageWithAuthor failed: %v", err)
+				}
+				if !strings.Contains(formatted, tt.header) {
+					t.Errorf("Expected header %q in %q", tt.header, formatted)
+				}
+			})
+		}
+	}
+
+	if _, err := ParseMentionMode("loud"); err == nil {
+		t.Error("Expected an error for an unknown mention mode")
+	}
+}
+
 func TestFormatMessage(t *testing.T) {
 	processor := NewMessageProcessor()
 
@@ -294,3 +665,641 @@ func TestFormatMessage(t *testing.T) {
 		})
 	}
 }
+
+func TestFrontmatterLabels(t *testing.T) {
+	t.Run("Custom label set", func(t *testing.T) {
+		labels, err := LocaleLabels("de", map[string]string{"thread_id": "Forum-Thema"})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		processor := NewMessageProcessor().SetLabels(labels)
+		result, err := processor.FormatMessage("alice", 1673793000, 42, "Hallo")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+
+		expected := "---\nAutor: **alice**\nVerfasst: 2023-01-15 14:30:00 UTC\nForum-Thema: 42\n---\n\nHallo"
+		if result != expected {
+			t.Errorf("Expected %q, got %q", expected, result)
+		}
+		if marker := processor.ThreadMarker(42); marker != "Forum-Thema: 42" {
+			t.Errorf("Expected localized thread marker, got %q", marker)
+		}
+	})
+
+	t.Run("Defaults to English", func(t *testing.T) {
+		labels, err := LocaleLabels("", nil)
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if labels != DefaultLabels() || labels.Author != "Author" {
+			t.Errorf("Expected English labels, got %+v", labels)
+		}
+	})
+
+	invalid := []struct {
+		name      string
+		locale    string
+		overrides map[string]string
+	}{
+		{name: "Unknown locale", locale: "xx"},
+		{name: "Unknown label", locale: "en", overrides: map[string]string{"title": "Title"}},
+		{name: "Missing label", locale: "en", overrides: map[string]string{"posted": " "}},
+		{name: "Multi-line label", locale: "en", overrides: map[string]string{"author": "By\nname"}},
+	}
+	for _, tt := range invalid {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LocaleLabels(tt.locale, tt.overrides); err == nil {
+				t.Error("Expected an error")
+			}
+		})
+	}
+
+	t.Run("Incomplete label set is ignored", func(t *testing.T) {
+		processor := NewMessageProcessor().SetLabels(Labels{Author: "By"})
+		if marker := processor.ThreadMarker(7); marker != "Original Thread ID: 7" {
+			t.Errorf("Expected default thread marker, got %q", marker)
+		}
+	})
+}
+
+func TestHeaderStyles(t *testing.T) {
+	tests := []struct {
+		style    HeaderStyle
+		avatar   string
+		expected string
+	}{
+		{
+			style:    HeaderFrontmatter,
+			expected: "---\nAuthor: **JohnDoe**\nPosted: 2022-01-16 17:10:05 UTC\nOriginal Thread ID: 42\n---\n\nHello",
+		},
+		{
+			style:    HeaderByline,
+			expected: "*JohnDoe — 2022-01-16 17:10 UTC*\n\nHello",
+		},
+		{
+			style:    HeaderByline,
+			avatar:   "./avatars/avatar_1.png",
+			expected: `<img src="./avatars/avatar_1.png" alt="" width="20" height="20"> *JohnDoe — 2022-01-16 17:10 UTC*` + "\n\nHello",
+		},
+		{
+			style:    HeaderNone,
+			expected: "Hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.style), func(t *testing.T) {
+			result, err := NewMessageProcessor().SetHeaderStyle(tt.style).
+				FormatMessageWithAvatar("JohnDoe", tt.avatar, 1642353005, 42, "Hello\n")
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	for style, expected := range map[HeaderStyle]string{
+		HeaderFrontmatter: "---\nAuthor: **JohnDoe** (@octocat)\n",
+		HeaderByline:      "*JohnDoe (@octocat) — 2022-01-16 17:10 UTC*",
+	} {
+		result, err := NewMessageProcessor().SetHeaderStyle(style).
+			FormatMessageWithAuthor(Author{Username: "JohnDoe", GitHubLogin: "@octocat"}, 1642353005, 42, "Hello")
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if !strings.HasPrefix(result, expected) {
+			t.Errorf("Expected %s header to start with %q, got %q", style, expected, result)
+		}
+	}
+
+	if _, err := ParseHeaderStyle("banner"); err == nil {
+		t.Error("Expected an error for an unknown header style")
+	}
+	if style, err := ParseHeaderStyle(""); err != nil || style != HeaderFrontmatter {
+		t.Errorf("Expected frontmatter by default, got %q (%v)", style, err)
+	}
+}
+
+func TestMetadataFormats(t *testing.T) {
+	meta := PostMetadata{ThreadID: 42, PostID: 420, AuthorID: 7, Author: "John--Doe", PostDate: 1642353005}
+
+	tests := []struct {
+		format   MetadataFormat
+		expected string
+	}{
+		{
+			format:   MetadataHTMLComment,
+			expected: "Hello\n\n" + `<!-- xenforo-import {"thread_id":42,"post_id":420,"author_id":7,"author":"John-\u002dDoe","post_date":1642353005} -->`,
+		},
+		{
+			format:   MetadataFrontmatter,
+			expected: "---\nxenforo_thread_id: 42\nxenforo_post_id: 420\nxenforo_author_id: 7\nxenforo_author: \"John--Doe\"\nxenforo_posted_at: 2022-01-16T17:10:05Z\n---\n\nHello",
+		},
+		{
+			format:   MetadataNone,
+			expected: "Hello",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.format), func(t *testing.T) {
+			result := NewMessageProcessor().SetMetadataFormat(tt.format).WithMetadata(meta, "Hello")
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// The comment stays valid JSON after escaping "--"
+	result := NewMessageProcessor().SetMetadataFormat(MetadataHTMLComment).WithMetadata(meta, "Hello")
+	data := strings.TrimSuffix(strings.TrimPrefix(result, "Hello\n\n"+metadataCommentPrefix), " -->")
+	var decoded PostMetadata
+	if err := json.Unmarshal([]byte(data), &decoded); err != nil || decoded != meta {
+		t.Errorf("Expected the comment to decode to %+v, got %+v (%v)", meta, decoded, err)
+	}
+
+	if _, err := ParseMetadataFormat("yaml"); err == nil {
+		t.Error("Expected an error for an unknown metadata format")
+	}
+}
+
+func TestFormatTopReplyCallout(t *testing.T) {
+	processor := NewMessageProcessor()
+
+	tests := []struct {
+		name     string
+		username string
+		score    int
+		content  string
+		expected string
+	}{
+		{
+			name:     "Single line reply",
+			username: "helper",
+			score:    1,
+			content:  "Restart the server.",
+			expected: "> [!TIP]\n> **Top reply** by **helper** (1 reaction)\n>\n> Restart the server.\n",
+		},
+		{
+			name:     "Multi-line reply keeps blank lines quoted",
+			username: "helper",
+			score:    12,
+			content:  "First step.\n\nSecond step.",
+			expected: "> [!TIP]\n> **Top reply** by **helper** (12 reactions)\n>\n> First step.\n>\n> Second step.\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := processor.FormatTopReplyCallout(tt.username, tt.score, tt.content)
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestFormatThreadStats(t *testing.T) {
+	processor := NewMessageProcessor()
+	postDate := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC).Unix()
+
+	tests := []struct {
+		name     string
+		template string
+		replies  int
+		views    int
+		expected string
+	}{
+		{
+			name:     "Small counts",
+			template: "Originally posted {date} · {replies} replies · {views} views",
+			replies:  42,
+			views:    999,
+			expected: "Originally posted 2019-04-01 · 42 replies · 999 views",
+		},
+		{
+			name:     "Thousands are abbreviated",
+			template: "Originally posted {date} · {replies} replies · {views} views",
+			replies:  1000,
+			views:    1234,
+			expected: "Originally posted 2019-04-01 · 1k replies · 1.2k views",
+		},
+		{
+			name:     "Millions are abbreviated",
+			template: "{views} views",
+			views:    3_450_000,
+			expected: "3.5M views",
+		},
+		{
+			name:     "Rounding up to the next unit",
+			template: "{views} views",
+			views:    999_999,
+			expected: "1M views",
+		},
+		{
+			name:     "Custom template",
+			template: "_{replies} replies since {date}_",
+			replies:  7,
+			expected: "_7 replies since 2019-04-01_",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := processor.FormatThreadStats(tt.template, postDate, tt.replies, tt.views)
+			if result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestSmileyImages(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		smileys  map[string]string
+		expected string
+	}{
+		{
+			name:     "Default smiley path",
+			input:    "Thanks [img]/styles/default/xenforo/smilies/smile.png[/img]",
+			smileys:  DefaultSmileys(),
+			expected: "Thanks 🙂",
+		},
+		{
+			name:     "Absolute URL with query",
+			input:    "[IMG]https://forum.example.com/styles/default/xenforo/smilies/wink.png?v=2[/IMG]",
+			smileys:  DefaultSmileys(),
+			expected: "😉",
+		},
+		{
+			name:     "Custom shortcode",
+			input:    "Nice [img]styles/default/xenforo/smilies/smile.png[/img]",
+			smileys:  map[string]string{"smilies/smile.png": ":)"},
+			expected: "Nice :)",
+		},
+		{
+			name:     "Unknown image is kept",
+			input:    "[img]https://example.com/photo.png[/img]",
+			smileys:  DefaultSmileys(),
+			expected: "![](https://example.com/photo.png)",
+		},
+		{
+			name:     "Disabled",
+			input:    "[img]/styles/default/xenforo/smilies/smile.png[/img]",
+			expected: "![](/styles/default/xenforo/smilies/smile.png)",
+		},
+		{
+			name:     "Text codes",
+			input:    ":) Great work ;-) (really :D) 8-)",
+			smileys:  DefaultSmileys(),
+			expected: "🙂 Great work 😉 (really 😁) 😎",
+		},
+		{
+			name:     "Codes inside words, links and code are kept",
+			input:    "See http://example.com/:p and a:D [icode]:)[/icode]",
+			smileys:  DefaultSmileys(),
+			expected: "See http://example.com/:p and a:D `:)`",
+		},
+		{
+			name:     "Custom text code",
+			input:    "Party :party: time",
+			smileys:  map[string]string{":party:": "🎉"},
+			expected: "Party 🎉 time",
+		},
+		{
+			name:     "Smilie sprite by shortname",
+			input:    `Hi <img src="data:image/gif;base64,R0lGOD" class="smilie smilie--sprite smilie--sprite1" alt=":)" title="Smile    :)" data-shortname=":)" />`,
+			smileys:  DefaultSmileys(),
+			expected: "Hi 🙂",
+		},
+		{
+			name:     "Smilie image by URL",
+			input:    `<img src="https://forum.example.com/styles/default/xenforo/smilies/wink.png" class="smilie" alt=";)" />`,
+			smileys:  DefaultSmileys(),
+			expected: "😉",
+		},
+		{
+			name:     "Unknown smilie keeps its image",
+			input:    `<img src="https://forum.example.com/data/smilies/party.gif" class="smilie" alt=":party:" />`,
+			smileys:  DefaultSmileys(),
+			expected: "![:party:](https://forum.example.com/data/smilies/party.gif)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := NewConverter().SetSmileys(tt.smileys)
+			if result := converter.ToMarkdown(tt.input); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestVideoTags(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      string
+		thumbnails bool
+		expected   string
+	}{
+		{
+			name:     "YouTube shorthand",
+			input:    "[youtube]dQw4w9WgXcQ[/youtube]",
+			expected: "[YouTube video](https://www.youtube.com/watch?v=dQw4w9WgXcQ)",
+		},
+		{
+			name:     "YouTube shorthand with URL",
+			input:    "[YOUTUBE]https://youtu.be/dQw4w9WgXcQ[/YOUTUBE]",
+			expected: "[YouTube video](https://www.youtube.com/watch?v=dQw4w9WgXcQ)",
+		},
+		{
+			name:     "Media embed uses the same provider",
+			input:    "[media=youtube]dQw4w9WgXcQ[/media]",
+			expected: "[YouTube video](https://www.youtube.com/watch?v=dQw4w9WgXcQ)",
+		},
+		{
+			name:       "YouTube thumbnail",
+			input:      "[youtube]dQw4w9WgXcQ[/youtube]",
+			thumbnails: true,
+			expected:   "[![YouTube video](https://img.youtube.com/vi/dQw4w9WgXcQ/hqdefault.jpg)](https://www.youtube.com/watch?v=dQw4w9WgXcQ)",
+		},
+		{
+			name:     "Video tag with provider URL",
+			input:    "[video]https://vimeo.com/76979871[/video]",
+			expected: "[Vimeo video](https://vimeo.com/76979871)",
+		},
+		{
+			name:     "Generic video URL",
+			input:    "[video]https://example.com/clips/demo.mp4[/video]",
+			expected: "[Video](https://example.com/clips/demo.mp4)",
+		},
+		{
+			name:     "Unknown media provider",
+			input:    "[media=imgur]abc123[/media]",
+			expected: "[imgur](abc123)",
+		},
+		{
+			name:     "Tweet",
+			input:    "[media=twitter]1234567890[/media]",
+			expected: "[Tweet](https://twitter.com/i/status/1234567890)",
+		},
+		{
+			name:     "Custom media site",
+			input:    "[MEDIA=Twitch]987654[/MEDIA]",
+			expected: "[twitch.tv media](https://www.twitch.tv/videos/987654)",
+		},
+		{
+			name:     "Custom media site overrides a built-in one",
+			input:    "[media=dailymotion]x8abc12[/media]",
+			expected: "[dm.example media](https://dm.example/v/x8abc12?autoplay=0)",
+		},
+	}
+
+	sites := map[string]string{
+		"twitch":      "https://www.twitch.tv/videos/{id}",
+		"dailymotion": "https://dm.example/v/{id}?autoplay=0",
+		"broken":      "https://example.com/no-placeholder",
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := NewConverter().SetVideoThumbnails(tt.thumbnails).SetMediaSites(sites)
+			if result := converter.ToMarkdown(tt.input); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestValidateMediaSites(t *testing.T) {
+	if err := ValidateMediaSites(map[string]string{"twitch": "https://www.twitch.tv/videos/{id}"}); err != nil {
+		t.Errorf("Expected a valid media site, got %v", err)
+	}
+	for _, siteURL := range []string{"https://example.com/videos", "ftp://example.com/{id}", "/videos/{id}"} {
+		if err := ValidateMediaSites(map[string]string{"site": siteURL}); err == nil {
+			t.Errorf("Expected an error for %q", siteURL)
+		}
+	}
+}
+
+func TestQuoteMemberLinks(t *testing.T) {
+	tests := []struct {
+		name     string
+		baseURL  string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Member linked attribution",
+			baseURL:  "https://forum.example.com/",
+			input:    `[quote="User, post: 12, member: 45"]Hello[/quote]`,
+			expected: "> **[User](https://forum.example.com/members/45) said:**\n> Hello\n",
+		},
+		{
+			name:     "No base URL",
+			input:    `[quote="User, post: 12, member: 45"]Hello[/quote]`,
+			expected: "> **User said:**\n> Hello\n",
+		},
+		{
+			name:     "No member ID",
+			baseURL:  "https://forum.example.com",
+			input:    `[quote="User, post: 12"]Hello[/quote]`,
+			expected: "> **User said:**\n> Hello\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			converter := NewConverter().SetMemberBaseURL(tt.baseURL)
+			if result := converter.ToMarkdown(tt.input); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestAccordionTabs(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Two-tab accordion",
+			input:    "[accordion]\n[tab=Install]Run [b]make[/b][/tab]\n[tab=\"Usage\"]Start it[/tab]\n[/accordion]",
+			expected: "<details><summary>Install</summary>\n\nRun **make**\n\n</details>\n\n<details><summary>Usage</summary>\n\nStart it\n\n</details>",
+		},
+		{
+			name:     "Missing titles are numbered",
+			input:    "[TABS][tab]First[/tab][tab=]Second[/tab][/TABS]",
+			expected: "<details><summary>Tab 1</summary>\n\nFirst\n\n</details>\n\n<details><summary>Tab 2</summary>\n\nSecond\n\n</details>",
+		},
+		{
+			name:     "Nested tabs",
+			input:    "[accordion][tab=Outer]Intro\n[accordion][tab=Inner]Deep[/tab][/accordion][/tab][/accordion]",
+			expected: "<details><summary>Outer</summary>\n\nIntro\n\n<details><summary>Inner</summary>\n\nDeep\n\n</details>\n\n</details>",
+		},
+		{
+			name:     "Tab outside a group",
+			input:    "Before\n[tab]Hidden[/tab]",
+			expected: "Before\n\n<details><summary>Details</summary>\n\nHidden\n\n</details>",
+		},
+		{
+			name:     "Title is escaped",
+			input:    "[accordion][tab=<b>Tips & tricks</b>]Text[/tab][/accordion]",
+			expected: "<details><summary>&lt;b&gt;Tips &amp; tricks&lt;/b&gt;</summary>\n\nText\n\n</details>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Blocks keep their surrounding line breaks, like code blocks
+			if result := strings.TrimSpace(NewConverter().ToMarkdown(tt.input)); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func TestTables(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Header row",
+			input:    "[TABLE]\n[TR][TH]Name[/TH][TH]Role[/TH][/TR]\n[TR][TD][b]Alice[/b][/TD][TD]Admin[/TD][/TR]\n[/TABLE]",
+			expected: "| Name | Role |\n| --- | --- |\n| **Alice** | Admin |",
+		},
+		{
+			name:     "No header row",
+			input:    "[table=collapse][tr][td]a[/td][td]b[/td][/tr][/table]",
+			expected: "|  |  |\n| --- | --- |\n| a | b |",
+		},
+		{
+			name:     "Short rows are padded",
+			input:    "[table][tr][th]A[/th][th]B[/th][th]C[/th][/tr][tr][td]1[/td][/tr][/table]",
+			expected: "| A | B | C |\n| --- | --- | --- |\n| 1 |  |  |",
+		},
+		{
+			name:     "Multi-line cells and pipes",
+			input:    "[table][tr][td]Line one\nLine two[/td][td]a | b[/td][/tr][/table]",
+			expected: "|  |  |\n| --- | --- |\n| Line one<br>Line two | a \\| b |",
+		},
+		{
+			name:     "Links in cells",
+			input:    "[table][tr][td][url=https://example.com]Site[/url][/td][/tr][/table]",
+			expected: "|  |\n| --- |\n| [Site](https://example.com) |",
+		},
+		{
+			name:     "Table without cells is dropped",
+			input:    "Before\n[table][/table]\nAfter",
+			expected: "Before\n\nAfter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := strings.TrimSpace(NewConverter().ToMarkdown(tt.input)); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// Tables are separated from surrounding text by blank lines
+	result := NewConverter().ToMarkdown("Intro[table][tr][td]x[/td][/tr][/table]Outro")
+	if expected := "Intro\n\n|  |\n| --- |\n| x |\n\nOutro"; result != expected {
+		t.Errorf("Expected %q, got %q", expected, result)
+	}
+}
+
+func TestParserConversion(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{
+			name:     "Quote inside spoiler inside list",
+			input:    "[list]\n[*]Item with [spoiler][quote=\"Ann\"]Inner [b]bold[/b][/quote][/spoiler]\n[*]Second\n[/list]",
+			expected: "- Item with\n  <details><summary>Spoiler</summary>\n\n  > **Ann said:**\n  > Inner **bold**\n\n  </details>\n- Second\n",
+		},
+		{
+			name:     "Spoiler title",
+			input:    "[spoiler=\"Plot <twist>\"]Hidden[/spoiler]",
+			expected: "<details><summary>Plot &lt;twist&gt;</summary>\n\nHidden\n\n</details>",
+		},
+		{
+			name:     "Nested quotes",
+			input:    "[quote]A[quote]B[/quote]C[/quote]",
+			expected: "> A\n> > B\n> \n> C\n",
+		},
+		{
+			name:     "Nested and ordered lists",
+			input:    "[list=1][*]One[*]Two[list][*]Nested[/list][/list]",
+			expected: "1. One\n2. Two\n   - Nested\n",
+		},
+		{
+			name:     "Misnested tags close at the outer tag",
+			input:    "[b]x[i]y[/b]z[/i]",
+			expected: "**xy**z",
+		},
+		{
+			name:     "Unclosed and stray tags are dropped",
+			input:    "[b]unclosed [/i]text",
+			expected: "unclosed text",
+		},
+		{
+			name:     "Case-insensitive tags with quoted options",
+			input:    "[URL='https://example.com']Site[/URL]",
+			expected: "[Site](https://example.com)",
+		},
+		{
+			name:     "Unquoted quote attribution",
+			input:    "[quote=Bob]Hi[/quote]After",
+			expected: "> **Bob said:**\n> Hi\n\nAfter",
+		},
+		{
+			name:     "Code content is not converted",
+			input:    "[code=go]a [b]not bold[/b][/code]",
+			expected: "\n```go\na [b]not bold[/b]\n```\n",
+		},
+		{
+			name:     "Markdown links and unknown tags with quotes are kept",
+			input:    "See [note](https://x.y) and [foo=\"bar\"]x[/foo] and [foo]y[/foo]",
+			expected: "See [note](https://x.y) and [foo=\"bar\"]x and y",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := NewConverter().ToMarkdown(tt.input); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+
+	// The legacy pipeline is still available during the transition
+	if result := NewConverter().SetLegacy(true).ToMarkdown("[b]x[i]y[/b]z[/i]"); result != "**x*y**z*" {
+		t.Errorf("Expected the legacy conversion, got %q", result)
+	}
+
+	// Both pipelines title spoilers alike
+	for _, legacy := range []bool{false, true} {
+		for input, summary := range map[string]string{
+			"[spoiler=\"Plot <twist>\"]Hidden[/spoiler]": "<summary>Plot &lt;twist&gt;</summary>",
+			"[spoiler]Hidden[/spoiler]":                  "<summary>Spoiler</summary>",
+		} {
+			if result := NewConverter().SetLegacy(legacy).ToMarkdown(input); !strings.Contains(result, summary) {
+				t.Errorf("Expected %q from %q (legacy %v), got %q", summary, input, legacy, result)
+			}
+		}
+	}
+}
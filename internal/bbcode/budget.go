@@ -0,0 +1,44 @@
+package bbcode
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrBudgetExceeded indicates that a conversion ran out of its processing
+// budget and returned a best-effort result.
+var ErrBudgetExceeded = errors.New("conversion budget exceeded")
+
+const (
+	defaultMaxSteps   = 200             // Conversion stages and loop iterations per post
+	defaultTimeBudget = 5 * time.Second // Wall-clock budget per post
+)
+
+// budget tracks the work spent converting a single post.
+type budget struct {
+	ctx      context.Context
+	steps    int
+	maxSteps int
+	err      error
+}
+
+// spend consumes one step and reports whether processing may continue.
+// Once exhausted, the budget stays exhausted.
+func (b *budget) spend() bool {
+	if b.err != nil {
+		return false
+	}
+
+	b.steps++
+	if b.maxSteps > 0 && b.steps > b.maxSteps {
+		b.err = fmt.Errorf("%w: more than %d steps", ErrBudgetExceeded, b.maxSteps)
+		return false
+	}
+	if err := b.ctx.Err(); err != nil {
+		b.err = fmt.Errorf("%w: %v", ErrBudgetExceeded, err)
+		return false
+	}
+	return true
+}
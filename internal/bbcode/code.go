@@ -0,0 +1,54 @@
+package bbcode
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	// legacyCodePattern matches code blocks for the legacy pipeline: [code]
+	// with an optional language and the [php], [html] and [sql] shorthands.
+	legacyCodePattern = regexp.MustCompile(`(?is)\[(code|php|html|sql)(?:=([^\]]*))?\](.*?)\[/(?:code|php|html|sql)\]`)
+
+	// codeLanguagePattern accepts language names GitHub can highlight, e.g.
+	// "javascript", "c++", "c#" or "objective-c".
+	codeLanguagePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9+#._-]*$`)
+)
+
+// codeLanguageAliases map XenForo code languages to the names GitHub
+// highlights. "rich" marks a rich-text code block without a language.
+var codeLanguageAliases = map[string]string{
+	"rich":   "",
+	"markup": "html",
+}
+
+// codeLanguage returns the language of a code block from the option of
+// [code=language] or the name of a [php], [html] or [sql] tag. Unknown or
+// malformed languages give a plain block.
+func codeLanguage(tag, option string) string {
+	if tag != "code" {
+		return tag
+	}
+
+	language := strings.ToLower(strings.Trim(strings.TrimSpace(option), `"'`))
+	if alias, ok := codeLanguageAliases[language]; ok {
+		return alias
+	}
+	if !codeLanguagePattern.MatchString(language) {
+		return ""
+	}
+	return language
+}
+
+// codeBlock renders a fenced code block, tagged with its language for syntax
+// highlighting.
+func codeBlock(language, content string) string {
+	return "\n```" + language + "\n" + strings.TrimSpace(content) + "\n```\n"
+}
+
+func (c *Converter) processCodeBlocks(input string) string {
+	return legacyCodePattern.ReplaceAllStringFunc(input, func(match string) string {
+		parts := legacyCodePattern.FindStringSubmatch(match)
+		return codeBlock(codeLanguage(strings.ToLower(parts[1]), parts[2]), parts[3])
+	})
+}
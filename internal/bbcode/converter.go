@@ -4,8 +4,11 @@
 package bbcode
 
 import (
+	"context"
+	"html"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/dlclark/regexp2"
 )
@@ -13,12 +16,73 @@ import (
 // Converter converts BB-code formatted text to GitHub-flavored Markdown.
 // Supports XenForo-style BB-code including quotes, formatting, links,
 // images, spoilers, and media embeds.
-type Converter struct{}
+type Converter struct {
+	preserveAlignment bool                     // Convert [left]/[right]/[justify] to aligned HTML instead of stripping them
+	stripSignatures   bool                     // Remove trailing signature blocks before conversion
+	signaturePattern  *regexp.Regexp           // Optional custom signature start pattern
+	smileys           map[string]string        // Normalized smiley image path to emoji (empty = keep images)
+	smileyCodes       map[string]string        // Smilie text code to emoji (empty = keep codes)
+	smileyCodePattern *regexp.Regexp           // Matches any key of smileyCodes
+	videoThumbnails   bool                     // Render provider videos as thumbnail images linking to the video
+	mediaSites        map[string]mediaProvider // Custom [media] sites by lower-cased site ID, overriding the built-in ones
+	memberBaseURL     string                   // Forum web URL for linking quoted members (empty = plain attribution)
+	userMentions      map[int]string           // Forum user ID to GitHub login for [USER] mentions (unmapped = name only)
+	mentionMode       MentionMode              // How mentions are rendered (empty = bold)
+	maxSteps          int                      // Processing steps allowed per post (0 = unlimited)
+	timeBudget        time.Duration            // Wall-clock budget per post (0 = context deadline only)
+	legacy            bool                     // Convert with the regular-expression pipeline instead of the parser
+}
 
 // NewConverter creates a new BB-code to Markdown converter.
 // Returns a converter ready to process XenForo BB-code content.
 func NewConverter() *Converter {
-	return &Converter{}
+	return &Converter{
+		preserveAlignment: true,
+		maxSteps:          defaultMaxSteps,
+		timeBudget:        defaultTimeBudget,
+	}
+}
+
+// SetBudget limits the processing spent on a single post. maxSteps bounds the
+// number of conversion stages and loop iterations and timeout bounds the wall
+// clock time; zero disables the respective limit.
+func (c *Converter) SetBudget(maxSteps int, timeout time.Duration) *Converter {
+	c.maxSteps = maxSteps
+	c.timeBudget = timeout
+	return c
+}
+
+// SetPreserveAlignment controls whether [left], [right] and [justify] tags are
+// converted to aligned HTML blocks. When disabled they are stripped like
+// color and size styling.
+func (c *Converter) SetPreserveAlignment(preserve bool) *Converter {
+	c.preserveAlignment = preserve
+	return c
+}
+
+// SetMemberBaseURL links quote attributions that carry a member ID to the
+// member's profile at baseURL/members/<id>. An empty URL keeps plain bold
+// attribution.
+func (c *Converter) SetMemberBaseURL(baseURL string) *Converter {
+	c.memberBaseURL = strings.TrimRight(strings.TrimSpace(baseURL), "/")
+	return c
+}
+
+// SetSignatureStripping enables removal of trailing signatures. Signatures
+// start at a [sig] tag, at the last standard "-- " delimiter line, or at the
+// first match of pattern when it is non-nil.
+func (c *Converter) SetSignatureStripping(enabled bool, pattern *regexp.Regexp) *Converter {
+	c.stripSignatures = enabled
+	c.signaturePattern = pattern
+	return c
+}
+
+// SetLegacy switches between the BB-code parser (default) and the original
+// regular-expression pipeline, which is kept during the transition. The
+// parser handles nested and malformed tags deterministically.
+func (c *Converter) SetLegacy(legacy bool) *Converter {
+	c.legacy = legacy
+	return c
 }
 
 // ToMarkdown converts BB-code formatted text to GitHub-flavored Markdown.
@@ -31,67 +95,169 @@ func NewConverter() *Converter {
 //	markdown := converter.ToMarkdown("[b]Bold text[/b]")
 //	// Result: "**Bold text**"
 func (c *Converter) ToMarkdown(bbcode string) string {
+	result, _ := c.ToMarkdownContext(context.Background(), bbcode)
+	return result
+}
+
+// ToMarkdownContext converts BB-code like ToMarkdown within the converter's
+// processing budget and the context deadline. When the budget is exhausted
+// the remaining stages are skipped and the best-effort result is returned
+// together with an error wrapping ErrBudgetExceeded.
+func (c *Converter) ToMarkdownContext(ctx context.Context, bbcode string) (string, error) {
 	if strings.TrimSpace(bbcode) == "" {
-		return ""
+		return "", nil
+	}
+
+	if c.timeBudget > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeBudget)
+		defer cancel()
+	}
+	b := &budget{ctx: ctx, maxSteps: c.maxSteps}
+
+	stages := []func(string) string{
+		c.stripSignature,
+
+		// Parse into a syntax tree and render it; every nesting level spends a step
+		func(input string) string { return c.renderMarkdown(input, b) },
+	}
+	if c.legacy {
+		stages = c.legacyStages(b)
 	}
 
 	result := bbcode
+	for _, stage := range stages {
+		if !b.spend() {
+			break
+		}
+		result = stage(result)
+	}
 
-	// First, handle multi-line code blocks
-	result = c.processCodeBlocks(result)
+	// Final cleanup always runs so partial results stay tidy
+	return c.finalCleanup(result), b.err
+}
 
-	// Handle quotes with attribution
-	result = c.processQuotes(result)
+// legacyStages returns the stages of the regular-expression pipeline. Quotes
+// and tabs spend a step per pass.
+func (c *Converter) legacyStages(b *budget) []func(string) string {
+	return []func(string) string{
+		c.stripSignature,
 
-	// URLs with quotes first
-	result = regexp.MustCompile(`\[url="([^"]+)"\](.*?)\[/url\]`).ReplaceAllString(result, "[$2]($1)")
+		// First, handle multi-line code blocks
+		c.processCodeBlocks,
 
-	// Handle text formatting with empty tag removal
-	result = c.processFormattingTag(result, `\[b\](.*?)\[/b\]`, "**", "**")
-	result = c.processFormattingTag(result, `\[i\](.*?)\[/i\]`, "*", "*")
-	result = c.processFormattingTag(result, `\[u\](.*?)\[/u\]`, "<u>", "</u>")
-	result = c.processFormattingTag(result, `\[s\](.*?)\[/s\]`, "~~", "~~")
-	result = c.processFormattingTag(result, `\[strike\](.*?)\[/strike\]`, "~~", "~~")
+		// Handle quotes with attribution
+		func(input string) string { return c.processQuotes(input, b) },
 
-	// Apply simple replacements
-	result = c.applySimpleReplacements(result)
+		// Accordion and tab groups become collapsible sections
+		func(input string) string { return c.processTabs(input, b) },
 
-	// Clean up unhandled BB codes
-	result = c.cleanupUnhandledTags(result)
+		// URLs with quotes first
+		func(input string) string {
+			return regexp.MustCompile(`\[url="([^"]+)"\](.*?)\[/url\]`).ReplaceAllString(input, "[$2]($1)")
+		},
 
-	// Final cleanup
-	result = c.finalCleanup(result)
+		// Handle text formatting with empty tag removal
+		func(input string) string {
+			result := c.processFormattingTag(input, `\[b\](.*?)\[/b\]`, "**", "**")
+			result = c.processFormattingTag(result, `\[i\](.*?)\[/i\]`, "*", "*")
+			result = c.processFormattingTag(result, `\[u\](.*?)\[/u\]`, "<u>", "</u>")
+			result = c.processFormattingTag(result, `\[s\](.*?)\[/s\]`, "~~", "~~")
+			return c.processFormattingTag(result, `\[strike\](.*?)\[/strike\]`, "~~", "~~")
+		},
 
-	return result
+		// Member mentions, before the cleanup drops their tags
+		c.processUserMentions,
+
+		// Replace known smiley images before generic image handling
+		c.processSmileys,
+
+		// Media embeds and video shorthand tags
+		c.processMedia,
+
+		// Lists, nested ones indented under their item
+		func(input string) string { return c.processLists(input, b) },
+
+		// Spoilers, titled like the parser renders them
+		c.processSpoilers,
+
+		// Apply simple replacements
+		c.applySimpleReplacements,
+
+		// Handle left/right/justify alignment
+		c.processAlignment,
+
+		// Tables, once their cells hold converted Markdown
+		func(input string) string { return c.processTables(input, b) },
+
+		// Clean up unhandled BB codes
+		c.cleanupUnhandledTags,
+	}
 }
 
-func (c *Converter) processCodeBlocks(input string) string {
-	return regexp.MustCompile(`(?s)\[code\](.*?)\[/code\]`).ReplaceAllStringFunc(input, func(match string) string {
-		parts := regexp.MustCompile(`(?s)\[code\](.*?)\[/code\]`).FindStringSubmatch(match)
-		if len(parts) < 2 {
-			return match
+var (
+	sigTagPattern             = regexp.MustCompile(`(?is)\[sig\].*?(?:\[/sig\]|$)`)
+	signatureDelimiterPattern = regexp.MustCompile(`(?m)^-- \r?$`)
+	codeOpenPattern           = regexp.MustCompile(`(?i)\[(?:code|php|html|sql)(?:=[^\]]*)?\]`)
+	codeClosePattern          = regexp.MustCompile(`(?i)\[/(?:code|php|html|sql)\]`)
+)
+
+// stripSignature removes a trailing signature block. Delimiters inside code
+// blocks are ignored so quoted shell output or diffs survive.
+func (c *Converter) stripSignature(input string) string {
+	if !c.stripSignatures {
+		return input
+	}
+
+	result := sigTagPattern.ReplaceAllString(input, "")
+
+	cut := -1
+	for _, loc := range signatureDelimiterPattern.FindAllStringIndex(result, -1) {
+		if !insideCodeBlock(result[:loc[0]]) {
+			cut = loc[0]
 		}
-		content := parts[1]
-		return "\n```\n" + strings.TrimSpace(content) + "\n```\n"
-	})
+	}
+	if c.signaturePattern != nil {
+		for _, loc := range c.signaturePattern.FindAllStringIndex(result, -1) {
+			if !insideCodeBlock(result[:loc[0]]) {
+				if cut < 0 || loc[0] < cut {
+					cut = loc[0]
+				}
+				break
+			}
+		}
+	}
+
+	if cut >= 0 {
+		result = result[:cut]
+	}
+	if result == input {
+		return input
+	}
+	return strings.TrimRight(result, " \t\r\n")
 }
 
-func (c *Converter) processQuotes(input string) string {
+// insideCodeBlock reports whether text ends inside an unclosed code block.
+func insideCodeBlock(text string) bool {
+	return len(codeOpenPattern.FindAllStringIndex(text, -1)) > len(codeClosePattern.FindAllStringIndex(text, -1))
+}
+
+func (c *Converter) processQuotes(input string, b *budget) string {
 	// Process quotes iteratively to handle nested quotes
 	result := input
 	maxIterations := 10 // Prevent infinite loops
 
-	for i := 0; i < maxIterations; i++ {
+	for i := 0; i < maxIterations && b.spend(); i++ {
 		oldResult := result
 
 		// Handle quotes with attribution first
-		result = regexp.MustCompile(`(?s)\[quote="([^,"]+)(?:,[^\]]+)?"\](.*?)\[/quote\]`).ReplaceAllStringFunc(result, func(match string) string {
-			parts := regexp.MustCompile(`(?s)\[quote="([^,"]+)(?:,[^\]]+)?"\](.*?)\[/quote\]`).FindStringSubmatch(match)
-			if len(parts) < 3 {
+		result = attributedQuotePattern.ReplaceAllStringFunc(result, func(match string) string {
+			parts := attributedQuotePattern.FindStringSubmatch(match)
+			if len(parts) < 4 {
 				return match
 			}
-			author := parts[1]
-			content := parts[2]
+			author := c.quoteAuthor(parts[1], parts[2])
+			content := parts[3]
 			lines := strings.Split(strings.TrimSpace(content), "\n")
 			quoted := "> **" + author + " said:**\n"
 			for _, line := range lines {
@@ -124,6 +290,24 @@ func (c *Converter) processQuotes(input string) string {
 	return result
 }
 
+var (
+	attributedQuotePattern = regexp.MustCompile(`(?s)\[quote="([^,"]+)(,[^\]]+)?"\](.*?)\[/quote\]`)
+	quoteMemberPattern     = regexp.MustCompile(`(?i)\bmember:\s*(\d+)`)
+)
+
+// quoteAuthor renders the quoted author, linked to their profile when a
+// member base URL is configured and the attribution carries a member ID.
+func (c *Converter) quoteAuthor(author, params string) string {
+	if c.memberBaseURL == "" {
+		return author
+	}
+	member := quoteMemberPattern.FindStringSubmatch(params)
+	if member == nil {
+		return author
+	}
+	return "[" + author + "](" + c.memberBaseURL + "/members/" + member[1] + ")"
+}
+
 func (c *Converter) processFormattingTag(input, pattern, openTag, closeTag string) string {
 	re := regexp.MustCompile(pattern)
 	return re.ReplaceAllStringFunc(input, func(match string) string {
@@ -151,14 +335,10 @@ func (c *Converter) applySimpleReplacements(input string) string {
 		// Images
 		{regexp.MustCompile(`\[img\](.*?)\[/img\]`), "![]($1)"},
 
-		// Spoilers
-		{regexp.MustCompile(`(?s)\[spoiler(?:="[^"]*")?\](.*?)\[/spoiler\]`), "<details><summary>Spoiler</summary>\n\n$1\n\n</details>"},
+		// Inline spoilers
 		{regexp.MustCompile(`\[ispoiler\](.*?)\[/ispoiler\]`), "||$1||"},
 
-		// Media embeds
-		{regexp.MustCompile(`\[media=([^\]]+)\](.*?)\[/media\]`), "[$1]($2)"},
-
-		// Lists
+		// List items outside of lists
 		{regexp.MustCompile(`\[\*\]`), "- "},
 		{regexp.MustCompile(`\[list=1\]\n`), "\n"},
 		{regexp.MustCompile(`\[list\]\n`), "\n"},
@@ -181,12 +361,46 @@ func (c *Converter) applySimpleReplacements(input string) string {
 	return result
 }
 
+// spoilerPattern matches [spoiler] blocks and their optional quoted title.
+var spoilerPattern = regexp.MustCompile(`(?s)\[spoiler(?:="([^"]*)")?\](.*?)\[/spoiler\]`)
+
+func (c *Converter) processSpoilers(input string) string {
+	return spoilerPattern.ReplaceAllStringFunc(input, func(match string) string {
+		parts := spoilerPattern.FindStringSubmatch(match)
+		title := strings.TrimSpace(parts[1])
+		if title == "" {
+			title = "Spoiler"
+		}
+		return "<details><summary>" + html.EscapeString(title) + "</summary>\n\n" + parts[2] + "\n\n</details>"
+	})
+}
+
+// alignmentPattern matches [left], [right] and [justify] blocks.
+var alignmentPattern = regexp.MustCompile(`(?is)\[(left|right|justify)\](.*?)\[/(?:left|right|justify)\]`)
+
+func (c *Converter) processAlignment(input string) string {
+	if !c.preserveAlignment {
+		return input
+	}
+
+	return alignmentPattern.ReplaceAllStringFunc(input, func(match string) string {
+		parts := alignmentPattern.FindStringSubmatch(match)
+		content := strings.TrimSpace(parts[2])
+		if content == "" {
+			return ""
+		}
+		// Blank lines let GitHub render Markdown inside the HTML block.
+		return "<div align=\"" + strings.ToLower(parts[1]) + "\">\n\n" + content + "\n\n</div>"
+	})
+}
+
 func (c *Converter) cleanupUnhandledTags(input string) string {
 	cleanupPattern := regexp2.MustCompile(`\[/?[a-zA-Z][a-zA-Z0-9=_-]*\](?!\()`, 0)
 	result, _ := cleanupPattern.ReplaceFunc(input, func(m regexp2.Match) string {
 		match := m.String()
 		// Preserve ATTACH tags for later processing
-		if strings.HasPrefix(match, "[ATTACH") || match == "[/ATTACH]" {
+		upper := strings.ToUpper(match)
+		if strings.HasPrefix(upper, "[ATTACH") || upper == "[/ATTACH]" {
 			return match
 		}
 		return ""
@@ -61,7 +61,7 @@ func ExampleConverter_ToMarkdown_spoiler() {
 	bbcode := "[spoiler=\"Click to reveal\"]Hidden content here[/spoiler]"
 	markdown := converter.ToMarkdown(bbcode)
 	fmt.Println(markdown)
-	// Output: <details><summary>Spoiler</summary>
+	// Output: <details><summary>Click to reveal</summary>
 	//
 	// Hidden content here
 	//
@@ -0,0 +1,48 @@
+package bbcode
+
+import (
+	"fmt"
+	"strings"
+)
+
+// HeaderStyle selects how the per-post metadata is rendered above the content.
+type HeaderStyle string
+
+const (
+	// HeaderFrontmatter renders the YAML-style block with author, date and
+	// thread ID lines. It is the only style carrying the thread marker.
+	HeaderFrontmatter HeaderStyle = "frontmatter"
+	// HeaderByline renders a single italic "*author — date*" line.
+	HeaderByline HeaderStyle = "byline"
+	// HeaderNone renders the converted content only.
+	HeaderNone HeaderStyle = "none"
+)
+
+// ParseHeaderStyle parses a header style name, defaulting to frontmatter when
+// empty.
+func ParseHeaderStyle(name string) (HeaderStyle, error) {
+	switch style := HeaderStyle(strings.ToLower(strings.TrimSpace(name))); style {
+	case "":
+		return HeaderFrontmatter, nil
+	case HeaderFrontmatter, HeaderByline, HeaderNone:
+		return style, nil
+	default:
+		return "", fmt.Errorf("unknown header style %q (available: frontmatter, byline, none)", name)
+	}
+}
+
+// HasThreadMarker reports whether formatted messages include the thread marker.
+func (s HeaderStyle) HasThreadMarker() bool {
+	return s == HeaderFrontmatter
+}
+
+// SetHeaderStyle sets how post metadata is rendered. Unknown styles fall back
+// to frontmatter.
+func (p *MessageProcessor) SetHeaderStyle(style HeaderStyle) *MessageProcessor {
+	if parsed, err := ParseHeaderStyle(string(style)); err == nil {
+		p.headerStyle = parsed
+	} else {
+		p.headerStyle = HeaderFrontmatter
+	}
+	return p
+}
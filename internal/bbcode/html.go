@@ -0,0 +1,103 @@
+package bbcode
+
+import (
+	"html"
+	"regexp"
+	"strings"
+)
+
+var (
+	// verbatimBlockPattern matches the blocks whose content is shown as is.
+	// Their entities are decoded, but their HTML is left alone.
+	verbatimBlockPattern = regexp.MustCompile(`(?is)\[(?:code|icode|plain|php|html|sql)(?:=[^\]]*)?\].*?\[/(?:code|icode|plain|php|html|sql)\]`)
+
+	htmlTagPattern       = regexp.MustCompile(`<[^<>]*>`)
+	htmlAttributePattern = regexp.MustCompile(`\s+([A-Za-z][A-Za-z0-9:_-]*)\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+)`)
+
+	// Elements removed with their content, and tags removed on their own
+	dangerousElementPattern = regexp.MustCompile(`(?is)<(?:script|style)\b[^>]*>.*?</(?:script|style)\s*>`)
+	dangerousTagPattern     = regexp.MustCompile(`(?i)</?(?:script|style|iframe|frame|frameset|object|embed|applet|form|input|button|textarea|select|meta|link|base)\b[^>]*>`)
+
+	htmlBreakPattern  = regexp.MustCompile(`(?i)<br\s*/?>`)
+	htmlBoldPattern   = regexp.MustCompile(`(?is)<(?:b|strong)(?:\s[^>]*)?>(.*?)</(?:b|strong)\s*>`)
+	htmlItalicPattern = regexp.MustCompile(`(?is)<(?:i|em)(?:\s[^>]*)?>(.*?)</(?:i|em)\s*>`)
+	htmlLinkPattern   = regexp.MustCompile(`(?is)<a\s[^>]*?\bhref\s*=\s*("[^"]*"|'[^']*')[^>]*>(.*?)</a\s*>`)
+)
+
+// SetHTMLConversion controls whether simple HTML tags left in posts by the
+// editor (<br>, <b>/<strong>, <i>/<em> and <a href>) are converted to
+// Markdown like their BB-code counterparts.
+func (p *MessageProcessor) SetHTMLConversion(enabled bool) *MessageProcessor {
+	p.convertHTML = enabled
+	return p
+}
+
+// cleanHTML decodes the HTML entities of a post and strips dangerous HTML
+// (scripts, styles, embedded frames and forms, event handler attributes and
+// script URLs) before its BB-code is converted. Entities inside tags are
+// kept, as their attributes are parsed later. Code and plain blocks only get
+// their entities decoded.
+func cleanHTML(content string, convertTags bool) string {
+	if !strings.ContainsAny(content, "&<") {
+		return content
+	}
+
+	var out strings.Builder
+	last := 0
+	for _, loc := range verbatimBlockPattern.FindAllStringIndex(content, -1) {
+		out.WriteString(cleanHTMLText(content[last:loc[0]], convertTags))
+		out.WriteString(html.UnescapeString(content[loc[0]:loc[1]]))
+		last = loc[1]
+	}
+	out.WriteString(cleanHTMLText(content[last:], convertTags))
+	return out.String()
+}
+
+// cleanHTMLText cleans the HTML of text outside of verbatim blocks.
+func cleanHTMLText(text string, convertTags bool) string {
+	var decoded strings.Builder
+	last := 0
+	for _, loc := range htmlTagPattern.FindAllStringIndex(text, -1) {
+		decoded.WriteString(html.UnescapeString(text[last:loc[0]]))
+		decoded.WriteString(text[loc[0]:loc[1]])
+		last = loc[1]
+	}
+	decoded.WriteString(html.UnescapeString(text[last:]))
+
+	result := dangerousElementPattern.ReplaceAllString(decoded.String(), "")
+	result = dangerousTagPattern.ReplaceAllString(result, "")
+	result = htmlTagPattern.ReplaceAllStringFunc(result, sanitizeHTMLTag)
+
+	if !convertTags {
+		return result
+	}
+	result = htmlBreakPattern.ReplaceAllString(result, "\n")
+	result = htmlBoldPattern.ReplaceAllString(result, "[B]$1[/B]")
+	result = htmlItalicPattern.ReplaceAllString(result, "[I]$1[/I]")
+	return htmlLinkPattern.ReplaceAllStringFunc(result, func(match string) string {
+		parts := htmlLinkPattern.FindStringSubmatch(match)
+		href := html.UnescapeString(strings.Trim(parts[1], `"'`))
+		return "[URL=" + href + "]" + parts[2] + "[/URL]"
+	})
+}
+
+// sanitizeHTMLTag removes the event handler attributes of a tag and the link
+// attributes holding script URLs.
+func sanitizeHTMLTag(tag string) string {
+	return htmlAttributePattern.ReplaceAllStringFunc(tag, func(attribute string) string {
+		parts := htmlAttributePattern.FindStringSubmatch(attribute)
+		name := strings.ToLower(parts[1])
+		if strings.HasPrefix(name, "on") {
+			return ""
+		}
+		switch name {
+		case "href", "src", "action", "formaction", "xlink:href":
+			value := strings.ToLower(html.UnescapeString(strings.Trim(parts[2], `"'`)))
+			value = strings.Join(strings.Fields(value), "")
+			if strings.HasPrefix(value, "javascript:") || strings.HasPrefix(value, "vbscript:") {
+				return ""
+			}
+		}
+		return attribute
+	})
+}
@@ -0,0 +1,99 @@
+package bbcode
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// DefaultLocale is the locale of the frontmatter labels used by default.
+const DefaultLocale = "en"
+
+// Labels are the frontmatter labels written before every formatted post.
+type Labels struct {
+	Author   string // Label of the author line
+	Posted   string // Label of the post date line
+	ThreadID string // Label of the original thread ID line
+}
+
+// localeLabels holds the built-in label sets keyed by locale.
+var localeLabels = map[string]Labels{
+	"en": {Author: "Author", Posted: "Posted", ThreadID: "Original Thread ID"},
+	"de": {Author: "Autor", Posted: "Verfasst", ThreadID: "Ursprüngliche Themen-ID"},
+	"es": {Author: "Autor", Posted: "Publicado", ThreadID: "ID del tema original"},
+	"fr": {Author: "Auteur", Posted: "Publié", ThreadID: "ID du sujet d'origine"},
+	"it": {Author: "Autore", Posted: "Pubblicato", ThreadID: "ID discussione originale"},
+	"pl": {Author: "Autor", Posted: "Opublikowano", ThreadID: "ID oryginalnego wątku"},
+	"pt": {Author: "Autor", Posted: "Publicado", ThreadID: "ID do tópico original"},
+	"ru": {Author: "Автор", Posted: "Опубликовано", ThreadID: "ID исходной темы"},
+	"uk": {Author: "Автор", Posted: "Опубліковано", ThreadID: "ID початкової теми"},
+}
+
+// labelKeys names the labels that can be overridden individually.
+var labelKeys = map[string]func(*Labels) *string{
+	"author":    func(l *Labels) *string { return &l.Author },
+	"posted":    func(l *Labels) *string { return &l.Posted },
+	"thread_id": func(l *Labels) *string { return &l.ThreadID },
+}
+
+// DefaultLabels returns the English frontmatter labels.
+func DefaultLabels() Labels {
+	return localeLabels[DefaultLocale]
+}
+
+// Locales returns the locales with built-in labels, sorted.
+func Locales() []string {
+	locales := make([]string, 0, len(localeLabels))
+	for locale := range localeLabels {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// LocaleLabels returns the labels of a built-in locale ("en" when empty) with
+// the given overrides applied. Override keys are "author", "posted" and
+// "thread_id". The resulting set is validated.
+func LocaleLabels(locale string, overrides map[string]string) (Labels, error) {
+	locale = strings.ToLower(strings.TrimSpace(locale))
+	if locale == "" {
+		locale = DefaultLocale
+	}
+
+	labels, ok := localeLabels[locale]
+	if !ok {
+		return Labels{}, fmt.Errorf("unknown locale %q (available: %s)", locale, strings.Join(Locales(), ", "))
+	}
+
+	for key, value := range overrides {
+		field, ok := labelKeys[strings.ToLower(strings.TrimSpace(key))]
+		if !ok {
+			return Labels{}, fmt.Errorf("unknown label %q (available: author, posted, thread_id)", key)
+		}
+		*field(&labels) = strings.TrimSpace(value)
+	}
+
+	return labels, labels.Validate()
+}
+
+// Validate checks that every label is present and fits on a single line.
+func (l Labels) Validate() error {
+	for name, value := range map[string]string{"author": l.Author, "posted": l.Posted, "thread_id": l.ThreadID} {
+		if strings.TrimSpace(value) == "" {
+			return fmt.Errorf("label %q is missing", name)
+		}
+		if strings.ContainsAny(value, "\r\n") {
+			return fmt.Errorf("label %q must be a single line", name)
+		}
+	}
+	return nil
+}
+
+// SetLabels sets the frontmatter labels of formatted posts. Incomplete label
+// sets are ignored in favor of the current labels.
+func (p *MessageProcessor) SetLabels(labels Labels) *MessageProcessor {
+	if labels.Validate() == nil {
+		p.labels = labels
+	}
+	return p
+}
@@ -0,0 +1,73 @@
+package bbcode
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+// listPattern matches a [list] block without nested lists, for the legacy
+// pipeline.
+var listPattern = regexp2.MustCompile(`(?is)\[list(=[^\]]*)?\]((?:(?!\[/?list[=\]]).)*?)\[/list\]`, 0)
+
+// processLists converts [list] blocks into Markdown lists, from the innermost
+// out so nested lists are indented under their item. Any [list=...] option
+// numbers the items. Text before the first [*] is kept as a line of its own.
+func (c *Converter) processLists(input string, b *budget) string {
+	result := input
+	for b.spend() {
+		runes := []rune(result) // regexp2 indexes runes
+		converted, _ := listPattern.ReplaceFunc(result, func(m regexp2.Match) string {
+			list := renderList(m.GroupByNumber(1).Length > 0, m.GroupByNumber(2).String())
+			// Lists start on a line of their own
+			if m.Index > 0 && runes[m.Index-1] != '\n' {
+				list = "\n" + list
+			}
+			return list
+		}, -1, -1)
+		if converted == result {
+			break
+		}
+		result = converted
+	}
+	return result
+}
+
+// renderList renders the content of a [list] block split at its [*] items.
+func renderList(ordered bool, content string) string {
+	var out strings.Builder
+	items := strings.Split(content, "[*]")
+	if text := strings.TrimSpace(items[0]); text != "" {
+		out.WriteString(text + "\n")
+	}
+	for i, item := range items[1:] {
+		out.WriteString(listItem(listMarker(ordered, i+1), item))
+	}
+	return out.String()
+}
+
+// listMarker returns the marker of the number-th item of a list.
+func listMarker(ordered bool, number int) string {
+	if ordered {
+		return fmt.Sprintf("%d. ", number)
+	}
+	return "- "
+}
+
+// listItem renders a list item. Lines after the first are indented under the
+// marker, so they and nested lists stay part of the item.
+func listItem(marker, content string) string {
+	var out strings.Builder
+	for i, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		switch {
+		case i == 0:
+			out.WriteString(marker + line)
+		case line == "":
+			out.WriteString("\n")
+		default:
+			out.WriteString("\n" + strings.Repeat(" ", len(marker)) + line)
+		}
+	}
+	return out.String() + "\n"
+}
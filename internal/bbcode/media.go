@@ -0,0 +1,196 @@
+package bbcode
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// mediaProvider describes how to link media hosted by a [media] provider.
+type mediaProvider struct {
+	text         string // Link text
+	urlFormat    string // Media page URL with a %s placeholder for the ID
+	thumbnailFmt string // Optional thumbnail image URL with a %s placeholder for the ID
+	idPattern    *regexp.Regexp
+}
+
+var mediaProviders = map[string]mediaProvider{
+	"youtube": {
+		text:         "YouTube video",
+		urlFormat:    "https://www.youtube.com/watch?v=%s",
+		thumbnailFmt: "https://img.youtube.com/vi/%s/hqdefault.jpg",
+		idPattern:    regexp.MustCompile(`^[A-Za-z0-9_-]{6,}$`),
+	},
+	"vimeo": {
+		text:      "Vimeo video",
+		urlFormat: "https://vimeo.com/%s",
+		idPattern: regexp.MustCompile(`^[0-9]+$`),
+	},
+	"dailymotion": {
+		text:      "Dailymotion video",
+		urlFormat: "https://www.dailymotion.com/video/%s",
+		idPattern: regexp.MustCompile(`^[A-Za-z0-9]+$`),
+	},
+	"twitter": {
+		text:      "Tweet",
+		urlFormat: "https://twitter.com/i/status/%s",
+		idPattern: regexp.MustCompile(`^[0-9]+$`),
+	},
+}
+
+// mediaSiteIDPlaceholder marks the media ID in the URL of a custom media site.
+const mediaSiteIDPlaceholder = "{id}"
+
+// customMediaIDPattern accepts the IDs of custom media sites, which may hold
+// path segments but nothing that would break the Markdown link.
+var customMediaIDPattern = regexp.MustCompile(`^[A-Za-z0-9_.~:/=&?%+-]+$`)
+
+// videoURLPatterns extract the provider and video ID from full video URLs.
+var videoURLPatterns = []struct {
+	provider string
+	pattern  *regexp.Regexp
+}{
+	{"youtube", regexp.MustCompile(`(?i)^https?://(?:www\.|m\.)?youtube\.com/(?:watch\?(?:.*&)?v=|embed/|shorts/|v/)([A-Za-z0-9_-]+)`)},
+	{"youtube", regexp.MustCompile(`(?i)^https?://youtu\.be/([A-Za-z0-9_-]+)`)},
+	{"vimeo", regexp.MustCompile(`(?i)^https?://(?:www\.|player\.)?vimeo\.com/(?:video/)?([0-9]+)`)},
+	{"dailymotion", regexp.MustCompile(`(?i)^https?://(?:www\.)?dailymotion\.com/video/([A-Za-z0-9]+)`)},
+}
+
+var (
+	mediaTagPattern   = regexp.MustCompile(`(?is)\[media=([^\]]+)\](.*?)\[/media\]`)
+	youtubeTagPattern = regexp.MustCompile(`(?is)\[youtube\](.*?)\[/youtube\]`)
+	videoTagPattern   = regexp.MustCompile(`(?is)\[video\](.*?)\[/video\]`)
+)
+
+// SetVideoThumbnails controls whether videos from providers with thumbnails
+// (e.g., YouTube) render as a thumbnail image linking to the video instead of
+// a text link.
+func (c *Converter) SetVideoThumbnails(enabled bool) *Converter {
+	c.videoThumbnails = enabled
+	return c
+}
+
+// ValidateMediaSites checks the URLs of custom media sites, keyed by the
+// site ID of their [media=site] tags.
+func ValidateMediaSites(sites map[string]string) error {
+	for site, siteURL := range sites {
+		if _, err := customMediaProvider(siteURL); err != nil {
+			return fmt.Errorf("media site %q: %w", site, err)
+		}
+	}
+	return nil
+}
+
+// SetMediaSites adds media sites for [media=site]ID[/media] tags, keyed by
+// site ID, with the URL of an item holding an {id} placeholder, e.g.
+// "https://www.twitch.tv/videos/{id}". Custom sites override the built-in
+// ones; invalid URLs are skipped.
+func (c *Converter) SetMediaSites(sites map[string]string) *Converter {
+	c.mediaSites = make(map[string]mediaProvider, len(sites))
+	for site, siteURL := range sites {
+		if provider, err := customMediaProvider(siteURL); err == nil {
+			c.mediaSites[strings.ToLower(strings.TrimSpace(site))] = provider
+		}
+	}
+	return c
+}
+
+// customMediaProvider describes a custom media site, linked as
+// "host media".
+func customMediaProvider(siteURL string) (mediaProvider, error) {
+	if !strings.Contains(siteURL, mediaSiteIDPlaceholder) {
+		return mediaProvider{}, fmt.Errorf("URL %q has no %s placeholder", siteURL, mediaSiteIDPlaceholder)
+	}
+	parsed, err := url.Parse(strings.ReplaceAll(siteURL, mediaSiteIDPlaceholder, "id"))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return mediaProvider{}, fmt.Errorf("URL %q is not an http(s) URL", siteURL)
+	}
+
+	return mediaProvider{
+		text:      strings.TrimPrefix(parsed.Hostname(), "www.") + " media",
+		urlFormat: strings.ReplaceAll(strings.ReplaceAll(siteURL, "%", "%%"), mediaSiteIDPlaceholder, "%s"),
+		idPattern: customMediaIDPattern,
+	}, nil
+}
+
+// processMedia converts [media=provider]ID[/media], [youtube]ID[/youtube] and
+// [video]URL[/video] into links to the video.
+func (c *Converter) processMedia(input string) string {
+	result := mediaTagPattern.ReplaceAllStringFunc(input, func(match string) string {
+		parts := mediaTagPattern.FindStringSubmatch(match)
+		return c.mediaEmbed(parts[1], strings.TrimSpace(parts[2]))
+	})
+
+	result = youtubeTagPattern.ReplaceAllStringFunc(result, func(match string) string {
+		if link, ok := c.youtubeEmbed(strings.TrimSpace(youtubeTagPattern.FindStringSubmatch(match)[1])); ok {
+			return link
+		}
+		return match
+	})
+
+	return videoTagPattern.ReplaceAllStringFunc(result, func(match string) string {
+		if link, ok := c.videoEmbed(strings.TrimSpace(videoTagPattern.FindStringSubmatch(match)[1])); ok {
+			return link
+		}
+		return match
+	})
+}
+
+// mediaEmbed renders [media=provider]id[/media]. Unknown providers keep a
+// generic provider link.
+func (c *Converter) mediaEmbed(provider, id string) string {
+	if link, ok := c.mediaLink(strings.ToLower(strings.TrimSpace(provider)), id); ok {
+		return link
+	}
+	return "[" + provider + "](" + id + ")"
+}
+
+// youtubeEmbed renders [youtube] with a video ID or URL.
+func (c *Converter) youtubeEmbed(id string) (string, bool) {
+	if provider, videoID, ok := parseVideoURL(id); ok && provider == "youtube" {
+		id = videoID
+	}
+	return c.mediaLink("youtube", id)
+}
+
+// videoEmbed renders [video] with a provider or generic http(s) video URL.
+func (c *Converter) videoEmbed(videoURL string) (string, bool) {
+	if provider, id, ok := parseVideoURL(videoURL); ok {
+		if link, ok := c.mediaLink(provider, id); ok {
+			return link, true
+		}
+	}
+	if parsed, err := url.Parse(videoURL); err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https") {
+		return "[Video](" + videoURL + ")", true
+	}
+	return "", false
+}
+
+// mediaLink renders a link to the media for a known provider and valid ID.
+func (c *Converter) mediaLink(provider, id string) (string, bool) {
+	p, ok := c.mediaSites[provider]
+	if !ok {
+		p, ok = mediaProviders[provider]
+	}
+	if !ok || !p.idPattern.MatchString(id) {
+		return "", false
+	}
+
+	videoURL := fmt.Sprintf(p.urlFormat, id)
+	text := p.text
+	if c.videoThumbnails && p.thumbnailFmt != "" {
+		return fmt.Sprintf("[![%s](%s)](%s)", text, fmt.Sprintf(p.thumbnailFmt, id), videoURL), true
+	}
+	return "[" + text + "](" + videoURL + ")", true
+}
+
+// parseVideoURL extracts the provider and video ID from a known video URL.
+func parseVideoURL(videoURL string) (string, string, bool) {
+	for _, candidate := range videoURLPatterns {
+		if parts := candidate.pattern.FindStringSubmatch(videoURL); parts != nil {
+			return candidate.provider, parts[1], true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,101 @@
+package bbcode
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// MentionMode selects how @mentions in posts are rendered. Mentions notify the
+// mentioned GitHub account, so posts migrated with real mentions can notify
+// unrelated accounts that share a forum member's name.
+type MentionMode string
+
+const (
+	// MentionBold renders mentions as the bold name. Mentions of members
+	// mapped to a GitHub login stay mentions of that login.
+	MentionBold MentionMode = "bold"
+	// MentionEscape keeps the @ but follows it with a zero-width space, so no
+	// mention notifies anyone, mapped members included.
+	MentionEscape MentionMode = "escape"
+	// MentionGitHub keeps every mention a GitHub mention, notifying any
+	// account of that name.
+	MentionGitHub MentionMode = "github-mention"
+	// MentionStrip renders mentions as the plain name without the @.
+	MentionStrip MentionMode = "strip"
+)
+
+// ParseMentionMode parses a mention mode name, defaulting to bold when empty.
+func ParseMentionMode(name string) (MentionMode, error) {
+	switch mode := MentionMode(strings.ToLower(strings.TrimSpace(name))); mode {
+	case "":
+		return MentionBold, nil
+	case MentionBold, MentionEscape, MentionGitHub, MentionStrip:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown mention mode %q (available: bold, escape, github-mention, strip)", name)
+	}
+}
+
+// mention renders a mention of name, which is the GitHub login of a mapped
+// member when mapped is set.
+func (m MentionMode) mention(name string, mapped bool) string {
+	switch m {
+	case MentionEscape:
+		return "@\u200b" + name
+	case MentionGitHub:
+		return "@" + name
+	case MentionStrip:
+		return name
+	default:
+		if mapped {
+			return "@" + name
+		}
+		return "**" + name + "**"
+	}
+}
+
+// SetMentionMode sets how mentions are rendered. Unknown modes render bold.
+func (c *Converter) SetMentionMode(mode MentionMode) *Converter {
+	if parsed, err := ParseMentionMode(string(mode)); err == nil {
+		c.mentionMode = parsed
+	} else {
+		c.mentionMode = MentionBold
+	}
+	return c
+}
+
+// SetUserMentions renders [USER=id]@name[/USER] mentions of forum members
+// mapped to a GitHub login as a mention of that login. Mentions of unmapped
+// members are rendered as the name, in the style of the mention mode.
+func (c *Converter) SetUserMentions(logins map[int]string) *Converter {
+	c.userMentions = logins
+	return c
+}
+
+// legacyUserPattern matches member mentions for the legacy pipeline.
+var legacyUserPattern = regexp.MustCompile(`(?is)\[user=([^\]]*)\](.*?)\[/user\]`)
+
+func (c *Converter) processUserMentions(input string) string {
+	return legacyUserPattern.ReplaceAllStringFunc(input, func(match string) string {
+		parts := legacyUserPattern.FindStringSubmatch(match)
+		return c.userMention(parts[1], parts[2])
+	})
+}
+
+// userMention renders the mention of the member with the given ID option and
+// displayed name.
+func (c *Converter) userMention(option, name string) string {
+	if userID, err := strconv.Atoi(strings.Trim(option, `"' `)); err == nil {
+		if login := c.userMentions[userID]; login != "" {
+			return c.mentionMode.mention(login, true)
+		}
+	}
+
+	name = strings.TrimPrefix(strings.TrimSpace(name), "@")
+	if name == "" {
+		return ""
+	}
+	return c.mentionMode.mention(name, false)
+}
@@ -0,0 +1,89 @@
+package bbcode
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MetadataFormat selects how the machine-readable import metadata of a post
+// is embedded in the migrated body.
+type MetadataFormat string
+
+const (
+	// MetadataHTMLComment appends an invisible "<!-- xenforo-import {...} -->"
+	// comment with the metadata as JSON.
+	MetadataHTMLComment MetadataFormat = "html-comment"
+	// MetadataFrontmatter prepends a YAML frontmatter block of xenforo_* keys.
+	MetadataFrontmatter MetadataFormat = "frontmatter"
+	// MetadataNone embeds no metadata.
+	MetadataNone MetadataFormat = "none"
+)
+
+// metadataCommentPrefix starts every metadata comment, for tooling to find.
+const metadataCommentPrefix = "<!-- xenforo-import "
+
+// ParseMetadataFormat parses a metadata format name, defaulting to none when
+// empty.
+func ParseMetadataFormat(name string) (MetadataFormat, error) {
+	switch format := MetadataFormat(strings.ToLower(strings.TrimSpace(name))); format {
+	case "":
+		return MetadataNone, nil
+	case MetadataHTMLComment, MetadataFrontmatter, MetadataNone:
+		return format, nil
+	default:
+		return "", fmt.Errorf("unknown metadata format %q (available: html-comment, frontmatter, none)", name)
+	}
+}
+
+// PostMetadata identifies the original forum post of a migrated discussion or
+// comment. GitHub does not allow setting creation dates, so the original
+// timeline can only be reconstructed from this metadata.
+type PostMetadata struct {
+	ThreadID int    `json:"thread_id"`
+	PostID   int    `json:"post_id"`
+	AuthorID int    `json:"author_id"` // 0 for guests
+	Author   string `json:"author"`
+	PostDate int64  `json:"post_date"` // Unix timestamp
+}
+
+// SetMetadataFormat sets how import metadata is embedded. Unknown formats
+// embed none.
+func (p *MessageProcessor) SetMetadataFormat(format MetadataFormat) *MessageProcessor {
+	if parsed, err := ParseMetadataFormat(string(format)); err == nil {
+		p.metadataFormat = parsed
+	} else {
+		p.metadataFormat = MetadataNone
+	}
+	return p
+}
+
+// WithMetadata embeds the post's import metadata in a formatted body: as a
+// trailing HTML comment or a leading frontmatter block.
+func (p *MessageProcessor) WithMetadata(meta PostMetadata, body string) string {
+	switch p.metadataFormat {
+	case MetadataHTMLComment:
+		data, err := json.Marshal(meta)
+		if err != nil {
+			return body
+		}
+		// "--" must not appear inside an HTML comment
+		comment := strings.ReplaceAll(string(data), "--", `-\u002d`)
+		return body + "\n\n" + metadataCommentPrefix + comment + " -->"
+	case MetadataFrontmatter:
+		return fmt.Sprintf(`---
+xenforo_thread_id: %d
+xenforo_post_id: %d
+xenforo_author_id: %d
+xenforo_author: %s
+xenforo_posted_at: %s
+---
+
+%s`, meta.ThreadID, meta.PostID, meta.AuthorID, strconv.Quote(meta.Author),
+			time.Unix(meta.PostDate, 0).UTC().Format(time.RFC3339), body)
+	default:
+		return body
+	}
+}
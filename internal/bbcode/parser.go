@@ -0,0 +1,344 @@
+package bbcode
+
+import (
+	"regexp"
+	"strings"
+)
+
+// node is an element of the BB-code syntax tree: a text run, or a tag with
+// its children. Verbatim tags keep their unparsed content in text.
+type node struct {
+	tag      string // Lower-case tag name, empty for text
+	option   string // Unquoted value after '=', or the attribute list
+	text     string // Text of a text node or raw content of a verbatim tag
+	open     string // Source of the opening tag
+	close    string // Source of the closing tag
+	dropOpen bool   // Drop the opening tag when the tag is never closed
+	children []*node
+}
+
+// tagToken is a lexed opening or closing tag.
+type tagToken struct {
+	raw     string
+	name    string
+	option  string
+	closing bool
+}
+
+var (
+	// knownTags are the tags parsed into the syntax tree. Other tags are
+	// handled like the legacy cleanup: simple ones are dropped, the rest is
+	// kept as text.
+	knownTags = map[string]bool{
+		"b": true, "i": true, "u": true, "s": true, "strike": true,
+		"url": true, "img": true, "quote": true, "code": true, "icode": true, "plain": true,
+		"php": true, "html": true, "sql": true,
+		"spoiler": true, "ispoiler": true, "list": true, "*": true,
+		"center": true, "left": true, "right": true, "justify": true,
+		"color": true, "size": true, "font": true, "sig": true, "user": true, "indent": true, "email": true,
+		"table": true, "tr": true, "td": true, "th": true,
+		"accordion": true, "tabs": true, "tab": true, "slide": true,
+		"media": true, "youtube": true, "video": true, "attach": true,
+	}
+
+	// verbatimTags keep their content unparsed up to the matching closing tag.
+	verbatimTags = map[string]bool{
+		"code": true, "icode": true, "plain": true, "img": true, "php": true, "html": true, "sql": true,
+		"media": true, "youtube": true, "video": true, "attach": true,
+	}
+
+	// simpleTagPattern matches the tags the legacy cleanup removes.
+	simpleTagPattern = regexp.MustCompile(`^\[/?[a-zA-Z][a-zA-Z0-9=_-]*\]$`)
+
+	// anyTagPattern matches an opening or closing tag and captures its name.
+	anyTagPattern = regexp.MustCompile(`\[/?([a-zA-Z][a-zA-Z0-9]*|\*)(?:=[^\]]*)?\]`)
+)
+
+// StripTags removes the known BB-code tags from text, keeping their content,
+// e.g. for thread titles. Bracketed text that is no known tag, such as
+// "[Solved]", is kept.
+func StripTags(text string) string {
+	return anyTagPattern.ReplaceAllStringFunc(text, func(tag string) string {
+		if knownTags[strings.ToLower(anyTagPattern.FindStringSubmatch(tag)[1])] {
+			return ""
+		}
+		return tag
+	})
+}
+
+// parser builds the syntax tree of a post. Every nesting level reached for
+// the first time spends a budget step; once the budget is exhausted the rest
+// of the input is kept as text.
+type parser struct {
+	input  string
+	budget *budget
+	stack  []*node
+	depth  int
+}
+
+// parseBBCode parses BB-code into a syntax tree. Parsing never fails:
+// unclosed tags are dropped while keeping their content, closing tags close
+// any tags still open inside them, and stray closing tags are handled like
+// unknown tags.
+func parseBBCode(input string, b *budget) *node {
+	root := &node{}
+	p := &parser{input: input, budget: b, stack: []*node{root}}
+
+	exhausted := false
+	for i := 0; i < len(input); {
+		j := strings.IndexByte(input[i:], '[')
+		if j < 0 {
+			p.appendText(input[i:])
+			break
+		}
+		p.appendText(input[i : i+j])
+		i += j
+
+		tok, end, ok := lexTag(input, i)
+		if !ok {
+			p.appendText("[")
+			i++
+			continue
+		}
+
+		switch {
+		case tok.closing:
+			p.closeTag(tok, end)
+		case tok.name == "*":
+			if !p.listItem(tok) {
+				p.appendText("- ")
+			} else if !p.push(&node{tag: "*", open: tok.raw}) {
+				exhausted = true
+			}
+		case !knownTags[tok.name]:
+			p.unknownTag(tok, end)
+		case verbatimTags[tok.name] || (tok.name == "url" && tok.option == ""):
+			closeTag := "[/" + tok.name + "]"
+			k := indexFold(input[end:], closeTag)
+			if k < 0 {
+				p.unknownTag(tok, end)
+				break
+			}
+			p.top().children = append(p.top().children, &node{
+				tag:    tok.name,
+				option: tok.option,
+				text:   input[end : end+k],
+				open:   tok.raw,
+				close:  input[end+k : end+k+len(closeTag)],
+			})
+			end += k + len(closeTag)
+		default:
+			n := &node{tag: tok.name, option: tok.option, open: tok.raw, dropOpen: p.droppable(tok, end)}
+			if !p.push(n) {
+				exhausted = true
+			}
+		}
+
+		if exhausted {
+			p.appendText(input[i:])
+			break
+		}
+		i = end
+	}
+
+	// Best-effort results keep the open tags; otherwise unclosed tags are dropped
+	for len(p.stack) > 1 {
+		if exhausted {
+			p.stack = p.stack[:len(p.stack)-1]
+		} else {
+			p.unwrapTop()
+		}
+	}
+	return root
+}
+
+func (p *parser) top() *node {
+	return p.stack[len(p.stack)-1]
+}
+
+// push opens a tag, spending a budget step for every new nesting level.
+func (p *parser) push(n *node) bool {
+	if len(p.stack) > p.depth {
+		if !p.budget.spend() {
+			return false
+		}
+		p.depth = len(p.stack)
+	}
+	p.top().children = append(p.top().children, n)
+	p.stack = append(p.stack, n)
+	return true
+}
+
+// appendText adds text to the current tag, merging adjacent text runs.
+func (p *parser) appendText(text string) {
+	if text == "" {
+		return
+	}
+	parent := p.top()
+	if last := len(parent.children) - 1; last >= 0 && parent.children[last].tag == "" {
+		parent.children[last].text += text
+		return
+	}
+	parent.children = append(parent.children, &node{text: text})
+}
+
+// closeTag closes the nearest open tag of the same name.
+func (p *parser) closeTag(tok tagToken, end int) {
+	for k := len(p.stack) - 1; k > 0; k-- {
+		if p.stack[k].tag != tok.name {
+			continue
+		}
+		for len(p.stack)-1 > k {
+			p.unwrapTop()
+		}
+		p.stack[k].close = tok.raw
+		p.stack = p.stack[:k]
+		return
+	}
+	p.unknownTag(tok, end)
+}
+
+// listItem closes the previous item of the enclosing list. It reports false
+// when there is no enclosing list.
+func (p *parser) listItem(tok tagToken) bool {
+	for k := len(p.stack) - 1; k > 0; k-- {
+		switch p.stack[k].tag {
+		case "*":
+			for len(p.stack)-1 >= k {
+				p.unwrapTop()
+			}
+			return true
+		case "list":
+			for len(p.stack)-1 > k {
+				p.unwrapTop()
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// unwrapTop closes the current tag without a closing tag. List items are
+// closed normally; other tags are dropped and their content moves to the
+// parent.
+func (p *parser) unwrapTop() {
+	n := p.top()
+	p.stack = p.stack[:len(p.stack)-1]
+	if n.tag == "*" {
+		return
+	}
+
+	parent := p.top()
+	parent.children = parent.children[:len(parent.children)-1]
+	if !n.dropOpen {
+		p.appendText(n.open)
+	}
+	for _, child := range n.children {
+		if child.tag == "" {
+			p.appendText(child.text)
+		} else {
+			parent.children = append(parent.children, child)
+		}
+	}
+}
+
+// unknownTag handles a tag outside the syntax: attachments and tags the
+// legacy cleanup keeps stay as text, the rest is dropped.
+func (p *parser) unknownTag(tok tagToken, end int) {
+	if tok.name == "attach" || !p.droppable(tok, end) {
+		p.appendText(tok.raw)
+	}
+}
+
+// droppable reports whether the legacy cleanup would remove the tag: a tag
+// without quotes or spaces that does not start a Markdown link.
+func (p *parser) droppable(tok tagToken, end int) bool {
+	return simpleTagPattern.MatchString(tok.raw) && (end >= len(p.input) || p.input[end] != '(')
+}
+
+// lexTag reads the tag starting at input[start] ('['). Tags are [name],
+// [name=option], [name attributes] and [/name], on a single line. Quoted
+// options may contain ']'.
+func lexTag(input string, start int) (tagToken, int, bool) {
+	i := start + 1
+	closing := i < len(input) && input[i] == '/'
+	if closing {
+		i++
+	}
+
+	nameStart := i
+	if i < len(input) && input[i] == '*' {
+		i++
+	} else {
+		for i < len(input) && isTagNameByte(input[i], i == nameStart) {
+			i++
+		}
+	}
+	if i == nameStart || i >= len(input) {
+		return tagToken{}, 0, false
+	}
+	tok := tagToken{name: strings.ToLower(input[nameStart:i]), closing: closing}
+
+	switch input[i] {
+	case ']':
+	case '=', ' ':
+		if closing {
+			return tagToken{}, 0, false
+		}
+		j, quoted := i+1, false
+		for ; j < len(input); j++ {
+			c := input[j]
+			if c == '\n' {
+				return tagToken{}, 0, false
+			}
+			if c == '"' {
+				quoted = !quoted
+			} else if c == ']' && !quoted {
+				break
+			}
+		}
+		if j >= len(input) {
+			return tagToken{}, 0, false
+		}
+		tok.option = strings.TrimSpace(input[i+1 : j])
+		if input[i] == '=' {
+			tok.option = unquoteOption(tok.option)
+		}
+		i = j
+	default:
+		return tagToken{}, 0, false
+	}
+
+	tok.raw = input[start : i+1]
+	return tok, i + 1, true
+}
+
+func isTagNameByte(c byte, first bool) bool {
+	switch {
+	case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		return true
+	case first:
+		return false
+	default:
+		return c >= '0' && c <= '9' || c == '_' || c == '-'
+	}
+}
+
+// unquoteOption strips matching single or double quotes around an option.
+func unquoteOption(option string) string {
+	if len(option) >= 2 && (option[0] == '"' || option[0] == '\'') && option[len(option)-1] == option[0] {
+		return strings.TrimSpace(option[1 : len(option)-1])
+	}
+	return option
+}
+
+// indexFold returns the index of the first ASCII case-insensitive match of
+// substr in s, or -1.
+func indexFold(s, substr string) int {
+	for i := 0; i+len(substr) <= len(s); i++ {
+		if strings.EqualFold(s[i:i+len(substr)], substr) {
+			return i
+		}
+	}
+	return -1
+}
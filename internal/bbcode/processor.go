@@ -1,9 +1,12 @@
 package bbcode
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"html"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
 )
@@ -12,23 +15,123 @@ import (
 // Combines BB-code conversion with metadata formatting including author,
 // timestamps, and thread information.
 type MessageProcessor struct {
-	converter *Converter
+	converter   *Converter
+	labels      Labels
+	headerStyle HeaderStyle
+
+	metadataFormat MetadataFormat
+	convertHTML    bool // Convert simple HTML tags to Markdown
+	mentionMode    MentionMode
+	mentionLogins  map[string]bool // Lower-cased GitHub logins of mapped members
 }
 
 // NewMessageProcessor creates a new message processor with an integrated
 // BB-code converter for complete forum post processing.
 func NewMessageProcessor() *MessageProcessor {
 	return &MessageProcessor{
-		converter: NewConverter(),
+		converter:   NewConverter(),
+		labels:      DefaultLabels(),
+		headerStyle: HeaderFrontmatter,
+
+		metadataFormat: MetadataNone,
+		mentionMode:    MentionBold,
 	}
 }
 
+// SetPreserveAlignment controls whether alignment tags are kept as aligned
+// HTML blocks. See Converter.SetPreserveAlignment.
+func (p *MessageProcessor) SetPreserveAlignment(preserve bool) *MessageProcessor {
+	p.converter.SetPreserveAlignment(preserve)
+	return p
+}
+
+// SetSignatureStripping enables removal of trailing signatures. See
+// Converter.SetSignatureStripping.
+func (p *MessageProcessor) SetSignatureStripping(enabled bool, pattern *regexp.Regexp) *MessageProcessor {
+	p.converter.SetSignatureStripping(enabled, pattern)
+	return p
+}
+
+// SetVideoThumbnails renders provider videos as thumbnail images linking to
+// the video. See Converter.SetVideoThumbnails.
+func (p *MessageProcessor) SetVideoThumbnails(enabled bool) *MessageProcessor {
+	p.converter.SetVideoThumbnails(enabled)
+	return p
+}
+
+// SetMediaSites adds custom [media] sites. See Converter.SetMediaSites.
+func (p *MessageProcessor) SetMediaSites(sites map[string]string) *MessageProcessor {
+	p.converter.SetMediaSites(sites)
+	return p
+}
+
+// SetMemberBaseURL links quoted members to their forum profiles. See
+// Converter.SetMemberBaseURL.
+func (p *MessageProcessor) SetMemberBaseURL(baseURL string) *MessageProcessor {
+	p.converter.SetMemberBaseURL(baseURL)
+	return p
+}
+
+// SetLegacyConverter selects the previous regular-expression conversion
+// pipeline. See Converter.SetLegacy.
+func (p *MessageProcessor) SetLegacyConverter(legacy bool) *MessageProcessor {
+	p.converter.SetLegacy(legacy)
+	return p
+}
+
+// SetSmileys replaces known smiley images with emoji. See Converter.SetSmileys.
+func (p *MessageProcessor) SetSmileys(smileys map[string]string) *MessageProcessor {
+	p.converter.SetSmileys(smileys)
+	return p
+}
+
+// SetMentionMode sets how [USER] and @name mentions and the mapped GitHub
+// account in post headers are rendered. Unknown modes render bold.
+func (p *MessageProcessor) SetMentionMode(mode MentionMode) *MessageProcessor {
+	p.converter.SetMentionMode(mode)
+	p.mentionMode = p.converter.mentionMode
+	return p
+}
+
+// SetUserMentions turns [USER] mentions of mapped members into GitHub
+// mentions. See Converter.SetUserMentions.
+func (p *MessageProcessor) SetUserMentions(logins map[int]string) *MessageProcessor {
+	p.converter.SetUserMentions(logins)
+	p.mentionLogins = make(map[string]bool, len(logins))
+	for _, login := range logins {
+		p.mentionLogins[strings.ToLower(login)] = true
+	}
+	return p
+}
+
 // FormatMessage formats a complete forum post with metadata and content conversion.
 // Combines author information, timestamps, thread ID, and BB-code converted content
 // into a formatted GitHub Discussion post with YAML frontmatter.
 //
 // Returns an error if any required parameters are invalid or timestamp conversion fails.
 func (p *MessageProcessor) FormatMessage(username string, postDate int64, threadID int, content string) (string, error) {
+	return p.FormatMessageWithAvatar(username, "", postDate, threadID, content)
+}
+
+// FormatMessageWithAvatar formats a post like FormatMessage and shows the
+// author's avatar as a small image before their name. An empty avatarURL
+// renders the plain header.
+func (p *MessageProcessor) FormatMessageWithAvatar(username, avatarURL string, postDate int64, threadID int, content string) (string, error) {
+	return p.FormatMessageWithAuthor(Author{Username: username, AvatarURL: avatarURL}, postDate, threadID, content)
+}
+
+// Author describes the author shown in a post header.
+type Author struct {
+	Username    string // Forum username
+	AvatarURL   string // Avatar shown before the name (empty = none)
+	GitHubLogin string // Mapped GitHub account mentioned after the name (empty = none)
+}
+
+// FormatMessageWithAuthor formats a post like FormatMessageWithAvatar and
+// mentions the author's mapped GitHub account after their name, which also
+// notifies them unless the mention mode escapes or strips mentions.
+func (p *MessageProcessor) FormatMessageWithAuthor(author Author, postDate int64, threadID int, content string) (string, error) {
+	username, avatarURL := author.Username, author.AvatarURL
 	if strings.TrimSpace(username) == "" {
 		return "", errors.New("username cannot be empty")
 	}
@@ -47,6 +150,7 @@ func (p *MessageProcessor) FormatMessage(username string, postDate int64, thread
 
 	// Handle potential time conversion issues
 	var timestamp string
+	var posted time.Time
 	func() {
 		defer func() {
 			if r := recover(); r != nil {
@@ -55,6 +159,7 @@ func (p *MessageProcessor) FormatMessage(username string, postDate int64, thread
 		}()
 
 		t := time.Unix(postDate, 0).UTC()
+		posted = t
 		now := time.Now().UTC()
 		minDate := time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC)
 		maxDate := now.AddDate(10, 0, 0)
@@ -70,26 +175,129 @@ func (p *MessageProcessor) FormatMessage(username string, postDate int64, thread
 		return "", fmt.Errorf("invalid timestamp: %d", postDate)
 	}
 
+	avatar := ""
+	if avatarURL != "" {
+		avatar = fmt.Sprintf(`<img src="%s" alt="" width="20" height="20"> `, html.EscapeString(avatarURL))
+	}
+
+	mention := ""
+	if login := strings.TrimPrefix(strings.TrimSpace(author.GitHubLogin), "@"); login != "" {
+		mention = " (" + p.mentionMode.mention(login, true) + ")"
+	}
+
+	switch p.headerStyle {
+	case HeaderNone:
+		return strings.TrimSpace(content), nil
+	case HeaderByline:
+		byline := fmt.Sprintf("%s*%s%s — %s*", avatar, strings.TrimSpace(username), mention, posted.Format("2006-01-02 15:04 UTC"))
+		return byline + "\n\n" + strings.TrimSpace(content), nil
+	}
+
+	authorLine := avatar + "**" + strings.TrimSpace(username) + "**" + mention
+
 	formatted := fmt.Sprintf(`---
-Author: **%s**
-Posted: %s
-Original Thread ID: %d
+%s: %s
+%s: %s
+%s
 ---
 
-%s`, strings.TrimSpace(username), timestamp, threadID, strings.TrimSpace(content))
+%s`, p.labels.Author, authorLine, p.labels.Posted, timestamp, p.ThreadMarker(threadID), strings.TrimSpace(content))
 
 	return formatted, nil
 }
 
+// ThreadMarker returns the line identifying the original thread in every
+// formatted message, using the configured thread ID label. It is used to find
+// migrated discussions on GitHub.
+func (p *MessageProcessor) ThreadMarker(threadID int) string {
+	return fmt.Sprintf("%s: %d", p.labels.ThreadID, threadID)
+}
+
+// FormatTopReplyCallout renders a highlighted "Top reply" callout quoting the
+// given converted reply, for placement at the top of a discussion.
+func (p *MessageProcessor) FormatTopReplyCallout(username string, reactionScore int, content string) string {
+	reactions := "reactions"
+	if reactionScore == 1 {
+		reactions = "reaction"
+	}
+
+	var b strings.Builder
+	b.WriteString("> [!TIP]\n")
+	fmt.Fprintf(&b, "> **Top reply** by **%s** (%d %s)\n>\n", strings.TrimSpace(username), reactionScore, reactions)
+	for _, line := range strings.Split(strings.TrimSpace(content), "\n") {
+		b.WriteString(strings.TrimRight("> "+line, " ") + "\n")
+	}
+	return b.String()
+}
+
+// FormatPostAnchor renders an HTML anchor for the original post ID so that
+// "#post-<id>" links resolve within the migrated discussion.
+func (p *MessageProcessor) FormatPostAnchor(postID int) string {
+	return fmt.Sprintf(`<a id="post-%d"></a>`, postID)
+}
+
+// FormatThreadStats renders a one-line summary of the original thread from a
+// template. Supported placeholders are {date}, {replies} and {views}; counts
+// of a thousand or more are abbreviated (e.g., 1.2k, 3M).
+func (p *MessageProcessor) FormatThreadStats(template string, postDate int64, replies, views int) string {
+	return strings.NewReplacer(
+		"{date}", time.Unix(postDate, 0).UTC().Format("2006-01-02"),
+		"{replies}", abbreviateCount(replies),
+		"{views}", abbreviateCount(views),
+	).Replace(template)
+}
+
+// abbreviateCount shortens large counts to one decimal with a k or M suffix.
+func abbreviateCount(n int) string {
+	switch {
+	case n < 1000:
+		return strconv.Itoa(n)
+	case n < 999_950:
+		return formatDecimal(float64(n)/1_000) + "k"
+	default:
+		return formatDecimal(float64(n)/1_000_000) + "M"
+	}
+}
+
+func formatDecimal(value float64) string {
+	return strings.TrimSuffix(strconv.FormatFloat(value, 'f', 1, 64), ".0")
+}
+
+// quotedPostPattern matches XenForo quote attributions that reference a post,
+// e.g. [QUOTE="Alice, post: 456, member: 12"].
+var quotedPostPattern = regexp.MustCompile(`(?i)\[quote="[^"\]]*?\bpost:\s*(\d+)`)
+
+// QuotedPostIDs returns the IDs of posts quoted in a BB-code message, in the
+// order they appear.
+func QuotedPostIDs(message string) []int {
+	var ids []int
+	for _, match := range quotedPostPattern.FindAllStringSubmatch(message, -1) {
+		if id, err := strconv.Atoi(match[1]); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// ProcessContent converts the BB-code of a post to Markdown. HTML entities
+// are decoded and dangerous HTML is stripped first.
 func (p *MessageProcessor) ProcessContent(content string) string {
-	result := p.converter.ToMarkdown(content)
+	result, _ := p.ProcessContentContext(context.Background(), content)
+	return result
+}
+
+// ProcessContentContext converts content within the converter's processing
+// budget. On ErrBudgetExceeded the best-effort conversion is still returned.
+func (p *MessageProcessor) ProcessContentContext(ctx context.Context, content string) (string, error) {
+	result, err := p.converter.ToMarkdownContext(ctx, cleanHTML(content, p.convertHTML))
 
 	result = p.convertAtMentions(result)
 
-	return result
+	return result, err
 }
 
-// convertAtMentions converts @username patterns to **username** bold format
+// convertAtMentions renders @username patterns in the style of the mention
+// mode. Mentions of mapped members' logins count as mapped.
 func (p *MessageProcessor) convertAtMentions(content string) string {
 	mentionRe := regexp.MustCompile(`@([a-zA-Z0-9_-]*[a-zA-Z]+[a-zA-Z0-9_-]*)\b`)
 
@@ -127,7 +335,10 @@ func (p *MessageProcessor) convertAtMentions(content string) string {
 			continue
 		}
 		username := parts[1]
-		replacement := "**" + username + "**"
+		replacement := p.mentionMode.mention(username, p.mentionLogins[strings.ToLower(username)])
+		if replacement == match {
+			continue
+		}
 
 		adjustedStart := matchStart + offset
 		adjustedEnd := matchEnd + offset
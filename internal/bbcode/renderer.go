@@ -0,0 +1,217 @@
+package bbcode
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"strings"
+)
+
+// markdownRenderer renders a BB-code syntax tree as GitHub-flavored Markdown
+// with the converter's options. The output matches the legacy pipeline for
+// well-formed input; nested blocks additionally start on their own line.
+type markdownRenderer struct {
+	c *Converter
+}
+
+// renderMarkdown parses BB-code and renders it as Markdown.
+func (c *Converter) renderMarkdown(input string, b *budget) string {
+	r := &markdownRenderer{c: c}
+	return r.children(parseBBCode(input, b), detailsTitle)
+}
+
+// detailsTitle titles [tab] sections used outside of a group.
+func detailsTitle() string {
+	return "Details"
+}
+
+// children renders the children of a node. Block elements are separated from
+// surrounding text by line breaks so Markdown recognizes them. tabTitle
+// supplies the title of untitled [tab] children.
+func (r *markdownRenderer) children(n *node, tabTitle func() string) string {
+	var out []byte
+	afterBlock := false
+	for _, child := range n.children {
+		text := r.render(child, tabTitle)
+		if text == "" {
+			continue
+		}
+
+		block := r.isBlock(child)
+		if block && len(out) > 0 && out[len(out)-1] != '\n' {
+			out = append(bytes.TrimRight(out, " \t"), '\n')
+		} else if afterBlock && !strings.HasPrefix(text, "\n") {
+			out = append(out, '\n')
+		}
+		out = append(out, text...)
+		afterBlock = block
+	}
+	return string(out)
+}
+
+// isBlock reports whether a node renders as a block that must start on its
+// own line.
+func (r *markdownRenderer) isBlock(n *node) bool {
+	switch n.tag {
+	case "quote", "list", "spoiler":
+		return true
+	case "left", "right", "justify":
+		return r.c.preserveAlignment
+	}
+	return false
+}
+
+func (r *markdownRenderer) render(n *node, tabTitle func() string) string {
+	switch n.tag {
+	case "":
+		return r.c.replaceSmileyCodes(n.text)
+	case "b":
+		return wrapNonEmpty(r.children(n, detailsTitle), "**", "**")
+	case "i":
+		return wrapNonEmpty(r.children(n, detailsTitle), "*", "*")
+	case "u":
+		return wrapNonEmpty(r.children(n, detailsTitle), "<u>", "</u>")
+	case "s", "strike":
+		return wrapNonEmpty(r.children(n, detailsTitle), "~~", "~~")
+	case "url":
+		if n.option == "" {
+			return "[" + n.text + "](" + n.text + ")"
+		}
+		return "[" + r.children(n, detailsTitle) + "](" + n.option + ")"
+	case "img":
+		if emoji, ok := r.c.lookupSmiley(n.text); ok {
+			return emoji
+		}
+		return "![](" + n.text + ")"
+	case "quote":
+		return r.quote(n)
+	case "code", "php", "html", "sql":
+		return codeBlock(codeLanguage(n.tag, n.option), n.text)
+	case "icode":
+		return "`" + n.text + "`"
+	case "plain":
+		return n.text
+	case "spoiler":
+		title := strings.TrimSpace(n.option)
+		if title == "" {
+			title = "Spoiler"
+		}
+		return "<details><summary>" + html.EscapeString(title) + "</summary>\n\n" + strings.TrimSpace(r.children(n, detailsTitle)) + "\n\n</details>"
+	case "ispoiler":
+		return "||" + r.children(n, detailsTitle) + "||"
+	case "list":
+		return r.list(n)
+	case "center":
+		return "<center>" + r.children(n, detailsTitle) + "</center>"
+	case "left", "right", "justify":
+		content := r.children(n, detailsTitle)
+		if !r.c.preserveAlignment {
+			return content
+		}
+		if strings.TrimSpace(content) == "" {
+			return ""
+		}
+		// Blank lines let GitHub render Markdown inside the HTML block.
+		return "<div align=\"" + n.tag + "\">\n\n" + strings.TrimSpace(content) + "\n\n</div>"
+	case "table":
+		return r.table(n)
+	case "accordion", "tabs":
+		index := 0
+		sections := r.children(n, func() string {
+			index++
+			return fmt.Sprintf("Tab %d", index)
+		})
+		return "\n" + strings.Trim(sections, "\n") + "\n"
+	case "tab", "slide":
+		title := strings.TrimSpace(n.option)
+		if title == "" {
+			title = tabTitle()
+		}
+		content := strings.TrimSpace(r.children(n, detailsTitle))
+		return "\n<details><summary>" + html.EscapeString(title) + "</summary>\n\n" + content + "\n\n</details>\n"
+	case "user":
+		return r.c.userMention(n.option, r.children(n, detailsTitle))
+	case "media":
+		return r.c.mediaEmbed(n.option, strings.TrimSpace(n.text))
+	case "youtube":
+		if link, ok := r.c.youtubeEmbed(strings.TrimSpace(n.text)); ok {
+			return link
+		}
+		return n.open + n.text + n.close
+	case "video":
+		if link, ok := r.c.videoEmbed(strings.TrimSpace(n.text)); ok {
+			return link
+		}
+		return n.open + n.text + n.close
+	case "attach":
+		// Attachments are replaced after conversion
+		return n.open + n.text + n.close
+	default:
+		// Styling without a Markdown equivalent, and table parts outside a table
+		return r.children(n, detailsTitle)
+	}
+}
+
+// wrapNonEmpty wraps content in Markdown delimiters, dropping tags without
+// content.
+func wrapNonEmpty(content, open, close string) string {
+	if strings.TrimSpace(content) == "" {
+		return ""
+	}
+	return open + content + close
+}
+
+// quote renders a quote, prefixed by its attribution when the option names
+// an author ([quote="Name, post: 1, member: 2"]).
+func (r *markdownRenderer) quote(n *node) string {
+	var out strings.Builder
+	if author, params, _ := strings.Cut(n.option, ","); strings.TrimSpace(author) != "" {
+		out.WriteString("> **" + r.c.quoteAuthor(strings.TrimSpace(author), params) + " said:**\n")
+	}
+	for _, line := range strings.Split(strings.TrimSpace(r.children(n, detailsTitle)), "\n") {
+		out.WriteString("> " + line + "\n")
+	}
+	return out.String()
+}
+
+// list renders [list] items as bullets, or numbered for [list=1] and other
+// list types. Continuation lines are indented under their item.
+func (r *markdownRenderer) list(n *node) string {
+	var out strings.Builder
+	number := 0
+	for _, child := range n.children {
+		if child.tag != "*" {
+			if text := strings.TrimSpace(r.render(child, detailsTitle)); text != "" {
+				out.WriteString(text + "\n")
+			}
+			continue
+		}
+
+		number++
+		out.WriteString(listItem(listMarker(n.option != "", number), r.children(child, detailsTitle)))
+	}
+	return out.String()
+}
+
+// table renders the [tr] rows of a table and their [th]/[td] cells.
+func (r *markdownRenderer) table(n *node) string {
+	var rows [][]tableCell
+	for _, row := range n.children {
+		if row.tag != "tr" {
+			continue
+		}
+		var cells []tableCell
+		for _, cell := range row.children {
+			if cell.tag == "td" || cell.tag == "th" {
+				cells = append(cells, tableCell{
+					header:  cell.tag == "th",
+					content: tableCellContent(r.children(cell, detailsTitle)),
+				})
+			}
+		}
+		if len(cells) > 0 {
+			rows = append(rows, cells)
+		}
+	}
+	return formatTable(rows)
+}
@@ -0,0 +1,245 @@
+package bbcode
+
+import (
+	"html"
+	"net/url"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// DefaultSmileys maps the image paths and text codes of XenForo's stock
+// smilies to their unicode emoji.
+func DefaultSmileys() map[string]string {
+	return map[string]string{
+		":)":         "🙂",
+		":-)":        "🙂",
+		";)":         "😉",
+		";-)":        "😉",
+		":(":         "🙁",
+		":-(":        "🙁",
+		":mad:":      "😠",
+		">:(":        "😠",
+		":confused:": "😕",
+		":cool:":     "😎",
+		"8-)":        "😎",
+		":p":         "😛",
+		":P":         "😛",
+		":-p":        "😛",
+		":D":         "😁",
+		":-D":        "😁",
+		":eek:":      "😮",
+		":o":         "😮",
+		":oops:":     "😳",
+		":rolleyes:": "🙄",
+		":cry:":      "😢",
+		";(":         "😢",
+		":LOL:":      "😂",
+		":lol:":      "😂",
+		":love:":     "😍",
+		":unsure:":   "😒",
+		":sick:":     "🤢",
+		":sleep:":    "😴",
+		":thumbsup:": "👍",
+		"styles/default/xenforo/smilies/smile.png":    "🙂",
+		"styles/default/xenforo/smilies/wink.png":     "😉",
+		"styles/default/xenforo/smilies/frown.png":    "🙁",
+		"styles/default/xenforo/smilies/mad.png":      "😠",
+		"styles/default/xenforo/smilies/confused.png": "😕",
+		"styles/default/xenforo/smilies/cool.png":     "😎",
+		"styles/default/xenforo/smilies/tongue.png":   "😛",
+		"styles/default/xenforo/smilies/biggrin.png":  "😁",
+		"styles/default/xenforo/smilies/eek.png":      "😮",
+		"styles/default/xenforo/smilies/oops.png":     "😳",
+		"styles/default/xenforo/smilies/rolleyes.png": "🙄",
+		"styles/default/xenforo/smilies/cry.png":      "😢",
+		"styles/default/xenforo/smilies/laugh.png":    "😂",
+		"styles/default/xenforo/smilies/love.png":     "😍",
+		"styles/default/xenforo/smilies/unsure.png":   "😒",
+		"styles/default/xenforo/smilies/sick.png":     "🤢",
+		"styles/default/xenforo/smilies/sleep.png":    "😴",
+		"styles/default/xenforo/smilies/thumbsup.png": "👍",
+	}
+}
+
+// SetSmileys replaces smilies with the mapped emoji or shortcode. Keys
+// containing a slash or ending in an image extension are image paths, matched
+// against the end of [img] and <img class="smilie"> sources so relative keys
+// also match absolute forum URLs. Other keys are text codes such as ":)",
+// replaced where they stand apart from the surrounding text. A nil or empty
+// map disables the conversion.
+func (c *Converter) SetSmileys(smileys map[string]string) *Converter {
+	c.smileys = make(map[string]string, len(smileys))
+	c.smileyCodes = make(map[string]string)
+	for key, emoji := range smileys {
+		if !isSmileyPath(key) {
+			if code := strings.TrimSpace(key); code != "" {
+				c.smileyCodes[code] = emoji
+			}
+		} else if path := normalizeSmileyPath(key); path != "" {
+			c.smileys[path] = emoji
+		}
+	}
+
+	c.smileyCodePattern = nil
+	if len(c.smileyCodes) > 0 {
+		codes := make([]string, 0, len(c.smileyCodes))
+		for code := range c.smileyCodes {
+			codes = append(codes, regexp.QuoteMeta(code))
+		}
+		// Longest first, so ":-)" is not matched as ":" followed by text
+		sort.Slice(codes, func(i, j int) bool {
+			if len(codes[i]) != len(codes[j]) {
+				return len(codes[i]) > len(codes[j])
+			}
+			return codes[i] < codes[j]
+		})
+		c.smileyCodePattern = regexp.MustCompile(strings.Join(codes, "|"))
+	}
+	return c
+}
+
+// isSmileyPath reports whether a smiley map key is an image path rather than
+// a text code.
+func isSmileyPath(key string) bool {
+	switch strings.ToLower(path.Ext(key)) {
+	case ".png", ".gif", ".jpg", ".jpeg", ".svg", ".webp":
+		return true
+	}
+	return strings.Contains(key, "/")
+}
+
+var smileyImagePattern = regexp.MustCompile(`(?i)\[img\]\s*(.*?)\s*\[/img\]`)
+
+func (c *Converter) processSmileys(input string) string {
+	if len(c.smileys) == 0 && len(c.smileyCodes) == 0 {
+		return input
+	}
+
+	result := smileyImagePattern.ReplaceAllStringFunc(input, func(match string) string {
+		parts := smileyImagePattern.FindStringSubmatch(match)
+		if emoji, ok := c.lookupSmiley(parts[1]); ok {
+			return emoji
+		}
+		return match
+	})
+	return c.replaceSmilieImages(result)
+}
+
+var (
+	smilieImagePattern     = regexp.MustCompile(`(?i)<img\s[^>]*\bclass="[^"]*\bsmilie\b[^"]*"[^>]*>`)
+	smilieAttributePattern = regexp.MustCompile(`(?i)\b(src|alt|data-shortname)="([^"]*)"`)
+)
+
+// replaceSmilieImages converts the <img class="smilie"> markup of rendered
+// XenForo content. The image source is looked up first, then the smilie's
+// code; unknown smilies become a Markdown image keeping the source URL, or
+// their code when the source is an inline sprite placeholder.
+func (c *Converter) replaceSmilieImages(text string) string {
+	if !strings.Contains(text, "<") {
+		return text
+	}
+
+	return smilieImagePattern.ReplaceAllStringFunc(text, func(match string) string {
+		attributes := make(map[string]string)
+		for _, attribute := range smilieAttributePattern.FindAllStringSubmatch(match, -1) {
+			attributes[strings.ToLower(attribute[1])] = html.UnescapeString(attribute[2])
+		}
+
+		src := attributes["src"]
+		inline := strings.HasPrefix(strings.ToLower(src), "data:")
+		if !inline {
+			if emoji, ok := c.lookupSmiley(src); ok {
+				return emoji
+			}
+		}
+
+		code := attributes["data-shortname"]
+		if code == "" {
+			code = attributes["alt"]
+		}
+		if emoji, ok := c.smileyCodes[code]; ok {
+			return emoji
+		}
+		if src != "" && !inline {
+			return "![" + attributes["alt"] + "](" + src + ")"
+		}
+		return code
+	})
+}
+
+// replaceSmileyCodes converts the smilie codes and <img class="smilie">
+// markup of a text node. Codes only count when they stand apart from the
+// surrounding text, so "http://" or "a:p" are left alone.
+func (c *Converter) replaceSmileyCodes(text string) string {
+	if len(c.smileys) == 0 && len(c.smileyCodes) == 0 {
+		return text
+	}
+	text = c.replaceSmilieImages(text)
+	if c.smileyCodePattern == nil {
+		return text
+	}
+
+	var result strings.Builder
+	last := 0
+	for _, match := range c.smileyCodePattern.FindAllStringIndex(text, -1) {
+		if !smileyBoundaryBefore(text[:match[0]]) || !smileyBoundaryAfter(text[match[1]:]) {
+			continue
+		}
+		result.WriteString(text[last:match[0]])
+		result.WriteString(c.smileyCodes[text[match[0]:match[1]]])
+		last = match[1]
+	}
+	if last == 0 {
+		return text
+	}
+	result.WriteString(text[last:])
+	return result.String()
+}
+
+// smileyBoundaryBefore reports whether a smilie code may follow before.
+func smileyBoundaryBefore(before string) bool {
+	if before == "" {
+		return true
+	}
+	r := rune(before[len(before)-1])
+	return r == '(' || unicode.IsSpace(r)
+}
+
+// smileyBoundaryAfter reports whether a smilie code may precede after.
+func smileyBoundaryAfter(after string) bool {
+	if after == "" {
+		return true
+	}
+	r := rune(after[0])
+	return strings.ContainsRune(".,!?;:)", r) || unicode.IsSpace(r)
+}
+
+// lookupSmiley finds the mapping whose key is the longest path suffix of the
+// image path.
+func (c *Converter) lookupSmiley(imagePath string) (string, bool) {
+	path := normalizeSmileyPath(imagePath)
+	for path != "" {
+		if emoji, ok := c.smileys[path]; ok {
+			return emoji, true
+		}
+		_, rest, found := strings.Cut(path, "/")
+		if !found {
+			break
+		}
+		path = rest
+	}
+	return "", false
+}
+
+// normalizeSmileyPath strips the scheme, host, query and leading slashes so
+// "https://forum.example.com/styles/x.png?v=1" and "/styles/x.png" compare equal.
+func normalizeSmileyPath(path string) string {
+	path = strings.TrimSpace(path)
+	if parsed, err := url.Parse(path); err == nil {
+		path = parsed.Path
+	}
+	return strings.ToLower(strings.TrimLeft(path, "/"))
+}
@@ -0,0 +1,118 @@
+package bbcode
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+var (
+	// tablePattern matches a [table] block without nested tables. XenForo
+	// options such as [table=collapse] are accepted and ignored.
+	tablePattern = regexp2.MustCompile(`(?is)\[table(?:=[^\]]*)?\]((?:(?!\[/?table[=\]]).)*?)\[/table\]`, 0)
+
+	tableRowPattern  = regexp.MustCompile(`(?is)\[tr\](.*?)\[/tr\]`)
+	tableCellPattern = regexp.MustCompile(`(?is)\[(td|th)(?:=[^\]]*)?\](.*?)\[/(?:td|th)\]`)
+	cellBreakPattern = regexp.MustCompile(`\s*\n\s*`)
+)
+
+// processTables converts [table] blocks of [tr] rows with [th]/[td] cells into
+// GitHub-flavored Markdown tables. A first row made only of [th] cells becomes
+// the table header; otherwise the header is left empty, since Markdown tables
+// always have one. Short rows are padded, line breaks within cells become
+// <br> and pipes are escaped. Nested tables are converted from the innermost
+// out.
+func (c *Converter) processTables(input string, b *budget) string {
+	result := input
+	for b.spend() {
+		converted, _ := tablePattern.ReplaceFunc(result, func(m regexp2.Match) string {
+			return renderTable(m.GroupByNumber(1).String())
+		}, -1, -1)
+		if converted == result {
+			break
+		}
+		result = converted
+	}
+	return result
+}
+
+type tableCell struct {
+	header  bool
+	content string
+}
+
+// renderTable renders the rows of a single table.
+func renderTable(body string) string {
+	var rows [][]tableCell
+	for _, row := range tableRowPattern.FindAllStringSubmatch(body, -1) {
+		var cells []tableCell
+		for _, cell := range tableCellPattern.FindAllStringSubmatch(row[1], -1) {
+			cells = append(cells, tableCell{
+				header:  strings.EqualFold(cell[1], "th"),
+				content: tableCellContent(cell[2]),
+			})
+		}
+		if len(cells) == 0 {
+			continue
+		}
+		rows = append(rows, cells)
+	}
+	return formatTable(rows)
+}
+
+// formatTable renders table rows as a Markdown table, padding short rows.
+// Tables without rows are dropped.
+func formatTable(rows [][]tableCell) string {
+	if len(rows) == 0 {
+		return ""
+	}
+	columns := 0
+	for _, cells := range rows {
+		columns = max(columns, len(cells))
+	}
+
+	header := make([]tableCell, columns)
+	if isHeaderRow(rows[0]) {
+		copy(header, rows[0])
+		rows = rows[1:]
+	}
+
+	var out strings.Builder
+	out.WriteString("\n\n")
+	writeTableRow(&out, header, columns)
+	out.WriteString("|" + strings.Repeat(" --- |", columns) + "\n")
+	for _, row := range rows {
+		writeTableRow(&out, row, columns)
+	}
+	out.WriteString("\n")
+	return out.String()
+}
+
+func isHeaderRow(cells []tableCell) bool {
+	for _, cell := range cells {
+		if !cell.header {
+			return false
+		}
+	}
+	return true
+}
+
+func writeTableRow(out *strings.Builder, cells []tableCell, columns int) {
+	out.WriteString("|")
+	for i := 0; i < columns; i++ {
+		content := ""
+		if i < len(cells) {
+			content = cells[i].content
+		}
+		out.WriteString(" " + content + " |")
+	}
+	out.WriteString("\n")
+}
+
+// tableCellContent fits converted cell content on a single Markdown line.
+func tableCellContent(content string) string {
+	content = strings.TrimSpace(content)
+	content = strings.ReplaceAll(content, "|", `\|`)
+	return cellBreakPattern.ReplaceAllString(content, "<br>")
+}
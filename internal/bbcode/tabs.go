@@ -0,0 +1,65 @@
+package bbcode
+
+import (
+	"fmt"
+	"html"
+	"strings"
+
+	"github.com/dlclark/regexp2"
+)
+
+var (
+	// tabGroupPattern matches an [accordion] or [tabs] group without nested groups.
+	tabGroupPattern = regexp2.MustCompile(`(?is)\[(accordion|tabs)\]((?:(?!\[/?(?:accordion|tabs)\]).)*?)\[/\1\]`, 0)
+
+	// tabPattern matches a [tab] or [slide] section without nested sections.
+	// The title is either quoted or runs up to the closing bracket.
+	tabPattern = regexp2.MustCompile(`(?is)\[(tab|slide)(?:=(?:"([^"\]]*)"|([^\]]*)))?\]((?:(?!\[/?(?:tab|slide)[=\]]).)*?)\[/\1\]`, 0)
+)
+
+// processTabs converts [accordion]/[tabs] groups of [tab=Title] (or [slide])
+// sections into a series of collapsible <details> blocks. Nested groups are
+// converted from the innermost out. Sections without a title are numbered
+// within their group, or titled "Details" outside of a group.
+func (c *Converter) processTabs(input string, b *budget) string {
+	result := input
+	for b.spend() {
+		converted, _ := tabGroupPattern.ReplaceFunc(result, func(m regexp2.Match) string {
+			index := 0
+			sections := convertTabs(m.GroupByNumber(2).String(), b, func() string {
+				index++
+				return fmt.Sprintf("Tab %d", index)
+			})
+			return "\n" + strings.Trim(sections, "\n") + "\n"
+		}, -1, -1)
+		if converted == result {
+			break
+		}
+		result = converted
+	}
+
+	// Sections used without a surrounding group
+	return convertTabs(result, b, func() string { return "Details" })
+}
+
+// convertTabs replaces tab sections in input, innermost first. defaultTitle
+// supplies the title of sections without one.
+func convertTabs(input string, b *budget, defaultTitle func() string) string {
+	result := input
+	for b.spend() {
+		converted, _ := tabPattern.ReplaceFunc(result, func(m regexp2.Match) string {
+			title := strings.TrimSpace(m.GroupByNumber(2).String() + m.GroupByNumber(3).String())
+			if title == "" {
+				title = defaultTitle()
+			}
+			content := strings.TrimSpace(m.GroupByNumber(4).String())
+			// Blank lines let GitHub render Markdown inside the HTML block.
+			return "\n<details><summary>" + html.EscapeString(title) + "</summary>\n\n" + content + "\n\n</details>\n"
+		}, -1, -1)
+		if converted == result {
+			break
+		}
+		result = converted
+	}
+	return result
+}
@@ -0,0 +1,49 @@
+// Package concurrency provides small synchronization helpers shared by the
+// migration subsystems, such as the global limit on in-flight work.
+package concurrency
+
+import "context"
+
+// Semaphore bounds the number of concurrently running operations.
+// A nil Semaphore is valid and imposes no limit.
+type Semaphore struct {
+	slots chan struct{}
+}
+
+// NewSemaphore creates a semaphore with the given number of slots.
+// Returns nil (unlimited) when size is zero or negative.
+func NewSemaphore(size int) *Semaphore {
+	if size <= 0 {
+		return nil
+	}
+	return &Semaphore{slots: make(chan struct{}, size)}
+}
+
+// Acquire blocks until a slot is available or the context is cancelled.
+func (s *Semaphore) Acquire(ctx context.Context) error {
+	if s == nil {
+		return ctx.Err()
+	}
+	select {
+	case s.slots <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Release frees a slot previously obtained with Acquire.
+func (s *Semaphore) Release() {
+	if s == nil {
+		return
+	}
+	<-s.slots
+}
+
+// Size returns the number of slots, or 0 for an unlimited semaphore.
+func (s *Semaphore) Size() int {
+	if s == nil {
+		return 0
+	}
+	return cap(s.slots)
+}
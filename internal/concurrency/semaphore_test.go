@@ -0,0 +1,65 @@
+package concurrency
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/testutil"
+)
+
+func TestSemaphoreLimitsConcurrency(t *testing.T) {
+	sem := NewSemaphore(2)
+
+	var probe testutil.ConcurrencyProbe
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := sem.Acquire(context.Background()); err != nil {
+				t.Errorf("Acquire failed: %v", err)
+				return
+			}
+			defer sem.Release()
+
+			leave := probe.Enter()
+			time.Sleep(10 * time.Millisecond)
+			leave()
+		}()
+	}
+	wg.Wait()
+
+	if probe.Max() > 2 {
+		t.Errorf("Expected at most 2 concurrent holders, got %d", probe.Max())
+	}
+}
+
+func TestSemaphoreNilIsUnlimited(t *testing.T) {
+	sem := NewSemaphore(0)
+	if sem != nil {
+		t.Fatal("Expected nil semaphore for zero size")
+	}
+	if err := sem.Acquire(context.Background()); err != nil {
+		t.Errorf("Nil semaphore Acquire should succeed: %v", err)
+	}
+	sem.Release()
+	if sem.Size() != 0 {
+		t.Errorf("Expected size 0, got %d", sem.Size())
+	}
+}
+
+func TestSemaphoreAcquireRespectsContext(t *testing.T) {
+	sem := NewSemaphore(1)
+	if err := sem.Acquire(context.Background()); err != nil {
+		t.Fatalf("Acquire failed: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if err := sem.Acquire(ctx); err == nil {
+		t.Error("Expected Acquire to fail when context expires while waiting")
+	}
+}
@@ -4,9 +4,14 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"strconv"
+	"strings"
 	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/bbcode"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
 )
 
 // Config holds all configuration settings for the migration tool.
@@ -26,19 +31,44 @@ type XenForoConfig struct {
 	APIKey  string // XenForo API key for authentication
 	APIUser string // XenForo user ID for API requests
 	NodeID  int    // Forum node/category ID to migrate
+	WebURL  string // Public forum URL used for profile and attachment links (e.g., "https://forum.example.com"), optional
+
+	Source      string // Where forum content is read from: SourceAPI (default), SourceDB or SourcePhpBB
+	DatabaseDSN string // MySQL DSN of the XenForo database for SourceDB (e.g., "user:pass@tcp(localhost:3306)/xenforo")
+	ExportFile  string // JSON export of the board for SourcePhpBB
+	DataDir     string // Directory attachment files are copied from: XenForo's internal_data or phpBB's files, optional
+
+	CacheDir string        // Directory caching thread and post listing responses of the API (empty = disabled)
+	CacheTTL time.Duration // How long cached listings are used without asking the forum (0 = always revalidate)
+
+	WebhookSecret string // Secret of the XenForo webhooks received by the serve command (required unless serve runs with --insecure)
 }
 
+// Forum sources.
+const (
+	SourceAPI   = "api"   // The XenForo REST API
+	SourceDB    = "db"    // The XenForo MySQL database
+	SourcePhpBB = "phpbb" // A phpBB board's JSON export
+)
+
 // GitHubConfig contains GitHub API connection and rate limiting settings.
 // Supports both legacy multi-category mapping and single-category migration.
 type GitHubConfig struct {
-	Token                string         // GitHub personal access token
-	Repository           string         // Target repository in "owner/repo" format
-	Categories           map[int]string // Kept for backward compatibility
-	XenForoNodeID        int            // Single source category
-	GitHubCategoryID     string         // Single target category
-	RateLimitDelay       time.Duration  // Delay between API calls
-	MaxRetries           int            // Maximum retries for rate limited requests
-	RetryBackoffMultiple int            // Multiplier for exponential backoff (seconds)
+	Token                string            // GitHub personal access token
+	AppID                int               // GitHub App authenticating instead of the token (0 = use the token)
+	AppInstallationID    int               // Installation of the GitHub App on the repository's owner
+	AppPrivateKeyFile    string            // PEM private key of the GitHub App
+	Repository           string            // Target repository in "owner/repo" format
+	Categories           map[int]string    // Kept for backward compatibility
+	XenForoNodeID        int               // Single source category
+	GitHubCategoryID     string            // Single target category
+	RateLimitDelay       time.Duration     // Minimum delay between API calls
+	MaxRetries           int               // Maximum retries for rate limited requests
+	RetryBackoffMultiple int               // Multiplier for exponential backoff (seconds)
+	CommentBatchSize     int               // Comments created per GraphQL request (0 or 1 = one request per comment)
+	NodeTitlePrefix      map[int]string    // Title prefix per source node (e.g., 2: "[General]")
+	PrefixCategoryMap    map[string]string // Thread prefix title or ID -> category overriding the node's category
+	PrefixLabelMap       map[string]string // Thread prefix title or ID -> repository label added to the discussion
 }
 
 // MigrationConfig controls migration behavior and retry logic.
@@ -49,7 +79,97 @@ type MigrationConfig struct {
 	Verbose      bool // Enable verbose logging
 	ResumeFrom   int
 	ProgressFile string
-	UserMapping  map[int]int
+	PauseFile    string // Control file that pauses the run between threads while it exists
+	WebhookURL   string // Webhook receiving a JSON run summary (empty = disabled)
+	DryRunOutput string // Directory receiving the converted Markdown of a dry run (empty = disabled)
+	ExportOnly   string // Directory receiving the rendered threads as NDJSON for a later upload instead of posting them (empty = disabled)
+
+	ProgressBackend    string // Progress storage: json (ProgressFile) or sqlite (ProgressFile with a .db extension)
+	ProgressBucketSize int    // Shard completed/failed threads into files of this many thread IDs (0 = single file)
+
+	RequireEmptyCategory  bool // Abort when a target category already has discussions (fresh-start guard)
+	AllowNonEmptyCategory bool // Only warn about non-empty target categories
+	RepairMappings        bool // Look up completed threads without a recorded discussion by their thread marker
+	DetectDuplicates      bool // Search for an existing discussion of each thread before creating one
+	ResumeListing         bool // Save the thread listing after every page and resume an interrupted listing
+
+	UserMapping       map[int]string // XenForo user ID -> GitHub username mentioned in post headers and [USER] mentions
+	InviteMappedUsers bool           // Invite mapped users to the repository with read access (organization repositories only)
+
+	RedirectsFile string // JSON file mapping old thread URLs to their discussions, written after each run (empty = disabled)
+	NginxMapFile  string // nginx map file redirecting old thread URLs to their discussions (empty = disabled)
+
+	MarkSolutions     bool // Mark the solution post of solved question threads as the discussion answer
+	LockClosedThreads bool // Lock discussions of threads closed to new replies
+
+	ReactionsMode string         // Post reactions: none, summary (a "👍 12 · ❤️ 3" line) or github (reactions by the token user); SourceDB only
+	ReactionEmoji map[int]string // Emoji of custom XenForo reaction IDs in summaries, overriding the defaults
+
+	AutoRetryFailed bool          // Retry the threads failed during the run once more before the summary
+	AutoRetryWait   time.Duration // Pause between the main run and the retry pass
+	RetryFailed     bool          // Only migrate the threads recorded as failed in the progress file
+
+	SyncInterval time.Duration // Pause between sync passes (0 = a single pass)
+	ServeAddr    string        // Address the serve command listens on for XenForo webhooks
+
+	// Concurrency settings. Thread workers and attachment workers are sized
+	// independently, and both draw slots from the global MaxConcurrency limit.
+	MigrationConcurrency int // Threads processed in parallel (0 or 1 = sequential)
+	AttachmentWorkers    int // Parallel attachment downloads per thread (0 or 1 = sequential)
+	MaxConcurrency       int // Global cap on in-flight thread and attachment work (0 = unlimited)
+
+	// Thread filters, applied to the listed threads. Empty values match every
+	// thread.
+	FilterSince      string   // Threads created on or after this date (YYYY-MM-DD or RFC 3339)
+	FilterUntil      string   // Threads created on or before this date (a date includes its whole day)
+	FilterMinReplies int      // Threads with at least this many replies
+	FilterPrefixes   []string // Threads with one of these prefix titles or IDs
+	FilterAuthors    []string // Threads started by one of these usernames
+	FilterState      string   // ThreadsOpen or ThreadsClosed
+	FilterThreads    string   // Thread IDs and ranges to migrate (e.g. "100,105,200-300"), or a file listing them
+	ExcludeThreads   string   // Thread IDs and ranges never migrated, or a file listing them
+
+	MigrationOrder string // Order threads are migrated in: OrderListing, OrderOldestFirst, OrderNewestFirst or OrderLastActivity
+
+	TopReplyCallout bool // Highlight the most-reacted reply at the top of each discussion
+	SinceLastRun    bool // Only migrate threads created since the last successful run
+	PostAnchors     bool // Start each post with an anchor named after its original post ID
+	AuthorAvatars   bool // Show each author's avatar in the post header (one extra request per author)
+
+	PreserveAlignment bool   // Keep [left]/[right]/[justify] as aligned HTML (false = strip like color/size)
+	StripSignatures   bool   // Remove trailing signatures ("-- " delimiter or [sig] tag)
+	SignaturePattern  string // Optional regex marking the start of a signature
+
+	SmileyEmoji bool              // Replace smiley images and codes with emoji
+	SmileyMap   map[string]string // Additional smiley image paths or codes and their emoji, overriding the defaults
+
+	VideoThumbnails bool              // Render embedded videos as a thumbnail linking to the video
+	MediaSites      map[string]string // Custom [media] sites: site ID to item URL with an {id} placeholder
+
+	LegacyConverter bool // Convert BB-code with the previous regular-expression pipeline instead of the parser
+	ConvertHTML     bool // Convert simple HTML tags left by the editor (br, b, i, a) to Markdown
+
+	Locale            string            // Locale of the post frontmatter labels (e.g., "en", "de")
+	FrontmatterLabels map[string]string // Label overrides keyed by "author", "posted" and "thread_id"
+	HeaderStyle       string            // Post metadata style: frontmatter, byline or none
+
+	MetadataFormat string // Machine-readable import metadata per post: html-comment, frontmatter or none
+
+	MentionMode string // How mentions are rendered: bold, escape, github-mention or strip
+
+	SplitThreadPosts int // Split threads with more posts into linked "Part N" discussions (0 = never)
+
+	EstimateMode          string // How the dry-run estimate counts attachments: quick, sample or full
+	EstimateSampleThreads int    // Threads whose posts are fetched by the sample mode
+	ConfirmEstimate       bool   // Show the estimate and ask for confirmation before migrating
+
+	ThreadStats         bool   // Append a thread stats line to the opening post
+	ThreadStatsTemplate string // Stats line template with {date}, {replies} and {views} placeholders
+
+	// Safety valve: when more than FailureThresholdPercent of the first
+	// FailureThresholdThreads threads fail, the rest of the run becomes a dry run.
+	FailureThresholdThreads int // Threads evaluated before deciding (0 = disabled)
+	FailureThresholdPercent int // Maximum tolerated failure rate in percent
 }
 
 // FilesystemConfig contains settings for file attachment handling.
@@ -57,38 +177,280 @@ type MigrationConfig struct {
 type FilesystemConfig struct {
 	AttachmentsDir           string        // Directory for storing downloaded attachments
 	AttachmentRateLimitDelay time.Duration // Delay between attachment downloads
+	AttachmentFilename       string        // Template for attachment filenames with {{.ID}}, {{.Name}} and {{.Ext}}
+
+	AttachmentTarget       string // Where attachments are stored: local, repo-path, lfs or assets-branch (empty = assets-branch with an upload repository, local otherwise)
+	AttachmentUploadRepo   string // Repository ("owner/repo") to upload attachments to (empty = the migration repository, or local links without a target)
+	AttachmentUploadBranch string // Branch receiving uploaded attachments (created if missing)
+	AttachmentUploadPath   string // Directory inside the repository for uploaded attachments
+	BatchAttachmentUploads bool   // Commit all attachments of a thread in a single commit
+	ImageMaxWidth          int    // Uploaded JPEG and PNG images wider than this are downsized (0 = disabled)
+	ImageQuality           int    // JPEG quality of downsized images (1-100)
+
+	MaxInlineImageSize int64 // Largest data URI image saved, in bytes (0 = unlimited)
+
+	MaxAttachmentSize int64    // Largest attachment or author avatar downloaded, in bytes (0 = unlimited); larger attachments link to the forum
+	AllowedExtensions []string // Attachment extensions downloaded (empty = all)
+	BlockedExtensions []string // Attachment extensions never downloaded
+
+	MaxDownloadBytesPerSec int64 // Combined throughput cap for attachment and avatar downloads (0 = unlimited)
 }
 
+// DefaultMaxInlineImageSize is the default size limit for data URI images.
+const DefaultMaxInlineImageSize = 5 << 20
+
+// DefaultThreadStatsTemplate is the default opening post stats line.
+const DefaultThreadStatsTemplate = "Originally posted {date} · {replies} replies · {views} views"
+
+// Reaction migration modes.
+const (
+	ReactionsNone    = "none"    // Reactions are not migrated
+	ReactionsSummary = "summary" // A summary line is appended to each post
+	ReactionsGitHub  = "github"  // Each reaction type is added once by the token user
+)
+
+// Estimate modes, trading dry-run time for accurate attachment counts.
+const (
+	EstimateQuick  = "quick"  // Attachments are assumed on 10% of the posts
+	EstimateSample = "sample" // The posts of EstimateSampleThreads threads are fetched and extrapolated
+	EstimateFull   = "full"   // The posts of every thread are fetched
+)
+
+// DefaultEstimateSampleThreads is the default number of threads sampled.
+const DefaultEstimateSampleThreads = 50
+
+// Thread states selected by the FilterState setting.
+const (
+	ThreadsOpen   = "open"   // Threads open to new replies
+	ThreadsClosed = "closed" // Threads closed to new replies
+)
+
+// Thread orders selected by the MigrationOrder setting.
+const (
+	OrderListing      = "listing"       // The order the forum lists threads in
+	OrderOldestFirst  = "oldest-first"  // By creation date, oldest first
+	OrderNewestFirst  = "newest-first"  // By creation date, newest first
+	OrderLastActivity = "last-activity" // By latest post, least recently active first
+)
+
+// ParseFilterDate parses a thread filter date, either a day (YYYY-MM-DD, in
+// UTC) or an RFC 3339 time. dateOnly reports a day.
+func ParseFilterDate(value string) (t time.Time, dateOnly bool, err error) {
+	if t, err := time.Parse(time.DateOnly, value); err == nil {
+		return t, true, nil
+	}
+	t, err = time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, false, fmt.Errorf("invalid date %q (expected YYYY-MM-DD or RFC 3339)", value)
+	}
+	return t, false, nil
+}
+
+// DefaultServeAddr is the address the serve command listens on by default.
+const DefaultServeAddr = ":8080"
+
+// Attachment targets, where downloaded attachments are stored.
+const (
+	AttachmentTargetLocal        = "local"         // Keep local files and links
+	AttachmentTargetRepoPath     = "repo-path"     // Commit to a path on the repository's default branch
+	AttachmentTargetLFS          = "lfs"           // Commit Git LFS pointers to the upload branch
+	AttachmentTargetAssetsBranch = "assets-branch" // Commit to a dedicated upload branch
+)
+
+// DefaultAttachmentFilename names attachments "attachment_<id>_<filename>".
+const DefaultAttachmentFilename = "attachment_{{.ID}}_{{.Name}}{{.Ext}}"
+
+// DefaultImageQuality is the default JPEG quality of downsized images.
+const DefaultImageQuality = 85
+
+// DefaultCacheTTL is how long cached XenForo listings are used by default.
+const DefaultCacheTTL = 24 * time.Hour
+
+// DefaultAutoRetryWait is the default pause before retrying failed threads.
+const DefaultAutoRetryWait = 2 * time.Minute
+
 // New creates a new Config with default values populated from environment variables.
 // Falls back to placeholder values if environment variables are not set.
 func New() *Config {
-	return &Config{
+	cfg := &Config{
 		XenForo: XenForoConfig{
 			APIURL:  getEnvOrDefault("XENFORO_API_URL", "https://your-forum.com/api"),
 			APIKey:  getEnvOrDefault("XENFORO_API_KEY", "your_xenforo_api_key"),
 			APIUser: getEnvOrDefault("XENFORO_API_USER", "1"),
 			NodeID:  getEnvIntOrDefault("XENFORO_NODE_ID", 1),
+			WebURL:  getEnvOrDefault("XENFORO_WEB_URL", ""),
+
+			Source:      getEnvOrDefault("SOURCE", SourceAPI),
+			DatabaseDSN: getEnvOrDefault("XENFORO_DB_DSN", ""),
+			ExportFile:  getEnvOrDefault("EXPORT_FILE", ""),
+			DataDir:     getEnvOrDefault("XENFORO_DATA_DIR", ""),
+
+			CacheDir: getEnvOrDefault("XENFORO_CACHE_DIR", ""),
+			CacheTTL: getEnvDurationOrDefault("XENFORO_CACHE_TTL", DefaultCacheTTL),
+
+			WebhookSecret: getEnvOrDefault("XENFORO_WEBHOOK_SECRET", ""),
 		},
 		GitHub: GitHubConfig{
 			Token:                getEnvOrDefault("GITHUB_TOKEN", "your_github_token"),
+			AppID:                getEnvIntOrDefault("GITHUB_APP_ID", 0),
+			AppInstallationID:    getEnvIntOrDefault("GITHUB_APP_INSTALLATION_ID", 0),
+			AppPrivateKeyFile:    getEnvOrDefault("GITHUB_APP_PRIVATE_KEY_FILE", ""),
 			Repository:           getEnvOrDefault("GITHUB_REPO", "your_username/your_repo"),
-			Categories:           make(map[int]string),
+			Categories:           getEnvNodeMap("CATEGORY_MAP"),
 			XenForoNodeID:        getEnvIntOrDefault("XENFORO_NODE_ID", 1),
 			GitHubCategoryID:     getEnvOrDefault("GITHUB_CATEGORY_ID", "DIC_kwDOxxxxxxxx"),
 			RateLimitDelay:       getEnvDurationOrDefault("GITHUB_RATE_LIMIT_DELAY", 1*time.Second),
 			MaxRetries:           getEnvIntOrDefault("GITHUB_MAX_RETRIES", 5),
 			RetryBackoffMultiple: getEnvIntOrDefault("GITHUB_RETRY_BACKOFF_MULTIPLE", 2),
+			CommentBatchSize:     getEnvIntOrDefault("COMMENT_BATCH_SIZE", 1),
+			NodeTitlePrefix:      getEnvNodeMap("NODE_TITLE_PREFIX"),
+			PrefixCategoryMap:    getEnvStringMap("PREFIX_CATEGORY_MAP"),
+			PrefixLabelMap:       getEnvStringMap("PREFIX_LABEL_MAP"),
 		},
 		Migration: MigrationConfig{
 			MaxRetries:   getEnvIntOrDefault("MAX_RETRIES", 3),
 			ProgressFile: getEnvOrDefault("PROGRESS_FILE", "migration_progress.json"),
-			UserMapping:  make(map[int]int),
+			PauseFile:    getEnvOrDefault("PAUSE_FILE", "migration.pause"),
+			WebhookURL:   getEnvOrDefault("WEBHOOK_URL", ""),
+
+			ProgressBackend:    getEnvOrDefault("PROGRESS_BACKEND", progress.BackendJSON),
+			ProgressBucketSize: getEnvIntOrDefault("PROGRESS_BUCKET_SIZE", 0),
+
+			RequireEmptyCategory:  getEnvBoolOrDefault("REQUIRE_EMPTY_CATEGORY", false),
+			AllowNonEmptyCategory: getEnvBoolOrDefault("ALLOW_NONEMPTY_CATEGORY", false),
+			RepairMappings:        getEnvBoolOrDefault("REPAIR_MAPPINGS", false),
+			DetectDuplicates:      getEnvBoolOrDefault("DETECT_DUPLICATES", false),
+			ResumeListing:         getEnvBoolOrDefault("RESUME_THREAD_LISTING", false),
+
+			UserMapping:       getEnvNodeMap("USER_MAPPING"),
+			InviteMappedUsers: getEnvBoolOrDefault("INVITE_MAPPED_USERS", false),
+
+			RedirectsFile: getEnvOrDefault("REDIRECTS_FILE", ""),
+			NginxMapFile:  getEnvOrDefault("NGINX_MAP_FILE", ""),
+
+			MarkSolutions:     getEnvBoolOrDefault("MARK_SOLUTIONS", true),
+			LockClosedThreads: getEnvBoolOrDefault("LOCK_CLOSED_THREADS", true),
+
+			ReactionsMode: getEnvOrDefault("REACTIONS_MODE", ReactionsNone),
+			ReactionEmoji: getEnvNodeMap("REACTION_EMOJI"),
+
+			AutoRetryFailed: getEnvBoolOrDefault("AUTO_RETRY_FAILED", false),
+			AutoRetryWait:   getEnvDurationOrDefault("AUTO_RETRY_WAIT", DefaultAutoRetryWait),
+			RetryFailed:     getEnvBoolOrDefault("RETRY_FAILED", false),
+
+			SyncInterval: getEnvDurationOrDefault("SYNC_INTERVAL", 0),
+			ServeAddr:    getEnvOrDefault("SERVE_ADDR", DefaultServeAddr),
+
+			MigrationConcurrency: getEnvIntOrDefault("MIGRATION_CONCURRENCY", 1),
+			AttachmentWorkers:    getEnvIntOrDefault("ATTACHMENT_WORKERS", 4),
+			MaxConcurrency:       getEnvIntOrDefault("MAX_CONCURRENCY", 8),
+
+			FilterSince:      getEnvOrDefault("THREAD_SINCE", ""),
+			FilterUntil:      getEnvOrDefault("THREAD_UNTIL", ""),
+			FilterMinReplies: getEnvIntOrDefault("THREAD_MIN_REPLIES", 0),
+			FilterPrefixes:   getEnvList("THREAD_PREFIXES"),
+			FilterAuthors:    getEnvList("THREAD_AUTHORS"),
+			FilterState:      getEnvOrDefault("THREAD_STATE", ""),
+			FilterThreads:    getEnvOrDefault("THREAD_IDS", ""),
+			ExcludeThreads:   getEnvOrDefault("EXCLUDE_THREAD_IDS", ""),
+
+			MigrationOrder: getEnvOrDefault("MIGRATION_ORDER", OrderListing),
+
+			TopReplyCallout: getEnvBoolOrDefault("TOP_REPLY_CALLOUT", false),
+			SinceLastRun:    getEnvBoolOrDefault("SINCE_LAST_RUN", false),
+			PostAnchors:     getEnvBoolOrDefault("POST_ANCHORS", false),
+			AuthorAvatars:   getEnvBoolOrDefault("AUTHOR_AVATARS", false),
+
+			PreserveAlignment: getEnvBoolOrDefault("PRESERVE_ALIGNMENT", true),
+			StripSignatures:   getEnvBoolOrDefault("STRIP_SIGNATURES", false),
+			SignaturePattern:  getEnvOrDefault("SIGNATURE_PATTERN", ""),
+
+			SmileyEmoji: getEnvBoolOrDefault("SMILEY_EMOJI", false),
+			SmileyMap:   getEnvStringMap("SMILEY_MAP"),
+
+			VideoThumbnails: getEnvBoolOrDefault("VIDEO_THUMBNAILS", false),
+			MediaSites:      getEnvStringMap("MEDIA_SITES"),
+
+			LegacyConverter: getEnvBoolOrDefault("LEGACY_CONVERTER", false),
+			ConvertHTML:     getEnvBoolOrDefault("CONVERT_HTML", false),
+
+			Locale:            getEnvOrDefault("LOCALE", bbcode.DefaultLocale),
+			FrontmatterLabels: getEnvStringMap("FRONTMATTER_LABELS"),
+			HeaderStyle:       getEnvOrDefault("HEADER_STYLE", string(bbcode.HeaderFrontmatter)),
+
+			MetadataFormat: getEnvOrDefault("METADATA_FORMAT", string(bbcode.MetadataHTMLComment)),
+
+			MentionMode: getEnvOrDefault("MENTION_MODE", string(bbcode.MentionBold)),
+
+			SplitThreadPosts: getEnvIntOrDefault("SPLIT_THREAD_POSTS", 0),
+
+			EstimateMode:          getEnvOrDefault("ESTIMATE_MODE", EstimateQuick),
+			EstimateSampleThreads: getEnvIntOrDefault("ESTIMATE_SAMPLE_THREADS", DefaultEstimateSampleThreads),
+			ConfirmEstimate:       getEnvBoolOrDefault("CONFIRM_ESTIMATE", false),
+
+			ThreadStats:         getEnvBoolOrDefault("THREAD_STATS", false),
+			ThreadStatsTemplate: getEnvOrDefault("THREAD_STATS_TEMPLATE", DefaultThreadStatsTemplate),
+
+			FailureThresholdThreads: getEnvIntOrDefault("FAILURE_THRESHOLD_THREADS", 0),
+			FailureThresholdPercent: getEnvIntOrDefault("FAILURE_THRESHOLD_PERCENT", 50),
 		},
 		Filesystem: FilesystemConfig{
 			AttachmentsDir:           getEnvOrDefault("ATTACHMENTS_DIR", "./attachments"),
 			AttachmentRateLimitDelay: getEnvDurationOrDefault("ATTACHMENT_RATE_LIMIT_DELAY", 500*time.Millisecond),
+			AttachmentFilename:       getEnvOrDefault("ATTACHMENT_FILENAME_TEMPLATE", DefaultAttachmentFilename),
+
+			AttachmentTarget:       getEnvOrDefault("ATTACHMENT_TARGET", ""),
+			AttachmentUploadRepo:   getEnvOrDefault("ATTACHMENT_UPLOAD_REPO", ""),
+			AttachmentUploadBranch: getEnvOrDefault("ATTACHMENT_UPLOAD_BRANCH", "forum-assets"),
+			AttachmentUploadPath:   getEnvOrDefault("ATTACHMENT_UPLOAD_PATH", "attachments"),
+			BatchAttachmentUploads: getEnvBoolOrDefault("BATCH_ATTACHMENT_UPLOADS", false),
+			ImageMaxWidth:          getEnvIntOrDefault("IMAGE_MAX_WIDTH", 0),
+			ImageQuality:           getEnvIntOrDefault("IMAGE_QUALITY", DefaultImageQuality),
+
+			MaxInlineImageSize: int64(getEnvIntOrDefault("MAX_INLINE_IMAGE_SIZE", DefaultMaxInlineImageSize)),
+
+			MaxAttachmentSize: int64(getEnvIntOrDefault("MAX_ATTACHMENT_SIZE", 0)),
+			AllowedExtensions: getEnvList("ALLOWED_EXTENSIONS"),
+			BlockedExtensions: getEnvList("BLOCKED_EXTENSIONS"),
+
+			MaxDownloadBytesPerSec: int64(getEnvIntOrDefault("MAX_DOWNLOAD_BYTES_PER_SEC", 0)),
 		},
 	}
+	cfg.GitHub.GitHubCategoryID = mappedCategory(cfg.GitHub.Categories, cfg.GitHub.XenForoNodeID, cfg.GitHub.GitHubCategoryID)
+	return cfg
+}
+
+// AttachmentHosting returns where attachments are stored and the repository
+// they are uploaded to, which is empty for local attachments. Without an
+// explicit ATTACHMENT_TARGET, attachments go to the upload branch when an
+// upload repository is set and stay local otherwise; other targets upload
+// to the migration repository unless an upload repository is set.
+func (c *Config) AttachmentHosting() (string, string) {
+	target := c.Filesystem.AttachmentTarget
+	if target == "" {
+		target = AttachmentTargetLocal
+		if c.Filesystem.AttachmentUploadRepo != "" {
+			target = AttachmentTargetAssetsBranch
+		}
+	}
+	if target == AttachmentTargetLocal {
+		return target, ""
+	}
+
+	repo := c.Filesystem.AttachmentUploadRepo
+	if repo == "" {
+		repo = c.GitHub.Repository
+	}
+	return target, repo
+}
+
+// mappedCategory returns the category mapped to the node by CATEGORY_MAP
+// unless GITHUB_CATEGORY_ID is set, and the given category otherwise.
+func mappedCategory(categories map[int]string, nodeID int, categoryID string) string {
+	if category, ok := categories[nodeID]; ok && os.Getenv("GITHUB_CATEGORY_ID") == "" {
+		return category
+	}
+	return categoryID
 }
 
 func getEnvOrDefault(key, defaultValue string) string {
@@ -107,6 +469,70 @@ func getEnvIntOrDefault(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvBoolOrDefault(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
+// getEnvNodeMap parses "nodeID=value" pairs separated by commas, e.g.
+// "2=[General],5=[Support]". Malformed pairs are ignored.
+func getEnvNodeMap(key string) map[int]string {
+	result := make(map[int]string)
+	for _, pair := range strings.Split(os.Getenv(key), ",") {
+		id, value, found := strings.Cut(pair, "=")
+		if !found {
+			continue
+		}
+		nodeID, err := strconv.Atoi(strings.TrimSpace(id))
+		if err != nil || nodeID <= 0 {
+			continue
+		}
+		result[nodeID] = strings.TrimSpace(value)
+	}
+	return result
+}
+
+// getEnvStringMap parses "key=value" pairs separated by commas, e.g.
+// "smilies/smile.png=:),smilies/frown.png=:(". Pairs without a key are ignored.
+func getEnvStringMap(key string) map[string]string {
+	return parseStringMap(os.Getenv(key))
+}
+
+// parseStringMap parses comma-separated "name=value" pairs.
+func parseStringMap(value string) map[string]string {
+	result := make(map[string]string)
+	for _, pair := range strings.Split(value, ",") {
+		name, value, found := strings.Cut(pair, "=")
+		name = strings.TrimSpace(name)
+		if !found || name == "" {
+			continue
+		}
+		result[name] = strings.TrimSpace(value)
+	}
+	return result
+}
+
+// getEnvList parses a comma-separated list, e.g. "png,jpg,pdf". Empty items
+// are ignored.
+func getEnvList(key string) []string {
+	return SplitList(os.Getenv(key))
+}
+
+// SplitList parses a comma-separated list, ignoring empty items.
+func SplitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
 func getEnvDurationOrDefault(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
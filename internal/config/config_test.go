@@ -2,6 +2,8 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"reflect"
 	"testing"
 	"time"
 )
@@ -37,10 +39,14 @@ func TestConfigEnvironmentVariables(t *testing.T) {
 	if err := os.Setenv("ATTACHMENT_RATE_LIMIT_DELAY", "1s"); err != nil {
 		t.Fatal(err)
 	}
+	if err := os.Setenv("BLOCKED_EXTENSIONS", " exe, ,.bat"); err != nil {
+		t.Fatal(err)
+	}
 	defer func() {
 		_ = os.Unsetenv("XENFORO_API_URL")
 		_ = os.Unsetenv("MAX_RETRIES")
 		_ = os.Unsetenv("ATTACHMENT_RATE_LIMIT_DELAY")
+		_ = os.Unsetenv("BLOCKED_EXTENSIONS")
 	}()
 
 	cfg := New()
@@ -56,6 +62,10 @@ func TestConfigEnvironmentVariables(t *testing.T) {
 	if cfg.Filesystem.AttachmentRateLimitDelay != 1*time.Second {
 		t.Error("Environment variable for attachment rate limit delay not used")
 	}
+
+	if !reflect.DeepEqual(cfg.Filesystem.BlockedExtensions, []string{"exe", ".bat"}) {
+		t.Errorf("Expected blocked extensions [exe .bat], got %q", cfg.Filesystem.BlockedExtensions)
+	}
 }
 
 func TestConfigValidation(t *testing.T) {
@@ -98,6 +108,246 @@ func TestConfigValidation(t *testing.T) {
 			},
 			shouldErr: true,
 		},
+		{
+			name: "Missing frontmatter label",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.Locale = "fr"
+				cfg.Migration.FrontmatterLabels = map[string]string{"author": ""}
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Invalid user mapping",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.UserMapping = map[int]string{12: "not a login"}
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Empty prefix label",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.GitHub.PrefixLabelMap = map[string]string{"Bug": ""}
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Unknown reactions mode",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.ReactionsMode = "likes"
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Reactions from the REST API",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.ReactionsMode = ReactionsSummary
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Thread filter with an invalid date",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.FilterSince = "01/02/2020"
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Thread filter until before since",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.FilterSince = "2021-01-01"
+				cfg.Migration.FilterUntil = "2020-12-31"
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Unknown thread state",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.FilterState = "archived"
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Unknown migration order",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.MigrationOrder = "random"
+			},
+			shouldErr: true,
+		},
+		{
+			name: "GitHub App instead of token",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = ""
+				cfg.GitHub.AppID = 123456
+				cfg.GitHub.AppInstallationID = 12345678
+				cfg.GitHub.AppPrivateKeyFile = "github_app.pem"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+			},
+			shouldErr: false,
+		},
+		{
+			name: "GitHub App without installation",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.AppID = 123456
+				cfg.GitHub.AppPrivateKeyFile = "github_app.pem"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Unknown estimate mode",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.EstimateMode = "exact"
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Database source without API settings",
+			setup: func(cfg *Config) {
+				cfg.XenForo.Source = SourceDB
+				cfg.XenForo.DatabaseDSN = "user:pass@tcp(localhost:3306)/xenforo"
+				cfg.XenForo.WebURL = "https://forum.example.com"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+			},
+			shouldErr: false,
+		},
+		{
+			name: "Database source without web URL",
+			setup: func(cfg *Config) {
+				cfg.XenForo.Source = SourceDB
+				cfg.XenForo.DatabaseDSN = "user:pass@tcp(localhost:3306)/xenforo"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+			},
+			shouldErr: true,
+		},
+		{
+			name: "phpBB source without export file",
+			setup: func(cfg *Config) {
+				cfg.XenForo.Source = SourcePhpBB
+				cfg.XenForo.WebURL = "https://forum.example.com"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+			},
+			shouldErr: true,
+		},
+		{
+			name: "Duplicate detection without thread marker",
+			setup: func(cfg *Config) {
+				cfg.XenForo.APIURL = "https://forum.example.com/api"
+				cfg.XenForo.APIKey = "valid_key"
+				cfg.XenForo.APIUser = "1"
+				cfg.XenForo.NodeID = 1
+				cfg.GitHub.Token = "valid_token"
+				cfg.GitHub.Repository = "owner/repo"
+				cfg.GitHub.XenForoNodeID = 1
+				cfg.GitHub.GitHubCategoryID = "DIC_kwDOtest123"
+				cfg.Migration.HeaderStyle = "byline"
+				cfg.Migration.DetectDuplicates = true
+			},
+			shouldErr: true,
+		},
 		{
 			name: "No category mappings",
 			setup: func(cfg *Config) {
@@ -130,3 +380,85 @@ func TestConfigValidation(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigNodeTitlePrefix(t *testing.T) {
+	if err := os.Setenv("NODE_TITLE_PREFIX", "2=[General], 5=[Support],bad,0=[Zero]"); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = os.Unsetenv("NODE_TITLE_PREFIX") }()
+
+	cfg := New()
+
+	expected := map[int]string{2: "[General]", 5: "[Support]"}
+	if len(cfg.GitHub.NodeTitlePrefix) != len(expected) {
+		t.Fatalf("Expected %d prefixes, got %v", len(expected), cfg.GitHub.NodeTitlePrefix)
+	}
+	for nodeID, prefix := range expected {
+		if cfg.GitHub.NodeTitlePrefix[nodeID] != prefix {
+			t.Errorf("Expected prefix %q for node %d, got %q", prefix, nodeID, cfg.GitHub.NodeTitlePrefix[nodeID])
+		}
+	}
+}
+
+func TestAttachmentHosting(t *testing.T) {
+	tests := []struct {
+		name       string
+		target     string
+		uploadRepo string
+		expected   string
+		repo       string
+	}{
+		{name: "Local by default", expected: "local"},
+		{name: "Assets branch with an upload repository", uploadRepo: "owner/assets", expected: "assets-branch", repo: "owner/assets"},
+		{name: "Explicit local ignores the upload repository", target: "local", uploadRepo: "owner/assets", expected: "local"},
+		{name: "Migration repository by default", target: "lfs", expected: "lfs", repo: "owner/discussions"},
+		{name: "Repository path in the upload repository", target: "repo-path", uploadRepo: "owner/assets", expected: "repo-path", repo: "owner/assets"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &Config{}
+			cfg.GitHub.Repository = "owner/discussions"
+			cfg.Filesystem.AttachmentTarget = tt.target
+			cfg.Filesystem.AttachmentUploadRepo = tt.uploadRepo
+
+			target, repo := cfg.AttachmentHosting()
+			if target != tt.expected || repo != tt.repo {
+				t.Errorf("Expected %q in %q, got %q in %q", tt.expected, tt.repo, target, repo)
+			}
+		})
+	}
+}
+
+func TestReadThreadIDs(t *testing.T) {
+	ids, err := ReadThreadIDs("100, 105,200-300")
+	if err != nil {
+		t.Fatalf("ReadThreadIDs returned error: %v", err)
+	}
+	for threadID, expected := range map[int]bool{100: true, 101: false, 105: true, 199: false, 200: true, 250: true, 300: true, 301: false} {
+		if ids.Contains(threadID) != expected {
+			t.Errorf("Expected Contains(%d) = %v", threadID, expected)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "threads.txt")
+	if err := os.WriteFile(path, []byte("# botched threads\n12\n40-42 # split wrongly\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ids, err = ReadThreadIDs(path)
+	if err != nil {
+		t.Fatalf("ReadThreadIDs returned error for a file: %v", err)
+	}
+	if !ids.Contains(12) || !ids.Contains(41) || ids.Contains(43) {
+		t.Errorf("Unexpected thread IDs from file: %v", ids)
+	}
+
+	for _, invalid := range []string{"12,abc", "0", "300-200", "missing-file.txt"} {
+		if _, err := ReadThreadIDs(invalid); err == nil {
+			t.Errorf("Expected an error for %q", invalid)
+		}
+	}
+	if ids, err := ReadThreadIDs(""); err != nil || ids != nil {
+		t.Errorf("Expected no thread IDs for an empty value, got %v, %v", ids, err)
+	}
+}
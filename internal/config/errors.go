@@ -107,12 +107,17 @@ func IsValidationError(err error) bool {
 	return errors.As(err, &validationErr)
 }
 
-// GetConfigurationField extracts the field name from a configuration error.
+// GetConfigurationField extracts the field name from a configuration error or
+// a Validate error.
 func GetConfigurationField(err error) string {
 	var configErr *ConfigurationError
 	if errors.As(err, &configErr) {
 		return configErr.Field
 	}
+	var fieldErr *fieldError
+	if errors.As(err, &fieldErr) {
+		return fieldErr.field
+	}
 	return ""
 }
 
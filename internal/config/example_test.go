@@ -127,7 +127,7 @@ func ExampleMigrationConfig() {
 		Verbose:      true, // Detailed logging
 		ResumeFrom:   0,    // Start from beginning
 		ProgressFile: "./migration_progress.json",
-		UserMapping:  map[int]int{1: 101, 2: 102}, // Map old user IDs to new ones
+		UserMapping:  map[int]string{1: "octocat", 2: "hubot"}, // Mention forum users' GitHub accounts
 	}
 
 	fmt.Printf("Dry run mode: %t\n", migrationConfig.DryRun)
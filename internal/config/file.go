@@ -0,0 +1,331 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// EnvConfigFile names the config file loaded when --config is not given.
+const EnvConfigFile = "CONFIG_FILE"
+
+// DefaultConfigFile is the file written by "config init".
+const DefaultConfigFile = "config.yaml"
+
+// fileSetting maps a config file key to the environment variable it stands
+// in for. Config files set the environment variables that are not set
+// already, so every setting keeps a single source of truth in New.
+type fileSetting struct {
+	section string
+	key     string
+	env     string
+	value   string // Written by the template; settings without one are commented out
+	example string // Commented-out example of a setting without a value
+	isMap   bool   // Nested "key: value" pairs, passed on as "key=value,..."
+	comment string
+}
+
+var fileSettings = []fileSetting{
+	{section: "xenforo", key: "api_url", env: "XENFORO_API_URL", value: "https://your-forum.com/api"},
+	{section: "xenforo", key: "api_key", env: "XENFORO_API_KEY", example: "your_xenforo_api_key", comment: "prompted for when not set"},
+	{section: "xenforo", key: "api_key_file", env: "XENFORO_API_KEY_FILE", example: "/run/secrets/xenforo_api_key", comment: "read the API key from this file instead"},
+	{section: "xenforo", key: "api_user", env: "XENFORO_API_USER", value: "1"},
+	{section: "xenforo", key: "node_id", env: "XENFORO_NODE_ID", value: "1", comment: "forum node to migrate"},
+	{section: "xenforo", key: "web_url", env: "XENFORO_WEB_URL", example: "https://your-forum.com", comment: "public forum URL for member and attachment links"},
+	{section: "xenforo", key: "source", env: "SOURCE", value: "api", comment: "api, db to read the forum database directly, or phpbb"},
+	{section: "xenforo", key: "db_dsn", env: "XENFORO_DB_DSN", example: "user:password@tcp(localhost:3306)/xenforo", comment: "MySQL DSN for the db source"},
+	{section: "xenforo", key: "export_file", env: "EXPORT_FILE", example: "phpbb-export.json", comment: "board export for the phpbb source"},
+	{section: "xenforo", key: "data_dir", env: "XENFORO_DATA_DIR", example: "/var/www/forum/internal_data", comment: "copy attachment files from here with the db or phpbb source"},
+	{section: "xenforo", key: "cache_dir", env: "XENFORO_CACHE_DIR", example: ".xenforo-cache", comment: "cache thread and post listings of the api source here"},
+	{section: "xenforo", key: "cache_ttl", env: "XENFORO_CACHE_TTL", value: DefaultCacheTTL.String(), comment: "use cached listings this long without asking the forum"},
+	{section: "xenforo", key: "webhook_secret", env: "XENFORO_WEBHOOK_SECRET", example: "your_webhook_secret", comment: "secret of the webhooks received by the serve command"},
+
+	{section: "github", key: "token", env: "GITHUB_TOKEN", example: "your_github_token", comment: "prompted for when not set"},
+	{section: "github", key: "token_file", env: "GITHUB_TOKEN_FILE", example: "/run/secrets/github_token", comment: "read the token from this file instead"},
+	{section: "github", key: "app_id", env: "GITHUB_APP_ID", example: "123456", comment: "authenticate as this GitHub App instead of the token"},
+	{section: "github", key: "app_installation_id", env: "GITHUB_APP_INSTALLATION_ID", example: "12345678", comment: "installation of the app on the repository owner"},
+	{section: "github", key: "app_private_key_file", env: "GITHUB_APP_PRIVATE_KEY_FILE", example: "/run/secrets/github_app.pem", comment: "private key of the app"},
+	{section: "github", key: "repository", env: "GITHUB_REPO", value: "owner/repository"},
+	{section: "github", key: "category_id", env: "GITHUB_CATEGORY_ID", example: "DIC_kwDOxxxxxxxx", comment: "discussion category of node_id; overrides categories"},
+	{section: "github", key: "categories", env: "CATEGORY_MAP", example: "2: DIC_kwDOxxxxxxxx", isMap: true, comment: "discussion category per forum node"},
+	{section: "github", key: "rate_limit_delay", env: "GITHUB_RATE_LIMIT_DELAY", value: "1s", comment: "minimum delay between GitHub API calls"},
+	{section: "github", key: "max_retries", env: "GITHUB_MAX_RETRIES", value: "5", comment: "retries of rate limited requests"},
+	{section: "github", key: "retry_backoff_multiple", env: "GITHUB_RETRY_BACKOFF_MULTIPLE", value: "2", comment: "exponential backoff multiplier (seconds)"},
+	{section: "github", key: "comment_batch_size", env: "COMMENT_BATCH_SIZE", value: "1", comment: "comments created per request (1 = one request per comment)"},
+	{section: "github", key: "node_title_prefix", env: "NODE_TITLE_PREFIX", example: "2: \"[General]\"", isMap: true, comment: "discussion title prefix per forum node"},
+	{section: "github", key: "prefix_categories", env: "PREFIX_CATEGORY_MAP", example: "Bug: DIC_kwDObugs", isMap: true, comment: "category per thread prefix title or ID"},
+	{section: "github", key: "prefix_labels", env: "PREFIX_LABEL_MAP", example: "Bug: bug", isMap: true, comment: "repository label per thread prefix title or ID"},
+
+	{section: "migration", key: "max_retries", env: "MAX_RETRIES", value: "3"},
+	{section: "migration", key: "progress_backend", env: "PROGRESS_BACKEND", value: "json", comment: "json, or sqlite for a database next to the progress file"},
+	{section: "migration", key: "progress_bucket_size", env: "PROGRESS_BUCKET_SIZE", value: "0", comment: "shard the progress file by this many thread IDs (0 = single file)"},
+	{section: "migration", key: "pause_file", env: "PAUSE_FILE", value: "migration.pause", comment: "pause between threads while this file exists"},
+	{section: "migration", key: "webhook_url", env: "WEBHOOK_URL", example: "https://hooks.example.com/migration", comment: "POST a JSON run summary here"},
+	{section: "migration", key: "log_format", env: "LOG_FORMAT", value: "text", comment: "text or json"},
+	{section: "migration", key: "require_empty_category", env: "REQUIRE_EMPTY_CATEGORY", value: "false"},
+	{section: "migration", key: "allow_nonempty_category", env: "ALLOW_NONEMPTY_CATEGORY", value: "false"},
+	{section: "migration", key: "repair_mappings", env: "REPAIR_MAPPINGS", value: "false"},
+	{section: "migration", key: "detect_duplicates", env: "DETECT_DUPLICATES", value: "false"},
+	{section: "migration", key: "resume_thread_listing", env: "RESUME_THREAD_LISTING", value: "false"},
+	{section: "migration", key: "since_last_run", env: "SINCE_LAST_RUN", value: "false"},
+	{section: "migration", key: "thread_since", env: "THREAD_SINCE", example: "2020-01-01", comment: "only threads created on or after this date"},
+	{section: "migration", key: "thread_until", env: "THREAD_UNTIL", example: "2023-12-31", comment: "only threads created on or before this date"},
+	{section: "migration", key: "thread_min_replies", env: "THREAD_MIN_REPLIES", value: "0", comment: "only threads with at least this many replies"},
+	{section: "migration", key: "thread_prefixes", env: "THREAD_PREFIXES", example: "Solved,12", comment: "only threads with these prefix titles or IDs"},
+	{section: "migration", key: "thread_authors", env: "THREAD_AUTHORS", example: "alice,bob", comment: "only threads started by these users"},
+	{section: "migration", key: "thread_state", env: "THREAD_STATE", example: "open", comment: "only open or closed threads"},
+	{section: "migration", key: "thread_ids", env: "THREAD_IDS", example: "100,105,200-300", comment: "only these thread IDs and ranges, or a file listing them"},
+	{section: "migration", key: "order", env: "MIGRATION_ORDER", value: OrderListing, comment: "listing, oldest-first, newest-first or last-activity"},
+	{section: "migration", key: "exclude_thread_ids", env: "EXCLUDE_THREAD_IDS", example: "skip-threads.txt", comment: "never these thread IDs and ranges, or a file listing them"},
+	{section: "migration", key: "auto_retry_failed", env: "AUTO_RETRY_FAILED", value: "false"},
+	{section: "migration", key: "auto_retry_wait", env: "AUTO_RETRY_WAIT", value: DefaultAutoRetryWait.String()},
+	{section: "migration", key: "retry_failed", env: "RETRY_FAILED", value: "false", comment: "only migrate the threads recorded as failed"},
+	{section: "migration", key: "sync_interval", env: "SYNC_INTERVAL", value: "0s", comment: "pause between passes of the sync command (0 = a single pass)"},
+	{section: "migration", key: "serve_addr", env: "SERVE_ADDR", value: DefaultServeAddr, comment: "address the serve command listens on for webhooks"},
+	{section: "migration", key: "failure_threshold_threads", env: "FAILURE_THRESHOLD_THREADS", value: "0", comment: "evaluate the failure rate after this many threads (0 = disabled)"},
+	{section: "migration", key: "failure_threshold_percent", env: "FAILURE_THRESHOLD_PERCENT", value: "50"},
+	{section: "migration", key: "split_thread_posts", env: "SPLIT_THREAD_POSTS", value: "0", comment: "split longer threads into \"Part N\" discussions (0 = never)"},
+	{section: "migration", key: "estimate_mode", env: "ESTIMATE_MODE", value: EstimateQuick, comment: "quick, sample or full"},
+	{section: "migration", key: "estimate_sample_threads", env: "ESTIMATE_SAMPLE_THREADS", value: strconv.Itoa(DefaultEstimateSampleThreads)},
+	{section: "migration", key: "confirm_estimate", env: "CONFIRM_ESTIMATE", value: "false"},
+	{section: "migration", key: "user_mapping", env: "USER_MAPPING", example: "12: octocat", isMap: true, comment: "GitHub account per forum user ID"},
+	{section: "migration", key: "invite_mapped_users", env: "INVITE_MAPPED_USERS", value: "false", comment: "read access; organization repositories only"},
+	{section: "migration", key: "redirects_file", env: "REDIRECTS_FILE", example: "redirects.json"},
+	{section: "migration", key: "nginx_map_file", env: "NGINX_MAP_FILE", example: "redirects.map"},
+
+	{section: "content", key: "header_style", env: "HEADER_STYLE", value: "frontmatter", comment: "frontmatter, byline or none"},
+	{section: "content", key: "metadata_format", env: "METADATA_FORMAT", value: "html-comment", comment: "html-comment, frontmatter or none"},
+	{section: "content", key: "mention_mode", env: "MENTION_MODE", value: "bold", comment: "bold, escape, github-mention or strip"},
+	{section: "content", key: "locale", env: "LOCALE", value: "en"},
+	{section: "content", key: "frontmatter_labels", env: "FRONTMATTER_LABELS", example: "author: Written by", isMap: true},
+	{section: "content", key: "post_anchors", env: "POST_ANCHORS", value: "false"},
+	{section: "content", key: "author_avatars", env: "AUTHOR_AVATARS", value: "false"},
+	{section: "content", key: "thread_stats", env: "THREAD_STATS", value: "false"},
+	{section: "content", key: "thread_stats_template", env: "THREAD_STATS_TEMPLATE", value: DefaultThreadStatsTemplate},
+	{section: "content", key: "top_reply_callout", env: "TOP_REPLY_CALLOUT", value: "false"},
+	{section: "content", key: "mark_solutions", env: "MARK_SOLUTIONS", value: "true"},
+	{section: "content", key: "lock_closed_threads", env: "LOCK_CLOSED_THREADS", value: "true"},
+	{section: "content", key: "reactions_mode", env: "REACTIONS_MODE", value: ReactionsNone, comment: "none, summary or github (database source only)"},
+	{section: "content", key: "reaction_emoji", env: "REACTION_EMOJI", example: "7: 🎉", isMap: true, comment: "emoji of custom reaction IDs"},
+	{section: "content", key: "preserve_alignment", env: "PRESERVE_ALIGNMENT", value: "true"},
+	{section: "content", key: "strip_signatures", env: "STRIP_SIGNATURES", value: "false"},
+	{section: "content", key: "signature_pattern", env: "SIGNATURE_PATTERN", example: "^Sent from my"},
+	{section: "content", key: "smiley_emoji", env: "SMILEY_EMOJI", value: "false"},
+	{section: "content", key: "smiley_map", env: "SMILEY_MAP", example: "\"styles/custom/smilies/smile.png\": \":)\"", isMap: true},
+	{section: "content", key: "video_thumbnails", env: "VIDEO_THUMBNAILS", value: "false"},
+	{section: "content", key: "media_sites", env: "MEDIA_SITES", example: "twitch: https://www.twitch.tv/videos/{id}", isMap: true, comment: "item URL per [media] site ID"},
+	{section: "content", key: "legacy_converter", env: "LEGACY_CONVERTER", value: "false"},
+	{section: "content", key: "convert_html", env: "CONVERT_HTML", value: "false"},
+
+	{section: "concurrency", key: "migration_concurrency", env: "MIGRATION_CONCURRENCY", value: "1", comment: "threads migrated in parallel"},
+	{section: "concurrency", key: "attachment_workers", env: "ATTACHMENT_WORKERS", value: "4", comment: "parallel attachment downloads"},
+	{section: "concurrency", key: "max_concurrency", env: "MAX_CONCURRENCY", value: "8", comment: "global cap shared by thread and attachment workers"},
+
+	{section: "attachments", key: "dir", env: "ATTACHMENTS_DIR", value: "./attachments"},
+	{section: "attachments", key: "rate_limit_delay", env: "ATTACHMENT_RATE_LIMIT_DELAY", value: "500ms", comment: "delay between downloads"},
+	{section: "attachments", key: "max_download_bytes_per_sec", env: "MAX_DOWNLOAD_BYTES_PER_SEC", value: "0", comment: "0 = unlimited"},
+	{section: "attachments", key: "filename_template", env: "ATTACHMENT_FILENAME_TEMPLATE", value: "attachment_{{.ID}}_{{.Name}}{{.Ext}}"},
+	{section: "attachments", key: "max_inline_image_size", env: "MAX_INLINE_IMAGE_SIZE", value: strconv.Itoa(DefaultMaxInlineImageSize), comment: "bytes (0 = unlimited)"},
+	{section: "attachments", key: "max_attachment_size", env: "MAX_ATTACHMENT_SIZE", value: "0", comment: "bytes (0 = unlimited); larger attachments link to the forum"},
+	{section: "attachments", key: "allowed_extensions", env: "ALLOWED_EXTENSIONS", example: "png,jpg,gif,pdf", comment: "only download these extensions"},
+	{section: "attachments", key: "blocked_extensions", env: "BLOCKED_EXTENSIONS", example: "exe,bat", comment: "never download these extensions"},
+	{section: "attachments", key: "target", env: "ATTACHMENT_TARGET", example: "assets-branch", comment: "local, repo-path, lfs or assets-branch"},
+	{section: "attachments", key: "upload_repo", env: "ATTACHMENT_UPLOAD_REPO", example: "owner/forum-assets", comment: "host attachments in this repository"},
+	{section: "attachments", key: "upload_branch", env: "ATTACHMENT_UPLOAD_BRANCH", value: "forum-assets"},
+	{section: "attachments", key: "upload_path", env: "ATTACHMENT_UPLOAD_PATH", value: "attachments"},
+	{section: "attachments", key: "batch_uploads", env: "BATCH_ATTACHMENT_UPLOADS", value: "false"},
+	{section: "attachments", key: "image_max_width", env: "IMAGE_MAX_WIDTH", value: "0"},
+	{section: "attachments", key: "image_quality", env: "IMAGE_QUALITY", value: "85"},
+}
+
+func lookupFileSetting(section, key string) (fileSetting, bool) {
+	for _, setting := range fileSettings {
+		if setting.section == section && setting.key == key {
+			return setting, true
+		}
+	}
+	return fileSetting{}, false
+}
+
+func isFileSection(section string) bool {
+	for _, setting := range fileSettings {
+		if setting.section == section {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadFile reads a YAML config file and sets the environment variables of its
+// settings that are not set already, so the file ranks below the environment
+// and command-line flags.
+func LoadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	values, err := parseFile(file)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	for env, value := range values {
+		if os.Getenv(env) != "" {
+			continue
+		}
+		if err := os.Setenv(env, value); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", env, err)
+		}
+	}
+	return nil
+}
+
+// parseFile parses a YAML config file of sections holding "key: value"
+// settings, where map settings hold nested "key: value" entries. Returns the
+// values by environment variable; map values are joined as "key=value,...".
+func parseFile(r io.Reader) (map[string]string, error) {
+	var document yaml.Node
+	if err := yaml.NewDecoder(r).Decode(&document); err != nil {
+		if errors.Is(err, io.EOF) {
+			return map[string]string{}, nil // Empty file
+		}
+		return nil, err
+	}
+
+	values := make(map[string]string)
+	root := document.Content[0]
+	if isNull(root) {
+		return values, nil
+	}
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: expected sections of settings", root.Line)
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		key, body := root.Content[i], root.Content[i+1]
+		section := key.Value
+		if !isNull(body) && body.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %d: %q is not a section (settings are indented under one)", key.Line, section)
+		}
+		if !isFileSection(section) {
+			return nil, fmt.Errorf("line %d: unknown section %q", key.Line, section)
+		}
+		if isNull(body) {
+			continue
+		}
+
+		for j := 0; j < len(body.Content); j += 2 {
+			key, node := body.Content[j], body.Content[j+1]
+			setting, ok := lookupFileSetting(section, key.Value)
+			if !ok {
+				return nil, fmt.Errorf("line %d: unknown setting %s.%s", key.Line, section, key.Value)
+			}
+
+			var value string
+			var err error
+			if setting.isMap {
+				value, err = mapValue(setting, node)
+			} else {
+				value, err = scalarValue(setting, node)
+			}
+			if err != nil {
+				return nil, err
+			}
+			values[setting.env] = value
+		}
+	}
+	return values, nil
+}
+
+// scalarValue returns the value of a setting as written; null is empty.
+func scalarValue(setting fileSetting, node *yaml.Node) (string, error) {
+	if isNull(node) {
+		return "", nil
+	}
+	if node.Kind != yaml.ScalarNode {
+		return "", fmt.Errorf("line %d: %s.%s takes a single value", node.Line, setting.section, setting.key)
+	}
+	return node.Value, nil
+}
+
+// mapValue joins the "key: value" entries of a map setting as
+// "key=value,...".
+func mapValue(setting fileSetting, node *yaml.Node) (string, error) {
+	if isNull(node) {
+		return "", nil
+	}
+	if node.Kind != yaml.MappingNode {
+		return "", fmt.Errorf("line %d: %s.%s takes indented \"key: value\" entries", node.Line, setting.section, setting.key)
+	}
+
+	pairs := make([]string, 0, len(node.Content)/2)
+	for i := 0; i < len(node.Content); i += 2 {
+		key, value := node.Content[i], node.Content[i+1]
+		if key.Kind != yaml.ScalarNode || (value.Kind != yaml.ScalarNode && !isNull(value)) {
+			return "", fmt.Errorf("line %d: %s entries must be \"key: value\"", key.Line, setting.key)
+		}
+		entryValue := value.Value
+		if isNull(value) {
+			entryValue = ""
+		}
+		if strings.ContainsAny(key.Value+entryValue, ",=") {
+			return "", fmt.Errorf("line %d: %s entries cannot contain \",\" or \"=\"", key.Line, setting.key)
+		}
+		pairs = append(pairs, key.Value+"="+entryValue)
+	}
+	return strings.Join(pairs, ","), nil
+}
+
+func isNull(node *yaml.Node) bool {
+	return node.Kind == yaml.ScalarNode && node.Tag == "!!null"
+}
+
+// WriteTemplate writes a commented config file with every setting at its
+// default value. Optional settings without a default are commented out.
+func WriteTemplate(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# XenForo to GitHub Discussions migration settings.\n")
+	b.WriteString("# Environment variables override these values, and command-line flags\n")
+	b.WriteString("# override both. Load with --config or CONFIG_FILE.\n")
+
+	section := ""
+	for _, setting := range fileSettings {
+		if setting.section != section {
+			section = setting.section
+			fmt.Fprintf(&b, "\n%s:\n", section)
+		}
+
+		fmt.Fprintf(&b, "  # %s", setting.env)
+		if setting.comment != "" {
+			fmt.Fprintf(&b, ": %s", setting.comment)
+		}
+		b.WriteString("\n")
+
+		switch {
+		case setting.isMap:
+			fmt.Fprintf(&b, "  %s:\n  #   %s\n", setting.key, setting.example)
+		case setting.value != "":
+			fmt.Fprintf(&b, "  %s: %s\n", setting.key, quoteValue(setting.value))
+		default:
+			fmt.Fprintf(&b, "  # %s: %s\n", setting.key, quoteValue(setting.example))
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// quoteValue quotes values that would not read back as the same plain scalar.
+func quoteValue(value string) string {
+	if value == "" || strings.ContainsAny(value, "#:\"'{}[]") || strings.TrimSpace(value) != value {
+		return strconv.Quote(value)
+	}
+	return value
+}
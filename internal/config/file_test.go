@@ -0,0 +1,149 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testConfigFile = `# Migration of the support forum
+xenforo:
+  api_url: https://forum.example.com/api  # trailing comment
+  node_id: 2
+
+github:
+  repository: "owner/repo"
+  rate_limit_delay: 2s
+  categories:
+    2: DIC_kwDOsupport
+    5: DIC_kwDOideas
+  prefix_labels: {}
+
+migration:
+  user_mapping:
+    12: octocat
+  webhook_url: 'https://hooks.example.com/#migration'
+
+content:
+  smiley_map:
+    "styles/smilies/smile.png": ":)"
+`
+
+func TestParseFile(t *testing.T) {
+	values, err := parseFile(strings.NewReader(testConfigFile))
+	if err != nil {
+		t.Fatalf("parseFile returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"XENFORO_API_URL":         "https://forum.example.com/api",
+		"XENFORO_NODE_ID":         "2",
+		"GITHUB_REPO":             "owner/repo",
+		"GITHUB_RATE_LIMIT_DELAY": "2s",
+		"CATEGORY_MAP":            "2=DIC_kwDOsupport,5=DIC_kwDOideas",
+		"PREFIX_LABEL_MAP":        "",
+		"USER_MAPPING":            "12=octocat",
+		"WEBHOOK_URL":             "https://hooks.example.com/#migration",
+		"SMILEY_MAP":              "styles/smilies/smile.png=:)",
+	}
+	for env, value := range expected {
+		if values[env] != value {
+			t.Errorf("Expected %s=%q, got %q", env, value, values[env])
+		}
+	}
+	if len(values) != len(expected) {
+		t.Errorf("Expected %d values, got %d: %v", len(expected), len(values), values)
+	}
+}
+
+func TestParseFileErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected string
+	}{
+		{name: "unknown section", content: "forum:\n  node_id: 2\n", expected: `line 1: unknown section "forum"`},
+		{name: "unknown setting", content: "github:\n  repo: owner/repo\n", expected: "line 2: unknown setting github.repo"},
+		{name: "top-level value", content: "node_id: 2\n", expected: `line 1: "node_id" is not a section`},
+		{name: "map value", content: "migration:\n  user_mapping: 12=octocat\n", expected: "line 2: migration.user_mapping takes indented"},
+		{name: "list value", content: "xenforo:\n  node_id: [1, 2]\n", expected: "line 2: xenforo.node_id takes a single value"},
+		{name: "missing colon", content: "xenforo:\n  node_id 2\n", expected: `line 1: "xenforo" is not a section`},
+		{name: "tab indent", content: "xenforo:\n\tnode_id: 2\n", expected: "line 2: found character that cannot start any token"},
+		{name: "map entry with a separator", content: "migration:\n  user_mapping:\n    12: a,b\n", expected: "line 3: user_mapping entries cannot contain"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseFile(strings.NewReader(tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.expected) {
+				t.Errorf("Expected error containing %q, got %v", tt.expected, err)
+			}
+		})
+	}
+}
+
+func TestLoadFilePrecedence(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfigFile), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	// Register every variable the file sets so the test restores them
+	values, err := parseFile(strings.NewReader(testConfigFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for env := range values {
+		t.Setenv(env, "")
+	}
+	t.Setenv("GITHUB_CATEGORY_ID", "")
+	t.Setenv("GITHUB_REPO", "env/repo")
+
+	if err := LoadFile(path); err != nil {
+		t.Fatalf("LoadFile returned error: %v", err)
+	}
+	cfg := New()
+
+	if cfg.GitHub.Repository != "env/repo" {
+		t.Errorf("Expected the environment to override the file, got repository %q", cfg.GitHub.Repository)
+	}
+	if cfg.GitHub.RateLimitDelay != 2*time.Second {
+		t.Errorf("Expected rate limit delay from the file, got %v", cfg.GitHub.RateLimitDelay)
+	}
+	if cfg.GitHub.GitHubCategoryID != "DIC_kwDOsupport" {
+		t.Errorf("Expected node 2's mapped category, got %q", cfg.GitHub.GitHubCategoryID)
+	}
+	if cfg.Migration.UserMapping[12] != "octocat" {
+		t.Errorf("Expected user mapping from the file, got %v", cfg.Migration.UserMapping)
+	}
+
+	if err := LoadFile(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected error for a missing config file")
+	}
+}
+
+func TestWriteTemplate(t *testing.T) {
+	var template strings.Builder
+	if err := WriteTemplate(&template); err != nil {
+		t.Fatalf("WriteTemplate returned error: %v", err)
+	}
+
+	values, err := parseFile(strings.NewReader(template.String()))
+	if err != nil {
+		t.Fatalf("Template does not parse: %v\n%s", err, template.String())
+	}
+
+	for _, setting := range fileSettings {
+		if setting.isMap || setting.value == "" {
+			continue
+		}
+		if values[setting.env] != setting.value {
+			t.Errorf("Expected template %s=%q, got %q", setting.env, setting.value, values[setting.env])
+		}
+	}
+	if _, ok := values["GITHUB_TOKEN"]; ok {
+		t.Error("Expected the GitHub token to be commented out so it is still prompted for")
+	}
+}
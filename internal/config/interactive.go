@@ -9,8 +9,12 @@ import (
 	"strings"
 	"time"
 
+	"github.com/exileum/xenforo-to-gh-discussions/internal/bbcode"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/phpbb"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo/dbsource"
 )
 
 // SelectOption represents an option in a selection list
@@ -226,10 +230,18 @@ func PromptChoice(min, max int) int {
 
 // InteractiveConfig creates a new config by prompting the user
 func InteractiveConfig() *Config {
+	return InteractiveConfigWithCredentials(Credentials{})
+}
+
+// InteractiveConfigWithCredentials creates a new config by prompting the user.
+// Non-empty credentials take precedence over environment variables and are
+// not prompted for.
+func InteractiveConfigWithCredentials(creds Credentials) *Config {
 	fmt.Println("=== XenForo to GitHub Discussions Migration Tool ===")
 	fmt.Println()
 
 	cfg := &Config{}
+	ctx := context.Background()
 
 	// XenForo Configuration
 	fmt.Println("XenForo Configuration:")
@@ -238,45 +250,62 @@ func InteractiveConfig() *Config {
 	var categories []SelectOption
 	var err error
 
-	for attempt := 1; attempt <= maxRetries; attempt++ {
-		if attempt == 1 {
-			// First attempt: collect initial credentials
-			cfg.XenForo.APIURL = PromptString("API URL", getEnvOrDefault("XENFORO_API_URL", "https://your-forum.com/api"))
-
-			// For API Key, check if environment variable exists
-			apiKeyEnv := os.Getenv("XENFORO_API_KEY")
-			if apiKeyEnv != "" {
-				cfg.XenForo.APIKey = apiKeyEnv
-				fmt.Printf("API Key: ********** (from environment)\n")
+	cfg.XenForo.Source = getEnvOrDefault("SOURCE", SourceAPI)
+	cfg.XenForo.DatabaseDSN = getEnvOrDefault("XENFORO_DB_DSN", "")
+	cfg.XenForo.ExportFile = getEnvOrDefault("EXPORT_FILE", "")
+	cfg.XenForo.DataDir = getEnvOrDefault("XENFORO_DATA_DIR", "")
+	cfg.XenForo.CacheDir = getEnvOrDefault("XENFORO_CACHE_DIR", "")
+	cfg.XenForo.CacheTTL = getEnvDurationOrDefault("XENFORO_CACHE_TTL", DefaultCacheTTL)
+	cfg.XenForo.WebhookSecret = getEnvOrDefault("XENFORO_WEBHOOK_SECRET", "")
+
+	if cfg.XenForo.Source == SourceDB {
+		categories = promptXenForoDatabase(ctx, cfg, maxRetries)
+	} else if cfg.XenForo.Source == SourcePhpBB {
+		categories = promptPhpBBExport(ctx, cfg, maxRetries)
+	} else {
+		for attempt := 1; attempt <= maxRetries; attempt++ {
+			if attempt == 1 {
+				// First attempt: collect initial credentials
+				cfg.XenForo.APIURL = PromptString("API URL", getEnvOrDefault("XENFORO_API_URL", "https://your-forum.com/api"))
+
+				// For API Key, check if a secret file or environment variable provides it
+				apiKeyEnv := os.Getenv("XENFORO_API_KEY")
+				if creds.XenForoAPIKey != "" {
+					cfg.XenForo.APIKey = creds.XenForoAPIKey
+					fmt.Printf("API Key: ********** (from secret file)\n")
+				} else if apiKeyEnv != "" {
+					cfg.XenForo.APIKey = apiKeyEnv
+					fmt.Printf("API Key: ********** (from environment)\n")
+				} else {
+					cfg.XenForo.APIKey = PromptPassword("API Key")
+				}
+
+				defaultAPIUser := getEnvIntOrDefault("XENFORO_API_USER", 1)
+				cfg.XenForo.APIUser = strconv.Itoa(PromptInt("API User", defaultAPIUser))
 			} else {
+				// Retry attempts: re-prompt for credentials
+				fmt.Printf("\nRetry attempt %d of %d\n", attempt, maxRetries)
+				fmt.Println("Please check your credentials and try again:")
+
+				cfg.XenForo.APIURL = PromptString("API URL", cfg.XenForo.APIURL)
 				cfg.XenForo.APIKey = PromptPassword("API Key")
+				cfg.XenForo.APIUser = strconv.Itoa(PromptInt("API User", 1))
 			}
 
-			defaultAPIUser := getEnvIntOrDefault("XENFORO_API_USER", 1)
-			cfg.XenForo.APIUser = strconv.Itoa(PromptInt("API User", defaultAPIUser))
-		} else {
-			// Retry attempts: re-prompt for credentials
-			fmt.Printf("\nRetry attempt %d of %d\n", attempt, maxRetries)
-			fmt.Println("Please check your credentials and try again:")
-
-			cfg.XenForo.APIURL = PromptString("API URL", cfg.XenForo.APIURL)
-			cfg.XenForo.APIKey = PromptPassword("API Key")
-			cfg.XenForo.APIUser = strconv.Itoa(PromptInt("API User", 1))
-		}
-
-		// Validate XenForo credentials
-		fmt.Print("Validating XenForo credentials... ")
-		categories, err = ValidateXenForoAuth(cfg.XenForo.APIURL, cfg.XenForo.APIKey, cfg.XenForo.APIUser)
-		if err == nil {
-			fmt.Println("✓ Connected successfully")
-			break
-		}
+			// Validate XenForo credentials
+			fmt.Print("Validating XenForo credentials... ")
+			categories, err = ValidateXenForoAuth(ctx, cfg.XenForo.APIURL, cfg.XenForo.APIKey, cfg.XenForo.APIUser)
+			if err == nil {
+				fmt.Println("✓ Connected successfully")
+				break
+			}
 
-		fmt.Printf("✗ %v\n", err)
+			fmt.Printf("✗ %v\n", err)
 
-		if attempt == maxRetries {
-			fmt.Printf("\nMaximum retry attempts (%d) reached. Exiting.\n", maxRetries)
-			os.Exit(1)
+			if attempt == maxRetries {
+				fmt.Printf("\nMaximum retry attempts (%d) reached. Exiting.\n", maxRetries)
+				os.Exit(1)
+			}
 		}
 	}
 
@@ -293,13 +322,24 @@ func InteractiveConfig() *Config {
 	nodeID, _ := strconv.Atoi(selectedCategory.ID)
 	cfg.GitHub.XenForoNodeID = nodeID
 	cfg.XenForo.NodeID = nodeID
+	cfg.XenForo.WebURL = getEnvOrDefault("XENFORO_WEB_URL", "")
 
 	// GitHub Configuration
 	fmt.Println("\nGitHub Configuration:")
 
-	// For GitHub Token, check if environment variable exists
+	// A GitHub App configured in the environment replaces the token
+	cfg.GitHub.AppID = getEnvIntOrDefault("GITHUB_APP_ID", 0)
+	cfg.GitHub.AppInstallationID = getEnvIntOrDefault("GITHUB_APP_INSTALLATION_ID", 0)
+	cfg.GitHub.AppPrivateKeyFile = getEnvOrDefault("GITHUB_APP_PRIVATE_KEY_FILE", "")
+
+	// For GitHub Token, check if a secret file or environment variable provides it
 	tokenEnv := os.Getenv("GITHUB_TOKEN")
-	if tokenEnv != "" {
+	if cfg.GitHub.AppID != 0 {
+		fmt.Printf("GitHub App: %d (installation %d, from environment)\n", cfg.GitHub.AppID, cfg.GitHub.AppInstallationID)
+	} else if creds.GitHubToken != "" {
+		cfg.GitHub.Token = creds.GitHubToken
+		fmt.Printf("Personal Access Token: ********** (from secret file)\n")
+	} else if tokenEnv != "" {
 		cfg.GitHub.Token = tokenEnv
 		fmt.Printf("Personal Access Token: ********** (from environment)\n")
 	} else {
@@ -308,16 +348,20 @@ func InteractiveConfig() *Config {
 
 	cfg.GitHub.Repository = PromptString("Repository", getEnvOrDefault("GITHUB_REPO", "your_username/your_repo"))
 
-	// Validate GitHub token immediately
-	fmt.Print("Validating GitHub token... ")
+	// Validate GitHub credentials immediately
+	fmt.Print("Validating GitHub credentials... ")
 
-	ctx := context.Background()
-	ghCategories, err := ValidateGitHubAuth(ctx, cfg.GitHub.Token, cfg.GitHub.Repository)
+	auth, err := cfg.GitHubAuth()
 	if err != nil {
 		fmt.Printf("✗ %v\n", err)
 		os.Exit(1)
 	}
-	fmt.Println("✓ Token has required permissions")
+	ghCategories, err := ValidateGitHubAuth(ctx, auth, cfg.GitHub.Repository)
+	if err != nil {
+		fmt.Printf("✗ %v\n", err)
+		os.Exit(1)
+	}
+	fmt.Println("✓ Credentials have required permissions")
 
 	// Select GitHub category
 	fmt.Printf("\nFetching GitHub Discussion categories... ")
@@ -331,45 +375,279 @@ func InteractiveConfig() *Config {
 
 	cfg.GitHub.GitHubCategoryID = selectedGHCategory.ID
 
+	cfg.GitHub.PrefixLabelMap = parseStringMap(PromptString("Thread prefix labels (e.g. \"Bug=bug,Solved=answered\", empty to disable)", os.Getenv("PREFIX_LABEL_MAP")))
+
 	// GitHub API Rate Limiting Settings
 	fmt.Println("\nGitHub API Rate Limiting (optional - press Enter for defaults):")
 	cfg.GitHub.RateLimitDelay = PromptDuration("API call delay", getEnvDurationOrDefault("GITHUB_RATE_LIMIT_DELAY", 1*time.Second))
 	cfg.GitHub.MaxRetries = PromptInt("Max retries for rate limited requests", getEnvIntOrDefault("GITHUB_MAX_RETRIES", 5))
 	cfg.GitHub.RetryBackoffMultiple = PromptInt("Retry backoff multiplier (seconds)", getEnvIntOrDefault("GITHUB_RETRY_BACKOFF_MULTIPLE", 2))
+	cfg.GitHub.CommentBatchSize = getEnvIntOrDefault("COMMENT_BATCH_SIZE", 1)
 
 	// Migration Settings
 	fmt.Println("\nMigration Settings:")
 	cfg.Migration.MaxRetries = PromptInt("Max Retries", getEnvIntOrDefault("MAX_RETRIES", 3))
 	cfg.Migration.ProgressFile = fmt.Sprintf("migration_progress_node%d.json", cfg.GitHub.XenForoNodeID)
+	cfg.Migration.ProgressBackend = getEnvOrDefault("PROGRESS_BACKEND", progress.BackendJSON)
+	cfg.Migration.ProgressBucketSize = getEnvIntOrDefault("PROGRESS_BUCKET_SIZE", 0)
+	cfg.Migration.PauseFile = getEnvOrDefault("PAUSE_FILE", "migration.pause")
+	cfg.Migration.WebhookURL = getEnvOrDefault("WEBHOOK_URL", "")
+	cfg.Migration.RequireEmptyCategory = getEnvBoolOrDefault("REQUIRE_EMPTY_CATEGORY", false)
+	cfg.Migration.AllowNonEmptyCategory = getEnvBoolOrDefault("ALLOW_NONEMPTY_CATEGORY", false)
+	cfg.Migration.RepairMappings = getEnvBoolOrDefault("REPAIR_MAPPINGS", false)
+	cfg.Migration.DetectDuplicates = getEnvBoolOrDefault("DETECT_DUPLICATES", false)
+	cfg.Migration.ResumeListing = getEnvBoolOrDefault("RESUME_THREAD_LISTING", false)
+	cfg.Migration.RedirectsFile = getEnvOrDefault("REDIRECTS_FILE", "")
+	cfg.Migration.NginxMapFile = getEnvOrDefault("NGINX_MAP_FILE", "")
+	cfg.Migration.MarkSolutions = getEnvBoolOrDefault("MARK_SOLUTIONS", true)
+	cfg.Migration.LockClosedThreads = getEnvBoolOrDefault("LOCK_CLOSED_THREADS", true)
+	cfg.Migration.ReactionsMode = getEnvOrDefault("REACTIONS_MODE", ReactionsNone)
+	cfg.Migration.ReactionEmoji = getEnvNodeMap("REACTION_EMOJI")
+	cfg.Migration.AutoRetryFailed = getEnvBoolOrDefault("AUTO_RETRY_FAILED", false)
+	cfg.Migration.AutoRetryWait = getEnvDurationOrDefault("AUTO_RETRY_WAIT", DefaultAutoRetryWait)
+	cfg.Migration.SyncInterval = getEnvDurationOrDefault("SYNC_INTERVAL", 0)
+	cfg.Migration.ServeAddr = getEnvOrDefault("SERVE_ADDR", DefaultServeAddr)
+	cfg.Migration.RetryFailed = getEnvBoolOrDefault("RETRY_FAILED", false)
+	cfg.Migration.MigrationConcurrency = getEnvIntOrDefault("MIGRATION_CONCURRENCY", 1)
+	cfg.Migration.AttachmentWorkers = getEnvIntOrDefault("ATTACHMENT_WORKERS", 4)
+	cfg.Migration.MaxConcurrency = getEnvIntOrDefault("MAX_CONCURRENCY", 8)
+	cfg.Migration.TopReplyCallout = getEnvBoolOrDefault("TOP_REPLY_CALLOUT", false)
+	cfg.Migration.SinceLastRun = getEnvBoolOrDefault("SINCE_LAST_RUN", false)
+	cfg.Migration.FilterSince = getEnvOrDefault("THREAD_SINCE", "")
+	cfg.Migration.FilterUntil = getEnvOrDefault("THREAD_UNTIL", "")
+	cfg.Migration.FilterMinReplies = getEnvIntOrDefault("THREAD_MIN_REPLIES", 0)
+	cfg.Migration.FilterPrefixes = getEnvList("THREAD_PREFIXES")
+	cfg.Migration.FilterAuthors = getEnvList("THREAD_AUTHORS")
+	cfg.Migration.FilterState = getEnvOrDefault("THREAD_STATE", "")
+	cfg.Migration.FilterThreads = getEnvOrDefault("THREAD_IDS", "")
+	cfg.Migration.ExcludeThreads = getEnvOrDefault("EXCLUDE_THREAD_IDS", "")
+	cfg.Migration.MigrationOrder = getEnvOrDefault("MIGRATION_ORDER", OrderListing)
+	cfg.Migration.PostAnchors = getEnvBoolOrDefault("POST_ANCHORS", false)
+	cfg.Migration.AuthorAvatars = getEnvBoolOrDefault("AUTHOR_AVATARS", false)
+	cfg.Migration.PreserveAlignment = getEnvBoolOrDefault("PRESERVE_ALIGNMENT", true)
+	cfg.Migration.StripSignatures = getEnvBoolOrDefault("STRIP_SIGNATURES", false)
+	cfg.Migration.SignaturePattern = getEnvOrDefault("SIGNATURE_PATTERN", "")
+	cfg.Migration.SmileyEmoji = getEnvBoolOrDefault("SMILEY_EMOJI", false)
+	cfg.Migration.SmileyMap = getEnvStringMap("SMILEY_MAP")
+	cfg.Migration.VideoThumbnails = getEnvBoolOrDefault("VIDEO_THUMBNAILS", false)
+	cfg.Migration.MediaSites = getEnvStringMap("MEDIA_SITES")
+	cfg.Migration.LegacyConverter = getEnvBoolOrDefault("LEGACY_CONVERTER", false)
+	cfg.Migration.ConvertHTML = getEnvBoolOrDefault("CONVERT_HTML", false)
+	cfg.Migration.Locale = getEnvOrDefault("LOCALE", bbcode.DefaultLocale)
+	cfg.Migration.FrontmatterLabels = getEnvStringMap("FRONTMATTER_LABELS")
+	cfg.Migration.HeaderStyle = getEnvOrDefault("HEADER_STYLE", string(bbcode.HeaderFrontmatter))
+	cfg.Migration.MetadataFormat = getEnvOrDefault("METADATA_FORMAT", string(bbcode.MetadataHTMLComment))
+	cfg.Migration.MentionMode = getEnvOrDefault("MENTION_MODE", string(bbcode.MentionBold))
+	cfg.Migration.SplitThreadPosts = getEnvIntOrDefault("SPLIT_THREAD_POSTS", 0)
+	cfg.Migration.EstimateMode = getEnvOrDefault("ESTIMATE_MODE", EstimateQuick)
+	cfg.Migration.EstimateSampleThreads = getEnvIntOrDefault("ESTIMATE_SAMPLE_THREADS", DefaultEstimateSampleThreads)
+	cfg.Migration.ConfirmEstimate = getEnvBoolOrDefault("CONFIRM_ESTIMATE", false)
+	cfg.Migration.ThreadStats = getEnvBoolOrDefault("THREAD_STATS", false)
+	cfg.Migration.ThreadStatsTemplate = getEnvOrDefault("THREAD_STATS_TEMPLATE", DefaultThreadStatsTemplate)
+	cfg.Migration.FailureThresholdThreads = getEnvIntOrDefault("FAILURE_THRESHOLD_THREADS", 0)
+	cfg.Migration.FailureThresholdPercent = getEnvIntOrDefault("FAILURE_THRESHOLD_PERCENT", 50)
 
 	// Filesystem Settings
 	cfg.Filesystem.AttachmentsDir = PromptString("Attachments Directory", getEnvOrDefault("ATTACHMENTS_DIR", "./attachments"))
 	cfg.Filesystem.AttachmentRateLimitDelay = PromptDuration("Attachment Rate Limit Delay", getEnvDurationOrDefault("ATTACHMENT_RATE_LIMIT_DELAY", 500*time.Millisecond))
+	cfg.Filesystem.AttachmentFilename = getEnvOrDefault("ATTACHMENT_FILENAME_TEMPLATE", DefaultAttachmentFilename)
+
+	cfg.Filesystem.AttachmentTarget = getEnvOrDefault("ATTACHMENT_TARGET", "")
+	cfg.Filesystem.AttachmentUploadRepo = getEnvOrDefault("ATTACHMENT_UPLOAD_REPO", "")
+	cfg.Filesystem.AttachmentUploadBranch = getEnvOrDefault("ATTACHMENT_UPLOAD_BRANCH", "forum-assets")
+	cfg.Filesystem.AttachmentUploadPath = getEnvOrDefault("ATTACHMENT_UPLOAD_PATH", "attachments")
+	cfg.Filesystem.BatchAttachmentUploads = getEnvBoolOrDefault("BATCH_ATTACHMENT_UPLOADS", false)
+	cfg.Filesystem.ImageMaxWidth = getEnvIntOrDefault("IMAGE_MAX_WIDTH", 0)
+	cfg.Filesystem.ImageQuality = getEnvIntOrDefault("IMAGE_QUALITY", DefaultImageQuality)
+	cfg.Filesystem.MaxInlineImageSize = int64(getEnvIntOrDefault("MAX_INLINE_IMAGE_SIZE", DefaultMaxInlineImageSize))
+	cfg.Filesystem.MaxAttachmentSize = int64(getEnvIntOrDefault("MAX_ATTACHMENT_SIZE", 0))
+	cfg.Filesystem.AllowedExtensions = getEnvList("ALLOWED_EXTENSIONS")
+	cfg.Filesystem.BlockedExtensions = getEnvList("BLOCKED_EXTENSIONS")
+	cfg.Filesystem.MaxDownloadBytesPerSec = int64(getEnvIntOrDefault("MAX_DOWNLOAD_BYTES_PER_SEC", 0))
 
 	// Set other defaults
-	cfg.Migration.UserMapping = make(map[int]int)
-	cfg.GitHub.Categories = make(map[int]string)
+	cfg.Migration.UserMapping = getEnvNodeMap("USER_MAPPING")
+	cfg.Migration.InviteMappedUsers = getEnvBoolOrDefault("INVITE_MAPPED_USERS", false)
+	cfg.GitHub.Categories = getEnvNodeMap("CATEGORY_MAP")
+	cfg.GitHub.NodeTitlePrefix = getEnvNodeMap("NODE_TITLE_PREFIX")
+	cfg.GitHub.PrefixCategoryMap = getEnvStringMap("PREFIX_CATEGORY_MAP")
+
+	// Catch structural issues before the migration starts
+	if err := validateInteractive(cfg, fieldPrompts, maxRetries); err != nil {
+		fmt.Printf("\nConfiguration is invalid: %v\n", err)
+		os.Exit(1)
+	}
 
 	return cfg
 }
 
+// fieldPrompts re-prompt for configuration fields, keyed by the field reported
+// in validation errors.
+var fieldPrompts = map[string]func(cfg *Config){
+	"XenForo.APIURL": func(cfg *Config) { cfg.XenForo.APIURL = PromptString("API URL", cfg.XenForo.APIURL) },
+	"XenForo.APIKey": func(cfg *Config) { cfg.XenForo.APIKey = PromptPassword("API Key") },
+	"XenForo.ExportFile": func(cfg *Config) {
+		cfg.XenForo.ExportFile = PromptString("Export file", cfg.XenForo.ExportFile)
+	},
+	"XenForo.APIUser": func(cfg *Config) {
+		cfg.XenForo.APIUser = strconv.Itoa(PromptInt("API User", 1))
+	},
+	"GitHub.Token": func(cfg *Config) { cfg.GitHub.Token = PromptPassword("Personal Access Token") },
+	"GitHub.Repository": func(cfg *Config) {
+		cfg.GitHub.Repository = PromptString("Repository", cfg.GitHub.Repository)
+	},
+	"GitHub.RateLimitDelay": func(cfg *Config) {
+		cfg.GitHub.RateLimitDelay = PromptDuration("API call delay", 1*time.Second)
+	},
+	"GitHub.MaxRetries": func(cfg *Config) {
+		cfg.GitHub.MaxRetries = PromptInt("Max retries for rate limited requests", 5)
+	},
+	"GitHub.RetryBackoffMultiple": func(cfg *Config) {
+		cfg.GitHub.RetryBackoffMultiple = PromptInt("Retry backoff multiplier (seconds)", 2)
+	},
+	"Migration.MaxRetries": func(cfg *Config) { cfg.Migration.MaxRetries = PromptInt("Max Retries", 3) },
+	"Migration.ProgressFile": func(cfg *Config) {
+		cfg.Migration.ProgressFile = PromptString("Progress File", fmt.Sprintf("migration_progress_node%d.json", cfg.GitHub.XenForoNodeID))
+	},
+	"XenForo.WebURL": func(cfg *Config) {
+		cfg.XenForo.WebURL = PromptString("Forum web URL for profile links (empty to disable)", "")
+	},
+	"Migration.WebhookURL": func(cfg *Config) {
+		cfg.Migration.WebhookURL = PromptString("Webhook URL (empty to disable)", "")
+	},
+	"Migration.SignaturePattern": func(cfg *Config) {
+		cfg.Migration.SignaturePattern = PromptString("Signature pattern (empty for the standard delimiter)", "")
+	},
+	"GitHub.PrefixLabelMap": func(cfg *Config) {
+		cfg.GitHub.PrefixLabelMap = parseStringMap(PromptString("Thread prefix labels (empty to disable)", ""))
+	},
+	"Filesystem.AttachmentTarget": func(cfg *Config) {
+		cfg.Filesystem.AttachmentTarget = PromptString("Attachment target (local, repo-path, lfs or assets-branch)", "")
+	},
+	"Filesystem.AttachmentUploadRepo": func(cfg *Config) {
+		cfg.Filesystem.AttachmentUploadRepo = PromptString("Attachment upload repository (owner/repo, empty to keep local links)", "")
+	},
+	"Filesystem.AttachmentUploadBranch": func(cfg *Config) {
+		cfg.Filesystem.AttachmentUploadBranch = PromptString("Attachment upload branch", "forum-assets")
+	},
+}
+
+// validateInteractive validates cfg and lets the user re-enter the offending
+// field, up to maxAttempts validations in total. Errors for fields without a
+// prompt are returned immediately.
+func validateInteractive(cfg *Config, prompts map[string]func(cfg *Config), maxAttempts int) error {
+	for attempt := 1; ; attempt++ {
+		err := cfg.Validate()
+		if err == nil {
+			return nil
+		}
+
+		prompt, ok := prompts[GetConfigurationField(err)]
+		if !ok || attempt >= maxAttempts {
+			return err
+		}
+
+		fmt.Printf("\n✗ %v\n", err)
+		fmt.Println("Please re-enter this setting:")
+		prompt(cfg)
+	}
+}
+
 // ValidateXenForoAuth validates XenForo credentials and returns available categories
-func ValidateXenForoAuth(apiURL, apiKey string, userID string) ([]SelectOption, error) {
+func ValidateXenForoAuth(ctx context.Context, apiURL, apiKey string, userID string) ([]SelectOption, error) {
 	// Create a temporary client for validation
 	client := xenforo.NewClient(apiURL, apiKey, userID, 3)
 
 	// Test connection
-	if err := client.TestConnection(); err != nil {
+	if err := client.TestConnection(ctx); err != nil {
 		return nil, err
 	}
 
 	// Fetch actual categories from XenForo API
-	nodes, err := client.GetNodes()
+	nodes, err := client.GetNodes(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
 	}
 
-	// Filter nodes to only include forum nodes and convert to SelectOptions
+	return forumCategories(nodes), nil
+}
+
+// promptXenForoDatabase prompts for the XenForo database DSN until it
+// connects and returns the available categories.
+func promptXenForoDatabase(ctx context.Context, cfg *Config, maxRetries int) []SelectOption {
+	fmt.Println("Reading the forum from its database")
+	for attempt := 1; ; attempt++ {
+		if cfg.XenForo.DatabaseDSN == "" || attempt > 1 {
+			cfg.XenForo.DatabaseDSN = PromptPassword("Database DSN (user:password@tcp(host:3306)/xenforo)")
+		} else {
+			fmt.Printf("Database DSN: ********** (from environment)\n")
+		}
+
+		fmt.Print("Validating XenForo database... ")
+		categories, err := ValidateXenForoDatabase(ctx, cfg.XenForo.DatabaseDSN)
+		if err == nil {
+			fmt.Println("✓ Connected successfully")
+			return categories
+		}
+
+		fmt.Printf("✗ %v\n", err)
+
+		if attempt == maxRetries {
+			fmt.Printf("\nMaximum retry attempts (%d) reached. Exiting.\n", maxRetries)
+			os.Exit(1)
+		}
+	}
+}
+
+// ValidateXenForoDatabase connects to the XenForo database and returns the
+// available categories.
+func ValidateXenForoDatabase(ctx context.Context, dsn string) ([]SelectOption, error) {
+	source, err := dbsource.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = source.Close() }()
+
+	if err := source.TestConnection(ctx); err != nil {
+		return nil, err
+	}
+
+	nodes, err := source.GetNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
+	}
+
+	return forumCategories(nodes), nil
+}
+
+// promptPhpBBExport prompts for the phpBB export file until it loads and
+// returns its forums.
+func promptPhpBBExport(ctx context.Context, cfg *Config, maxRetries int) []SelectOption {
+	fmt.Println("Reading a phpBB board export")
+	for attempt := 1; ; attempt++ {
+		cfg.XenForo.ExportFile = PromptString("Export file", cfg.XenForo.ExportFile)
+
+		fmt.Print("Loading phpBB export... ")
+		source, err := phpbb.Open(cfg.XenForo.ExportFile)
+		if err == nil {
+			var nodes []xenforo.Node
+			if nodes, err = source.GetNodes(ctx); err == nil {
+				fmt.Println("✓ Loaded successfully")
+				return forumCategories(nodes)
+			}
+		}
+
+		fmt.Printf("✗ %v\n", err)
+
+		if attempt == maxRetries {
+			fmt.Printf("\nMaximum retry attempts (%d) reached. Exiting.\n", maxRetries)
+			os.Exit(1)
+		}
+	}
+}
+
+// forumCategories converts the listed forum nodes to SelectOptions.
+func forumCategories(nodes []xenforo.Node) []SelectOption {
 	var categories []SelectOption
 	for _, node := range nodes {
 		// Only include forum type nodes that are displayed in lists
@@ -386,13 +664,13 @@ func ValidateXenForoAuth(apiURL, apiKey string, userID string) ([]SelectOption,
 		}
 	}
 
-	return categories, nil
+	return categories
 }
 
-// ValidateGitHubAuth validates GitHub token and returns available discussion categories
-func ValidateGitHubAuth(ctx context.Context, token, repository string) ([]SelectOption, error) {
+// ValidateGitHubAuth validates GitHub credentials and returns available discussion categories
+func ValidateGitHubAuth(ctx context.Context, auth github.Auth, repository string) ([]SelectOption, error) {
 	// Create a temporary client for validation
-	client, err := github.NewClient(token, 1*time.Second, 3, 2)
+	client, err := github.NewClient(auth, 1*time.Second, 3, 2)
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newInteractiveTestConfig returns a config as collected by InteractiveConfig.
+func newInteractiveTestConfig() *Config {
+	cfg := &Config{}
+	cfg.XenForo = XenForoConfig{APIURL: "https://forum.example.com/api", APIKey: "valid_key", APIUser: "1", NodeID: 2}
+	cfg.GitHub = GitHubConfig{
+		Token:                "valid_token",
+		Repository:           "owner/repo",
+		Categories:           make(map[int]string),
+		XenForoNodeID:        2,
+		GitHubCategoryID:     "DIC_kwDOtest123",
+		RateLimitDelay:       1 * time.Second,
+		MaxRetries:           5,
+		RetryBackoffMultiple: 2,
+	}
+	cfg.Migration = MigrationConfig{MaxRetries: 3, ProgressFile: "migration_progress_node2.json"}
+	return cfg
+}
+
+func TestValidateInteractive(t *testing.T) {
+	t.Run("Valid config", func(t *testing.T) {
+		prompted := 0
+		prompts := map[string]func(*Config){"Migration.ProgressFile": func(*Config) { prompted++ }}
+
+		if err := validateInteractive(newInteractiveTestConfig(), prompts, 3); err != nil {
+			t.Fatalf("Expected valid config, got: %v", err)
+		}
+		if prompted != 0 {
+			t.Errorf("Valid config should not prompt, prompted %d times", prompted)
+		}
+	})
+
+	t.Run("Offending field is re-entered", func(t *testing.T) {
+		cfg := newInteractiveTestConfig()
+		cfg.Migration.ProgressFile = ""
+
+		prompts := map[string]func(*Config){
+			"Migration.ProgressFile": func(cfg *Config) { cfg.Migration.ProgressFile = "progress.json" },
+		}
+		if err := validateInteractive(cfg, prompts, 3); err != nil {
+			t.Fatalf("Expected the re-entered value to pass validation, got: %v", err)
+		}
+		if cfg.Migration.ProgressFile != "progress.json" {
+			t.Errorf("Expected re-entered progress file, got %q", cfg.Migration.ProgressFile)
+		}
+	})
+
+	t.Run("Attempts are limited", func(t *testing.T) {
+		cfg := newInteractiveTestConfig()
+		cfg.Migration.ProgressFile = ""
+
+		prompted := 0
+		prompts := map[string]func(*Config){"Migration.ProgressFile": func(*Config) { prompted++ }}
+
+		err := validateInteractive(cfg, prompts, 3)
+		if GetConfigurationField(err) != "Migration.ProgressFile" {
+			t.Fatalf("Expected progress file error, got: %v", err)
+		}
+		if prompted != 2 {
+			t.Errorf("Expected 2 prompts before giving up, got %d", prompted)
+		}
+	})
+
+	t.Run("Field without prompt is caught", func(t *testing.T) {
+		cfg := newInteractiveTestConfig()
+		cfg.Migration.MigrationConcurrency = -1
+
+		err := validateInteractive(cfg, fieldPrompts, 3)
+		if GetConfigurationField(err) != "Migration.MigrationConcurrency" {
+			t.Errorf("Expected migration concurrency error, got: %v", err)
+		}
+	})
+}
+
+func TestValidateInteractivePromptsFromStdin(t *testing.T) {
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = reader
+	defer func() {
+		os.Stdin = stdin
+		_ = reader.Close()
+	}()
+
+	if _, err := writer.WriteString("owner/fixed-repo\n"); err != nil {
+		t.Fatal(err)
+	}
+	_ = writer.Close()
+
+	cfg := newInteractiveTestConfig()
+	cfg.GitHub.Repository = "not-a-repository"
+
+	if err := validateInteractive(cfg, fieldPrompts, 3); err != nil {
+		t.Fatalf("Expected the re-entered repository to pass validation, got: %v", err)
+	}
+	if cfg.GitHub.Repository != "owner/fixed-repo" {
+		t.Errorf("Expected repository from stdin, got %q", cfg.GitHub.Repository)
+	}
+}
+
+func TestValidationErrorsReportField(t *testing.T) {
+	cfg := newInteractiveTestConfig()
+	cfg.Migration.WebhookURL = "ftp://example.com/hook"
+
+	err := cfg.Validate()
+	if GetConfigurationField(err) != "Migration.WebhookURL" {
+		t.Fatalf("Expected webhook URL field, got: %v", err)
+	}
+	if !strings.Contains(err.Error(), "webhook URL must be an absolute http(s) URL") {
+		t.Errorf("Expected actionable message, got: %v", err)
+	}
+}
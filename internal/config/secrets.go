@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+)
+
+// StdinSecret is the secret file path that reads the secret from standard input.
+const StdinSecret = "-"
+
+// Credentials holds secrets supplied up front (e.g., Docker or Kubernetes
+// secrets mounted as files) so interactive setup does not prompt for them.
+type Credentials struct {
+	GitHubToken   string
+	XenForoAPIKey string
+}
+
+// ReadSecret reads a secret from the file at path, or from stdin when path is
+// "-". Surrounding whitespace, including the trailing newline, is trimmed.
+func ReadSecret(path string, stdin io.Reader) (string, error) {
+	var (
+		data []byte
+		err  error
+	)
+	if path == StdinSecret {
+		data, err = io.ReadAll(stdin)
+	} else {
+		data, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret from %s: %w", secretSource(path), err)
+	}
+
+	secret := strings.TrimSpace(string(data))
+	if secret == "" {
+		return "", fmt.Errorf("secret from %s is empty", secretSource(path))
+	}
+	return secret, nil
+}
+
+// ReadCredentials reads the GitHub token and XenForo API key from their secret
+// files. Empty paths are skipped and leave the corresponding field empty.
+// Only one of the secrets can be read from stdin.
+func ReadCredentials(githubTokenFile, xenforoKeyFile string, stdin io.Reader) (Credentials, error) {
+	var creds Credentials
+
+	if githubTokenFile == StdinSecret && xenforoKeyFile == StdinSecret {
+		return creds, fmt.Errorf("only one secret can be read from stdin")
+	}
+
+	if githubTokenFile != "" {
+		token, err := ReadSecret(githubTokenFile, stdin)
+		if err != nil {
+			return creds, fmt.Errorf("GitHub token: %w", err)
+		}
+		creds.GitHubToken = token
+	}
+
+	if xenforoKeyFile != "" {
+		key, err := ReadSecret(xenforoKeyFile, stdin)
+		if err != nil {
+			return creds, fmt.Errorf("XenForo API key: %w", err)
+		}
+		creds.XenForoAPIKey = key
+	}
+
+	return creds, nil
+}
+
+// ApplyCredentials overrides the configured secrets with the non-empty
+// credentials, so secret files take precedence over environment variables.
+func (c *Config) ApplyCredentials(creds Credentials) {
+	if creds.GitHubToken != "" {
+		c.GitHub.Token = creds.GitHubToken
+	}
+	if creds.XenForoAPIKey != "" {
+		c.XenForo.APIKey = creds.XenForoAPIKey
+	}
+}
+
+// GitHubAuth returns the credentials GitHub clients authenticate with: the
+// configured GitHub App, reading its private key file, or else the token.
+func (c *Config) GitHubAuth() (github.Auth, error) {
+	if c.GitHub.AppID == 0 {
+		return github.TokenAuth(c.GitHub.Token), nil
+	}
+
+	key, err := os.ReadFile(c.GitHub.AppPrivateKeyFile)
+	if err != nil {
+		return github.Auth{}, fmt.Errorf("failed to read GitHub App private key: %w", err)
+	}
+	return github.Auth{App: &github.AppCredentials{
+		AppID:          int64(c.GitHub.AppID),
+		InstallationID: int64(c.GitHub.AppInstallationID),
+		PrivateKey:     key,
+	}}, nil
+}
+
+// NewGitHubClient creates a GitHub client with the configured credentials
+// and rate limiting.
+func (c *Config) NewGitHubClient() (*github.Client, error) {
+	auth, err := c.GitHubAuth()
+	if err != nil {
+		return nil, err
+	}
+	return github.NewClient(auth, c.GitHub.RateLimitDelay, c.GitHub.MaxRetries, c.GitHub.RetryBackoffMultiple)
+}
+
+func secretSource(path string) string {
+	if path == StdinSecret {
+		return "stdin"
+	}
+	return path
+}
@@ -0,0 +1,89 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeSecretFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "secret")
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestReadSecret(t *testing.T) {
+	path := writeSecretFile(t, "  ghp_from_file\n")
+
+	secret, err := ReadSecret(path, nil)
+	if err != nil {
+		t.Fatalf("ReadSecret returned error: %v", err)
+	}
+	if secret != "ghp_from_file" {
+		t.Errorf("Expected trimmed secret, got %q", secret)
+	}
+
+	secret, err = ReadSecret(StdinSecret, strings.NewReader("key_from_stdin\r\n"))
+	if err != nil {
+		t.Fatalf("ReadSecret from stdin returned error: %v", err)
+	}
+	if secret != "key_from_stdin" {
+		t.Errorf("Expected trimmed stdin secret, got %q", secret)
+	}
+
+	if _, err := ReadSecret(writeSecretFile(t, " \n"), nil); err == nil {
+		t.Error("Expected error for empty secret file")
+	}
+
+	if _, err := ReadSecret(filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Error("Expected error for missing secret file")
+	}
+}
+
+func TestReadCredentials(t *testing.T) {
+	tokenFile := writeSecretFile(t, "ghp_from_file\n")
+
+	creds, err := ReadCredentials(tokenFile, StdinSecret, strings.NewReader("key_from_stdin\n"))
+	if err != nil {
+		t.Fatalf("ReadCredentials returned error: %v", err)
+	}
+	if creds.GitHubToken != "ghp_from_file" || creds.XenForoAPIKey != "key_from_stdin" {
+		t.Errorf("Unexpected credentials: %+v", creds)
+	}
+
+	if _, err := ReadCredentials(StdinSecret, StdinSecret, strings.NewReader("secret\n")); err == nil {
+		t.Error("Expected error when both secrets are read from stdin")
+	}
+
+	creds, err = ReadCredentials("", "", nil)
+	if err != nil {
+		t.Fatalf("ReadCredentials without files returned error: %v", err)
+	}
+	if creds != (Credentials{}) {
+		t.Errorf("Expected empty credentials, got %+v", creds)
+	}
+}
+
+func TestApplyCredentialsPrecedence(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "ghp_from_env")
+	t.Setenv("XENFORO_API_KEY", "key_from_env")
+
+	creds, err := ReadCredentials(writeSecretFile(t, "ghp_from_file\n"), "", nil)
+	if err != nil {
+		t.Fatalf("ReadCredentials returned error: %v", err)
+	}
+
+	cfg := New()
+	cfg.ApplyCredentials(creds)
+
+	if cfg.GitHub.Token != "ghp_from_file" {
+		t.Errorf("Secret file should take precedence over GITHUB_TOKEN, got %q", cfg.GitHub.Token)
+	}
+	if cfg.XenForo.APIKey != "key_from_env" {
+		t.Errorf("XENFORO_API_KEY should be kept without a secret file, got %q", cfg.XenForo.APIKey)
+	}
+}
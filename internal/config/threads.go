@@ -0,0 +1,76 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ThreadIDRange is an inclusive range of thread IDs; single IDs have From
+// equal to To.
+type ThreadIDRange struct {
+	From, To int
+}
+
+// ThreadIDs is a set of thread IDs given as IDs and ranges, e.g.
+// "100,105,200-300".
+type ThreadIDs []ThreadIDRange
+
+// Contains reports whether the set includes a thread ID.
+func (ids ThreadIDs) Contains(threadID int) bool {
+	for _, r := range ids {
+		if threadID >= r.From && threadID <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// ParseThreadIDs parses thread IDs and ranges separated by commas, spaces or
+// newlines. Text after "#" on a line is a comment.
+func ParseThreadIDs(spec string) (ThreadIDs, error) {
+	var ids ThreadIDs
+	for _, line := range strings.Split(spec, "\n") {
+		line, _, _ = strings.Cut(line, "#")
+		for _, item := range strings.FieldsFunc(line, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' || r == '\r' }) {
+			from, to, isRange := strings.Cut(item, "-")
+			first, err := strconv.Atoi(from)
+			last := first
+			if err == nil && isRange {
+				last, err = strconv.Atoi(to)
+			}
+			if err != nil || first <= 0 || last < first {
+				return nil, fmt.Errorf("invalid thread ID or range %q (expected e.g. 105 or 200-300)", item)
+			}
+			ids = append(ids, ThreadIDRange{From: first, To: last})
+		}
+	}
+	return ids, nil
+}
+
+// ReadThreadIDs parses value as thread IDs and ranges or, when it is the path
+// of a file, reads them from that file. An empty value selects no threads.
+func ReadThreadIDs(value string) (ThreadIDs, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+
+	ids, err := ParseThreadIDs(value)
+	if err == nil {
+		return ids, nil
+	}
+	data, readErr := os.ReadFile(value)
+	if errors.Is(readErr, os.ErrNotExist) {
+		return nil, err
+	} else if readErr != nil {
+		return nil, fmt.Errorf("failed to read thread IDs: %w", readErr)
+	}
+
+	ids, err = ParseThreadIDs(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", value, err)
+	}
+	return ids, nil
+}
@@ -3,9 +3,22 @@ package config
 import (
 	"fmt"
 	"net/url"
+	"regexp"
 	"strings"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/bbcode"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
 )
 
+// githubLoginPattern matches GitHub usernames: up to 39 alphanumeric
+// characters or single hyphens, not starting or ending with a hyphen.
+// maxLabelLength is the longest repository label name GitHub accepts.
+const maxLabelLength = 50
+
+var githubLoginPattern = regexp.MustCompile(`^[A-Za-z0-9](?:-?[A-Za-z0-9]){0,38}$`)
+
 // CategoryValidator defines the interface for validating GitHub category configurations
 type CategoryValidator interface {
 	ValidateSingleCategory(nodeID int, categoryID string) error
@@ -50,6 +63,26 @@ func (v *basicConfigValidator) ValidateNoConfiguration() error {
 	return fmt.Errorf("either single-category configuration (XenForoNodeID + GitHubCategoryID) or legacy category mappings must be configured")
 }
 
+// fieldError tags a validation error with the configuration field that
+// caused it, so interactive setup can re-prompt for it. The message is kept
+// unchanged.
+type fieldError struct {
+	field string
+	err   error
+}
+
+func (e *fieldError) Error() string {
+	return e.err.Error()
+}
+
+func (e *fieldError) Unwrap() error {
+	return e.err
+}
+
+func invalidField(field, format string, args ...interface{}) error {
+	return &fieldError{field: field, err: fmt.Errorf(format, args...)}
+}
+
 func (c *Config) Validate() error {
 	if err := c.validateXenForo(); err != nil {
 		return fmt.Errorf("XenForo config validation failed: %w", err)
@@ -63,28 +96,78 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("migration config validation failed: %w", err)
 	}
 
+	if err := c.validateFilesystem(); err != nil {
+		return fmt.Errorf("filesystem config validation failed: %w", err)
+	}
+
 	return nil
 }
 
 func (c *Config) validateXenForo() error {
+	switch c.XenForo.Source {
+	case "", SourceAPI:
+	case SourceDB, SourcePhpBB:
+		return c.validateForumSource()
+	default:
+		return invalidField("XenForo.Source", "unknown forum source %q (expected %s, %s or %s)", c.XenForo.Source, SourceAPI, SourceDB, SourcePhpBB)
+	}
+
 	if c.XenForo.APIURL == "" || c.XenForo.APIURL == "https://your-forum.com/api" {
-		return fmt.Errorf("XenForo API URL must be configured")
+		return invalidField("XenForo.APIURL", "XenForo API URL must be configured")
 	}
 
 	if _, err := url.Parse(c.XenForo.APIURL); err != nil {
-		return fmt.Errorf("invalid XenForo API URL: %w", err)
+		return invalidField("XenForo.APIURL", "invalid XenForo API URL: %w", err)
 	}
 
 	if c.XenForo.APIKey == "" || c.XenForo.APIKey == "your_xenforo_api_key" {
-		return fmt.Errorf("XenForo API key must be configured")
+		return invalidField("XenForo.APIKey", "XenForo API key must be configured")
 	}
 
 	if c.XenForo.APIUser == "" {
-		return fmt.Errorf("XenForo API user must be configured")
+		return invalidField("XenForo.APIUser", "XenForo API user must be configured")
 	}
 
 	if c.XenForo.NodeID <= 0 {
-		return fmt.Errorf("XenForo node ID must be positive")
+		return invalidField("XenForo.NodeID", "XenForo node ID must be positive")
+	}
+
+	if c.XenForo.CacheTTL < 0 {
+		return invalidField("XenForo.CacheTTL", "XenForo cache TTL cannot be negative")
+	}
+
+	return c.validateXenForoWebURL()
+}
+
+// validateForumSource validates the settings of the database and phpBB
+// sources, which need the forum URL to build attachment links instead of the
+// API settings.
+func (c *Config) validateForumSource() error {
+	if c.XenForo.Source == SourceDB && strings.TrimSpace(c.XenForo.DatabaseDSN) == "" {
+		return invalidField("XenForo.DatabaseDSN", "XenForo database DSN must be configured for the %s source", SourceDB)
+	}
+
+	if c.XenForo.Source == SourcePhpBB && strings.TrimSpace(c.XenForo.ExportFile) == "" {
+		return invalidField("XenForo.ExportFile", "export file must be configured for the %s source", SourcePhpBB)
+	}
+
+	if c.XenForo.NodeID <= 0 {
+		return invalidField("XenForo.NodeID", "XenForo node ID must be positive")
+	}
+
+	if c.XenForo.WebURL == "" {
+		return invalidField("XenForo.WebURL", "XenForo web URL must be configured for the %s source", c.XenForo.Source)
+	}
+
+	return c.validateXenForoWebURL()
+}
+
+func (c *Config) validateXenForoWebURL() error {
+	if c.XenForo.WebURL != "" {
+		parsed, err := url.Parse(c.XenForo.WebURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return invalidField("XenForo.WebURL", "XenForo web URL must be an absolute http(s) URL")
+		}
 	}
 
 	return nil
@@ -107,51 +190,318 @@ func (c *Config) validateGitHub() error {
 }
 
 func (c *Config) validateGitHubAuth() error {
+	if c.GitHub.AppID != 0 || c.GitHub.AppInstallationID != 0 || c.GitHub.AppPrivateKeyFile != "" {
+		return c.validateGitHubApp()
+	}
 	if c.GitHub.Token == "" || c.GitHub.Token == "your_github_token" {
-		return fmt.Errorf("GitHub token must be configured")
+		return invalidField("GitHub.Token", "GitHub token must be configured")
+	}
+	return nil
+}
+
+func (c *Config) validateGitHubApp() error {
+	if c.GitHub.AppID <= 0 {
+		return invalidField("GitHub.AppID", "GitHub App ID must be positive, got: %d", c.GitHub.AppID)
+	}
+	if c.GitHub.AppInstallationID <= 0 {
+		return invalidField("GitHub.AppInstallationID", "GitHub App installation ID must be positive, got: %d", c.GitHub.AppInstallationID)
+	}
+	if c.GitHub.AppPrivateKeyFile == "" {
+		return invalidField("GitHub.AppPrivateKeyFile", "GitHub App private key file must be configured")
 	}
 	return nil
 }
 
 func (c *Config) validateGitHubRepository() error {
 	if c.GitHub.Repository == "" || c.GitHub.Repository == "your_username/your_repo" {
-		return fmt.Errorf("GitHub repository must be configured")
+		return invalidField("GitHub.Repository", "GitHub repository must be configured")
 	}
 
 	parts := strings.Split(c.GitHub.Repository, "/")
 	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
-		return fmt.Errorf("GitHub repository must be in format 'owner/repo'")
+		return invalidField("GitHub.Repository", "GitHub repository must be in format 'owner/repo'")
 	}
 	return nil
 }
 
 func (c *Config) validateGitHubRateLimiting() error {
 	if c.GitHub.RateLimitDelay < 0 {
-		return fmt.Errorf("GitHub rate limit delay cannot be negative")
+		return invalidField("GitHub.RateLimitDelay", "GitHub rate limit delay cannot be negative")
 	}
 
 	if c.GitHub.MaxRetries < 0 {
-		return fmt.Errorf("GitHub max retries cannot be negative")
+		return invalidField("GitHub.MaxRetries", "GitHub max retries cannot be negative")
 	}
 
 	if c.GitHub.RetryBackoffMultiple <= 0 {
-		return fmt.Errorf("GitHub retry backoff multiple must be positive")
+		return invalidField("GitHub.RetryBackoffMultiple", "GitHub retry backoff multiple must be positive")
+	}
+
+	if c.GitHub.CommentBatchSize < 0 || c.GitHub.CommentBatchSize > github.MaxCommentBatchSize {
+		return invalidField("GitHub.CommentBatchSize", "comment batch size must be between 0 and %d", github.MaxCommentBatchSize)
 	}
 	return nil
 }
 
 func (c *Config) validateGitHubCategories() error {
 	validator := &basicConfigValidator{}
-	return ValidateCategoryConfiguration(c, validator)
+	if err := ValidateCategoryConfiguration(c, validator); err != nil {
+		return err
+	}
+
+	for prefix, categoryID := range c.GitHub.PrefixCategoryMap {
+		if categoryID == "" || categoryID == "DIC_kwDOxxxxxxxx" {
+			return fmt.Errorf("category ID must be configured for thread prefix %q", prefix)
+		}
+	}
+
+	for prefix, label := range c.GitHub.PrefixLabelMap {
+		if strings.TrimSpace(label) == "" || len(label) > maxLabelLength {
+			return invalidField("GitHub.PrefixLabelMap", "label for thread prefix %q must be 1-%d characters", prefix, maxLabelLength)
+		}
+	}
+	return nil
+}
+
+// validateThreadFilters checks the thread filter dates parse and form a
+// range, the thread ID lists parse, and the other filters have valid values.
+func (c *Config) validateThreadFilters() error {
+	var since, until time.Time
+	if c.Migration.FilterSince != "" {
+		t, _, err := ParseFilterDate(c.Migration.FilterSince)
+		if err != nil {
+			return invalidField("Migration.FilterSince", "thread filter since: %w", err)
+		}
+		since = t
+	}
+	if c.Migration.FilterUntil != "" {
+		t, _, err := ParseFilterDate(c.Migration.FilterUntil)
+		if err != nil {
+			return invalidField("Migration.FilterUntil", "thread filter until: %w", err)
+		}
+		until = t
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		return invalidField("Migration.FilterUntil", "thread filter until (%s) is before since (%s)", c.Migration.FilterUntil, c.Migration.FilterSince)
+	}
+
+	if c.Migration.FilterMinReplies < 0 {
+		return invalidField("Migration.FilterMinReplies", "thread filter min replies cannot be negative")
+	}
+
+	switch c.Migration.FilterState {
+	case "", ThreadsOpen, ThreadsClosed:
+	default:
+		return invalidField("Migration.FilterState", "unknown thread state %q (expected %s or %s)", c.Migration.FilterState, ThreadsOpen, ThreadsClosed)
+	}
+
+	if _, err := ReadThreadIDs(c.Migration.FilterThreads); err != nil {
+		return invalidField("Migration.FilterThreads", "thread IDs: %w", err)
+	}
+
+	if _, err := ReadThreadIDs(c.Migration.ExcludeThreads); err != nil {
+		return invalidField("Migration.ExcludeThreads", "excluded thread IDs: %w", err)
+	}
+
+	return nil
 }
 
 func (c *Config) validateMigration() error {
 	if c.Migration.MaxRetries <= 0 {
-		return fmt.Errorf("max retries must be positive")
+		return invalidField("Migration.MaxRetries", "max retries must be positive")
 	}
 
 	if c.Migration.ProgressFile == "" {
-		return fmt.Errorf("progress file path must be configured")
+		return invalidField("Migration.ProgressFile", "progress file path must be configured")
+	}
+
+	if c.Migration.AutoRetryWait < 0 {
+		return invalidField("Migration.AutoRetryWait", "auto retry wait cannot be negative")
+	}
+
+	if c.Migration.SyncInterval < 0 {
+		return invalidField("Migration.SyncInterval", "sync interval cannot be negative")
+	}
+
+	if c.Migration.ProgressBucketSize < 0 {
+		return invalidField("Migration.ProgressBucketSize", "progress bucket size cannot be negative")
+	}
+
+	switch c.Migration.ProgressBackend {
+	case progress.BackendJSON, "":
+	case progress.BackendSQLite:
+		if !progress.SQLiteSupported {
+			return invalidField("Migration.ProgressBackend", "the sqlite progress backend needs a build with cgo enabled (CGO_ENABLED=1)")
+		}
+		if c.Migration.ProgressBucketSize > 0 {
+			return invalidField("Migration.ProgressBucketSize", "progress buckets are not used with the sqlite progress backend")
+		}
+	default:
+		return invalidField("Migration.ProgressBackend", "progress backend must be %q or %q, got %q", progress.BackendJSON, progress.BackendSQLite, c.Migration.ProgressBackend)
+	}
+
+	if err := c.validateFailureThreshold(); err != nil {
+		return err
+	}
+
+	if err := c.validateThreadFilters(); err != nil {
+		return err
+	}
+
+	if c.Migration.SplitThreadPosts < 0 {
+		return invalidField("Migration.SplitThreadPosts", "split thread posts cannot be negative")
+	}
+
+	switch c.Migration.MigrationOrder {
+	case "", OrderListing, OrderOldestFirst, OrderNewestFirst, OrderLastActivity:
+	default:
+		return invalidField("Migration.MigrationOrder", "unknown migration order %q (expected %s, %s, %s or %s)",
+			c.Migration.MigrationOrder, OrderListing, OrderOldestFirst, OrderNewestFirst, OrderLastActivity)
+	}
+
+	switch c.Migration.EstimateMode {
+	case "", EstimateQuick, EstimateSample, EstimateFull:
+	default:
+		return invalidField("Migration.EstimateMode", "unknown estimate mode %q (expected %s, %s or %s)",
+			c.Migration.EstimateMode, EstimateQuick, EstimateSample, EstimateFull)
+	}
+
+	if c.Migration.EstimateMode == EstimateSample && c.Migration.EstimateSampleThreads <= 0 {
+		return invalidField("Migration.EstimateSampleThreads", "estimate sample threads must be positive")
+	}
+
+	if c.Migration.WebhookURL != "" {
+		parsed, err := url.Parse(c.Migration.WebhookURL)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return invalidField("Migration.WebhookURL", "webhook URL must be an absolute http(s) URL")
+		}
+	}
+
+	if c.Migration.SignaturePattern != "" {
+		if _, err := regexp.Compile(c.Migration.SignaturePattern); err != nil {
+			return invalidField("Migration.SignaturePattern", "invalid signature pattern: %w", err)
+		}
+	}
+
+	for userID, login := range c.Migration.UserMapping {
+		if !githubLoginPattern.MatchString(strings.TrimPrefix(login, "@")) {
+			return invalidField("Migration.UserMapping", "%w: user %d maps to invalid GitHub username %q", ErrInvalidUserMapping, userID, login)
+		}
+	}
+
+	if _, err := bbcode.LocaleLabels(c.Migration.Locale, c.Migration.FrontmatterLabels); err != nil {
+		return invalidField("Migration.FrontmatterLabels", "invalid frontmatter labels: %w", err)
+	}
+
+	style, err := bbcode.ParseHeaderStyle(c.Migration.HeaderStyle)
+	if err != nil {
+		return invalidField("Migration.HeaderStyle", "%w", err)
+	}
+	if c.Migration.DetectDuplicates && !style.HasThreadMarker() {
+		return invalidField("Migration.DetectDuplicates", "duplicate detection needs the thread marker of the frontmatter header style, not %s", style)
+	}
+
+	if _, err := bbcode.ParseMetadataFormat(c.Migration.MetadataFormat); err != nil {
+		return invalidField("Migration.MetadataFormat", "%w", err)
+	}
+
+	if err := bbcode.ValidateMediaSites(c.Migration.MediaSites); err != nil {
+		return invalidField("Migration.MediaSites", "%w", err)
+	}
+
+	if _, err := bbcode.ParseMentionMode(c.Migration.MentionMode); err != nil {
+		return invalidField("Migration.MentionMode", "%w", err)
+	}
+
+	switch c.Migration.ReactionsMode {
+	case "", ReactionsNone, ReactionsSummary, ReactionsGitHub:
+	default:
+		return invalidField("Migration.ReactionsMode", "unknown reactions mode %q (expected %s, %s or %s)",
+			c.Migration.ReactionsMode, ReactionsNone, ReactionsSummary, ReactionsGitHub)
+	}
+	// The REST API returns a post's reaction score, not its reactions
+	if (c.Migration.ReactionsMode == ReactionsSummary || c.Migration.ReactionsMode == ReactionsGitHub) && c.XenForo.Source != SourceDB {
+		return invalidField("Migration.ReactionsMode", "reactions are only read from the %s source", SourceDB)
+	}
+
+	return c.validateConcurrency()
+}
+
+func (c *Config) validateFailureThreshold() error {
+	if c.Migration.FailureThresholdThreads < 0 {
+		return invalidField("Migration.FailureThresholdThreads", "failure threshold threads cannot be negative")
+	}
+
+	if c.Migration.FailureThresholdThreads > 0 &&
+		(c.Migration.FailureThresholdPercent < 0 || c.Migration.FailureThresholdPercent > 100) {
+		return invalidField("Migration.FailureThresholdPercent", "failure threshold percent must be between 0 and 100")
+	}
+
+	return nil
+}
+
+func (c *Config) validateConcurrency() error {
+	if c.Migration.MigrationConcurrency < 0 {
+		return invalidField("Migration.MigrationConcurrency", "migration concurrency cannot be negative")
+	}
+
+	if c.Migration.AttachmentWorkers < 0 {
+		return invalidField("Migration.AttachmentWorkers", "attachment workers cannot be negative")
+	}
+
+	if c.Migration.MaxConcurrency < 0 {
+		return invalidField("Migration.MaxConcurrency", "max concurrency cannot be negative")
+	}
+
+	// Each thread worker holds a global slot while it runs, so at least one
+	// slot must remain for attachment downloads to make progress.
+	if c.Migration.MaxConcurrency > 0 && c.Migration.MaxConcurrency <= c.Migration.MigrationConcurrency {
+		return invalidField("Migration.MaxConcurrency", "max concurrency (%d) must be greater than migration concurrency (%d)",
+			c.Migration.MaxConcurrency, c.Migration.MigrationConcurrency)
+	}
+
+	return nil
+}
+
+func (c *Config) validateFilesystem() error {
+	if c.Filesystem.MaxInlineImageSize < 0 {
+		return invalidField("Filesystem.MaxInlineImageSize", "max inline image size must be non-negative, got %d", c.Filesystem.MaxInlineImageSize)
+	}
+
+	if c.Filesystem.ImageMaxWidth < 0 {
+		return invalidField("Filesystem.ImageMaxWidth", "image max width must be non-negative, got %d", c.Filesystem.ImageMaxWidth)
+	}
+
+	if c.Filesystem.ImageMaxWidth > 0 && (c.Filesystem.ImageQuality < 1 || c.Filesystem.ImageQuality > 100) {
+		return invalidField("Filesystem.ImageQuality", "image quality must be between 1 and 100, got %d", c.Filesystem.ImageQuality)
+	}
+
+	if c.Filesystem.MaxAttachmentSize < 0 {
+		return invalidField("Filesystem.MaxAttachmentSize", "max attachment size must be non-negative, got %d", c.Filesystem.MaxAttachmentSize)
+	}
+
+	if c.Filesystem.MaxDownloadBytesPerSec < 0 {
+		return invalidField("Filesystem.MaxDownloadBytesPerSec", "max download bytes per second must be non-negative, got %d", c.Filesystem.MaxDownloadBytesPerSec)
+	}
+
+	target, _ := c.AttachmentHosting()
+	switch target {
+	case AttachmentTargetLocal:
+		return nil
+	case AttachmentTargetRepoPath, AttachmentTargetLFS, AttachmentTargetAssetsBranch:
+	default:
+		return invalidField("Filesystem.AttachmentTarget", "attachment target must be local, repo-path, lfs or assets-branch, got %q", target)
+	}
+
+	if c.Filesystem.AttachmentUploadRepo != "" {
+		parts := strings.Split(c.Filesystem.AttachmentUploadRepo, "/")
+		if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+			return invalidField("Filesystem.AttachmentUploadRepo", "attachment upload repository must be in format 'owner/repo'")
+		}
+	}
+
+	if target != AttachmentTargetRepoPath && strings.TrimSpace(c.Filesystem.AttachmentUploadBranch) == "" {
+		return invalidField("Filesystem.AttachmentUploadBranch", "attachment upload branch must be configured")
 	}
 
 	return nil
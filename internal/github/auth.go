@@ -0,0 +1,193 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+)
+
+// installationTokenRefresh is how long before it expires an installation
+// token is replaced, so requests never start with a token about to expire.
+const installationTokenRefresh = 5 * time.Minute
+
+// Auth holds the credentials the client authenticates with: a personal access
+// token, or a GitHub App installation when App is set.
+type Auth struct {
+	Token string          // Personal access token
+	App   *AppCredentials // GitHub App installation, used instead of Token
+}
+
+// AppCredentials identify a GitHub App installation. The client exchanges a
+// JWT signed with the private key for installation tokens, which expire after
+// an hour and are refreshed automatically.
+type AppCredentials struct {
+	AppID          int64  // GitHub App ID
+	InstallationID int64  // Installation of the app on the repository's owner
+	PrivateKey     []byte // PEM-encoded RSA private key of the app
+}
+
+// TokenAuth returns the credentials of a personal access token.
+func TokenAuth(token string) Auth {
+	return Auth{Token: token}
+}
+
+// tokenSource returns the source of the access tokens sent with every request,
+// and the installation token source when authenticating as a GitHub App.
+func (a Auth) tokenSource() (oauth2.TokenSource, *installationTokenSource, error) {
+	if a.App == nil {
+		if strings.TrimSpace(a.Token) == "" {
+			return nil, nil, errors.New("GitHub token cannot be empty")
+		}
+		if len(a.Token) < 20 {
+			return nil, nil, errors.New("GitHub token appears to be invalid (too short)")
+		}
+		return oauth2.StaticTokenSource(&oauth2.Token{AccessToken: a.Token}), nil, nil
+	}
+
+	if a.App.AppID <= 0 {
+		return nil, nil, errors.New("GitHub App ID must be positive")
+	}
+	if a.App.InstallationID <= 0 {
+		return nil, nil, errors.New("GitHub App installation ID must be positive")
+	}
+	key, err := ParsePrivateKey(a.App.PrivateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	installation := &installationTokenSource{
+		appID:          a.App.AppID,
+		installationID: a.App.InstallationID,
+		key:            key,
+		baseURL:        defaultRESTBaseURL,
+		httpClient:     &http.Client{Timeout: 30 * time.Second},
+	}
+	return oauth2.ReuseTokenSourceWithExpiry(nil, installation, installationTokenRefresh), installation, nil
+}
+
+// ParsePrivateKey parses a GitHub App private key in PKCS #1 (as downloaded
+// from GitHub) or PKCS #8 PEM form.
+func ParsePrivateKey(data []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("GitHub App private key is not PEM-encoded")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("GitHub App private key is not an RSA key")
+	}
+	return key, nil
+}
+
+// installationTokenSource creates GitHub App installation tokens. It is
+// wrapped in a reusing source, which only asks for a new token once the
+// current one is about to expire.
+type installationTokenSource struct {
+	appID          int64
+	installationID int64
+	key            *rsa.PrivateKey
+	httpClient     *http.Client // Unauthenticated client for the token requests
+
+	mu      sync.Mutex
+	baseURL string // Base URL of the GitHub REST API
+}
+
+// setBaseURL points the token requests at another REST API base URL.
+func (s *installationTokenSource) setBaseURL(baseURL string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.baseURL = baseURL
+}
+
+// Token creates an installation token.
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	jwt, err := s.appJWT(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	url := fmt.Sprintf("%s/app/installations/%d/access_tokens", s.baseURL, s.installationID)
+	s.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.httpClient.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create installation token request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("Authorization", "Bearer "+jwt)
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to request GitHub App installation token: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var payload struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+		Message   string    `json:"message"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&payload); err != nil && resp.StatusCode == http.StatusCreated {
+		return nil, fmt.Errorf("failed to parse GitHub App installation token: %w", err)
+	}
+	if resp.StatusCode != http.StatusCreated {
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: "installation token: " + payload.Message, Header: resp.Header}
+	}
+	if payload.Token == "" {
+		return nil, errors.New("GitHub returned an empty installation token")
+	}
+
+	return &oauth2.Token{AccessToken: payload.Token, TokenType: "Bearer", Expiry: payload.ExpiresAt}, nil
+}
+
+// appJWT returns the JWT authenticating as the app, valid for nine minutes.
+// It is backdated a minute against clock drift, as GitHub recommends.
+func (s *installationTokenSource) appJWT(now time.Time) (string, error) {
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claims, err := json.Marshal(map[string]int64{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": s.appID,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	encoding := base64.RawURLEncoding
+	signed := encoding.EncodeToString(header) + "." + encoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+	return signed + "." + encoding.EncodeToString(signature), nil
+}
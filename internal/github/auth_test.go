@@ -0,0 +1,151 @@
+package github
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewClient_GitHubApp(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var mu sync.Mutex
+	var issued int
+	var used []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.URL.Path != "/app/installations/42/access_tokens" {
+			used = append(used, r.Header.Get("Authorization"))
+			_, _ = w.Write([]byte(`{}`))
+			return
+		}
+		if r.Method != http.MethodPost || !validAppJWT(t, strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer "), &key.PublicKey, 7) {
+			w.WriteHeader(http.StatusUnauthorized)
+			_, _ = w.Write([]byte(`{"message":"A JSON web token could not be decoded"}`))
+			return
+		}
+
+		// The first token expires within the refresh window
+		issued++
+		expiresAt := time.Now().Add(time.Hour)
+		if issued == 1 {
+			expiresAt = time.Now().Add(time.Minute)
+		}
+		w.WriteHeader(http.StatusCreated)
+		_ = json.NewEncoder(w).Encode(map[string]any{"token": fmt.Sprintf("ghs_%d", issued), "expires_at": expiresAt})
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Auth{App: &AppCredentials{AppID: 7, InstallationID: 42, PrivateKey: privateKey}}, 0, 0, 1)
+	if err != nil {
+		t.Fatalf("NewClient returned error: %v", err)
+	}
+	client.SetRESTBaseURL(server.URL)
+
+	for i := 0; i < 3; i++ {
+		if err := client.doRESTRequest(context.Background(), http.MethodGet, "/repos/owner/repo", nil, nil); err != nil {
+			t.Fatalf("Request %d failed: %v", i+1, err)
+		}
+	}
+
+	expected := []string{"Bearer ghs_1", "Bearer ghs_2", "Bearer ghs_2"}
+	if strings.Join(used, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected requests authorized with %v, got %v", expected, used)
+	}
+	if issued != 2 {
+		t.Errorf("Expected the expiring token to be refreshed once, got %d tokens", issued)
+	}
+}
+
+func TestNewClient_GitHubAppValidation(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("Failed to generate key: %v", err)
+	}
+	pkcs8, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("Failed to encode key: %v", err)
+	}
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8})
+
+	tests := []struct {
+		name   string
+		app    AppCredentials
+		errMsg string
+	}{
+		{name: "PKCS #8 key", app: AppCredentials{AppID: 7, InstallationID: 42, PrivateKey: privateKey}},
+		{name: "Missing app ID", app: AppCredentials{InstallationID: 42, PrivateKey: privateKey}, errMsg: "GitHub App ID must be positive"},
+		{name: "Missing installation ID", app: AppCredentials{AppID: 7, PrivateKey: privateKey}, errMsg: "installation ID must be positive"},
+		{name: "Key not PEM-encoded", app: AppCredentials{AppID: 7, InstallationID: 42, PrivateKey: []byte("not a key")}, errMsg: "not PEM-encoded"},
+		{name: "Malformed key", app: AppCredentials{AppID: 7, InstallationID: 42, PrivateKey: pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: []byte("garbage")})}, errMsg: "failed to parse GitHub App private key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := tt.app
+			_, err := NewClient(Auth{App: &app}, 0, 0, 1)
+			if tt.errMsg == "" {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.errMsg) {
+				t.Errorf("Expected error containing %q, got %v", tt.errMsg, err)
+			}
+		})
+	}
+}
+
+// validAppJWT reports whether token is an RS256 JWT signed with key and
+// issued by the app.
+func validAppJWT(t *testing.T, token string, key *rsa.PublicKey, appID int64) bool {
+	t.Helper()
+
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return false
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return false
+	}
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], signature) != nil {
+		return false
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return false
+	}
+	var claims struct {
+		Issuer    int64 `json:"iss"`
+		IssuedAt  int64 `json:"iat"`
+		ExpiresAt int64 `json:"exp"`
+	}
+	if json.Unmarshal(data, &claims) != nil {
+		return false
+	}
+	now := time.Now().Unix()
+	return claims.Issuer == appID && claims.IssuedAt <= now && claims.ExpiresAt > now && claims.ExpiresAt-claims.IssuedAt <= 600
+}
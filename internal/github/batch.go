@@ -0,0 +1,215 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/retry"
+)
+
+const defaultGraphQLURL = "https://api.github.com/graphql"
+
+// MaxCommentBatchSize is the largest number of comments AddComments sends in
+// one request, keeping the mutation well below GitHub's query limits.
+const MaxCommentBatchSize = 50
+
+// CommentInput is one comment of a batch.
+type CommentInput struct {
+	DiscussionID string
+	ReplyToID    string // Top-level comment the comment replies to (empty = top-level)
+	Body         string
+}
+
+// CommentResult is the outcome of one comment of a batch: the ID of the
+// created comment, or the error GitHub reported for it.
+type CommentResult struct {
+	ID  string
+	Err error
+}
+
+// graphQLError is an error entry of a GraphQL response. Path names the
+// aliased field that failed, if any.
+type graphQLError struct {
+	Type    string        `json:"type"`
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path"`
+}
+
+// AddComments adds several comments in a single GraphQL request, one aliased
+// addDiscussionComment mutation per comment. GitHub runs them in order. A
+// comment GitHub rejects fails alone: its result carries the error and the
+// other comments are still created. An error is returned when the whole
+// request failed, wrapping ErrCommentsRefused when GitHub answered that it
+// created none of them. As part of the batch may have been created otherwise,
+// a failed request is only retried when GitHub refused it for its rate limit.
+func (c *Client) AddComments(ctx context.Context, comments []CommentInput) ([]CommentResult, error) {
+	if len(comments) == 0 {
+		return nil, nil
+	}
+	if len(comments) > MaxCommentBatchSize {
+		return nil, fmt.Errorf("comment batch of %d exceeds the maximum of %d", len(comments), MaxCommentBatchSize)
+	}
+	for i, comment := range comments {
+		if strings.TrimSpace(comment.DiscussionID) == "" {
+			return nil, fmt.Errorf("comment %d: discussionID cannot be empty", i)
+		}
+		if strings.TrimSpace(comment.Body) == "" {
+			return nil, fmt.Errorf("comment %d: comment body cannot be empty", i)
+		}
+	}
+
+	query, variables := buildCommentBatch(comments)
+
+	var results []CommentResult
+	err := c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var response struct {
+			Data map[string]*struct {
+				Comment struct {
+					ID string `json:"id"`
+				} `json:"comment"`
+			} `json:"data"`
+			Errors []graphQLError `json:"errors"`
+		}
+		if err := c.doGraphQLRequest(ctx, query, variables, &response); err != nil {
+			var apiErr *APIError
+			if errors.As(err, &apiErr) && apiErr.StatusCode < http.StatusInternalServerError {
+				err = fmt.Errorf("%w: %w", ErrCommentsRefused, err)
+			}
+			return c.retryIfRefused(err)
+		}
+
+		results = make([]CommentResult, len(comments))
+		created := 0
+		for i := range comments {
+			if field := response.Data[commentAlias(i)]; field != nil && field.Comment.ID != "" {
+				results[i].ID = field.Comment.ID
+				created++
+			}
+		}
+
+		var requestErrors []string
+		for _, graphErr := range response.Errors {
+			if i, ok := commentIndex(graphErr.Path, len(comments)); ok && created > 0 {
+				results[i].Err = fmt.Errorf("failed to add comment to discussion %q: %s", comments[i].DiscussionID, graphErr.Message)
+				continue
+			}
+			requestErrors = append(requestErrors, graphErr.Message)
+		}
+		if created == 0 && len(requestErrors) > 0 {
+			return c.retryIfRefused(fmt.Errorf("%w: failed to add %d comments: %s", ErrCommentsRefused, len(comments), strings.Join(requestErrors, "; ")))
+		}
+
+		for i := range results {
+			if results[i].ID == "" && results[i].Err == nil {
+				results[i].Err = fmt.Errorf("failed to add comment to discussion %q: no comment returned", comments[i].DiscussionID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// retryIfRefused marks a failed batch request as not retryable unless GitHub
+// refused it for its rate limit, in which case no mutation ran.
+func (c *Client) retryIfRefused(err error) error {
+	if _, refused := c.parseRateLimitFromError(err); refused {
+		return err
+	}
+	return retry.Permanent(err)
+}
+
+// buildCommentBatch builds the aliased mutation "c0: addDiscussionComment(input:
+// $c0) ..." and its variables.
+func buildCommentBatch(comments []CommentInput) (string, map[string]interface{}) {
+	var params, fields strings.Builder
+	variables := make(map[string]interface{}, len(comments))
+	for i, comment := range comments {
+		alias := commentAlias(i)
+		if i > 0 {
+			params.WriteString(", ")
+		}
+		fmt.Fprintf(&params, "$%s: AddDiscussionCommentInput!", alias)
+		fmt.Fprintf(&fields, " %s: addDiscussionComment(input: $%s) { comment { id } }", alias, alias)
+
+		input := map[string]interface{}{
+			"discussionId": comment.DiscussionID,
+			"body":         comment.Body,
+		}
+		if comment.ReplyToID != "" {
+			input["replyToId"] = comment.ReplyToID
+		}
+		variables[alias] = input
+	}
+	return fmt.Sprintf("mutation(%s) {%s }", params.String(), fields.String()), variables
+}
+
+func commentAlias(i int) string {
+	return fmt.Sprintf("c%d", i)
+}
+
+// commentIndex returns the batch index of the comment an error path points
+// at.
+func commentIndex(path []interface{}, count int) (int, bool) {
+	if len(path) == 0 {
+		return 0, false
+	}
+	alias, _ := path[0].(string)
+	for i := 0; i < count; i++ {
+		if alias == commentAlias(i) {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// doGraphQLRequest posts a raw GraphQL request, for documents the typed
+// client cannot express such as a variable number of aliased mutations.
+func (c *Client) doGraphQLRequest(ctx context.Context, query string, variables map[string]interface{}, out interface{}) error {
+	data, err := json.Marshal(map[string]interface{}{"query": query, "variables": variables})
+	if err != nil {
+		return fmt.Errorf("failed to encode request body: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.graphqlURL, bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("GraphQL request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		var payload struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(body))
+		if json.Unmarshal(body, &payload) == nil && payload.Message != "" {
+			message = payload.Message
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: message, Header: resp.Header}
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
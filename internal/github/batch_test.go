@@ -0,0 +1,156 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestGraphQLClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 0, 0, 1)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetGraphQLURL(server.URL)
+	return client
+}
+
+func TestClient_AddComments(t *testing.T) {
+	var query string
+	var variables map[string]map[string]interface{}
+	client := newTestGraphQLClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string                            `json:"query"`
+			Variables map[string]map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		query, variables = req.Query, req.Variables
+
+		// The second comment replies to a comment that cannot be replied to
+		_, _ = fmt.Fprint(w, `{"data":{"c0":{"comment":{"id":"C_1"}},"c1":null,"c2":{"comment":{"id":"C_2"}}},
+			"errors":[{"type":"UNPROCESSABLE","path":["c1"],"message":"Parent comment is a reply"}]}`)
+	})
+
+	results, err := client.AddComments(context.Background(), []CommentInput{
+		{DiscussionID: "D_1", Body: "First"},
+		{DiscussionID: "D_1", ReplyToID: "C_0", Body: "Second"},
+		{DiscussionID: "D_1", Body: "Third"},
+	})
+	if err != nil {
+		t.Fatalf("AddComments returned error: %v", err)
+	}
+
+	for _, expected := range []string{
+		"$c0: AddDiscussionCommentInput!, $c1: AddDiscussionCommentInput!, $c2: AddDiscussionCommentInput!",
+		"c0: addDiscussionComment(input: $c0) { comment { id } }",
+		"c2: addDiscussionComment(input: $c2)",
+	} {
+		if !strings.Contains(query, expected) {
+			t.Errorf("Expected %q in query %q", expected, query)
+		}
+	}
+	if variables["c1"]["replyToId"] != "C_0" || variables["c2"]["body"] != "Third" {
+		t.Errorf("Unexpected variables: %v", variables)
+	}
+	if _, ok := variables["c0"]["replyToId"]; ok {
+		t.Error("Expected no replyToId for a top-level comment")
+	}
+
+	if results[0].ID != "C_1" || results[0].Err != nil || results[2].ID != "C_2" || results[2].Err != nil {
+		t.Errorf("Expected comments 0 and 2 to be created, got %+v", results)
+	}
+	if results[1].Err == nil || !strings.Contains(results[1].Err.Error(), "Parent comment is a reply") {
+		t.Errorf("Expected comment 1 to fail with GitHub's error, got %+v", results[1])
+	}
+}
+
+func TestClient_AddCommentsRequestFailure(t *testing.T) {
+	client := newTestGraphQLClient(t, func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded for user ID 1."}]}`)
+	})
+
+	_, err := client.AddComments(context.Background(), []CommentInput{{DiscussionID: "D_1", Body: "First"}})
+	if !errors.Is(err, ErrRateLimitExhausted) {
+		t.Errorf("Expected ErrRateLimitExhausted when nothing was created, got %v", err)
+	}
+}
+
+func TestClient_AddCommentsNotRetried(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		http.Error(w, "bad gateway", http.StatusBadGateway)
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 0, 2, 1)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetGraphQLURL(server.URL)
+
+	// Part of the batch may have been created, so it is not sent again
+	_, err = client.AddComments(context.Background(), []CommentInput{{DiscussionID: "D_1", Body: "First"}})
+	if err == nil || errors.Is(err, ErrCommentsRefused) {
+		t.Errorf("Expected a failed batch that may have been created, got %v", err)
+	}
+	if requests != 1 {
+		t.Errorf("Expected a single request, got %d", requests)
+	}
+}
+
+func TestClient_AddCommentsRefused(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		body   string
+	}{
+		{name: "Client error", status: http.StatusUnprocessableEntity, body: `{"message":"Validation Failed"}`},
+		{name: "GraphQL errors", status: http.StatusOK, body: `{"errors":[{"type":"UNPROCESSABLE","message":"Body is invalid"}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestGraphQLClient(t, func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = fmt.Fprint(w, tt.body)
+			})
+
+			_, err := client.AddComments(context.Background(), []CommentInput{{DiscussionID: "D_1", Body: "First"}})
+			if !errors.Is(err, ErrCommentsRefused) {
+				t.Errorf("Expected ErrCommentsRefused, got %v", err)
+			}
+		})
+	}
+}
+
+func TestClient_AddCommentsValidation(t *testing.T) {
+	client := newTestGraphQLClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Expected no request for an invalid batch")
+	})
+
+	tooMany := make([]CommentInput, MaxCommentBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = CommentInput{DiscussionID: "D_1", Body: "Body"}
+	}
+
+	for name, comments := range map[string][]CommentInput{
+		"empty body":    {{DiscussionID: "D_1", Body: " "}},
+		"no discussion": {{Body: "Body"}},
+		"too many":      tooMany,
+	} {
+		if _, err := client.AddComments(context.Background(), comments); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
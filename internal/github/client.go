@@ -7,27 +7,38 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"net/http"
 	"strings"
 	"sync/atomic"
 	"time"
 
 	"github.com/shurcooL/githubv4"
 	"golang.org/x/oauth2"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/retry"
 )
 
 // Client provides a GitHub GraphQL API client with built-in rate limiting,
 // retry mechanisms, and statistics tracking. It manages GitHub Discussions
 // operations with automatic error recovery and monitoring.
 type Client struct {
-	client               *githubv4.Client // GitHub GraphQL client
-	repositoryID         string           // Target repository ID
-	repositoryName       string           // Repository name for logging
-	rateLimitDelay       time.Duration    // Delay between API calls
-	maxRetries           int              // Maximum retry attempts
-	retryBackoffMultiple int              // Exponential backoff multiplier
-	operationCount       int64            // Total operations attempted (atomic)
-	rateLimitHits        int64            // Rate limit encounters (atomic)
+	client               *githubv4.Client         // GitHub GraphQL client
+	httpClient           *http.Client             // Authenticated HTTP client for REST calls
+	restBaseURL          string                   // Base URL of the GitHub REST API
+	lfsBaseURL           string                   // Base URL of repositories' Git LFS endpoints
+	lfsClient            *http.Client             // Unauthenticated client for Git LFS requests
+	tokens               oauth2.TokenSource       // Access tokens, also sent to Git LFS with basic authentication
+	installation         *installationTokenSource // Installation token source when authenticating as a GitHub App
+	graphqlURL           string                   // GraphQL endpoint for raw requests
+	repositoryID         string                   // Target repository ID
+	repositoryName       string                   // Repository name for logging
+	rateLimitDelay       time.Duration            // Minimum delay between API calls
+	quota                *quota                   // Rate limit state from the latest response
+	maxRetries           int                      // Maximum retry attempts
+	retryBackoffMultiple int                      // Exponential backoff multiplier
+	operationCount       int64                    // Total operations attempted (atomic)
+	rateLimitHits        int64                    // Rate limit encounters (atomic)
 }
 
 // RateLimitError represents a GitHub API rate limit violation.
@@ -43,16 +54,25 @@ func (e *RateLimitError) Error() string {
 		e.Message, e.Remaining, e.ResetTime.Format(time.RFC3339))
 }
 
+// ErrRateLimitExhausted is returned, wrapping the RateLimitError, when an
+// operation is still rate limited after all retries. Callers can checkpoint
+// their work and resume later instead of treating it as a permanent failure.
+var ErrRateLimitExhausted = errors.New("GitHub API rate limit exhausted")
+
+// ErrCommentsRefused is returned, wrapping GitHub's answer, when GitHub
+// refused a whole comment batch, so none of its comments were created and
+// they can be sent again.
+var ErrCommentsRefused = errors.New("GitHub refused the comments")
+
 // NewClient creates a new GitHub GraphQL API client with comprehensive validation.
-// Validates token format, rate limiting parameters, and retry configuration.
+// Validates the credentials, rate limiting parameters, and retry configuration.
+// A GitHub App client creates its first installation token with the first
+// request and replaces it before it expires, so long migrations keep going.
 // Returns an initialized client ready for GitHub Discussions operations.
-func NewClient(token string, rateLimitDelay time.Duration, maxRetries, retryBackoffMultiple int) (*Client, error) {
-	if strings.TrimSpace(token) == "" {
-		return nil, errors.New("GitHub token cannot be empty")
-	}
-
-	if len(token) < 20 {
-		return nil, errors.New("GitHub token appears to be invalid (too short)")
+func NewClient(auth Auth, rateLimitDelay time.Duration, maxRetries, retryBackoffMultiple int) (*Client, error) {
+	src, installation, err := auth.tokenSource()
+	if err != nil {
+		return nil, err
 	}
 
 	if rateLimitDelay < 0 {
@@ -65,14 +85,12 @@ func NewClient(token string, rateLimitDelay time.Duration, maxRetries, retryBack
 		return nil, errors.New("retry backoff multiple must be at least 1")
 	}
 
-	src := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: token},
-	)
-
 	httpClient := oauth2.NewClient(context.Background(), src)
 	if httpClient == nil {
 		return nil, errors.New("failed to create OAuth2 HTTP client")
 	}
+	rateLimitQuota := &quota{}
+	httpClient.Transport = &quotaTransport{base: httpClient.Transport, quota: rateLimitQuota}
 
 	graphqlClient := githubv4.NewClient(httpClient)
 	if graphqlClient == nil {
@@ -81,7 +99,15 @@ func NewClient(token string, rateLimitDelay time.Duration, maxRetries, retryBack
 
 	client := &Client{
 		client:               graphqlClient,
+		httpClient:           httpClient,
+		restBaseURL:          defaultRESTBaseURL,
+		lfsBaseURL:           defaultLFSBaseURL,
+		lfsClient:            &http.Client{Timeout: lfsRequestTimeout},
+		tokens:               src,
+		installation:         installation,
+		graphqlURL:           defaultGraphQLURL,
 		rateLimitDelay:       rateLimitDelay,
+		quota:                rateLimitQuota,
 		maxRetries:           maxRetries,
 		retryBackoffMultiple: retryBackoffMultiple,
 	}
@@ -97,6 +123,13 @@ func (c *Client) SetRepositoryID(id string) {
 	c.repositoryID = id
 }
 
+// SetGraphQLURL points the GraphQL client at a different endpoint (e.g., for
+// GitHub Enterprise Server or tests).
+func (c *Client) SetGraphQLURL(url string) {
+	c.client = githubv4.NewEnterpriseClient(url, c.httpClient)
+	c.graphqlURL = url
+}
+
 // GetRepositoryID returns the currently configured repository ID.
 func (c *Client) GetRepositoryID() string {
 	return c.repositoryID
@@ -112,11 +145,24 @@ func (c *Client) GetRepositoryName() string {
 	return c.repositoryName
 }
 
+// RateLimitStatus returns the remaining and total GitHub GraphQL API points
+// and when they reset, as reported by the latest GraphQL response. All zero
+// until the first response.
+func (c *Client) RateLimitStatus() (remaining, limit int, resetAt time.Time) {
+	state, _ := c.quota.status(resourceGraphQL)
+	return state.remaining, state.limit, state.resetAt
+}
+
 func (c *Client) parseRateLimitFromError(err error) (*RateLimitError, bool) {
 	if err == nil {
 		return nil, false
 	}
 
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return rateLimitFromAPIError(apiErr)
+	}
+
 	errStr := err.Error()
 
 	if !strings.Contains(strings.ToLower(errStr), "rate limit") &&
@@ -126,12 +172,11 @@ func (c *Client) parseRateLimitFromError(err error) (*RateLimitError, bool) {
 		return nil, false
 	}
 
-	resetTime := time.Now().Add(1 * time.Hour)
-
-	if strings.Contains(errStr, "please retry your request after") {
-		resetTime = time.Now().Add(60 * time.Minute)
-	} else if strings.Contains(strings.ToLower(errStr), "secondary rate limit") {
-		resetTime = time.Now().Add(10 * time.Minute)
+	// GitHub's headers tell when to retry; without them, secondary limits
+	// ask clients to wait at least a minute.
+	resetTime, ok := c.quota.resetTime(time.Now())
+	if !ok {
+		resetTime = time.Now().Add(1 * time.Minute)
 	}
 
 	rateLimitErr := &RateLimitError{
@@ -144,142 +189,94 @@ func (c *Client) parseRateLimitFromError(err error) (*RateLimitError, bool) {
 }
 
 func (c *Client) logRateLimitStatus() {
-	log.Printf("GitHub API: Using rate limit delay: %v, max retries: %d, backoff multiplier: %dx",
+	logging.Infof(context.Background(), "GitHub API: Using rate limit delay: %v, max retries: %d, backoff multiplier: %dx",
 		c.rateLimitDelay, c.maxRetries, c.retryBackoffMultiple)
 }
 
-// executeWithRetry executes a function with rate limit handling, exponential backoff, and context support
-func (c *Client) executeWithRetry(ctx context.Context, operation func() error) error {
-	var lastErr error
+// executeWithRetry executes a function with rate limit handling, backoff, and
+// context support. It is paced by the quota of resource, the rate limit
+// resource the function's requests spend. Rate-limited attempts wait for the reset GitHub reports
+// (up to two hours); other transient failures back off linearly by
+// retryBackoffMultiple seconds, capped at five minutes.
+func (c *Client) executeWithRetry(ctx context.Context, resource string, operation func() error) error {
 	atomic.AddInt64(&c.operationCount, 1)
 
-	for attempt := 0; attempt <= c.maxRetries; attempt++ {
-		if err := c.checkContextCancellation(ctx); err != nil {
-			return err
-		}
+	policy := retry.Policy{
+		MaxAttempts: c.maxRetries + 1,
+		Backoff:     retry.Linear(time.Duration(c.retryBackoffMultiple) * time.Second),
+		MaxDelay:    5 * time.Minute,
+		Jitter:      0.1,
+		Classify: func(err error) (bool, time.Duration) {
+			return c.classifyError(ctx, err)
+		},
+		OnRetry: func(n int, err error, delay time.Duration) {
+			logging.Warnf(ctx, "GitHub API operation failed (attempt %d/%d): %v", n, c.maxRetries+1, err)
+			logging.Warnf(ctx, "GitHub API retry attempt %d/%d, waiting %v... (total ops: %d, rate limit hits: %d)",
+				n, c.maxRetries, delay.Round(time.Millisecond), atomic.LoadInt64(&c.operationCount), atomic.LoadInt64(&c.rateLimitHits))
+		},
+	}
 
-		if err := c.handleDelays(ctx, attempt); err != nil {
-			return err
+	attempts := 0
+	err := policy.Do(ctx, func(attempt int) error {
+		attempts = attempt + 1
+		if attempt == 0 {
+			if err := c.pace(ctx, resource); err != nil {
+				return fmt.Errorf("operation cancelled during rate limit delay: %w", err)
+			}
 		}
-
-		err := operation()
-		if err == nil {
-			c.logSuccessAfterRetries(attempt)
-			return nil
+		return operation()
+	})
+
+	var exhausted *retry.ExhaustedError
+	switch {
+	case err == nil:
+		if attempts > 1 {
+			logging.Infof(ctx, "GitHub API operation succeeded after %d retries (total ops: %d)", attempts-1, atomic.LoadInt64(&c.operationCount))
 		}
-
-		lastErr = err
-
-		if shouldContinue, retryErr := c.handleRetryableError(ctx, err, attempt); retryErr != nil {
-			return retryErr
-		} else if !shouldContinue {
-			return err
+		return nil
+	case err == ctx.Err():
+		return fmt.Errorf("operation cancelled: %w", err)
+	case errors.As(err, &exhausted):
+		if rateLimitErr, ok := c.parseRateLimitFromError(exhausted.Err); ok {
+			logging.Errorf(ctx, "Maximum retries (%d) exceeded for GitHub API rate limit (total rate limit hits: %d)", c.maxRetries, atomic.LoadInt64(&c.rateLimitHits))
+			return fmt.Errorf("%w: %w", ErrRateLimitExhausted, rateLimitErr)
 		}
-
-		c.logRetryAttempt(attempt, err)
-	}
-
-	return fmt.Errorf("GitHub API operation failed after %d retries: %w", c.maxRetries, lastErr)
-}
-
-// checkContextCancellation checks if the context has been cancelled
-func (c *Client) checkContextCancellation(ctx context.Context) error {
-	select {
-	case <-ctx.Done():
-		return fmt.Errorf("operation cancelled: %w", ctx.Err())
+		logging.Errorf(ctx, "Maximum retries (%d) exceeded for GitHub API operation (total ops: %d)", c.maxRetries, atomic.LoadInt64(&c.operationCount))
+		return fmt.Errorf("GitHub API operation failed after %d retries: %w", c.maxRetries, exhausted.Err)
 	default:
-		return nil
-	}
-}
-
-// handleDelays manages exponential backoff and rate limiting delays
-func (c *Client) handleDelays(ctx context.Context, attempt int) error {
-	const maxBackoffDuration = 5 * time.Minute
-
-	if attempt > 0 {
-		backoffDuration := c.calculateBackoffDuration(attempt, maxBackoffDuration)
-		log.Printf("GitHub API retry attempt %d/%d, waiting %v... (total ops: %d, rate limit hits: %d)",
-			attempt, c.maxRetries, backoffDuration, atomic.LoadInt64(&c.operationCount), atomic.LoadInt64(&c.rateLimitHits))
-
-		return c.waitWithContext(ctx, backoffDuration, "operation cancelled during backoff")
-	} else if c.rateLimitDelay > 0 {
-		return c.waitWithContext(ctx, c.rateLimitDelay, "operation cancelled during rate limit delay")
+		return err
 	}
-
-	return nil
 }
 
-// calculateBackoffDuration calculates the exponential backoff duration with maximum cap
-func (c *Client) calculateBackoffDuration(attempt int, maxDuration time.Duration) time.Duration {
-	backoffDuration := time.Duration(attempt*c.retryBackoffMultiple) * time.Second
-	if backoffDuration > maxDuration {
-		backoffDuration = maxDuration
+// pace waits before an operation for at least rateLimitDelay, and longer as
+// the remaining GitHub quota of resource drops.
+func (c *Client) pace(ctx context.Context, resource string) error {
+	delay := c.quota.delay(resource, c.rateLimitDelay, time.Now())
+	if state, ok := c.quota.status(resource); ok && delay > c.rateLimitDelay {
+		logging.Debugf(ctx, "GitHub API: %d/%d %s points left until %s, pacing requests %v apart",
+			state.remaining, state.limit, resource, state.resetAt.Format(time.RFC3339), delay.Round(time.Millisecond))
 	}
-	return backoffDuration
+	return retry.Wait(ctx, delay)
 }
 
-// waitWithContext waits for the specified duration while respecting context cancellation
-func (c *Client) waitWithContext(ctx context.Context, duration time.Duration, cancelMessage string) error {
-	select {
-	case <-ctx.Done():
-		return fmt.Errorf("%s: %w", cancelMessage, ctx.Err())
-	case <-time.After(duration):
-		return nil
-	}
-}
-
-// handleRetryableError processes rate limit and retryable errors
-// Returns (shouldContinue, error) where shouldContinue indicates if the retry loop should continue
-func (c *Client) handleRetryableError(ctx context.Context, err error, attempt int) (bool, error) {
+// classifyError decides whether a failed operation is retried. Rate limit
+// errors are counted and wait for GitHub's reset when it is near enough.
+func (c *Client) classifyError(ctx context.Context, err error) (bool, time.Duration) {
 	if rateLimitErr, isRateLimit := c.parseRateLimitFromError(err); isRateLimit {
-		return c.handleRateLimitError(ctx, rateLimitErr, attempt)
-	}
-
-	if !c.isRetryableError(err) {
-		log.Printf("GitHub API operation failed with non-retryable error: %v", err)
-		return false, nil
-	}
+		atomic.AddInt64(&c.rateLimitHits, 1)
+		logging.Warnf(ctx, "GitHub API rate limit detected (#%d): %s", atomic.LoadInt64(&c.rateLimitHits), rateLimitErr.Error())
 
-	if attempt >= c.maxRetries {
-		log.Printf("Maximum retries (%d) exceeded for GitHub API operation (total ops: %d)", c.maxRetries, atomic.LoadInt64(&c.operationCount))
-		return false, nil
-	}
-
-	return true, nil
-}
-
-// handleRateLimitError processes rate limit errors with appropriate waiting
-func (c *Client) handleRateLimitError(ctx context.Context, rateLimitErr *RateLimitError, attempt int) (bool, error) {
-	atomic.AddInt64(&c.rateLimitHits, 1)
-	log.Printf("GitHub API rate limit detected (#%d): %s", atomic.LoadInt64(&c.rateLimitHits), rateLimitErr.Error())
-
-	if attempt >= c.maxRetries {
-		log.Printf("Maximum retries (%d) exceeded for GitHub API rate limit (total rate limit hits: %d)", c.maxRetries, atomic.LoadInt64(&c.rateLimitHits))
-		return false, rateLimitErr
-	}
-
-	waitTime := time.Until(rateLimitErr.ResetTime)
-	if waitTime > 0 && waitTime < 2*time.Hour {
-		log.Printf("Waiting %v for GitHub API rate limit to reset... (hit #%d)", waitTime, atomic.LoadInt64(&c.rateLimitHits))
-
-		if err := c.waitWithContext(ctx, waitTime, "operation cancelled during rate limit wait"); err != nil {
-			return false, err
+		if waitTime := time.Until(rateLimitErr.ResetTime); waitTime > 0 && waitTime < 2*time.Hour {
+			return true, waitTime
 		}
+		return true, 0
 	}
 
-	return true, nil
-}
-
-// logSuccessAfterRetries logs successful operations after retries
-func (c *Client) logSuccessAfterRetries(attempt int) {
-	if attempt > 0 {
-		log.Printf("GitHub API operation succeeded after %d retries (total ops: %d)", attempt, atomic.LoadInt64(&c.operationCount))
+	if !c.isRetryableError(err) {
+		logging.Errorf(ctx, "GitHub API operation failed with non-retryable error: %v", err)
+		return false, 0
 	}
-}
-
-// logRetryAttempt logs retry attempts
-func (c *Client) logRetryAttempt(attempt int, err error) {
-	log.Printf("GitHub API operation failed (attempt %d/%d): %v", attempt+1, c.maxRetries+1, err)
+	return true, 0
 }
 
 // isRetryableError determines if an error is transient and should trigger a retry
@@ -0,0 +1,51 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// InviteCollaborator invites a user to the repository with the given
+// permission ("pull", "triage", "push", ...). Returns false when the user
+// already has access and no invitation was needed.
+func (c *Client) InviteCollaborator(ctx context.Context, repo, username, permission string) (bool, error) {
+	owner, name, err := splitRepository(repo)
+	if err != nil {
+		return false, err
+	}
+
+	// 201 returns the invitation; 204 (existing collaborator) has no body
+	var invitation struct {
+		ID int64 `json:"id"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s/collaborators/%s", owner, name, url.PathEscape(username))
+	body := map[string]string{"permission": permission}
+	if err := c.restRequest(ctx, http.MethodPut, path, body, &invitation); err != nil {
+		return false, fmt.Errorf("failed to invite %s: %w", username, err)
+	}
+
+	return invitation.ID != 0, nil
+}
+
+// OwnerIsOrganization reports whether a repository is owned by an
+// organization. Collaborators of a repository owned by a user always get
+// write access, whatever permission they are invited with.
+func (c *Client) OwnerIsOrganization(ctx context.Context, repo string) (bool, error) {
+	owner, name, err := splitRepository(repo)
+	if err != nil {
+		return false, err
+	}
+
+	var repository struct {
+		Owner struct {
+			Type string `json:"type"`
+		} `json:"owner"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(name))
+	if err := c.restRequest(ctx, http.MethodGet, path, nil, &repository); err != nil {
+		return false, fmt.Errorf("failed to look up repository %s: %w", repo, err)
+	}
+	return repository.Owner.Type == "Organization", nil
+}
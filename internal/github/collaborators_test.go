@@ -0,0 +1,71 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestClient_InviteCollaborator(t *testing.T) {
+	var permission string
+	client := newTestRESTClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		permission = body["permission"]
+
+		switch {
+		case r.Method != http.MethodPut:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case r.URL.Path == "/repos/owner/repo/collaborators/octocat":
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"id":42}`))
+		case r.URL.Path == "/repos/owner/repo/collaborators/hubot":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}))
+
+	invited, err := client.InviteCollaborator(context.Background(), "owner/repo", "octocat", "pull")
+	if err != nil || !invited {
+		t.Fatalf("Expected an invitation, got %v (%v)", invited, err)
+	}
+	if permission != "pull" {
+		t.Errorf("Expected pull permission, got %q", permission)
+	}
+
+	invited, err = client.InviteCollaborator(context.Background(), "owner/repo", "hubot", "pull")
+	if err != nil || invited {
+		t.Errorf("Expected an existing collaborator, got %v (%v)", invited, err)
+	}
+
+	if _, err := client.InviteCollaborator(context.Background(), "owner/repo", "ghost", "pull"); err == nil {
+		t.Error("Expected an error for an unknown user")
+	}
+}
+
+func TestClient_OwnerIsOrganization(t *testing.T) {
+	client := newTestRESTClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/org/repo":
+			_, _ = w.Write([]byte(`{"owner":{"login":"org","type":"Organization"}}`))
+		case "/repos/user/repo":
+			_, _ = w.Write([]byte(`{"owner":{"login":"user","type":"User"}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		}
+	}))
+
+	for repo, expected := range map[string]bool{"org/repo": true, "user/repo": false} {
+		org, err := client.OwnerIsOrganization(context.Background(), repo)
+		if err != nil || org != expected {
+			t.Errorf("%s: expected organization=%v, got %v (%v)", repo, expected, org, err)
+		}
+	}
+	if _, err := client.OwnerIsOrganization(context.Background(), "owner/missing"); err == nil {
+		t.Error("Expected an error for an unknown repository")
+	}
+}
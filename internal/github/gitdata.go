@@ -0,0 +1,199 @@
+package github
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// TreeFile is a file to be committed through the Git data API.
+type TreeFile struct {
+	Path    string // Path inside the repository
+	Content []byte // Raw file contents
+}
+
+// CommitResult describes a commit created by CommitFiles.
+type CommitResult struct {
+	SHA      string            // SHA of the new commit
+	URLs     map[string]string // Raw download URL per committed path
+	BlobSHAs map[string]string // SHA of the blob GitHub stored per committed path
+}
+
+// BlobSHA returns the Git blob hash of content, as computed by GitHub for an
+// uploaded blob.
+func BlobSHA(content []byte) string {
+	hash := sha1.New()
+	fmt.Fprintf(hash, "blob %d\x00", len(content))
+	hash.Write(content)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// CommitFiles writes all files to branch in a single commit using the Git
+// data API (blobs, tree, commit, ref). When the branch does not exist yet it
+// is created as an orphan branch containing only the committed files.
+func (c *Client) CommitFiles(ctx context.Context, repo, branch, message string, files []TreeFile) (*CommitResult, error) {
+	owner, name, err := splitRepository(repo)
+	if err != nil {
+		return nil, err
+	}
+	if strings.TrimSpace(branch) == "" {
+		return nil, fmt.Errorf("branch cannot be empty")
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files to commit")
+	}
+
+	base := fmt.Sprintf("/repos/%s/%s/git", url.PathEscape(owner), url.PathEscape(name))
+
+	parentSHA, baseTreeSHA, err := c.branchHead(ctx, base, branch)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]map[string]string, 0, len(files))
+	blobSHAs := make(map[string]string, len(files))
+	for _, file := range files {
+		var blob struct {
+			SHA string `json:"sha"`
+		}
+		err := c.restRequest(ctx, http.MethodPost, base+"/blobs", map[string]string{
+			"content":  base64.StdEncoding.EncodeToString(file.Content),
+			"encoding": "base64",
+		}, &blob)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create blob for %s: %w", file.Path, err)
+		}
+		blobSHAs[file.Path] = blob.SHA
+		entries = append(entries, map[string]string{
+			"path": file.Path,
+			"mode": "100644",
+			"type": "blob",
+			"sha":  blob.SHA,
+		})
+	}
+
+	treeRequest := map[string]interface{}{"tree": entries}
+	if baseTreeSHA != "" {
+		treeRequest["base_tree"] = baseTreeSHA
+	}
+	var tree struct {
+		SHA string `json:"sha"`
+	}
+	if err := c.restRequest(ctx, http.MethodPost, base+"/trees", treeRequest, &tree); err != nil {
+		return nil, fmt.Errorf("failed to create tree: %w", err)
+	}
+
+	parents := []string{}
+	if parentSHA != "" {
+		parents = append(parents, parentSHA)
+	}
+	var commit struct {
+		SHA string `json:"sha"`
+	}
+	err = c.restRequest(ctx, http.MethodPost, base+"/commits", map[string]interface{}{
+		"message": message,
+		"tree":    tree.SHA,
+		"parents": parents,
+	}, &commit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create commit: %w", err)
+	}
+
+	if parentSHA != "" {
+		err = c.restRequest(ctx, http.MethodPatch, base+"/refs/heads/"+escapeRefPath(branch), map[string]string{
+			"sha": commit.SHA,
+		}, nil)
+	} else {
+		err = c.restRequest(ctx, http.MethodPost, base+"/refs", map[string]string{
+			"ref": "refs/heads/" + branch,
+			"sha": commit.SHA,
+		}, nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to update branch %s: %w", branch, err)
+	}
+
+	result := &CommitResult{SHA: commit.SHA, URLs: make(map[string]string, len(files)), BlobSHAs: blobSHAs}
+	for _, file := range files {
+		result.URLs[file.Path] = RawFileURL(repo, branch, file.Path)
+	}
+	return result, nil
+}
+
+// branchHead returns the head commit and tree SHAs of branch, or empty
+// strings when the branch does not exist.
+func (c *Client) branchHead(ctx context.Context, base, branch string) (string, string, error) {
+	var ref struct {
+		Object struct {
+			SHA string `json:"sha"`
+		} `json:"object"`
+	}
+	err := c.restRequest(ctx, http.MethodGet, base+"/ref/heads/"+escapeRefPath(branch), nil, &ref)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+		return "", "", nil
+	}
+	if err != nil {
+		return "", "", fmt.Errorf("failed to look up branch %s: %w", branch, err)
+	}
+
+	var commit struct {
+		Tree struct {
+			SHA string `json:"sha"`
+		} `json:"tree"`
+	}
+	if err := c.restRequest(ctx, http.MethodGet, base+"/commits/"+ref.Object.SHA, nil, &commit); err != nil {
+		return "", "", fmt.Errorf("failed to look up commit %s: %w", ref.Object.SHA, err)
+	}
+
+	return ref.Object.SHA, commit.Tree.SHA, nil
+}
+
+// DefaultBranch returns the name of repo's default branch.
+func (c *Client) DefaultBranch(ctx context.Context, repo string) (string, error) {
+	owner, name, err := splitRepository(repo)
+	if err != nil {
+		return "", err
+	}
+
+	var repository struct {
+		DefaultBranch string `json:"default_branch"`
+	}
+	path := fmt.Sprintf("/repos/%s/%s", url.PathEscape(owner), url.PathEscape(name))
+	if err := c.restRequest(ctx, http.MethodGet, path, nil, &repository); err != nil {
+		return "", fmt.Errorf("failed to look up repository %s: %w", repo, err)
+	}
+	if repository.DefaultBranch == "" {
+		return "", fmt.Errorf("repository %s has no default branch", repo)
+	}
+	return repository.DefaultBranch, nil
+}
+
+// RawFileURL returns the raw download URL of a file on a branch.
+func RawFileURL(repo, branch, path string) string {
+	return fmt.Sprintf("https://raw.githubusercontent.com/%s/%s/%s", repo, escapeRefPath(branch), escapeRefPath(path))
+}
+
+// escapeRefPath escapes each segment of a slash-separated path.
+func escapeRefPath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
+}
+
+func splitRepository(repo string) (string, string, error) {
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
+		return "", "", fmt.Errorf("invalid repository format - expected 'owner/repo'")
+	}
+	return parts[0], parts[1], nil
+}
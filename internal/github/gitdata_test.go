@@ -0,0 +1,325 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeGitDataAPI implements the subset of the Git data REST API used by CommitFiles.
+type fakeGitDataAPI struct {
+	mu           sync.Mutex
+	branchExists bool
+	blobs        int
+	tree         map[string]interface{}
+	parents      []string
+	refCreated   string
+	refUpdated   bool
+
+	// blobRateLimits is the number of blob requests rejected with a
+	// secondary rate limit response before blobs are accepted.
+	blobRateLimits int
+
+	headFailures   int // Branch lookups failing with a server error
+	commitFailures int // Commit requests failing with a server error
+	headLookups    int
+	commitRequests int
+}
+
+func (f *fakeGitDataAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	path := strings.TrimPrefix(r.URL.Path, "/repos/owner/repo/git")
+	var body map[string]interface{}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+
+	switch {
+	case r.Method == http.MethodGet && strings.HasPrefix(path, "/ref/heads/"):
+		f.headLookups++
+		if f.headFailures > 0 {
+			f.headFailures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		if !f.branchExists {
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"object":{"sha":"head-sha"}}`))
+	case r.Method == http.MethodGet && path == "/commits/head-sha":
+		_, _ = w.Write([]byte(`{"tree":{"sha":"base-tree-sha"}}`))
+	case r.Method == http.MethodPost && path == "/blobs":
+		if f.blobRateLimits > 0 {
+			f.blobRateLimits--
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+			return
+		}
+		f.blobs++
+		_, _ = w.Write([]byte(`{"sha":"blob-sha"}`))
+	case r.Method == http.MethodPost && path == "/trees":
+		f.tree = body
+		_, _ = w.Write([]byte(`{"sha":"tree-sha"}`))
+	case r.Method == http.MethodPost && path == "/commits":
+		f.commitRequests++
+		if f.commitFailures > 0 {
+			f.commitFailures--
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		for _, p := range body["parents"].([]interface{}) {
+			f.parents = append(f.parents, p.(string))
+		}
+		_, _ = w.Write([]byte(`{"sha":"commit-sha"}`))
+	case r.Method == http.MethodPatch && strings.HasPrefix(path, "/refs/heads/"):
+		f.refUpdated = true
+		_, _ = w.Write([]byte(`{}`))
+	case r.Method == http.MethodPost && path == "/refs":
+		f.refCreated = body["ref"].(string)
+		_, _ = w.Write([]byte(`{}`))
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func newTestRESTClient(t *testing.T, handler http.Handler) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 1*time.Millisecond, 2, 1)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetRESTBaseURL(server.URL)
+	return client
+}
+
+func TestClient_CommitFilesMultiFileTree(t *testing.T) {
+	api := &fakeGitDataAPI{branchExists: true}
+	client := newTestRESTClient(t, api)
+
+	files := []TreeFile{
+		{Path: "attachments/thread-1/attachment_1_a.png", Content: []byte("a")},
+		{Path: "attachments/thread-1/attachment_2_b file.pdf", Content: []byte("b")},
+	}
+
+	result, err := client.CommitFiles(context.Background(), "owner/repo", "forum-assets", "Add attachments", files)
+	if err != nil {
+		t.Fatalf("CommitFiles returned error: %v", err)
+	}
+
+	if api.blobs != 2 {
+		t.Errorf("Expected 2 blobs, got %d", api.blobs)
+	}
+	if entries := api.tree["tree"].([]interface{}); len(entries) != 2 {
+		t.Errorf("Expected 2 tree entries, got %d", len(entries))
+	}
+	if api.tree["base_tree"] != "base-tree-sha" {
+		t.Errorf("Expected tree to build on base tree, got %v", api.tree["base_tree"])
+	}
+	if len(api.parents) != 1 || api.parents[0] != "head-sha" {
+		t.Errorf("Expected commit parent head-sha, got %v", api.parents)
+	}
+	if !api.refUpdated {
+		t.Error("Expected existing branch ref to be updated")
+	}
+
+	if result.SHA != "commit-sha" {
+		t.Errorf("Expected commit SHA commit-sha, got %s", result.SHA)
+	}
+	expectedURLs := map[string]string{
+		"attachments/thread-1/attachment_1_a.png":      "https://raw.githubusercontent.com/owner/repo/forum-assets/attachments/thread-1/attachment_1_a.png",
+		"attachments/thread-1/attachment_2_b file.pdf": "https://raw.githubusercontent.com/owner/repo/forum-assets/attachments/thread-1/attachment_2_b%20file.pdf",
+	}
+	for path, expected := range expectedURLs {
+		if result.URLs[path] != expected {
+			t.Errorf("Expected URL %q for %s, got %q", expected, path, result.URLs[path])
+		}
+		if result.BlobSHAs[path] != "blob-sha" {
+			t.Errorf("Expected stored blob SHA for %s, got %q", path, result.BlobSHAs[path])
+		}
+	}
+}
+
+func TestBlobSHA(t *testing.T) {
+	// Matches `echo hello | git hash-object --stdin`
+	if sha := BlobSHA([]byte("hello\n")); sha != "ce013625030ba8dba906f756967f9e9ca394464a" {
+		t.Errorf("Unexpected blob SHA %s", sha)
+	}
+}
+
+func TestClient_CommitFilesCreatesMissingBranch(t *testing.T) {
+	api := &fakeGitDataAPI{branchExists: false}
+	client := newTestRESTClient(t, api)
+
+	_, err := client.CommitFiles(context.Background(), "owner/repo", "forum-assets", "Add attachments",
+		[]TreeFile{{Path: "a.png", Content: []byte("a")}})
+	if err != nil {
+		t.Fatalf("CommitFiles returned error: %v", err)
+	}
+
+	if _, ok := api.tree["base_tree"]; ok {
+		t.Error("Orphan branch tree should not have a base tree")
+	}
+	if len(api.parents) != 0 {
+		t.Errorf("Orphan commit should have no parents, got %v", api.parents)
+	}
+	if api.refCreated != "refs/heads/forum-assets" {
+		t.Errorf("Expected branch ref to be created, got %q", api.refCreated)
+	}
+}
+
+func TestClient_CommitFilesValidation(t *testing.T) {
+	client := newTestRESTClient(t, &fakeGitDataAPI{})
+
+	if _, err := client.CommitFiles(context.Background(), "invalid", "main", "msg", []TreeFile{{Path: "a"}}); err == nil {
+		t.Error("Expected error for invalid repository")
+	}
+	if _, err := client.CommitFiles(context.Background(), "owner/repo", "main", "msg", nil); err == nil {
+		t.Error("Expected error for empty file list")
+	}
+}
+
+func TestClient_CommitFilesRetriesRateLimitedUpload(t *testing.T) {
+	api := &fakeGitDataAPI{branchExists: true, blobRateLimits: 1}
+	client := newTestRESTClient(t, api)
+
+	_, err := client.CommitFiles(context.Background(), "owner/repo", "forum-assets", "Add attachments",
+		[]TreeFile{{Path: "a.png", Content: []byte("a")}})
+	if err != nil {
+		t.Fatalf("CommitFiles should succeed after rate limit backoff, got: %v", err)
+	}
+
+	if api.blobs != 1 {
+		t.Errorf("Expected blob to be created after retry, got %d blobs", api.blobs)
+	}
+	if _, rateLimitHits := client.GetStats(); rateLimitHits != 1 {
+		t.Errorf("Expected 1 rate limit hit, got %d", rateLimitHits)
+	}
+}
+
+func TestClient_CommitFilesRateLimitExhaustsRetries(t *testing.T) {
+	api := &fakeGitDataAPI{branchExists: true, blobRateLimits: 10}
+	client := newTestRESTClient(t, api)
+
+	_, err := client.CommitFiles(context.Background(), "owner/repo", "forum-assets", "Add attachments",
+		[]TreeFile{{Path: "a.png", Content: []byte("a")}})
+
+	var rateLimitErr *RateLimitError
+	if !errors.As(err, &rateLimitErr) {
+		t.Fatalf("Expected RateLimitError after exhausting retries, got: %v", err)
+	}
+	if !errors.Is(err, ErrRateLimitExhausted) {
+		t.Errorf("Expected ErrRateLimitExhausted after exhausting retries, got: %v", err)
+	}
+}
+
+func TestClient_CommitFilesRetries(t *testing.T) {
+	tests := []struct {
+		name        string
+		api         *fakeGitDataAPI
+		shouldErr   bool
+		headLookups int
+		commits     int
+	}{
+		{name: "Failed branch lookup is retried", api: &fakeGitDataAPI{branchExists: true, headFailures: 1}, headLookups: 2, commits: 1},
+		{name: "Missing branch is looked up once", api: &fakeGitDataAPI{}, headLookups: 1, commits: 1},
+		{name: "Failed commit is not sent again", api: &fakeGitDataAPI{branchExists: true, commitFailures: 1}, shouldErr: true, headLookups: 1, commits: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestRESTClient(t, tt.api)
+			_, err := client.CommitFiles(context.Background(), "owner/repo", "forum-assets", "Add attachments",
+				[]TreeFile{{Path: "a.png", Content: []byte("a")}})
+			if (err != nil) != tt.shouldErr {
+				t.Fatalf("Expected error %v, got: %v", tt.shouldErr, err)
+			}
+			if tt.api.headLookups != tt.headLookups {
+				t.Errorf("Expected %d branch lookups, got %d", tt.headLookups, tt.api.headLookups)
+			}
+			if tt.api.commitRequests != tt.commits {
+				t.Errorf("Expected %d commit requests, got %d", tt.commits, tt.api.commitRequests)
+			}
+		})
+	}
+}
+
+func TestClient_parseRateLimitFromAPIError(t *testing.T) {
+	client := &Client{}
+	reset := time.Now().Add(30 * time.Minute).Truncate(time.Second)
+
+	tests := []struct {
+		name            string
+		err             *APIError
+		expectRateLimit bool
+		expectReset     time.Time
+	}{
+		{
+			name: "Primary rate limit with reset header",
+			err: &APIError{StatusCode: http.StatusForbidden, Message: "API rate limit exceeded", Header: http.Header{
+				"X-Ratelimit-Remaining": []string{"0"},
+				"X-Ratelimit-Reset":     []string{fmt.Sprint(reset.Unix())},
+			}},
+			expectRateLimit: true,
+			expectReset:     reset,
+		},
+		{
+			name:            "Too many requests",
+			err:             &APIError{StatusCode: http.StatusTooManyRequests, Message: "slow down", Header: http.Header{}},
+			expectRateLimit: true,
+		},
+		{
+			name:            "Plain forbidden",
+			err:             &APIError{StatusCode: http.StatusForbidden, Message: "Resource not accessible by integration", Header: http.Header{}},
+			expectRateLimit: false,
+		},
+		{
+			name:            "Server error",
+			err:             &APIError{StatusCode: http.StatusBadGateway, Message: "Bad Gateway", Header: http.Header{}},
+			expectRateLimit: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rateLimitErr, isRateLimit := client.parseRateLimitFromError(fmt.Errorf("wrapped: %w", tt.err))
+			if isRateLimit != tt.expectRateLimit {
+				t.Fatalf("Expected rate limit detection %v, got %v", tt.expectRateLimit, isRateLimit)
+			}
+			if !tt.expectReset.IsZero() && !rateLimitErr.ResetTime.Equal(tt.expectReset) {
+				t.Errorf("Expected reset time %v, got %v", tt.expectReset, rateLimitErr.ResetTime)
+			}
+		})
+	}
+}
+
+func TestClient_DefaultBranch(t *testing.T) {
+	client := newTestRESTClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/owner/repo" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		_, _ = w.Write([]byte(`{"default_branch":"trunk"}`))
+	}))
+
+	branch, err := client.DefaultBranch(context.Background(), "owner/repo")
+	if err != nil {
+		t.Fatalf("DefaultBranch returned error: %v", err)
+	}
+	if branch != "trunk" {
+		t.Errorf("Expected branch trunk, got %q", branch)
+	}
+}
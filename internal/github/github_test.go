@@ -2,7 +2,10 @@ package github
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"net/http"
 	"strings"
 	"testing"
 	"time"
@@ -47,7 +50,7 @@ func TestNewClient(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client, err := NewClient(tt.token, 1*time.Second, 3, 2)
+			client, err := NewClient(TokenAuth(tt.token), 1*time.Second, 3, 2)
 
 			if tt.shouldErr {
 				if err == nil {
@@ -74,7 +77,7 @@ func TestNewClient(t *testing.T) {
 }
 
 func TestClientRepositoryID(t *testing.T) {
-	client, err := NewClient("test_github_token_for_testing_only", 1*time.Second, 3, 2)
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 1*time.Second, 3, 2)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -150,7 +153,7 @@ func TestNewClientParameterValidation(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client, err := NewClient(tt.token, tt.rateLimitDelay, tt.maxRetries, tt.retryBackoffMultiple)
+			client, err := NewClient(TokenAuth(tt.token), tt.rateLimitDelay, tt.maxRetries, tt.retryBackoffMultiple)
 
 			if tt.shouldErr {
 				if err == nil {
@@ -189,7 +192,7 @@ func TestNewClientParameterValidation(t *testing.T) {
 }
 
 func TestClient_GetStats(t *testing.T) {
-	client, err := NewClient("test_github_token_for_testing_only", 1*time.Second, 3, 2)
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 1*time.Second, 3, 2)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -228,7 +231,7 @@ func TestRateLimitError(t *testing.T) {
 }
 
 func TestClient_parseRateLimitFromError(t *testing.T) {
-	client, err := NewClient("test_github_token_for_testing_only", 1*time.Second, 3, 2)
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 1*time.Second, 3, 2)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -303,7 +306,7 @@ func TestClient_parseRateLimitFromError(t *testing.T) {
 }
 
 func TestClient_isRetryableError(t *testing.T) {
-	client, err := NewClient("test_github_token_for_testing_only", 1*time.Second, 3, 2)
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 1*time.Second, 3, 2)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -391,7 +394,7 @@ func TestClient_isRetryableError(t *testing.T) {
 }
 
 func TestClient_executeWithRetryContextCancellation(t *testing.T) {
-	client, err := NewClient("test_github_token_for_testing_only", 100*time.Millisecond, 3, 2)
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 100*time.Millisecond, 3, 2)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -400,7 +403,7 @@ func TestClient_executeWithRetryContextCancellation(t *testing.T) {
 	ctx, cancel := context.WithCancel(context.Background())
 	cancel() // Cancel immediately
 
-	err = client.executeWithRetry(ctx, func() error {
+	err = client.executeWithRetry(ctx, resourceGraphQL, func() error {
 		return errors.New("test error")
 	})
 
@@ -413,7 +416,7 @@ func TestClient_executeWithRetryContextCancellation(t *testing.T) {
 }
 
 func TestClient_executeWithRetrySuccess(t *testing.T) {
-	client, err := NewClient("test_github_token_for_testing_only", 1*time.Millisecond, 3, 2)
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 1*time.Millisecond, 3, 2)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -421,7 +424,7 @@ func TestClient_executeWithRetrySuccess(t *testing.T) {
 	ctx := context.Background()
 	callCount := 0
 
-	err = client.executeWithRetry(ctx, func() error {
+	err = client.executeWithRetry(ctx, resourceGraphQL, func() error {
 		callCount++
 		if callCount < 2 {
 			return errors.New("temporary failure")
@@ -438,7 +441,7 @@ func TestClient_executeWithRetrySuccess(t *testing.T) {
 }
 
 func TestClient_executeWithRetryMaxRetries(t *testing.T) {
-	client, err := NewClient("test_github_token_for_testing_only", 1*time.Millisecond, 2, 2)
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), 1*time.Millisecond, 2, 2)
 	if err != nil {
 		t.Fatalf("Failed to create client: %v", err)
 	}
@@ -446,7 +449,7 @@ func TestClient_executeWithRetryMaxRetries(t *testing.T) {
 	ctx := context.Background()
 	callCount := 0
 
-	err = client.executeWithRetry(ctx, func() error {
+	err = client.executeWithRetry(ctx, resourceGraphQL, func() error {
 		callCount++
 		return errors.New("persistent failure")
 	})
@@ -462,3 +465,52 @@ func TestClient_executeWithRetryMaxRetries(t *testing.T) {
 		t.Errorf("Expected 3 calls (1 initial + 2 retries), got %d", callCount)
 	}
 }
+
+func TestClient_GetDiscussionContentReplies(t *testing.T) {
+	var cursors []string
+	client := newTestGraphQLClient(t, func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		if !strings.Contains(req.Query, "node(id: $id)") {
+			_, _ = fmt.Fprint(w, `{"data":{"repository":{"discussion":{"id":"D_1","title":"Title","body":"Body","comments":{
+				"nodes":[{"id":"C_1","body":"Comment","createdAt":"2022-01-01T00:00:01Z","replies":{
+					"nodes":[{"id":"R_1","body":"Reply 1","createdAt":"2022-01-01T00:00:02Z"}],
+					"pageInfo":{"hasNextPage":true,"endCursor":"r1"}}}],
+				"pageInfo":{"hasNextPage":false,"endCursor":"c1"}}}}}}`)
+			return
+		}
+
+		// The remaining replies follow over two pages
+		cursor, _ := req.Variables["cursor"].(string)
+		cursors = append(cursors, cursor)
+		if cursor == "r1" {
+			_, _ = fmt.Fprint(w, `{"data":{"node":{"replies":{
+				"nodes":[{"id":"R_2","body":"Reply 2","createdAt":"2022-01-01T00:00:03Z"}],
+				"pageInfo":{"hasNextPage":true,"endCursor":"r2"}}}}}`)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"data":{"node":{"replies":{
+			"nodes":[{"id":"R_3","body":"Reply 3","createdAt":"2022-01-01T00:00:04Z"}],
+			"pageInfo":{"hasNextPage":false,"endCursor":"r3"}}}}}`)
+	})
+
+	content, err := client.GetDiscussionContent(context.Background(), "owner/repo", 1)
+	if err != nil {
+		t.Fatalf("GetDiscussionContent returned error: %v", err)
+	}
+
+	expected := []string{"C_1", "R_1", "R_2", "R_3"}
+	if strings.Join(content.CommentIDs, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected comments %v, got %v", expected, content.CommentIDs)
+	}
+	if parents := []string{"", "C_1", "C_1", "C_1"}; strings.Join(content.ParentIDs, ",") != strings.Join(parents, ",") {
+		t.Errorf("Expected parents %v, got %v", parents, content.ParentIDs)
+	}
+	if strings.Join(cursors, ",") != "r1,r2" {
+		t.Errorf("Expected reply pages after r1 and r2, got %v", cursors)
+	}
+}
@@ -0,0 +1,129 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/shurcooL/githubv4"
+)
+
+// DefaultLabelColor is the color of labels created for thread prefixes.
+const DefaultLabelColor = "ededed"
+
+// LabelID returns the ID of a repository label, or an empty ID when the
+// repository has no label with that name.
+func (c *Client) LabelID(ctx context.Context, repo, name string) (string, error) {
+	owner, repoName, err := splitRepository(repo)
+	if err != nil {
+		return "", err
+	}
+
+	var labelID string
+
+	err = c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var query struct {
+			RateLimit  queryCost
+			Repository struct {
+				Label *struct {
+					ID string
+				} `graphql:"label(name: $label)"`
+			} `graphql:"repository(owner: $owner, name: $name)"`
+		}
+
+		variables := map[string]interface{}{
+			"owner": githubv4.String(owner),
+			"name":  githubv4.String(repoName),
+			"label": githubv4.String(name),
+		}
+
+		if err := c.client.Query(ctx, &query, variables); err != nil {
+			return fmt.Errorf("failed to look up label %q: %w", name, err)
+		}
+		c.quota.recordCost(resourceGraphQL, query.RateLimit.Cost)
+
+		labelID = ""
+		if query.Repository.Label != nil {
+			labelID = query.Repository.Label.ID
+		}
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return labelID, nil
+}
+
+// CreateLabel creates a label in the target repository and returns its ID.
+// color is a hex color without the leading '#'.
+func (c *Client) CreateLabel(ctx context.Context, name, color string) (string, error) {
+	if strings.TrimSpace(name) == "" {
+		return "", fmt.Errorf("label name cannot be empty")
+	}
+
+	var labelID string
+
+	err := c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var mutation struct {
+			CreateLabel struct {
+				Label struct {
+					ID string
+				}
+			} `graphql:"createLabel(input: $input)"`
+		}
+
+		input := githubv4.CreateLabelInput{
+			RepositoryID: githubv4.ID(c.repositoryID),
+			Name:         githubv4.String(name),
+			Color:        githubv4.String(color),
+		}
+
+		if err := c.client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to create label %q: %w", name, err)
+		}
+
+		labelID = mutation.CreateLabel.Label.ID
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return labelID, nil
+}
+
+// AddLabels adds labels to a discussion, issue or pull request.
+func (c *Client) AddLabels(ctx context.Context, labelableID string, labelIDs ...string) error {
+	if strings.TrimSpace(labelableID) == "" {
+		return fmt.Errorf("labelableID cannot be empty")
+	}
+	if len(labelIDs) == 0 {
+		return nil
+	}
+
+	ids := make([]githubv4.ID, len(labelIDs))
+	for i, id := range labelIDs {
+		ids[i] = githubv4.ID(id)
+	}
+
+	return c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var mutation struct {
+			AddLabelsToLabelable struct {
+				Typename string `graphql:"__typename"`
+			} `graphql:"addLabelsToLabelable(input: $input)"`
+		}
+
+		input := githubv4.AddLabelsToLabelableInput{
+			LabelableID: githubv4.ID(labelableID),
+			LabelIDs:    ids,
+		}
+
+		if err := c.client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to add labels to %q: %w", labelableID, err)
+		}
+		return nil
+	})
+}
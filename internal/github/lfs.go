@@ -0,0 +1,190 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLFSBaseURL = "https://github.com"
+	lfsMediaType      = "application/vnd.git-lfs+json"
+	lfsRequestTimeout = 5 * time.Minute // Longest Git LFS request, uploads included
+)
+
+// SetLFSBaseURL overrides the base URL of the Git LFS endpoints (e.g., for
+// GitHub Enterprise Server or tests).
+func (c *Client) SetLFSBaseURL(baseURL string) {
+	c.lfsBaseURL = strings.TrimRight(baseURL, "/")
+}
+
+// LFSPointer returns the Git LFS pointer file committed in place of content.
+func LFSPointer(content []byte) []byte {
+	oid, size := lfsObjectID(content)
+	return fmt.Appendf(nil, "version https://git-lfs.github.com/spec/v1\noid sha256:%s\nsize %d\n", oid, size)
+}
+
+// MediaFileURL returns the download URL of a Git LFS file on a branch.
+func MediaFileURL(repo, branch, path string) string {
+	return fmt.Sprintf("https://media.githubusercontent.com/media/%s/%s/%s", repo, escapeRefPath(branch), escapeRefPath(path))
+}
+
+func lfsObjectID(content []byte) (string, int64) {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:]), int64(len(content))
+}
+
+// lfsObject identifies an object in a Git LFS batch request.
+type lfsObject struct {
+	OID  string `json:"oid"`
+	Size int64  `json:"size"`
+}
+
+// lfsAction is a transfer GitHub asks the client to perform for an object.
+type lfsAction struct {
+	Href   string            `json:"href"`
+	Header map[string]string `json:"header"`
+}
+
+// UploadLFSObjects stores contents in the Git LFS storage of repo through the
+// batch API, so pointer files committed for them resolve to the content.
+// Objects GitHub already stores are not uploaded again.
+func (c *Client) UploadLFSObjects(ctx context.Context, repo string, contents [][]byte) error {
+	owner, name, err := splitRepository(repo)
+	if err != nil {
+		return err
+	}
+
+	objects := make([]lfsObject, len(contents))
+	byOID := make(map[string][]byte, len(contents))
+	for i, content := range contents {
+		objects[i].OID, objects[i].Size = lfsObjectID(content)
+		byOID[objects[i].OID] = content
+	}
+
+	var batch struct {
+		Objects []struct {
+			lfsObject
+			Actions map[string]lfsAction `json:"actions"`
+			Error   *struct {
+				Code    int    `json:"code"`
+				Message string `json:"message"`
+			} `json:"error"`
+		} `json:"objects"`
+	}
+	batchURL := fmt.Sprintf("%s/%s/%s.git/info/lfs/objects/batch", c.lfsBaseURL, url.PathEscape(owner), url.PathEscape(name))
+	err = c.executeWithRetry(ctx, resourceLFS, func() error {
+		return c.lfsRequest(ctx, http.MethodPost, batchURL, nil, map[string]interface{}{
+			"operation": "upload",
+			"transfers": []string{"basic"},
+			"objects":   objects,
+		}, &batch)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to request LFS upload: %w", err)
+	}
+
+	for _, object := range batch.Objects {
+		if object.Error != nil {
+			return fmt.Errorf("LFS rejected object %s: %s (code %d)", object.OID, object.Error.Message, object.Error.Code)
+		}
+		upload, ok := object.Actions["upload"]
+		if !ok {
+			continue // Already stored
+		}
+
+		err := c.executeWithRetry(ctx, resourceLFS, func() error {
+			return c.lfsRequest(ctx, http.MethodPut, upload.Href, upload.Header, byOID[object.OID], nil)
+		})
+		if err != nil {
+			return fmt.Errorf("failed to upload LFS object %s: %w", object.OID, err)
+		}
+
+		if verify, ok := object.Actions["verify"]; ok {
+			err := c.executeWithRetry(ctx, resourceLFS, func() error {
+				return c.lfsRequest(ctx, http.MethodPost, verify.Href, verify.Header, object.lfsObject, nil)
+			})
+			if err != nil {
+				return fmt.Errorf("failed to verify LFS object %s: %w", object.OID, err)
+			}
+		}
+	}
+	return nil
+}
+
+// lfsRequest performs a single Git LFS request. Raw content is sent as is and
+// other bodies JSON-encoded. Requests without action headers authenticate
+// with the token; action headers carry their own authorization, so requests
+// go through a client of their own rather than the authenticated one.
+func (c *Client) lfsRequest(ctx context.Context, method, target string, header map[string]string, body, out interface{}) error {
+	var reader io.Reader
+	contentType := lfsMediaType
+	switch body := body.(type) {
+	case nil:
+	case []byte:
+		reader = bytes.NewReader(body)
+		contentType = "application/octet-stream"
+	default:
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", lfsMediaType)
+	if reader != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if header == nil {
+		token, err := c.tokens.Token()
+		if err != nil {
+			return fmt.Errorf("failed to get GitHub access token: %w", err)
+		}
+		req.SetBasicAuth("x-access-token", token.AccessToken)
+	}
+	for key, value := range header {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := c.lfsClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, req.URL.Redacted(), err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var payload struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &payload) == nil && payload.Message != "" {
+			message = payload.Message
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: message, Header: resp.Header}
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLFSPointer(t *testing.T) {
+	expected := "version https://git-lfs.github.com/spec/v1\n" +
+		"oid sha256:2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824\n" +
+		"size 5\n"
+	if pointer := string(LFSPointer([]byte("hello"))); pointer != expected {
+		t.Errorf("Expected pointer %q, got %q", expected, pointer)
+	}
+}
+
+func TestClient_UploadLFSObjects(t *testing.T) {
+	stored, _ := lfsObjectID([]byte("stored"))
+	missing, _ := lfsObjectID([]byte("missing"))
+
+	var mu sync.Mutex
+	var uploads []string
+	verified := 0
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		switch r.URL.Path {
+		case "/owner/repo.git/info/lfs/objects/batch":
+			if user, token, ok := r.BasicAuth(); !ok || user != "x-access-token" || token != "test_github_token_for_testing_only" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			var batch struct {
+				Operation string      `json:"operation"`
+				Objects   []lfsObject `json:"objects"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&batch)
+			if batch.Operation != "upload" || len(batch.Objects) != 2 {
+				t.Errorf("Unexpected batch request: %+v", batch)
+			}
+			_, _ = io.WriteString(w, `{"objects":[
+				{"oid":"`+stored+`","size":6},
+				{"oid":"`+missing+`","size":7,"actions":{
+					"upload":{"href":"`+server.URL+`/upload","header":{"Authorization":"RemoteAuth upload"}},
+					"verify":{"href":"`+server.URL+`/verify","header":{"Authorization":"RemoteAuth verify"}}}}]}`)
+		case "/upload":
+			if r.Method != http.MethodPut || r.Header.Get("Authorization") != "RemoteAuth upload" {
+				t.Errorf("Unexpected upload request %s with authorization %q", r.Method, r.Header.Get("Authorization"))
+			}
+			body, _ := io.ReadAll(r.Body)
+			uploads = append(uploads, string(body))
+		case "/verify":
+			var object lfsObject
+			_ = json.NewDecoder(r.Body).Decode(&object)
+			if object.OID != missing || object.Size != 7 {
+				t.Errorf("Unexpected verify request: %+v", object)
+			}
+			verified++
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), time.Millisecond, 2, 1)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetLFSBaseURL(server.URL)
+
+	if err := client.UploadLFSObjects(context.Background(), "owner/repo", [][]byte{[]byte("stored"), []byte("missing")}); err != nil {
+		t.Fatalf("UploadLFSObjects returned error: %v", err)
+	}
+
+	if strings.Join(uploads, ",") != "missing" {
+		t.Errorf("Expected only the missing object to be uploaded, got %q", uploads)
+	}
+	if verified != 1 {
+		t.Errorf("Expected 1 verification, got %d", verified)
+	}
+}
+
+func TestClient_UploadLFSObjectsTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	client, err := NewClient(TokenAuth("test_github_token_for_testing_only"), time.Millisecond, 0, 1)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetLFSBaseURL(server.URL)
+	client.lfsClient.Timeout = 50 * time.Millisecond
+
+	// A stalled LFS server fails the upload instead of hanging it
+	if err := client.UploadLFSObjects(context.Background(), "owner/repo", [][]byte{[]byte("content")}); err == nil {
+		t.Error("Expected an error from a stalled LFS server")
+	}
+}
@@ -27,7 +27,7 @@ func (c *Client) CreateDiscussion(ctx context.Context, title, body, categoryID s
 
 	var result *DiscussionResult
 
-	err := c.executeWithRetry(ctx, func() error {
+	err := c.executeWithRetry(ctx, resourceGraphQL, func() error {
 		var mutation struct {
 			CreateDiscussion struct {
 				Discussion struct {
@@ -65,15 +65,24 @@ func (c *Client) CreateDiscussion(ctx context.Context, title, body, categoryID s
 }
 
 func (c *Client) AddComment(ctx context.Context, discussionID, body string) error {
+	_, err := c.AddCommentReply(ctx, discussionID, "", body)
+	return err
+}
+
+// AddCommentReply adds a comment to a discussion and returns its ID. When
+// replyToID is set the comment is threaded under that top-level comment.
+func (c *Client) AddCommentReply(ctx context.Context, discussionID, replyToID, body string) (string, error) {
 	// Input validation
 	if strings.TrimSpace(discussionID) == "" {
-		return fmt.Errorf("discussionID cannot be empty")
+		return "", fmt.Errorf("discussionID cannot be empty")
 	}
 	if strings.TrimSpace(body) == "" {
-		return fmt.Errorf("comment body cannot be empty")
+		return "", fmt.Errorf("comment body cannot be empty")
 	}
 
-	return c.executeWithRetry(ctx, func() error {
+	var commentID string
+
+	err := c.executeWithRetry(ctx, resourceGraphQL, func() error {
 		var mutation struct {
 			AddDiscussionComment struct {
 				Comment struct {
@@ -86,12 +95,194 @@ func (c *Client) AddComment(ctx context.Context, discussionID, body string) erro
 			DiscussionID: githubv4.ID(discussionID),
 			Body:         githubv4.String(body),
 		}
+		if replyToID != "" {
+			replyTo := githubv4.ID(replyToID)
+			input.ReplyToID = &replyTo
+		}
 
 		err := c.client.Mutate(ctx, &mutation, input, nil)
 		if err != nil {
 			return fmt.Errorf("failed to add comment to discussion %q: %w", discussionID, err)
 		}
 
+		commentID = fmt.Sprint(mutation.AddDiscussionComment.Comment.ID)
+		return nil
+	})
+
+	if err != nil {
+		return "", err
+	}
+
+	return commentID, nil
+}
+
+// DeleteDiscussion permanently deletes a discussion and its comments.
+func (c *Client) DeleteDiscussion(ctx context.Context, discussionID string) error {
+	if strings.TrimSpace(discussionID) == "" {
+		return fmt.Errorf("discussionID cannot be empty")
+	}
+
+	return c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var mutation struct {
+			DeleteDiscussion struct {
+				Discussion struct {
+					ID githubv4.ID
+				}
+			} `graphql:"deleteDiscussion(input: $input)"`
+		}
+
+		input := githubv4.DeleteDiscussionInput{
+			ID: githubv4.ID(discussionID),
+		}
+
+		if err := c.client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to delete discussion %q: %w", discussionID, err)
+		}
+		return nil
+	})
+}
+
+// UpdateDiscussion replaces the title and body of a discussion. An empty
+// title keeps the current one.
+func (c *Client) UpdateDiscussion(ctx context.Context, discussionID, title, body string) error {
+	if strings.TrimSpace(discussionID) == "" {
+		return fmt.Errorf("discussionID cannot be empty")
+	}
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("discussion body cannot be empty")
+	}
+
+	return c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var mutation struct {
+			UpdateDiscussion struct {
+				Discussion struct {
+					ID githubv4.ID
+				}
+			} `graphql:"updateDiscussion(input: $input)"`
+		}
+
+		input := githubv4.UpdateDiscussionInput{
+			DiscussionID: githubv4.ID(discussionID),
+			Body:         githubv4.NewString(githubv4.String(body)),
+		}
+		if title != "" {
+			input.Title = githubv4.NewString(githubv4.String(title))
+		}
+
+		if err := c.client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to update discussion %q: %w", discussionID, err)
+		}
+		return nil
+	})
+}
+
+// UpdateDiscussionComment replaces the body of a discussion comment or reply.
+func (c *Client) UpdateDiscussionComment(ctx context.Context, commentID, body string) error {
+	if strings.TrimSpace(commentID) == "" {
+		return fmt.Errorf("commentID cannot be empty")
+	}
+	if strings.TrimSpace(body) == "" {
+		return fmt.Errorf("comment body cannot be empty")
+	}
+
+	return c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var mutation struct {
+			UpdateDiscussionComment struct {
+				Comment struct {
+					ID githubv4.ID
+				}
+			} `graphql:"updateDiscussionComment(input: $input)"`
+		}
+
+		input := githubv4.UpdateDiscussionCommentInput{
+			CommentID: githubv4.ID(commentID),
+			Body:      githubv4.String(body),
+		}
+
+		if err := c.client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to update comment %q: %w", commentID, err)
+		}
+		return nil
+	})
+}
+
+// LockDiscussion locks a discussion so only collaborators can comment.
+func (c *Client) LockDiscussion(ctx context.Context, discussionID string) error {
+	if strings.TrimSpace(discussionID) == "" {
+		return fmt.Errorf("discussionID cannot be empty")
+	}
+
+	return c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var mutation struct {
+			LockLockable struct {
+				LockedRecord struct {
+					Locked bool
+				}
+			} `graphql:"lockLockable(input: $input)"`
+		}
+
+		input := githubv4.LockLockableInput{
+			LockableID: githubv4.ID(discussionID),
+		}
+
+		if err := c.client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to lock discussion %q: %w", discussionID, err)
+		}
+		return nil
+	})
+}
+
+// AddReaction reacts to a discussion or comment as the token user. content is
+// a GitHub reaction such as "THUMBS_UP" or "HEART".
+func (c *Client) AddReaction(ctx context.Context, subjectID, content string) error {
+	if strings.TrimSpace(subjectID) == "" {
+		return fmt.Errorf("subjectID cannot be empty")
+	}
+
+	return c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var mutation struct {
+			AddReaction struct {
+				Reaction struct {
+					Content githubv4.ReactionContent
+				}
+			} `graphql:"addReaction(input: $input)"`
+		}
+
+		input := githubv4.AddReactionInput{
+			SubjectID: githubv4.ID(subjectID),
+			Content:   githubv4.ReactionContent(content),
+		}
+
+		if err := c.client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to add %s reaction to %q: %w", content, subjectID, err)
+		}
+		return nil
+	})
+}
+
+// MarkCommentAsAnswer marks a top-level discussion comment as the answer of
+// its discussion. The discussion must be in an answerable (Q&A) category.
+func (c *Client) MarkCommentAsAnswer(ctx context.Context, commentID string) error {
+	if strings.TrimSpace(commentID) == "" {
+		return fmt.Errorf("commentID cannot be empty")
+	}
+
+	return c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var mutation struct {
+			MarkDiscussionCommentAsAnswer struct {
+				Discussion struct {
+					ID githubv4.ID
+				}
+			} `graphql:"markDiscussionCommentAsAnswer(input: $input)"`
+		}
+
+		input := githubv4.MarkDiscussionCommentAsAnswerInput{
+			ID: githubv4.ID(commentID),
+		}
+
+		if err := c.client.Mutate(ctx, &mutation, input, nil); err != nil {
+			return fmt.Errorf("failed to mark comment %q as answer: %w", commentID, err)
+		}
 		return nil
 	})
 }
@@ -0,0 +1,162 @@
+package github
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// quotaPaceThreshold is the share of the hourly quota below which requests
+// are spread over the time left until the quota resets.
+const quotaPaceThreshold = 0.5
+
+// Rate limit resources, as named by the X-RateLimit-Resource header. Each has
+// its own quota.
+const (
+	resourceCore    = "core"    // REST API, and responses naming no resource
+	resourceGraphQL = "graphql" // GraphQL API
+	resourceLFS     = "lfs"     // Git LFS, which reports no quota of its own
+)
+
+// quota is the GitHub API rate limit state reported by the X-RateLimit-*
+// headers of the latest response of each resource. GitHub sends them on
+// every GraphQL and REST response, mutations included, where the rateLimit
+// object cannot be queried.
+type quota struct {
+	mu         sync.Mutex
+	resources  map[string]*resourceQuota
+	retryAfter time.Time // Secondary rate limit wait requested by Retry-After
+}
+
+// resourceQuota is the rate limit state of one resource.
+type resourceQuota struct {
+	limit     int
+	remaining int
+	resetAt   time.Time
+	cost      int // Points the latest response used (at least 1)
+}
+
+// update records the rate limit headers of a response. A response costs a
+// point unless its GraphQL rateLimit object reports otherwise, see
+// recordCost. Responses without the headers, e.g. from test servers, leave
+// the state unchanged.
+func (q *quota) update(header http.Header, now time.Time) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if seconds, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+		q.retryAfter = now.Add(time.Duration(seconds) * time.Second)
+	}
+
+	remaining, err := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	var resetAt time.Time
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		resetAt = time.Unix(reset, 0)
+	}
+
+	resource := header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = resourceCore
+	}
+	if q.resources == nil {
+		q.resources = make(map[string]*resourceQuota)
+	}
+	q.resources[resource] = &resourceQuota{limit: limit, remaining: remaining, resetAt: resetAt, cost: 1}
+}
+
+// recordCost sets the points the latest response of resource used, as
+// reported by a GraphQL query's rateLimit { cost }.
+func (q *quota) recordCost(resource string, cost int) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if state, ok := q.resources[resource]; ok {
+		state.cost = max(cost, 1)
+	}
+}
+
+// queryCost selects a GraphQL query's rateLimit { cost }, the points the
+// query used, for recordCost.
+type queryCost struct {
+	Cost int
+}
+
+// status returns the latest rate limit state of resource, if any.
+func (q *quota) status(resource string) (resourceQuota, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	state, ok := q.resources[resource]
+	if !ok {
+		return resourceQuota{}, false
+	}
+	return *state, true
+}
+
+// delay returns how long to wait before the next request on resource: the
+// base delay while more than quotaPaceThreshold of its quota is left, the
+// time until the reset spread over the requests the remaining points allow
+// once below it, and the full wait when the quota is exhausted or GitHub
+// asked to retry later.
+func (q *quota) delay(resource string, base time.Duration, now time.Time) time.Duration {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if wait := q.retryAfter.Sub(now); wait > base {
+		return wait
+	}
+	state, ok := q.resources[resource]
+	if !ok || state.limit <= 0 || !state.resetAt.After(now) {
+		return base
+	}
+	if float64(state.remaining) > float64(state.limit)*quotaPaceThreshold {
+		return base
+	}
+
+	untilReset := state.resetAt.Sub(now)
+	if state.remaining < state.cost {
+		return untilReset
+	}
+	requests := state.remaining / state.cost
+	return max(base, untilReset/time.Duration(requests))
+}
+
+// resetTime returns when GitHub allows requests again after a rate limit
+// error: the Retry-After deadline, or the earliest reset of a resource whose
+// points are used up. Returns false when the headers did not say.
+func (q *quota) resetTime(now time.Time) (time.Time, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.retryAfter.After(now) {
+		return q.retryAfter, true
+	}
+	var resetAt time.Time
+	for _, state := range q.resources {
+		if state.remaining == 0 && state.resetAt.After(now) && (resetAt.IsZero() || state.resetAt.Before(resetAt)) {
+			resetAt = state.resetAt
+		}
+	}
+	return resetAt, !resetAt.IsZero()
+}
+
+// quotaTransport records the rate limit headers of every response on the
+// authenticated HTTP client, covering typed GraphQL, raw GraphQL and REST
+// requests alike.
+type quotaTransport struct {
+	base  http.RoundTripper
+	quota *quota
+}
+
+func (t *quotaTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if resp != nil {
+		t.quota.update(resp.Header, time.Now())
+	}
+	return resp, err
+}
@@ -0,0 +1,108 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func rateLimitHeader(resource string, limit, remaining int, reset time.Time) http.Header {
+	header := http.Header{}
+	header.Set("X-RateLimit-Resource", resource)
+	header.Set("X-RateLimit-Limit", strconv.Itoa(limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+	return header
+}
+
+func TestQuotaDelay(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	reset := now.Add(10 * time.Minute)
+	base := time.Second
+
+	tests := []struct {
+		name      string
+		responses []http.Header
+		cost      int // GraphQL cost of the latest response (0 = none reported)
+		expected  time.Duration
+	}{
+		{name: "no headers", expected: base},
+		{name: "plenty left", responses: []http.Header{rateLimitHeader("graphql", 5000, 4000, reset)}, expected: base},
+		{name: "spread over reset", responses: []http.Header{rateLimitHeader("graphql", 5000, 100, reset)}, expected: 6 * time.Second},
+		{name: "spread by request cost", responses: []http.Header{rateLimitHeader("graphql", 5000, 100, reset)}, cost: 10, expected: time.Minute},
+		{
+			name:      "cost of the latest response",
+			responses: []http.Header{rateLimitHeader("graphql", 5000, 110, reset), rateLimitHeader("graphql", 5000, 100, reset)},
+			expected:  6 * time.Second,
+		},
+		{name: "exhausted", responses: []http.Header{rateLimitHeader("graphql", 5000, 0, reset)}, expected: 10 * time.Minute},
+		{name: "other resource exhausted", responses: []http.Header{rateLimitHeader("core", 5000, 0, reset)}, expected: base},
+		{
+			name:      "no resource header",
+			responses: []http.Header{rateLimitHeader("", 5000, 0, reset), rateLimitHeader("graphql", 5000, 4000, reset)},
+			expected:  base,
+		},
+		{name: "reset passed", responses: []http.Header{rateLimitHeader("graphql", 5000, 0, now.Add(-time.Second))}, expected: base},
+		{name: "retry after", responses: []http.Header{{"Retry-After": []string{"90"}}}, expected: 90 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := &quota{}
+			for _, header := range tt.responses {
+				q.update(header, now)
+			}
+			if tt.cost > 0 {
+				q.recordCost(resourceGraphQL, tt.cost)
+			}
+			if delay := q.delay(resourceGraphQL, base, now); delay != tt.expected {
+				t.Errorf("Expected delay %v, got %v", tt.expected, delay)
+			}
+		})
+	}
+}
+
+func TestClient_RateLimitFromHeaders(t *testing.T) {
+	reset := time.Now().Add(20 * time.Minute).Truncate(time.Second)
+	client := newTestGraphQLClient(t, func(w http.ResponseWriter, r *http.Request) {
+		for key, values := range rateLimitHeader("graphql", 5000, 0, reset) {
+			w.Header()[key] = values
+		}
+		_, _ = fmt.Fprint(w, `{"data":{"c0":{"comment":{"id":"C_1"}}}}`)
+	})
+
+	if _, err := client.AddComments(context.Background(), []CommentInput{{DiscussionID: "D_1", Body: "First"}}); err != nil {
+		t.Fatalf("AddComments returned error: %v", err)
+	}
+
+	remaining, limit, resetAt := client.RateLimitStatus()
+	if remaining != 0 || limit != 5000 || !resetAt.Equal(reset) {
+		t.Errorf("Expected 0/5000 points until %v, got %d/%d until %v", reset, remaining, limit, resetAt)
+	}
+
+	rateLimitErr, ok := client.parseRateLimitFromError(errors.New("API rate limit exceeded for user ID 1."))
+	if !ok || !rateLimitErr.ResetTime.Equal(reset) {
+		t.Errorf("Expected the rate limit to reset at the reported %v, got %+v", reset, rateLimitErr)
+	}
+}
+
+func TestClient_QueryCost(t *testing.T) {
+	reset := time.Now().Add(20 * time.Minute)
+	client := newTestGraphQLClient(t, func(w http.ResponseWriter, r *http.Request) {
+		for key, values := range rateLimitHeader("graphql", 5000, 100, reset) {
+			w.Header()[key] = values
+		}
+		_, _ = fmt.Fprint(w, `{"data":{"rateLimit":{"cost":7},"repository":{"label":{"id":"L_1"}}}}`)
+	})
+
+	if _, err := client.LabelID(context.Background(), "owner/repo", "bug"); err != nil {
+		t.Fatalf("LabelID returned error: %v", err)
+	}
+	if state, ok := client.quota.status(resourceGraphQL); !ok || state.cost != 7 {
+		t.Errorf("Expected the query's cost of 7 points, got %+v", state)
+	}
+}
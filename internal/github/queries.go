@@ -3,6 +3,7 @@ package github
 import (
 	"context"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/shurcooL/githubv4"
@@ -10,6 +11,7 @@ import (
 
 type RepositoryInfo struct {
 	ID                    string
+	NameWithOwner         string // Canonical "owner/repo" casing as reported by GitHub
 	HasDiscussionsEnabled bool
 	DiscussionCategories  []Category
 }
@@ -27,10 +29,12 @@ func (c *Client) GetRepositoryInfo(ctx context.Context, repo string) (*Repositor
 
 	var info *RepositoryInfo
 
-	err := c.executeWithRetry(ctx, func() error {
+	err := c.executeWithRetry(ctx, resourceGraphQL, func() error {
 		var query struct {
+			RateLimit  queryCost
 			Repository struct {
 				ID                    string
+				NameWithOwner         string
 				HasDiscussionsEnabled bool
 				DiscussionCategories  struct {
 					Nodes []struct {
@@ -50,6 +54,7 @@ func (c *Client) GetRepositoryInfo(ctx context.Context, repo string) (*Repositor
 		if err != nil {
 			return fmt.Errorf("GitHub API query failed: %w", err)
 		}
+		c.quota.recordCost(resourceGraphQL, query.RateLimit.Cost)
 
 		if !query.Repository.HasDiscussionsEnabled {
 			return fmt.Errorf("GitHub Discussions is not enabled for repository %s", repo)
@@ -65,12 +70,16 @@ func (c *Client) GetRepositoryInfo(ctx context.Context, repo string) (*Repositor
 
 		info = &RepositoryInfo{
 			ID:                    query.Repository.ID,
+			NameWithOwner:         query.Repository.NameWithOwner,
 			HasDiscussionsEnabled: query.Repository.HasDiscussionsEnabled,
 			DiscussionCategories:  categories,
 		}
 
 		c.repositoryID = info.ID
 		c.repositoryName = repo
+		if info.NameWithOwner != "" {
+			c.repositoryName = info.NameWithOwner
+		}
 
 		return nil
 	})
@@ -82,6 +91,326 @@ func (c *Client) GetRepositoryInfo(ctx context.Context, repo string) (*Repositor
 	return info, nil
 }
 
+// CountCategoryDiscussions returns the number of discussions in a category of
+// the repository.
+func (c *Client) CountCategoryDiscussions(ctx context.Context, repo, categoryID string) (int, error) {
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 {
+		return 0, fmt.Errorf("invalid repository format - expected 'owner/repo'")
+	}
+	if strings.TrimSpace(categoryID) == "" {
+		return 0, fmt.Errorf("categoryID cannot be empty")
+	}
+
+	var count int
+
+	err := c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var query struct {
+			RateLimit  queryCost
+			Repository struct {
+				Discussions struct {
+					TotalCount int
+				} `graphql:"discussions(first: 1, categoryId: $categoryId)"`
+			} `graphql:"repository(owner: $owner, name: $name)"`
+		}
+
+		variables := map[string]interface{}{
+			"owner":      githubv4.String(parts[0]),
+			"name":       githubv4.String(parts[1]),
+			"categoryId": githubv4.ID(categoryID),
+		}
+
+		if err := c.client.Query(ctx, &query, variables); err != nil {
+			return fmt.Errorf("failed to count discussions in category %q: %w", categoryID, err)
+		}
+		c.quota.recordCost(resourceGraphQL, query.RateLimit.Cost)
+
+		count = query.Repository.Discussions.TotalCount
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindDiscussionByMarker searches the repository for the discussion whose body
+// contains marker on a line of its own. When several discussions match (e.g.,
+// a split thread), the oldest one is returned. It returns nil without an error
+// when no discussion matches.
+func (c *Client) FindDiscussionByMarker(ctx context.Context, repo, marker string) (*DiscussionResult, error) {
+	return c.findDiscussion(ctx, repo, marker, func(foundDiscussion) bool { return true })
+}
+
+// FindDiscussion searches the repository for the oldest discussion with the
+// given title in a category whose body contains marker on a line of its own.
+// It returns nil without an error when no discussion matches.
+func (c *Client) FindDiscussion(ctx context.Context, repo, categoryID, title, marker string) (*DiscussionResult, error) {
+	return c.findDiscussion(ctx, repo, marker, func(d foundDiscussion) bool {
+		return d.Title == title && d.Category.ID == categoryID
+	})
+}
+
+// foundDiscussion is a discussion returned by a search.
+type foundDiscussion struct {
+	ID       string
+	Number   int
+	Title    string
+	Body     string
+	Category struct {
+		ID string
+	}
+}
+
+func (c *Client) findDiscussion(ctx context.Context, repo, marker string, match func(foundDiscussion) bool) (*DiscussionResult, error) {
+	if len(strings.Split(repo, "/")) != 2 {
+		return nil, fmt.Errorf("invalid repository format - expected 'owner/repo'")
+	}
+	if strings.TrimSpace(marker) == "" {
+		return nil, fmt.Errorf("marker cannot be empty")
+	}
+
+	var result *DiscussionResult
+
+	err := c.executeWithRetry(ctx, resourceGraphQL, func() error {
+		var query struct {
+			RateLimit queryCost
+			Search    struct {
+				Nodes []struct {
+					Discussion foundDiscussion `graphql:"... on Discussion"`
+				}
+			} `graphql:"search(query: $query, type: DISCUSSION, first: 20)"`
+		}
+
+		variables := map[string]interface{}{
+			"query": githubv4.String(fmt.Sprintf("repo:%s in:body %q", repo, marker)),
+		}
+
+		if err := c.client.Query(ctx, &query, variables); err != nil {
+			return fmt.Errorf("failed to search discussions for %q: %w", marker, err)
+		}
+		c.quota.recordCost(resourceGraphQL, query.RateLimit.Cost)
+
+		result = nil
+		for _, node := range query.Search.Nodes {
+			discussion := node.Discussion
+			if discussion.ID == "" || !containsLine(discussion.Body, marker) || !match(discussion) {
+				continue
+			}
+			if result == nil || discussion.Number < result.Number {
+				result = &DiscussionResult{ID: discussion.ID, Number: discussion.Number}
+			}
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// DiscussionContent is the title and the posts of a discussion.
+type DiscussionContent struct {
+	ID         string
+	Title      string
+	Body       string
+	Comments   []string // Bodies of the comments and their replies in creation order
+	CommentIDs []string // Node IDs of Comments, in the same order
+	ParentIDs  []string // Node IDs of the comments Comments reply to, empty for top-level comments
+}
+
+// discussionCommentsPageSize is the number of comments fetched per request.
+// Each comment also fetches its first page of replies.
+const discussionCommentsPageSize = 50
+
+// discussionRepliesPageSize is the number of replies fetched per request.
+const discussionRepliesPageSize = 100
+
+// discussionReply is a reply to a discussion comment.
+type discussionReply struct {
+	ID        string
+	Body      string
+	CreatedAt githubv4.DateTime
+}
+
+// discussionReplies is a page of replies to a discussion comment.
+type discussionReplies struct {
+	Nodes    []discussionReply
+	PageInfo struct {
+		HasNextPage bool
+		EndCursor   githubv4.String
+	}
+}
+
+// GetDiscussionContent fetches a discussion with the bodies of its comments
+// and replies, for comparison with the source thread or editing its posts.
+func (c *Client) GetDiscussionContent(ctx context.Context, repo string, number int) (*DiscussionContent, error) {
+	parts := strings.Split(repo, "/")
+	if len(parts) != 2 {
+		return nil, fmt.Errorf("invalid repository format - expected 'owner/repo'")
+	}
+	if number <= 0 {
+		return nil, fmt.Errorf("discussion number must be positive, got: %d", number)
+	}
+
+	type post struct {
+		id        string
+		parentID  string
+		body      string
+		createdAt int64
+	}
+
+	var (
+		content     DiscussionContent
+		posts, page []post
+		cursor      *githubv4.String
+		moreReplies = make(map[string]githubv4.String) // Cursors of comments with more replies
+	)
+	for {
+		var (
+			hasNextPage bool
+			endCursor   githubv4.String
+		)
+		err := c.executeWithRetry(ctx, resourceGraphQL, func() error {
+			var query struct {
+				RateLimit  queryCost
+				Repository struct {
+					Discussion struct {
+						ID       string
+						Title    string
+						Body     string
+						Comments struct {
+							Nodes []struct {
+								ID        string
+								Body      string
+								CreatedAt githubv4.DateTime
+								Replies   discussionReplies `graphql:"replies(first: $replies)"`
+							}
+							PageInfo struct {
+								HasNextPage bool
+								EndCursor   githubv4.String
+							}
+						} `graphql:"comments(first: $first, after: $cursor)"`
+					} `graphql:"discussion(number: $number)"`
+				} `graphql:"repository(owner: $owner, name: $name)"`
+			}
+
+			variables := map[string]interface{}{
+				"owner":   githubv4.String(parts[0]),
+				"name":    githubv4.String(parts[1]),
+				"number":  githubv4.Int(number),
+				"first":   githubv4.Int(discussionCommentsPageSize),
+				"replies": githubv4.Int(discussionRepliesPageSize),
+				"cursor":  cursor,
+			}
+
+			if err := c.client.Query(ctx, &query, variables); err != nil {
+				return fmt.Errorf("failed to fetch discussion #%d: %w", number, err)
+			}
+			c.quota.recordCost(resourceGraphQL, query.RateLimit.Cost)
+
+			discussion := query.Repository.Discussion
+			content.ID, content.Title, content.Body = discussion.ID, discussion.Title, discussion.Body
+			page = page[:0]
+			for _, comment := range discussion.Comments.Nodes {
+				page = append(page, post{id: comment.ID, body: comment.Body, createdAt: comment.CreatedAt.UnixNano()})
+				for _, reply := range comment.Replies.Nodes {
+					page = append(page, post{id: reply.ID, parentID: comment.ID, body: reply.Body, createdAt: reply.CreatedAt.UnixNano()})
+				}
+				if comment.Replies.PageInfo.HasNextPage {
+					moreReplies[comment.ID] = comment.Replies.PageInfo.EndCursor
+				}
+			}
+			hasNextPage, endCursor = discussion.Comments.PageInfo.HasNextPage, discussion.Comments.PageInfo.EndCursor
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		posts = append(posts, page...)
+
+		if !hasNextPage {
+			break
+		}
+		cursor = githubv4.NewString(endCursor)
+	}
+
+	for commentID, replyCursor := range moreReplies {
+		replies, err := c.commentReplies(ctx, commentID, replyCursor)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch replies of discussion #%d: %w", number, err)
+		}
+		for _, reply := range replies {
+			posts = append(posts, post{id: reply.ID, parentID: commentID, body: reply.Body, createdAt: reply.CreatedAt.UnixNano()})
+		}
+	}
+
+	sort.SliceStable(posts, func(i, j int) bool { return posts[i].createdAt < posts[j].createdAt })
+	for _, p := range posts {
+		content.Comments = append(content.Comments, p.body)
+		content.CommentIDs = append(content.CommentIDs, p.id)
+		content.ParentIDs = append(content.ParentIDs, p.parentID)
+	}
+	return &content, nil
+}
+
+// commentReplies fetches the replies to a discussion comment after cursor,
+// following their pages.
+func (c *Client) commentReplies(ctx context.Context, commentID string, cursor githubv4.String) ([]discussionReply, error) {
+	var replies []discussionReply
+	for {
+		var page discussionReplies
+		err := c.executeWithRetry(ctx, resourceGraphQL, func() error {
+			var query struct {
+				RateLimit queryCost
+				Node      struct {
+					Comment struct {
+						Replies discussionReplies `graphql:"replies(first: $first, after: $cursor)"`
+					} `graphql:"... on DiscussionComment"`
+				} `graphql:"node(id: $id)"`
+			}
+
+			variables := map[string]interface{}{
+				"id":     githubv4.ID(commentID),
+				"first":  githubv4.Int(discussionRepliesPageSize),
+				"cursor": githubv4.NewString(cursor),
+			}
+
+			if err := c.client.Query(ctx, &query, variables); err != nil {
+				return fmt.Errorf("failed to fetch replies to comment %s: %w", commentID, err)
+			}
+			c.quota.recordCost(resourceGraphQL, query.RateLimit.Cost)
+
+			page = query.Node.Comment.Replies
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		replies = append(replies, page.Nodes...)
+		if !page.PageInfo.HasNextPage {
+			return replies, nil
+		}
+		cursor = page.PageInfo.EndCursor
+	}
+}
+
+// containsLine reports whether text has a line equal to line, ignoring
+// surrounding whitespace, so "Thread ID: 1" does not match "Thread ID: 12".
+func containsLine(text, line string) bool {
+	for _, candidate := range strings.Split(text, "\n") {
+		if strings.TrimSpace(candidate) == line {
+			return true
+		}
+	}
+	return false
+}
+
 func (c *Client) ValidateCategoryMappings(ctx context.Context, categories map[int]string) error {
 	// Ensure we have a repository name stored
 	if strings.TrimSpace(c.repositoryName) == "" {
@@ -0,0 +1,158 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/retry"
+)
+
+const defaultRESTBaseURL = "https://api.github.com"
+
+// APIError represents a non-successful response from the GitHub REST API.
+type APIError struct {
+	StatusCode int         // HTTP status code
+	Message    string      // Error message from the response body
+	Header     http.Header // Response headers (rate limit information)
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("GitHub REST API error (status %d): %s", e.StatusCode, e.Message)
+}
+
+// SetRESTBaseURL overrides the GitHub REST API base URL (e.g., for GitHub
+// Enterprise Server or tests).
+func (c *Client) SetRESTBaseURL(baseURL string) {
+	c.restBaseURL = strings.TrimRight(baseURL, "/")
+	if c.installation != nil {
+		c.installation.setBaseURL(c.restBaseURL)
+	}
+}
+
+// restRequest performs an authenticated REST API call through the shared
+// retry and rate-limit handling used for GraphQL mutations. The request body
+// is JSON-encoded and a successful response is decoded into out when non-nil.
+// Only the failures retryableREST accepts are retried.
+func (c *Client) restRequest(ctx context.Context, method, path string, body, out interface{}) error {
+	return c.executeWithRetry(ctx, resourceCore, func() error {
+		err := c.doRESTRequest(ctx, method, path, body, out)
+		if err != nil && !retryableREST(method, err) {
+			return retry.Permanent(err)
+		}
+		return err
+	})
+}
+
+// retryableREST reports whether a failed REST call may be sent again.
+// Rate-limited calls were rejected before GitHub acted on them. Other
+// failures are only retried for reads and ref updates to a commit SHA, which
+// have the same effect when repeated: a POST may have created its blob,
+// commit, ref or invitation before the connection failed. Client errors, such
+// as the 404 of a missing branch, are final.
+func retryableREST(method string, err error) bool {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		if _, ok := rateLimitFromAPIError(apiErr); ok {
+			return true
+		}
+		if apiErr.StatusCode < http.StatusInternalServerError {
+			return false
+		}
+	}
+	return method == http.MethodGet || method == http.MethodPatch
+}
+
+// doRESTRequest performs a single REST API call without retries.
+func (c *Client) doRESTRequest(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request body: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.restBaseURL+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, path, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response body: %w", err)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var payload struct {
+			Message string `json:"message"`
+		}
+		message := strings.TrimSpace(string(data))
+		if json.Unmarshal(data, &payload) == nil && payload.Message != "" {
+			message = payload.Message
+		}
+		return &APIError{StatusCode: resp.StatusCode, Message: message, Header: resp.Header}
+	}
+
+	if out != nil && len(data) > 0 {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("failed to decode response: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// rateLimitFromAPIError recognizes primary and secondary (abuse) rate limit
+// responses from the REST API and derives the reset time from the
+// Retry-After or X-RateLimit-Reset headers.
+func rateLimitFromAPIError(apiErr *APIError) (*RateLimitError, bool) {
+	if apiErr.StatusCode != http.StatusForbidden && apiErr.StatusCode != http.StatusTooManyRequests {
+		return nil, false
+	}
+
+	retryAfter := apiErr.Header.Get("Retry-After")
+	remaining := apiErr.Header.Get("X-RateLimit-Remaining")
+	message := strings.ToLower(apiErr.Message)
+	isRateLimit := apiErr.StatusCode == http.StatusTooManyRequests ||
+		retryAfter != "" ||
+		remaining == "0" ||
+		strings.Contains(message, "rate limit") ||
+		strings.Contains(message, "abuse detection")
+	if !isRateLimit {
+		return nil, false
+	}
+
+	// Secondary limits without explicit timing ask clients to wait at least a minute.
+	resetTime := time.Now().Add(1 * time.Minute)
+	if seconds, err := strconv.Atoi(retryAfter); err == nil {
+		resetTime = time.Now().Add(time.Duration(seconds) * time.Second)
+	} else if reset, err := strconv.ParseInt(apiErr.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		resetTime = time.Unix(reset, 0)
+	}
+
+	return &RateLimitError{
+		Message:   apiErr.Error(),
+		Remaining: 0,
+		ResetTime: resetTime,
+	}, true
+}
@@ -0,0 +1,105 @@
+// Package inventory exports the forum structure as JSON so migrations can be
+// planned (e.g., building custom mapping files) without converting or posting
+// anything.
+package inventory
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// Source provides the forum data needed for an inventory.
+// *xenforo.Client satisfies this interface.
+type Source interface {
+	GetNodes(ctx context.Context) ([]xenforo.Node, error)
+	GetThreads(ctx context.Context, nodeID int) ([]xenforo.Thread, error)
+}
+
+// NodeTree is a forum node with its child nodes.
+type NodeTree struct {
+	xenforo.Node
+	Children []*NodeTree `json:"children,omitempty"`
+}
+
+// Inventory is the exported forum structure.
+type Inventory struct {
+	GeneratedAt string           `json:"generated_at"`
+	Nodes       []*NodeTree      `json:"nodes"`
+	NodeID      int              `json:"node_id,omitempty"` // Node whose threads are listed
+	Threads     []xenforo.Thread `json:"threads,omitempty"`
+}
+
+// Build fetches the node tree and, when nodeID is positive, the thread list
+// of that node.
+func Build(ctx context.Context, source Source, nodeID int) (*Inventory, error) {
+	nodes, err := source.GetNodes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch nodes: %w", err)
+	}
+
+	inv := &Inventory{
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339),
+		Nodes:       buildTree(nodes),
+	}
+
+	if nodeID > 0 {
+		threads, err := source.GetThreads(ctx, nodeID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch threads for node %d: %w", nodeID, err)
+		}
+		inv.NodeID = nodeID
+		inv.Threads = threads
+	}
+
+	return inv, nil
+}
+
+// Write encodes the inventory as indented JSON.
+func Write(w io.Writer, inv *Inventory) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(inv); err != nil {
+		return fmt.Errorf("failed to encode inventory: %w", err)
+	}
+	return nil
+}
+
+// buildTree arranges nodes under their parents, ordered by display order.
+// Nodes whose parent is missing are treated as roots.
+func buildTree(nodes []xenforo.Node) []*NodeTree {
+	byID := make(map[int]*NodeTree, len(nodes))
+	for _, node := range nodes {
+		byID[node.NodeID] = &NodeTree{Node: node}
+	}
+
+	roots := []*NodeTree{}
+	for _, node := range nodes {
+		tree := byID[node.NodeID]
+		if parent, ok := byID[node.ParentNodeID]; ok && node.ParentNodeID != node.NodeID {
+			parent.Children = append(parent.Children, tree)
+		} else {
+			roots = append(roots, tree)
+		}
+	}
+
+	sortTrees(roots)
+	return roots
+}
+
+func sortTrees(trees []*NodeTree) {
+	sort.SliceStable(trees, func(i, j int) bool {
+		if trees[i].DisplayOrder != trees[j].DisplayOrder {
+			return trees[i].DisplayOrder < trees[j].DisplayOrder
+		}
+		return trees[i].NodeID < trees[j].NodeID
+	})
+	for _, tree := range trees {
+		sortTrees(tree.Children)
+	}
+}
@@ -0,0 +1,115 @@
+package inventory
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+type fakeSource struct {
+	nodes   []xenforo.Node
+	threads map[int][]xenforo.Thread
+	err     error
+}
+
+func (f *fakeSource) GetNodes(ctx context.Context) ([]xenforo.Node, error) {
+	return f.nodes, f.err
+}
+
+func (f *fakeSource) GetThreads(ctx context.Context, nodeID int) ([]xenforo.Thread, error) {
+	return f.threads[nodeID], nil
+}
+
+func newFakeSource() *fakeSource {
+	return &fakeSource{
+		nodes: []xenforo.Node{
+			{NodeID: 3, Title: "Support", NodeTypeID: "Forum", ParentNodeID: 1, DisplayOrder: 20},
+			{NodeID: 1, Title: "Community", NodeTypeID: "Category", ParentNodeID: 0, DisplayOrder: 10},
+			{NodeID: 2, Title: "General", NodeTypeID: "Forum", ParentNodeID: 1, DisplayOrder: 10},
+			{NodeID: 4, Title: "Archive", NodeTypeID: "Category", ParentNodeID: 0, DisplayOrder: 20},
+		},
+		threads: map[int][]xenforo.Thread{
+			2: {{ThreadID: 10, Title: "Welcome", NodeID: 2, Username: "admin", PostDate: 1640000000, ReplyCount: 3}},
+		},
+	}
+}
+
+func TestBuildInventoryJSONShape(t *testing.T) {
+	inv, err := Build(context.Background(), newFakeSource(), 2)
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, inv); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	var decoded struct {
+		GeneratedAt string `json:"generated_at"`
+		Nodes       []struct {
+			NodeID   int    `json:"node_id"`
+			Title    string `json:"title"`
+			Children []struct {
+				NodeID int    `json:"node_id"`
+				Title  string `json:"title"`
+			} `json:"children"`
+		} `json:"nodes"`
+		NodeID  int `json:"node_id"`
+		Threads []struct {
+			ThreadID int    `json:"thread_id"`
+			Title    string `json:"title"`
+		} `json:"threads"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("Inventory is not valid JSON: %v", err)
+	}
+
+	if decoded.GeneratedAt == "" {
+		t.Error("Expected generated_at to be set")
+	}
+	if len(decoded.Nodes) != 2 || decoded.Nodes[0].Title != "Community" || decoded.Nodes[1].Title != "Archive" {
+		t.Fatalf("Expected root nodes Community and Archive, got %+v", decoded.Nodes)
+	}
+	children := decoded.Nodes[0].Children
+	if len(children) != 2 || children[0].NodeID != 2 || children[1].NodeID != 3 {
+		t.Errorf("Expected children General then Support, got %+v", children)
+	}
+	if decoded.NodeID != 2 || len(decoded.Threads) != 1 || decoded.Threads[0].Title != "Welcome" {
+		t.Errorf("Expected thread list for node 2, got node %d threads %+v", decoded.NodeID, decoded.Threads)
+	}
+}
+
+func TestBuildInventoryWithoutThreads(t *testing.T) {
+	inv, err := Build(context.Background(), newFakeSource(), 0)
+	if err != nil {
+		t.Fatalf("Build returned error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, inv); err != nil {
+		t.Fatalf("Write returned error: %v", err)
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(buf.Bytes(), &raw); err != nil {
+		t.Fatalf("Inventory is not valid JSON: %v", err)
+	}
+	if _, ok := raw["threads"]; ok {
+		t.Error("Expected no threads key when no node is selected")
+	}
+	if _, ok := raw["nodes"]; !ok {
+		t.Error("Expected nodes key")
+	}
+}
+
+func TestBuildInventoryError(t *testing.T) {
+	source := &fakeSource{err: errors.New("connection refused")}
+	if _, err := Build(context.Background(), source, 0); err == nil {
+		t.Error("Expected error when nodes cannot be fetched")
+	}
+}
@@ -0,0 +1,136 @@
+// Package logging provides the leveled, structured logger used across the
+// migration. Messages keep their human-readable text; fields such as the
+// thread ID, post ID and phase are attached to a context with With and are
+// emitted as JSON fields with the json format.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"sync"
+)
+
+// Log formats accepted by Setup.
+const (
+	FormatText = "text" // Plain messages, as printed by the standard logger
+	FormatJSON = "json" // One JSON object per record, with the context fields
+)
+
+type fieldsKey struct{}
+
+// With returns a context whose log records carry the given key-value pairs in
+// addition to those of ctx. A key already in ctx is replaced.
+func With(ctx context.Context, args ...any) context.Context {
+	fields := slices.Clone(contextFields(ctx))
+	record := slog.Record{}
+	record.Add(args...)
+	record.Attrs(func(attr slog.Attr) bool {
+		fields = slices.DeleteFunc(fields, func(field slog.Attr) bool { return field.Key == attr.Key })
+		fields = append(fields, attr)
+		return true
+	})
+	return context.WithValue(ctx, fieldsKey{}, fields)
+}
+
+func contextFields(ctx context.Context) []slog.Attr {
+	fields, _ := ctx.Value(fieldsKey{}).([]slog.Attr)
+	return fields
+}
+
+// Debugf logs a message that is only shown in verbose mode.
+func Debugf(ctx context.Context, format string, args ...any) {
+	logf(ctx, slog.LevelDebug, format, args...)
+}
+
+// Infof logs progress.
+func Infof(ctx context.Context, format string, args ...any) {
+	logf(ctx, slog.LevelInfo, format, args...)
+}
+
+// Warnf logs a problem the migration recovers from.
+func Warnf(ctx context.Context, format string, args ...any) {
+	logf(ctx, slog.LevelWarn, format, args...)
+}
+
+// Errorf logs a failed operation.
+func Errorf(ctx context.Context, format string, args ...any) {
+	logf(ctx, slog.LevelError, format, args...)
+}
+
+func logf(ctx context.Context, level slog.Level, format string, args ...any) {
+	logger := slog.Default()
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	logger.Log(ctx, level, fmt.Sprintf(format, args...))
+}
+
+// Setup installs the default logger writing to w in the given format ("" is
+// text). Debug messages are only logged when verbose. The standard log
+// package is redirected to it as well.
+func Setup(w io.Writer, format string, verbose bool) error {
+	level := slog.LevelInfo
+	if verbose {
+		level = slog.LevelDebug
+	}
+
+	var handler slog.Handler
+	switch format {
+	case "", FormatText:
+		handler = &textHandler{mu: &sync.Mutex{}, w: w, level: level}
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, &slog.HandlerOptions{Level: level})
+	default:
+		return fmt.Errorf("unknown log format %q (available: text, json)", format)
+	}
+
+	slog.SetDefault(slog.New(contextHandler{handler}))
+	return nil
+}
+
+// contextHandler adds the fields attached to the context with With.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	record.AddAttrs(contextFields(ctx)...)
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
+
+// textHandler prints the message only, prefixed with the time like the
+// standard logger. The fields are already part of the human-readable
+// messages.
+type textHandler struct {
+	mu    *sync.Mutex
+	w     io.Writer
+	level slog.Level
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level
+}
+
+func (h *textHandler) Handle(_ context.Context, record slog.Record) error {
+	line := record.Time.Format("2006/01/02 15:04:05 ") + record.Message + "\n"
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := io.WriteString(h.w, line)
+	return err
+}
+
+func (h *textHandler) WithAttrs([]slog.Attr) slog.Handler { return h }
+
+func (h *textHandler) WithGroup(string) slog.Handler { return h }
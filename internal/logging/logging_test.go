@@ -0,0 +1,57 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSetupJSON(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var out bytes.Buffer
+	if err := Setup(&out, FormatJSON, false); err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+
+	ctx := With(context.Background(), "thread_id", 12, "phase", "fetch")
+	ctx = With(ctx, "phase", "posts", "post_id", 120)
+	Warnf(ctx, "⚠ Post %d: retrying", 120)
+	Debugf(ctx, "hidden without verbose")
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("Expected one record, got %q", out.String())
+	}
+	var record map[string]interface{}
+	if err := json.Unmarshal([]byte(lines[0]), &record); err != nil {
+		t.Fatalf("Record is not JSON: %v", err)
+	}
+	expected := map[string]interface{}{"level": "WARN", "msg": "⚠ Post 120: retrying", "thread_id": 12.0, "phase": "posts", "post_id": 120.0}
+	for key, value := range expected {
+		if record[key] != value {
+			t.Errorf("Expected %s=%v, got %v", key, value, record[key])
+		}
+	}
+}
+
+func TestSetupText(t *testing.T) {
+	defer slog.SetDefault(slog.Default())
+
+	var out bytes.Buffer
+	if err := Setup(&out, "", true); err != nil {
+		t.Fatalf("Setup returned error: %v", err)
+	}
+
+	Debugf(With(context.Background(), "thread_id", 12), "  ✓ Found %d posts for thread", 3)
+	if line := out.String(); !strings.HasSuffix(line, " ✓ Found 3 posts for thread\n") || strings.Contains(line, "thread_id") {
+		t.Errorf("Expected the plain message, got %q", line)
+	}
+
+	if err := Setup(&out, "xml", false); err == nil {
+		t.Error("Expected an error for an unknown format")
+	}
+}
@@ -0,0 +1,127 @@
+package migration
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+)
+
+// maxBodyLength is the longest discussion or comment body GitHub accepts.
+// Bodies are measured in bytes, which never undercounts GitHub's characters.
+const maxBodyLength = 65536
+
+// bodySplitReserve is kept free in every part of a split body for the
+// continuation marker and the fences closing and reopening a code block.
+const bodySplitReserve = 256
+
+// continuationPrefix starts the comments continuing a post that was split at
+// the body limit.
+const continuationPrefix = "*(continued)*\n\n"
+
+// splitBody splits a body longer than limit into parts that fit, at
+// paragraph boundaries where possible, then at line boundaries, and within
+// overlong lines as a last resort. Code blocks cut in two are closed and
+// reopened. Parts after the first start with the continuation marker.
+func splitBody(body string, limit int) []string {
+	if len(body) <= limit {
+		return []string{body}
+	}
+	limit -= bodySplitReserve
+
+	var parts []string
+	var part strings.Builder
+	fence := "" // Opening line of the code block open at the end of part
+	for _, piece := range bodyPieces(body, limit) {
+		if part.Len() > 0 && part.Len()+len(piece) > limit {
+			text := strings.TrimRight(part.String(), "\n")
+			part.Reset()
+			if fence != "" {
+				text += "\n" + fenceMarker(fence)
+				part.WriteString(fence + "\n")
+			}
+			parts = append(parts, text)
+		}
+		part.WriteString(piece)
+		fence = fenceAfter(fence, piece)
+	}
+	parts = append(parts, strings.TrimRight(part.String(), "\n"))
+
+	for i := 1; i < len(parts); i++ {
+		parts[i] = continuationPrefix + parts[i]
+	}
+	return parts
+}
+
+// bodyPieces cuts a body into paragraphs, paragraphs longer than limit into
+// lines, and lines longer than limit at rune boundaries. Each piece keeps
+// its trailing line breaks.
+func bodyPieces(body string, limit int) []string {
+	var pieces []string
+	for _, paragraph := range strings.SplitAfter(body, "\n\n") {
+		if len(paragraph) <= limit {
+			pieces = append(pieces, paragraph)
+			continue
+		}
+		for _, line := range strings.SplitAfter(paragraph, "\n") {
+			for len(line) > limit {
+				cut := limit
+				for cut > 0 && !utf8.RuneStart(line[cut]) {
+					cut--
+				}
+				pieces = append(pieces, line[:cut])
+				line = line[cut:]
+			}
+			if line != "" {
+				pieces = append(pieces, line)
+			}
+		}
+	}
+	return pieces
+}
+
+// fenceAfter returns the opening line of the code block open after text,
+// given the one open before it (empty = none).
+func fenceAfter(fence, text string) string {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case fence == "" && (strings.HasPrefix(line, "```") || strings.HasPrefix(line, "~~~")):
+			fence = line
+		case fence != "" && strings.HasPrefix(line, fenceMarker(fence)) && strings.Trim(line, "`~") == "":
+			fence = ""
+		}
+	}
+	return fence
+}
+
+// fenceMarker returns the backticks or tildes of a code block's opening line.
+func fenceMarker(fence string) string {
+	return fence[:len(fence)-len(strings.TrimLeft(fence, fence[:1]))]
+}
+
+// addContinuations adds the continuations of a post split at the body limit
+// in order, as comments of the discussion or as replies to replyToID. They
+// are not part of the checkpoint, so failures are logged rather than
+// returned.
+func (r *Runner) addContinuations(ctx context.Context, discussionID, replyToID string, continuations []string) {
+	if len(continuations) == 0 {
+		return
+	}
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would add %d continuation comments of a long post", len(continuations))
+		return
+	}
+	if discussionID == "" {
+		return
+	}
+
+	for i, body := range continuations {
+		if _, err := r.githubClient.AddCommentReply(ctx, discussionID, replyToID, body); err != nil {
+			logging.Errorf(ctx, "✗ Failed to add continuation %d of %d of a long post: %v", i+1, len(continuations), err)
+			return
+		}
+	}
+	logging.Infof(ctx, "  ✓ Added %d continuation comments of a long post", len(continuations))
+}
@@ -0,0 +1,108 @@
+package migration
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestSplitBody(t *testing.T) {
+	if parts := splitBody("Short body", 1000); len(parts) != 1 || parts[0] != "Short body" {
+		t.Errorf("Expected a short body unchanged, got %q", parts)
+	}
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"Paragraphs", strings.Repeat(strings.Repeat("word ", 40)+"\n\n", 20)},
+		{"Code block", "Intro\n\n```go\n" + strings.Repeat("fmt.Println(\"line\")\n", 200) + "```\n\nOutro"},
+		{"Overlong line", strings.Repeat("ü", 2000)},
+	}
+
+	const limit = 1000
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := splitBody(tt.body, limit)
+			if len(parts) < 2 {
+				t.Fatalf("Expected the body to be split, got %d parts", len(parts))
+			}
+
+			var joined []string
+			for i, part := range parts {
+				if len(part) > limit {
+					t.Errorf("Part %d has %d bytes, over the limit of %d", i, len(part), limit)
+				}
+				if strings.Count(part, "```")%2 != 0 {
+					t.Errorf("Part %d leaves a code block open: %q", i, part)
+				}
+				if i > 0 {
+					content, ok := strings.CutPrefix(part, continuationPrefix)
+					if !ok {
+						t.Errorf("Part %d does not start with the continuation marker: %q", i, part)
+					}
+					part = content
+				}
+				joined = append(joined, part)
+			}
+
+			// Fences closing and reopening the code block aside
+			withoutFences := strings.NewReplacer("```go", "", "```", "")
+			words := strings.Fields(withoutFences.Replace(tt.body))
+			rejoined := strings.Fields(withoutFences.Replace(strings.Join(joined, "\n")))
+			if strings.Join(rejoined, "") != strings.Join(words, "") {
+				t.Error("Expected the parts to hold the whole body")
+			}
+		})
+	}
+}
+
+func TestRunner_SplitsLongPosts(t *testing.T) {
+	long := strings.Repeat(strings.Repeat("word ", 19)+"end.\n\n", 1000)
+
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 2
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: long},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: long},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Short"},
+	}
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+		cfg.GitHub.CommentBatchSize = 5
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	if len(api.discussions) != 1 || len(api.discussions[0].Body) > maxBodyLength {
+		t.Fatalf("Expected one discussion within the body limit, got %+v", api.discussions)
+	}
+	if len(api.comments) != 4 {
+		t.Fatalf("Expected 4 comments, got %d", len(api.comments))
+	}
+
+	// The opening post continues in a top-level comment, alice's post in a
+	// reply to her comment, and bob's comment follows them
+	expected := []struct {
+		replyToID    string
+		continuation bool
+	}{
+		{"", true},
+		{"", false},
+		{"C_2", true},
+	}
+	for i, want := range expected {
+		comment := api.comments[i]
+		if comment.ReplyToID != want.replyToID || strings.HasPrefix(comment.Body, continuationPrefix) != want.continuation || len(comment.Body) > maxBodyLength {
+			t.Errorf("Unexpected comment %d: reply to %q, %d bytes, starting %q", i, comment.ReplyToID, len(comment.Body), comment.Body[:min(40, len(comment.Body))])
+		}
+	}
+	if !strings.Contains(api.comments[3].Body, "Short") {
+		t.Errorf("Expected bob's comment last, got %q", api.comments[3].Body)
+	}
+}
@@ -0,0 +1,58 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/bbcode"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+)
+
+// checkMappings verifies that every completed thread has a recorded
+// discussion. A missing entry means a discussion was created but never
+// recorded. With RepairMappings enabled, the discussion is searched on GitHub
+// by its thread marker and recorded. Returns the threads still unmapped.
+func (r *Runner) checkMappings(ctx context.Context) []int {
+	unmapped := r.tracker.UnmappedThreads()
+	if len(unmapped) == 0 {
+		return nil
+	}
+
+	logging.Warnf(ctx, "⚠ %d completed threads have no recorded discussion: %v", len(unmapped), unmapped)
+	if !r.config.Migration.RepairMappings || r.githubClient == nil {
+		return unmapped
+	}
+	if style, _ := bbcode.ParseHeaderStyle(r.config.Migration.HeaderStyle); !style.HasThreadMarker() {
+		logging.Warnf(ctx, "  ⚠ Cannot search for the discussions: the %s header style has no thread marker", style)
+		return unmapped
+	}
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would search for the discussions of %d unmapped threads", len(unmapped))
+		return unmapped
+	}
+
+	var remaining []int
+	for _, threadID := range unmapped {
+		result, err := r.githubClient.FindDiscussionByMarker(ctx, r.config.GitHub.Repository, r.processor.ThreadMarker(threadID))
+		if err != nil {
+			logging.Errorf(ctx, "✗ Failed to search for the discussion of thread %d: %v", threadID, err)
+			remaining = append(remaining, threadID)
+			continue
+		}
+		if result == nil {
+			logging.Warnf(ctx, "  ⚠ No discussion found for thread %d", threadID)
+			remaining = append(remaining, threadID)
+			continue
+		}
+
+		ref := progress.DiscussionRef{ID: result.ID, Number: result.Number, URL: r.discussionURL(result.Number)}
+		if err := r.tracker.RecordDiscussion(threadID, ref); err != nil {
+			logging.Warnf(ctx, "✗ Warning: Failed to record discussion for thread %d: %v", threadID, err)
+			remaining = append(remaining, threadID)
+			continue
+		}
+		logging.Infof(ctx, "  ✓ Repaired mapping: thread %d -> discussion #%d", threadID, result.Number)
+	}
+
+	return remaining
+}
@@ -0,0 +1,229 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// ConversionSource fetches the thread shown by ShowConversion.
+type ConversionSource interface {
+	GetThread(ctx context.Context, threadID int) (*xenforo.Thread, error)
+	GetPosts(ctx context.Context, thread xenforo.Thread) ([]xenforo.Post, error)
+}
+
+// ConversionOptions controls how ShowConversion prints each post.
+type ConversionOptions struct {
+	SideBySide bool // Print original and converted columns instead of a diff
+	Color      bool // Highlight removed and added lines with ANSI colors
+	Width      int  // Total width of side-by-side output (default 160)
+}
+
+const (
+	ansiRed   = "\x1b[31m"
+	ansiGreen = "\x1b[32m"
+	ansiBold  = "\x1b[1m"
+	ansiReset = "\x1b[0m"
+)
+
+// ShowConversion fetches a thread and prints, per post, the original BB-code
+// next to the Markdown it converts to with the configured conversion options.
+// Attachments are left as BB-code since nothing is downloaded.
+func ShowConversion(ctx context.Context, cfg *config.Config, source ConversionSource, threadID int, w io.Writer, opts ConversionOptions) error {
+	thread, err := source.GetThread(ctx, threadID)
+	if err != nil {
+		return err
+	}
+
+	posts, err := source.GetPosts(ctx, *thread)
+	if err != nil {
+		return fmt.Errorf("failed to get posts of thread %d: %w", threadID, err)
+	}
+
+	processor := newMessageProcessor(cfg)
+	_, _ = fmt.Fprintf(w, "Thread %d: %s (%d posts)\n", thread.ThreadID, thread.Title, len(posts))
+
+	for _, post := range posts {
+		heading := fmt.Sprintf("=== Post %d by %s ===", post.PostID, post.Username)
+		if opts.Color {
+			heading = ansiBold + heading + ansiReset
+		}
+		_, _ = fmt.Fprintf(w, "\n%s\n", heading)
+
+		converted := processor.ProcessContent(post.Message)
+		if opts.SideBySide {
+			_, _ = io.WriteString(w, sideBySide(post.Message, converted, opts.Width, opts.Color))
+		} else {
+			_, _ = io.WriteString(w, conversionDiff(post.Message, converted, opts.Color))
+		}
+	}
+	return nil
+}
+
+// diffLine is one line of a line diff: ' ' kept, '-' only in the original,
+// '+' only in the converted text.
+type diffLine struct {
+	op   byte
+	text string
+}
+
+// lineDiff computes a minimal line diff using the longest common subsequence.
+func lineDiff(before, after string) []diffLine {
+	a := splitLines(before)
+	b := splitLines(after)
+
+	// lcs[i][j] is the LCS length of a[i:] and b[j:]
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var lines []diffLine
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			lines = append(lines, diffLine{' ', a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			lines = append(lines, diffLine{'-', a[i]})
+			i++
+		default:
+			lines = append(lines, diffLine{'+', b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		lines = append(lines, diffLine{'-', a[i]})
+	}
+	for ; j < len(b); j++ {
+		lines = append(lines, diffLine{'+', b[j]})
+	}
+	return lines
+}
+
+func splitLines(text string) []string {
+	text = strings.TrimRight(strings.ReplaceAll(text, "\r\n", "\n"), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// conversionDiff renders a unified-style diff of the original and converted
+// text, without hunk headers.
+func conversionDiff(before, after string, color bool) string {
+	var b strings.Builder
+	for _, line := range lineDiff(before, after) {
+		text := string(line.op) + " " + line.text
+		switch {
+		case color && line.op == '-':
+			text = ansiRed + text + ansiReset
+		case color && line.op == '+':
+			text = ansiGreen + text + ansiReset
+		}
+		b.WriteString(text + "\n")
+	}
+	return b.String()
+}
+
+// sideBySide renders the original and converted text in two columns aligned
+// on their common lines. The gutter shows '|' for changed lines, '<' for
+// removed and '>' for added ones. Long lines are wrapped.
+func sideBySide(before, after string, width int, color bool) string {
+	if width <= 0 {
+		width = 160
+	}
+	column := max((width-3)/2, 10)
+
+	type row struct {
+		left, right string
+		gutter      byte
+	}
+	var rows []row
+
+	diff := lineDiff(before, after)
+	for i := 0; i < len(diff); {
+		if diff[i].op == ' ' {
+			rows = append(rows, row{diff[i].text, diff[i].text, ' '})
+			i++
+			continue
+		}
+
+		// Pair a run of removed lines with the added lines that follow
+		var removed, added []string
+		for ; i < len(diff) && diff[i].op == '-'; i++ {
+			removed = append(removed, diff[i].text)
+		}
+		for ; i < len(diff) && diff[i].op == '+'; i++ {
+			added = append(added, diff[i].text)
+		}
+		for k := 0; k < max(len(removed), len(added)); k++ {
+			switch {
+			case k < len(removed) && k < len(added):
+				rows = append(rows, row{removed[k], added[k], '|'})
+			case k < len(removed):
+				rows = append(rows, row{removed[k], "", '<'})
+			default:
+				rows = append(rows, row{"", added[k], '>'})
+			}
+		}
+	}
+
+	var b strings.Builder
+	for _, r := range rows {
+		left := wrapRunes(r.left, column)
+		right := wrapRunes(r.right, column)
+		for k := 0; k < max(len(left), len(right)); k++ {
+			l, rt := "", ""
+			if k < len(left) {
+				l = left[k]
+			}
+			if k < len(right) {
+				rt = right[k]
+			}
+			l += strings.Repeat(" ", column-utf8.RuneCountInString(l))
+
+			gutter := " "
+			if k == 0 {
+				gutter = string(r.gutter)
+			}
+			if color && r.gutter != ' ' {
+				l = ansiRed + l + ansiReset
+				rt = ansiGreen + rt + ansiReset
+			}
+			b.WriteString(strings.TrimRight(l+" "+gutter+" "+rt, " ") + "\n")
+		}
+	}
+	return b.String()
+}
+
+// wrapRunes splits text into chunks of at most width runes. Empty text is a
+// single empty chunk.
+func wrapRunes(text string, width int) []string {
+	runes := []rune(text)
+	if len(runes) == 0 {
+		return []string{""}
+	}
+	var chunks []string
+	for len(runes) > width {
+		chunks = append(chunks, string(runes[:width]))
+		runes = runes[width:]
+	}
+	return append(chunks, string(runes))
+}
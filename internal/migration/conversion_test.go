@@ -0,0 +1,92 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestConversionDiff(t *testing.T) {
+	before := "Hello [b]world[/b]\nSame line\n[i]gone[/i]"
+	after := "Hello **world**\nSame line\n\n*gone*\nNew line"
+
+	expected := "- Hello [b]world[/b]\n" +
+		"+ Hello **world**\n" +
+		"  Same line\n" +
+		"- [i]gone[/i]\n" +
+		"+ \n" +
+		"+ *gone*\n" +
+		"+ New line\n"
+	if result := conversionDiff(before, after, false); result != expected {
+		t.Errorf("Expected diff:\n%s\ngot:\n%s", expected, result)
+	}
+
+	colored := conversionDiff("a", "b", true)
+	if colored != ansiRed+"- a"+ansiReset+"\n"+ansiGreen+"+ b"+ansiReset+"\n" {
+		t.Errorf("Expected colored diff, got %q", colored)
+	}
+
+	if result := conversionDiff("same\r\n", "same", false); result != "  same\n" {
+		t.Errorf("Expected line endings to be ignored, got %q", result)
+	}
+}
+
+func TestSideBySide(t *testing.T) {
+	before := "Same\n[b]bold[/b]\n[i]removed[/i]"
+	after := "Same\n**bold**"
+
+	// 12-rune columns; the long line wraps
+	expected := "Same           Same\n" +
+		"[b]bold[/b]  | **bold**\n" +
+		"[i]removed[/ <\n" +
+		"i]\n"
+	if result := sideBySide(before, after, 27, false); result != expected {
+		t.Errorf("Expected side-by-side output:\n%s\ngot:\n%s", expected, result)
+	}
+}
+
+type fakeConversionSource struct {
+	thread xenforo.Thread
+	posts  []xenforo.Post
+}
+
+func (f *fakeConversionSource) GetThread(ctx context.Context, threadID int) (*xenforo.Thread, error) {
+	if threadID != f.thread.ThreadID {
+		return nil, fmt.Errorf("thread %d not found", threadID)
+	}
+	return &f.thread, nil
+}
+
+func (f *fakeConversionSource) GetPosts(ctx context.Context, thread xenforo.Thread) ([]xenforo.Post, error) {
+	return f.posts, nil
+}
+
+func TestShowConversion(t *testing.T) {
+	source := &fakeConversionSource{
+		thread: xenforo.Thread{ThreadID: 7, Title: "Welcome"},
+		posts: []xenforo.Post{
+			{PostID: 70, Username: "alice", Message: "[b]Hi[/b]"},
+			{PostID: 71, Username: "bob", Message: "Plain"},
+		},
+	}
+
+	var out strings.Builder
+	if err := ShowConversion(context.Background(), &config.Config{}, source, 7, &out, ConversionOptions{}); err != nil {
+		t.Fatalf("ShowConversion returned error: %v", err)
+	}
+
+	expected := "Thread 7: Welcome (2 posts)\n" +
+		"\n=== Post 70 by alice ===\n- [b]Hi[/b]\n+ **Hi**\n" +
+		"\n=== Post 71 by bob ===\n  Plain\n"
+	if out.String() != expected {
+		t.Errorf("Expected output:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	if err := ShowConversion(context.Background(), &config.Config{}, source, 8, &out, ConversionOptions{}); err == nil {
+		t.Error("Expected an error for an unknown thread")
+	}
+}
@@ -0,0 +1,45 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+)
+
+// writeDryRunDiscussion writes a would-be discussion to
+// DIR/<thread-id>/discussion.md (discussion-part-N.md for later parts of a
+// split thread) when dry-run output is enabled.
+func (r *Runner) writeDryRunDiscussion(ctx context.Context, threadID, part int, title, body string) {
+	name := "discussion.md"
+	if part > 1 {
+		name = fmt.Sprintf("discussion-part-%d.md", part)
+	}
+	r.writeDryRunFile(ctx, threadID, name, "# "+title+"\n\n"+body)
+}
+
+// writeDryRunComment writes a would-be comment to DIR/<thread-id>/comment-N.md,
+// numbered by the post's position among the thread's replies.
+func (r *Runner) writeDryRunComment(ctx context.Context, threadID, number int, body string) {
+	r.writeDryRunFile(ctx, threadID, fmt.Sprintf("comment-%d.md", number), body)
+}
+
+func (r *Runner) writeDryRunFile(ctx context.Context, threadID int, name, content string) {
+	dir := r.config.Migration.DryRunOutput
+	if dir == "" || !r.isDryRun(ctx) {
+		return
+	}
+
+	threadDir := filepath.Join(dir, strconv.Itoa(threadID))
+	if err := os.MkdirAll(threadDir, 0755); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to create dry-run output directory %s: %v", threadDir, err)
+		return
+	}
+	path := filepath.Join(threadDir, name)
+	if err := os.WriteFile(path, []byte(content+"\n"), 0644); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to write dry-run output %s: %v", path, err)
+	}
+}
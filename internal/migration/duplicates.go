@@ -0,0 +1,100 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// findExistingDiscussion searches the target category for a discussion already
+// created for the thread, e.g. by a run whose progress file was lost. A match
+// needs the same title and the thread marker. Returns nil when the thread has
+// no discussion yet or detection is disabled.
+func (r *Runner) findExistingDiscussion(ctx context.Context, thread xenforo.Thread, title string) (*progress.DiscussionRef, error) {
+	if !r.config.Migration.DetectDuplicates || r.githubClient == nil {
+		return nil, nil
+	}
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would check for an existing discussion of thread %d", thread.ThreadID)
+		return nil, nil
+	}
+
+	result, err := r.githubClient.FindDiscussion(ctx, r.config.GitHub.Repository, r.discussionCategory(thread), title, r.processor.ThreadMarker(thread.ThreadID))
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for an existing discussion: %w", err)
+	}
+	if result == nil {
+		return nil, nil
+	}
+	return &progress.DiscussionRef{ID: result.ID, Number: result.Number, URL: r.discussionURL(result.Number)}, nil
+}
+
+// existingCheckpoint returns a checkpoint resuming a thread into the
+// discussions found for its parts, and the number of its posts they hold.
+// The posts already written are counted from the discussion bodies and
+// comments, like fixup lines them up, and the thread continues after them
+// with replies threaded under the comments found. Returns nil when the thread
+// has no discussion yet.
+func (r *Runner) existingCheckpoint(ctx context.Context, thread xenforo.Thread, parts [][]xenforo.Post) (*progress.ThreadCheckpoint, int, error) {
+	checkpoint := &progress.ThreadCheckpoint{}
+	done := 0
+	for i, part := range parts {
+		existing, err := r.findExistingDiscussion(ctx, thread, r.partTitle(thread, i+1, len(parts)))
+		if err != nil {
+			return nil, 0, err
+		}
+		if existing == nil {
+			break
+		}
+		content, err := r.githubClient.GetDiscussionContent(ctx, r.config.GitHub.Repository, existing.Number)
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read existing discussion #%d: %w", existing.Number, err)
+		}
+
+		checkpoint.Discussions = append(checkpoint.Discussions, progress.DiscussionRef{ID: existing.ID, Number: existing.Number})
+		checkpoint.ResetComments()
+		written := storedComments(storedPosts(content), part, checkpoint)
+		done += written
+		if written < len(part) {
+			break
+		}
+		if hasNextPartLink(content) {
+			checkpoint.LinkedParts = i + 1
+		}
+	}
+	if len(checkpoint.Discussions) == 0 {
+		return nil, 0, nil
+	}
+
+	posts := slices.Concat(parts...)
+	checkpoint.LastPostID = posts[done-1].PostID
+	return checkpoint, done, nil
+}
+
+// storedComments records the comments holding the posts of part already in a
+// discussion, for replies to them to thread under, and returns the number of
+// posts of part the discussion holds.
+func storedComments(stored []storedPost, part []xenforo.Post, checkpoint *progress.ThreadCheckpoint) int {
+	written := min(len(stored), len(part))
+	for j := 1; j < written; j++ {
+		checkpoint.AddComment(part[j].PostID, stored[j].threadID())
+	}
+	return written
+}
+
+// hasNextPartLink reports whether a discussion links to the next part of its
+// thread.
+func hasNextPartLink(content *github.DiscussionContent) bool {
+	for _, comment := range content.Comments {
+		if strings.HasPrefix(comment, nextPartLinkPrefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/attachments"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// Estimate is the expected size, GitHub API usage and duration of migrating a
+// node with the current configuration.
+type Estimate struct {
+	xenforo.NodeStats
+	Mode     string        // Estimate mode the attachments were counted with
+	APICalls int           // GitHub API calls, not counting retries
+	Duration time.Duration // Wall-clock time given the configured delays and workers
+}
+
+// StatsSource provides the node statistics an estimate is based on.
+type StatsSource interface {
+	GetDryRunStats(ctx context.Context, nodeID int) (threadCount, postCount, attachmentCount, userCount int, err error)
+	GetNodeStats(ctx context.Context, nodeID, sampleThreads int) (*xenforo.NodeStats, error)
+}
+
+// EstimateMigration counts the content of the configured node the way
+// EstimateMode asks for and derives the GitHub API calls and wall-clock time
+// of migrating it.
+func EstimateMigration(ctx context.Context, source StatsSource, cfg *config.Config) (*Estimate, error) {
+	nodeID := cfg.GitHub.XenForoNodeID
+
+	var stats *xenforo.NodeStats
+	switch cfg.Migration.EstimateMode {
+	case config.EstimateSample:
+		var err error
+		if stats, err = source.GetNodeStats(ctx, nodeID, cfg.Migration.EstimateSampleThreads); err != nil {
+			return nil, err
+		}
+	case config.EstimateFull:
+		var err error
+		if stats, err = source.GetNodeStats(ctx, nodeID, 0); err != nil {
+			return nil, err
+		}
+	default:
+		threads, posts, attachments, users, err := source.GetDryRunStats(ctx, nodeID)
+		if err != nil {
+			return nil, err
+		}
+		stats = &xenforo.NodeStats{Threads: threads, Posts: posts, Attachments: attachments, Users: users}
+	}
+
+	return estimateRun(cfg, *stats), nil
+}
+
+// estimateRun derives the API calls and duration of migrating the counted
+// content. The opening post creates the discussion and replies add comments,
+// CommentBatchSize per request; every request is paced by RateLimitDelay,
+// comment requests also wait the post delay, and attachments wait
+// AttachmentRateLimitDelay per download. Threads run in parallel on MigrationConcurrency workers, and the download
+// throughput cap bounds the total.
+func estimateRun(cfg *config.Config, stats xenforo.NodeStats) *Estimate {
+	mode := cfg.Migration.EstimateMode
+	if mode == "" {
+		mode = config.EstimateQuick
+	}
+	estimate := &Estimate{NodeStats: stats, Mode: mode}
+
+	commentWrites := max(0, stats.Posts-stats.Threads)
+	if batch := cfg.GitHub.CommentBatchSize; batch > 1 {
+		commentWrites = (commentWrites + batch - 1) / batch
+	}
+	calls := stats.Threads + commentWrites
+	if split := cfg.Migration.SplitThreadPosts; split > 0 {
+		// At least the parts needed if the posts were spread evenly; each part
+		// adds a discussion and a link comment
+		parts := max(0, (stats.Posts+split-1)/split-stats.Threads)
+		calls += 2 * parts
+	}
+	if cfg.Migration.DetectDuplicates {
+		calls += stats.Threads
+	}
+	if target, _ := cfg.AttachmentHosting(); target != config.AttachmentTargetLocal {
+		if cfg.Filesystem.BatchAttachmentUploads {
+			calls += min(stats.Attachments, stats.Threads)
+		} else {
+			calls += stats.Attachments
+		}
+	}
+	estimate.APICalls = calls
+
+	attachmentWorkers := max(1, cfg.Migration.AttachmentWorkers)
+	work := time.Duration(calls)*cfg.GitHub.RateLimitDelay +
+		time.Duration(commentWrites)*defaultPostDelay +
+		time.Duration(stats.Attachments)*cfg.Filesystem.AttachmentRateLimitDelay/time.Duration(attachmentWorkers)
+	duration := work / time.Duration(max(1, cfg.Migration.MigrationConcurrency))
+
+	if rate := cfg.Filesystem.MaxDownloadBytesPerSec; rate > 0 {
+		duration = max(duration, time.Duration(stats.AttachmentBytes*int64(time.Second)/rate))
+	}
+	estimate.Duration = duration.Round(time.Second)
+
+	return estimate
+}
+
+// Print writes the estimate as the dry-run summary table.
+func (e *Estimate) Print(w io.Writer) {
+	size := attachments.FormatSize(e.AttachmentBytes)
+	attachments := fmt.Sprint(e.Attachments)
+	switch {
+	case e.Mode == config.EstimateQuick:
+		attachments = "~" + attachments
+		size = "unknown"
+	case e.Extrapolated:
+		attachments = "~" + attachments
+		size = "~" + size
+	}
+
+	_, _ = fmt.Fprintln(w, "┌──────────────────┬──────────────┐")
+	_, _ = fmt.Fprintln(w, "│ Content          │ Count        │")
+	_, _ = fmt.Fprintln(w, "├──────────────────┼──────────────┤")
+	_, _ = fmt.Fprintf(w, "│ Threads          │ %12d │\n", e.Threads)
+	_, _ = fmt.Fprintf(w, "│ Posts            │ %12d │\n", e.Posts)
+	_, _ = fmt.Fprintf(w, "│ Attachments      │ %12s │\n", attachments)
+	_, _ = fmt.Fprintf(w, "│ Attachment size  │ %12s │\n", size)
+	_, _ = fmt.Fprintf(w, "│ Users            │ %12d │\n", e.Users)
+	_, _ = fmt.Fprintln(w, "├──────────────────┼──────────────┤")
+	_, _ = fmt.Fprintf(w, "│ GitHub API calls │ %12d │\n", e.APICalls)
+	_, _ = fmt.Fprintf(w, "│ Estimated time   │ %12s │\n", e.Duration)
+	_, _ = fmt.Fprintln(w, "└──────────────────┴──────────────┘")
+
+	switch {
+	case e.Mode == config.EstimateQuick:
+		_, _ = fmt.Fprintln(w, "Attachments are assumed on 10% of the posts; set ESTIMATE_MODE=sample or full to count them.")
+	case e.Extrapolated:
+		_, _ = fmt.Fprintf(w, "Attachments are extrapolated from %d of %d threads.\n", e.SampledThreads, e.Threads)
+	}
+}
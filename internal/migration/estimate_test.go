@@ -0,0 +1,91 @@
+package migration
+
+import (
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestEstimateMigration(t *testing.T) {
+	forum := newTestForum(4)
+	for _, id := range []int{1, 3} {
+		forum.posts[id][0].Attachments = []xenforo.Attachment{{AttachmentID: id, Filename: "photo.png", FileSize: 1000}}
+	}
+	server := httptest.NewServer(forum)
+	t.Cleanup(server.Close)
+	client := xenforo.NewClient(server.URL, "test_key", "1", 1)
+
+	cfg := &config.Config{
+		GitHub:     config.GitHubConfig{XenForoNodeID: 1, RateLimitDelay: time.Second},
+		Migration:  config.MigrationConfig{MigrationConcurrency: 2, EstimateSampleThreads: 2},
+		Filesystem: config.FilesystemConfig{AttachmentRateLimitDelay: 500 * time.Millisecond},
+	}
+
+	tests := []struct {
+		mode         string
+		attachments  int
+		bytes        int64
+		extrapolated bool
+		duration     time.Duration
+	}{
+		{mode: config.EstimateQuick, attachments: 0, duration: 2 * time.Second},
+		{mode: config.EstimateFull, attachments: 2, bytes: 2000, duration: 2500 * time.Millisecond},
+		// Threads 1 and 3 are sampled, so every post seems to have an attachment
+		{mode: config.EstimateSample, attachments: 4, bytes: 4000, extrapolated: true, duration: 3 * time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			cfg.Migration.EstimateMode = tt.mode
+			estimate, err := EstimateMigration(context.Background(), client, cfg)
+			if err != nil {
+				t.Fatalf("EstimateMigration returned error: %v", err)
+			}
+
+			if estimate.Threads != 4 || estimate.Posts != 4 {
+				t.Errorf("Expected 4 threads and 4 posts, got %d and %d", estimate.Threads, estimate.Posts)
+			}
+			if estimate.Attachments != tt.attachments || estimate.AttachmentBytes != tt.bytes {
+				t.Errorf("Expected %d attachments of %d bytes, got %d of %d", tt.attachments, tt.bytes, estimate.Attachments, estimate.AttachmentBytes)
+			}
+			if estimate.Extrapolated != tt.extrapolated {
+				t.Errorf("Expected extrapolated %v, got %v", tt.extrapolated, estimate.Extrapolated)
+			}
+			if estimate.APICalls != 4 {
+				t.Errorf("Expected 4 API calls, got %d", estimate.APICalls)
+			}
+			// Durations are rounded to the second
+			if estimate.Duration != tt.duration.Round(time.Second) {
+				t.Errorf("Expected duration %v, got %v", tt.duration.Round(time.Second), estimate.Duration)
+			}
+		})
+	}
+}
+
+func TestEstimatePrint(t *testing.T) {
+	estimate := &Estimate{
+		NodeStats: xenforo.NodeStats{Threads: 10, Posts: 120, Attachments: 30, AttachmentBytes: 3 << 20, Users: 7, SampledThreads: 5, Extrapolated: true},
+		Mode:      config.EstimateSample,
+		APICalls:  120,
+		Duration:  4 * time.Minute,
+	}
+
+	var out strings.Builder
+	estimate.Print(&out)
+	for _, expected := range []string{
+		"│ Attachments      │          ~30 │",
+		"│ Attachment size  │     ~3.0 MiB │",
+		"│ GitHub API calls │          120 │",
+		"│ Estimated time   │         4m0s │",
+		"Attachments are extrapolated from 5 of 10 threads.",
+	} {
+		if !strings.Contains(out.String(), expected) {
+			t.Errorf("Expected %q in:\n%s", expected, out.String())
+		}
+	}
+}
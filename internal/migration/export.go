@@ -0,0 +1,226 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/bbcode"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// ExportFileName is the file an export-only run writes to its directory and
+// the upload command reads from it.
+const ExportFileName = "discussions.ndjson"
+
+// ExportedThread is a thread rendered by an export-only run, written as one
+// line of the export file.
+type ExportedThread struct {
+	ThreadID    int                  `json:"thread_id"`
+	Locked      bool                 `json:"locked,omitempty"` // Lock the discussions once uploaded
+	Discussions []ExportedDiscussion `json:"discussions"`      // One per part of a split thread
+	Attachments []ExportedAttachment `json:"attachments,omitempty"`
+}
+
+// ExportedDiscussion is a discussion of an exported thread. Later parts of a
+// split thread get their link to the previous part when uploaded.
+type ExportedDiscussion struct {
+	PostID     int               `json:"post_id"` // Opening post
+	Title      string            `json:"title"`
+	CategoryID string            `json:"category_id"`
+	Body       string            `json:"body"`
+	Comments   []ExportedComment `json:"comments"`
+}
+
+// ExportedComment is a reply of an exported discussion.
+type ExportedComment struct {
+	PostID        int    `json:"post_id"`
+	Author        string `json:"author"`
+	ReplyToPostID int    `json:"reply_to_post_id,omitempty"` // Earlier comment of the discussion this one is threaded under
+	Answer        bool   `json:"answer,omitempty"`           // Mark the comment as the answer
+	Body          string `json:"body"`
+}
+
+// ExportedAttachment is a downloaded attachment of an exported thread. Bodies
+// link it by its local link, which is replaced with the hosted URL when the
+// upload hosts attachments.
+type ExportedAttachment struct {
+	AttachmentID int    `json:"attachment_id"`
+	Filename     string `json:"filename"`
+	Path         string `json:"path"`             // Downloaded file
+	Link         string `json:"link"`             // Link target in the bodies
+	SHA256       string `json:"sha256,omitempty"` // Content hash of the file, checked before uploading it
+}
+
+// ThreadExporter writes the threads of an export-only run as NDJSON. Threads
+// are collected while they are rendered and written once complete, so the
+// file only holds fully rendered threads.
+type ThreadExporter struct {
+	path    string
+	mu      sync.Mutex
+	file    *os.File
+	encoder *json.Encoder
+	pending map[int]*ExportedThread
+	count   int
+}
+
+// NewThreadExporter creates DIR/discussions.ndjson, replacing the file of an
+// earlier export.
+func NewThreadExporter(dir string) (*ThreadExporter, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create export directory: %w", err)
+	}
+
+	path := filepath.Join(dir, ExportFileName)
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create export file: %w", err)
+	}
+
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
+	return &ThreadExporter{
+		path:    path,
+		file:    file,
+		encoder: encoder,
+		pending: make(map[int]*ExportedThread),
+	}, nil
+}
+
+// Path returns the export file.
+func (e *ThreadExporter) Path() string {
+	return e.path
+}
+
+// Count returns the number of threads written.
+func (e *ThreadExporter) Count() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.count
+}
+
+// Close closes the export file.
+func (e *ThreadExporter) Close() error {
+	return e.file.Close()
+}
+
+// addDiscussion starts part number of a thread. The first part discards
+// anything collected for the thread by an earlier, failed attempt.
+func (e *ThreadExporter) addDiscussion(threadID, number int, discussion ExportedDiscussion) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	thread, ok := e.pending[threadID]
+	if !ok || number == 1 {
+		thread = &ExportedThread{ThreadID: threadID}
+		e.pending[threadID] = thread
+	}
+	thread.Discussions = append(thread.Discussions, discussion)
+}
+
+// addComment adds a reply to the thread's current discussion, threaded under
+// the first quoted post that is an earlier comment of that discussion.
+// Answers stay top-level, as GitHub only accepts those as answers.
+func (e *ThreadExporter) addComment(threadID int, post xenforo.Post, body string, answer bool) {
+	if e == nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	thread, ok := e.pending[threadID]
+	if !ok || len(thread.Discussions) == 0 {
+		return
+	}
+	discussion := &thread.Discussions[len(thread.Discussions)-1]
+
+	comment := ExportedComment{PostID: post.PostID, Author: post.Username, Answer: answer, Body: body}
+	if !answer {
+		comment.ReplyToPostID = quotedComment(post, discussion.Comments)
+	}
+	discussion.Comments = append(discussion.Comments, comment)
+}
+
+// quotedComment returns the first post quoted by post that is one of
+// comments, or 0.
+func quotedComment(post xenforo.Post, comments []ExportedComment) int {
+	for _, quotedID := range bbcode.QuotedPostIDs(post.Message) {
+		for _, comment := range comments {
+			if comment.PostID == quotedID {
+				return quotedID
+			}
+		}
+	}
+	return 0
+}
+
+// finish writes the collected thread with its attachment manifest to the
+// export file.
+func (e *ThreadExporter) finish(threadID int, locked bool, attachments []ExportedAttachment) error {
+	if e == nil {
+		return nil
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	thread, ok := e.pending[threadID]
+	if !ok {
+		return nil
+	}
+	delete(e.pending, threadID)
+
+	thread.Locked = locked
+	thread.Attachments = attachments
+	if err := e.encoder.Encode(thread); err != nil {
+		return fmt.Errorf("failed to write thread %d to export: %w", threadID, err)
+	}
+	e.count++
+	return nil
+}
+
+// attachmentManifest describes the thread's downloaded attachments that are
+// not hosted yet. Files missing on disk are listed without a hash.
+func (r *Runner) attachmentManifest(ctx context.Context, threadAttachments []xenforo.Attachment, hostedURLs map[int]string) []ExportedAttachment {
+	var manifest []ExportedAttachment
+	for _, attachment := range r.downloader.Allowed(threadAttachments) {
+		if hostedURLs[attachment.AttachmentID] != "" {
+			continue
+		}
+
+		exported := ExportedAttachment{
+			AttachmentID: attachment.AttachmentID,
+			Filename:     attachment.Filename,
+			Path:         r.downloader.LocalPath(attachment),
+			Link:         r.downloader.LocalLink(attachment),
+		}
+		if sum, err := fileSHA256(exported.Path); err != nil {
+			logging.Warnf(ctx, "  ⚠ Exporting attachment %s without its file: %v", attachment.Filename, err)
+		} else {
+			exported.SHA256 = sum
+		}
+		manifest = append(manifest, exported)
+	}
+	return manifest
+}
+
+// fileSHA256 returns the hex SHA-256 hash of a file's content.
+func fileSHA256(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,175 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// threadFilter selects threads by the thread filter settings.
+type threadFilter struct {
+	since, until int64            // Creation time bounds in Unix seconds, until exclusive (0 = unbounded)
+	minReplies   int              // Fewest replies a thread needs
+	prefixes     map[string]bool  // Lower-case prefix titles and IDs (empty = any prefix)
+	authors      map[string]bool  // Lower-case author usernames (empty = any author)
+	state        string           // config.ThreadsOpen, config.ThreadsClosed or empty
+	include      config.ThreadIDs // Thread IDs migrated (empty = all)
+	exclude      config.ThreadIDs // Thread IDs never migrated
+	description  []string         // Active filters for messages
+}
+
+// newThreadFilter returns the filter the migration settings configure, or nil
+// when no thread filter is set.
+func newThreadFilter(cfg config.MigrationConfig) (*threadFilter, error) {
+	f := &threadFilter{minReplies: cfg.FilterMinReplies, state: cfg.FilterState}
+
+	if cfg.FilterSince != "" {
+		since, _, err := config.ParseFilterDate(cfg.FilterSince)
+		if err != nil {
+			return nil, err
+		}
+		f.since = since.Unix()
+		f.description = append(f.description, "since "+cfg.FilterSince)
+	}
+	if cfg.FilterUntil != "" {
+		until, dateOnly, err := config.ParseFilterDate(cfg.FilterUntil)
+		if err != nil {
+			return nil, err
+		}
+		if dateOnly {
+			until = until.AddDate(0, 0, 1)
+		} else {
+			until = until.Add(time.Second)
+		}
+		f.until = until.Unix()
+		f.description = append(f.description, "until "+cfg.FilterUntil)
+	}
+	if f.minReplies > 0 {
+		f.description = append(f.description, fmt.Sprintf("at least %d replies", f.minReplies))
+	}
+	if len(cfg.FilterPrefixes) > 0 {
+		f.prefixes = lowerSet(cfg.FilterPrefixes)
+		f.description = append(f.description, "prefix "+strings.Join(cfg.FilterPrefixes, " or "))
+	}
+	if len(cfg.FilterAuthors) > 0 {
+		f.authors = lowerSet(cfg.FilterAuthors)
+		f.description = append(f.description, "by "+strings.Join(cfg.FilterAuthors, " or "))
+	}
+	if f.state != "" {
+		f.description = append(f.description, f.state)
+	}
+
+	var err error
+	if f.include, err = config.ReadThreadIDs(cfg.FilterThreads); err != nil {
+		return nil, err
+	}
+	if len(f.include) > 0 {
+		f.description = append(f.description, fmt.Sprintf("%d selected thread IDs or ranges", len(f.include)))
+	}
+	if f.exclude, err = config.ReadThreadIDs(cfg.ExcludeThreads); err != nil {
+		return nil, err
+	}
+	if len(f.exclude) > 0 {
+		f.description = append(f.description, fmt.Sprintf("excluding %d thread IDs or ranges", len(f.exclude)))
+	}
+
+	if len(f.description) == 0 {
+		return nil, nil
+	}
+	return f, nil
+}
+
+func lowerSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[strings.ToLower(strings.TrimSpace(value))] = true
+	}
+	return set
+}
+
+// matches reports whether a thread passes every filter.
+func (f *threadFilter) matches(thread xenforo.Thread) bool {
+	switch {
+	case f.since > 0 && thread.PostDate < f.since:
+		return false
+	case f.until > 0 && thread.PostDate >= f.until:
+		return false
+	case thread.ReplyCount < f.minReplies:
+		return false
+	case len(f.prefixes) > 0 && !f.matchesPrefix(thread):
+		return false
+	case len(f.authors) > 0 && !f.authors[strings.ToLower(strings.TrimSpace(thread.Username))]:
+		return false
+	case f.state == config.ThreadsOpen && thread.IsLocked():
+		return false
+	case f.state == config.ThreadsClosed && !thread.IsLocked():
+		return false
+	case len(f.include) > 0 && !f.include.Contains(thread.ThreadID):
+		return false
+	case f.exclude.Contains(thread.ThreadID):
+		return false
+	}
+	return true
+}
+
+// matchesPrefix matches the thread's prefix by title or ID, like the prefix
+// category and label mappings.
+func (f *threadFilter) matchesPrefix(thread xenforo.Thread) bool {
+	if title := strings.ToLower(strings.TrimSpace(thread.Prefix)); title != "" && f.prefixes[title] {
+		return true
+	}
+	return thread.PrefixID > 0 && f.prefixes[strconv.Itoa(thread.PrefixID)]
+}
+
+// filterThreads keeps only the threads matching the configured thread
+// filters.
+func (r *Runner) filterThreads(ctx context.Context, threads []xenforo.Thread) ([]xenforo.Thread, error) {
+	filter, err := newThreadFilter(r.config.Migration)
+	if err != nil {
+		return nil, fmt.Errorf("invalid thread filter: %w", err)
+	}
+	if filter == nil {
+		return threads, nil
+	}
+
+	var filtered []xenforo.Thread
+	for _, thread := range threads {
+		if filter.matches(thread) {
+			filtered = append(filtered, thread)
+		}
+	}
+	logging.Infof(ctx, "✓ %d of %d threads match the thread filters (%s)", len(filtered), len(threads), strings.Join(filter.description, ", "))
+	return filtered, nil
+}
+
+// orderThreads sorts threads into the configured migration order. Threads
+// without a latest post date sort by their creation date, and ties keep
+// thread ID order. With parallel workers the order is only approximate.
+func (r *Runner) orderThreads(ctx context.Context, threads []xenforo.Thread) {
+	var key func(thread xenforo.Thread) int64
+	switch r.config.Migration.MigrationOrder {
+	case config.OrderOldestFirst:
+		key = func(thread xenforo.Thread) int64 { return thread.PostDate }
+	case config.OrderNewestFirst:
+		key = func(thread xenforo.Thread) int64 { return -thread.PostDate }
+	case config.OrderLastActivity:
+		key = func(thread xenforo.Thread) int64 { return max(thread.LastPostDate, thread.PostDate) }
+	default:
+		return
+	}
+
+	sort.SliceStable(threads, func(i, j int) bool {
+		if ki, kj := key(threads[i]), key(threads[j]); ki != kj {
+			return ki < kj
+		}
+		return threads[i].ThreadID < threads[j].ThreadID
+	})
+	logging.Infof(ctx, "✓ Migrating threads %s", r.config.Migration.MigrationOrder)
+}
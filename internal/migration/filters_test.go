@@ -0,0 +1,109 @@
+package migration
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestFilterThreads(t *testing.T) {
+	closed := false
+	day := func(date string) int64 {
+		parsed, err := time.Parse(time.DateOnly, date)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return parsed.Unix()
+	}
+	threads := []xenforo.Thread{
+		{ThreadID: 1, Username: "alice", PostDate: day("2019-06-01"), ReplyCount: 3},
+		{ThreadID: 2, Username: "Bob", PostDate: day("2020-01-01"), ReplyCount: 0, Prefix: "Solved"},
+		{ThreadID: 3, Username: "alice", PostDate: day("2021-12-31") + 23*3600, ReplyCount: 1, PrefixID: 12},
+		{ThreadID: 4, Username: "carol", PostDate: day("2022-01-01"), ReplyCount: 5, DiscussionOpen: &closed},
+	}
+
+	tests := []struct {
+		name     string
+		setup    func(cfg *config.MigrationConfig)
+		expected []int
+	}{
+		{"No filters", func(cfg *config.MigrationConfig) {}, []int{1, 2, 3, 4}},
+		{"Since", func(cfg *config.MigrationConfig) { cfg.FilterSince = "2020-01-01" }, []int{2, 3, 4}},
+		{"Until includes the whole day", func(cfg *config.MigrationConfig) { cfg.FilterUntil = "2021-12-31" }, []int{1, 2, 3}},
+		{"Until an exact time", func(cfg *config.MigrationConfig) { cfg.FilterUntil = "2021-12-31T12:00:00Z" }, []int{1, 2}},
+		{"Min replies", func(cfg *config.MigrationConfig) { cfg.FilterMinReplies = 1 }, []int{1, 3, 4}},
+		{"Prefix title or ID", func(cfg *config.MigrationConfig) { cfg.FilterPrefixes = []string{"solved", "12"} }, []int{2, 3}},
+		{"Author", func(cfg *config.MigrationConfig) { cfg.FilterAuthors = []string{"bob", "carol"} }, []int{2, 4}},
+		{"Open", func(cfg *config.MigrationConfig) { cfg.FilterState = config.ThreadsOpen }, []int{1, 2, 3}},
+		{"Closed", func(cfg *config.MigrationConfig) { cfg.FilterState = config.ThreadsClosed }, []int{4}},
+		{"Thread IDs", func(cfg *config.MigrationConfig) { cfg.FilterThreads = "1,3-10" }, []int{1, 3, 4}},
+		{"Excluded thread IDs", func(cfg *config.MigrationConfig) {
+			cfg.FilterThreads = "1-3"
+			cfg.ExcludeThreads = "2"
+		}, []int{1, 3}},
+		{"Combined", func(cfg *config.MigrationConfig) {
+			cfg.FilterSince = "2020-01-01"
+			cfg.FilterMinReplies = 1
+			cfg.FilterAuthors = []string{"alice"}
+		}, []int{3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner := &Runner{config: &config.Config{}}
+			tt.setup(&runner.config.Migration)
+
+			filtered, err := runner.filterThreads(context.Background(), threads)
+			if err != nil {
+				t.Fatalf("filterThreads returned error: %v", err)
+			}
+			var ids []int
+			for _, thread := range filtered {
+				ids = append(ids, thread.ThreadID)
+			}
+			if len(ids) != len(tt.expected) {
+				t.Fatalf("Expected threads %v, got %v", tt.expected, ids)
+			}
+			for i := range ids {
+				if ids[i] != tt.expected[i] {
+					t.Fatalf("Expected threads %v, got %v", tt.expected, ids)
+				}
+			}
+		})
+	}
+}
+
+func TestOrderThreads(t *testing.T) {
+	tests := []struct {
+		order    string
+		expected []int
+	}{
+		{config.OrderListing, []int{3, 1, 4, 2}},
+		{config.OrderOldestFirst, []int{1, 2, 3, 4}},
+		{config.OrderNewestFirst, []int{4, 3, 1, 2}},
+		{config.OrderLastActivity, []int{2, 4, 1, 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.order, func(t *testing.T) {
+			threads := []xenforo.Thread{
+				{ThreadID: 3, PostDate: 300, LastPostDate: 900},
+				{ThreadID: 1, PostDate: 100, LastPostDate: 500},
+				{ThreadID: 4, PostDate: 400},
+				{ThreadID: 2, PostDate: 100, LastPostDate: 150},
+			}
+			runner := &Runner{config: &config.Config{}}
+			runner.config.Migration.MigrationOrder = tt.order
+
+			runner.orderThreads(context.Background(), threads)
+			for i, thread := range threads {
+				if thread.ThreadID != tt.expected[i] {
+					t.Fatalf("Expected order %v, got thread %d at %d", tt.expected, thread.ThreadID, i)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,245 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// Fixup renders the threads recorded in the progress file again with the
+// current configuration and converter, and patches the titles and posts of
+// their discussions that differ from the new rendering, e.g. after a
+// converter fix. Links to forum threads are rewritten to their discussions
+// like the relink command does, when the forum's web URL is configured. Only
+// threadIDs are fixed up when given. Attachments are
+// linked at the URLs the uploader hosted them at, without uploading them
+// again. Discussions whose comments no longer line up with the thread's posts
+// keep their comments. An exhausted rate limit stops the fixup. Returns the
+// number of updated posts.
+func (r *Runner) Fixup(ctx context.Context, threadIDs []int, dryRun bool) (int, error) {
+	recorded := r.tracker.Discussions()
+	var links *threadLinkRewriter
+	if r.config.XenForo.WebURL != "" {
+		var err error
+		if links, err = newThreadLinkRewriter(r.config.XenForo.WebURL, r.threadLinkTargets(recorded)); err != nil {
+			return 0, err
+		}
+	}
+
+	threads, err := r.xenforoSource.GetThreadsFrom(ctx, r.config.GitHub.XenForoNodeID, 1, nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list threads: %w", err)
+	}
+
+	updated := 0
+	for _, thread := range threads {
+		ref, ok := recorded[thread.ThreadID]
+		if !ok || (len(threadIDs) > 0 && !slices.Contains(threadIDs, thread.ThreadID)) {
+			continue
+		}
+
+		ctx := logging.With(ctx, "thread_id", thread.ThreadID)
+		logging.Infof(ctx, "\nFixing up thread %d: %s", thread.ThreadID, thread.Title)
+		count, err := r.fixupThread(ctx, thread, ref, links, dryRun)
+		updated += count
+		if err != nil {
+			if errors.Is(err, github.ErrRateLimitExhausted) {
+				return updated, err
+			}
+			logging.Errorf(ctx, "✗ Failed to fix up thread %d: %v", thread.ThreadID, err)
+		}
+	}
+	return updated, nil
+}
+
+// fixupThread renders a thread like processPosts and updates the posts of its
+// discussions that differ.
+func (r *Runner) fixupThread(ctx context.Context, thread xenforo.Thread, ref progress.DiscussionRef, links *threadLinkRewriter, dryRun bool) (int, error) {
+	posts, err := r.fetchPosts(ctx, thread)
+	if err != nil {
+		return 0, err
+	}
+	r.saveInlineImages(posts)
+	threadAttachments := r.collectAttachments(posts)
+	hostedURLs := r.hostedAttachmentURLs(thread.ThreadID, threadAttachments)
+
+	parts := splitPosts(posts, r.config.Migration.SplitThreadPosts)
+	refs := append([]progress.DiscussionRef{ref}, ref.Parts...)
+	if len(parts) != len(refs) {
+		return 0, fmt.Errorf("thread splits into %d parts but %d discussions are recorded", len(parts), len(refs))
+	}
+
+	updated := 0
+	for i, part := range parts {
+		number := i + 1
+		content, err := r.githubClient.GetDiscussionContent(ctx, r.config.GitHub.Repository, refs[i].Number)
+		if err != nil {
+			return updated, err
+		}
+
+		body, err := r.formatPost(ctx, part[0], thread.ThreadID, threadAttachments, hostedURLs)
+		if err != nil {
+			return updated, err
+		}
+		if number == 1 {
+			body = r.withThreadSummary(thread, posts, body, threadAttachments, hostedURLs)
+		} else {
+			body = r.continuedFrom(number, refs[i-1].Number) + body
+		}
+		body, _ = links.rewrite(body)
+
+		stored := storedPosts(content)
+		title := r.partTitle(thread, number, len(parts))
+		description := fmt.Sprintf("discussion #%d", refs[i].Number)
+		count, err := r.fixupPost(ctx, description, stored[0], body, title != content.Title, dryRun, func(id, body string) error {
+			if id == content.ID {
+				return r.githubClient.UpdateDiscussion(ctx, id, title, body)
+			}
+			return r.githubClient.UpdateDiscussionComment(ctx, id, body)
+		})
+		updated += count
+		if err != nil {
+			return updated, err
+		}
+
+		comments := stored[1:]
+		if len(comments) != len(part)-1 {
+			logging.Warnf(ctx, "  ⚠ Discussion #%d has %d comments for %d posts, keeping its comments", refs[i].Number, len(comments), len(part)-1)
+			continue
+		}
+
+		for j, post := range part[1:] {
+			body, err := r.formatPost(logging.With(ctx, "post_id", post.PostID), post, thread.ThreadID, threadAttachments, hostedURLs)
+			if err != nil {
+				return updated, err
+			}
+			body, _ = links.rewrite(body)
+
+			description := fmt.Sprintf("the comment for post %d in discussion #%d", post.PostID, refs[i].Number)
+			count, err := r.fixupPost(ctx, description, comments[j], body, false, dryRun, func(id, body string) error {
+				return r.githubClient.UpdateDiscussionComment(ctx, id, body)
+			})
+			updated += count
+			if err != nil {
+				return updated, err
+			}
+		}
+	}
+	return updated, nil
+}
+
+// storedPost is a migrated post: its discussion body or comment, followed by
+// the comments continuing it when it was split at the body limit.
+type storedPost struct {
+	ids    []string
+	bodies []string
+	parent string // Comment the post replies to, empty for a top-level comment
+}
+
+// threadID returns the comment ID that replies to the post thread under.
+// Discussions nest one level deep, so replies thread under their parent.
+func (p storedPost) threadID() string {
+	if p.parent != "" {
+		return p.parent
+	}
+	return p.ids[0]
+}
+
+// storedPosts groups the body and comments of a discussion into its migrated
+// posts. Links between the parts of a split thread are not posts.
+func storedPosts(content *github.DiscussionContent) []storedPost {
+	posts := []storedPost{{ids: []string{content.ID}, bodies: []string{content.Body}}}
+	for j, comment := range content.Comments {
+		switch {
+		case strings.HasPrefix(comment, nextPartLinkPrefix):
+		case strings.HasPrefix(comment, strings.TrimSpace(continuationPrefix)):
+			last := &posts[len(posts)-1]
+			last.ids = append(last.ids, content.CommentIDs[j])
+			last.bodies = append(last.bodies, comment)
+		default:
+			posts = append(posts, storedPost{ids: []string{content.CommentIDs[j]}, bodies: []string{comment}, parent: content.ParentIDs[j]})
+		}
+	}
+	return posts
+}
+
+// fixupPost updates the parts of a migrated post that differ from its new
+// rendering, or its first part regardless when stale. A post whose rendering
+// splits into a different number of parts is kept. Returns the number of
+// updated parts.
+func (r *Runner) fixupPost(ctx context.Context, description string, stored storedPost, body string, stale, dryRun bool, update func(id, body string) error) (int, error) {
+	parts := splitBody(body, maxBodyLength)
+	if len(parts) != len(stored.bodies) {
+		logging.Warnf(ctx, "  ⚠ Not updating %s: it is continued in %d comments but would be in %d", description, len(stored.bodies)-1, len(parts)-1)
+		return 0, nil
+	}
+
+	updated := 0
+	for i, part := range parts {
+		if sameBody(part, stored.bodies[i]) && (i > 0 || !stale) {
+			continue
+		}
+
+		partDescription := description
+		if i > 0 {
+			partDescription = fmt.Sprintf("continuation %d of %s", i, description)
+		}
+		ok, err := r.updatePost(ctx, partDescription, dryRun, func() error {
+			return update(stored.ids[i], part)
+		})
+		if err != nil {
+			return updated, err
+		}
+		if ok {
+			updated++
+		}
+	}
+	return updated, nil
+}
+
+// updatePost runs the update of a post that differs from its new rendering
+// and reports whether the post was updated. Only an exhausted rate limit is
+// returned; other failures are logged.
+func (r *Runner) updatePost(ctx context.Context, description string, dryRun bool, update func() error) (bool, error) {
+	if dryRun {
+		logging.Infof(ctx, "  [DRY-RUN] Would update %s", description)
+		return false, nil
+	}
+
+	if err := update(); err != nil {
+		if errors.Is(err, github.ErrRateLimitExhausted) {
+			return false, err
+		}
+		logging.Errorf(ctx, "✗ Failed to update %s: %v", description, err)
+		return false, nil
+	}
+	logging.Infof(ctx, "  ✓ Updated %s", description)
+	return true, nil
+}
+
+// hostedAttachmentURLs returns the URLs the uploader hosts the thread's
+// attachments at, or nil without an uploader.
+func (r *Runner) hostedAttachmentURLs(threadID int, threadAttachments []xenforo.Attachment) map[int]string {
+	if r.uploader == nil {
+		return nil
+	}
+
+	hostedURLs := make(map[int]string, len(threadAttachments))
+	for _, attachment := range threadAttachments {
+		hostedURLs[attachment.AttachmentID] = r.uploader.HostedURL(threadID, attachment)
+	}
+	return hostedURLs
+}
+
+// sameBody reports whether a rendered body matches a body stored by GitHub,
+// which normalizes line endings and surrounding whitespace.
+func sameBody(rendered, stored string) bool {
+	return strings.TrimSpace(rendered) == strings.TrimSpace(strings.ReplaceAll(stored, "\r\n", "\n"))
+}
@@ -0,0 +1,60 @@
+package migration
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestRunner_Fixup(t *testing.T) {
+	forum := newTestForum(2)
+	forum.threads[0].ReplyCount = 2
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question [b]here[/b]"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "Answer"},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "See https://forum.example.com/threads/2/"},
+	}
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+		cfg.XenForo.WebURL = "https://forum.example.com"
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+	answer := api.comments[0].Body
+
+	// Fix a typo at the source and rename the discussion on GitHub
+	forum.posts[1][0].Message = "Question [b]fixed[/b]"
+	api.discussions[0].Title = "Renamed"
+
+	updated, err := runner.Fixup(context.Background(), []int{1}, true)
+	if err != nil || updated != 0 || api.discussions[0].Title != "Renamed" {
+		t.Fatalf("Expected a dry run to update nothing, got %d updates (err: %v)", updated, err)
+	}
+
+	updated, err = runner.Fixup(context.Background(), nil, false)
+	if err != nil {
+		t.Fatalf("Fixup returned error: %v", err)
+	}
+	if updated != 2 {
+		t.Errorf("Expected the discussion and the linking comment to be updated, got %d updates", updated)
+	}
+	if discussion := api.discussions[0]; discussion.Title != "Thread 1" || !strings.Contains(discussion.Body, "**fixed**") {
+		t.Errorf("Expected the discussion to be rendered again, got %q:\n%s", discussion.Title, discussion.Body)
+	}
+	if api.comments[0].Body != answer {
+		t.Errorf("Expected the unchanged comment to be kept, got:\n%s", api.comments[0].Body)
+	}
+	if !strings.Contains(api.comments[1].Body, "See https://github.com/test/repo/discussions/2") {
+		t.Errorf("Expected the thread link to be rewritten, got:\n%s", api.comments[1].Body)
+	}
+
+	updated, err = runner.Fixup(context.Background(), nil, false)
+	if err != nil || updated != 0 {
+		t.Errorf("Expected a second fixup to find nothing to update, got %d updates (err: %v)", updated, err)
+	}
+}
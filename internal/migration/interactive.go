@@ -3,13 +3,12 @@ package migration
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
 	"strconv"
 
 	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
-	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
 )
 
 // InteractiveRunner handles the interactive migration flow
@@ -32,6 +31,10 @@ func (r *InteractiveRunner) Run(cfg *config.Config) error {
 		if shouldContinue, err := r.handlePreMigrationSteps(cfg); err != nil {
 			return err
 		} else if !shouldContinue {
+			if r.nonInteractive {
+				fmt.Println("Migration cancelled.")
+				return nil
+			}
 			continue
 		}
 
@@ -47,7 +50,7 @@ func (r *InteractiveRunner) Run(cfg *config.Config) error {
 		}
 
 		if shouldContinue, err := r.handlePostMigrationSteps(cfg); err != nil {
-			log.Printf("Error selecting categories: %v", err)
+			logging.Infof(context.Background(), "Error selecting categories: %v", err)
 			break
 		} else if !shouldContinue {
 			break
@@ -62,13 +65,22 @@ func (r *InteractiveRunner) setProgressFile(cfg *config.Config) {
 }
 
 func (r *InteractiveRunner) handlePreMigrationSteps(cfg *config.Config) (bool, error) {
+	// The estimate is confirmed before any run that writes to GitHub, also
+	// when the remaining configuration comes from the environment
+	if cfg.Migration.ConfirmEstimate && !cfg.Migration.DryRun {
+		if err := r.runDryRun(cfg); err != nil {
+			return false, fmt.Errorf("estimate failed: %w", err)
+		}
+		return config.PromptBool("Start the migration now?", false), nil
+	}
+
 	if r.nonInteractive || cfg.Migration.DryRun {
 		return true, nil
 	}
 
 	if config.PromptBool("Would you like to do a dry run first? (recommended)", true) {
 		if err := r.runDryRun(cfg); err != nil {
-			log.Printf("Dry run failed: %v", err)
+			logging.Infof(context.Background(), "Dry run failed: %v", err)
 			return false, nil
 		}
 	}
@@ -121,7 +133,7 @@ func (r *InteractiveRunner) handleMigrationError(err error, cfg *config.Config)
 		fmt.Println("Skipping current thread...")
 
 		// Get current progress to find last processed thread
-		tracker, err := progress.NewTracker(cfg.Migration.ProgressFile, false)
+		tracker, err := openTracker(cfg, false)
 		if err != nil {
 			fmt.Printf("Warning: Could not load progress file: %v\n", err)
 			return
@@ -143,7 +155,7 @@ func (r *InteractiveRunner) handleMigrationError(err error, cfg *config.Config)
 
 // getLastProcessedID reads the progress file to get the last processed thread ID
 func (r *InteractiveRunner) getLastProcessedID(cfg *config.Config) int {
-	tracker, err := progress.NewTracker(cfg.Migration.ProgressFile, true) // dryRun=true just for reading
+	tracker, err := openTracker(cfg, true) // dryRun=true just for reading
 	if err != nil {
 		return 0
 	}
@@ -152,13 +164,23 @@ func (r *InteractiveRunner) getLastProcessedID(cfg *config.Config) int {
 	return progressData.LastThreadID
 }
 
+// openTracker loads the progress of the configured backend.
+func openTracker(cfg *config.Config, dryRun bool) (*progress.Tracker, error) {
+	persist, err := progress.OpenPersistence(cfg.Migration.ProgressFile, cfg.Migration.ProgressBackend, cfg.Migration.ProgressBucketSize)
+	if err != nil {
+		return nil, err
+	}
+	return progress.NewTrackerWithPersistence(persist, dryRun)
+}
+
 // selectNewCategories prompts the user to select new source and target categories
 func (r *InteractiveRunner) selectNewCategories(cfg *config.Config) error {
 	fmt.Println("\n=== Select Next Migration ===")
+	ctx := context.Background()
 
 	// Fetch XenForo categories
 	fmt.Print("\nFetching XenForo categories... ")
-	categories, err := config.ValidateXenForoAuth(cfg.XenForo.APIURL, cfg.XenForo.APIKey, cfg.XenForo.APIUser)
+	categories, err := config.ValidateXenForoAuth(ctx, cfg.XenForo.APIURL, cfg.XenForo.APIKey, cfg.XenForo.APIUser)
 	if err != nil {
 		return fmt.Errorf("failed to fetch XenForo categories: %w", err)
 	}
@@ -184,8 +206,11 @@ func (r *InteractiveRunner) selectNewCategories(cfg *config.Config) error {
 
 	// Fetch GitHub categories
 	fmt.Print("\nFetching GitHub Discussion categories... ")
-	ctx := context.Background()
-	ghCategories, err := config.ValidateGitHubAuth(ctx, cfg.GitHub.Token, cfg.GitHub.Repository)
+	auth, err := cfg.GitHubAuth()
+	if err != nil {
+		return err
+	}
+	ghCategories, err := config.ValidateGitHubAuth(ctx, auth, cfg.GitHub.Repository)
 	if err != nil {
 		return fmt.Errorf("failed to fetch GitHub categories: %w", err)
 	}
@@ -204,25 +229,23 @@ func (r *InteractiveRunner) selectNewCategories(cfg *config.Config) error {
 // runDryRun performs a dry run of the migration
 func (r *InteractiveRunner) runDryRun(cfg *config.Config) error {
 	fmt.Println("\nRunning dry run...")
+	ctx := context.Background()
 
-	// Create XenForo client
-	client := xenforo.NewClient(cfg.XenForo.APIURL, cfg.XenForo.APIKey, cfg.XenForo.APIUser, cfg.Migration.MaxRetries)
+	// Create the XenForo API client or database source
+	source, closeSource, err := NewForumSource(cfg)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeSource() }()
 
-	// Get statistics from XenForo API
-	threadCount, postCount, attachmentCount, userCount, err := client.GetDryRunStats(cfg.GitHub.XenForoNodeID)
+	// Get statistics from XenForo
+	estimate, err := EstimateMigration(ctx, source, cfg)
 	if err != nil {
 		return fmt.Errorf("failed to get dry run statistics: %w", err)
 	}
 
 	fmt.Println("\nDry run complete. Migration summary:")
-	fmt.Println("┌─────────────┬────────┐")
-	fmt.Println("│ Content     │ Count  │")
-	fmt.Println("├─────────────┼────────┤")
-	fmt.Printf("│ Threads     │ %6d │\n", threadCount)
-	fmt.Printf("│ Posts       │ %6d │\n", postCount)
-	fmt.Printf("│ Attachments │ %6d │\n", attachmentCount)
-	fmt.Printf("│ Users       │ %6d │\n", userCount)
-	fmt.Println("└─────────────┴────────┘")
+	estimate.Print(os.Stdout)
 
 	return nil
 }
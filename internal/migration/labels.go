@@ -0,0 +1,70 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// labelDiscussion adds the label mapped to the thread's prefix to a created
+// discussion. Failures are logged, since the discussion itself is already
+// migrated and its opening post not yet checkpointed.
+func (r *Runner) labelDiscussion(ctx context.Context, thread xenforo.Thread, discussionID string) {
+	label, ok := prefixMapping(thread, r.config.GitHub.PrefixLabelMap)
+	if !ok {
+		return
+	}
+
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would add label %q", label)
+		return
+	}
+
+	if discussionID == "" {
+		return
+	}
+
+	labelID, err := r.labelID(ctx, label)
+	if err == nil {
+		err = r.githubClient.AddLabels(ctx, discussionID, labelID)
+	}
+	if err != nil {
+		logging.Warnf(ctx, "  ⚠ Failed to add label %q: %v", label, err)
+		return
+	}
+	logging.Infof(ctx, "  ✓ Added label %q", label)
+}
+
+// labelID returns the ID of a repository label, creating missing labels.
+// IDs are cached for the rest of the run.
+func (r *Runner) labelID(ctx context.Context, name string) (string, error) {
+	r.labelsMu.Lock()
+	defer r.labelsMu.Unlock()
+
+	if id, ok := r.labelIDs[name]; ok {
+		return id, nil
+	}
+
+	id, err := r.githubClient.LabelID(ctx, r.config.GitHub.Repository, name)
+	if err != nil {
+		return "", err
+	}
+	if id == "" {
+		if id, err = r.githubClient.CreateLabel(ctx, name, github.DefaultLabelColor); err != nil {
+			return "", err
+		}
+		logging.Infof(ctx, "  ✓ Created label %q", name)
+	}
+	if id == "" {
+		return "", fmt.Errorf("no ID returned for label %q", name)
+	}
+
+	if r.labelIDs == nil {
+		r.labelIDs = make(map[string]string)
+	}
+	r.labelIDs[name] = id
+	return id, nil
+}
@@ -1,6 +1,7 @@
 package migration
 
 import (
+	"context"
 	"errors"
 	"testing"
 
@@ -192,7 +193,7 @@ type mockDownloader struct {
 	downloadCalls [][]xenforo.Attachment
 }
 
-func (m *mockDownloader) DownloadAttachments(attachments []xenforo.Attachment) error {
+func (m *mockDownloader) DownloadAttachments(ctx context.Context, attachments []xenforo.Attachment) error {
 	m.downloadCalls = append(m.downloadCalls, attachments)
 	return m.downloadError
 }
@@ -212,7 +213,7 @@ type testMigratorWithDownloader struct {
 func (tm *testMigratorWithDownloader) processThreadWithDownloads(t *testing.T, thread xenforo.Thread) error {
 	// Simulate the download logic from runner.go
 	if len(tm.attachments) > 0 {
-		if err := tm.downloader.DownloadAttachments(tm.attachments); err != nil {
+		if err := tm.downloader.DownloadAttachments(context.Background(), tm.attachments); err != nil {
 			t.Logf("Failed to download attachments: %v", err)
 		}
 	}
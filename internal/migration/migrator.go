@@ -5,13 +5,20 @@ package migration
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"text/template"
+	"time"
 
 	"github.com/exileum/xenforo-to-gh-discussions/internal/attachments"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/concurrency"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/notify"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
-	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/retry"
 )
 
 // Migrator orchestrates the complete migration process from XenForo to GitHub Discussions.
@@ -39,29 +46,26 @@ func (m *Migrator) Run(ctx context.Context) error {
 	}
 
 	// Initialize clients
-	xenforoClient := xenforo.NewClient(
-		m.config.XenForo.APIURL,
-		m.config.XenForo.APIKey,
-		m.config.XenForo.APIUser,
-		m.config.Migration.MaxRetries,
-	)
+	xenforoSource, closeSource, err := NewForumSource(m.config)
+	if err != nil {
+		return fmt.Errorf("failed to initialize forum source: %w", err)
+	}
+	defer func() { _ = closeSource() }()
 
 	var githubClient *github.Client
 	if !m.config.Migration.DryRun {
-		var err error
-		githubClient, err = github.NewClient(
-			m.config.GitHub.Token,
-			m.config.GitHub.RateLimitDelay,
-			m.config.GitHub.MaxRetries,
-			m.config.GitHub.RetryBackoffMultiple,
-		)
+		githubClient, err = m.config.NewGitHubClient()
 		if err != nil {
 			return fmt.Errorf("failed to initialize GitHub client: %w", err)
 		}
 	}
 
 	// Initialize progress tracker
-	tracker, err := progress.NewTracker(m.config.Migration.ProgressFile, m.config.Migration.DryRun)
+	persist, err := progress.OpenPersistence(m.config.Migration.ProgressFile, m.config.Migration.ProgressBackend, m.config.Migration.ProgressBucketSize)
+	if err != nil {
+		return fmt.Errorf("failed to open progress: %w", err)
+	}
+	tracker, err := progress.NewTrackerWithPersistence(persist, m.config.Migration.DryRun)
 	if err != nil {
 		return fmt.Errorf("failed to initialize progress tracker: %w", err)
 	}
@@ -71,21 +75,316 @@ func (m *Migrator) Run(ctx context.Context) error {
 		tracker.SetResumeFrom(m.config.Migration.ResumeFrom)
 	}
 
-	// Initialize attachment downloader
-	downloader := attachments.NewDownloader(
-		m.config.Filesystem.AttachmentsDir,
-		m.config.Migration.DryRun,
-		xenforoClient,
-		m.config.Filesystem.AttachmentRateLimitDelay,
-	)
+	// Thread workers and attachment workers share one global limit
+	limiter := concurrency.NewSemaphore(m.config.Migration.MaxConcurrency)
 
 	// Run pre-flight checks
-	checker := NewPreflightChecker(m.config, xenforoClient, githubClient)
+	state := tracker.GetProgress()
+	resuming := len(state.CompletedThreads) > 0 || len(state.Checkpoints) > 0 || m.config.Migration.ResumeFrom > 0
+	checker := NewPreflightChecker(m.config, xenforoSource, githubClient).SetResuming(resuming)
 	if err := checker.RunChecks(ctx); err != nil {
 		return fmt.Errorf("pre-flight checks failed: %w", err)
 	}
 
 	// Run migration
-	runner := NewRunner(m.config, xenforoClient, githubClient, tracker, downloader)
-	return runner.RunMigration(ctx)
+	runner, err := m.newRunner(ctx, xenforoSource, githubClient, tracker, limiter)
+	if err != nil {
+		return err
+	}
+
+	// Report the run summary to a webhook when configured
+	if m.config.Migration.WebhookURL != "" {
+		runner.SetNotifier(notify.NewWebhookNotifier(
+			m.config.Migration.WebhookURL,
+			m.config.GitHub.Token,
+			m.config.XenForo.APIKey,
+		))
+	}
+
+	// Write the rendered threads for a later upload in export-only runs
+	var exporter *ThreadExporter
+	if m.config.Migration.ExportOnly != "" {
+		exporter, err = NewThreadExporter(m.config.Migration.ExportOnly)
+		if err != nil {
+			return err
+		}
+		defer func() { _ = exporter.Close() }()
+		runner.SetExporter(exporter)
+	}
+
+	if err := runner.RunMigration(ctx); err != nil {
+		return err
+	}
+	if exporter != nil {
+		logging.Infof(ctx, "✓ Exported %d threads to %s", exporter.Count(), exporter.Path())
+	}
+	return nil
+}
+
+// Fixup renders the threads recorded in the progress file again and patches
+// their discussions where the rendering changed, see Runner.Fixup. Returns
+// the number of updated posts.
+func (m *Migrator) Fixup(ctx context.Context, threadIDs []int, dryRun bool) (int, error) {
+	if err := m.config.Validate(); err != nil {
+		return 0, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	source, closeSource, err := NewForumSource(m.config)
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize forum source: %w", err)
+	}
+	defer func() { _ = closeSource() }()
+
+	githubClient, err := m.config.NewGitHubClient()
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize GitHub client: %w", err)
+	}
+	info, err := githubClient.GetRepositoryInfo(ctx, m.config.GitHub.Repository)
+	if err != nil {
+		return 0, fmt.Errorf("GitHub API check failed: %w", err)
+	}
+	NewPreflightChecker(m.config, source, githubClient).normalizeRepository(info.NameWithOwner)
+
+	// The progress file is only read
+	persist, err := progress.OpenPersistence(m.config.Migration.ProgressFile, m.config.Migration.ProgressBackend, m.config.Migration.ProgressBucketSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open progress: %w", err)
+	}
+	tracker, err := progress.NewTrackerWithPersistence(persist, true)
+	if err != nil {
+		return 0, fmt.Errorf("failed to initialize progress tracker: %w", err)
+	}
+
+	runner, err := m.newRunner(ctx, source, githubClient, tracker, concurrency.NewSemaphore(m.config.Migration.MaxConcurrency))
+	if err != nil {
+		return 0, err
+	}
+	return runner.Fixup(ctx, threadIDs, dryRun)
+}
+
+// Sync mirrors new threads and posts to GitHub, see Runner.Sync. With a
+// SyncInterval it keeps running a pass every interval until ctx is
+// cancelled; an exhausted rate limit or a failed listing is logged and
+// retried at the next pass. Without one it runs a single pass.
+func (m *Migrator) Sync(ctx context.Context) error {
+	runner, closeSource, err := m.mirrorRunner(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeSource() }()
+
+	interval := m.config.Migration.SyncInterval
+	for {
+		logging.Infof(ctx, "Syncing forum node %d...", m.config.GitHub.XenForoNodeID)
+		appended, err := runner.Sync(ctx)
+		if interval == 0 {
+			if err != nil {
+				return fmt.Errorf("sync stopped after %d appended posts: %w", appended, err)
+			}
+			logging.Infof(ctx, "✓ Appended %d new posts", appended)
+			return nil
+		}
+
+		if err != nil {
+			logging.Errorf(ctx, "✗ Sync pass failed after %d appended posts: %v", appended, err)
+		} else {
+			logging.Infof(ctx, "✓ Appended %d new posts, next sync in %s", appended, interval)
+		}
+		if err := retry.Wait(ctx, interval); err != nil {
+			logging.Infof(ctx, "Sync stopped")
+			return nil
+		}
+	}
+}
+
+// Serve listens on ServeAddr for XenForo webhooks and mirrors the threads and
+// posts they announce until ctx is cancelled, see WebhookServer. Webhooks
+// need the XenForo API source, which looks up the threads of events, and a
+// webhook secret unless insecure accepts unauthenticated webhooks.
+func (m *Migrator) Serve(ctx context.Context, insecure bool) error {
+	if m.config.XenForo.WebhookSecret == "" {
+		if !insecure {
+			return errors.New("XENFORO_WEBHOOK_SECRET is not set: set it to the secret of the XenForo webhooks, or pass --insecure to accept webhooks from anyone")
+		}
+		logging.Warnf(ctx, "⚠ XENFORO_WEBHOOK_SECRET is not set: accepting webhooks from anyone who can reach %s", m.config.Migration.ServeAddr)
+	}
+
+	runner, closeSource, err := m.mirrorRunner(ctx)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = closeSource() }()
+
+	threads, ok := runner.xenforoSource.(ConversionSource)
+	if !ok {
+		return fmt.Errorf("webhooks need the XenForo API source, not %s", sourceName(m.config))
+	}
+
+	webhooks := NewWebhookServer(runner, threads, m.config.XenForo.WebhookSecret)
+	server := &http.Server{
+		Addr:              m.config.Migration.ServeAddr,
+		Handler:           webhooks.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+	logging.Infof(ctx, "✓ Listening for XenForo webhooks on %s%s", m.config.Migration.ServeAddr, WebhookPath)
+
+	done := make(chan struct{})
+	go func() {
+		webhooks.Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return fmt.Errorf("webhook server failed: %w", err)
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to shut down the webhook server: %v", err)
+	}
+	<-done
+	logging.Infof(ctx, "Webhook server stopped")
+	return nil
+}
+
+// mirrorRunner sets up the runner of the sync and serve commands, which
+// write to discussions recorded by earlier runs. Cached XenForo listings are
+// always revalidated, since one within its TTL would hide new posts. The
+// returned close function releases the forum source.
+func (m *Migrator) mirrorRunner(ctx context.Context) (*Runner, func() error, error) {
+	if err := m.config.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+	m.config.XenForo.CacheTTL = 0
+
+	source, closeSource, err := NewForumSource(m.config)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to initialize forum source: %w", err)
+	}
+	runner, err := m.newMirrorRunner(ctx, source)
+	if err != nil {
+		_ = closeSource()
+		return nil, nil, err
+	}
+	return runner, closeSource, nil
+}
+
+// newMirrorRunner creates the GitHub client, progress tracker and runner of
+// mirrorRunner once the pre-flight checks pass.
+func (m *Migrator) newMirrorRunner(ctx context.Context, source ForumSource) (*Runner, error) {
+	var githubClient *github.Client
+	if !m.config.Migration.DryRun {
+		var err error
+		githubClient, err = m.config.NewGitHubClient()
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize GitHub client: %w", err)
+		}
+	}
+
+	persist, err := progress.OpenPersistence(m.config.Migration.ProgressFile, m.config.Migration.ProgressBackend, m.config.Migration.ProgressBucketSize)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open progress: %w", err)
+	}
+	tracker, err := progress.NewTrackerWithPersistence(persist, m.config.Migration.DryRun)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize progress tracker: %w", err)
+	}
+
+	checker := NewPreflightChecker(m.config, source, githubClient).SetResuming(true)
+	if err := checker.RunChecks(ctx); err != nil {
+		return nil, fmt.Errorf("pre-flight checks failed: %w", err)
+	}
+
+	return m.newRunner(ctx, source, githubClient, tracker, concurrency.NewSemaphore(m.config.Migration.MaxConcurrency))
+}
+
+// newRunner creates a runner rendering and writing posts with the configured
+// attachment downloads, uploads and author avatars.
+func (m *Migrator) newRunner(ctx context.Context, source ForumSource, githubClient *github.Client, tracker *progress.Tracker, limiter *concurrency.Semaphore) (*Runner, error) {
+	// Initialize attachment downloader
+	// Export-only runs download attachments but write nothing to GitHub
+	downloader := attachments.NewDownloader(
+		m.config.Filesystem.AttachmentsDir,
+		m.config.Migration.DryRun && m.config.Migration.ExportOnly == "",
+		source,
+		m.config.Filesystem.AttachmentRateLimitDelay,
+	).SetConcurrency(m.config.Migration.AttachmentWorkers, limiter).
+		SetForumBaseURL(m.config.XenForo.WebURL).
+		SetPolicy(m.config.Filesystem.MaxAttachmentSize, m.config.Filesystem.AllowedExtensions, m.config.Filesystem.BlockedExtensions)
+	filenameTemplate, err := parseFilenameTemplate(m.config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure attachment downloader: %w", err)
+	}
+	downloader.SetFilenameTemplate(filenameTemplate)
+
+	runner := NewRunner(m.config, source, githubClient, tracker, downloader).SetLimiter(limiter)
+
+	// Upload attachments to GitHub unless they stay local
+	var uploader *attachments.Uploader
+	if githubClient != nil {
+		if uploader, err = NewAttachmentUploader(ctx, m.config, githubClient); err != nil {
+			return nil, err
+		}
+		if uploader != nil {
+			runner.SetUploader(uploader)
+		}
+	}
+
+	// Show author avatars in post headers when enabled
+	if m.config.Migration.AuthorAvatars {
+		avatars := attachments.NewAvatarCache(m.config.Filesystem.AttachmentsDir, m.config.Migration.DryRun, source).
+			SetMaxSize(m.config.Filesystem.MaxAttachmentSize)
+		if uploader != nil {
+			avatars.SetUploader(uploader)
+		}
+		runner.SetAvatars(avatars)
+	}
+
+	return runner, nil
+}
+
+// parseFilenameTemplate parses the template naming attachments on disk and
+// in the upload repository, which is nil for the default naming.
+func parseFilenameTemplate(cfg *config.Config) (*template.Template, error) {
+	if cfg.Filesystem.AttachmentFilename == "" {
+		return nil, nil
+	}
+	return attachments.ParseFilenameTemplate(cfg.Filesystem.AttachmentFilename)
+}
+
+// NewAttachmentUploader returns the uploader storing attachments at the
+// configured target, or nil when attachments stay local. The repo-path target
+// commits to the default branch of the upload repository, the lfs and
+// assets-branch targets to the upload branch.
+func NewAttachmentUploader(ctx context.Context, cfg *config.Config, client *github.Client) (*attachments.Uploader, error) {
+	target, repo := cfg.AttachmentHosting()
+	if target == config.AttachmentTargetLocal {
+		return nil, nil
+	}
+
+	filenameTemplate, err := parseFilenameTemplate(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to configure attachment uploads: %w", err)
+	}
+
+	branch := cfg.Filesystem.AttachmentUploadBranch
+	if target == config.AttachmentTargetRepoPath {
+		if branch, err = client.DefaultBranch(ctx, repo); err != nil {
+			return nil, fmt.Errorf("failed to configure attachment uploads: %w", err)
+		}
+	}
+
+	uploader := attachments.NewUploader(client, repo, branch, cfg.Filesystem.AttachmentUploadPath, cfg.Filesystem.BatchAttachmentUploads).
+		SetImageProcessing(cfg.Filesystem.ImageMaxWidth, cfg.Filesystem.ImageQuality).
+		SetFilenameTemplate(filenameTemplate)
+	if target == config.AttachmentTargetLFS {
+		uploader.SetLFS(client)
+	}
+	return uploader, nil
 }
@@ -258,3 +258,11 @@ func TestMigrator_RunDryRunMode(t *testing.T) {
 		t.Errorf("Dry run mode should pass configuration validation: %v", err)
 	}
 }
+
+func TestMigrator_ServeRequiresSecret(t *testing.T) {
+	// The secret is checked before the configuration or any connection
+	err := NewMigrator(&config.Config{}).Serve(context.Background(), false)
+	if err == nil || !strings.Contains(err.Error(), "XENFORO_WEBHOOK_SECRET") {
+		t.Errorf("Expected serve to refuse starting without a webhook secret, got %v", err)
+	}
+}
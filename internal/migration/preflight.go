@@ -3,11 +3,12 @@ package migration
 import (
 	"context"
 	"fmt"
-	"log"
 	"os"
+	"strings"
 
 	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
 )
 
@@ -20,7 +21,7 @@ func (v *runtimeCategoryValidator) ValidateSingleCategory(nodeID int, categoryID
 	if !v.validCategories[categoryID] {
 		return fmt.Errorf("invalid GitHub category ID '%s'", categoryID)
 	}
-	log.Printf("  ✓ Single category mapping validated: node %d -> %s", nodeID, categoryID)
+	logging.Infof(context.Background(), "  ✓ Single category mapping validated: node %d -> %s", nodeID, categoryID)
 	return nil
 }
 
@@ -30,7 +31,7 @@ func (v *runtimeCategoryValidator) ValidateMultiCategory(categories map[int]stri
 			return fmt.Errorf("invalid category ID '%s' for node %d", categoryID, nodeID)
 		}
 	}
-	log.Println("  ✓ All legacy category mappings are valid")
+	logging.Infof(context.Background(), "  ✓ All legacy category mappings are valid")
 	return nil
 }
 
@@ -41,26 +42,34 @@ func (v *runtimeCategoryValidator) ValidateNoConfiguration() error {
 
 type PreflightChecker struct {
 	config        *config.Config
-	xenforoClient *xenforo.Client
+	xenforoSource ForumSource
 	githubClient  *github.Client
+	resuming      bool // Earlier runs already migrated threads into the target categories
 }
 
-func NewPreflightChecker(cfg *config.Config, xenforoClient *xenforo.Client, githubClient *github.Client) *PreflightChecker {
+func NewPreflightChecker(cfg *config.Config, source ForumSource, githubClient *github.Client) *PreflightChecker {
 	return &PreflightChecker{
 		config:        cfg,
-		xenforoClient: xenforoClient,
+		xenforoSource: source,
 		githubClient:  githubClient,
 	}
 }
 
+// SetResuming marks the run as continuing an earlier migration, which skips
+// the empty category guard since the categories hold its discussions.
+func (p *PreflightChecker) SetResuming(resuming bool) *PreflightChecker {
+	p.resuming = resuming
+	return p
+}
+
 func (p *PreflightChecker) RunChecks(ctx context.Context) error {
-	log.Println("Running pre-flight checks...")
+	logging.Infof(ctx, "Running pre-flight checks...")
 
 	if p.config.Migration.DryRun {
-		log.Println("  Running in DRY-RUN mode - no actual changes will be made")
+		logging.Infof(ctx, "  Running in DRY-RUN mode - no actual changes will be made")
 	}
 
-	if err := p.checkXenForoAPI(); err != nil {
+	if err := p.checkXenForoAPI(ctx); err != nil {
 		return err
 	}
 
@@ -72,15 +81,28 @@ func (p *PreflightChecker) RunChecks(ctx context.Context) error {
 		return err
 	}
 
-	log.Println("✓ All pre-flight checks passed")
+	logging.Infof(ctx, "✓ All pre-flight checks passed")
 	return nil
 }
 
-func (p *PreflightChecker) checkXenForoAPI() error {
-	if err := p.xenforoClient.TestConnection(); err != nil {
-		return fmt.Errorf("XenForo API check failed: %w", err)
+func (p *PreflightChecker) checkXenForoAPI(ctx context.Context) error {
+	name := sourceName(p.config)
+	if err := p.xenforoSource.TestConnection(ctx); err != nil {
+		return fmt.Errorf("%s check failed: %w", name, err)
+	}
+
+	versioned, ok := p.xenforoSource.(interface{ Version() xenforo.Version })
+	if !ok {
+		logging.Infof(context.Background(), "  ✓ %s access verified", name)
+		return nil
+	}
+
+	version := versioned.Version()
+	logging.Infof(context.Background(), "  ✓ %s access verified (XenForo %s)", name, version)
+	if !version.AtLeast(2, 2) && p.config.Migration.MarkSolutions {
+		// Question threads and their solutions arrived with thread types in 2.2
+		logging.Warnf(context.Background(), "  ⚠ XenForo %s has no question threads; no solutions will be marked as answers", version)
 	}
-	log.Println("  ✓ XenForo API access verified")
 	return nil
 }
 
@@ -98,6 +120,8 @@ func (p *PreflightChecker) checkGitHubAPI(ctx context.Context) error {
 		return fmt.Errorf("GitHub Discussions is not enabled for repository %s", p.config.GitHub.Repository)
 	}
 
+	p.normalizeRepository(info.NameWithOwner)
+
 	// Validate category configuration
 	validCategories := make(map[string]bool)
 	for _, cat := range info.DiscussionCategories {
@@ -110,25 +134,111 @@ func (p *PreflightChecker) checkGitHubAPI(ctx context.Context) error {
 		return err
 	}
 
-	log.Println("  ✓ GitHub API access verified")
-	log.Println("  ✓ GitHub Discussions is enabled")
+	if err := p.checkPrefixCategories(validCategories); err != nil {
+		return err
+	}
+
+	if err := p.checkCategoriesEmpty(ctx); err != nil {
+		return err
+	}
+
+	logging.Infof(ctx, "  ✓ GitHub API access verified")
+	logging.Infof(ctx, "  ✓ GitHub Discussions is enabled")
+
+	return nil
+}
+
+// checkPrefixCategories verifies that every category in the thread prefix
+// mapping exists in the repository.
+func (p *PreflightChecker) checkPrefixCategories(validCategories map[string]bool) error {
+	if len(p.config.GitHub.PrefixCategoryMap) == 0 {
+		return nil
+	}
+
+	for prefix, categoryID := range p.config.GitHub.PrefixCategoryMap {
+		if !validCategories[categoryID] {
+			return fmt.Errorf("invalid category ID '%s' for thread prefix %q", categoryID, prefix)
+		}
+	}
+	logging.Infof(context.Background(), "  ✓ %d thread prefix category mappings validated", len(p.config.GitHub.PrefixCategoryMap))
+	return nil
+}
+
+// checkCategoriesEmpty guards against migrating into categories that already
+// hold discussions when RequireEmptyCategory is enabled. With
+// AllowNonEmptyCategory the check only warns.
+func (p *PreflightChecker) checkCategoriesEmpty(ctx context.Context) error {
+	if !p.config.Migration.RequireEmptyCategory {
+		return nil
+	}
+	if p.resuming {
+		logging.Infof(ctx, "  ✓ Resuming an earlier migration, skipping the empty category check")
+		return nil
+	}
+
+	categories := []string{p.config.GitHub.GitHubCategoryID}
+	for _, categoryID := range p.config.GitHub.PrefixCategoryMap {
+		categories = append(categories, categoryID)
+	}
+
+	checked := make(map[string]bool)
+	for _, categoryID := range categories {
+		if categoryID == "" || checked[categoryID] {
+			continue
+		}
+		checked[categoryID] = true
+
+		count, err := p.githubClient.CountCategoryDiscussions(ctx, p.config.GitHub.Repository, categoryID)
+		if err != nil {
+			return fmt.Errorf("failed to check target category: %w", err)
+		}
+		if count == 0 {
+			continue
+		}
+
+		if !p.config.Migration.AllowNonEmptyCategory {
+			return fmt.Errorf("target category %s already contains %d discussions; use --allow-nonempty to migrate into it anyway", categoryID, count)
+		}
+		logging.Warnf(ctx, "  ⚠ Target category %s already contains %d discussions", categoryID, count)
+	}
 
+	logging.Infof(ctx, "  ✓ Target categories checked for existing discussions")
 	return nil
 }
 
+// normalizeRepository replaces the configured repository with the canonical
+// casing reported by GitHub so URLs and run bookkeeping use a single form.
+func (p *PreflightChecker) normalizeRepository(canonical string) {
+	configured := p.config.GitHub.Repository
+	if canonical == "" || canonical == configured || !strings.EqualFold(canonical, configured) {
+		return
+	}
+
+	logging.Warnf(context.Background(), "  ⚠ Repository %s is spelled %s on GitHub, using the canonical form", configured, canonical)
+	p.config.GitHub.Repository = canonical
+
+	if strings.EqualFold(p.config.Filesystem.AttachmentUploadRepo, configured) {
+		p.config.Filesystem.AttachmentUploadRepo = canonical
+	}
+}
+
 func (p *PreflightChecker) checkFileSystem() error {
+	if _, err := parseFilenameTemplate(p.config); err != nil {
+		return fmt.Errorf("attachment filename check failed: %w", err)
+	}
+
 	if p.config.Migration.DryRun {
 		// In dry-run mode, just check if the path is valid without creating the directory
 		if p.config.Filesystem.AttachmentsDir == "" {
 			return fmt.Errorf("attachments directory path is empty")
 		}
-		log.Println("  ✓ Attachments directory path validated (dry-run)")
+		logging.Infof(context.Background(), "  ✓ Attachments directory path validated (dry-run)")
 		return nil
 	}
 
 	if err := os.MkdirAll(p.config.Filesystem.AttachmentsDir, 0755); err != nil {
 		return fmt.Errorf("failed to create attachments directory: %w", err)
 	}
-	log.Println("  ✓ Attachments directory ready")
+	logging.Infof(context.Background(), "  ✓ Attachments directory ready")
 	return nil
 }
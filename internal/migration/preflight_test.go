@@ -0,0 +1,199 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+)
+
+// newRepositoryInfoServer serves a GraphQL repository query reporting the given canonical name.
+func newRepositoryInfoServer(t *testing.T, nameWithOwner string) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"data":{"repository":{
+			"id":"R_test",
+			"nameWithOwner":"` + nameWithOwner + `",
+			"hasDiscussionsEnabled":true,
+			"discussionCategories":{"nodes":[{"id":"DIC_kwDOtest123","name":"General"}]}
+		}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPreflight_NormalizesRepositoryCasing(t *testing.T) {
+	tests := []struct {
+		name       string
+		configured string
+		canonical  string
+		expected   string
+	}{
+		{name: "Mis-cased repository is normalized", configured: "exileum/XenForo-Migration", canonical: "Exileum/xenforo-migration", expected: "Exileum/xenforo-migration"},
+		{name: "Matching repository is unchanged", configured: "exileum/repo", canonical: "exileum/repo", expected: "exileum/repo"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newRepositoryInfoServer(t, tt.canonical)
+
+			client, err := github.NewClient(github.TokenAuth("test_github_token_for_testing_only"), 1*time.Millisecond, 1, 1)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			client.SetGraphQLURL(server.URL)
+
+			cfg := &config.Config{
+				GitHub: config.GitHubConfig{
+					Repository:       tt.configured,
+					XenForoNodeID:    1,
+					GitHubCategoryID: "DIC_kwDOtest123",
+				},
+				Filesystem: config.FilesystemConfig{AttachmentUploadRepo: tt.configured},
+			}
+
+			checker := NewPreflightChecker(cfg, nil, client)
+			if err := checker.checkGitHubAPI(context.Background()); err != nil {
+				t.Fatalf("checkGitHubAPI returned error: %v", err)
+			}
+
+			if cfg.GitHub.Repository != tt.expected {
+				t.Errorf("Expected repository %q, got %q", tt.expected, cfg.GitHub.Repository)
+			}
+			if cfg.Filesystem.AttachmentUploadRepo != tt.expected {
+				t.Errorf("Expected upload repository %q, got %q", tt.expected, cfg.Filesystem.AttachmentUploadRepo)
+			}
+			if client.GetRepositoryName() != tt.expected {
+				t.Errorf("Expected client repository name %q, got %q", tt.expected, client.GetRepositoryName())
+			}
+		})
+	}
+}
+
+func TestPreflight_ValidatesPrefixCategories(t *testing.T) {
+	server := newRepositoryInfoServer(t, "test/repo")
+
+	client, err := github.NewClient(github.TokenAuth("test_github_token_for_testing_only"), 1*time.Millisecond, 1, 1)
+	if err != nil {
+		t.Fatalf("Failed to create client: %v", err)
+	}
+	client.SetGraphQLURL(server.URL)
+
+	tests := []struct {
+		name      string
+		mapping   map[string]string
+		expectErr bool
+	}{
+		{name: "Known category", mapping: map[string]string{"Bug": "DIC_kwDOtest123"}},
+		{name: "Unknown category", mapping: map[string]string{"Bug": "DIC_missing"}, expectErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{
+				GitHub: config.GitHubConfig{
+					Repository:        "test/repo",
+					XenForoNodeID:     1,
+					GitHubCategoryID:  "DIC_kwDOtest123",
+					PrefixCategoryMap: tt.mapping,
+				},
+			}
+
+			err := NewPreflightChecker(cfg, nil, client).checkGitHubAPI(context.Background())
+			if tt.expectErr && err == nil {
+				t.Error("Expected an error for an unknown prefix category")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+// newCategoryCountServer answers repository and discussion count queries with
+// the given number of discussions per category.
+func newCategoryCountServer(t *testing.T, counts map[string]int) *httptest.Server {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Query     string                 `json:"query"`
+			Variables map[string]interface{} `json:"variables"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+
+		if strings.Contains(req.Query, "totalCount") {
+			categoryID, _ := req.Variables["categoryId"].(string)
+			_, _ = fmt.Fprintf(w, `{"data":{"repository":{"discussions":{"totalCount":%d}}}}`, counts[categoryID])
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"repository":{
+			"id":"R_test",
+			"nameWithOwner":"test/repo",
+			"hasDiscussionsEnabled":true,
+			"discussionCategories":{"nodes":[{"id":"DIC_kwDOtest123","name":"General"},{"id":"DIC_bugs","name":"Bugs"}]}
+		}}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestPreflight_EmptyCategoryGuard(t *testing.T) {
+	tests := []struct {
+		name          string
+		counts        map[string]int
+		prefixes      map[string]string
+		allowNonEmpty bool
+		resuming      bool
+		expectErr     bool
+	}{
+		{name: "Empty category", counts: map[string]int{}},
+		{name: "Non-empty category", counts: map[string]int{"DIC_kwDOtest123": 3}, expectErr: true},
+		{name: "Non-empty prefix category", counts: map[string]int{"DIC_bugs": 1}, prefixes: map[string]string{"Bug": "DIC_bugs"}, expectErr: true},
+		{name: "Non-empty category allowed", counts: map[string]int{"DIC_kwDOtest123": 3}, allowNonEmpty: true},
+		{name: "Resumed migration", counts: map[string]int{"DIC_kwDOtest123": 3}, resuming: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newCategoryCountServer(t, tt.counts)
+
+			client, err := github.NewClient(github.TokenAuth("test_github_token_for_testing_only"), 1*time.Millisecond, 1, 1)
+			if err != nil {
+				t.Fatalf("Failed to create client: %v", err)
+			}
+			client.SetGraphQLURL(server.URL)
+
+			cfg := &config.Config{
+				GitHub: config.GitHubConfig{
+					Repository:        "test/repo",
+					XenForoNodeID:     1,
+					GitHubCategoryID:  "DIC_kwDOtest123",
+					PrefixCategoryMap: tt.prefixes,
+				},
+				Migration: config.MigrationConfig{
+					RequireEmptyCategory:  true,
+					AllowNonEmptyCategory: tt.allowNonEmpty,
+				},
+			}
+
+			err = NewPreflightChecker(cfg, nil, client).SetResuming(tt.resuming).checkGitHubAPI(context.Background())
+			if tt.expectErr && err == nil {
+				t.Error("Expected an error for a non-empty category")
+			}
+			if !tt.expectErr && err != nil {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
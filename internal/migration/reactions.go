@@ -0,0 +1,98 @@
+package migration
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+var (
+	// defaultReactionEmoji are the emoji of XenForo's default reactions.
+	defaultReactionEmoji = map[int]string{1: "👍", 2: "❤️", 3: "😂", 4: "😮", 5: "😢", 6: "😠"}
+
+	// githubReactions maps XenForo's default reactions to the closest GitHub
+	// reaction. GitHub has no reaction for custom XenForo reactions.
+	githubReactions = map[int]string{1: "THUMBS_UP", 2: "HEART", 3: "LAUGH", 4: "EYES", 5: "CONFUSED", 6: "THUMBS_DOWN"}
+)
+
+// reactionIDs returns the post's reaction IDs by descending count, then ID.
+func reactionIDs(reactions xenforo.ReactionCounts) []int {
+	var ids []int
+	for id, count := range reactions {
+		if count > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if reactions[ids[i]] != reactions[ids[j]] {
+			return reactions[ids[i]] > reactions[ids[j]]
+		}
+		return ids[i] < ids[j]
+	})
+	return ids
+}
+
+// withReactionSummary appends a "👍 12 · ❤️ 3" line to the post body in
+// summary mode. Reactions without a configured or default emoji are left out.
+func (r *Runner) withReactionSummary(post xenforo.Post, body string) string {
+	if r.config.Migration.ReactionsMode != config.ReactionsSummary {
+		return body
+	}
+
+	var parts []string
+	for _, id := range reactionIDs(post.Reactions) {
+		emoji := r.config.Migration.ReactionEmoji[id]
+		if emoji == "" {
+			emoji = defaultReactionEmoji[id]
+		}
+		if emoji != "" {
+			parts = append(parts, fmt.Sprintf("%s %d", emoji, post.Reactions[id]))
+		}
+	}
+	if len(parts) == 0 {
+		return body
+	}
+	return body + "\n\n" + strings.Join(parts, " · ")
+}
+
+// addReactions adds each of the post's reaction types once to the migrated
+// discussion or comment in github mode. GitHub records reactions per user, so
+// counts cannot be preserved. Failures are logged; the post itself is already
+// migrated.
+func (r *Runner) addReactions(ctx context.Context, post xenforo.Post, subjectID string) {
+	if r.config.Migration.ReactionsMode != config.ReactionsGitHub {
+		return
+	}
+
+	var contents []string
+	for _, id := range reactionIDs(post.Reactions) {
+		if content, ok := githubReactions[id]; ok {
+			contents = append(contents, content)
+		}
+	}
+	if len(contents) == 0 {
+		return
+	}
+
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would add %d reactions to the post by %s", len(contents), post.Username)
+		return
+	}
+
+	if subjectID == "" {
+		return
+	}
+
+	for _, content := range contents {
+		if err := r.githubClient.AddReaction(ctx, subjectID, content); err != nil {
+			logging.Warnf(ctx, "  ⚠ Failed to add reactions to the post by %s: %v", post.Username, err)
+			return
+		}
+	}
+	logging.Infof(ctx, "  ✓ Added %d reactions to the post by %s", len(contents), post.Username)
+}
@@ -0,0 +1,112 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+)
+
+// redirect maps an original forum thread to the discussion it was migrated to.
+type redirect struct {
+	ThreadID         int    `json:"thread_id"`
+	OldURL           string `json:"old_url"`
+	DiscussionNumber int    `json:"discussion_number"`
+	DiscussionID     string `json:"discussion_id"`
+	URL              string `json:"url"`
+}
+
+// writeRedirects writes the recorded thread → discussion mapping to the
+// configured redirect files, so forum operators can redirect old thread URLs
+// to the migrated discussions. Both files cover every discussion recorded in
+// the progress file, not only the threads of this run.
+func (r *Runner) writeRedirects() {
+	jsonFile, mapFile := r.config.Migration.RedirectsFile, r.config.Migration.NginxMapFile
+	if jsonFile == "" && mapFile == "" {
+		return
+	}
+
+	redirects := r.redirects()
+	if r.config.Migration.DryRun {
+		logging.Infof(context.Background(), "[DRY-RUN] Would write %d thread redirects", len(redirects))
+		return
+	}
+
+	if jsonFile != "" {
+		if err := writeRedirectsJSON(jsonFile, redirects); err != nil {
+			logging.Warnf(context.Background(), "✗ Warning: Failed to write redirects to %s: %v", jsonFile, err)
+		} else {
+			logging.Infof(context.Background(), "✓ Wrote %d thread redirects to %s", len(redirects), jsonFile)
+		}
+	}
+	if mapFile != "" {
+		if err := writeNginxMap(mapFile, r.forumPath(), redirects); err != nil {
+			logging.Warnf(context.Background(), "✗ Warning: Failed to write nginx map to %s: %v", mapFile, err)
+		} else {
+			logging.Infof(context.Background(), "✓ Wrote %d thread redirects to %s", len(redirects), mapFile)
+		}
+	}
+}
+
+// redirects returns the recorded discussions in thread ID order.
+func (r *Runner) redirects() []redirect {
+	forumURL := strings.TrimRight(r.config.XenForo.WebURL, "/")
+
+	var redirects []redirect
+	for threadID, ref := range r.tracker.Discussions() {
+		target := ref.URL
+		if target == "" {
+			target = r.discussionURL(ref.Number)
+		}
+		redirects = append(redirects, redirect{
+			ThreadID:         threadID,
+			OldURL:           fmt.Sprintf("%s/threads/%d/", forumURL, threadID),
+			DiscussionNumber: ref.Number,
+			DiscussionID:     ref.ID,
+			URL:              target,
+		})
+	}
+	sort.Slice(redirects, func(i, j int) bool { return redirects[i].ThreadID < redirects[j].ThreadID })
+	return redirects
+}
+
+// forumPath returns the path the forum is served under (e.g. "/community"),
+// empty for forums at the root or without a configured web URL.
+func (r *Runner) forumPath() string {
+	parsed, err := url.Parse(r.config.XenForo.WebURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimRight(parsed.Path, "/")
+}
+
+func writeRedirectsJSON(path string, redirects []redirect) error {
+	if redirects == nil {
+		redirects = []redirect{}
+	}
+	data, err := json.MarshalIndent(redirects, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal redirects: %w", err)
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// writeNginxMap writes map entries matching every URL of a thread, with or
+// without the title slug (/threads/title.123/page-2), for use as:
+//
+//	map $uri $discussion_url { include redirects.map; }
+//	if ($discussion_url) { return 301 $discussion_url; }
+func writeNginxMap(path, forumPath string, redirects []redirect) error {
+	var b strings.Builder
+	b.WriteString("# Old forum thread URLs -> GitHub discussions, generated by xenforo-to-gh-discussions\n")
+	for _, rd := range redirects {
+		fmt.Fprintf(&b, "\"~^%s/threads/(?:[^/]*\\.)?%d(?:/|$)\" %s;\n", regexp.QuoteMeta(forumPath), rd.ThreadID, rd.URL)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
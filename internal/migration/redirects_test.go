@@ -0,0 +1,73 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+)
+
+func TestRunner_WriteRedirects(t *testing.T) {
+	dir := t.TempDir()
+	jsonFile := filepath.Join(dir, "redirects.json")
+	mapFile := filepath.Join(dir, "redirects.map")
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, newTestForum(2), api, func(cfg *config.Config) {
+		cfg.XenForo.WebURL = "https://forum.example.com/community/"
+		cfg.Migration.RedirectsFile = jsonFile
+		cfg.Migration.NginxMapFile = mapFile
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	data, err := os.ReadFile(jsonFile)
+	if err != nil {
+		t.Fatalf("Failed to read redirects: %v", err)
+	}
+	var redirects []redirect
+	if err := json.Unmarshal(data, &redirects); err != nil {
+		t.Fatalf("Failed to parse redirects: %v", err)
+	}
+	expected := []redirect{
+		{ThreadID: 1, OldURL: "https://forum.example.com/community/threads/1/", DiscussionNumber: 1, DiscussionID: "D_1", URL: "https://github.com/test/repo/discussions/1"},
+		{ThreadID: 2, OldURL: "https://forum.example.com/community/threads/2/", DiscussionNumber: 2, DiscussionID: "D_2", URL: "https://github.com/test/repo/discussions/2"},
+	}
+	if len(redirects) != len(expected) {
+		t.Fatalf("Expected %d redirects, got %+v", len(expected), redirects)
+	}
+	for i := range expected {
+		if redirects[i] != expected[i] {
+			t.Errorf("Redirect %d: expected %+v, got %+v", i, expected[i], redirects[i])
+		}
+	}
+
+	nginxMap, err := os.ReadFile(mapFile)
+	if err != nil {
+		t.Fatalf("Failed to read nginx map: %v", err)
+	}
+	expectedMap := "# Old forum thread URLs -> GitHub discussions, generated by xenforo-to-gh-discussions\n" +
+		"\"~^/community/threads/(?:[^/]*\\.)?1(?:/|$)\" https://github.com/test/repo/discussions/1;\n" +
+		"\"~^/community/threads/(?:[^/]*\\.)?2(?:/|$)\" https://github.com/test/repo/discussions/2;\n"
+	if string(nginxMap) != expectedMap {
+		t.Errorf("Expected nginx map:\n%s\ngot:\n%s", expectedMap, nginxMap)
+	}
+}
+
+func TestRunner_WriteRedirectsDryRun(t *testing.T) {
+	jsonFile := filepath.Join(t.TempDir(), "redirects.json")
+	runner, _ := newTestRunner(t, newTestForum(1), func(cfg *config.Config) {
+		cfg.Migration.RedirectsFile = jsonFile
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	if _, err := os.Stat(jsonFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no redirects file in dry-run mode, got %v", err)
+	}
+}
@@ -0,0 +1,147 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+)
+
+// DiscussionEditor fetches migrated discussions and replaces the bodies of
+// their posts.
+type DiscussionEditor interface {
+	DiscussionFetcher
+	UpdateDiscussion(ctx context.Context, discussionID, title, body string) error
+	UpdateDiscussionComment(ctx context.Context, commentID, body string) error
+}
+
+// threadLinkRewriter replaces links to forum threads with links to the
+// discussions they were migrated to.
+type threadLinkRewriter struct {
+	pattern *regexp.Regexp
+	targets map[int]string // Thread ID to discussion URL
+}
+
+// newThreadLinkRewriter matches thread URLs of the forum at forumURL, with or
+// without the title slug, page or post, over http and https. Links to a page
+// or post of a thread lead to its (first) discussion.
+func newThreadLinkRewriter(forumURL string, targets map[int]string) (*threadLinkRewriter, error) {
+	parsed, err := url.Parse(strings.TrimRight(forumURL, "/"))
+	if err != nil || parsed.Host == "" {
+		return nil, fmt.Errorf("invalid forum URL %q", forumURL)
+	}
+
+	pattern := fmt.Sprintf(`(?i)https?://%s%s/(?:index\.php\?)?threads/(?:[^/\s\])]*\.)?(\d+)\b(?:/(?:page-\d+|post-\d+)?)?(?:#post-\d+)?`,
+		regexp.QuoteMeta(parsed.Host), regexp.QuoteMeta(parsed.Path))
+	return &threadLinkRewriter{pattern: regexp.MustCompile(pattern), targets: targets}, nil
+}
+
+// rewrite returns text with the links to migrated threads replaced and the
+// number of replaced links. Links to threads without a discussion are kept. A
+// nil rewriter keeps every link.
+func (w *threadLinkRewriter) rewrite(text string) (string, int) {
+	if w == nil {
+		return text, 0
+	}
+
+	count := 0
+	result := w.pattern.ReplaceAllStringFunc(text, func(match string) string {
+		threadID, _ := strconv.Atoi(w.pattern.FindStringSubmatch(match)[1])
+		target, ok := w.targets[threadID]
+		if !ok {
+			return match
+		}
+		count++
+		return target
+	})
+	return result, count
+}
+
+// RewriteThreadLinks edits the recorded discussions, replacing links to
+// forum threads with links to the discussions those threads were migrated
+// to. It runs as a second pass once every thread has its discussion, so
+// links to threads migrated later than the linking post are rewritten too.
+// Links to threads that were not migrated are kept. An exhausted rate limit
+// stops the rewrite; running it again continues, as rewritten links no
+// longer match. Returns the number of edited posts.
+func RewriteThreadLinks(ctx context.Context, cfg *config.Config, editor DiscussionEditor, recorded map[int]progress.DiscussionRef, dryRun bool) (int, error) {
+	var discussions []progress.DiscussionRef
+	for _, ref := range recorded {
+		discussions = append(discussions, ref)
+		discussions = append(discussions, ref.Parts...)
+	}
+	sort.Slice(discussions, func(i, j int) bool { return discussions[i].Number < discussions[j].Number })
+
+	r := &Runner{config: cfg}
+	rewriter, err := newThreadLinkRewriter(cfg.XenForo.WebURL, r.threadLinkTargets(recorded))
+	if err != nil {
+		return 0, err
+	}
+
+	edited := 0
+	for _, ref := range discussions {
+		content, err := editor.GetDiscussionContent(ctx, cfg.GitHub.Repository, ref.Number)
+		if err != nil {
+			if errors.Is(err, github.ErrRateLimitExhausted) {
+				return edited, err
+			}
+			logging.Errorf(ctx, "✗ Failed to fetch discussion #%d: %v", ref.Number, err)
+			continue
+		}
+
+		posts := append([]string{content.Body}, content.Comments...)
+		for i, body := range posts {
+			rewritten, links := rewriter.rewrite(body)
+			if links == 0 {
+				continue
+			}
+
+			post := "body"
+			if i > 0 {
+				post = fmt.Sprintf("comment %d", i)
+			}
+			if dryRun {
+				logging.Infof(ctx, "[DRY-RUN] Would rewrite %d thread links in the %s of discussion #%d", links, post, ref.Number)
+				continue
+			}
+
+			if i == 0 {
+				err = editor.UpdateDiscussion(ctx, content.ID, "", rewritten)
+			} else {
+				err = editor.UpdateDiscussionComment(ctx, content.CommentIDs[i-1], rewritten)
+			}
+			if err != nil {
+				if errors.Is(err, github.ErrRateLimitExhausted) {
+					return edited, err
+				}
+				logging.Errorf(ctx, "✗ Failed to update the %s of discussion #%d: %v", post, ref.Number, err)
+				continue
+			}
+			edited++
+			logging.Infof(ctx, "✓ Rewrote %d thread links in the %s of discussion #%d", links, post, ref.Number)
+		}
+	}
+	return edited, nil
+}
+
+// threadLinkTargets returns the URL of each recorded thread's (first)
+// discussion.
+func (r *Runner) threadLinkTargets(recorded map[int]progress.DiscussionRef) map[int]string {
+	targets := make(map[int]string, len(recorded))
+	for threadID, ref := range recorded {
+		targets[threadID] = ref.URL
+		if ref.URL == "" {
+			targets[threadID] = r.discussionURL(ref.Number)
+		}
+	}
+	return targets
+}
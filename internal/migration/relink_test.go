@@ -0,0 +1,50 @@
+package migration
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestRewriteThreadLinks(t *testing.T) {
+	forum := newTestForum(2)
+	forum.threads[0].ReplyCount = 1
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "See [URL=https://forum.example.com/community/threads/thread-2.2/post-20]this thread[/URL]"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "Back to https://forum.example.com/community/threads/1/, not http://forum.example.com/community/threads/3/ or https://other.example.com/threads/2/"},
+	}
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+		cfg.XenForo.WebURL = "https://forum.example.com/community/"
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	edited, err := RewriteThreadLinks(context.Background(), runner.config, runner.githubClient, runner.tracker.Discussions(), true)
+	if err != nil || edited != 0 || !strings.Contains(api.discussions[0].Body, "/threads/thread-2.2/post-20") {
+		t.Fatalf("Expected a dry run to edit nothing, got %d edits (err: %v)", edited, err)
+	}
+
+	edited, err = RewriteThreadLinks(context.Background(), runner.config, runner.githubClient, runner.tracker.Discussions(), false)
+	if err != nil {
+		t.Fatalf("RewriteThreadLinks returned error: %v", err)
+	}
+	if edited != 2 {
+		t.Errorf("Expected the discussion body and the comment to be edited, got %d edits", edited)
+	}
+	if !strings.Contains(api.discussions[0].Body, "[this thread](https://github.com/test/repo/discussions/2)") {
+		t.Errorf("Expected the thread link to point to discussion 2, got:\n%s", api.discussions[0].Body)
+	}
+	comment := api.comments[0].Body
+	if !strings.Contains(comment, "Back to https://github.com/test/repo/discussions/1,") {
+		t.Errorf("Expected the comment's thread link to point to discussion 1, got:\n%s", comment)
+	}
+	if !strings.Contains(comment, "http://forum.example.com/community/threads/3/") || !strings.Contains(comment, "https://other.example.com/threads/2/") {
+		t.Errorf("Expected links to unmigrated threads and other sites to be kept, got:\n%s", comment)
+	}
+}
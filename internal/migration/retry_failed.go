@@ -0,0 +1,78 @@
+package migration
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// recordFailedThread remembers a thread that failed during this run for the
+// grace retry pass.
+func (r *Runner) recordFailedThread(thread xenforo.Thread) {
+	r.failedMu.Lock()
+	defer r.failedMu.Unlock()
+	r.failedThreads = append(r.failedThreads, thread)
+}
+
+// takeFailedThreads returns and forgets the threads failed so far.
+func (r *Runner) takeFailedThreads() []xenforo.Thread {
+	r.failedMu.Lock()
+	defer r.failedMu.Unlock()
+	failed := r.failedThreads
+	r.failedThreads = nil
+	return failed
+}
+
+// retryFailedThreads makes one more sequential pass over the threads that
+// failed during this run when AutoRetryFailed is enabled, since many failures
+// are transient. The pass starts after AutoRetryWait and waits twice as long
+// between posts. Threads that succeed are cleared from the failed set and no
+// longer count as failures in the run summary.
+func (r *Runner) retryFailedThreads(ctx context.Context) {
+	if !r.config.Migration.AutoRetryFailed {
+		return
+	}
+
+	failed := r.takeFailedThreads()
+	if len(failed) == 0 {
+		return
+	}
+	if atomic.LoadInt32(&r.rateLimited) == 1 || (r.isDryRun(ctx) && !r.config.Migration.DryRun) {
+		logging.Warnf(ctx, "⚠ Not retrying %d failed threads: the run was stopped early", len(failed))
+		return
+	}
+
+	wait := r.config.Migration.AutoRetryWait
+	logging.Warnf(ctx, "\n⚠ %d threads failed, retrying them in %s...", len(failed), wait)
+	select {
+	case <-ctx.Done():
+		logging.Errorf(ctx, "✗ Retry of failed threads cancelled: %v", ctx.Err())
+		return
+	case <-time.After(wait):
+	}
+
+	postDelay := r.postDelay
+	r.postDelay = 2 * postDelay
+	defer func() { r.postDelay = postDelay }()
+
+	recovered := 0
+	for i, thread := range failed {
+		processed := atomic.LoadInt64(&r.processed)
+		r.migrateThread(ctx, thread, i+1, len(failed))
+		if atomic.LoadInt64(&r.processed) == processed {
+			continue // Not attempted (cancelled or rate limited)
+		}
+		// The retry replaces the failed first attempt in the run counts
+		atomic.AddInt64(&r.processed, -1)
+		atomic.AddInt64(&r.failures, -1)
+
+		if r.tracker.IsCompleted(thread.ThreadID) {
+			recovered++ // Completing the thread cleared it from the failed set
+		}
+	}
+
+	logging.Infof(ctx, "✓ Retry pass recovered %d of %d failed threads", recovered, len(failed))
+}
@@ -0,0 +1,80 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+)
+
+// RollbackTarget is a thread whose migrated discussions a rollback deletes.
+type RollbackTarget struct {
+	ThreadID    int
+	Discussions []progress.DiscussionRef
+}
+
+// RollbackTargets returns the discussions recorded in the progress file, in
+// thread ID order: those of migrated threads, including every part of split
+// threads, and those of threads interrupted part-way.
+func RollbackTargets(tracker *progress.Tracker) []RollbackTarget {
+	byThread := make(map[int][]progress.DiscussionRef)
+	for threadID, ref := range tracker.Discussions() {
+		parts := ref.Parts
+		ref.Parts = nil
+		byThread[threadID] = append([]progress.DiscussionRef{ref}, parts...)
+	}
+	for threadID, checkpoint := range tracker.GetProgress().Checkpoints {
+		if _, ok := byThread[threadID]; !ok && len(checkpoint.Discussions) > 0 {
+			byThread[threadID] = append([]progress.DiscussionRef(nil), checkpoint.Discussions...)
+		}
+	}
+
+	targets := make([]RollbackTarget, 0, len(byThread))
+	for threadID, discussions := range byThread {
+		targets = append(targets, RollbackTarget{ThreadID: threadID, Discussions: discussions})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ThreadID < targets[j].ThreadID })
+	return targets
+}
+
+// Rollback deletes the discussions of the targets and forgets their threads,
+// so the next run migrates them again. Threads whose discussions could not all
+// be deleted stay recorded for another rollback. An exhausted rate limit stops
+// the rollback; the progress file reflects the threads rolled back so far.
+// Returns the number of deleted discussions.
+func Rollback(ctx context.Context, client *github.Client, tracker *progress.Tracker, targets []RollbackTarget, dryRun bool) (int, error) {
+	deleted := 0
+	for _, target := range targets {
+		if dryRun {
+			for _, discussion := range target.Discussions {
+				logging.Infof(ctx, "[DRY-RUN] Would delete discussion #%d of thread %d", discussion.Number, target.ThreadID)
+			}
+			continue
+		}
+
+		failed := false
+		for _, discussion := range target.Discussions {
+			if err := client.DeleteDiscussion(ctx, discussion.ID); err != nil {
+				if errors.Is(err, github.ErrRateLimitExhausted) {
+					return deleted, err
+				}
+				logging.Errorf(ctx, "✗ Failed to delete discussion #%d of thread %d: %v", discussion.Number, target.ThreadID, err)
+				failed = true
+				continue
+			}
+			deleted++
+			logging.Infof(ctx, "✓ Deleted discussion #%d of thread %d", discussion.Number, target.ThreadID)
+		}
+		if failed {
+			continue
+		}
+
+		if err := tracker.ForgetThread(target.ThreadID); err != nil {
+			logging.Warnf(ctx, "✗ Warning: Failed to remove thread %d from the progress file: %v", target.ThreadID, err)
+		}
+	}
+	return deleted, nil
+}
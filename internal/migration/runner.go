@@ -2,93 +2,552 @@ package migration
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
+	"maps"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/exileum/xenforo-to-gh-discussions/internal/attachments"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/bbcode"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/concurrency"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/notify"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
 )
 
 type Runner struct {
 	config        *config.Config
-	xenforoClient *xenforo.Client
+	xenforoSource ForumSource
 	githubClient  *github.Client
 	tracker       *progress.Tracker
 	downloader    *attachments.Downloader
+	uploader      *attachments.Uploader
+	avatars       *attachments.AvatarCache
 	processor     *bbcode.MessageProcessor
+	limiter       *concurrency.Semaphore
+	notifier      *notify.WebhookNotifier
+	exporter      *ThreadExporter
+	failures      int64 // Threads that failed during this run (atomic)
+	processed     int64 // Threads finished (completed or failed) during this run (atomic)
+	safetyDryRun  int32 // Set to 1 once the failure threshold switched the run to dry-run (atomic)
+	rateLimited   int32 // Set to 1 once the GitHub rate limit was exhausted; no new threads are started (atomic)
+	pausePoll     time.Duration
+	postDelay     time.Duration // Pause between GitHub writes for consecutive posts
+
+	failedMu      sync.Mutex
+	failedThreads []xenforo.Thread // Threads failed during this run, for the grace retry pass
+
+	labelsMu sync.Mutex
+	labelIDs map[string]string // Repository label IDs by name, resolved on first use
+
+	checkpointPosts    int           // Posts written before a thread's checkpoint is saved again
+	checkpointInterval time.Duration // Time after which a thread's checkpoint is saved again
+	checkpointMu       sync.Mutex
+	checkpointSaves    map[int]*checkpointSave // Last checkpoint save of the threads in progress
 }
 
-func NewRunner(cfg *config.Config, xenforoClient *xenforo.Client, githubClient *github.Client, tracker *progress.Tracker, downloader *attachments.Downloader) *Runner {
+// defaultPausePollInterval is how often the pause control file is checked while paused.
+const defaultPausePollInterval = 2 * time.Second
+
+// defaultPostDelay is the pause between GitHub writes for consecutive posts.
+const defaultPostDelay = 1 * time.Second
+
+// A thread's checkpoint is saved after this many written posts or this long
+// after its last save, whichever comes first.
+const (
+	defaultCheckpointPosts    = 20
+	defaultCheckpointInterval = 10 * time.Second
+)
+
+func NewRunner(cfg *config.Config, source ForumSource, githubClient *github.Client, tracker *progress.Tracker, downloader *attachments.Downloader) *Runner {
 	return &Runner{
 		config:        cfg,
-		xenforoClient: xenforoClient,
+		xenforoSource: source,
 		githubClient:  githubClient,
 		tracker:       tracker,
 		downloader:    downloader,
-		processor:     bbcode.NewMessageProcessor(),
+		processor:     newMessageProcessor(cfg),
+		pausePoll:     defaultPausePollInterval,
+		postDelay:     defaultPostDelay,
+
+		checkpointPosts:    defaultCheckpointPosts,
+		checkpointInterval: defaultCheckpointInterval,
+	}
+}
+
+// newMessageProcessor builds the message processor with the configured
+// conversion options.
+func newMessageProcessor(cfg *config.Config) *bbcode.MessageProcessor {
+	var signaturePattern *regexp.Regexp
+	if cfg.Migration.SignaturePattern != "" {
+		pattern, err := regexp.Compile(cfg.Migration.SignaturePattern)
+		if err != nil {
+			logging.Warnf(context.Background(), "✗ Warning: Ignoring invalid signature pattern: %v", err)
+		} else {
+			signaturePattern = pattern
+		}
+	}
+
+	var smileys map[string]string
+	if cfg.Migration.SmileyEmoji {
+		smileys = bbcode.DefaultSmileys()
+		for path, emoji := range cfg.Migration.SmileyMap {
+			smileys[path] = emoji
+		}
+	}
+
+	labels, err := bbcode.LocaleLabels(cfg.Migration.Locale, cfg.Migration.FrontmatterLabels)
+	if err != nil {
+		logging.Warnf(context.Background(), "✗ Warning: Using default frontmatter labels: %v", err)
+		labels = bbcode.DefaultLabels()
 	}
+
+	return bbcode.NewMessageProcessor().
+		SetPreserveAlignment(cfg.Migration.PreserveAlignment).
+		SetSignatureStripping(cfg.Migration.StripSignatures, signaturePattern).
+		SetSmileys(smileys).
+		SetVideoThumbnails(cfg.Migration.VideoThumbnails).
+		SetMediaSites(cfg.Migration.MediaSites).
+		SetMemberBaseURL(cfg.XenForo.WebURL).
+		SetMentionMode(bbcode.MentionMode(cfg.Migration.MentionMode)).
+		SetUserMentions(cfg.Migration.UserMapping).
+		SetLabels(labels).
+		SetHeaderStyle(bbcode.HeaderStyle(cfg.Migration.HeaderStyle)).
+		SetMetadataFormat(bbcode.MetadataFormat(cfg.Migration.MetadataFormat)).
+		SetHTMLConversion(cfg.Migration.ConvertHTML).
+		SetLegacyConverter(cfg.Migration.LegacyConverter)
+}
+
+// SetLimiter configures the global semaphore shared with the attachment
+// downloader. Each thread worker holds one slot while processing a thread.
+func (r *Runner) SetLimiter(limiter *concurrency.Semaphore) *Runner {
+	r.limiter = limiter
+	return r
+}
+
+// SetNotifier enables posting a JSON run summary to a webhook after the run.
+func (r *Runner) SetNotifier(notifier *notify.WebhookNotifier) *Runner {
+	r.notifier = notifier
+	return r
+}
+
+// SetUploader enables uploading downloaded attachments to GitHub so posts link
+// to hosted URLs instead of local paths.
+func (r *Runner) SetUploader(uploader *attachments.Uploader) *Runner {
+	r.uploader = uploader
+	return r
+}
+
+// SetExporter writes every rendered thread to the exporter. Used by
+// export-only runs, which are dry runs otherwise.
+func (r *Runner) SetExporter(exporter *ThreadExporter) *Runner {
+	r.exporter = exporter
+	return r
+}
+
+// SetAvatars shows each author's avatar, resolved through the cache, in the
+// post headers.
+func (r *Runner) SetAvatars(avatars *attachments.AvatarCache) *Runner {
+	r.avatars = avatars
+	return r
 }
 
 func (r *Runner) RunMigration(ctx context.Context) error {
-	log.Printf("Fetching threads from forum node %d...", r.config.GitHub.XenForoNodeID)
-	threads, err := r.xenforoClient.GetThreads(r.config.GitHub.XenForoNodeID)
+	startedAt := time.Now()
+
+	logging.Infof(ctx, "Fetching threads from forum node %d...", r.config.GitHub.XenForoNodeID)
+	threads, err := r.fetchThreads(ctx)
 	if err != nil {
 		return err
 	}
-	log.Printf("✓ Found %d threads to migrate", len(threads))
+	logging.Infof(ctx, "✓ Found %d threads to migrate", len(threads))
 
 	threads = r.tracker.FilterCompletedThreads(threads)
-	log.Printf("✓ %d threads remaining after filtering completed ones", len(threads))
+	logging.Infof(ctx, "✓ %d threads remaining after filtering completed ones", len(threads))
 
-	for i, thread := range threads {
-		log.Printf("\nProcessing thread %d/%d: %s", i+1, len(threads), thread.Title)
+	threads = r.filterSinceLastRun(threads)
+	threads = r.filterRetryFailed(ctx, threads)
+	if threads, err = r.filterThreads(ctx, threads); err != nil {
+		return err
+	}
+	r.orderThreads(ctx, threads)
 
-		if err := r.processThread(ctx, thread); err != nil {
-			log.Printf("✗ Failed to process thread %d: %v", thread.ThreadID, err)
-			if markErr := r.tracker.MarkFailed(thread.ThreadID); markErr != nil {
-				log.Printf("✗ Warning: Failed to mark thread %d as failed in progress tracker: %v", thread.ThreadID, markErr)
-			}
-			continue
+	r.inviteMappedUsers(ctx)
+	r.processThreads(ctx, threads)
+	r.retryFailedThreads(ctx)
+	r.downloader.LogSkipped(ctx)
+	r.recordRun(ctx, startedAt)
+	r.checkMappings(ctx)
+	r.writeRedirects()
+
+	r.tracker.PrintSummary()
+	if atomic.LoadInt32(&r.safetyDryRun) == 1 {
+		logging.Warnf(ctx, "⚠ The failure threshold was exceeded: remaining threads were processed in dry-run mode and left pending")
+	}
+
+	r.sendSummary(len(threads), startedAt)
+
+	if atomic.LoadInt32(&r.rateLimited) == 1 {
+		logging.Warnf(ctx, "⚠ The GitHub API rate limit was exhausted: run again after it resets to resume where this run stopped")
+		return fmt.Errorf("migration stopped early: %w", github.ErrRateLimitExhausted)
+	}
+	return nil
+}
+
+// fetchThreads lists the node's threads. With ResumeListing enabled, every
+// fetched page is saved so an interrupted listing continues after the last
+// fetched page on the next run.
+func (r *Runner) fetchThreads(ctx context.Context) ([]xenforo.Thread, error) {
+	nodeID := r.config.GitHub.XenForoNodeID
+	if !r.config.Migration.ResumeListing {
+		return r.xenforoSource.GetThreadsFrom(ctx, nodeID, 1, nil, nil)
+	}
+
+	startPage := 1
+	var collected []xenforo.Thread
+	if listing, ok := r.tracker.Listing(nodeID); ok {
+		startPage = listing.Page + 1
+		collected = listing.Threads
+		logging.Infof(ctx, "  Resuming thread listing at page %d (%d threads already listed)", startPage, len(collected))
+	}
+
+	threads, err := r.xenforoSource.GetThreadsFrom(ctx, nodeID, startPage, collected, func(page int, threads []xenforo.Thread) error {
+		if err := r.tracker.SaveListing(nodeID, page, threads); err != nil {
+			logging.Warnf(ctx, "✗ Warning: Failed to save thread listing after page %d: %v", page, err)
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.tracker.ClearListing(); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to clear saved thread listing: %v", err)
+	}
+	return threads, nil
+}
+
+// sendSummary posts the run summary to the configured webhook. It uses its own
+// timeout so cancelled runs are still reported.
+func (r *Runner) sendSummary(total int, startedAt time.Time) {
+	if r.notifier == nil {
+		return
+	}
+
+	finishedAt := time.Now()
+	processed := atomic.LoadInt64(&r.processed)
+	failed := atomic.LoadInt64(&r.failures)
+	summary := notify.Summary{
+		Repository:      r.config.GitHub.Repository,
+		NodeID:          r.config.GitHub.XenForoNodeID,
+		DryRun:          r.config.Migration.DryRun,
+		ThreadsTotal:    total,
+		ThreadsMigrated: processed - failed,
+		ThreadsFailed:   failed,
+		StartedAt:       startedAt.UTC().Format(time.RFC3339),
+		FinishedAt:      finishedAt.UTC().Format(time.RFC3339),
+		DurationSeconds: int64(finishedAt.Sub(startedAt).Seconds()),
+	}
+	summary.Text = fmt.Sprintf("Migration of node %d to %s finished: %d of %d threads migrated, %d failed",
+		summary.NodeID, summary.Repository, summary.ThreadsMigrated, total, failed)
+	if r.config.Migration.DryRun || atomic.LoadInt32(&r.safetyDryRun) == 1 {
+		summary.Text += " (dry-run)"
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 1*time.Minute)
+	defer cancel()
+
+	if err := r.notifier.Send(ctx, summary); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to send run summary webhook: %v", err)
+		return
+	}
+	logging.Infof(ctx, "✓ Run summary sent to webhook")
+}
+
+// threadDryRunKey marks in a thread's context whether the thread started
+// after the failure threshold switched the run to dry-run.
+type threadDryRunKey struct{}
+
+// isDryRun reports whether writes are disabled in ctx, either by configuration
+// or because the failure threshold switched the run to dry-run. Threads decide
+// once, when they start, so a switch never interrupts a thread half-written.
+func (r *Runner) isDryRun(ctx context.Context) bool {
+	if r.config.Migration.DryRun {
+		return true
+	}
+	if dryRun, ok := ctx.Value(threadDryRunKey{}).(bool); ok {
+		return dryRun
+	}
+	return atomic.LoadInt32(&r.safetyDryRun) == 1
+}
+
+// checkFailureThreshold switches the rest of the run to dry-run once the first
+// FailureThresholdThreads threads have finished with a failure rate above
+// FailureThresholdPercent. Threads already in flight keep writing and record
+// their discussions; only threads started afterwards run in dry-run.
+func (r *Runner) checkFailureThreshold(processed int64) {
+	threshold := int64(r.config.Migration.FailureThresholdThreads)
+	if threshold <= 0 || processed != threshold || r.config.Migration.DryRun {
+		return
+	}
+
+	failures := atomic.LoadInt64(&r.failures)
+	if failures*100 <= threshold*int64(r.config.Migration.FailureThresholdPercent) {
+		return
+	}
+
+	if atomic.CompareAndSwapInt32(&r.safetyDryRun, 0, 1) {
+		logging.Warnf(context.Background(), "⚠ %d of the first %d threads failed (more than %d%%): switching the remaining run to dry-run",
+			failures, threshold, r.config.Migration.FailureThresholdPercent)
+		logging.Warnf(context.Background(), "⚠ Discussions created so far are kept; investigate the failures before re-running")
+	}
+}
+
+// runKey identifies the repository and node pair for run bookkeeping.
+func (r *Runner) runKey() string {
+	return fmt.Sprintf("%s#%d", r.config.GitHub.Repository, r.config.GitHub.XenForoNodeID)
+}
 
-		if err := r.tracker.MarkCompleted(thread.ThreadID); err != nil {
-			log.Printf("✗ Warning: Failed to mark thread %d as completed in progress tracker: %v", thread.ThreadID, err)
+// filterSinceLastRun keeps only threads created at or after the start of the
+// last successful run when SinceLastRun is enabled. Without a recorded run
+// every thread is kept.
+func (r *Runner) filterSinceLastRun(threads []xenforo.Thread) []xenforo.Thread {
+	if !r.config.Migration.SinceLastRun {
+		return threads
+	}
+
+	since, ok := r.tracker.LastRunAt(r.runKey())
+	if !ok {
+		logging.Infof(context.Background(), "  No previous run recorded for %s, migrating all threads", r.runKey())
+		return threads
+	}
+
+	var filtered []xenforo.Thread
+	for _, thread := range threads {
+		if thread.PostDate >= since {
+			filtered = append(filtered, thread)
 		}
 	}
+	logging.Infof(context.Background(), "✓ %d threads created since last run (%s)", len(filtered), time.Unix(since, 0).UTC().Format(time.RFC3339))
+	return filtered
+}
 
-	r.tracker.PrintSummary()
-	return nil
+// filterRetryFailed keeps only the threads recorded as failed when
+// RetryFailed is enabled. Failed threads no longer listed in the node are
+// reported and stay failed.
+func (r *Runner) filterRetryFailed(ctx context.Context, threads []xenforo.Thread) []xenforo.Thread {
+	if !r.config.Migration.RetryFailed {
+		return threads
+	}
+
+	failed := r.tracker.FailedThreads()
+	var filtered []xenforo.Thread
+	for _, thread := range threads {
+		if _, ok := failed[thread.ThreadID]; ok {
+			filtered = append(filtered, thread)
+			delete(failed, thread.ThreadID)
+		}
+	}
+	logging.Infof(ctx, "✓ Retrying %d failed threads", len(filtered))
+	for _, threadID := range slices.Sorted(maps.Keys(failed)) {
+		logging.Warnf(ctx, "⚠ Failed thread %d is not listed in node %d, skipping it", threadID, r.config.GitHub.XenForoNodeID)
+	}
+	return filtered
+}
+
+// recordRun stores the run start time so the next --since-last-run picks up
+// from here. Dry runs, cancelled or rate limited runs and runs with failures
+// are not recorded.
+func (r *Runner) recordRun(ctx context.Context, startedAt time.Time) {
+	if r.config.Migration.DryRun || ctx.Err() != nil || atomic.LoadInt64(&r.failures) > 0 || atomic.LoadInt32(&r.rateLimited) == 1 {
+		return
+	}
+
+	if err := r.tracker.RecordRun(r.runKey(), startedAt.Unix()); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to record run timestamp: %v", err)
+	}
 }
 
+// processThreads distributes threads across MigrationConcurrency workers.
+// With a single worker threads are migrated strictly in order.
+func (r *Runner) processThreads(ctx context.Context, threads []xenforo.Thread) {
+	workers := max(1, min(r.config.Migration.MigrationConcurrency, len(threads)))
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				r.migrateThread(ctx, threads[i], i+1, len(threads))
+			}
+		}()
+	}
+
+	for i := range threads {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// waitWhilePaused blocks while the pause control file exists. Threads already
+// in progress finish and save their progress before workers pause here.
+func (r *Runner) waitWhilePaused(ctx context.Context) error {
+	pauseFile := r.config.Migration.PauseFile
+	if pauseFile == "" {
+		return nil
+	}
+
+	if _, err := os.Stat(pauseFile); err != nil {
+		return nil
+	}
+
+	logging.Warnf(ctx, "⚠ Pause file %s found, pausing until it is removed...", pauseFile)
+	ticker := time.NewTicker(r.pausePoll)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("cancelled while paused: %w", ctx.Err())
+		case <-ticker.C:
+			if _, err := os.Stat(pauseFile); err != nil {
+				logging.Infof(ctx, "✓ Pause file removed, resuming migration")
+				return nil
+			}
+		}
+	}
+}
+
+func (r *Runner) migrateThread(ctx context.Context, thread xenforo.Thread, position, total int) {
+	ctx = logging.With(ctx, "thread_id", thread.ThreadID)
+
+	// Remaining threads stay pending once the rate limit is exhausted.
+	if atomic.LoadInt32(&r.rateLimited) == 1 {
+		return
+	}
+
+	if err := r.waitWhilePaused(ctx); err != nil {
+		logging.Errorf(ctx, "✗ Skipping thread %d: %v", thread.ThreadID, err)
+		return
+	}
+
+	if err := r.limiter.Acquire(ctx); err != nil {
+		logging.Errorf(ctx, "✗ Skipping thread %d: %v", thread.ThreadID, err)
+		return
+	}
+	defer r.limiter.Release()
+	ctx = context.WithValue(ctx, threadDryRunKey{}, r.isDryRun(ctx))
+
+	logging.Infof(ctx, "\nProcessing thread %d/%d: %s", position, total, thread.Title)
+
+	startedAt := time.Now()
+	err := r.processThread(ctx, thread)
+	ctx = logging.With(ctx, "duration_seconds", time.Since(startedAt).Seconds())
+	if errors.Is(err, github.ErrRateLimitExhausted) {
+		// Not a permanent failure: the thread resumes from its checkpoint next run.
+		logging.Errorf(ctx, "✗ Stopping at thread %d: %v", thread.ThreadID, err)
+		atomic.StoreInt32(&r.rateLimited, 1)
+		return
+	}
+	if err != nil && ctx.Err() != nil {
+		// Interrupted, the thread resumes from its checkpoint next run.
+		logging.Errorf(ctx, "✗ Stopping at thread %d: %v", thread.ThreadID, err)
+		return
+	}
+	if err != nil {
+		atomic.AddInt64(&r.failures, 1)
+	}
+	r.checkFailureThreshold(atomic.AddInt64(&r.processed, 1))
+
+	if err != nil {
+		logging.Errorf(ctx, "✗ Failed to process thread %d: %v", thread.ThreadID, err)
+		r.recordFailedThread(thread)
+		r.markFailed(ctx, thread.ThreadID, err)
+		return
+	}
+
+	// Threads started after the failure threshold tripped were not written
+	// and must stay pending for the next run.
+	if r.isDryRun(ctx) && !r.config.Migration.DryRun {
+		return
+	}
+
+	if err := r.tracker.MarkCompleted(thread.ThreadID); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to mark thread %d as completed in progress tracker: %v", thread.ThreadID, err)
+	}
+	logging.Infof(ctx, "  ✓ Thread %d processed in %s", thread.ThreadID, time.Since(startedAt).Round(time.Millisecond))
+}
+
+// markFailed records a failed attempt at a thread in the progress file, with
+// the phase and message of a MigrationError.
+func (r *Runner) markFailed(ctx context.Context, threadID int, err error) {
+	message := err.Error()
+	var migrationErr *MigrationError
+	if errors.As(err, &migrationErr) {
+		message = migrationErr.Message
+	}
+	if markErr := r.tracker.MarkFailed(threadID, GetMigrationPhase(err), message); markErr != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to mark thread %d as failed in progress tracker: %v", threadID, markErr)
+	}
+}
+
+// processThread migrates a thread. Errors are MigrationErrors naming the
+// phase that failed.
 func (r *Runner) processThread(ctx context.Context, thread xenforo.Thread) error {
-	posts, err := r.fetchPosts(thread)
+	posts, err := r.fetchPosts(logging.With(ctx, "phase", "fetch"), thread)
 	if err != nil {
-		return err
+		return NewThreadMigrationError("fetch", thread.ThreadID, err.Error(), err)
 	}
 
+	ctx = logging.With(ctx, "phase", "attachments")
+	r.saveInlineImages(posts)
 	threadAttachments := r.collectAttachments(posts)
-	if err := r.downloadAttachments(thread.ThreadID, threadAttachments); err != nil {
+	if err := r.downloadAttachments(ctx, thread.ThreadID, threadAttachments); err != nil {
 		// Log warning but continue processing
-		log.Printf("✗ Warning: Failed to download attachments for thread %d: %v", thread.ThreadID, err)
+		logging.Warnf(ctx, "✗ Warning: Failed to download attachments for thread %d: %v", thread.ThreadID, err)
 	}
 
-	return r.processPosts(ctx, thread, posts, threadAttachments)
+	hostedURLs := r.uploadAttachments(ctx, thread.ThreadID, threadAttachments)
+
+	if err := r.processPosts(logging.With(ctx, "phase", "posts"), thread, posts, threadAttachments, hostedURLs); err != nil {
+		return NewThreadMigrationError("posts", thread.ThreadID, err.Error(), err)
+	}
+	return nil
 }
 
-func (r *Runner) fetchPosts(thread xenforo.Thread) ([]xenforo.Post, error) {
-	posts, err := r.xenforoClient.GetPosts(thread)
+func (r *Runner) fetchPosts(ctx context.Context, thread xenforo.Thread) ([]xenforo.Post, error) {
+	posts, err := r.xenforoSource.GetPosts(ctx, thread)
 	if err != nil {
 		return nil, err
 	}
-	log.Printf("  ✓ Found %d posts for thread", len(posts))
+	logging.Infof(ctx, "  ✓ Found %d posts for thread", len(posts))
 	return posts, nil
 }
 
+// saveInlineImages stores data URI images embedded in the posts as attachments
+// so they are linked and uploaded like regular attachments.
+func (r *Runner) saveInlineImages(posts []xenforo.Post) {
+	for i := range posts {
+		message, inline := r.downloader.SaveInlineImages(posts[i].PostID, posts[i].Message, r.config.Filesystem.MaxInlineImageSize)
+		posts[i].Message = message
+		posts[i].Attachments = append(posts[i].Attachments, inline...)
+	}
+}
+
 func (r *Runner) collectAttachments(posts []xenforo.Post) []xenforo.Attachment {
 	var threadAttachments []xenforo.Attachment
 	for _, post := range posts {
@@ -97,91 +556,812 @@ func (r *Runner) collectAttachments(posts []xenforo.Post) []xenforo.Attachment {
 	return threadAttachments
 }
 
-func (r *Runner) downloadAttachments(threadID int, attachments []xenforo.Attachment) error {
+func (r *Runner) downloadAttachments(ctx context.Context, threadID int, attachments []xenforo.Attachment) error {
 	if len(attachments) == 0 {
 		return nil
 	}
 
-	log.Printf("  ✓ Found %d attachments across all posts", len(attachments))
-	log.Printf("  Downloading attachments...")
-	return r.downloader.DownloadAttachments(attachments)
+	logging.Infof(ctx, "  ✓ Found %d attachments across all posts", len(attachments))
+	logging.Infof(ctx, "  Downloading attachments...")
+	return r.downloader.DownloadAttachments(ctx, attachments)
+}
+
+// uploadAttachments uploads the thread's attachments when an uploader is
+// configured. Upload failures fall back to local links.
+func (r *Runner) uploadAttachments(ctx context.Context, threadID int, threadAttachments []xenforo.Attachment) map[int]string {
+	if r.uploader == nil || len(threadAttachments) == 0 || r.isDryRun(ctx) {
+		return nil
+	}
+
+	logging.Infof(ctx, "  Uploading attachments...")
+	hostedURLs, err := r.uploader.UploadThreadAttachments(ctx, threadID, r.downloader.Allowed(threadAttachments), r.downloader.LocalPath)
+	if err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to upload attachments for thread %d: %v", threadID, err)
+	}
+	return hostedURLs
 }
 
-func (r *Runner) processPosts(ctx context.Context, thread xenforo.Thread, posts []xenforo.Post, threadAttachments []xenforo.Attachment) error {
-	var discussionID string
+// discussionPart identifies a discussion created for (part of) a thread.
+type discussionPart struct {
+	id     string
+	number int
+}
 
-	for j, post := range posts {
-		body, err := r.formatPost(post, thread.ThreadID, threadAttachments)
+// processPosts migrates the thread's posts. Threads longer than
+// SplitThreadPosts are split into several linked discussions. When the GitHub
+// rate limit is exhausted part-way, a checkpoint is saved and the next run
+// continues after the last written post.
+func (r *Runner) processPosts(ctx context.Context, thread xenforo.Thread, posts []xenforo.Post, threadAttachments []xenforo.Attachment, hostedURLs map[int]string) (err error) {
+	checkpoint, done, err := r.resumeCheckpoint(ctx, thread.ThreadID, posts)
+	if err != nil {
+		return err
+	}
+	defer func() { r.endCheckpoint(ctx, thread.ThreadID, checkpoint, err) }()
+
+	parts := splitPosts(posts, r.config.Migration.SplitThreadPosts)
+	if len(parts) > 1 {
+		logging.Infof(ctx, "  Splitting %d posts into %d discussions", len(posts), len(parts))
+	}
+
+	if len(checkpoint.Discussions) > 0 {
+		if done, err = r.reconcileCheckpoint(ctx, thread.ThreadID, posts, parts, checkpoint, done); err != nil {
+			return err
+		}
+	} else {
+		existing, written, err := r.existingCheckpoint(ctx, thread, parts)
+		if err != nil {
+			return err
+		}
+		if existing != nil {
+			logging.Warnf(ctx, "  ⚠ Thread %d was already migrated to discussion #%d, resuming after post %d (%d of %d posts already migrated)",
+				thread.ThreadID, existing.Discussions[0].Number, existing.LastPostID, written, len(posts))
+			checkpoint, done = existing, written
+		}
+	}
+
+	var previous *discussionPart
+	offset := 0
+	for i, part := range parts {
+		skip := min(max(done-offset, 0), len(part))
+		current, err := r.processPart(ctx, thread, posts, part, i+1, len(parts), offset, skip, previous, checkpoint, threadAttachments, hostedURLs)
+		if err == nil && previous != nil && checkpoint.LinkedParts < i {
+			if err = r.linkNextPart(ctx, previous, current, i+1); err == nil {
+				checkpoint.LinkedParts = i
+			}
+		}
 		if err != nil {
+			return r.checkpointOnRateLimit(ctx, thread.ThreadID, checkpoint, err)
+		}
+
+		previous = current
+		offset += len(part)
+	}
+
+	if err := r.lockDiscussions(ctx, thread, checkpoint); err != nil {
+		return r.checkpointOnRateLimit(ctx, thread.ThreadID, checkpoint, err)
+	}
+
+	if r.exporter != nil {
+		manifest := r.attachmentManifest(ctx, threadAttachments, hostedURLs)
+		if err := r.exporter.finish(thread.ThreadID, r.config.Migration.LockClosedThreads && thread.IsLocked(), manifest); err != nil {
 			return err
 		}
+	}
+
+	r.recordDiscussion(ctx, thread.ThreadID, checkpoint)
+	return nil
+}
+
+// recordDiscussion stores the thread's first discussion in the progress file.
+func (r *Runner) recordDiscussion(ctx context.Context, threadID int, checkpoint *progress.ThreadCheckpoint) {
+	if r.isDryRun(ctx) || len(checkpoint.Discussions) == 0 || checkpoint.Discussions[0].ID == "" {
+		return
+	}
+
+	ref := checkpoint.Discussions[0]
+	ref.URL = r.discussionURL(ref.Number)
+	ref.LastPostID = checkpoint.LastPostID
+	for _, part := range checkpoint.Discussions[1:] {
+		part.URL = r.discussionURL(part.Number)
+		ref.Parts = append(ref.Parts, part)
+	}
+	if err := r.tracker.RecordDiscussion(threadID, ref); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to record discussion for thread %d: %v", threadID, err)
+	}
+}
+
+// resumeCheckpoint returns the checkpoint of an interrupted thread and the
+// number of its posts already written. Threads without a checkpoint start
+// from an empty one.
+func (r *Runner) resumeCheckpoint(ctx context.Context, threadID int, posts []xenforo.Post) (*progress.ThreadCheckpoint, int, error) {
+	checkpoint, ok := r.tracker.Checkpoint(threadID)
+	if !ok || r.isDryRun(ctx) {
+		return &progress.ThreadCheckpoint{CommentIDs: make(map[int]string)}, 0, nil
+	}
+
+	for i, post := range posts {
+		if post.PostID == checkpoint.LastPostID {
+			logging.Infof(ctx, "  Resuming after post %d (%d of %d posts already migrated)", post.PostID, i+1, len(posts))
+			return checkpoint, i + 1, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("checkpoint post %d no longer exists in thread %d", checkpoint.LastPostID, threadID)
+}
+
+// reconcileCheckpoint catches a resumed checkpoint up with its last
+// discussion, which also holds the posts written after the checkpoint was
+// last saved when a run crashed, and returns the number of posts written.
+func (r *Runner) reconcileCheckpoint(ctx context.Context, threadID int, posts []xenforo.Post, parts [][]xenforo.Post, checkpoint *progress.ThreadCheckpoint, done int) (int, error) {
+	k := len(checkpoint.Discussions) - 1
+	ref := checkpoint.Discussions[k]
+	if r.githubClient == nil || r.isDryRun(ctx) || k >= len(parts) || ref.Number <= 0 {
+		return done, nil
+	}
+
+	content, err := r.githubClient.GetDiscussionContent(ctx, r.config.GitHub.Repository, ref.Number)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read discussion #%d of thread %d: %w", ref.Number, threadID, err)
+	}
+	offset := len(slices.Concat(parts[:k]...))
+	stored := storedPosts(content)
+	if offset+min(len(stored), len(parts[k])) <= done {
+		return done, nil
+	}
+
+	checkpoint.ResetComments()
+	written := storedComments(stored, parts[k], checkpoint)
+	if written == len(parts[k]) && hasNextPartLink(content) {
+		checkpoint.LinkedParts = max(checkpoint.LinkedParts, k+1)
+	}
+	done = offset + written
+	checkpoint.LastPostID = posts[done-1].PostID
+	logging.Warnf(ctx, "  ⚠ Discussion #%d holds posts written after the checkpoint, resuming after post %d (%d of %d posts already migrated)",
+		ref.Number, checkpoint.LastPostID, done, len(posts))
+	return done, nil
+}
+
+// checkpointSave tracks what a thread wrote since its checkpoint was saved.
+type checkpointSave struct {
+	at          time.Time // Time of the last save
+	posts       int       // Posts written since
+	discussions int       // Discussions in the saved checkpoint
+}
+
+// saveCheckpoint records that posts more posts of the thread were written,
+// and persists the checkpoint once a discussion was created, checkpointPosts
+// posts were written or checkpointInterval passed since the last save, so a
+// run interrupted mid-thread resumes into the existing discussion instead of
+// creating a duplicate. A thread stopped by an error saves the rest when it
+// ends; after a crash, resuming finds the posts written since the last save
+// in the discussion.
+func (r *Runner) saveCheckpoint(ctx context.Context, threadID int, checkpoint *progress.ThreadCheckpoint, posts int) {
+	if r.isDryRun(ctx) || len(checkpoint.Discussions) == 0 || checkpoint.Discussions[0].ID == "" {
+		return
+	}
+
+	r.checkpointMu.Lock()
+	if r.checkpointSaves == nil {
+		r.checkpointSaves = make(map[int]*checkpointSave)
+	}
+	last, ok := r.checkpointSaves[threadID]
+	if !ok {
+		last = &checkpointSave{}
+		r.checkpointSaves[threadID] = last
+	}
+	last.posts += posts
+	due := len(checkpoint.Discussions) > last.discussions || last.posts >= r.checkpointPosts || time.Since(last.at) >= r.checkpointInterval
+	if due {
+		*last = checkpointSave{at: time.Now(), discussions: len(checkpoint.Discussions)}
+	}
+	r.checkpointMu.Unlock()
+
+	if due {
+		r.writeCheckpoint(ctx, threadID, checkpoint)
+	}
+}
+
+// endCheckpoint stops tracking the checkpoint saves of a thread. A thread
+// stopped by err saves the posts written since the last save.
+func (r *Runner) endCheckpoint(ctx context.Context, threadID int, checkpoint *progress.ThreadCheckpoint, err error) {
+	r.checkpointMu.Lock()
+	last, ok := r.checkpointSaves[threadID]
+	delete(r.checkpointSaves, threadID)
+	r.checkpointMu.Unlock()
+
+	if err != nil && ok && last.posts > 0 {
+		r.writeCheckpoint(ctx, threadID, checkpoint)
+	}
+}
+
+func (r *Runner) writeCheckpoint(ctx context.Context, threadID int, checkpoint *progress.ThreadCheckpoint) {
+	if err := r.tracker.SaveCheckpoint(threadID, checkpoint); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to save checkpoint for thread %d: %v", threadID, err)
+	}
+}
+
+// checkpointOnRateLimit saves the thread's checkpoint when err reports an
+// exhausted GitHub rate limit, so the thread is resumed rather than recreated.
+// The error is returned unchanged.
+func (r *Runner) checkpointOnRateLimit(ctx context.Context, threadID int, checkpoint *progress.ThreadCheckpoint, err error) error {
+	if !errors.Is(err, github.ErrRateLimitExhausted) || len(checkpoint.Discussions) == 0 || r.isDryRun(ctx) {
+		return err
+	}
+
+	r.checkpointMu.Lock()
+	delete(r.checkpointSaves, threadID)
+	r.checkpointMu.Unlock()
+
+	if saveErr := r.tracker.SaveCheckpoint(threadID, checkpoint); saveErr != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to save checkpoint for thread %d: %v", threadID, saveErr)
+		return err
+	}
+	logging.Warnf(ctx, "  ⚠ Saved checkpoint for thread %d after post %d", threadID, checkpoint.LastPostID)
+	return err
+}
+
+// splitPosts divides posts into consecutive parts of at most size posts.
+// A size of zero or less keeps all posts in a single part.
+func splitPosts(posts []xenforo.Post, size int) [][]xenforo.Post {
+	if size <= 0 || len(posts) <= size {
+		return [][]xenforo.Post{posts}
+	}
+
+	var parts [][]xenforo.Post
+	for start := 0; start < len(posts); start += size {
+		parts = append(parts, posts[start:min(start+size, len(posts))])
+	}
+	return parts
+}
+
+// processPart creates one discussion from the first post of part and adds the
+// remaining posts as comments, skipping the first skip posts written by an
+// earlier run. allPosts is the complete thread, used for the top reply
+// callout on the first part, and part starts at its post offset. Progress is recorded in checkpoint after every
+// post.
+func (r *Runner) processPart(ctx context.Context, thread xenforo.Thread, allPosts, part []xenforo.Post, number, total, offset, skip int, previous *discussionPart, checkpoint *progress.ThreadCheckpoint, threadAttachments []xenforo.Attachment, hostedURLs map[int]string) (*discussionPart, error) {
+	current := &discussionPart{}
+	if skip > 0 {
+		if len(checkpoint.Discussions) < number {
+			return nil, fmt.Errorf("checkpoint has no discussion for part %d", number)
+		}
+		current.id, current.number = checkpoint.Discussions[number-1].ID, checkpoint.Discussions[number-1].Number
+	}
+
+	var batch []pendingComment
+	for j := skip; j < len(part); j++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		post := part[j]
+		ctx := logging.With(ctx, "post_id", post.PostID)
+		body, err := r.formatPost(ctx, post, thread.ThreadID, threadAttachments, hostedURLs)
+		if err != nil {
+			return nil, err
+		}
 
 		if j == 0 {
-			discussionID, _, err = r.createDiscussion(ctx, thread, body)
+			if number == 1 {
+				body = r.withThreadSummary(thread, allPosts, body, threadAttachments, hostedURLs)
+			}
+
+			title := r.partTitle(thread, number, total)
+			r.exporter.addDiscussion(thread.ThreadID, number, ExportedDiscussion{
+				PostID:     post.PostID,
+				Title:      title,
+				CategoryID: r.discussionCategory(thread),
+				Body:       body,
+			})
+
+			if previous != nil {
+				body = r.continuedFrom(number, previous.number) + body
+			}
+			r.writeDryRunDiscussion(ctx, thread.ThreadID, number, title, body)
+
+			current.id, current.number, err = r.createDiscussion(ctx, title, body, r.discussionCategory(thread))
 			if err != nil {
-				return err
+				return nil, err
 			}
+			checkpoint.Discussions = append(checkpoint.Discussions, progress.DiscussionRef{ID: current.id, Number: current.number})
+			r.labelDiscussion(ctx, thread, current.id)
+			r.addReactions(ctx, post, current.id)
+			checkpoint.ResetComments()
 		} else {
-			if err := r.addComment(ctx, post, discussionID, body); err != nil {
-				log.Printf("✗ Failed to add comment: %v", err)
+			// A reply to a comment of the pending batch needs that comment's ID
+			if quotesPending(post, batch) {
+				if err := r.flushComments(ctx, thread.ThreadID, current.id, batch, checkpoint); err != nil {
+					return nil, err
+				}
+				batch = nil
+			}
+
+			// Only top-level comments can be marked as the answer
+			comment := pendingComment{post: post, body: body}
+			comment.solution = r.config.Migration.MarkSolutions && post.PostID == thread.TypeData.SolutionPostID
+			if !comment.solution {
+				comment.replyToID = replyTarget(post, checkpoint.CommentIDs)
+			}
+			r.writeDryRunComment(ctx, thread.ThreadID, offset+j, body)
+			r.exporter.addComment(thread.ThreadID, post, body, comment.solution)
+
+			if r.batchComments(ctx) {
+				if len(body) <= maxBodyLength {
+					batch = append(batch, comment)
+					if len(batch) == r.config.GitHub.CommentBatchSize || j == len(part)-1 {
+						if err := r.flushComments(ctx, thread.ThreadID, current.id, batch, checkpoint); err != nil {
+							return nil, err
+						}
+						batch = nil
+					}
+					continue
+				}
+
+				// Long posts are split over several comments, which follow
+				// the pending batch so they stay in order.
+				if err := r.flushComments(ctx, thread.ThreadID, current.id, batch, checkpoint); err != nil {
+					return nil, err
+				}
+				batch = nil
+			}
+
+			commentID, addErr := r.addComment(ctx, post, current.id, comment.replyToID, body)
+			if err := r.commentAdded(ctx, current.id, comment, commentID, addErr, checkpoint); err != nil {
+				return nil, err
 			}
 		}
+		checkpoint.LastPostID = post.PostID
+		r.saveCheckpoint(ctx, thread.ThreadID, checkpoint, 1)
+
+		if !r.isDryRun(ctx) {
+			time.Sleep(r.postDelay)
+		}
+	}
+
+	return current, nil
+}
+
+// withThreadSummary adds the top reply callout and the thread stats to the
+// body of a thread's opening post.
+func (r *Runner) withThreadSummary(thread xenforo.Thread, allPosts []xenforo.Post, body string, threadAttachments []xenforo.Attachment, hostedURLs map[int]string) string {
+	if callout := r.topReplyCallout(allPosts, threadAttachments, hostedURLs); callout != "" {
+		body = callout + "\n" + body
+	}
+	if stats := r.threadStats(thread); stats != "" {
+		body = body + "\n\n" + stats
+	}
+	return body
+}
+
+// partTitle returns the discussion title of part number of a thread split
+// into total parts. The title is normalized and shortened to GitHub's limit,
+// keeping the part suffix; threads without a title are named by their ID.
+func (r *Runner) partTitle(thread xenforo.Thread, number, total int) string {
+	title := normalizeTitle(r.discussionTitle(thread))
+	if title == "" {
+		title = fmt.Sprintf("Thread %d", thread.ThreadID)
+	}
+
+	suffix := ""
+	if total > 1 {
+		suffix = fmt.Sprintf(" (Part %d)", number)
+	}
+	return truncateTitle(title, maxTitleLength-len(suffix)) + suffix
+}
+
+// continuedFrom returns the line opening part number of a split thread, which
+// links the previous part's discussion.
+func (r *Runner) continuedFrom(number, previousNumber int) string {
+	return fmt.Sprintf("**Continued from [Part %d](%s)**\n\n", number-1, r.discussionURL(previousNumber))
+}
+
+// markAnswer marks the comment of a question's solution post as the answer of
+// its discussion. Failures, e.g. in categories without answers, are logged;
+// the comment itself is already migrated.
+func (r *Runner) markAnswer(ctx context.Context, post xenforo.Post, commentID string) {
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would mark the comment by %s as the answer", post.Username)
+		return
+	}
+
+	if commentID == "" {
+		return
+	}
+
+	if err := r.githubClient.MarkCommentAsAnswer(ctx, commentID); err != nil {
+		logging.Warnf(ctx, "  ⚠ Failed to mark the comment by %s as the answer: %v", post.Username, err)
+		return
+	}
+	logging.Infof(ctx, "  ✓ Marked the comment by %s as the answer", post.Username)
+}
 
-		if !r.config.Migration.DryRun {
-			time.Sleep(1 * time.Second)
+// lockDiscussions locks the discussions of a thread closed on the forum, once
+// all of its posts are migrated. Only an exhausted rate limit is returned;
+// other failures are logged.
+func (r *Runner) lockDiscussions(ctx context.Context, thread xenforo.Thread, checkpoint *progress.ThreadCheckpoint) error {
+	if !r.config.Migration.LockClosedThreads || !thread.IsLocked() {
+		return nil
+	}
+
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would lock the discussion of closed thread %d", thread.ThreadID)
+		return nil
+	}
+	return r.lockCheckpointDiscussions(ctx, checkpoint)
+}
+
+// lockCheckpointDiscussions locks every discussion recorded in checkpoint.
+// Only an exhausted rate limit is returned; other failures are logged.
+func (r *Runner) lockCheckpointDiscussions(ctx context.Context, checkpoint *progress.ThreadCheckpoint) error {
+	for _, discussion := range checkpoint.Discussions {
+		if discussion.ID == "" {
+			continue
 		}
+		if err := r.githubClient.LockDiscussion(ctx, discussion.ID); err != nil {
+			if errors.Is(err, github.ErrRateLimitExhausted) {
+				return err
+			}
+			logging.Warnf(ctx, "  ⚠ Failed to lock discussion #%d: %v", discussion.Number, err)
+			continue
+		}
+		logging.Infof(ctx, "  ✓ Locked discussion #%d", discussion.Number)
+	}
+	return nil
+}
+
+// nextPartLinkPrefix starts the comment linking a part of a split thread to
+// the next part.
+const nextPartLinkPrefix = "**Continued in [Part "
+
+// linkNextPart adds a closing comment to previous pointing at the next part.
+// Only an exhausted rate limit is returned; other failures are logged.
+func (r *Runner) linkNextPart(ctx context.Context, previous, next *discussionPart, nextNumber int) error {
+	body := fmt.Sprintf("%s%d](%s)**", nextPartLinkPrefix, nextNumber, r.discussionURL(next.number))
+
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would link part %d to part %d", nextNumber-1, nextNumber)
+		return nil
+	}
+
+	if previous.id == "" {
+		return nil
 	}
 
+	if err := r.githubClient.AddComment(ctx, previous.id, body); err != nil {
+		if errors.Is(err, github.ErrRateLimitExhausted) {
+			return err
+		}
+		logging.Errorf(ctx, "✗ Failed to link part %d to part %d: %v", nextNumber-1, nextNumber, err)
+	}
 	return nil
 }
 
-func (r *Runner) formatPost(post xenforo.Post, threadID int, threadAttachments []xenforo.Attachment) (string, error) {
-	markdown := r.processor.ProcessContent(post.Message)
-	markdown = r.downloader.ReplaceAttachmentLinks(markdown, threadAttachments)
+// discussionURL returns the web URL of a discussion in the target repository.
+func (r *Runner) discussionURL(number int) string {
+	return fmt.Sprintf("https://github.com/%s/discussions/%d", r.config.GitHub.Repository, number)
+}
 
-	body, err := r.processor.FormatMessage(post.Username, post.PostDate, threadID, markdown)
+func (r *Runner) formatPost(ctx context.Context, post xenforo.Post, threadID int, threadAttachments []xenforo.Attachment, hostedURLs map[int]string) (string, error) {
+	markdown, err := r.processor.ProcessContentContext(ctx, post.Message)
 	if err != nil {
-		log.Printf("  Error formatting message for post by %s: %v", post.Username, err)
+		logging.Warnf(ctx, "  ⚠ Post %d: %v, using best-effort conversion", post.PostID, err)
+	}
+	markdown = r.downloader.ReplaceAttachmentLinksWithURLs(markdown, threadAttachments, hostedURLs)
+
+	avatarURL := ""
+	if r.avatars != nil {
+		avatarURL = r.avatars.Avatar(ctx, post.UserID)
+	}
+
+	author := bbcode.Author{
+		Username:    post.Username,
+		AvatarURL:   avatarURL,
+		GitHubLogin: r.config.Migration.UserMapping[post.UserID],
+	}
+	body, err := r.processor.FormatMessageWithAuthor(author, post.PostDate, threadID, markdown)
+	if err != nil {
+		logging.Errorf(ctx, "  Error formatting message for post by %s: %v", post.Username, err)
 		return "", fmt.Errorf("failed to format message: %w", err)
 	}
-	return body, nil
+	body = r.withPostAnchor(post, r.withReactionSummary(post, body))
+	return r.processor.WithMetadata(bbcode.PostMetadata{
+		ThreadID: threadID,
+		PostID:   post.PostID,
+		AuthorID: post.UserID,
+		Author:   post.Username,
+		PostDate: post.PostDate,
+	}, body), nil
 }
 
-func (r *Runner) createDiscussion(ctx context.Context, thread xenforo.Thread, body string) (string, int, error) {
-	categoryID := r.config.GitHub.GitHubCategoryID
+// withPostAnchor prepends the original post ID anchor when PostAnchors is
+// enabled. The blank line keeps the anchor from turning the following
+// metadata separator into a heading.
+func (r *Runner) withPostAnchor(post xenforo.Post, body string) string {
+	if !r.config.Migration.PostAnchors || post.PostID <= 0 {
+		return body
+	}
+	return r.processor.FormatPostAnchor(post.PostID) + "\n\n" + body
+}
 
-	if r.config.Migration.DryRun {
-		log.Printf("  [DRY-RUN] Would create discussion: %s", thread.Title)
-		if r.config.Migration.Verbose {
-			log.Printf("\n--- Discussion Body Preview ---\n%s\n--- End Preview ---\n", body)
+// threadStats renders the stats line for the opening post, or an empty string
+// when the option is disabled.
+func (r *Runner) threadStats(thread xenforo.Thread) string {
+	if !r.config.Migration.ThreadStats {
+		return ""
+	}
+
+	template := r.config.Migration.ThreadStatsTemplate
+	if strings.TrimSpace(template) == "" {
+		template = config.DefaultThreadStatsTemplate
+	}
+	return r.processor.FormatThreadStats(template, thread.PostDate, thread.ReplyCount, thread.ViewCount)
+}
+
+// topReplyCallout renders the most-reacted reply as a callout for the opening
+// post. Returns an empty string when the option is disabled or no reply has reactions.
+func (r *Runner) topReplyCallout(posts []xenforo.Post, threadAttachments []xenforo.Attachment, hostedURLs map[int]string) string {
+	if !r.config.Migration.TopReplyCallout {
+		return ""
+	}
+
+	top, ok := selectTopReply(posts)
+	if !ok {
+		return ""
+	}
+
+	markdown := r.processor.ProcessContent(top.Message)
+	markdown = r.downloader.ReplaceAttachmentLinksWithURLs(markdown, threadAttachments, hostedURLs)
+	if strings.TrimSpace(markdown) == "" {
+		return ""
+	}
+
+	return r.processor.FormatTopReplyCallout(top.Username, top.ReactionScore, markdown)
+}
+
+// selectTopReply returns the reply (excluding the opening post) with the highest
+// positive reaction score. Ties go to the earliest reply.
+func selectTopReply(posts []xenforo.Post) (xenforo.Post, bool) {
+	var top xenforo.Post
+	found := false
+
+	for _, post := range posts[min(1, len(posts)):] {
+		if post.ReactionScore > 0 && (!found || post.ReactionScore > top.ReactionScore) {
+			top = post
+			found = true
 		}
+	}
+
+	return top, found
+}
+
+// createDiscussion creates a discussion. A body over GitHub's limit is split,
+// its continuations following as the first comments.
+func (r *Runner) createDiscussion(ctx context.Context, title, body, categoryID string) (string, int, error) {
+	parts := splitBody(body, maxBodyLength)
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would create discussion: %s", title)
+		logging.Debugf(ctx, "\n--- Discussion Body Preview ---\n%s\n--- End Preview ---\n", body)
+		r.addContinuations(ctx, "", "", parts[1:])
 		return "", 0, nil
 	}
 
-	result, err := r.githubClient.CreateDiscussion(ctx, thread.Title, body, categoryID)
+	result, err := r.githubClient.CreateDiscussion(ctx, title, parts[0], categoryID)
 	if err != nil {
 		return "", 0, err
 	}
-	log.Printf("✓ Created discussion #%d", result.Number)
+	logging.Infof(ctx, "✓ Created discussion #%d", result.Number)
+	r.addContinuations(ctx, result.ID, "", parts[1:])
 	return result.ID, result.Number, nil
 }
 
-func (r *Runner) addComment(ctx context.Context, post xenforo.Post, discussionID, body string) error {
-	if r.config.Migration.DryRun {
-		log.Printf("  [DRY-RUN] Would add comment by %s", post.Username)
-		if r.config.Migration.Verbose {
-			log.Printf("\n--- Comment Preview ---\n%s\n--- End Preview ---\n", body)
+// discussionCategory returns the category mapped to the thread's prefix,
+// matched by prefix title (case-insensitive) or prefix ID. Threads without a
+// mapped prefix use the configured category.
+func (r *Runner) discussionCategory(thread xenforo.Thread) string {
+	if categoryID, ok := prefixMapping(thread, r.config.GitHub.PrefixCategoryMap); ok {
+		return categoryID
+	}
+	return r.config.GitHub.GitHubCategoryID
+}
+
+// prefixMapping returns the value mapped to the thread's prefix ID or prefix
+// title (case-insensitive). The ID takes precedence over the title, and an
+// exact title over titles differing in case, which are tried in sorted order
+// so the value never depends on map iteration order.
+func prefixMapping(thread xenforo.Thread, mapping map[string]string) (string, bool) {
+	if thread.PrefixID > 0 {
+		if value, ok := mapping[strconv.Itoa(thread.PrefixID)]; ok {
+			return value, true
 		}
-		return nil
 	}
+	prefixTitle := strings.TrimSpace(thread.Prefix)
+	if prefixTitle == "" {
+		return "", false
+	}
+	if value, ok := mapping[prefixTitle]; ok {
+		return value, true
+	}
+	for _, prefix := range slices.Sorted(maps.Keys(mapping)) {
+		if strings.EqualFold(prefix, prefixTitle) {
+			return mapping[prefix], true
+		}
+	}
+	return "", false
+}
 
-	if discussionID == "" {
+// discussionTitle returns the thread title with the prefix configured for the
+// thread's source node. Unmapped nodes get no prefix.
+func (r *Runner) discussionTitle(thread xenforo.Thread) string {
+	prefix := strings.TrimSpace(r.config.GitHub.NodeTitlePrefix[thread.NodeID])
+	if prefix == "" {
+		return thread.Title
+	}
+	return prefix + " " + thread.Title
+}
+
+// replyTarget returns the comment a post should be threaded under: the first
+// quoted post that has already been migrated as a comment. Posts quoting
+// nothing known (including the opening post) become top-level comments.
+func replyTarget(post xenforo.Post, commentIDs map[int]string) string {
+	for _, quotedID := range bbcode.QuotedPostIDs(post.Message) {
+		if commentID, ok := commentIDs[quotedID]; ok {
+			return commentID
+		}
+	}
+	return ""
+}
+
+// pendingComment is a comment of a post waiting to be added, possibly in a
+// batch.
+type pendingComment struct {
+	post      xenforo.Post
+	body      string
+	replyToID string // Comment the post replies to (empty = top-level)
+	solution  bool   // Mark the comment as the answer
+}
+
+// batchComments reports whether comments are added in batches of
+// CommentBatchSize.
+func (r *Runner) batchComments(ctx context.Context) bool {
+	return r.config.GitHub.CommentBatchSize > 1 && !r.isDryRun(ctx)
+}
+
+// quotesPending reports whether post quotes a post of the pending batch.
+func quotesPending(post xenforo.Post, batch []pendingComment) bool {
+	for _, quotedID := range bbcode.QuotedPostIDs(post.Message) {
+		for _, comment := range batch {
+			if comment.post.PostID == quotedID {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// flushComments adds the pending comments in a single request, completes each
+// of them like an individually added comment, and checkpoints them. When
+// GitHub refuses the request, the comments are added one at a time instead.
+// Another failed request may have created part of the batch and is returned,
+// like the errors commentAdded returns; the checkpoint then ends at the last
+// completed comment.
+func (r *Runner) flushComments(ctx context.Context, threadID int, discussionID string, batch []pendingComment, checkpoint *progress.ThreadCheckpoint) error {
+	if len(batch) == 0 {
 		return nil
 	}
 
-	if err := r.githubClient.AddComment(ctx, discussionID, body); err != nil {
+	inputs := make([]github.CommentInput, len(batch))
+	for i, comment := range batch {
+		inputs[i] = github.CommentInput{DiscussionID: discussionID, ReplyToID: comment.replyToID, Body: comment.body}
+	}
+	results, err := r.githubClient.AddComments(ctx, inputs)
+	if errors.Is(err, github.ErrRateLimitExhausted) {
+		return err
+	}
+	if err != nil && !errors.Is(err, github.ErrCommentsRefused) {
+		// The thread stops at its checkpoint and resumes after the comments
+		// its discussion holds.
+		return fmt.Errorf("failed to add %d comments: %w", len(batch), err)
+	}
+	if err != nil {
+		logging.Warnf(ctx, "⚠ GitHub refused %d comments at once, adding them one at a time: %v", len(batch), err)
+	}
+
+	for i, comment := range batch {
+		ctx := logging.With(ctx, "post_id", comment.post.PostID)
+		var commentID string
+		var commentErr error
+		if err != nil {
+			commentID, commentErr = r.addComment(ctx, comment.post, discussionID, comment.replyToID, comment.body)
+		} else {
+			commentID, commentErr = results[i].ID, results[i].Err
+			if commentErr == nil {
+				logCommentAdded(ctx, comment.post, comment.replyToID)
+			}
+		}
+		if err := r.commentAdded(ctx, discussionID, comment, commentID, commentErr, checkpoint); err != nil {
+			r.saveCheckpoint(ctx, threadID, checkpoint, i)
+			return err
+		}
+		checkpoint.LastPostID = comment.post.PostID
+	}
+
+	r.saveCheckpoint(ctx, threadID, checkpoint, len(batch))
+	time.Sleep(r.postDelay)
+	return nil
+}
+
+// commentAdded completes a comment once GitHub answered: a failed reply is
+// retried as a top-level comment, then reactions, the answer and the comment
+// ID used to thread later replies are recorded. Only an exhausted rate limit
+// or a cancelled ctx is returned, as they stop the thread before the post;
+// other failures are logged.
+func (r *Runner) commentAdded(ctx context.Context, discussionID string, comment pendingComment, commentID string, err error, checkpoint *progress.ThreadCheckpoint) error {
+	if err != nil && (errors.Is(err, github.ErrRateLimitExhausted) || ctx.Err() != nil) {
 		return err
 	}
-	log.Printf("  ✓ Added comment by %s", post.Username)
+	if err != nil && comment.replyToID != "" {
+		logging.Warnf(ctx, "  ⚠ Failed to thread reply by %s, posting as a top-level comment: %v", comment.post.Username, err)
+		comment.replyToID = ""
+		commentID, err = r.addComment(ctx, comment.post, discussionID, "", comment.body)
+		if err != nil && (errors.Is(err, github.ErrRateLimitExhausted) || ctx.Err() != nil) {
+			return err
+		}
+	}
+	if err != nil {
+		logging.Errorf(ctx, "✗ Failed to add comment: %v", err)
+		return nil
+	}
+
+	r.addReactions(ctx, comment.post, commentID)
+	if comment.solution {
+		r.markAnswer(ctx, comment.post, commentID)
+	}
+	if commentID != "" {
+		// Discussions nest one level deep, so replies map to their parent.
+		if comment.replyToID != "" {
+			commentID = comment.replyToID
+		}
+		checkpoint.AddComment(comment.post.PostID, commentID)
+	}
 	return nil
 }
+
+func logCommentAdded(ctx context.Context, post xenforo.Post, replyToID string) {
+	if replyToID != "" {
+		logging.Infof(ctx, "  ✓ Added reply by %s", post.Username)
+	} else {
+		logging.Infof(ctx, "  ✓ Added comment by %s", post.Username)
+	}
+}
+
+// addComment adds a post as a comment, or as a reply to replyToID. A body
+// over GitHub's limit is split, its continuations following as replies.
+func (r *Runner) addComment(ctx context.Context, post xenforo.Post, discussionID, replyToID, body string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	parts := splitBody(body, maxBodyLength)
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "  [DRY-RUN] Would add comment by %s", post.Username)
+		logging.Debugf(ctx, "\n--- Comment Preview ---\n%s\n--- End Preview ---\n", body)
+		r.addContinuations(ctx, "", "", parts[1:])
+		return "", nil
+	}
+
+	if discussionID == "" {
+		return "", nil
+	}
+
+	commentID, err := r.githubClient.AddCommentReply(ctx, discussionID, replyToID, parts[0])
+	if err != nil {
+		return "", err
+	}
+	logCommentAdded(ctx, post, replyToID)
+
+	// Discussions nest one level deep, so continuations of a reply are
+	// replies to the same comment.
+	parent := replyToID
+	if parent == "" {
+		parent = commentID
+	}
+	r.addContinuations(ctx, discussionID, parent, parts[1:])
+	return commentID, nil
+}
@@ -0,0 +1,2003 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/attachments"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/concurrency"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/notify"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/testutil"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// testForum is a minimal XenForo API served over httptest.
+type testForum struct {
+	threads     []xenforo.Thread
+	threadsPage int          // Threads per listing page (0 = a single page)
+	failPage    int          // Listing page returning a server error (0 = none)
+	pagesServed []int        // Listing pages requested
+	avatars     map[int]bool // Users with an avatar
+	usersServed int32
+	avatarsSent int32
+	posts       map[int][]xenforo.Post
+	failPosts   map[int]bool // Threads whose posts request returns a server error
+	flakyPosts  map[int]int  // Threads whose posts request fails this many times before succeeding
+	postsDelay  time.Duration
+	postsProbe  testutil.ConcurrencyProbe // Posts requests in flight
+	postsServed int32
+}
+
+func (f *testForum) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch {
+	case strings.HasSuffix(r.URL.Path, "/threads") && strings.Contains(r.URL.Path, "/forums/"):
+		page, _ := strconv.Atoi(r.URL.Query().Get("page"))
+		f.pagesServed = append(f.pagesServed, page)
+		if page == f.failPage {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"errors":[{"message":"server error"}]}`))
+			return
+		}
+		resp := xenforo.ThreadsResponse{Threads: f.threads}
+		resp.Pagination.CurrentPage = 1
+		resp.Pagination.TotalPages = 1
+		if f.threadsPage > 0 {
+			start := min((page-1)*f.threadsPage, len(f.threads))
+			resp.Threads = f.threads[start:min(start+f.threadsPage, len(f.threads))]
+			resp.Pagination.CurrentPage = page
+			resp.Pagination.TotalPages = (len(f.threads) + f.threadsPage - 1) / f.threadsPage
+		}
+		_ = json.NewEncoder(w).Encode(resp)
+	case strings.HasSuffix(r.URL.Path, "/posts"):
+		atomic.AddInt32(&f.postsServed, 1)
+		defer f.postsProbe.Enter()()
+		time.Sleep(f.postsDelay)
+
+		var threadID int
+		_, _ = fmt.Sscanf(r.URL.Path, "/threads/%d/posts", &threadID)
+		if f.flakyPosts[threadID] > 0 {
+			f.flakyPosts[threadID]--
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"errors":[{"message":"server error"}]}`))
+			return
+		}
+		if f.failPosts[threadID] {
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"errors":[{"message":"server error"}]}`))
+			return
+		}
+		resp := xenforo.PostsResponse{Posts: f.posts[threadID]}
+		resp.Pagination.CurrentPage = 1
+		resp.Pagination.TotalPages = 1
+		_ = json.NewEncoder(w).Encode(resp)
+	case strings.HasPrefix(r.URL.Path, "/threads/"):
+		var threadID int
+		_, _ = fmt.Sscanf(r.URL.Path, "/threads/%d", &threadID)
+		for _, thread := range f.threads {
+			if thread.ThreadID == threadID {
+				_ = json.NewEncoder(w).Encode(xenforo.ThreadResponse{Thread: thread})
+				return
+			}
+		}
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = w.Write([]byte(`{"errors":[{"message":"not found"}]}`))
+	case strings.HasPrefix(r.URL.Path, "/users/"):
+		atomic.AddInt32(&f.usersServed, 1)
+		var userID int
+		_, _ = fmt.Sscanf(r.URL.Path, "/users/%d", &userID)
+		user := xenforo.User{UserID: userID}
+		if f.avatars[userID] {
+			user.AvatarURLs = map[string]string{"s": fmt.Sprintf("http://%s/avatars/%d.png", r.Host, userID)}
+		}
+		_ = json.NewEncoder(w).Encode(xenforo.UserResponse{User: user})
+	case strings.HasPrefix(r.URL.Path, "/avatars/"):
+		atomic.AddInt32(&f.avatarsSent, 1)
+		_, _ = w.Write([]byte("\x89PNG avatar"))
+	default:
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(`{}`))
+	}
+}
+
+// newTestForum creates a forum with the given number of single-post threads.
+func newTestForum(threadCount int) *testForum {
+	forum := &testForum{posts: make(map[int][]xenforo.Post)}
+	for id := 1; id <= threadCount; id++ {
+		forum.threads = append(forum.threads, xenforo.Thread{
+			ThreadID: id,
+			Title:    fmt.Sprintf("Thread %d", id),
+			NodeID:   1,
+			Username: "author",
+			PostDate: 1640000000,
+		})
+		forum.posts[id] = []xenforo.Post{{
+			PostID:   id * 10,
+			ThreadID: id,
+			Username: "author",
+			PostDate: 1640000000,
+			Message:  fmt.Sprintf("Post in thread %d", id),
+		}}
+	}
+	return forum
+}
+
+// newTestRunner builds a dry-run Runner backed by the given test forum.
+func newTestRunner(t *testing.T, forum http.Handler, mutate func(cfg *config.Config)) (*Runner, *progress.Tracker) {
+	t.Helper()
+
+	server := httptest.NewServer(forum)
+	t.Cleanup(server.Close)
+
+	cfg := &config.Config{
+		XenForo: config.XenForoConfig{APIURL: server.URL, APIKey: "test_key", APIUser: "1", NodeID: 1},
+		GitHub: config.GitHubConfig{
+			Repository:       "test/repo",
+			XenForoNodeID:    1,
+			GitHubCategoryID: "DIC_kwDOtest123",
+		},
+		Migration: config.MigrationConfig{
+			MaxRetries:   1,
+			DryRun:       true,
+			ProgressFile: filepath.Join(t.TempDir(), "progress.json"),
+		},
+		Filesystem: config.FilesystemConfig{AttachmentsDir: t.TempDir()},
+	}
+	if mutate != nil {
+		mutate(cfg)
+	}
+
+	tracker, err := progress.NewTracker(cfg.Migration.ProgressFile, cfg.Migration.DryRun)
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+
+	xenforoClient := xenforo.NewClient(cfg.XenForo.APIURL, cfg.XenForo.APIKey, cfg.XenForo.APIUser, cfg.Migration.MaxRetries)
+	downloader := attachments.NewDownloader(cfg.Filesystem.AttachmentsDir, cfg.Migration.DryRun, xenforoClient, 0)
+	limiter := concurrency.NewSemaphore(cfg.Migration.MaxConcurrency)
+
+	return NewRunner(cfg, xenforoClient, nil, tracker, downloader).SetLimiter(limiter), tracker
+}
+
+func TestRunner_ThreadWorkers(t *testing.T) {
+	tests := []struct {
+		name          string
+		workers       int
+		maxConcurrent int
+		expected      int32
+	}{
+		{name: "Sequential by default", workers: 0, expected: 1},
+		{name: "Parallel thread workers", workers: 3, expected: 3},
+		{name: "Bounded by global limiter", workers: 4, maxConcurrent: 2, expected: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forum := newTestForum(6)
+			forum.postsDelay = 30 * time.Millisecond
+
+			runner, tracker := newTestRunner(t, forum, func(cfg *config.Config) {
+				cfg.Migration.MigrationConcurrency = tt.workers
+				cfg.Migration.MaxConcurrency = tt.maxConcurrent
+			})
+
+			if err := runner.RunMigration(context.Background()); err != nil {
+				t.Fatalf("RunMigration returned error: %v", err)
+			}
+
+			if forum.postsProbe.Max() != tt.expected {
+				t.Errorf("Expected %d threads in flight, got %d", tt.expected, forum.postsProbe.Max())
+			}
+
+			if completed := len(tracker.GetProgress().CompletedThreads); completed != 6 {
+				t.Errorf("Expected 6 completed threads, got %d", completed)
+			}
+		})
+	}
+}
+
+func TestSelectTopReply(t *testing.T) {
+	tests := []struct {
+		name     string
+		posts    []xenforo.Post
+		expected int
+		found    bool
+	}{
+		{
+			name: "Most-reacted reply wins",
+			posts: []xenforo.Post{
+				{PostID: 1, ReactionScore: 50},
+				{PostID: 2, ReactionScore: 3},
+				{PostID: 3, ReactionScore: 7},
+			},
+			expected: 3,
+			found:    true,
+		},
+		{
+			name: "Ties go to the earliest reply",
+			posts: []xenforo.Post{
+				{PostID: 1},
+				{PostID: 2, ReactionScore: 4},
+				{PostID: 3, ReactionScore: 4},
+			},
+			expected: 2,
+			found:    true,
+		},
+		{
+			name: "No reactions on replies",
+			posts: []xenforo.Post{
+				{PostID: 1, ReactionScore: 10},
+				{PostID: 2},
+			},
+			found: false,
+		},
+		{
+			name:  "No posts",
+			posts: nil,
+			found: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			top, found := selectTopReply(tt.posts)
+			if found != tt.found {
+				t.Fatalf("Expected found=%v, got %v", tt.found, found)
+			}
+			if found && top.PostID != tt.expected {
+				t.Errorf("Expected post %d, got %d", tt.expected, top.PostID)
+			}
+		})
+	}
+}
+
+func TestRunner_TopReplyCallout(t *testing.T) {
+	posts := []xenforo.Post{
+		{PostID: 1, Username: "author", Message: "Question"},
+		{PostID: 2, Username: "helper", Message: "[b]Try this[/b]", ReactionScore: 5},
+	}
+
+	disabled, _ := newTestRunner(t, newTestForum(0), nil)
+	if callout := disabled.topReplyCallout(posts, nil, nil); callout != "" {
+		t.Errorf("Expected no callout when option is disabled, got %q", callout)
+	}
+
+	enabled, _ := newTestRunner(t, newTestForum(0), func(cfg *config.Config) {
+		cfg.Migration.TopReplyCallout = true
+	})
+	expected := "> [!TIP]\n> **Top reply** by **helper** (5 reactions)\n>\n> **Try this**\n"
+	if callout := enabled.topReplyCallout(posts, nil, nil); callout != expected {
+		t.Errorf("Expected %q, got %q", expected, callout)
+	}
+
+	if callout := enabled.topReplyCallout(posts[:1], nil, nil); callout != "" {
+		t.Errorf("Expected no callout without reactions, got %q", callout)
+	}
+}
+
+func TestRunner_DiscussionTitle(t *testing.T) {
+	runner, _ := newTestRunner(t, newTestForum(0), func(cfg *config.Config) {
+		cfg.GitHub.NodeTitlePrefix = map[int]string{
+			2: "[General]",
+			5: "[Support] ",
+		}
+	})
+
+	tests := []struct {
+		name     string
+		nodeID   int
+		expected string
+	}{
+		{name: "General node", nodeID: 2, expected: "[General] How do I install?"},
+		{name: "Support node with trailing space", nodeID: 5, expected: "[Support] How do I install?"},
+		{name: "Unmapped node", nodeID: 7, expected: "How do I install?"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thread := xenforo.Thread{ThreadID: 1, Title: "How do I install?", NodeID: tt.nodeID}
+			if title := runner.discussionTitle(thread); title != tt.expected {
+				t.Errorf("Expected title %q, got %q", tt.expected, title)
+			}
+		})
+	}
+}
+
+func TestRunner_FormatPostOptions(t *testing.T) {
+	tests := []struct {
+		name     string
+		mutate   func(cfg *config.Config)
+		post     xenforo.Post
+		contains string
+		excludes string
+	}{
+		{
+			name:     "No anchor by default",
+			post:     xenforo.Post{PostID: 456, Username: "helper", PostDate: 1640000100, Message: "Answer"},
+			excludes: "<a id=",
+		},
+		{
+			name:     "Anchor for the original post ID",
+			mutate:   func(cfg *config.Config) { cfg.Migration.PostAnchors = true },
+			post:     xenforo.Post{PostID: 455, Username: "author", PostDate: 1640000000, Message: "Question"},
+			contains: "<a id=\"post-455\"></a>\n\n---\n",
+		},
+		{
+			name:     "Mapped user mentioned",
+			mutate:   func(cfg *config.Config) { cfg.Migration.UserMapping = map[int]string{12: "octocat"} },
+			post:     xenforo.Post{PostID: 1, UserID: 12, Username: "alice", PostDate: 1640000000, Message: "Hi"},
+			contains: "Author: **alice** (@octocat)\n",
+		},
+		{
+			name:     "Unmapped user not mentioned",
+			mutate:   func(cfg *config.Config) { cfg.Migration.UserMapping = map[int]string{12: "octocat"} },
+			post:     xenforo.Post{PostID: 2, UserID: 13, Username: "bob", PostDate: 1640000000, Message: "Hi"},
+			excludes: "(@",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runner, _ := newTestRunner(t, newTestForum(0), tt.mutate)
+			body, err := runner.formatPost(context.Background(), tt.post, 1, nil, nil)
+			if err != nil {
+				t.Fatalf("formatPost returned error: %v", err)
+			}
+			if tt.contains != "" && !strings.Contains(body, tt.contains) {
+				t.Errorf("Expected body to contain %q, got %q", tt.contains, body)
+			}
+			if tt.excludes != "" && strings.Contains(body, tt.excludes) {
+				t.Errorf("Expected body without %q, got %q", tt.excludes, body)
+			}
+		})
+	}
+}
+
+func TestRunner_FailureThreshold(t *testing.T) {
+	t.Run("Exceeding the early failure rate switches to dry-run", func(t *testing.T) {
+		forum := newTestForum(6)
+		forum.failPosts = map[int]bool{1: true, 2: true, 3: true, 4: true}
+
+		runner, tracker := newTestRunner(t, forum, func(cfg *config.Config) {
+			cfg.Migration.DryRun = false
+			cfg.Migration.FailureThresholdThreads = 4
+			cfg.Migration.FailureThresholdPercent = 50
+		})
+
+		if err := runner.RunMigration(context.Background()); err != nil {
+			t.Fatalf("RunMigration returned error: %v", err)
+		}
+
+		if !runner.isDryRun(context.Background()) {
+			t.Error("Expected run to switch to dry-run after exceeding the failure threshold")
+		}
+
+		p := tracker.GetProgress()
+		if len(p.FailedThreads) != 4 {
+			t.Errorf("Expected 4 failed threads, got %v", p.FailedThreads)
+		}
+		if len(p.CompletedThreads) != 0 {
+			t.Errorf("Threads processed in safety dry-run must stay pending, got completed %v", p.CompletedThreads)
+		}
+	})
+
+	t.Run("Failure rate within threshold keeps writing", func(t *testing.T) {
+		forum := newTestForum(4)
+		forum.failPosts = map[int]bool{1: true, 2: true, 3: true, 4: true}
+
+		runner, tracker := newTestRunner(t, forum, func(cfg *config.Config) {
+			cfg.Migration.DryRun = false
+			cfg.Migration.FailureThresholdThreads = 4
+			cfg.Migration.FailureThresholdPercent = 100
+		})
+
+		if err := runner.RunMigration(context.Background()); err != nil {
+			t.Fatalf("RunMigration returned error: %v", err)
+		}
+
+		if runner.isDryRun(context.Background()) {
+			t.Error("Expected run to keep writing when the failure rate is within the threshold")
+		}
+		if failed := len(tracker.GetProgress().FailedThreads); failed != 4 {
+			t.Errorf("Expected 4 failed threads, got %d", failed)
+		}
+	})
+
+	t.Run("Threads in flight finish writing", func(t *testing.T) {
+		forum := newTestForum(1)
+		forum.threads[0].ReplyCount = 2
+		forum.posts[1] = []xenforo.Post{
+			{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question"},
+			{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First"},
+			{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Second"},
+		}
+
+		api := &fakeDiscussionsAPI{}
+		runner := newWritingTestRunner(t, forum, api, nil)
+		// The threshold trips in another worker while the thread is written
+		api.beforeComment = func() { atomic.StoreInt32(&runner.safetyDryRun, 1) }
+
+		if err := runner.RunMigration(context.Background()); err != nil {
+			t.Fatalf("RunMigration returned error: %v", err)
+		}
+
+		if len(api.comments) != 2 {
+			t.Errorf("Expected both comments written, got %d", len(api.comments))
+		}
+		if !runner.tracker.IsCompleted(1) {
+			t.Error("Expected the thread in flight to be marked completed")
+		}
+		if ref, ok := runner.tracker.Discussion(1); !ok || ref.ID != "D_1" {
+			t.Errorf("Expected discussion D_1 recorded for the thread, got %+v", ref)
+		}
+	})
+}
+
+func TestRunner_ThreadStats(t *testing.T) {
+	thread := xenforo.Thread{ThreadID: 1, Title: "Stats", PostDate: 1554120000, ReplyCount: 42, ViewCount: 1234}
+
+	disabled, _ := newTestRunner(t, newTestForum(0), nil)
+	if stats := disabled.threadStats(thread); stats != "" {
+		t.Errorf("Expected no stats line when option is disabled, got %q", stats)
+	}
+
+	enabled, _ := newTestRunner(t, newTestForum(0), func(cfg *config.Config) {
+		cfg.Migration.ThreadStats = true
+	})
+	expected := "Originally posted 2019-04-01 · 42 replies · 1.2k views"
+	if stats := enabled.threadStats(thread); stats != expected {
+		t.Errorf("Expected %q, got %q", expected, stats)
+	}
+}
+
+func TestRunner_PauseFile(t *testing.T) {
+	pauseFile := filepath.Join(t.TempDir(), "migration.pause")
+	if err := os.WriteFile(pauseFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	forum := newTestForum(3)
+	runner, tracker := newTestRunner(t, forum, func(cfg *config.Config) {
+		cfg.Migration.PauseFile = pauseFile
+	})
+	runner.pausePoll = 5 * time.Millisecond
+
+	done := make(chan error, 1)
+	go func() { done <- runner.RunMigration(context.Background()) }()
+
+	time.Sleep(50 * time.Millisecond)
+	if served := atomic.LoadInt32(&forum.postsServed); served != 0 {
+		t.Fatalf("Expected no threads processed while paused, got %d", served)
+	}
+
+	if err := os.Remove(pauseFile); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunMigration returned error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Migration did not resume after the pause file was removed")
+	}
+
+	if completed := len(tracker.GetProgress().CompletedThreads); completed != 3 {
+		t.Errorf("Expected 3 completed threads after resuming, got %d", completed)
+	}
+}
+
+func TestRunner_PauseFileRespectsCancellation(t *testing.T) {
+	pauseFile := filepath.Join(t.TempDir(), "migration.pause")
+	if err := os.WriteFile(pauseFile, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	forum := newTestForum(2)
+	runner, tracker := newTestRunner(t, forum, func(cfg *config.Config) {
+		cfg.Migration.PauseFile = pauseFile
+	})
+	runner.pausePoll = 5 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	if err := runner.RunMigration(ctx); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	if served := atomic.LoadInt32(&forum.postsServed); served != 0 {
+		t.Errorf("Expected no threads processed after cancellation while paused, got %d", served)
+	}
+	if completed := len(tracker.GetProgress().CompletedThreads); completed != 0 {
+		t.Errorf("Expected no completed threads, got %d", completed)
+	}
+}
+
+func TestRunner_WebhookSummary(t *testing.T) {
+	var received notify.Summary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	forum := newTestForum(3)
+	forum.failPosts = map[int]bool{2: true}
+	runner, _ := newTestRunner(t, forum, nil)
+	runner.SetNotifier(notify.NewWebhookNotifier(server.URL))
+
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	if received.Repository != "test/repo" || received.NodeID != 1 || !received.DryRun {
+		t.Errorf("Unexpected summary metadata: %+v", received)
+	}
+	if received.ThreadsTotal != 3 || received.ThreadsMigrated != 2 || received.ThreadsFailed != 1 {
+		t.Errorf("Unexpected summary counts: %+v", received)
+	}
+	if !strings.Contains(received.Text, "2 of 3 threads migrated, 1 failed (dry-run)") {
+		t.Errorf("Unexpected summary text: %q", received.Text)
+	}
+}
+
+func TestRunner_AutoRetryFailed(t *testing.T) {
+	var received notify.Summary
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer server.Close()
+
+	forum := newTestForum(3)
+	forum.flakyPosts = map[int]int{2: 1}
+	forum.failPosts = map[int]bool{3: true}
+	runner, tracker := newTestRunner(t, forum, func(cfg *config.Config) {
+		cfg.Migration.AutoRetryFailed = true
+		cfg.Migration.AutoRetryWait = 10 * time.Millisecond
+	})
+	runner.SetNotifier(notify.NewWebhookNotifier(server.URL))
+
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	state := tracker.GetProgress()
+	if !reflect.DeepEqual(state.CompletedThreads, []int{1, 2}) {
+		t.Errorf("Expected threads 1 and 2 completed, got %v", state.CompletedThreads)
+	}
+	if !reflect.DeepEqual(state.FailedThreads, []int{3}) {
+		t.Errorf("Expected only thread 3 failed, got %v", state.FailedThreads)
+	}
+	if received.ThreadsMigrated != 2 || received.ThreadsFailed != 1 {
+		t.Errorf("Expected 2 migrated and 1 failed thread in the summary, got %+v", received)
+	}
+}
+
+func TestRunner_RetryFailed(t *testing.T) {
+	forum := newTestForum(4)
+	forum.failPosts = map[int]bool{3: true}
+	runner, tracker := newTestRunner(t, forum, func(cfg *config.Config) {
+		cfg.Migration.RetryFailed = true
+	})
+	for _, threadID := range []int{2, 3, 9} {
+		if err := tracker.MarkFailed(threadID, "posts", "earlier failure"); err != nil {
+			t.Fatalf("Failed to seed failed thread %d: %v", threadID, err)
+		}
+	}
+
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	state := tracker.GetProgress()
+	if !reflect.DeepEqual(state.CompletedThreads, []int{2}) {
+		t.Errorf("Expected only thread 2 migrated, got %v", state.CompletedThreads)
+	}
+	if !reflect.DeepEqual(state.FailedThreads, []int{3, 9}) {
+		t.Errorf("Expected threads 3 and 9 still failed, got %v", state.FailedThreads)
+	}
+	if _, ok := state.Failures[2]; ok {
+		t.Errorf("Expected the error of thread 2 to be cleared, got %+v", state.Failures[2])
+	}
+	if failure := state.Failures[3]; failure.Phase != "fetch" || failure.Attempts != 2 || failure.Error == "earlier failure" || strings.HasPrefix(failure.Error, "migration error") {
+		t.Errorf("Expected the fetch error of the retry recorded for thread 3, got %+v", failure)
+	}
+	if failure := state.Failures[9]; failure.Error != "earlier failure" {
+		t.Errorf("Expected the error of unlisted thread 9 kept, got %q", failure.Error)
+	}
+}
+
+func TestReplyTarget(t *testing.T) {
+	commentIDs := map[int]string{11: "C_11", 12: "C_11"}
+
+	tests := []struct {
+		name     string
+		message  string
+		expected string
+	}{
+		{name: "Quote of a migrated comment", message: `[QUOTE="alice, post: 11, member: 2"]Hi[/QUOTE] Reply`, expected: "C_11"},
+		{name: "Quote of a nested reply maps to its parent", message: `[quote="bob, post: 12, member: 3"]Hi[/quote]`, expected: "C_11"},
+		{name: "Quote of an unknown post", message: `[QUOTE="carol, post: 99, member: 4"]Hi[/QUOTE]`, expected: ""},
+		{name: "First known quote wins", message: `[QUOTE="x, post: 99"]a[/QUOTE][QUOTE="y, post: 12"]b[/QUOTE]`, expected: "C_11"},
+		{name: "Quote without post reference", message: `[QUOTE="alice"]Hi[/QUOTE]`, expected: ""},
+		{name: "No quote", message: "Plain reply", expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			post := xenforo.Post{PostID: 20, Message: tt.message}
+			if target := replyTarget(post, commentIDs); target != tt.expected {
+				t.Errorf("Expected reply target %q, got %q", tt.expected, target)
+			}
+		})
+	}
+}
+
+func TestPrefixMapping(t *testing.T) {
+	mapping := map[string]string{"BUG": "upper", "Bug": "exact", "bug": "lower", "7": "id", "idea": "idea"}
+
+	tests := []struct {
+		name     string
+		thread   xenforo.Thread
+		expected string
+	}{
+		{name: "Prefix ID before title", thread: xenforo.Thread{Prefix: "Bug", PrefixID: 7}, expected: "id"},
+		{name: "Exact title before other cases", thread: xenforo.Thread{Prefix: "Bug"}, expected: "exact"},
+		{name: "Case-insensitive titles in sorted order", thread: xenforo.Thread{Prefix: "bUG"}, expected: "upper"},
+		{name: "Unmapped ID falls back to the title", thread: xenforo.Thread{Prefix: "Idea", PrefixID: 9}, expected: "idea"},
+		{name: "Unmapped prefix", thread: xenforo.Thread{Prefix: "Other", PrefixID: 9}, expected: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Map iteration order must not matter
+			for range 20 {
+				if value, _ := prefixMapping(tt.thread, mapping); value != tt.expected {
+					t.Fatalf("Expected %q, got %q", tt.expected, value)
+				}
+			}
+		})
+	}
+}
+
+// fakeDiscussionsAPI is a minimal GitHub GraphQL API recording created
+// discussions and comments. Discussion searches return every discussion.
+type fakeDiscussionsAPI struct {
+	mu             sync.Mutex
+	discussions    []fakeDiscussion
+	comments       []fakeComment
+	answers        []string            // Comments marked as the answer
+	labels         []string            // Repository labels; label n has ID L_n
+	labeled        map[string][]string // Label IDs added per discussion
+	locked         []string            // Locked discussions
+	reactions      []string            // Added reactions as "subject:content"
+	deleted        []string            // Deleted discussions
+	rateLimitAfter int                 // Writes accepted before every request is rate limited (0 = unlimited)
+	beforeComment  func()              // Called before each comment is added
+	batches        []int               // Comments per batched request
+	batchStatus    int                 // Status answering batched requests when set, e.g. a server error
+	rejectBody     string              // Comments containing it are rejected
+}
+
+type fakeDiscussion struct {
+	ID         string
+	Number     int
+	Title      string
+	Body       string
+	CategoryID string
+}
+
+type fakeComment struct {
+	ID           string
+	DiscussionID string
+	ReplyToID    string
+	Body         string
+}
+
+func (f *fakeDiscussionsAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Query     string `json:"query"`
+		Variables struct {
+			Input  map[string]interface{} `json:"input"`
+			Label  string                 `json:"label"`
+			Number int                    `json:"number"`
+		} `json:"variables"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	_ = json.Unmarshal(body, &req)
+	input := func(key string) string {
+		value, _ := req.Variables.Input[key].(string)
+		return value
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+
+	if f.rateLimitAfter > 0 && len(f.discussions)+len(f.comments) >= f.rateLimitAfter {
+		_, _ = fmt.Fprint(w, `{"errors":[{"type":"RATE_LIMITED","message":"API rate limit exceeded for user ID 1."}]}`)
+		return
+	}
+
+	if strings.Contains(req.Query, "search(") {
+		nodes := make([]map[string]interface{}, 0, len(f.discussions))
+		for _, discussion := range f.discussions {
+			nodes = append(nodes, map[string]interface{}{
+				"id": discussion.ID, "number": discussion.Number, "title": discussion.Title, "body": discussion.Body,
+				"category": map[string]interface{}{"id": discussion.CategoryID},
+			})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"search": map[string]interface{}{"nodes": nodes}}})
+		return
+	}
+
+	if strings.Contains(req.Query, "discussion(number:") {
+		var discussion map[string]interface{}
+		for _, d := range f.discussions {
+			if d.Number != req.Variables.Number {
+				continue
+			}
+			createdAt := func(i int) string { return time.Unix(1640000000+int64(i), 0).UTC().Format(time.RFC3339) }
+			nodes := []map[string]interface{}{}
+			for i, comment := range f.comments {
+				if comment.DiscussionID != d.ID || comment.ReplyToID != "" {
+					continue
+				}
+				replies := []map[string]interface{}{}
+				for j, reply := range f.comments {
+					if reply.ReplyToID == comment.ID {
+						replies = append(replies, map[string]interface{}{"id": reply.ID, "body": reply.Body, "createdAt": createdAt(j)})
+					}
+				}
+				nodes = append(nodes, map[string]interface{}{"id": comment.ID, "body": comment.Body, "createdAt": createdAt(i), "replies": map[string]interface{}{"nodes": replies}})
+			}
+			discussion = map[string]interface{}{
+				"id": d.ID, "title": d.Title, "body": d.Body,
+				"comments": map[string]interface{}{"nodes": nodes, "pageInfo": map[string]interface{}{"hasNextPage": false, "endCursor": ""}},
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": map[string]interface{}{"repository": map[string]interface{}{"discussion": discussion}}})
+		return
+	}
+
+	if strings.Contains(req.Query, "createDiscussion") {
+		number := len(f.discussions) + 1
+		discussion := fakeDiscussion{ID: fmt.Sprintf("D_%d", number), Number: number, Title: input("title"), Body: input("body"), CategoryID: input("categoryId")}
+		f.discussions = append(f.discussions, discussion)
+		_, _ = fmt.Fprintf(w, `{"data":{"createDiscussion":{"discussion":{"id":%q,"number":%d}}}}`, discussion.ID, discussion.Number)
+		return
+	}
+
+	if strings.Contains(req.Query, "label(name:") {
+		label := "null"
+		for i, name := range f.labels {
+			if name == req.Variables.Label {
+				label = fmt.Sprintf(`{"id":"L_%d"}`, i+1)
+			}
+		}
+		_, _ = fmt.Fprintf(w, `{"data":{"repository":{"label":%s}}}`, label)
+		return
+	}
+
+	if strings.Contains(req.Query, "createLabel") {
+		f.labels = append(f.labels, input("name"))
+		_, _ = fmt.Fprintf(w, `{"data":{"createLabel":{"label":{"id":"L_%d"}}}}`, len(f.labels))
+		return
+	}
+
+	if strings.Contains(req.Query, "addLabelsToLabelable") {
+		if f.labeled == nil {
+			f.labeled = make(map[string][]string)
+		}
+		ids, _ := req.Variables.Input["labelIds"].([]interface{})
+		for _, id := range ids {
+			f.labeled[input("labelableId")] = append(f.labeled[input("labelableId")], fmt.Sprint(id))
+		}
+		_, _ = fmt.Fprint(w, `{"data":{"addLabelsToLabelable":{"__typename":"AddLabelsToLabelablePayload"}}}`)
+		return
+	}
+
+	if strings.Contains(req.Query, "addReaction") {
+		f.reactions = append(f.reactions, input("subjectId")+":"+input("content"))
+		_, _ = fmt.Fprintf(w, `{"data":{"addReaction":{"reaction":{"content":%q}}}}`, input("content"))
+		return
+	}
+
+	if strings.Contains(req.Query, "lockLockable") {
+		f.locked = append(f.locked, input("lockableId"))
+		_, _ = fmt.Fprint(w, `{"data":{"lockLockable":{"lockedRecord":{"locked":true}}}}`)
+		return
+	}
+
+	if strings.Contains(req.Query, "deleteDiscussion") {
+		f.deleted = append(f.deleted, input("id"))
+		_, _ = fmt.Fprintf(w, `{"data":{"deleteDiscussion":{"discussion":{"id":%q}}}}`, input("id"))
+		return
+	}
+
+	if strings.Contains(req.Query, "updateDiscussionComment") {
+		for i := range f.comments {
+			if f.comments[i].ID == input("commentId") {
+				f.comments[i].Body = input("body")
+			}
+		}
+		_, _ = fmt.Fprintf(w, `{"data":{"updateDiscussionComment":{"comment":{"id":%q}}}}`, input("commentId"))
+		return
+	}
+
+	if strings.Contains(req.Query, "updateDiscussion") {
+		for i := range f.discussions {
+			if f.discussions[i].ID == input("discussionId") {
+				f.discussions[i].Body = input("body")
+				if title := input("title"); title != "" {
+					f.discussions[i].Title = title
+				}
+			}
+		}
+		_, _ = fmt.Fprintf(w, `{"data":{"updateDiscussion":{"discussion":{"id":%q}}}}`, input("discussionId"))
+		return
+	}
+
+	if strings.Contains(req.Query, "markDiscussionCommentAsAnswer") {
+		f.answers = append(f.answers, input("id"))
+		_, _ = fmt.Fprint(w, `{"data":{"markDiscussionCommentAsAnswer":{"discussion":{"id":"D_1"}}}}`)
+		return
+	}
+
+	if strings.Contains(req.Query, "c0: addDiscussionComment") {
+		var batch struct {
+			Variables map[string]map[string]interface{} `json:"variables"`
+		}
+		_ = json.Unmarshal(body, &batch)
+		if f.batchStatus != 0 {
+			f.batches = append(f.batches, len(batch.Variables))
+			http.Error(w, http.StatusText(f.batchStatus), f.batchStatus)
+			return
+		}
+		data := make(map[string]interface{})
+		for i := 0; i < len(batch.Variables); i++ {
+			alias := fmt.Sprintf("c%d", i)
+			discussionID, _ := batch.Variables[alias]["discussionId"].(string)
+			replyToID, _ := batch.Variables[alias]["replyToId"].(string)
+			commentBody, _ := batch.Variables[alias]["body"].(string)
+			comment := fakeComment{ID: fmt.Sprintf("C_%d", len(f.comments)+1), DiscussionID: discussionID, ReplyToID: replyToID, Body: commentBody}
+			f.comments = append(f.comments, comment)
+			data[alias] = map[string]interface{}{"comment": map[string]interface{}{"id": comment.ID}}
+		}
+		f.batches = append(f.batches, len(batch.Variables))
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"data": data})
+		return
+	}
+
+	if f.beforeComment != nil {
+		f.beforeComment()
+	}
+	if f.rejectBody != "" && strings.Contains(input("body"), f.rejectBody) {
+		_, _ = fmt.Fprint(w, `{"errors":[{"type":"UNPROCESSABLE","message":"Body is invalid"}]}`)
+		return
+	}
+	comment := fakeComment{
+		ID:           fmt.Sprintf("C_%d", len(f.comments)+1),
+		DiscussionID: input("discussionId"),
+		ReplyToID:    input("replyToId"),
+		Body:         input("body"),
+	}
+	f.comments = append(f.comments, comment)
+	_, _ = fmt.Fprintf(w, `{"data":{"addDiscussionComment":{"comment":{"id":%q}}}}`, comment.ID)
+}
+
+// newWritingTestRunner builds a non-dry-run Runner that writes to a fake GitHub
+// API. Rate limits are not retried.
+func newWritingTestRunner(t *testing.T, forum http.Handler, api *fakeDiscussionsAPI, mutate func(cfg *config.Config)) *Runner {
+	t.Helper()
+
+	server := httptest.NewServer(api)
+	t.Cleanup(server.Close)
+
+	githubClient, err := github.NewClient(github.TokenAuth("test_github_token_for_testing_only"), 0, 0, 1)
+	if err != nil {
+		t.Fatalf("Failed to create GitHub client: %v", err)
+	}
+	githubClient.SetGraphQLURL(server.URL)
+
+	runner, _ := newTestRunner(t, forum, func(cfg *config.Config) {
+		cfg.Migration.DryRun = false
+		if mutate != nil {
+			mutate(cfg)
+		}
+	})
+	runner.githubClient = githubClient
+	runner.postDelay = 0
+
+	return runner
+}
+
+func TestRunner_ReplyThreading(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 4
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: `[QUOTE="author, post: 10, member: 1"]Question[/QUOTE] Answer`},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: `[QUOTE="alice, post: 11, member: 2"]Answer[/QUOTE] Thanks`},
+		{PostID: 13, ThreadID: 1, Username: "carol", PostDate: 1640000300, Message: `[QUOTE="bob, post: 12, member: 3"]Thanks[/QUOTE] Same here`},
+		{PostID: 14, ThreadID: 1, Username: "dave", PostDate: 1640000400, Message: `[QUOTE="ghost, post: 5, member: 9"]Old[/QUOTE] Unknown`},
+	}
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, nil)
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	expected := []string{
+		"",    // Quotes the opening post: top-level
+		"C_1", // Quotes alice's comment
+		"C_1", // Quotes bob's reply: nests under its parent
+		"",    // Quoted post was not migrated: top-level
+	}
+	if len(api.comments) != len(expected) {
+		t.Fatalf("Expected %d comments, got %+v", len(expected), api.comments)
+	}
+	for i, target := range expected {
+		if api.comments[i].ReplyToID != target {
+			t.Errorf("Comment %s: expected replyToId %q, got %q", api.comments[i].ID, target, api.comments[i].ReplyToID)
+		}
+	}
+}
+
+func TestRunner_CommentBatching(t *testing.T) {
+	forum := newTestForum(2)
+	forum.threads[0].ReplyCount = 4
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: `[QUOTE="author, post: 10, member: 1"]Question[/QUOTE] Answer`},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: `[QUOTE="alice, post: 11, member: 2"]Answer[/QUOTE] Thanks`},
+		{PostID: 13, ThreadID: 1, Username: "carol", PostDate: 1640000300, Message: "Same here"},
+		{PostID: 14, ThreadID: 1, Username: "dave", PostDate: 1640000400, Message: "Me too"},
+	}
+	forum.threads[1].ReplyCount = 5
+	for i := 1; i <= 5; i++ {
+		forum.posts[2] = append(forum.posts[2], xenforo.Post{PostID: 20 + i, ThreadID: 2, Username: "user", PostDate: 1640000000 + int64(i), Message: fmt.Sprintf("Reply %d", i)})
+	}
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+		cfg.GitHub.CommentBatchSize = 3
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	// Bob's reply waits for alice's comment, the rest fill batches of three
+	expectedBatches := []int{1, 3, 3, 2}
+	if !reflect.DeepEqual(api.batches, expectedBatches) {
+		t.Errorf("Expected batches %v, got %v", expectedBatches, api.batches)
+	}
+	if len(api.comments) != 9 {
+		t.Fatalf("Expected 9 comments, got %+v", api.comments)
+	}
+	if api.comments[1].ReplyToID != "C_1" {
+		t.Errorf("Expected bob's reply threaded under C_1, got %q", api.comments[1].ReplyToID)
+	}
+	for i, comment := range api.comments[4:] {
+		if expected := fmt.Sprintf("Reply %d", i+1); !strings.Contains(comment.Body, expected) {
+			t.Errorf("Expected comment %s to contain %q, got %q", comment.ID, expected, comment.Body)
+		}
+	}
+
+	if len(runner.tracker.GetProgress().Checkpoints) != 0 {
+		t.Error("Expected no checkpoints left after completed threads")
+	}
+}
+
+func TestRunner_BatchCommentsFailure(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 3
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First"},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Second"},
+		{PostID: 13, ThreadID: 1, Username: "carol", PostDate: 1640000300, Message: "Third"},
+	}
+
+	// A refused batch is not sent again: its comments are added one at a time
+	api := &fakeDiscussionsAPI{batchStatus: http.StatusUnprocessableEntity}
+	mutate := func(cfg *config.Config) {
+		cfg.GitHub.CommentBatchSize = 3
+	}
+	runner := newWritingTestRunner(t, forum, api, mutate)
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(api.batches, []int{3}) {
+		t.Errorf("Expected a single batch of 3, got %v", api.batches)
+	}
+	if len(api.comments) != 3 {
+		t.Fatalf("Expected 3 comments, got %+v", api.comments)
+	}
+	for i, expected := range []string{"First", "Second", "Third"} {
+		if !strings.Contains(api.comments[i].Body, expected) {
+			t.Errorf("Expected comment %d to contain %q, got %q", i, expected, api.comments[i].Body)
+		}
+	}
+	if !runner.tracker.IsCompleted(1) {
+		t.Error("Expected thread 1 to be completed")
+	}
+
+	// A batch that may have been created fails the thread at its checkpoint
+	api = &fakeDiscussionsAPI{batchStatus: http.StatusBadGateway}
+	runner = newWritingTestRunner(t, forum, api, mutate)
+	_ = runner.RunMigration(context.Background())
+
+	if len(api.comments) != 0 || runner.tracker.IsCompleted(1) {
+		t.Errorf("Expected thread 1 to fail without adding comments, got %+v", api.comments)
+	}
+	if checkpoint, ok := runner.tracker.Checkpoint(1); !ok || checkpoint.LastPostID != 10 {
+		t.Errorf("Expected the checkpoint kept after post 10, got %+v", checkpoint)
+	}
+}
+
+func TestRunner_MarkSolutions(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 2
+	forum.threads[0].TypeData.SolutionPostID = 12
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "Guess"},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: `[QUOTE="alice, post: 11, member: 2"]Guess[/QUOTE] Solution`},
+	}
+
+	for _, enabled := range []bool{true, false} {
+		api := &fakeDiscussionsAPI{}
+		runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+			cfg.Migration.MarkSolutions = enabled
+		})
+		if err := runner.RunMigration(context.Background()); err != nil {
+			t.Fatalf("RunMigration returned error: %v", err)
+		}
+		if len(api.comments) != 2 {
+			t.Fatalf("Expected 2 comments, got %+v", api.comments)
+		}
+
+		if !enabled {
+			if len(api.answers) != 0 || api.comments[1].ReplyToID != "C_1" {
+				t.Errorf("Expected a threaded reply without answer when disabled, got %+v, answers %v", api.comments[1], api.answers)
+			}
+			continue
+		}
+		// The solution is posted top-level so it can be marked
+		if api.comments[1].ReplyToID != "" {
+			t.Errorf("Expected the solution as a top-level comment, got replyToId %q", api.comments[1].ReplyToID)
+		}
+		if len(api.answers) != 1 || api.answers[0] != "C_2" {
+			t.Errorf("Expected C_2 marked as the answer, got %v", api.answers)
+		}
+	}
+}
+
+func TestRunner_ThreadMetadata(t *testing.T) {
+	closed, open := false, true
+
+	tests := []struct {
+		name   string
+		labels []string // Labels existing in the repository
+		mutate func(cfg *config.Config)
+		check  func(t *testing.T, api *fakeDiscussionsAPI)
+	}{
+		{
+			name:   "Prefix labels",
+			labels: []string{"bug"},
+			mutate: func(cfg *config.Config) {
+				cfg.GitHub.PrefixLabelMap = map[string]string{"bug": "bug", "7": "idea", "Idea": "idea"}
+			},
+			check: func(t *testing.T, api *fakeDiscussionsAPI) {
+				// The existing label is reused and the missing one created once
+				if len(api.labels) != 2 || api.labels[1] != "idea" {
+					t.Fatalf("Expected the idea label to be created once, got %v", api.labels)
+				}
+				expected := map[string][]string{"D_1": {"L_1"}, "D_2": {"L_2"}, "D_3": {"L_2"}}
+				if !reflect.DeepEqual(api.labeled, expected) {
+					t.Errorf("Expected labels %v, got %v", expected, api.labeled)
+				}
+			},
+		},
+		{
+			name: "Prefix categories",
+			mutate: func(cfg *config.Config) {
+				cfg.GitHub.PrefixCategoryMap = map[string]string{"bug": "DIC_bugs", "7": "DIC_ideas"}
+			},
+			check: func(t *testing.T, api *fakeDiscussionsAPI) {
+				expected := []string{
+					"DIC_bugs",        // Prefix title, matched case-insensitively
+					"DIC_ideas",       // Prefix ID
+					"DIC_kwDOtest123", // Unmapped prefix uses the default category
+					"DIC_kwDOtest123", // No prefix
+				}
+				for i, discussion := range api.discussions {
+					if discussion.CategoryID != expected[i] {
+						t.Errorf("%s: expected category %q, got %q", discussion.Title, expected[i], discussion.CategoryID)
+					}
+				}
+			},
+		},
+		{
+			name:   "Closed threads locked",
+			mutate: func(cfg *config.Config) { cfg.Migration.LockClosedThreads = true },
+			check: func(t *testing.T, api *fakeDiscussionsAPI) {
+				if !reflect.DeepEqual(api.locked, []string{"D_2"}) {
+					t.Errorf("Expected only D_2 to be locked, got %v", api.locked)
+				}
+			},
+		},
+		{
+			name: "Closed threads left unlocked by default",
+			check: func(t *testing.T, api *fakeDiscussionsAPI) {
+				if len(api.locked) != 0 {
+					t.Errorf("Expected no locked discussions, got %v", api.locked)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			forum := newTestForum(4)
+			forum.threads[0].Prefix = "Bug"
+			forum.threads[1].PrefixID = 7
+			forum.threads[1].DiscussionOpen = &closed
+			forum.threads[2].Prefix = "idea"
+			forum.threads[3].DiscussionOpen = &open
+
+			api := &fakeDiscussionsAPI{labels: tt.labels}
+			runner := newWritingTestRunner(t, forum, api, tt.mutate)
+			if err := runner.RunMigration(context.Background()); err != nil {
+				t.Fatalf("RunMigration returned error: %v", err)
+			}
+			if len(api.discussions) != 4 {
+				t.Fatalf("Expected 4 discussions, got %d", len(api.discussions))
+			}
+			tt.check(t, api)
+		})
+	}
+}
+
+func TestRunner_Reactions(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 1
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question", Reactions: xenforo.ReactionCounts{2: 3, 1: 12, 7: 1}},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "Answer", Reactions: xenforo.ReactionCounts{3: 1}},
+	}
+
+	t.Run("summary", func(t *testing.T) {
+		api := &fakeDiscussionsAPI{}
+		runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+			cfg.Migration.ReactionsMode = config.ReactionsSummary
+			cfg.Migration.ReactionEmoji = map[int]string{7: "🎉"}
+		})
+		if err := runner.RunMigration(context.Background()); err != nil {
+			t.Fatalf("RunMigration returned error: %v", err)
+		}
+
+		if !strings.HasSuffix(api.discussions[0].Body, "\n\n👍 12 · ❤️ 3 · 🎉 1") {
+			t.Errorf("Expected a reaction summary in the discussion, got %q", api.discussions[0].Body)
+		}
+		if !strings.HasSuffix(api.comments[0].Body, "\n\n😂 1") {
+			t.Errorf("Expected a reaction summary in the comment, got %q", api.comments[0].Body)
+		}
+		if len(api.reactions) != 0 {
+			t.Errorf("Expected no GitHub reactions in summary mode, got %v", api.reactions)
+		}
+	})
+
+	t.Run("github", func(t *testing.T) {
+		api := &fakeDiscussionsAPI{}
+		runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+			cfg.Migration.ReactionsMode = config.ReactionsGitHub
+		})
+		if err := runner.RunMigration(context.Background()); err != nil {
+			t.Fatalf("RunMigration returned error: %v", err)
+		}
+
+		// The custom reaction has no GitHub equivalent
+		expected := []string{"D_1:THUMBS_UP", "D_1:HEART", "C_1:LAUGH"}
+		if !reflect.DeepEqual(api.reactions, expected) {
+			t.Errorf("Expected reactions %v, got %v", expected, api.reactions)
+		}
+		if strings.Contains(api.discussions[0].Body, "👍") {
+			t.Errorf("Expected no reaction summary in github mode, got %q", api.discussions[0].Body)
+		}
+	})
+}
+
+func TestRunner_DryRunOutput(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 2
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "[b]Question[/b]"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First answer"},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Second answer"},
+	}
+
+	dir := t.TempDir()
+	runner, _ := newTestRunner(t, forum, func(cfg *config.Config) {
+		cfg.Migration.DryRunOutput = dir
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	expected := map[string]string{
+		"discussion.md": "# Thread 1\n\n",
+		"comment-1.md":  "First answer",
+		"comment-2.md":  "Second answer",
+	}
+	for name, content := range expected {
+		data, err := os.ReadFile(filepath.Join(dir, "1", name))
+		if err != nil {
+			t.Fatalf("Expected %s to be written: %v", name, err)
+		}
+		if !strings.Contains(string(data), content) {
+			t.Errorf("Expected %s to contain %q, got %q", name, content, data)
+		}
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "1", "discussion.md")); !strings.Contains(string(data), "**Question**") {
+		t.Errorf("Expected the converted opening post, got %q", data)
+	}
+}
+
+func TestRunner_DryRunOutputSplitThread(t *testing.T) {
+	forum := newTestForum(1)
+	forum.posts[1] = nil
+	for i := range 4 {
+		forum.posts[1] = append(forum.posts[1], xenforo.Post{PostID: 10 + i, ThreadID: 1, Username: "alice", PostDate: 1640000000, Message: fmt.Sprintf("Post %d", i)})
+	}
+	forum.threads[0].ReplyCount = 3
+
+	dir := t.TempDir()
+	runner, _ := newTestRunner(t, forum, func(cfg *config.Config) {
+		cfg.Migration.DryRunOutput = dir
+		cfg.Migration.SplitThreadPosts = 2
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	// Comments are numbered by their position in the whole thread
+	for name, content := range map[string]string{"comment-1.md": "Post 1", "discussion-part-2.md": "Post 2", "comment-3.md": "Post 3"} {
+		data, err := os.ReadFile(filepath.Join(dir, "1", name))
+		if err != nil || !strings.Contains(string(data), content) {
+			t.Errorf("Expected %s to contain %q, got %q (%v)", name, content, data, err)
+		}
+	}
+}
+
+func TestRunner_ExportOnlyUpload(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 4
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "Answer"},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: `[QUOTE="alice, post: 11, member: 2"]Answer[/QUOTE] Thanks`},
+		{PostID: 13, ThreadID: 1, Username: "carol", PostDate: 1640000300, Message: "Later"},
+		{PostID: 14, ThreadID: 1, Username: "dave", PostDate: 1640000400, Message: `[QUOTE="alice, post: 11, member: 2"]Answer[/QUOTE] Across parts`},
+	}
+
+	dir := t.TempDir()
+	exporter, err := NewThreadExporter(dir)
+	if err != nil {
+		t.Fatalf("Failed to create exporter: %v", err)
+	}
+	runner, _ := newTestRunner(t, forum, func(cfg *config.Config) {
+		cfg.Migration.SplitThreadPosts = 3
+	})
+	runner.SetExporter(exporter)
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+	if err := exporter.Close(); err != nil {
+		t.Fatalf("Failed to close exporter: %v", err)
+	}
+	if exporter.Count() != 1 {
+		t.Fatalf("Expected 1 exported thread, got %d", exporter.Count())
+	}
+
+	api := &fakeDiscussionsAPI{}
+	uploader := newWritingTestRunner(t, forum, api, nil)
+	uploaded, err := uploader.UploadExport(context.Background(), filepath.Join(dir, ExportFileName))
+	if err != nil || uploaded != 1 {
+		t.Fatalf("Expected 1 uploaded thread, got %d (err: %v)", uploaded, err)
+	}
+
+	if len(api.discussions) != 2 {
+		t.Fatalf("Expected 2 discussions, got %+v", api.discussions)
+	}
+	backLink := "**Continued from [Part 1](https://github.com/test/repo/discussions/1)**"
+	if api.discussions[1].Title != "Thread 1 (Part 2)" || !strings.HasPrefix(api.discussions[1].Body, backLink) {
+		t.Errorf("Unexpected second part: %+v", api.discussions[1])
+	}
+
+	// Part 1: alice, bob replying to alice; part 2: dave (alice's comment is in part 1); link 1->2
+	expected := []fakeComment{
+		{ID: "C_1", DiscussionID: "D_1"},
+		{ID: "C_2", DiscussionID: "D_1", ReplyToID: "C_1"},
+		{ID: "C_3", DiscussionID: "D_2"},
+		{ID: "C_4", DiscussionID: "D_1"},
+	}
+	if len(api.comments) != len(expected) {
+		t.Fatalf("Expected %d comments, got %+v", len(expected), api.comments)
+	}
+	for i, comment := range expected {
+		if api.comments[i].DiscussionID != comment.DiscussionID || api.comments[i].ReplyToID != comment.ReplyToID {
+			t.Errorf("Comment %d: expected %+v, got %+v", i+1, comment, api.comments[i])
+		}
+	}
+	if !strings.Contains(api.comments[2].Body, "Across parts") || !strings.HasPrefix(api.comments[3].Body, "**Continued in [Part 2]") {
+		t.Errorf("Unexpected comment bodies: %q, %q", api.comments[2].Body, api.comments[3].Body)
+	}
+
+	// Uploaded threads are recorded and skipped by the next upload
+	if ref, ok := uploader.tracker.Discussion(1); !ok || ref.Number != 1 || len(ref.Parts) != 1 {
+		t.Errorf("Expected the uploaded discussions to be recorded, got %+v", ref)
+	}
+	if uploaded, err := uploader.UploadExport(context.Background(), filepath.Join(dir, ExportFileName)); err != nil || uploaded != 0 {
+		t.Errorf("Expected nothing to upload again, got %d (err: %v)", uploaded, err)
+	}
+}
+
+// fakeCommitter hosts committed files under a fixed raw URL.
+type fakeCommitter struct {
+	paths []string
+}
+
+func (f *fakeCommitter) CommitFiles(ctx context.Context, repo, branch, message string, files []github.TreeFile) (*github.CommitResult, error) {
+	result := &github.CommitResult{SHA: "abc", URLs: make(map[string]string)}
+	for _, file := range files {
+		f.paths = append(f.paths, file.Path)
+		result.URLs[file.Path] = "https://raw.example.com/" + file.Path
+	}
+	return result, nil
+}
+
+func TestRunner_HostExportedAttachments(t *testing.T) {
+	dir := t.TempDir()
+	kept, changed := filepath.Join(dir, "a.png"), filepath.Join(dir, "b.pdf")
+	for _, path := range []string{kept, changed} {
+		if err := os.WriteFile(path, []byte("exported"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sum, err := fileSHA256(kept)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	thread := ExportedThread{
+		ThreadID: 1,
+		Discussions: []ExportedDiscussion{{
+			Body:     "![a.png](./png/a.png)",
+			Comments: []ExportedComment{{Body: "[b.pdf](./pdf/b.pdf) and ![a.png](./png/a.png)"}},
+		}},
+		Attachments: []ExportedAttachment{
+			{AttachmentID: 5, Filename: "a.png", Path: kept, Link: "./png/a.png", SHA256: sum},
+			{AttachmentID: 6, Filename: "b.pdf", Path: changed, Link: "./pdf/b.pdf", SHA256: "0000"},
+		},
+	}
+
+	committer := &fakeCommitter{}
+	runner := newWritingTestRunner(t, newTestForum(0), &fakeDiscussionsAPI{}, nil)
+	runner.SetUploader(attachments.NewUploader(committer, "owner/assets", "main", "files", true))
+	runner.hostExportedAttachments(context.Background(), &thread)
+
+	if len(committer.paths) != 1 {
+		t.Fatalf("Expected only the unchanged attachment to be uploaded, got %v", committer.paths)
+	}
+	hosted := "https://raw.example.com/" + committer.paths[0]
+	if expected := "![a.png](" + hosted + ")"; thread.Discussions[0].Body != expected {
+		t.Errorf("Expected body %q, got %q", expected, thread.Discussions[0].Body)
+	}
+	if expected := "[b.pdf](./pdf/b.pdf) and ![a.png](" + hosted + ")"; thread.Discussions[0].Comments[0].Body != expected {
+		t.Errorf("Expected comment %q, got %q", expected, thread.Discussions[0].Comments[0].Body)
+	}
+}
+
+func TestRunner_Checkpoints(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 3
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First"},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Second"},
+		{PostID: 13, ThreadID: 1, Username: "carol", PostDate: 1640000300, Message: "Third"},
+	}
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, nil)
+	runner.checkpointPosts = 2
+
+	// Saved once the discussion exists, then every two posts
+	var seen []int
+	api.beforeComment = func() {
+		checkpoint, ok := runner.tracker.Checkpoint(1)
+		if !ok || len(checkpoint.Discussions) != 1 || checkpoint.Discussions[0].ID != "D_1" {
+			t.Errorf("Expected a checkpoint with the created discussion, got %+v", checkpoint)
+			return
+		}
+		seen = append(seen, checkpoint.LastPostID)
+	}
+
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+	if expected := []int{10, 10, 12}; !reflect.DeepEqual(seen, expected) {
+		t.Errorf("Expected checkpoints after posts %v, got %v", expected, seen)
+	}
+	if _, ok := runner.tracker.Checkpoint(1); ok {
+		t.Error("Expected the checkpoint to be removed once the thread completed")
+	}
+
+	// A thread stopped by an error saves the posts written since
+	ctx := context.Background()
+	checkpoint := &progress.ThreadCheckpoint{Discussions: []progress.DiscussionRef{{ID: "D_9", Number: 9}}}
+	for postID := 20; postID <= 22; postID++ {
+		checkpoint.LastPostID = postID
+		checkpoint.AddComment(postID, fmt.Sprintf("C_%d", postID))
+		runner.saveCheckpoint(ctx, 9, checkpoint, 1)
+	}
+	if saved, _ := runner.tracker.Checkpoint(9); saved.LastPostID != 22 || len(saved.CommentIDs) != 3 {
+		t.Errorf("Expected a checkpoint after post 22, got %+v", saved)
+	}
+	checkpoint.LastPostID = 23
+	runner.saveCheckpoint(ctx, 9, checkpoint, 1)
+	runner.endCheckpoint(ctx, 9, checkpoint, errors.New("failed"))
+	if saved, _ := runner.tracker.Checkpoint(9); saved.LastPostID != 23 {
+		t.Errorf("Expected the checkpoint saved when the thread failed, got %+v", saved)
+	}
+}
+
+func TestRunner_CancelledCheckpoint(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 3
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First"},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Second"},
+		{PostID: 13, ThreadID: 1, Username: "carol", PostDate: 1640000300, Message: "Third"},
+	}
+
+	// Cancelled while the second comment is in flight
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	api := &fakeDiscussionsAPI{}
+	comments := 0
+	api.beforeComment = func() {
+		if comments++; comments == 2 {
+			cancel()
+			time.Sleep(50 * time.Millisecond)
+		}
+	}
+	runner := newWritingTestRunner(t, forum, api, nil)
+	_ = runner.RunMigration(ctx)
+
+	state := runner.tracker.GetProgress()
+	if len(state.CompletedThreads) != 0 || len(state.FailedThreads) != 0 {
+		t.Errorf("Cancelled thread should be neither completed nor failed: %+v", state)
+	}
+	checkpoint, ok := runner.tracker.Checkpoint(1)
+	if !ok {
+		t.Fatal("Expected the checkpoint kept for the cancelled thread")
+	}
+	if checkpoint.LastPostID != 11 || checkpoint.CommentIDs[11] != "C_1" || checkpoint.CommentIDs[12] != "" {
+		t.Errorf("Expected a checkpoint after post 11, got %+v", checkpoint)
+	}
+}
+
+func TestRunner_CrashedCheckpoint(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 3
+	forum.posts[1] = append(forum.posts[1],
+		xenforo.Post{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First"},
+		xenforo.Post{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Second"},
+		xenforo.Post{PostID: 13, ThreadID: 1, Username: "carol", PostDate: 1640000300, Message: `[QUOTE="bob, post: 12, member: 3"]Second[/QUOTE] Third`},
+	)
+
+	// The run crashed after post 12 was written but before it was saved
+	api := &fakeDiscussionsAPI{
+		discussions: []fakeDiscussion{{ID: "D_1", Number: 1, Title: "Thread 1", Body: "Question", CategoryID: "DIC_kwDOtest123"}},
+		comments: []fakeComment{
+			{ID: "C_1", DiscussionID: "D_1", Body: "First"},
+			{ID: "C_2", DiscussionID: "D_1", Body: "Second"},
+		},
+	}
+	runner := newWritingTestRunner(t, forum, api, nil)
+	checkpoint := &progress.ThreadCheckpoint{Discussions: []progress.DiscussionRef{{ID: "D_1", Number: 1}}, LastPostID: 11}
+	checkpoint.AddComment(11, "C_1")
+	if err := runner.tracker.SaveCheckpoint(1, checkpoint); err != nil {
+		t.Fatalf("SaveCheckpoint returned error: %v", err)
+	}
+
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+	if len(api.comments) != 3 || api.comments[2].ReplyToID != "C_2" || !strings.Contains(api.comments[2].Body, "Third") {
+		t.Errorf("Expected only post 13 added as a reply to C_2, got %+v", api.comments)
+	}
+	if discussions := runner.tracker.Discussions(); discussions[1].LastPostID != 13 {
+		t.Errorf("Expected thread 1 migrated up to post 13, got %+v", discussions)
+	}
+}
+
+func TestRunner_DetectDuplicates(t *testing.T) {
+	forum := newTestForum(2)
+	forum.threads[0].ReplyCount = 2
+	forum.posts[1] = append(forum.posts[1],
+		xenforo.Post{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First"},
+		xenforo.Post{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Second"},
+	)
+	api := &fakeDiscussionsAPI{
+		discussions: []fakeDiscussion{
+			{ID: "D_1", Number: 1, Title: "Thread 1", Body: "---\nOriginal Thread ID: 1\n---", CategoryID: "DIC_kwDOtest123"},
+			{ID: "D_2", Number: 2, Title: "Renamed", Body: "---\nOriginal Thread ID: 2\n---", CategoryID: "DIC_kwDOtest123"},
+		},
+		comments: []fakeComment{{ID: "C_1", DiscussionID: "D_1", Body: "First"}},
+	}
+	runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+		cfg.Migration.DetectDuplicates = true
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	// Thread 1 resumes in its discussion; thread 2's title no longer matches
+	if len(api.discussions) != 3 || api.discussions[2].Title != "Thread 2" {
+		t.Fatalf("Expected only thread 2 to be created, got %+v", api.discussions)
+	}
+	if len(api.comments) != 2 || api.comments[1].DiscussionID != "D_1" || !strings.Contains(api.comments[1].Body, "Second") {
+		t.Errorf("Expected only post 12 appended to D_1, got %+v", api.comments)
+	}
+	discussions := runner.tracker.Discussions()
+	if discussions[1].ID != "D_1" || discussions[1].LastPostID != 12 || discussions[2].ID != "D_3" {
+		t.Errorf("Expected threads mapped to D_1 and D_3, got %+v", discussions)
+	}
+	if !runner.tracker.IsCompleted(1) {
+		t.Error("Expected the duplicate thread to be marked completed")
+	}
+}
+
+func TestRunner_DetectDuplicatesReplies(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 3
+	forum.posts[1] = append(forum.posts[1],
+		xenforo.Post{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First"},
+		xenforo.Post{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Second"},
+		xenforo.Post{PostID: 13, ThreadID: 1, Username: "carol", PostDate: 1640000300, Message: `[QUOTE="bob, post: 12, member: 3"]Second[/QUOTE] Third`},
+	)
+	api := &fakeDiscussionsAPI{
+		discussions: []fakeDiscussion{
+			{ID: "D_1", Number: 1, Title: "Thread 1", Body: "---\nOriginal Thread ID: 1\n---", CategoryID: "DIC_kwDOtest123"},
+		},
+		comments: []fakeComment{
+			{ID: "C_1", DiscussionID: "D_1", Body: "First"},
+			{ID: "C_2", DiscussionID: "D_1", ReplyToID: "C_1", Body: "Second"},
+		},
+	}
+	runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+		cfg.Migration.DetectDuplicates = true
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	// The quote of a post migrated by the interrupted run threads under its parent
+	if len(api.comments) != 3 || api.comments[2].ReplyToID != "C_1" || !strings.Contains(api.comments[2].Body, "Third") {
+		t.Errorf("Expected post 13 added as a reply to C_1, got %+v", api.comments)
+	}
+}
+
+func TestRunner_SplitLargeThreads(t *testing.T) {
+	forum := newTestForum(1)
+	forum.threads[0].ReplyCount = 6
+	forum.posts[1] = nil
+	for i := 0; i < 7; i++ {
+		forum.posts[1] = append(forum.posts[1], xenforo.Post{
+			PostID: 100 + i, ThreadID: 1, Username: "user", PostDate: 1640000000 + int64(i), Message: fmt.Sprintf("Post %d", i),
+		})
+	}
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+		cfg.Migration.SplitThreadPosts = 3
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	if len(api.discussions) != 3 {
+		t.Fatalf("Expected 3 discussions, got %d", len(api.discussions))
+	}
+	for i, discussion := range api.discussions {
+		expectedTitle := fmt.Sprintf("Thread 1 (Part %d)", i+1)
+		if discussion.Title != expectedTitle {
+			t.Errorf("Expected title %q, got %q", expectedTitle, discussion.Title)
+		}
+		expectedPost := fmt.Sprintf("Post %d", i*3)
+		if !strings.Contains(discussion.Body, expectedPost) {
+			t.Errorf("Part %d should open with %q, got %q", i+1, expectedPost, discussion.Body)
+		}
+		if i > 0 {
+			backLink := fmt.Sprintf("**Continued from [Part %d](https://github.com/test/repo/discussions/%d)**", i, i)
+			if !strings.HasPrefix(discussion.Body, backLink) {
+				t.Errorf("Part %d should start with %q, got %q", i+1, backLink, discussion.Body)
+			}
+		}
+	}
+
+	// Comments in order: part 1 posts 1-2, part 2 posts 4-5, link 1->2, link 2->3 (part 3 has no replies)
+	var summary []string
+	for _, comment := range api.comments {
+		switch {
+		case strings.HasPrefix(comment.Body, "**Continued in"):
+			summary = append(summary, comment.DiscussionID+" "+comment.Body)
+		default:
+			for i := 0; i < 7; i++ {
+				if strings.Contains(comment.Body, fmt.Sprintf("Post %d", i)) {
+					summary = append(summary, fmt.Sprintf("%s post %d", comment.DiscussionID, i))
+				}
+			}
+		}
+	}
+	expected := []string{
+		"D_1 post 1",
+		"D_1 post 2",
+		"D_2 post 4",
+		"D_2 post 5",
+		"D_1 **Continued in [Part 2](https://github.com/test/repo/discussions/2)**",
+		"D_2 **Continued in [Part 3](https://github.com/test/repo/discussions/3)**",
+	}
+	if strings.Join(summary, "\n") != strings.Join(expected, "\n") {
+		t.Errorf("Unexpected comments:\n%s\nexpected:\n%s", strings.Join(summary, "\n"), strings.Join(expected, "\n"))
+	}
+}
+
+func TestRunner_RateLimitCheckpoint(t *testing.T) {
+	forum := newTestForum(2)
+	forum.posts[1] = nil
+	for i := 0; i < 5; i++ {
+		forum.posts[1] = append(forum.posts[1], xenforo.Post{
+			PostID: 100 + i, ThreadID: 1, Username: "user", PostDate: 1640000000 + int64(i), Message: fmt.Sprintf("Post %d", i),
+		})
+	}
+
+	progressFile := filepath.Join(t.TempDir(), "progress.json")
+	mutate := func(cfg *config.Config) {
+		cfg.Migration.ProgressFile = progressFile
+		cfg.Migration.SplitThreadPosts = 3
+	}
+
+	// Writes: part 1 (3 posts), part 2 opening post, then the limit is exhausted.
+	api := &fakeDiscussionsAPI{rateLimitAfter: 4}
+	runner := newWritingTestRunner(t, forum, api, mutate)
+
+	err := runner.RunMigration(context.Background())
+	if !errors.Is(err, github.ErrRateLimitExhausted) {
+		t.Fatalf("Expected ErrRateLimitExhausted, got: %v", err)
+	}
+
+	state := runner.tracker.GetProgress()
+	if len(state.CompletedThreads) != 0 || len(state.FailedThreads) != 0 {
+		t.Errorf("Rate limited thread should be neither completed nor failed: %+v", state)
+	}
+	checkpoint, ok := runner.tracker.Checkpoint(1)
+	if !ok {
+		t.Fatal("Expected a checkpoint for thread 1")
+	}
+	expectedDiscussions := []progress.DiscussionRef{{ID: "D_1", Number: 1}, {ID: "D_2", Number: 2}}
+	if !reflect.DeepEqual(checkpoint.Discussions, expectedDiscussions) || checkpoint.LastPostID != 103 || checkpoint.LinkedParts != 0 {
+		t.Errorf("Unexpected checkpoint: %+v", checkpoint)
+	}
+	if len(api.discussions) != 2 {
+		t.Errorf("Thread 2 should not be started after the rate limit is exhausted, got %d discussions", len(api.discussions))
+	}
+
+	// The next run resumes thread 1 in its existing discussions.
+	api.rateLimitAfter = 0
+	runner = newWritingTestRunner(t, forum, api, mutate)
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("Resumed run returned error: %v", err)
+	}
+
+	if len(api.discussions) != 3 || api.discussions[2].Title != "Thread 2" {
+		t.Fatalf("Expected only thread 2 to create a discussion, got %+v", api.discussions)
+	}
+	var resumed []string
+	for _, comment := range api.comments[2:] {
+		resumed = append(resumed, comment.DiscussionID+" "+comment.Body[strings.LastIndex(comment.Body, "\n")+1:])
+	}
+	expected := []string{
+		"D_2 Post 4",
+		"D_1 **Continued in [Part 2](https://github.com/test/repo/discussions/2)**",
+	}
+	if !reflect.DeepEqual(resumed, expected) {
+		t.Errorf("Unexpected comments after resuming: %q", resumed)
+	}
+
+	if _, ok := runner.tracker.Checkpoint(1); ok {
+		t.Error("Checkpoint should be cleared once the thread completes")
+	}
+	if completed := runner.tracker.GetProgress().CompletedThreads; len(completed) != 2 {
+		t.Errorf("Expected both threads completed, got %v", completed)
+	}
+}
+
+func TestRunner_SinceLastRun(t *testing.T) {
+	forum := newTestForum(4)
+	forum.threads[0].PostDate = 1600000000
+	forum.threads[1].PostDate = 1650000000
+	forum.threads[2].PostDate = 1700000000
+	forum.threads[3].PostDate = 1750000000
+
+	t.Run("First run migrates everything", func(t *testing.T) {
+		runner, tracker := newTestRunner(t, forum, func(cfg *config.Config) {
+			cfg.Migration.SinceLastRun = true
+		})
+
+		if err := runner.RunMigration(context.Background()); err != nil {
+			t.Fatalf("RunMigration returned error: %v", err)
+		}
+		if completed := len(tracker.GetProgress().CompletedThreads); completed != 4 {
+			t.Errorf("Expected 4 completed threads, got %d", completed)
+		}
+	})
+
+	t.Run("Second run uses the stored timestamp", func(t *testing.T) {
+		runner, tracker := newTestRunner(t, forum, func(cfg *config.Config) {
+			cfg.Migration.SinceLastRun = true
+		})
+		if err := tracker.RecordRun(runner.runKey(), 1700000000); err != nil {
+			t.Fatalf("Failed to seed last run: %v", err)
+		}
+
+		if err := runner.RunMigration(context.Background()); err != nil {
+			t.Fatalf("RunMigration returned error: %v", err)
+		}
+
+		completed := tracker.GetProgress().CompletedThreads
+		if len(completed) != 2 || completed[0] != 3 || completed[1] != 4 {
+			t.Errorf("Expected threads [3 4] to be migrated, got %v", completed)
+		}
+	})
+}
+
+func TestRunner_RecordRun(t *testing.T) {
+	startedAt := time.Unix(1700000000, 0)
+
+	t.Run("Successful live run is recorded", func(t *testing.T) {
+		runner, tracker := newTestRunner(t, newTestForum(0), func(cfg *config.Config) {
+			cfg.Migration.DryRun = false
+		})
+		runner.recordRun(context.Background(), startedAt)
+
+		if at, ok := tracker.LastRunAt(runner.runKey()); !ok || at != startedAt.Unix() {
+			t.Errorf("Expected run at %d to be recorded, got %d (found=%v)", startedAt.Unix(), at, ok)
+		}
+	})
+
+	t.Run("Runs with failures are not recorded", func(t *testing.T) {
+		runner, tracker := newTestRunner(t, newTestForum(0), func(cfg *config.Config) {
+			cfg.Migration.DryRun = false
+		})
+		runner.failures = 1
+		runner.recordRun(context.Background(), startedAt)
+
+		if _, ok := tracker.LastRunAt(runner.runKey()); ok {
+			t.Error("Run with failures should not be recorded")
+		}
+	})
+
+	t.Run("Dry runs are not recorded", func(t *testing.T) {
+		runner, tracker := newTestRunner(t, newTestForum(0), nil)
+		runner.recordRun(context.Background(), startedAt)
+
+		if _, ok := tracker.LastRunAt(runner.runKey()); ok {
+			t.Error("Dry run should not be recorded")
+		}
+	})
+}
+
+func TestRunner_MappingConsistency(t *testing.T) {
+	forum := newTestForum(2)
+	progressFile := filepath.Join(t.TempDir(), "progress.json")
+	mutate := func(cfg *config.Config) { cfg.Migration.ProgressFile = progressFile }
+
+	// Thread 1 was created on GitHub by an earlier run that never recorded it.
+	api := &fakeDiscussionsAPI{discussions: []fakeDiscussion{
+		{ID: "D_1", Number: 1, Body: "---\nOriginal Thread ID: 12\n---"},
+		{ID: "D_2", Number: 2, Body: "---\nOriginal Thread ID: 1\n---"},
+	}}
+	runner := newWritingTestRunner(t, forum, api, mutate)
+	if err := runner.tracker.MarkCompleted(1); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	if unmapped := runner.tracker.UnmappedThreads(); !reflect.DeepEqual(unmapped, []int{1}) {
+		t.Fatalf("Expected thread 1 to be flagged as unmapped, got %v", unmapped)
+	}
+	ref, ok := runner.tracker.Discussion(2)
+	if !ok || ref.ID != "D_3" || ref.URL != "https://github.com/test/repo/discussions/3" {
+		t.Errorf("Expected thread 2 to be mapped to its new discussion, got %+v (found=%v)", ref, ok)
+	}
+
+	// With repair enabled, the missing entry is found by its thread marker.
+	runner = newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+		mutate(cfg)
+		cfg.Migration.RepairMappings = true
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	if unmapped := runner.tracker.UnmappedThreads(); len(unmapped) != 0 {
+		t.Errorf("Expected all threads to be mapped after repair, got %v", unmapped)
+	}
+	if ref, ok := runner.tracker.Discussion(1); !ok || ref.ID != "D_2" || ref.Number != 2 {
+		t.Errorf("Expected thread 1 to be repaired to discussion #2, got %+v (found=%v)", ref, ok)
+	}
+}
+
+func TestRunner_ResumeThreadListing(t *testing.T) {
+	forum := newTestForum(5)
+	forum.threadsPage = 2
+	forum.failPage = 3
+
+	progressFile := filepath.Join(t.TempDir(), "progress.json")
+	mutate := func(cfg *config.Config) {
+		cfg.Migration.ProgressFile = progressFile
+		cfg.Migration.ResumeListing = true
+	}
+
+	runner, tracker := newTestRunner(t, forum, mutate)
+	runner.xenforoSource.(*xenforo.Client).SetPageDelay(0)
+	if err := runner.RunMigration(context.Background()); err == nil {
+		t.Fatal("Expected the listing failure to be returned")
+	}
+
+	listing, ok := tracker.Listing(1)
+	if !ok || listing.Page != 2 || len(listing.Threads) != 4 {
+		t.Fatalf("Expected listing saved after page 2 with 4 threads, got %+v (found=%v)", listing, ok)
+	}
+	if forum.postsServed != 0 {
+		t.Errorf("No thread should be migrated from an incomplete listing, got %d posts requests", forum.postsServed)
+	}
+
+	// A new thread shifts thread 4 onto page 3; it must not be listed twice.
+	forum.failPage = 0
+	forum.pagesServed = nil
+	forum.threads = append([]xenforo.Thread{{ThreadID: 6, Title: "Thread 6", NodeID: 1, Username: "author", PostDate: 1640000000}}, forum.threads...)
+	forum.posts[6] = []xenforo.Post{{PostID: 60, ThreadID: 6, Username: "author", PostDate: 1640000000, Message: "Post in thread 6"}}
+
+	runner, tracker = newTestRunner(t, forum, mutate)
+	runner.xenforoSource.(*xenforo.Client).SetPageDelay(0)
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("Resumed run returned error: %v", err)
+	}
+
+	if !reflect.DeepEqual(forum.pagesServed, []int{3}) {
+		t.Errorf("Expected the listing to resume at page 3, requested pages %v", forum.pagesServed)
+	}
+	if forum.postsServed != 5 {
+		t.Errorf("Expected 5 listed threads to be migrated once, got %d posts requests", forum.postsServed)
+	}
+	if _, ok := tracker.Listing(1); ok {
+		t.Error("Saved listing should be cleared once the listing completes")
+	}
+}
+
+func TestRunner_AuthorAvatars(t *testing.T) {
+	forum := newTestForum(2)
+	forum.avatars = map[int]bool{7: true}
+	forum.threads[0].ReplyCount = 2
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, UserID: 7, Username: "alice", PostDate: 1640000000, Message: "Question"},
+		{PostID: 11, ThreadID: 1, UserID: 8, Username: "bob", PostDate: 1640000100, Message: "Answer"},
+		{PostID: 12, ThreadID: 1, UserID: 7, Username: "alice", PostDate: 1640000200, Message: "Thanks"},
+	}
+	forum.posts[2][0].UserID, forum.posts[2][0].Username = 7, "alice"
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, nil)
+	runner.SetAvatars(attachments.NewAvatarCache(runner.config.Filesystem.AttachmentsDir, false, runner.xenforoSource))
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	if forum.usersServed != 2 || forum.avatarsSent != 1 {
+		t.Errorf("Expected 2 user lookups and 1 avatar download, got %d and %d", forum.usersServed, forum.avatarsSent)
+	}
+
+	avatar := `Author: <img src="./avatars/avatar_7.png" alt="" width="20" height="20"> **alice**`
+	bodies := []string{api.discussions[0].Body, api.comments[1].Body, api.discussions[1].Body}
+	for i, body := range bodies {
+		if !strings.Contains(body, avatar) {
+			t.Errorf("Post %d by alice should show the avatar, got:\n%s", i, body)
+		}
+	}
+	if !strings.Contains(api.comments[0].Body, "Author: **bob**") {
+		t.Errorf("Author without an avatar should keep the plain header, got:\n%s", api.comments[0].Body)
+	}
+}
+
+func TestRollback(t *testing.T) {
+	forum := newTestForum(2)
+	forum.threads[0].ReplyCount = 4
+	forum.posts[1] = nil
+	for i := 0; i < 5; i++ {
+		forum.posts[1] = append(forum.posts[1], xenforo.Post{
+			PostID: 100 + i, ThreadID: 1, Username: "user", PostDate: 1640000000 + int64(i), Message: fmt.Sprintf("Post %d", i),
+		})
+	}
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, func(cfg *config.Config) {
+		cfg.Migration.SplitThreadPosts = 3
+	})
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+	// A thread interrupted after its discussion was created
+	if err := runner.tracker.SaveCheckpoint(3, &progress.ThreadCheckpoint{Discussions: []progress.DiscussionRef{{ID: "D_9", Number: 9}}}); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	targets := RollbackTargets(runner.tracker)
+	if len(targets) != 3 || len(targets[0].Discussions) != 2 {
+		t.Fatalf("Expected 3 threads with both parts of thread 1, got %+v", targets)
+	}
+
+	// Dry runs delete nothing
+	if _, err := Rollback(context.Background(), runner.githubClient, runner.tracker, targets, true); err != nil {
+		t.Fatalf("Dry-run rollback returned error: %v", err)
+	}
+	if len(api.deleted) != 0 {
+		t.Fatalf("Dry-run rollback should not delete discussions, got %v", api.deleted)
+	}
+
+	deleted, err := Rollback(context.Background(), runner.githubClient, runner.tracker, targets, false)
+	if err != nil {
+		t.Fatalf("Rollback returned error: %v", err)
+	}
+	if expected := []string{"D_1", "D_2", "D_3", "D_9"}; deleted != 4 || !reflect.DeepEqual(api.deleted, expected) {
+		t.Errorf("Expected %v to be deleted, got %v (%d)", expected, api.deleted, deleted)
+	}
+	for _, id := range []int{1, 2} {
+		if runner.tracker.IsCompleted(id) {
+			t.Errorf("Thread %d should be forgotten after the rollback", id)
+		}
+	}
+	if len(runner.tracker.Discussions()) != 0 || len(RollbackTargets(runner.tracker)) != 0 {
+		t.Errorf("Expected no recorded discussions after the rollback, got %+v", RollbackTargets(runner.tracker))
+	}
+}
@@ -0,0 +1,72 @@
+package migration
+
+import (
+	"context"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/phpbb"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo/dbsource"
+)
+
+// ForumSource provides the forum content a migration reads.
+// *xenforo.Client reads it through the XenForo REST API, *dbsource.Source
+// straight from the forum database and *phpbb.Source from a phpBB export.
+// All of them count content for estimates.
+type ForumSource interface {
+	StatsSource
+	TestConnection(ctx context.Context) error
+	GetThreadsFrom(ctx context.Context, nodeID, startPage int, threads []xenforo.Thread, onPage func(page int, threads []xenforo.Thread) error) ([]xenforo.Thread, error)
+	GetPosts(ctx context.Context, thread xenforo.Thread) ([]xenforo.Post, error)
+	GetUser(ctx context.Context, userID int) (*xenforo.User, error)
+	DownloadAttachment(ctx context.Context, url, filepath string) error
+}
+
+// NewForumSource creates the source XenForo.Source selects. Attachments the
+// database and phpBB sources cannot read from the data directory are
+// downloaded from the forum through the API client. The returned close function releases
+// the source.
+func NewForumSource(cfg *config.Config) (ForumSource, func() error, error) {
+	client := xenforo.NewClient(
+		cfg.XenForo.APIURL,
+		cfg.XenForo.APIKey,
+		cfg.XenForo.APIUser,
+		cfg.Migration.MaxRetries,
+	).SetBandwidthLimiter(xenforo.NewBandwidthLimiter(cfg.Filesystem.MaxDownloadBytesPerSec)).
+		SetCache(cfg.XenForo.CacheDir, cfg.XenForo.CacheTTL)
+
+	switch cfg.XenForo.Source {
+	case config.SourceDB:
+		source, err := dbsource.Open(cfg.XenForo.DatabaseDSN)
+		if err != nil {
+			return nil, nil, err
+		}
+		source.SetWebURL(cfg.XenForo.WebURL).
+			SetDataDir(cfg.XenForo.DataDir).
+			SetDownloader(client)
+		return source, source.Close, nil
+	case config.SourcePhpBB:
+		source, err := phpbb.Open(cfg.XenForo.ExportFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		source.SetBoardURL(cfg.XenForo.WebURL).
+			SetFilesDir(cfg.XenForo.DataDir).
+			SetDownloader(client)
+		return source, func() error { return nil }, nil
+	default:
+		return client, func() error { return nil }, nil
+	}
+}
+
+// sourceName names the configured forum source in messages.
+func sourceName(cfg *config.Config) string {
+	switch cfg.XenForo.Source {
+	case config.SourceDB:
+		return "XenForo database"
+	case config.SourcePhpBB:
+		return "phpBB export"
+	default:
+		return "XenForo API"
+	}
+}
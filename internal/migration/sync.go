@@ -0,0 +1,175 @@
+package migration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// Sync mirrors the forum activity since the last sync pass: posts added to
+// migrated threads are appended as comments to their discussion, the last
+// part of split threads, and new threads are migrated like a regular run
+// with the configured filters. Threads whose last post predates the last
+// pass are skipped without fetching their posts. The pass start is recorded
+// once every thread synced, so a failed pass is repeated in full. Returns the
+// number of appended posts.
+func (r *Runner) Sync(ctx context.Context) (int, error) {
+	startedAt := time.Now()
+	atomic.StoreInt64(&r.failures, 0)
+	atomic.StoreInt32(&r.rateLimited, 0)
+
+	since, hasSince := r.syncedSince()
+	threads, err := r.xenforoSource.GetThreadsFrom(ctx, r.config.GitHub.XenForoNodeID, 1, nil, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list threads: %w", err)
+	}
+
+	recorded := r.tracker.Discussions()
+	appended := 0
+	var created []xenforo.Thread
+	for _, thread := range threads {
+		ref, ok := recorded[thread.ThreadID]
+		if !ok {
+			if !r.tracker.IsCompleted(thread.ThreadID) {
+				created = append(created, thread)
+			}
+			continue
+		}
+		if hasSince && thread.LastPostDate > 0 && thread.LastPostDate < since {
+			continue
+		}
+
+		ctx := logging.With(ctx, "thread_id", thread.ThreadID)
+		count, err := r.syncThread(ctx, thread, ref, since, hasSince)
+		appended += count
+		if errors.Is(err, github.ErrRateLimitExhausted) {
+			return appended, err
+		}
+		if err != nil {
+			atomic.AddInt64(&r.failures, 1)
+			logging.Errorf(ctx, "✗ Failed to sync thread %d: %v", thread.ThreadID, err)
+		}
+	}
+
+	if created, err = r.filterThreads(ctx, created); err != nil {
+		return appended, err
+	}
+	if len(created) > 0 {
+		logging.Infof(ctx, "✓ Migrating %d new threads", len(created))
+		r.orderThreads(ctx, created)
+		r.processThreads(ctx, created)
+	}
+	if atomic.LoadInt32(&r.rateLimited) == 1 {
+		return appended, fmt.Errorf("sync stopped early: %w", github.ErrRateLimitExhausted)
+	}
+
+	if !r.isDryRun(ctx) && ctx.Err() == nil && atomic.LoadInt64(&r.failures) == 0 {
+		if err := r.tracker.RecordSync(r.runKey(), startedAt.Unix()); err != nil {
+			logging.Warnf(ctx, "✗ Warning: Failed to record sync timestamp: %v", err)
+		}
+	}
+	return appended, nil
+}
+
+// syncedSince returns the start of the last sync pass, or of the last run
+// when no pass was recorded yet.
+func (r *Runner) syncedSince() (int64, bool) {
+	if since, ok := r.tracker.LastSyncAt(r.runKey()); ok {
+		return since, true
+	}
+	return r.tracker.LastRunAt(r.runKey())
+}
+
+// syncThread appends the posts of a migrated thread written after the last
+// post recorded for its discussion. Discussions recorded before their last
+// post was tracked take the posts created since the last pass, or the last
+// run when none was recorded, and are skipped without either. The recorded
+// discussion is updated after every appended post. A post that could not be
+// added stops the thread with an error, so the next pass retries it.
+func (r *Runner) syncThread(ctx context.Context, thread xenforo.Thread, ref progress.DiscussionRef, since int64, hasSince bool) (int, error) {
+	if ref.LastPostID == 0 && !hasSince {
+		logging.Warnf(ctx, "  ⚠ Thread %d has no recorded last post or previous run, skipping it", thread.ThreadID)
+		return 0, nil
+	}
+
+	posts, err := r.xenforoSource.GetPosts(ctx, thread)
+	if err != nil || len(posts) == 0 {
+		return 0, err
+	}
+
+	var added []xenforo.Post
+	for _, post := range posts[1:] {
+		if (ref.LastPostID > 0 && post.PostID > ref.LastPostID) || (ref.LastPostID == 0 && post.PostDate >= since) {
+			added = append(added, post)
+		}
+	}
+	if len(added) == 0 {
+		if ref.LastPostID == 0 {
+			r.recordSyncedPost(ctx, thread.ThreadID, ref, posts[len(posts)-1].PostID)
+		}
+		return 0, nil
+	}
+
+	target := ref
+	if len(ref.Parts) > 0 {
+		target = ref.Parts[len(ref.Parts)-1]
+	}
+	logging.Infof(ctx, "\nSyncing %d new posts of thread %d to discussion #%d: %s", len(added), thread.ThreadID, target.Number, thread.Title)
+
+	r.saveInlineImages(added)
+	threadAttachments := r.collectAttachments(added)
+	if err := r.downloadAttachments(ctx, thread.ThreadID, threadAttachments); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to download attachments for thread %d: %v", thread.ThreadID, err)
+	}
+	hostedURLs := r.uploadAttachments(ctx, thread.ThreadID, threadAttachments)
+
+	// Replies are threaded under the posts appended in this pass
+	checkpoint := &progress.ThreadCheckpoint{CommentIDs: make(map[int]string)}
+	for i, post := range added {
+		ctx := logging.With(ctx, "post_id", post.PostID)
+		body, err := r.formatPost(ctx, post, thread.ThreadID, threadAttachments, hostedURLs)
+		if err != nil {
+			return i, err
+		}
+
+		comment := pendingComment{post: post, body: body}
+		comment.solution = r.config.Migration.MarkSolutions && post.PostID == thread.TypeData.SolutionPostID
+		if !comment.solution {
+			comment.replyToID = replyTarget(post, checkpoint.CommentIDs)
+		}
+		commentID, addErr := r.addComment(ctx, post, target.ID, comment.replyToID, body)
+		if err := r.commentAdded(ctx, target.ID, comment, commentID, addErr, checkpoint); err != nil {
+			return i, err
+		}
+		if !r.isDryRun(ctx) && checkpoint.CommentIDs[post.PostID] == "" {
+			return i, fmt.Errorf("post %d was not added to discussion #%d", post.PostID, target.Number)
+		}
+
+		ref = r.recordSyncedPost(ctx, thread.ThreadID, ref, post.PostID)
+		if !r.isDryRun(ctx) {
+			time.Sleep(r.postDelay)
+		}
+	}
+	return len(added), nil
+}
+
+// recordSyncedPost records postID as the last post written to the thread's
+// discussion and returns the updated reference.
+func (r *Runner) recordSyncedPost(ctx context.Context, threadID int, ref progress.DiscussionRef, postID int) progress.DiscussionRef {
+	if r.isDryRun(ctx) {
+		return ref
+	}
+
+	ref.LastPostID = postID
+	if err := r.tracker.RecordDiscussion(threadID, ref); err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to record discussion for thread %d: %v", threadID, err)
+	}
+	return ref
+}
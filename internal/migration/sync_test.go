@@ -0,0 +1,112 @@
+package migration
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestRunner_Sync(t *testing.T) {
+	forum := newTestForum(2)
+	forum.posts[1] = append(forum.posts[1], xenforo.Post{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First reply"})
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, nil)
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+	if ref, _ := runner.tracker.Discussion(1); ref.LastPostID != 11 {
+		t.Fatalf("Expected the migration to record post 11 as the last post, got %d", ref.LastPostID)
+	}
+
+	// New posts in a migrated thread and a new thread
+	now := time.Now().Unix()
+	forum.threads[0].LastPostDate = now + 60
+	forum.posts[1] = append(forum.posts[1],
+		xenforo.Post{PostID: 12, ThreadID: 1, Username: "bob", PostDate: now + 30, Message: "Second reply"},
+		xenforo.Post{PostID: 13, ThreadID: 1, Username: "carol", PostDate: now + 60, Message: `[QUOTE="bob, post: 12, member: 3"]Second reply[/QUOTE] Agreed`},
+	)
+	forum.threads = append(forum.threads, xenforo.Thread{ThreadID: 3, Title: "Thread 3", NodeID: 1, Username: "dave", PostDate: now + 90})
+	forum.posts[3] = []xenforo.Post{{PostID: 30, ThreadID: 3, Username: "dave", PostDate: now + 90, Message: "New thread"}}
+
+	appended, err := runner.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if appended != 2 {
+		t.Errorf("Expected 2 appended posts, got %d", appended)
+	}
+	if len(api.comments) != 3 || !strings.Contains(api.comments[1].Body, "Second reply") || api.comments[1].DiscussionID != "D_1" {
+		t.Fatalf("Expected the new posts to be appended to discussion D_1, got %+v", api.comments)
+	}
+	if api.comments[2].ReplyToID != api.comments[1].ID {
+		t.Errorf("Expected the quoting post to reply to the appended comment, got reply to %q", api.comments[2].ReplyToID)
+	}
+	if len(api.discussions) != 3 || api.discussions[2].Title != "Thread 3" {
+		t.Errorf("Expected the new thread to be migrated, got %+v", api.discussions)
+	}
+	if ref, _ := runner.tracker.Discussion(1); ref.LastPostID != 13 {
+		t.Errorf("Expected post 13 to be recorded as the last post, got %d", ref.LastPostID)
+	}
+	if _, ok := runner.tracker.LastSyncAt(runner.runKey()); !ok {
+		t.Error("Expected the sync pass to be recorded")
+	}
+
+	// Threads without activity since the last pass are not fetched again
+	forum.threads[1].LastPostDate = 1640000000
+	forum.threads[2].LastPostDate = now + 90
+	if err := runner.tracker.RecordSync(runner.runKey(), now+120); err != nil {
+		t.Fatalf("RecordSync returned error: %v", err)
+	}
+	served := atomic.LoadInt32(&forum.postsServed)
+	appended, err = runner.Sync(context.Background())
+	if err != nil || appended != 0 {
+		t.Fatalf("Expected a second pass to append nothing, got %d (err: %v)", appended, err)
+	}
+	if got := atomic.LoadInt32(&forum.postsServed); got != served {
+		t.Errorf("Expected no posts to be fetched, got %d requests", got-served)
+	}
+	if len(api.comments) != 3 || len(api.discussions) != 3 {
+		t.Errorf("Expected no new comments or discussions, got %d comments and %d discussions", len(api.comments), len(api.discussions))
+	}
+}
+
+func TestRunner_SyncFailedPost(t *testing.T) {
+	forum := newTestForum(1)
+	forum.posts[1] = append(forum.posts[1], xenforo.Post{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "First reply"})
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, nil)
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	now := time.Now().Unix()
+	forum.posts[1] = append(forum.posts[1],
+		xenforo.Post{PostID: 12, ThreadID: 1, Username: "bob", PostDate: now + 30, Message: "Rejected reply"},
+		xenforo.Post{PostID: 13, ThreadID: 1, Username: "carol", PostDate: now + 60, Message: "Later reply"},
+	)
+	api.rejectBody = "Rejected"
+
+	// The thread stops at the post GitHub rejected, left to the next pass
+	appended, err := runner.Sync(context.Background())
+	if err != nil {
+		t.Fatalf("Sync returned error: %v", err)
+	}
+	if appended != 0 || len(api.comments) != 1 {
+		t.Errorf("Expected no appended posts, got %d (comments: %+v)", appended, api.comments)
+	}
+	if failures := atomic.LoadInt64(&runner.failures); failures != 1 {
+		t.Errorf("Expected 1 failure, got %d", failures)
+	}
+	if ref, _ := runner.tracker.Discussion(1); ref.LastPostID != 11 {
+		t.Errorf("Expected post 11 to stay the last post, got %d", ref.LastPostID)
+	}
+	if _, ok := runner.tracker.LastSyncAt(runner.runKey()); ok {
+		t.Error("Expected the failed sync pass not to be recorded")
+	}
+}
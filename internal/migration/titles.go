@@ -0,0 +1,73 @@
+package migration
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/bbcode"
+)
+
+// maxTitleLength is the longest discussion title GitHub accepts, in
+// characters.
+const maxTitleLength = 256
+
+// titleEllipsis ends titles shortened to the length limit.
+const titleEllipsis = "…"
+
+// normalizeTitle prepares a thread title for GitHub: BB-code tags and control
+// characters are removed and whitespace is collapsed. Direction marks of
+// right-to-left titles are kept.
+func normalizeTitle(title string) string {
+	title = bbcode.StripTags(title)
+	title = strings.Join(strings.Fields(title), " ")
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, title)
+}
+
+// truncateTitle shortens a title to at most limit characters, ending it with
+// an ellipsis. Emoji sequences, such as flags or emoji joined by zero-width
+// joiners, and combining marks are not cut apart.
+func truncateTitle(title string, limit int) string {
+	runes := []rune(title)
+	if len(runes) <= limit {
+		return title
+	}
+
+	cut := limit - len([]rune(titleEllipsis))
+	for cut > 0 && continuesCharacter(runes, cut) {
+		cut--
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace) + titleEllipsis
+}
+
+// continuesCharacter reports whether cutting runes before index i splits a
+// user-perceived character.
+func continuesCharacter(runes []rune, i int) bool {
+	r, previous := runes[i], runes[i-1]
+	switch {
+	case r == '\u200d' || previous == '\u200d': // Zero-width joiner
+		return true
+	case r == '\ufe0e' || r == '\ufe0f': // Variation selectors
+		return true
+	case r >= 0x1f3fb && r <= 0x1f3ff: // Skin tone modifiers
+		return true
+	case unicode.In(r, unicode.Mn, unicode.Me):
+		return true
+	case isRegionalIndicator(r):
+		// Flags are pairs of regional indicators
+		pairs := 0
+		for j := i - 1; j >= 0 && isRegionalIndicator(runes[j]); j-- {
+			pairs++
+		}
+		return pairs%2 == 1
+	}
+	return false
+}
+
+func isRegionalIndicator(r rune) bool {
+	return r >= 0x1f1e6 && r <= 0x1f1ff
+}
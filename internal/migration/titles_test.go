@@ -0,0 +1,78 @@
+package migration
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestPartTitle(t *testing.T) {
+	runner := &Runner{config: &config.Config{}}
+
+	long := strings.Repeat("word ", 60)
+	emoji := strings.Repeat("👨‍👩‍👧", 100)
+	flags := "x" + strings.Repeat("🇩🇪", 200)
+	rtl := "‏שלום   עולם\t"
+
+	tests := []struct {
+		name     string
+		thread   xenforo.Thread
+		number   int
+		total    int
+		expected string
+	}{
+		{
+			name:     "Whitespace, control characters and BB-code",
+			thread:   xenforo.Thread{Title: "  [B]Hello[/B]\n\tworld\x07 [Solved] "},
+			number:   1,
+			total:    1,
+			expected: "Hello world [Solved]",
+		},
+		{
+			name:     "Empty title",
+			thread:   xenforo.Thread{ThreadID: 7, Title: " [b][/b] "},
+			number:   1,
+			total:    1,
+			expected: "Thread 7",
+		},
+		{
+			name:     "Right-to-left title",
+			thread:   xenforo.Thread{Title: rtl},
+			number:   1,
+			total:    1,
+			expected: "‏שלום עולם",
+		},
+		{
+			name:     "Long title keeps the part suffix",
+			thread:   xenforo.Thread{Title: long},
+			number:   2,
+			total:    3,
+			expected: long[:246] + "… (Part 2)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if title := runner.partTitle(tt.thread, tt.number, tt.total); title != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, title)
+			}
+		})
+	}
+
+	for _, title := range []string{emoji, flags} {
+		truncated := runner.partTitle(xenforo.Thread{Title: title}, 1, 1)
+		if length := utf8.RuneCountInString(truncated); length > maxTitleLength {
+			t.Errorf("Expected at most %d characters, got %d", maxTitleLength, length)
+		}
+		if kept := strings.TrimSuffix(truncated, titleEllipsis); !strings.HasPrefix(title, kept) || strings.HasSuffix(kept, "‍") {
+			t.Errorf("Expected a prefix of whole emoji, got %q", kept)
+		}
+	}
+	if flag := strings.TrimSuffix(runner.partTitle(xenforo.Thread{Title: flags}, 1, 1), titleEllipsis); utf8.RuneCountInString(flag)%2 != 1 {
+		t.Errorf("Expected whole flags, got %d characters", utf8.RuneCountInString(flag))
+	}
+}
@@ -0,0 +1,218 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// UploadExport creates the discussions of an export file written by an
+// export-only run. Threads are recorded in the progress file like migrated
+// ones: completed threads are skipped, and a thread interrupted by an
+// exhausted rate limit resumes from its checkpoint on the next upload, which
+// then stops. Returns the number of uploaded threads.
+func (r *Runner) UploadExport(ctx context.Context, path string) (int, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open export: %w", err)
+	}
+	defer func() { _ = file.Close() }()
+
+	uploaded := 0
+	decoder := json.NewDecoder(file)
+	for {
+		var thread ExportedThread
+		if err := decoder.Decode(&thread); errors.Is(err, io.EOF) {
+			return uploaded, nil
+		} else if err != nil {
+			return uploaded, fmt.Errorf("failed to parse export %s: %w", path, err)
+		}
+
+		if r.tracker.IsCompleted(thread.ThreadID) || len(thread.Discussions) == 0 {
+			continue
+		}
+
+		ctx := logging.With(ctx, "thread_id", thread.ThreadID)
+		logging.Infof(ctx, "\nUploading thread %d: %s", thread.ThreadID, thread.Discussions[0].Title)
+		if err := r.uploadThread(ctx, thread); err != nil {
+			if errors.Is(err, github.ErrRateLimitExhausted) {
+				logging.Errorf(ctx, "✗ Stopping at thread %d: %v", thread.ThreadID, err)
+				return uploaded, err
+			}
+			logging.Errorf(ctx, "✗ Failed to upload thread %d: %v", thread.ThreadID, err)
+			r.markFailed(ctx, thread.ThreadID, NewThreadMigrationError("upload", thread.ThreadID, err.Error(), err))
+			continue
+		}
+
+		if err := r.tracker.MarkCompleted(thread.ThreadID); err != nil {
+			logging.Warnf(ctx, "✗ Warning: Failed to mark thread %d as completed in progress tracker: %v", thread.ThreadID, err)
+		}
+		uploaded++
+	}
+}
+
+// uploadThread creates the discussions and comments of an exported thread,
+// linking the parts of split threads and checkpointing as it goes.
+func (r *Runner) uploadThread(ctx context.Context, thread ExportedThread) (err error) {
+	checkpoint, done, err := r.exportCheckpoint(ctx, thread)
+	if err != nil {
+		return err
+	}
+	defer func() { r.endCheckpoint(ctx, thread.ThreadID, checkpoint, err) }()
+	r.hostExportedAttachments(ctx, &thread)
+
+	position := 0
+	var previous *discussionPart
+	for i, discussion := range thread.Discussions {
+		number := i + 1
+		current := &discussionPart{}
+		if position < done {
+			if len(checkpoint.Discussions) < number {
+				return fmt.Errorf("checkpoint has no discussion for part %d", number)
+			}
+			current.id, current.number = checkpoint.Discussions[i].ID, checkpoint.Discussions[i].Number
+		} else {
+			body := discussion.Body
+			if previous != nil {
+				body = r.continuedFrom(number, previous.number) + body
+			}
+			current.id, current.number, err = r.createDiscussion(ctx, discussion.Title, body, discussion.CategoryID)
+			if err != nil {
+				return r.checkpointOnRateLimit(ctx, thread.ThreadID, checkpoint, err)
+			}
+			checkpoint.Discussions = append(checkpoint.Discussions, progress.DiscussionRef{ID: current.id, Number: current.number})
+			checkpoint.ResetComments()
+			r.postWritten(ctx, thread.ThreadID, discussion.PostID, checkpoint)
+		}
+		position++
+
+		for _, exported := range discussion.Comments {
+			position++
+			if position <= done {
+				continue
+			}
+
+			comment := pendingComment{
+				post:     xenforo.Post{PostID: exported.PostID, Username: exported.Author},
+				body:     exported.Body,
+				solution: exported.Answer,
+			}
+			if exported.ReplyToPostID > 0 {
+				comment.replyToID = checkpoint.CommentIDs[exported.ReplyToPostID]
+			}
+			commentID, addErr := r.addComment(ctx, comment.post, current.id, comment.replyToID, comment.body)
+			if err := r.commentAdded(ctx, current.id, comment, commentID, addErr, checkpoint); err != nil {
+				return r.checkpointOnRateLimit(ctx, thread.ThreadID, checkpoint, err)
+			}
+			r.postWritten(ctx, thread.ThreadID, exported.PostID, checkpoint)
+		}
+
+		if previous != nil && checkpoint.LinkedParts < i {
+			if err := r.linkNextPart(ctx, previous, current, number); err != nil {
+				return r.checkpointOnRateLimit(ctx, thread.ThreadID, checkpoint, err)
+			}
+			checkpoint.LinkedParts = i
+		}
+		previous = current
+	}
+
+	if thread.Locked && !r.isDryRun(ctx) {
+		if err := r.lockCheckpointDiscussions(ctx, checkpoint); err != nil {
+			return r.checkpointOnRateLimit(ctx, thread.ThreadID, checkpoint, err)
+		}
+	}
+
+	r.recordDiscussion(ctx, thread.ThreadID, checkpoint)
+	return nil
+}
+
+// hostExportedAttachments uploads the thread's attachments when an uploader
+// is configured and links the hosted URLs in its bodies. Attachments whose
+// file is missing or changed since the export keep their local link.
+func (r *Runner) hostExportedAttachments(ctx context.Context, thread *ExportedThread) {
+	if r.uploader == nil || len(thread.Attachments) == 0 || r.isDryRun(ctx) {
+		return
+	}
+
+	paths := make(map[int]string, len(thread.Attachments))
+	var uploads []xenforo.Attachment
+	for _, attachment := range thread.Attachments {
+		if sum, err := fileSHA256(attachment.Path); err != nil || sum != attachment.SHA256 {
+			logging.Warnf(ctx, "  ⚠ Not uploading %s: file missing or changed since the export", attachment.Filename)
+			continue
+		}
+		paths[attachment.AttachmentID] = attachment.Path
+		uploads = append(uploads, xenforo.Attachment{AttachmentID: attachment.AttachmentID, Filename: attachment.Filename})
+	}
+
+	logging.Infof(ctx, "  Uploading attachments...")
+	hostedURLs, err := r.uploader.UploadThreadAttachments(ctx, thread.ThreadID, uploads, func(attachment xenforo.Attachment) string {
+		return paths[attachment.AttachmentID]
+	})
+	if err != nil {
+		logging.Warnf(ctx, "✗ Warning: Failed to upload attachments for thread %d: %v", thread.ThreadID, err)
+	}
+
+	var links []string
+	for _, attachment := range thread.Attachments {
+		if url := hostedURLs[attachment.AttachmentID]; url != "" {
+			links = append(links, "]("+attachment.Link+")", "]("+url+")")
+		}
+	}
+	if len(links) == 0 {
+		return
+	}
+
+	replacer := strings.NewReplacer(links...)
+	for i := range thread.Discussions {
+		discussion := &thread.Discussions[i]
+		discussion.Body = replacer.Replace(discussion.Body)
+		for j := range discussion.Comments {
+			discussion.Comments[j].Body = replacer.Replace(discussion.Comments[j].Body)
+		}
+	}
+}
+
+// exportCheckpoint returns the checkpoint of a thread interrupted during an
+// earlier upload and the number of its posts already written.
+func (r *Runner) exportCheckpoint(ctx context.Context, thread ExportedThread) (*progress.ThreadCheckpoint, int, error) {
+	checkpoint, ok := r.tracker.Checkpoint(thread.ThreadID)
+	if !ok || r.isDryRun(ctx) {
+		return &progress.ThreadCheckpoint{CommentIDs: make(map[int]string)}, 0, nil
+	}
+
+	var postIDs []int
+	for _, discussion := range thread.Discussions {
+		postIDs = append(postIDs, discussion.PostID)
+		for _, comment := range discussion.Comments {
+			postIDs = append(postIDs, comment.PostID)
+		}
+	}
+	for i, postID := range postIDs {
+		if postID == checkpoint.LastPostID {
+			logging.Infof(ctx, "  Resuming after post %d (%d of %d posts already uploaded)", postID, i+1, len(postIDs))
+			return checkpoint, i + 1, nil
+		}
+	}
+	return nil, 0, fmt.Errorf("checkpoint post %d is not part of exported thread %d", checkpoint.LastPostID, thread.ThreadID)
+}
+
+// postWritten checkpoints the thread after a written post and pauses before
+// the next GitHub write.
+func (r *Runner) postWritten(ctx context.Context, threadID, postID int, checkpoint *progress.ThreadCheckpoint) {
+	checkpoint.LastPostID = postID
+	r.saveCheckpoint(ctx, threadID, checkpoint, 1)
+	if !r.isDryRun(ctx) {
+		time.Sleep(r.postDelay)
+	}
+}
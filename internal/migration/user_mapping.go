@@ -0,0 +1,58 @@
+package migration
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+)
+
+// inviteMappedUsers invites the GitHub accounts of the user mapping to the
+// repository with read access, so the authors mentioned in migrated posts can
+// follow their discussions. Only organization repositories have read-only
+// collaborators: collaborators of a user's repository get write access, so
+// no one is invited there. Failed invitations are logged and do not stop the
+// migration.
+func (r *Runner) inviteMappedUsers(ctx context.Context) {
+	mapping := r.config.Migration.UserMapping
+	if !r.config.Migration.InviteMappedUsers || len(mapping) == 0 || r.githubClient == nil {
+		return
+	}
+
+	userIDs := make([]int, 0, len(mapping))
+	for userID := range mapping {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Ints(userIDs)
+
+	repo := r.config.GitHub.Repository
+	organization, err := r.githubClient.OwnerIsOrganization(ctx, repo)
+	if err != nil {
+		logging.Errorf(ctx, "✗ Not inviting mapped users: %v", err)
+		return
+	}
+	if !organization {
+		logging.Warnf(ctx, "⚠ Not inviting mapped users: %s is owned by a user, whose collaborators get write access", repo)
+		return
+	}
+
+	if r.isDryRun(ctx) {
+		logging.Infof(ctx, "[DRY-RUN] Would invite %d mapped GitHub users to %s", len(userIDs), repo)
+		return
+	}
+
+	logging.Infof(ctx, "Inviting %d mapped GitHub users to %s...", len(userIDs), repo)
+	for _, userID := range userIDs {
+		login := strings.TrimPrefix(mapping[userID], "@")
+		invited, err := r.githubClient.InviteCollaborator(ctx, repo, login, "pull")
+		switch {
+		case err != nil:
+			logging.Errorf(ctx, "  ✗ Failed to invite @%s (user %d): %v", login, userID, err)
+		case invited:
+			logging.Infof(ctx, "  ✓ Invited @%s (user %d)", login, userID)
+		default:
+			logging.Infof(ctx, "  ✓ @%s (user %d) already has access", login, userID)
+		}
+	}
+}
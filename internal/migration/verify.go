@@ -0,0 +1,233 @@
+package migration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/config"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/github"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/progress"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// DiscussionFetcher fetches the migrated discussions compared by Verify.
+type DiscussionFetcher interface {
+	GetDiscussionContent(ctx context.Context, repo string, number int) (*github.DiscussionContent, error)
+}
+
+var (
+	// Attachments, links and URLs differ between the source and the migrated
+	// posts, so they are left out of the compared content.
+	verifyAttachmentPattern = regexp.MustCompile(`(?is)\[attach[^\]]*\].*?\[/attach\]`)
+	verifyLinkTargetPattern = regexp.MustCompile(`\]\([^)]*\)`)
+	verifyURLPattern        = regexp.MustCompile(`https?://\S+`)
+	verifyWordPattern       = regexp.MustCompile(`[\p{L}\p{N}]+`)
+)
+
+// threadVerification collects the differences found for one thread.
+type threadVerification struct {
+	threadID      int
+	title         string
+	discussions   []int
+	sourcePosts   int
+	migratedPosts int
+	problems      []string
+}
+
+// Verify re-fetches the discussions recorded for migrated threads and
+// compares them against the source: discussion titles, post counts, and the
+// content of every post, which must appear in full in its migrated body.
+// Threads that differ are written to w as a report. Returns the number of
+// threads that differ; an exhausted rate limit stops the verification.
+func Verify(ctx context.Context, cfg *config.Config, source ConversionSource, fetcher DiscussionFetcher, recorded map[int]progress.DiscussionRef, w io.Writer) (int, error) {
+	threadIDs := make([]int, 0, len(recorded))
+	for threadID := range recorded {
+		threadIDs = append(threadIDs, threadID)
+	}
+	sort.Ints(threadIDs)
+
+	// Titles and conversions are those a runner with this configuration produces
+	r := &Runner{config: cfg, processor: newMessageProcessor(cfg)}
+	differing := 0
+	for _, threadID := range threadIDs {
+		result, err := r.verifyThread(ctx, source, fetcher, threadID, recorded[threadID])
+		if err != nil {
+			return differing, err
+		}
+		if len(result.problems) == 0 {
+			continue
+		}
+
+		differing++
+		_, _ = fmt.Fprintf(w, "Thread %d %q -> %s: %d of %d posts migrated\n",
+			result.threadID, result.title, discussionNumbers(result.discussions), result.migratedPosts, result.sourcePosts)
+		for _, problem := range result.problems {
+			_, _ = fmt.Fprintf(w, "  - %s\n", problem)
+		}
+	}
+
+	_, _ = fmt.Fprintf(w, "%d of %d threads verified, %d differ\n", len(threadIDs)-differing, len(threadIDs), differing)
+	return differing, nil
+}
+
+func (r *Runner) verifyThread(ctx context.Context, source ConversionSource, fetcher DiscussionFetcher, threadID int, ref progress.DiscussionRef) (*threadVerification, error) {
+	result := &threadVerification{threadID: threadID}
+	refs := append([]progress.DiscussionRef{ref}, ref.Parts...)
+	for _, part := range refs {
+		result.discussions = append(result.discussions, part.Number)
+	}
+
+	thread, err := source.GetThread(ctx, threadID)
+	if err != nil {
+		result.problems = append(result.problems, fmt.Sprintf("failed to fetch the source thread: %v", err))
+		return result, nil
+	}
+	result.title = thread.Title
+
+	posts, err := source.GetPosts(ctx, *thread)
+	if err != nil {
+		result.problems = append(result.problems, fmt.Sprintf("failed to fetch the source posts: %v", err))
+		return result, nil
+	}
+	result.sourcePosts = len(posts)
+
+	var migrated []string
+	for i, part := range refs {
+		content, err := fetcher.GetDiscussionContent(ctx, r.config.GitHub.Repository, part.Number)
+		if err != nil {
+			if errors.Is(err, github.ErrRateLimitExhausted) {
+				return nil, err
+			}
+			result.problems = append(result.problems, fmt.Sprintf("failed to fetch discussion #%d: %v", part.Number, err))
+			return result, nil
+		}
+
+		expected := r.partTitle(*thread, i+1, len(refs))
+		if content.Title != expected {
+			result.problems = append(result.problems, fmt.Sprintf("discussion #%d is titled %q instead of %q", part.Number, content.Title, expected))
+		}
+
+		migrated = append(migrated, content.Body)
+		for _, comment := range content.Comments {
+			// Links between the parts of a split thread are not posts
+			if strings.HasPrefix(comment, nextPartLinkPrefix) {
+				continue
+			}
+			// Continuations of a long post are part of it
+			if continuation, ok := strings.CutPrefix(comment, strings.TrimSpace(continuationPrefix)); ok {
+				migrated[len(migrated)-1] += "\n\n" + continuation
+				continue
+			}
+			migrated = append(migrated, comment)
+		}
+	}
+	result.migratedPosts = len(migrated)
+
+	result.problems = append(result.problems, r.comparePosts(posts, migrated)...)
+	return result, nil
+}
+
+// comparePosts matches the source posts in order against the migrated bodies.
+// A post whose words appear in full in the next unmatched body (or a later
+// one, when bodies are missing) is migrated; a post whose words start the end
+// of the next body is truncated; any other post is missing.
+func (r *Runner) comparePosts(posts []xenforo.Post, migrated []string) []string {
+	migratedWords := make([][]string, len(migrated))
+	for i, body := range migrated {
+		migratedWords[i] = contentWords(body)
+	}
+
+	var problems, missing []string
+	next := 0
+	for _, post := range posts {
+		words := contentWords(verifyAttachmentPattern.ReplaceAllString(r.processor.ProcessContent(post.Message), ""))
+
+		found := -1
+		for i := next; i < len(migrated); i++ {
+			if matchedWords(migratedWords[i], words) == len(words) {
+				found = i
+				break
+			}
+		}
+		if found >= 0 {
+			next = found + 1
+			continue
+		}
+
+		if next < len(migrated) {
+			if matched := truncatedWords(migratedWords[next], words); matched > 0 {
+				problems = append(problems, fmt.Sprintf("post %d is truncated: %d of %d words (content hash %s)",
+					post.PostID, matched, len(words), contentHash(words)))
+				next++
+				continue
+			}
+		}
+		missing = append(missing, fmt.Sprint(post.PostID))
+	}
+
+	if len(missing) > 0 {
+		problems = append([]string{fmt.Sprintf("%d posts missing: %s", len(missing), strings.Join(missing, ", "))}, problems...)
+	}
+	return problems
+}
+
+// contentWords returns the lower-cased words of a post, without links and
+// URLs.
+func contentWords(text string) []string {
+	text = verifyLinkTargetPattern.ReplaceAllString(text, "]")
+	text = verifyURLPattern.ReplaceAllString(text, "")
+	return verifyWordPattern.FindAllString(strings.ToLower(text), -1)
+}
+
+// matchedWords returns the length of the longest prefix of want that appears
+// as a contiguous run in have.
+func matchedWords(have, want []string) int {
+	best := 0
+	for start := range have {
+		n := 0
+		for n < len(want) && start+n < len(have) && have[start+n] == want[n] {
+			n++
+		}
+		if n > best {
+			best = n
+			if best == len(want) {
+				break
+			}
+		}
+	}
+	return best
+}
+
+// truncatedWords returns the length of the longest prefix of want that ends
+// have, as left by a body cut off part-way through the post.
+func truncatedWords(have, want []string) int {
+	for n := min(len(have), len(want)-1); n > 0; n-- {
+		if slices.Equal(have[len(have)-n:], want[:n]) {
+			return n
+		}
+	}
+	return 0
+}
+
+// contentHash returns a short hash of a post's words, identifying the source
+// content in reports.
+func contentHash(words []string) string {
+	sum := sha256.Sum256([]byte(strings.Join(words, " ")))
+	return hex.EncodeToString(sum[:6])
+}
+
+func discussionNumbers(numbers []int) string {
+	parts := make([]string, len(numbers))
+	for i, number := range numbers {
+		parts[i] = fmt.Sprintf("#%d", number)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,68 @@
+package migration
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestVerify(t *testing.T) {
+	forum := newTestForum(2)
+	forum.threads[0].ReplyCount = 2
+	forum.posts[1] = []xenforo.Post{
+		{PostID: 10, ThreadID: 1, Username: "author", PostDate: 1640000000, Message: "Question here"},
+		{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: `[QUOTE="author, post: 10, member: 1"]Question here[/QUOTE] First answer`},
+		{PostID: 12, ThreadID: 1, Username: "bob", PostDate: 1640000200, Message: "Another answer with several words"},
+	}
+
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, nil)
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	var report strings.Builder
+	differing, err := Verify(context.Background(), runner.config, runner.xenforoSource.(*xenforo.Client), runner.githubClient, runner.tracker.Discussions(), &report)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if differing != 0 {
+		t.Fatalf("Expected a faithful migration to verify, got:\n%s", report.String())
+	}
+
+	// Lose the reply, truncate the last comment and rename thread 2's discussion
+	var kept []fakeComment
+	for _, comment := range api.comments {
+		switch {
+		case strings.Contains(comment.Body, "First answer"):
+			continue
+		case strings.Contains(comment.Body, "Another answer"):
+			comment.Body = comment.Body[:strings.Index(comment.Body, "several")]
+		}
+		kept = append(kept, comment)
+	}
+	api.comments = kept
+	api.discussions[1].Title = "Renamed"
+
+	report.Reset()
+	differing, err = Verify(context.Background(), runner.config, runner.xenforoSource.(*xenforo.Client), runner.githubClient, runner.tracker.Discussions(), &report)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if differing != 2 {
+		t.Errorf("Expected both threads to differ, got %d", differing)
+	}
+	for _, expected := range []string{
+		`Thread 1 "Thread 1" -> #1: 2 of 3 posts migrated`,
+		"  - 1 posts missing: 11\n",
+		"  - post 12 is truncated: 3 of 5 words",
+		`  - discussion #2 is titled "Renamed" instead of "Thread 2"`,
+		"0 of 2 threads verified, 2 differ\n",
+	} {
+		if !strings.Contains(report.String(), expected) {
+			t.Errorf("Expected the report to contain %q, got:\n%s", expected, report.String())
+		}
+	}
+}
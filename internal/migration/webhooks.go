@@ -0,0 +1,170 @@
+package migration
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync/atomic"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// WebhookPath is the path XenForo webhooks are delivered to.
+const WebhookPath = "/webhooks/xenforo"
+
+// webhookSecretHeader carries the secret XenForo sends with every webhook.
+const webhookSecretHeader = "XF-Webhook-Secret"
+
+const (
+	maxWebhookBytes  = 1 << 20 // Largest accepted webhook body
+	webhookQueueSize = 100     // Events waiting to be mirrored before webhooks are refused
+)
+
+// WebhookEvent is the body of a XenForo 2.3 webhook: the event on a piece of
+// content and the content's API representation.
+type WebhookEvent struct {
+	ContentType string          `json:"content_type"` // e.g. "thread" or "post"
+	ContentID   int             `json:"content_id"`
+	Event       string          `json:"event"` // e.g. "insert", "update" or "delete"
+	Data        json.RawMessage `json:"data"`
+}
+
+// WebhookServer mirrors the threads and posts announced by XenForo webhooks
+// to GitHub. Received events are queued and mirrored one at a time, in the
+// order they arrive: a new thread is migrated like in a regular run, and a
+// new post appends the thread's posts after the last one recorded for its
+// discussion, as the sync command does. Missed or failed events are caught
+// up by the next sync.
+type WebhookServer struct {
+	runner  *Runner
+	threads ConversionSource
+	secret  string
+	events  chan WebhookEvent
+}
+
+// NewWebhookServer creates a server mirroring events with runner and looking
+// up the threads of events in threads. Webhooks must carry secret in the
+// XF-Webhook-Secret header. Migrator.Serve requires a secret unless run with
+// --insecure, the only case in which secret is empty and webhooks are
+// accepted unauthenticated.
+func NewWebhookServer(runner *Runner, threads ConversionSource, secret string) *WebhookServer {
+	return &WebhookServer{
+		runner:  runner,
+		threads: threads,
+		secret:  secret,
+		events:  make(chan WebhookEvent, webhookQueueSize),
+	}
+}
+
+// Handler returns the HTTP handler receiving webhooks at WebhookPath, with a
+// health check at /healthz.
+func (s *WebhookServer) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST "+WebhookPath, s.receive)
+	mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return mux
+}
+
+// receive queues a webhook event. Events other than new threads and posts
+// are acknowledged and dropped; a full queue is refused so XenForo retries
+// the delivery later.
+func (s *WebhookServer) receive(w http.ResponseWriter, req *http.Request) {
+	if s.secret != "" && subtle.ConstantTimeCompare([]byte(req.Header.Get(webhookSecretHeader)), []byte(s.secret)) != 1 {
+		http.Error(w, "invalid webhook secret", http.StatusUnauthorized)
+		return
+	}
+
+	var event WebhookEvent
+	if err := json.NewDecoder(io.LimitReader(req.Body, maxWebhookBytes)).Decode(&event); err != nil {
+		http.Error(w, "invalid webhook body", http.StatusBadRequest)
+		return
+	}
+
+	if event.Event != "insert" || (event.ContentType != "thread" && event.ContentType != "post") {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	select {
+	case s.events <- event:
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		logging.Warnf(req.Context(), "⚠ Refusing %s %d: %d events are waiting", event.ContentType, event.ContentID, webhookQueueSize)
+		http.Error(w, "too many pending events", http.StatusServiceUnavailable)
+	}
+}
+
+// Run mirrors queued events until ctx is cancelled. Events still queued then
+// are left to the next sync.
+func (s *WebhookServer) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			if pending := len(s.events); pending > 0 {
+				logging.Warnf(ctx, "⚠ %d received events were not mirrored: run sync to catch up", pending)
+			}
+			return
+		case event := <-s.events:
+			if err := s.handle(ctx, event); err != nil {
+				logging.Errorf(ctx, "✗ Failed to mirror %s %d: %v", event.ContentType, event.ContentID, err)
+			}
+		}
+	}
+}
+
+// handle mirrors a new thread or post. The thread is fetched from the API
+// rather than taken from the event, and threads of other nodes are ignored.
+func (s *WebhookServer) handle(ctx context.Context, event WebhookEvent) error {
+	r := s.runner
+	atomic.StoreInt32(&r.rateLimited, 0)
+
+	threadID := 0
+	switch event.ContentType {
+	case "thread":
+		threadID = event.ContentID
+	case "post":
+		var post xenforo.Post
+		if err := json.Unmarshal(event.Data, &post); err != nil {
+			return fmt.Errorf("failed to parse post: %w", err)
+		}
+		threadID = post.ThreadID
+	}
+	if threadID == 0 {
+		return errors.New("event names no thread")
+	}
+	found, err := s.threads.GetThread(ctx, threadID)
+	if err != nil {
+		return err
+	}
+	thread := *found
+	if thread.NodeID != r.config.GitHub.XenForoNodeID {
+		return nil
+	}
+
+	ctx = logging.With(ctx, "thread_id", thread.ThreadID)
+	if ref, ok := r.tracker.Discussion(thread.ThreadID); ok {
+		since, hasSince := r.syncedSince()
+		_, err := r.syncThread(ctx, thread, ref, since, hasSince)
+		return err
+	}
+	if r.tracker.IsCompleted(thread.ThreadID) {
+		return nil
+	}
+
+	threads, err := r.filterThreads(ctx, []xenforo.Thread{thread})
+	if err != nil || len(threads) == 0 {
+		return err
+	}
+	r.migrateThread(ctx, thread, 1, 1)
+	if atomic.LoadInt32(&r.rateLimited) == 1 {
+		logging.Warnf(ctx, "⚠ The GitHub API rate limit was exhausted: thread %d is left to the next sync", thread.ThreadID)
+	}
+	return nil
+}
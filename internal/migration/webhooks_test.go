@@ -0,0 +1,95 @@
+package migration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestWebhookServer(t *testing.T) {
+	forum := newTestForum(1)
+	api := &fakeDiscussionsAPI{}
+	runner := newWritingTestRunner(t, forum, api, nil)
+	if err := runner.RunMigration(context.Background()); err != nil {
+		t.Fatalf("RunMigration returned error: %v", err)
+	}
+
+	threads, ok := runner.xenforoSource.(ConversionSource)
+	if !ok {
+		t.Fatal("Expected the test forum source to look up threads")
+	}
+	server := NewWebhookServer(runner, threads, "s3cret")
+	handler := server.Handler()
+
+	deliver := func(secret, contentType string, contentID int, data any) int {
+		t.Helper()
+		payload, err := json.Marshal(data)
+		if err != nil {
+			t.Fatalf("Failed to encode webhook data: %v", err)
+		}
+		body, _ := json.Marshal(WebhookEvent{ContentType: contentType, ContentID: contentID, Event: "insert", Data: payload})
+		req := httptest.NewRequest(http.MethodPost, WebhookPath, strings.NewReader(string(body)))
+		req.Header.Set(webhookSecretHeader, secret)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	mirror := func() {
+		t.Helper()
+		if err := server.handle(context.Background(), <-server.events); err != nil {
+			t.Fatalf("handle returned error: %v", err)
+		}
+	}
+
+	post := xenforo.Post{PostID: 11, ThreadID: 1, Username: "alice", PostDate: 1640000100, Message: "New reply"}
+	if code := deliver("wrong", "post", post.PostID, post); code != http.StatusUnauthorized {
+		t.Errorf("Expected a wrong secret to be refused with 401, got %d", code)
+	}
+	if len(server.events) != 0 {
+		t.Fatal("Expected a refused webhook not to be queued")
+	}
+
+	// A new post in a migrated thread is appended to its discussion
+	forum.posts[1] = append(forum.posts[1], post)
+	if code := deliver("s3cret", "post", post.PostID, post); code != http.StatusAccepted {
+		t.Fatalf("Expected the post webhook to be accepted, got %d", code)
+	}
+	mirror()
+	if len(api.comments) != 1 || api.comments[0].DiscussionID != "D_1" || !strings.Contains(api.comments[0].Body, "New reply") {
+		t.Fatalf("Expected the new post to be appended to discussion D_1, got %+v", api.comments)
+	}
+	if ref, _ := runner.tracker.Discussion(1); ref.LastPostID != 11 {
+		t.Errorf("Expected post 11 to be recorded as the last post, got %d", ref.LastPostID)
+	}
+
+	// A new thread is migrated as the API returns it, whatever the event says
+	thread := xenforo.Thread{ThreadID: 2, Title: "Thread 2", NodeID: 1, Username: "bob", PostDate: 1640000200}
+	forum.threads = append(forum.threads, thread)
+	forum.posts[2] = []xenforo.Post{{PostID: 20, ThreadID: 2, Username: "bob", PostDate: 1640000200, Message: "New thread"}}
+	forged := xenforo.Thread{ThreadID: 2, Title: "Forged", NodeID: 1}
+	if code := deliver("s3cret", "thread", thread.ThreadID, forged); code != http.StatusAccepted {
+		t.Fatalf("Expected the thread webhook to be accepted, got %d", code)
+	}
+	mirror()
+	if len(api.discussions) != 2 || api.discussions[1].Title != "Thread 2" {
+		t.Errorf("Expected the new thread to be migrated, got %+v", api.discussions)
+	}
+	if !runner.tracker.IsCompleted(2) {
+		t.Error("Expected the new thread to be marked completed")
+	}
+
+	// Threads of other nodes are ignored, even when the event claims otherwise
+	forum.threads = append(forum.threads, xenforo.Thread{ThreadID: 3, Title: "Thread 3", NodeID: 2})
+	if code := deliver("s3cret", "thread", 3, xenforo.Thread{ThreadID: 3, NodeID: 1}); code != http.StatusAccepted {
+		t.Fatalf("Expected the thread webhook to be accepted, got %d", code)
+	}
+	mirror()
+	if len(api.discussions) != 2 {
+		t.Errorf("Expected a thread of another node to be ignored, got %d discussions", len(api.discussions))
+	}
+}
@@ -0,0 +1,139 @@
+// Package notify sends migration run summaries to external services such as
+// Slack, Discord or custom endpoints via JSON webhooks.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+)
+
+// Summary is the JSON payload posted to the webhook after a run.
+type Summary struct {
+	Repository      string `json:"repository"`
+	NodeID          int    `json:"node_id"`
+	DryRun          bool   `json:"dry_run"`
+	ThreadsTotal    int    `json:"threads_total"`
+	ThreadsMigrated int64  `json:"threads_migrated"`
+	ThreadsFailed   int64  `json:"threads_failed"`
+	StartedAt       string `json:"started_at"`
+	FinishedAt      string `json:"finished_at"`
+	DurationSeconds int64  `json:"duration_seconds"`
+	Text            string `json:"text"` // Human-readable line for chat webhooks
+}
+
+// WebhookNotifier posts run summaries to a webhook URL with retries on
+// transient failures. Configured secrets are redacted from every payload.
+type WebhookNotifier struct {
+	url        string
+	client     *http.Client
+	maxRetries int
+	retryDelay time.Duration
+	secrets    []string
+}
+
+// NewWebhookNotifier creates a notifier for the given webhook URL. Secrets
+// (tokens, API keys) are replaced with "[REDACTED]" before sending.
+func NewWebhookNotifier(webhookURL string, secrets ...string) *WebhookNotifier {
+	var nonEmpty []string
+	for _, secret := range secrets {
+		if strings.TrimSpace(secret) != "" {
+			nonEmpty = append(nonEmpty, secret)
+		}
+	}
+
+	return &WebhookNotifier{
+		url:        webhookURL,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		maxRetries: 3,
+		retryDelay: 2 * time.Second,
+		secrets:    nonEmpty,
+	}
+}
+
+// SetRetry configures the number of retries and the base delay between them.
+func (n *WebhookNotifier) SetRetry(maxRetries int, delay time.Duration) *WebhookNotifier {
+	n.maxRetries = maxRetries
+	n.retryDelay = delay
+	return n
+}
+
+// Send posts the summary to the webhook. Network errors, 429 and 5xx
+// responses are retried with linear backoff; other failures are returned.
+func (n *WebhookNotifier) Send(ctx context.Context, summary Summary) error {
+	data, err := json.Marshal(summary)
+	if err != nil {
+		return fmt.Errorf("failed to encode webhook payload: %w", err)
+	}
+	payload := []byte(n.redact(string(data)))
+
+	var lastErr error
+	for attempt := 0; attempt <= n.maxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("webhook cancelled: %w", ctx.Err())
+			case <-time.After(time.Duration(attempt) * n.retryDelay):
+			}
+		}
+
+		retryable, err := n.post(ctx, payload)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retryable {
+			break
+		}
+		logging.Warnf(ctx, "⚠ Webhook delivery to %s failed (attempt %d/%d): %v", RedactURL(n.url), attempt+1, n.maxRetries+1, err)
+	}
+
+	return fmt.Errorf("webhook delivery to %s failed: %w", RedactURL(n.url), lastErr)
+}
+
+// post sends the payload once and reports whether a failure is retryable.
+func (n *WebhookNotifier) post(ctx context.Context, payload []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, n.url, bytes.NewReader(payload))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		// Strip the URL from transport errors so secrets in the path stay out of logs.
+		return ctx.Err() == nil, fmt.Errorf("request failed: %s", n.redact(strings.ReplaceAll(err.Error(), n.url, RedactURL(n.url))))
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	retryable := resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return retryable, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+func (n *WebhookNotifier) redact(text string) string {
+	for _, secret := range n.secrets {
+		text = strings.ReplaceAll(text, secret, "[REDACTED]")
+	}
+	return text
+}
+
+// RedactURL hides the path and query of a webhook URL, which commonly embed
+// credentials (e.g., Slack and Discord webhook tokens).
+func RedactURL(webhookURL string) string {
+	parsed, err := url.Parse(webhookURL)
+	if err != nil || parsed.Host == "" {
+		return "[REDACTED]"
+	}
+	return parsed.Scheme + "://" + parsed.Host + "/[REDACTED]"
+}
@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWebhookNotifier_Send(t *testing.T) {
+	var received Summary
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Failed to decode payload: %v", err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	summary := Summary{
+		Repository:      "owner/repo",
+		NodeID:          3,
+		ThreadsTotal:    10,
+		ThreadsMigrated: 9,
+		ThreadsFailed:   1,
+		Text:            "done",
+	}
+
+	if err := NewWebhookNotifier(server.URL).Send(context.Background(), summary); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if contentType != "application/json" {
+		t.Errorf("Expected JSON content type, got %q", contentType)
+	}
+	if received != summary {
+		t.Errorf("Expected payload %+v, got %+v", summary, received)
+	}
+}
+
+func TestWebhookNotifier_RetriesTransientFailures(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL).SetRetry(3, time.Millisecond)
+	if err := notifier.Send(context.Background(), Summary{}); err != nil {
+		t.Fatalf("Send should succeed after retries, got: %v", err)
+	}
+	if attempts != 3 {
+		t.Errorf("Expected 3 attempts, got %d", attempts)
+	}
+}
+
+func TestWebhookNotifier_DoesNotRetryClientErrors(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL+"/hooks/secret-token").SetRetry(3, time.Millisecond)
+	err := notifier.Send(context.Background(), Summary{})
+	if err == nil {
+		t.Fatal("Expected error for client error response")
+	}
+	if attempts != 1 {
+		t.Errorf("Expected a single attempt, got %d", attempts)
+	}
+	if strings.Contains(err.Error(), "secret-token") {
+		t.Errorf("Error should not expose the webhook path: %v", err)
+	}
+}
+
+func TestWebhookNotifier_RedactsSecrets(t *testing.T) {
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		body = string(data)
+	}))
+	defer server.Close()
+
+	notifier := NewWebhookNotifier(server.URL, "ghp_secret_token", "", "xf_api_key")
+	summary := Summary{Text: "failed with ghp_secret_token and xf_api_key"}
+	if err := notifier.Send(context.Background(), summary); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+
+	if strings.Contains(body, "ghp_secret_token") || strings.Contains(body, "xf_api_key") {
+		t.Errorf("Payload contains secrets: %s", body)
+	}
+	if !strings.Contains(body, "[REDACTED]") {
+		t.Errorf("Expected redaction marker in payload: %s", body)
+	}
+}
+
+func TestRedactURL(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"https://hooks.slack.com/services/T000/B000/XXXX", "https://hooks.slack.com/[REDACTED]"},
+		{"https://discord.com/api/webhooks/1/token?wait=true", "https://discord.com/[REDACTED]"},
+		{"not a url", "[REDACTED]"},
+	}
+
+	for _, tt := range tests {
+		if result := RedactURL(tt.input); result != tt.expected {
+			t.Errorf("RedactURL(%q) = %q, expected %q", tt.input, result, tt.expected)
+		}
+	}
+}
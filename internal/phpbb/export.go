@@ -0,0 +1,118 @@
+// Package phpbb reads a phpBB board from a JSON export and provides its
+// forums, topics and posts as the XenForo models the migration works with,
+// so phpBB boards can be migrated like XenForo forums.
+//
+// The export holds the rows of the phpBB tables the migration needs, with
+// their phpBB column names, e.g. dumped with a JSON-producing SQL client:
+//
+//	{
+//	  "forums":      [{"forum_id": 2, "parent_id": 0, "left_id": 1, "forum_name": "Support", "forum_type": 1}],
+//	  "topics":      [{"topic_id": 5, "forum_id": 2, "topic_title": "Hello", ...}],
+//	  "posts":       [{"post_id": 10, "topic_id": 5, "poster_id": 3, "post_text": "...", "bbcode_uid": "abc123", ...}],
+//	  "attachments": [{"attach_id": 1, "post_msg_id": 10, "physical_filename": "3_0a1b", "real_filename": "a.png", ...}],
+//	  "users":       [{"user_id": 3, "username": "alice"}]
+//	}
+package phpbb
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Export is a phpBB board export.
+type Export struct {
+	Forums      []Forum      `json:"forums"`
+	Topics      []Topic      `json:"topics"`
+	Posts       []Post       `json:"posts"`
+	Attachments []Attachment `json:"attachments"`
+	Users       []User       `json:"users"`
+}
+
+// Forum is a row of phpbb_forums.
+type Forum struct {
+	ForumID  int    `json:"forum_id"`
+	ParentID int    `json:"parent_id"`
+	LeftID   int    `json:"left_id"` // Position in the board's forum tree
+	Name     string `json:"forum_name"`
+	Desc     string `json:"forum_desc"`
+	Type     int    `json:"forum_type"` // forumTypeCategory, forumTypePost, or 2 for links
+}
+
+// phpBB forum types.
+const (
+	forumTypeCategory = 0
+	forumTypePost     = 1
+)
+
+// Topic is a row of phpbb_topics.
+type Topic struct {
+	TopicID         int    `json:"topic_id"`
+	ForumID         int    `json:"forum_id"`
+	Title           string `json:"topic_title"` // HTML-escaped, as phpBB stores it
+	FirstPostID     int    `json:"topic_first_post_id"`
+	FirstPosterName string `json:"topic_first_poster_name"`
+	Time            int64  `json:"topic_time"`
+	LastPostTime    int64  `json:"topic_last_post_time"`
+	Views           int    `json:"topic_views"`
+	Status          int    `json:"topic_status"`     // topicLocked for locked topics
+	Visibility      int    `json:"topic_visibility"` // itemApproved for visible topics
+	MovedID         int    `json:"topic_moved_id"`   // Topic a shadow topic was moved to (0 = not a shadow)
+	PostsApproved   int    `json:"topic_posts_approved"`
+}
+
+// phpBB item states.
+const (
+	itemApproved = 1
+	topicLocked  = 1
+)
+
+// Post is a row of phpbb_posts.
+type Post struct {
+	PostID     int    `json:"post_id"`
+	TopicID    int    `json:"topic_id"`
+	PosterID   int    `json:"poster_id"`
+	Username   string `json:"post_username"` // Guest name; members are named by their user
+	Time       int64  `json:"post_time"`
+	Text       string `json:"post_text"`
+	BBCodeUID  string `json:"bbcode_uid"`
+	Visibility int    `json:"post_visibility"`
+}
+
+// Attachment is a row of phpbb_attachments.
+type Attachment struct {
+	AttachID         int    `json:"attach_id"`
+	PostID           int    `json:"post_msg_id"`
+	PhysicalFilename string `json:"physical_filename"` // File name below the board's files directory
+	RealFilename     string `json:"real_filename"`
+	Filesize         int64  `json:"filesize"`
+	InMessage        int    `json:"in_message"` // 1 for attachments of private messages
+}
+
+// User is a row of phpbb_users.
+type User struct {
+	UserID     int    `json:"user_id"`
+	Username   string `json:"username"`
+	Avatar     string `json:"user_avatar"`
+	AvatarType string `json:"user_avatar_type"` // avatarRemote for avatars linked by URL
+}
+
+// avatarRemote is the avatar type of avatars linked by URL.
+const avatarRemote = "avatar.driver.remote"
+
+// anonymousUserID is the user ID phpBB records for guest posts.
+const anonymousUserID = 1
+
+// LoadExport reads a JSON export.
+func LoadExport(path string) (*Export, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read phpBB export: %w", err)
+	}
+
+	var export Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		return nil, fmt.Errorf("failed to parse phpBB export %s: %w", path, err)
+	}
+	return &export, nil
+}
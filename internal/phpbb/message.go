@@ -0,0 +1,78 @@
+package phpbb
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	// xmlTag matches the markup of messages stored as XML by phpBB 3.2+, e.g.
+	// <r><B><s>[b]</s>bold<e>[/b]</e></B></r>. The BB-code is kept as text
+	// inside it, so removing the tags restores the original message.
+	xmlTag = regexp.MustCompile(`<[^>]*>`)
+
+	// Markup phpBB 3.0 and 3.1 store around smilies, magic links and inline
+	// attachment names
+	legacySmiley     = regexp.MustCompile(`<!-- s(\S+) --><img [^>]*><!-- s\S+ -->`)
+	legacyMagicLink  = regexp.MustCompile(`<!-- [lmwe] --><a [^>]*href="([^"]*)"[^>]*>.*?</a><!-- [lmwe] -->`)
+	legacyAttachName = regexp.MustCompile(`<!-- ia\d+ -->`)
+
+	listEnd          = regexp.MustCompile(`(?i)\[/list:[uo]\]`)
+	listItemEnd      = regexp.MustCompile(`(?i)\[/\*(?::m)?\]`)
+	quoteAttribution = regexp.MustCompile(`(?i)\[quote="([^"]*)"([^\]]*)\]`)
+	quoteAttribute   = regexp.MustCompile(`(\w+)=(\d+)`)
+	inlineAttachment = regexp.MustCompile(`(?is)\[attachment=(\d+)\](.*?)\[/attachment\]`)
+)
+
+// convertMessage turns a stored phpBB message into the BB-code dialect of
+// XenForo: the BB-code UID suffixes and stored HTML are removed, quotes
+// reference posts as [QUOTE="name, post: 1, member: 2"] and inline
+// attachments become [ATTACH]<attach_id>[/ATTACH]. attachmentIDs are the
+// attachments of the post in phpBB's inline index order.
+func convertMessage(text, bbcodeUID string, attachmentIDs []int) string {
+	if strings.HasPrefix(text, "<r>") || strings.HasPrefix(text, "<t>") {
+		text = strings.ReplaceAll(text, "<br/>", "")
+		text = xmlTag.ReplaceAllString(text, "")
+	} else {
+		if bbcodeUID != "" {
+			text = strings.ReplaceAll(text, ":"+bbcodeUID+"]", "]")
+		}
+		text = legacySmiley.ReplaceAllString(text, "$1")
+		text = legacyMagicLink.ReplaceAllString(text, "$1")
+		text = legacyAttachName.ReplaceAllString(text, "")
+	}
+	text = html.UnescapeString(text)
+
+	text = listEnd.ReplaceAllString(text, "[/list]")
+	text = listItemEnd.ReplaceAllString(text, "")
+	text = quoteAttribution.ReplaceAllStringFunc(text, convertQuote)
+	text = inlineAttachment.ReplaceAllStringFunc(text, func(tag string) string {
+		match := inlineAttachment.FindStringSubmatch(tag)
+		index, _ := strconv.Atoi(match[1])
+		if index >= len(attachmentIDs) {
+			return match[2]
+		}
+		return fmt.Sprintf("[ATTACH]%d[/ATTACH]", attachmentIDs[index])
+	})
+
+	return text
+}
+
+// convertQuote converts a phpBB 3.2+ quote attribution such as
+// [quote="alice" post_id=10 time=1700000000 user_id=3].
+func convertQuote(tag string) string {
+	match := quoteAttribution.FindStringSubmatch(tag)
+	attribution := match[1]
+	for _, attribute := range quoteAttribute.FindAllStringSubmatch(match[2], -1) {
+		switch attribute[1] {
+		case "post_id":
+			attribution += ", post: " + attribute[2]
+		case "user_id":
+			attribution += ", member: " + attribute[2]
+		}
+	}
+	return fmt.Sprintf(`[QUOTE="%s"]`, attribution)
+}
@@ -0,0 +1,170 @@
+package phpbb
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func TestConvertMessage(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		bbcodeUID     string
+		attachmentIDs []int
+		expected      string
+	}{
+		{
+			name:      "Legacy format",
+			text:      "[b:abc123]bold[/b:abc123] &amp; <!-- s:) --><img src=\"{SMILIES_PATH}/icon_smile.gif\" alt=\":)\" /><!-- s:) -->",
+			bbcodeUID: "abc123",
+			expected:  "[b]bold[/b] & :)",
+		},
+		{
+			name:      "Legacy magic link and list",
+			text:      "<!-- m --><a class=\"postlink\" href=\"https://example.com\">https://example.com</a><!-- m -->\n[list:abc123][*:abc123]one[/*:m:abc123][/list:u:abc123]",
+			bbcodeUID: "abc123",
+			expected:  "https://example.com\n[list][*]one[/list]",
+		},
+		{
+			name:     "XML format",
+			text:     "<r><B><s>[b]</s>bold<e>[/b]</e></B><br/>\n&lt;tag&gt;</r>",
+			expected: "[b]bold[/b]\n<tag>",
+		},
+		{
+			name:     "Plain XML format",
+			text:     "<t>Just text</t>",
+			expected: "Just text",
+		},
+		{
+			name:     "Quote with post reference",
+			text:     "<r><QUOTE author=\"alice\" post_id=\"10\" time=\"1700000000\" user_id=\"3\"><s>[quote=\"alice\" post_id=10 time=1700000000 user_id=3]</s>Hi<e>[/quote]</e></QUOTE></r>",
+			expected: "[QUOTE=\"alice, post: 10, member: 3\"]Hi[/quote]",
+		},
+		{
+			name:          "Inline attachments",
+			text:          "<r><ATTACHMENT filename=\"b.png\" index=\"0\"><s>[attachment=0]</s>b.png<e>[/attachment]</e></ATTACHMENT> [attachment=2]c.png[/attachment]</r>",
+			attachmentIDs: []int{8, 7},
+			expected:      "[ATTACH]8[/ATTACH] c.png",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := convertMessage(tt.text, tt.bbcodeUID, tt.attachmentIDs); result != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, result)
+			}
+		})
+	}
+}
+
+func newTestSource() *Source {
+	return New(&Export{
+		Forums: []Forum{
+			{ForumID: 1, LeftID: 1, Name: "General", Type: forumTypeCategory},
+			{ForumID: 2, ParentID: 1, LeftID: 2, Name: "Q &amp; A", Type: forumTypePost},
+		},
+		Topics: []Topic{
+			{TopicID: 6, ForumID: 2, Title: "Locked", Status: topicLocked, Visibility: itemApproved, PostsApproved: 1},
+			{TopicID: 5, ForumID: 2, Title: "Hello", Visibility: itemApproved, PostsApproved: 2},
+			{TopicID: 7, ForumID: 2, Title: "Moved", Visibility: itemApproved, MovedID: 9},
+			{TopicID: 8, ForumID: 2, Title: "Unapproved"},
+		},
+		Posts: []Post{
+			{PostID: 11, TopicID: 5, PosterID: anonymousUserID, Username: "visitor", Time: 200, Text: "<t>Reply</t>", Visibility: itemApproved},
+			{PostID: 10, TopicID: 5, PosterID: 3, Time: 100, Text: "<r><s>[attachment=0]</s>a.png<e>[/attachment]</e></r>", Visibility: itemApproved},
+			{PostID: 12, TopicID: 5, PosterID: 3, Time: 300, Text: "<t>Hidden</t>"},
+			{PostID: 13, TopicID: 6, PosterID: 3, Time: 400, Text: "<t>Closed</t>", Visibility: itemApproved},
+		},
+		Attachments: []Attachment{
+			{AttachID: 1, PostID: 10, PhysicalFilename: "3_0a1b", RealFilename: "a.png", Filesize: 5},
+			{AttachID: 2, PostID: 10, RealFilename: "private.png", InMessage: 1},
+		},
+		Users: []User{
+			{UserID: 3, Username: "alice", Avatar: "https://example.com/a.png", AvatarType: avatarRemote},
+		},
+	}).SetBoardURL("https://board.example.com/")
+}
+
+func TestSource_GetNodes(t *testing.T) {
+	nodes, err := newTestSource().GetNodes(context.Background())
+	if err != nil {
+		t.Fatalf("GetNodes returned error: %v", err)
+	}
+
+	if len(nodes) != 2 || nodes[0].NodeTypeID != "Category" || nodes[1].NodeTypeID != "Forum" {
+		t.Fatalf("Unexpected nodes: %+v", nodes)
+	}
+	if nodes[1].Title != "Q & A" || nodes[1].ThreadCount == nil || *nodes[1].ThreadCount != 2 {
+		t.Errorf("Unexpected forum node: %+v", nodes[1])
+	}
+}
+
+func TestSource_GetThreadsFrom(t *testing.T) {
+	source := newTestSource()
+
+	var pages int
+	threads, err := source.GetThreadsFrom(context.Background(), 2, 1, []xenforo.Thread{{ThreadID: 5}}, func(page int, threads []xenforo.Thread) error {
+		pages++
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GetThreadsFrom returned error: %v", err)
+	}
+
+	if len(threads) != 2 || pages != 1 {
+		t.Fatalf("Expected the collected thread and one new thread on one page, got %+v on %d pages", threads, pages)
+	}
+	if threads[1].ThreadID != 6 || !threads[1].IsLocked() {
+		t.Errorf("Expected locked thread 6, got %+v", threads[1])
+	}
+}
+
+func TestSource_GetPosts(t *testing.T) {
+	filesDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(filesDir, "3_0a1b"), []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	source := newTestSource().SetFilesDir(filesDir)
+
+	posts, err := source.GetPosts(context.Background(), xenforo.Thread{ThreadID: 5})
+	if err != nil {
+		t.Fatalf("GetPosts returned error: %v", err)
+	}
+
+	if len(posts) != 2 || posts[0].PostID != 10 || posts[1].PostID != 11 {
+		t.Fatalf("Expected posts 10 and 11 in posting order, got %+v", posts)
+	}
+	if posts[0].Username != "alice" || posts[0].UserID != 3 || posts[1].Username != "visitor" || posts[1].UserID != 0 {
+		t.Errorf("Unexpected authors: %q (%d), %q (%d)", posts[0].Username, posts[0].UserID, posts[1].Username, posts[1].UserID)
+	}
+	if posts[0].Message != "[ATTACH]1[/ATTACH]" || len(posts[0].Attachments) != 1 {
+		t.Fatalf("Unexpected first post: %+v", posts[0])
+	}
+
+	attachment := posts[0].Attachments[0]
+	if attachment.DirectURL != "https://board.example.com/download/file.php?id=1" || !attachment.IsValid() {
+		t.Errorf("Unexpected attachment: %+v", attachment)
+	}
+
+	target := filepath.Join(t.TempDir(), "a.png")
+	if err := source.DownloadAttachment(context.Background(), attachment.DirectURL, target); err != nil {
+		t.Fatalf("DownloadAttachment returned error: %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "image" {
+		t.Errorf("Expected the file from the files directory, got %q (err: %v)", data, err)
+	}
+}
+
+func TestSource_GetNodeStats(t *testing.T) {
+	stats, err := newTestSource().GetNodeStats(context.Background(), 2, 1)
+	if err != nil {
+		t.Fatalf("GetNodeStats returned error: %v", err)
+	}
+	if stats.Threads != 2 || stats.Posts != 3 || stats.Users != 2 || stats.Attachments != 1 || stats.AttachmentBytes != 5 || stats.Extrapolated {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -0,0 +1,308 @@
+package phpbb
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// Downloader fetches attachment files over HTTP. *xenforo.Client satisfies
+// this interface.
+type Downloader interface {
+	DownloadAttachment(ctx context.Context, url, filepath string) error
+}
+
+// Source provides the forums, topics and posts of an export as XenForo
+// nodes, threads and posts.
+type Source struct {
+	export     *Export
+	boardURL   string     // Public board URL attachment URLs are built from
+	filesDir   string     // The board's files directory attachments are copied from ("" = download)
+	downloader Downloader // Downloads attachments not read from filesDir
+
+	users       map[int]User
+	topics      map[int][]Topic      // By forum, in topic ID order
+	posts       map[int][]Post       // By topic, in post order
+	attachments map[int][]Attachment // By post, in phpBB's inline index order
+
+	files sync.Map // Attachment URL -> physical file name
+}
+
+// Open loads a JSON export, see LoadExport.
+func Open(path string) (*Source, error) {
+	export, err := LoadExport(path)
+	if err != nil {
+		return nil, err
+	}
+	return New(export), nil
+}
+
+// New creates a source for an export.
+func New(export *Export) *Source {
+	s := &Source{
+		export:      export,
+		users:       make(map[int]User, len(export.Users)),
+		topics:      make(map[int][]Topic),
+		posts:       make(map[int][]Post),
+		attachments: make(map[int][]Attachment),
+	}
+
+	for _, user := range export.Users {
+		s.users[user.UserID] = user
+	}
+	for _, topic := range export.Topics {
+		if topic.Visibility == itemApproved && topic.MovedID == 0 {
+			s.topics[topic.ForumID] = append(s.topics[topic.ForumID], topic)
+		}
+	}
+	for _, post := range export.Posts {
+		if post.Visibility == itemApproved {
+			s.posts[post.TopicID] = append(s.posts[post.TopicID], post)
+		}
+	}
+	for _, attachment := range export.Attachments {
+		if attachment.InMessage == 0 {
+			s.attachments[attachment.PostID] = append(s.attachments[attachment.PostID], attachment)
+		}
+	}
+
+	for _, topics := range s.topics {
+		sort.Slice(topics, func(i, j int) bool { return topics[i].TopicID < topics[j].TopicID })
+	}
+	for _, posts := range s.posts {
+		sort.Slice(posts, func(i, j int) bool {
+			if posts[i].Time != posts[j].Time {
+				return posts[i].Time < posts[j].Time
+			}
+			return posts[i].PostID < posts[j].PostID
+		})
+	}
+	// [attachment=0] is the newest attachment of a post
+	for _, attachments := range s.attachments {
+		sort.Slice(attachments, func(i, j int) bool { return attachments[i].AttachID > attachments[j].AttachID })
+	}
+
+	return s
+}
+
+// SetBoardURL sets the public board URL (e.g., "https://forum.example.com")
+// attachment URLs are built from.
+func (s *Source) SetBoardURL(url string) *Source {
+	s.boardURL = strings.TrimRight(url, "/")
+	return s
+}
+
+// SetFilesDir copies attachment files from the board's files directory
+// instead of downloading them.
+func (s *Source) SetFilesDir(dir string) *Source {
+	s.filesDir = dir
+	return s
+}
+
+// SetDownloader sets the client attachments are downloaded with when they
+// are not read from the files directory.
+func (s *Source) SetDownloader(downloader Downloader) *Source {
+	s.downloader = downloader
+	return s
+}
+
+// TestConnection checks the export holds topics to migrate.
+func (s *Source) TestConnection(ctx context.Context) error {
+	if len(s.export.Topics) == 0 {
+		return fmt.Errorf("phpBB export has no topics")
+	}
+	return nil
+}
+
+// GetNodes returns the forums as nodes: categories as "Category" and forums
+// that hold topics as "Forum" nodes.
+func (s *Source) GetNodes(ctx context.Context) ([]xenforo.Node, error) {
+	nodes := make([]xenforo.Node, 0, len(s.export.Forums))
+	for _, forum := range s.export.Forums {
+		node := xenforo.Node{
+			NodeID:        forum.ForumID,
+			Title:         html.UnescapeString(forum.Name),
+			NodeTypeID:    "LinkForum",
+			ParentNodeID:  forum.ParentID,
+			DisplayOrder:  forum.LeftID,
+			DisplayInList: true,
+		}
+		switch forum.Type {
+		case forumTypeCategory:
+			node.NodeTypeID = "Category"
+		case forumTypePost:
+			node.NodeTypeID = "Forum"
+			count := len(s.topics[forum.ForumID])
+			node.ThreadCount = &count
+		}
+		if forum.Desc != "" {
+			description := forum.Desc
+			node.Description = &description
+		}
+		nodes = append(nodes, node)
+	}
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].DisplayOrder < nodes[j].DisplayOrder })
+	return nodes, nil
+}
+
+func (s *Source) GetThreads(ctx context.Context, nodeID int) ([]xenforo.Thread, error) {
+	return s.GetThreadsFrom(ctx, nodeID, 1, nil, nil)
+}
+
+// GetThreadsFrom lists the visible topics of a forum. The export is read
+// in one page, so startPage only matters for skipping the threads already
+// collected.
+func (s *Source) GetThreadsFrom(ctx context.Context, nodeID, startPage int, threads []xenforo.Thread, onPage func(page int, threads []xenforo.Thread) error) ([]xenforo.Thread, error) {
+	seen := make(map[int]bool, len(threads))
+	for _, thread := range threads {
+		seen[thread.ThreadID] = true
+	}
+
+	for _, topic := range s.topics[nodeID] {
+		if seen[topic.TopicID] {
+			continue
+		}
+		open := topic.Status != topicLocked
+		threads = append(threads, xenforo.Thread{
+			ThreadID:       topic.TopicID,
+			Title:          html.UnescapeString(topic.Title),
+			NodeID:         topic.ForumID,
+			Username:       topic.FirstPosterName,
+			PostDate:       topic.Time,
+			LastPostDate:   topic.LastPostTime,
+			FirstPostID:    topic.FirstPostID,
+			ReplyCount:     max(0, topic.PostsApproved-1),
+			ViewCount:      topic.Views,
+			DiscussionOpen: &open,
+		})
+	}
+
+	if onPage != nil {
+		if err := onPage(max(startPage, 1), threads); err != nil {
+			return nil, err
+		}
+	}
+	return threads, nil
+}
+
+// GetPosts returns the visible posts of a topic in posting order, with their
+// attachments.
+func (s *Source) GetPosts(ctx context.Context, thread xenforo.Thread) ([]xenforo.Post, error) {
+	topicPosts := s.posts[thread.ThreadID]
+	posts := make([]xenforo.Post, 0, len(topicPosts))
+	for _, post := range topicPosts {
+		converted := xenforo.Post{
+			PostID:   post.PostID,
+			ThreadID: post.TopicID,
+			Username: post.Username,
+			PostDate: post.Time,
+		}
+		if user, ok := s.users[post.PosterID]; ok && post.PosterID != anonymousUserID {
+			converted.UserID = user.UserID
+			converted.Username = user.Username
+		} else if converted.Username == "" {
+			converted.Username = "Guest"
+		}
+
+		var attachmentIDs []int
+		for _, attachment := range s.attachments[post.PostID] {
+			attachmentIDs = append(attachmentIDs, attachment.AttachID)
+			converted.Attachments = append(converted.Attachments, s.attachment(attachment))
+		}
+		converted.Message = convertMessage(post.Text, post.BBCodeUID, attachmentIDs)
+
+		posts = append(posts, converted)
+	}
+	return posts, nil
+}
+
+// attachment converts an attachment, downloaded from the board's
+// download/file.php.
+func (s *Source) attachment(attachment Attachment) xenforo.Attachment {
+	url := fmt.Sprintf("%s/download/file.php?id=%d", s.boardURL, attachment.AttachID)
+	s.files.Store(url, attachment.PhysicalFilename)
+	return xenforo.Attachment{
+		AttachmentID: attachment.AttachID,
+		Filename:     attachment.RealFilename,
+		DirectURL:    url,
+		FileSize:     attachment.Filesize,
+	}
+}
+
+// DownloadAttachment copies an attachment from the files directory when one
+// is set, or downloads it otherwise.
+func (s *Source) DownloadAttachment(ctx context.Context, url, filePath string) error {
+	if physical, ok := s.files.Load(url); ok && s.filesDir != "" {
+		return copyFile(filepath.Join(s.filesDir, filepath.Base(physical.(string))), filePath)
+	}
+	if s.downloader == nil {
+		return fmt.Errorf("cannot download %s: no phpBB files directory or downloader configured", url)
+	}
+	return s.downloader.DownloadAttachment(ctx, url, filePath)
+}
+
+func copyFile(source, target string) error {
+	data, err := os.ReadFile(source)
+	if err != nil {
+		return fmt.Errorf("failed to read attachment file: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.WriteFile(target, data, 0644); err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	return nil
+}
+
+// GetUser returns a member. Only avatars linked by URL are known.
+func (s *Source) GetUser(ctx context.Context, userID int) (*xenforo.User, error) {
+	user, ok := s.users[userID]
+	if !ok {
+		return nil, fmt.Errorf("user %d not found in phpBB export", userID)
+	}
+
+	converted := &xenforo.User{UserID: user.UserID, Username: user.Username}
+	if user.AvatarType == avatarRemote && user.Avatar != "" {
+		converted.AvatarURLs = map[string]string{"o": user.Avatar}
+	}
+	return converted, nil
+}
+
+// GetNodeStats counts the content of a forum. The export is complete, so no
+// topics are sampled whatever sampleThreads asks for.
+func (s *Source) GetNodeStats(ctx context.Context, nodeID, sampleThreads int) (*xenforo.NodeStats, error) {
+	stats := &xenforo.NodeStats{}
+	users := make(map[string]bool)
+	for _, topic := range s.topics[nodeID] {
+		stats.Threads++
+		for _, post := range s.posts[topic.TopicID] {
+			stats.Posts++
+			users[fmt.Sprintf("%d/%s", post.PosterID, post.Username)] = true
+			for _, attachment := range s.attachments[post.PostID] {
+				stats.Attachments++
+				stats.AttachmentBytes += attachment.Filesize
+			}
+		}
+	}
+	stats.Users = len(users)
+	stats.SampledThreads = stats.Threads
+	return stats, nil
+}
+
+// GetDryRunStats counts the content of a forum exactly, see GetNodeStats.
+func (s *Source) GetDryRunStats(ctx context.Context, nodeID int) (threadCount, postCount, attachmentCount, userCount int, err error) {
+	stats, err := s.GetNodeStats(ctx, nodeID, 0)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	return stats.Threads, stats.Posts, stats.Attachments, stats.Users, nil
+}
@@ -0,0 +1,390 @@
+package progress
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+)
+
+// progressBucket holds the per-thread state of one thread-ID range.
+type progressBucket struct {
+	CompletedThreads []int                 `json:"completed_threads"`
+	FailedThreads    []int                 `json:"failed_threads"`
+	Discussions      map[int]DiscussionRef `json:"discussions,omitempty"`
+}
+
+// BucketedPersistence shards the completed and failed threads and the
+// discussion mapping across files covering bucketSize thread IDs each, stored
+// in the "<file>.buckets" directory next to the progress file. The progress
+// file keeps the remaining state.
+//
+// It is a ThreadStore: Load reads the progress file only, buckets are loaded
+// into the progress the first time one of their threads is needed, and saves
+// rewrite only the buckets of the threads that changed.
+//
+// Threads recorded in a single-file progress file are moved into buckets on
+// the first save, so an existing migration can switch to bucketed mode.
+// Threads are ordered by bucket after a reload.
+type BucketedPersistence struct {
+	mu         sync.Mutex
+	filePath   string
+	bucketDir  string
+	bucketSize int
+
+	buckets map[int]*progressBucket // Buckets read or saved, keyed by their first thread ID
+	written map[int][]byte          // Last written contents of each bucket file
+	loaded  map[int]bool            // Buckets loaded into the progress
+	inline  bool                    // The progress file still holds threads, moved to buckets on the next save
+}
+
+func NewBucketedPersistence(filePath string, bucketSize int) *BucketedPersistence {
+	if bucketSize < 1 {
+		bucketSize = 1
+	}
+	return &BucketedPersistence{
+		filePath:   filePath,
+		bucketDir:  filePath + ".buckets",
+		bucketSize: bucketSize,
+		buckets:    make(map[int]*progressBucket),
+		written:    make(map[int][]byte),
+		loaded:     make(map[int]bool),
+	}
+}
+
+// Load reads the progress file. Threads still recorded in it, by a
+// single-file progress file, are loaded together with every bucket.
+func (p *BucketedPersistence) Load() (*MigrationProgress, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	progress, err := NewPersistence(p.filePath).Load()
+	if err != nil && !os.IsNotExist(err) {
+		return progress, err
+	}
+
+	starts, dirErr := p.bucketStarts()
+	if dirErr != nil {
+		return progress, dirErr
+	}
+	if err != nil && len(starts) == 0 {
+		return progress, err
+	}
+
+	p.inline = len(progress.CompletedThreads) > 0 || len(progress.FailedThreads) > 0 || len(progress.Discussions) > 0
+	if p.inline {
+		if err := p.loadBuckets(progress); err != nil {
+			return progress, err
+		}
+	}
+	return progress, nil
+}
+
+// LoadThread loads the bucket holding threadID into progress, unless it is
+// loaded already.
+func (p *BucketedPersistence) LoadThread(progress *MigrationProgress, threadID int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.loadBucket(progress, p.bucketStart(threadID), nil, nil)
+}
+
+// LoadThreads loads every bucket not loaded yet into progress.
+func (p *BucketedPersistence) LoadThreads(progress *MigrationProgress) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.loadBuckets(progress)
+}
+
+func (p *BucketedPersistence) loadBuckets(progress *MigrationProgress) error {
+	starts, err := p.bucketStarts()
+	if err != nil {
+		return err
+	}
+
+	completed := idSet(progress.CompletedThreads)
+	failed := idSet(progress.FailedThreads)
+	for _, start := range starts {
+		if err := p.loadBucket(progress, start, completed, failed); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loadBucket appends the threads of a bucket to progress. Threads are only
+// recorded in progress once their bucket is loaded, so they cannot be there
+// already unless the progress file still holds threads: the completed and
+// failed sets, when given, skip those.
+func (p *BucketedPersistence) loadBucket(progress *MigrationProgress, start int, completed, failed map[int]bool) error {
+	if p.loaded[start] {
+		return nil
+	}
+	bucket, err := p.bucket(start)
+	if err != nil {
+		return err
+	}
+
+	if completed == nil {
+		progress.CompletedThreads = append(progress.CompletedThreads, bucket.CompletedThreads...)
+		progress.FailedThreads = append(progress.FailedThreads, bucket.FailedThreads...)
+	} else {
+		progress.CompletedThreads = appendMissing(progress.CompletedThreads, bucket.CompletedThreads, completed)
+		progress.FailedThreads = appendMissing(progress.FailedThreads, bucket.FailedThreads, failed)
+	}
+	for threadID, ref := range bucket.Discussions {
+		if progress.Discussions == nil {
+			progress.Discussions = make(map[int]DiscussionRef)
+		}
+		progress.Discussions[threadID] = ref
+	}
+	p.loaded[start] = true
+	return nil
+}
+
+// Save loads the buckets not loaded yet and writes the progress file and the
+// buckets whose contents changed.
+func (p *BucketedPersistence) Save(progress *MigrationProgress) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.saveAll(progress)
+}
+
+// SaveThreads writes the progress file and the buckets of the given threads,
+// whose state progress holds since their buckets were loaded. The first save
+// after loading a single-file progress file writes every bucket.
+func (p *BucketedPersistence) SaveThreads(progress *MigrationProgress, threadIDs ...int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.inline {
+		return p.saveAll(progress)
+	}
+
+	changed := make(map[int]bool)
+	for _, threadID := range threadIDs {
+		start := p.bucketStart(threadID)
+		bucket, err := p.bucket(start)
+		if err != nil {
+			return err
+		}
+		bucket.CompletedThreads = setID(bucket.CompletedThreads, threadID, containsID(progress.CompletedThreads, threadID))
+		bucket.FailedThreads = setID(bucket.FailedThreads, threadID, containsID(progress.FailedThreads, threadID))
+		if ref, ok := progress.Discussions[threadID]; ok {
+			if bucket.Discussions == nil {
+				bucket.Discussions = make(map[int]DiscussionRef)
+			}
+			bucket.Discussions[threadID] = ref
+		} else {
+			delete(bucket.Discussions, threadID)
+		}
+		changed[start] = true
+	}
+
+	if len(changed) > 0 {
+		if err := os.MkdirAll(p.bucketDir, 0755); err != nil {
+			return fmt.Errorf("failed to create bucket directory %s: %w", p.bucketDir, err)
+		}
+	}
+	for start := range changed {
+		if err := p.saveBucket(start, p.buckets[start]); err != nil {
+			return err
+		}
+	}
+	return p.saveProgressFile(progress)
+}
+
+// saveAll rebuilds every bucket from progress once all of them are loaded.
+func (p *BucketedPersistence) saveAll(progress *MigrationProgress) error {
+	if err := p.loadBuckets(progress); err != nil {
+		return err
+	}
+
+	buckets := make(map[int]*progressBucket)
+	bucketOf := func(threadID int) *progressBucket {
+		start := p.bucketStart(threadID)
+		bucket, ok := buckets[start]
+		if !ok {
+			bucket = &progressBucket{CompletedThreads: []int{}, FailedThreads: []int{}}
+			buckets[start] = bucket
+		}
+		return bucket
+	}
+	for _, threadID := range progress.CompletedThreads {
+		bucket := bucketOf(threadID)
+		bucket.CompletedThreads = append(bucket.CompletedThreads, threadID)
+	}
+	for _, threadID := range progress.FailedThreads {
+		bucket := bucketOf(threadID)
+		bucket.FailedThreads = append(bucket.FailedThreads, threadID)
+	}
+	for threadID, ref := range progress.Discussions {
+		bucket := bucketOf(threadID)
+		if bucket.Discussions == nil {
+			bucket.Discussions = make(map[int]DiscussionRef)
+		}
+		bucket.Discussions[threadID] = ref
+	}
+
+	// Buckets emptied since the last save are rewritten as empty
+	for start := range p.written {
+		if _, ok := buckets[start]; !ok {
+			buckets[start] = &progressBucket{CompletedThreads: []int{}, FailedThreads: []int{}}
+		}
+	}
+
+	if err := os.MkdirAll(p.bucketDir, 0755); err != nil {
+		return fmt.Errorf("failed to create bucket directory %s: %w", p.bucketDir, err)
+	}
+	for start, bucket := range buckets {
+		if err := p.saveBucket(start, bucket); err != nil {
+			return err
+		}
+		p.loaded[start] = true
+	}
+
+	if err := p.saveProgressFile(progress); err != nil {
+		return err
+	}
+	p.inline = false
+	return nil
+}
+
+// saveProgressFile writes the progress file without the bucketed state.
+func (p *BucketedPersistence) saveProgressFile(progress *MigrationProgress) error {
+	remaining := *progress
+	remaining.CompletedThreads = []int{}
+	remaining.FailedThreads = []int{}
+	remaining.Discussions = nil
+	return NewPersistence(p.filePath).Save(&remaining)
+}
+
+// bucketStart returns the first thread ID of the bucket holding threadID.
+func (p *BucketedPersistence) bucketStart(threadID int) int {
+	if threadID < 0 {
+		return -((-threadID-1)/p.bucketSize + 1) * p.bucketSize
+	}
+	return threadID / p.bucketSize * p.bucketSize
+}
+
+func (p *BucketedPersistence) bucketPath(start int) string {
+	return filepath.Join(p.bucketDir, fmt.Sprintf("bucket_%d.json", start))
+}
+
+// bucket returns a bucket, reading it from disk the first time. Missing
+// bucket files are empty buckets.
+func (p *BucketedPersistence) bucket(start int) (*progressBucket, error) {
+	if bucket, ok := p.buckets[start]; ok {
+		return bucket, nil
+	}
+
+	bucket := &progressBucket{CompletedThreads: []int{}, FailedThreads: []int{}}
+	data, err := os.ReadFile(p.bucketPath(start))
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return nil, fmt.Errorf("failed to read progress bucket %d: %w", start, err)
+	default:
+		if err := json.Unmarshal(data, bucket); err != nil {
+			return nil, fmt.Errorf("failed to parse progress bucket %d: %w", start, err)
+		}
+		p.written[start] = data
+	}
+
+	p.buckets[start] = bucket
+	return bucket, nil
+}
+
+func (p *BucketedPersistence) saveBucket(start int, bucket *progressBucket) error {
+	data, err := json.MarshalIndent(bucket, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress bucket %d: %w", start, err)
+	}
+
+	p.buckets[start] = bucket
+	if previous, ok := p.written[start]; ok && bytes.Equal(previous, data) {
+		return nil
+	}
+
+	if err := writeFileAtomic(p.bucketPath(start), data, false); err != nil {
+		logging.Errorf(context.Background(), "Failed to save progress bucket to %s: %v", p.bucketPath(start), err)
+		return err
+	}
+	p.written[start] = data
+	return nil
+}
+
+// bucketStarts lists the buckets present on disk in thread-ID order.
+func (p *BucketedPersistence) bucketStarts() ([]int, error) {
+	entries, err := os.ReadDir(p.bucketDir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read bucket directory %s: %w", p.bucketDir, err)
+	}
+
+	var starts []int
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, "bucket_") || !strings.HasSuffix(name, ".json") {
+			continue
+		}
+		start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(name, "bucket_"), ".json"))
+		if err != nil {
+			continue
+		}
+		starts = append(starts, start)
+	}
+	sort.Ints(starts)
+	return starts, nil
+}
+
+// setID adds id to ids, or removes it when present is false.
+func setID(ids []int, id int, present bool) []int {
+	if !present {
+		return removeThreadID(ids, id)
+	}
+	if containsID(ids, id) {
+		return ids
+	}
+	return append(ids, id)
+}
+
+func containsID(ids []int, id int) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func idSet(ids []int) map[int]bool {
+	set := make(map[int]bool, len(ids))
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+// appendMissing appends the IDs not yet in seen to ids and records them.
+func appendMissing(ids, more []int, seen map[int]bool) []int {
+	for _, id := range more {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
@@ -1,22 +1,71 @@
 package progress
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
 	"os"
+	"path/filepath"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+)
+
+// Persistence loads and saves the migration progress.
+type Persistence interface {
+	Load() (*MigrationProgress, error)
+	Save(progress *MigrationProgress) error
+}
+
+// ThreadStore is a Persistence keeping the per-thread state (completed and
+// failed threads, discussions) apart from the rest of the progress. Its Load
+// leaves that state out: the tracker loads a thread's state before it reads
+// or changes the thread, and every thread's only when it needs them all.
+// SaveThreads saves the given threads instead of every thread.
+type ThreadStore interface {
+	Persistence
+	LoadThread(progress *MigrationProgress, threadID int) error
+	LoadThreads(progress *MigrationProgress) error
+	SaveThreads(progress *MigrationProgress, threadIDs ...int) error
+}
+
+// Progress backends.
+const (
+	BackendJSON   = "json"   // JSON files, single or bucketed
+	BackendSQLite = "sqlite" // SQLite database next to the progress file
 )
 
-type Persistence struct {
+// OpenPersistence returns the persistence for a progress file: a single JSON
+// file, bucketed files when bucketSize is positive, or with the SQLite
+// backend the database at SQLitePath(filePath).
+func OpenPersistence(filePath, backend string, bucketSize int) (Persistence, error) {
+	switch backend {
+	case BackendSQLite:
+		return OpenSQLitePersistence(SQLitePath(filePath), filePath)
+	case BackendJSON, "":
+		if bucketSize > 0 {
+			return NewBucketedPersistence(filePath, bucketSize), nil
+		}
+		return NewPersistence(filePath), nil
+	default:
+		return nil, fmt.Errorf("unknown progress backend %q", backend)
+	}
+}
+
+// FilePersistence stores the whole progress in a single JSON file. The file
+// is replaced atomically on save, keeping the previous version as
+// "<file>.bak", from which a missing or corrupted file is recovered on load.
+type FilePersistence struct {
 	filePath string
 }
 
-func NewPersistence(filePath string) *Persistence {
-	return &Persistence{
+func NewPersistence(filePath string) *FilePersistence {
+	return &FilePersistence{
 		filePath: filePath,
 	}
 }
 
-func (p *Persistence) Load() (*MigrationProgress, error) {
+func (p *FilePersistence) Load() (*MigrationProgress, error) {
 	progress := &MigrationProgress{
 		CompletedThreads: []int{},
 		FailedThreads:    []int{},
@@ -24,13 +73,19 @@ func (p *Persistence) Load() (*MigrationProgress, error) {
 
 	data, err := os.ReadFile(p.filePath)
 	if err != nil {
+		if recovered, ok := p.recoverBackup(); ok {
+			return recovered, nil
+		}
 		return progress, err
 	}
 
 	err = json.Unmarshal(data, progress)
 	if err != nil {
-		log.Printf("Failed to unmarshal progress data from %s: %v", p.filePath, err)
-		log.Printf("Using default progress state instead of corrupted data")
+		logging.Errorf(context.Background(), "Failed to unmarshal progress data from %s: %v", p.filePath, err)
+		if recovered, ok := p.recoverBackup(); ok {
+			return recovered, nil
+		}
+		logging.Warnf(context.Background(), "Using default progress state instead of corrupted data")
 		return &MigrationProgress{
 			CompletedThreads: []int{},
 			FailedThreads:    []int{},
@@ -40,18 +95,107 @@ func (p *Persistence) Load() (*MigrationProgress, error) {
 	return progress, nil
 }
 
-func (p *Persistence) Save(progress *MigrationProgress) error {
+// recoverBackup loads the previous version of the progress file kept by the
+// last save.
+func (p *FilePersistence) recoverBackup() (*MigrationProgress, bool) {
+	data, err := os.ReadFile(backupPath(p.filePath))
+	if err != nil {
+		return nil, false
+	}
+
+	progress := &MigrationProgress{
+		CompletedThreads: []int{},
+		FailedThreads:    []int{},
+	}
+	if err := json.Unmarshal(data, progress); err != nil {
+		logging.Errorf(context.Background(), "Failed to unmarshal progress backup %s: %v", backupPath(p.filePath), err)
+		return nil, false
+	}
+	logging.Warnf(context.Background(), "⚠ Recovered progress from backup %s", backupPath(p.filePath))
+	return progress, true
+}
+
+func (p *FilePersistence) Save(progress *MigrationProgress) error {
 	data, err := json.MarshalIndent(progress, "", "  ")
 	if err != nil {
-		log.Printf("Failed to marshal progress data: %v", err)
+		logging.Errorf(context.Background(), "Failed to marshal progress data: %v", err)
+		return err
+	}
+
+	err = writeFileAtomic(p.filePath, data, true)
+	if err != nil {
+		logging.Errorf(context.Background(), "Failed to save progress to %s: %v", p.filePath, err)
 		return err
 	}
 
-	err = os.WriteFile(p.filePath, data, 0644)
+	return nil
+}
+
+// backupPath returns the file keeping the previous version of a progress
+// file.
+func backupPath(path string) string {
+	return path + ".bak"
+}
+
+// writeFileAtomic replaces a file with data, so a crash leaves either the old
+// or the new file: data is written and synced to "<path>.tmp", which is then
+// renamed over path. With backup, the replaced file is kept as "<path>.bak".
+func writeFileAtomic(path string, data []byte, backup bool) error {
+	tmpPath := path + ".tmp"
+	file, err := os.OpenFile(tmpPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
-		log.Printf("Failed to save progress to %s: %v", p.filePath, err)
 		return err
 	}
+	if _, err := file.Write(data); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		_ = os.Remove(tmpPath)
+		return err
+	}
+	if err := file.Close(); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	if backup {
+		if err := replaceBackup(path); err != nil {
+			_ = os.Remove(tmpPath)
+			return err
+		}
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		_ = os.Remove(tmpPath)
+		return err
+	}
+
+	// Persist the rename; not every platform can sync a directory
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		_ = dir.Sync()
+		_ = dir.Close()
+	}
+	return nil
+}
+
+// replaceBackup keeps the current version of a file as its backup. The file
+// is hard-linked where possible, so it stays in place until it is replaced.
+func replaceBackup(path string) error {
+	if _, err := os.Stat(path); errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
 
+	bakPath := backupPath(path)
+	if err := os.Remove(bakPath); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove backup %s: %w", bakPath, err)
+	}
+	if err := os.Link(path, bakPath); err == nil {
+		return nil
+	}
+	if err := os.Rename(path, bakPath); err != nil {
+		return fmt.Errorf("failed to back up %s: %w", path, err)
+	}
 	return nil
 }
@@ -1,8 +1,13 @@
 package progress
 
 import (
+	"fmt"
+	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
 )
@@ -42,7 +47,7 @@ func TestProgressTracker(t *testing.T) {
 	}
 
 	// Test marking failed
-	err = tracker.MarkFailed(456)
+	err = tracker.MarkFailed(456, "", "")
 	if err != nil {
 		t.Errorf("Failed to mark thread as failed: %v", err)
 	}
@@ -128,13 +133,13 @@ func TestMarkFailedDuplicatePrevention(t *testing.T) {
 	tracker, _ := newTestTracker(t)
 
 	// Mark thread 2 as failed multiple times
-	if err := tracker.MarkFailed(2); err != nil {
+	if err := tracker.MarkFailed(2, "", ""); err != nil {
 		t.Fatalf("Failed to mark thread 2 as failed: %v", err)
 	}
-	if err := tracker.MarkFailed(2); err != nil {
+	if err := tracker.MarkFailed(2, "", ""); err != nil {
 		t.Fatalf("Failed to mark thread 2 as failed (duplicate): %v", err)
 	}
-	if err := tracker.MarkFailed(2); err != nil {
+	if err := tracker.MarkFailed(2, "", ""); err != nil {
 		t.Fatalf("Failed to mark thread 2 as failed (duplicate 2): %v", err)
 	}
 
@@ -150,3 +155,516 @@ func TestMarkFailedDuplicatePrevention(t *testing.T) {
 		t.Errorf("Expected thread 2 to appear once in FailedThreads, but found %d occurrences", count)
 	}
 }
+
+func TestMarkFailedRecordsDetails(t *testing.T) {
+	tracker, progressFile := newTestTracker(t)
+
+	before := time.Now().Unix()
+	if err := tracker.MarkFailed(2, "fetch", "first error"); err != nil {
+		t.Fatalf("Failed to mark thread 2 as failed: %v", err)
+	}
+	if err := tracker.MarkFailed(2, "posts", "second error"); err != nil {
+		t.Fatalf("Failed to mark thread 2 as failed again: %v", err)
+	}
+	if err := tracker.MarkFailed(3, "", ""); err != nil {
+		t.Fatalf("Failed to mark thread 3 as failed: %v", err)
+	}
+
+	reloaded, err := NewTracker(progressFile, false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	failed := reloaded.FailedThreads()
+	if len(failed) != 2 {
+		t.Fatalf("Expected 2 failed threads, got %v", failed)
+	}
+	failure := failed[2]
+	if failure.Phase != "posts" || failure.Error != "second error" || failure.Attempts != 2 || failure.LastTried < before {
+		t.Errorf("Expected the last of 2 attempts recorded for thread 2, got %+v", failure)
+	}
+	if failed[3].Attempts != 1 || failed[3].String() != "1 attempt, last "+time.Unix(failed[3].LastTried, 0).UTC().Format(time.RFC3339) {
+		t.Errorf("Expected one attempt without details for thread 3, got %+v (%s)", failed[3], failed[3])
+	}
+
+	if err := reloaded.MarkCompleted(2); err != nil {
+		t.Fatalf("Failed to mark thread 2 as completed: %v", err)
+	}
+	state := reloaded.GetProgress()
+	if _, ok := state.Failures[2]; ok || !reflect.DeepEqual(state.FailedThreads, []int{3}) {
+		t.Errorf("Expected completing thread 2 to clear its failure, got %v and %v", state.FailedThreads, state.Failures)
+	}
+}
+
+func TestRecordRunPersistence(t *testing.T) {
+	tracker, progressFile := newTestTracker(t)
+
+	if _, ok := tracker.LastRunAt("owner/repo#1"); ok {
+		t.Fatal("New tracker should have no recorded runs")
+	}
+
+	if err := tracker.RecordRun("owner/repo#1", 1700000000); err != nil {
+		t.Fatalf("Failed to record run: %v", err)
+	}
+
+	reloaded, err := NewTracker(progressFile, false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+
+	at, ok := reloaded.LastRunAt("owner/repo#1")
+	if !ok || at != 1700000000 {
+		t.Errorf("Expected recorded run 1700000000, got %d (found=%v)", at, ok)
+	}
+
+	if _, ok := reloaded.LastRunAt("owner/repo#2"); ok {
+		t.Error("Runs should be recorded per repo and node")
+	}
+}
+
+func TestRecordSyncPersistence(t *testing.T) {
+	tracker, progressFile := newTestTracker(t)
+
+	if err := tracker.RecordRun("owner/repo#1", 1700000000); err != nil {
+		t.Fatalf("Failed to record run: %v", err)
+	}
+	if _, ok := tracker.LastSyncAt("owner/repo#1"); ok {
+		t.Fatal("A recorded run should not count as a sync pass")
+	}
+
+	if err := tracker.RecordSync("owner/repo#1", 1700000600); err != nil {
+		t.Fatalf("Failed to record sync: %v", err)
+	}
+
+	reloaded, err := NewTracker(progressFile, false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	if at, ok := reloaded.LastSyncAt("owner/repo#1"); !ok || at != 1700000600 {
+		t.Errorf("Expected recorded sync 1700000600, got %d (found=%v)", at, ok)
+	}
+	if at, _ := reloaded.LastRunAt("owner/repo#1"); at != 1700000000 {
+		t.Errorf("Expected the recorded run to be kept, got %d", at)
+	}
+}
+
+func TestThreadCheckpoint(t *testing.T) {
+	tracker, progressFile := newTestTracker(t)
+
+	checkpoint := &ThreadCheckpoint{
+		Discussions: []DiscussionRef{{ID: "D_1", Number: 7}},
+		LastPostID:  42,
+		CommentIDs:  map[int]string{41: "C_1"},
+	}
+	if err := tracker.SaveCheckpoint(5, checkpoint); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	// Checkpoints persist across tracker instances
+	tracker2, err := NewTracker(progressFile, false)
+	if err != nil {
+		t.Fatalf("Failed to create second tracker: %v", err)
+	}
+	loaded, ok := tracker2.Checkpoint(5)
+	if !ok {
+		t.Fatal("Checkpoint should persist across tracker instances")
+	}
+	if loaded.LastPostID != 42 || len(loaded.Discussions) != 1 || loaded.Discussions[0].Number != 7 || loaded.CommentIDs[41] != "C_1" {
+		t.Errorf("Unexpected checkpoint: %+v", loaded)
+	}
+
+	// Later saves copy the comments added since
+	loaded.LastPostID = 44
+	loaded.AddComment(43, "C_2")
+	if err := tracker2.SaveCheckpoint(5, loaded); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	if saved, _ := tracker2.Checkpoint(5); saved.LastPostID != 44 || !reflect.DeepEqual(saved.CommentIDs, map[int]string{41: "C_1", 43: "C_2"}) {
+		t.Errorf("Unexpected checkpoint: %+v", saved)
+	}
+	loaded.Discussions = append(loaded.Discussions, DiscussionRef{ID: "D_2", Number: 8})
+	loaded.ResetComments()
+	loaded.AddComment(45, "C_3")
+	if err := tracker2.SaveCheckpoint(5, loaded); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+	if saved, _ := tracker2.Checkpoint(5); len(saved.Discussions) != 2 || !reflect.DeepEqual(saved.CommentIDs, map[int]string{45: "C_3"}) {
+		t.Errorf("Expected the comments of the new discussion only, got %+v", saved)
+	}
+
+	// Checkpoints are removed once the thread completes
+	if err := tracker2.MarkCompleted(5); err != nil {
+		t.Fatalf("Failed to mark thread 5 as completed: %v", err)
+	}
+	if _, ok := tracker2.Checkpoint(5); ok {
+		t.Error("Checkpoint should be removed when the thread is completed")
+	}
+}
+
+func TestUnmappedThreads(t *testing.T) {
+	tracker, progressFile := newTestTracker(t)
+
+	for _, id := range []int{1, 2, 3} {
+		if err := tracker.MarkCompleted(id); err != nil {
+			t.Fatalf("Failed to mark thread %d as completed: %v", id, err)
+		}
+	}
+	if err := tracker.RecordDiscussion(2, DiscussionRef{ID: "D_1", Number: 1, URL: "https://github.com/owner/repo/discussions/1"}); err != nil {
+		t.Fatalf("Failed to record discussion: %v", err)
+	}
+
+	reloaded, err := NewTracker(progressFile, false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	if unmapped := reloaded.UnmappedThreads(); !reflect.DeepEqual(unmapped, []int{1, 3}) {
+		t.Errorf("Expected threads 1 and 3 to be unmapped, got %v", unmapped)
+	}
+	if ref, ok := reloaded.Discussion(2); !ok || ref.URL != "https://github.com/owner/repo/discussions/1" {
+		t.Errorf("Expected recorded discussion to persist, got %+v (found=%v)", ref, ok)
+	}
+}
+
+func TestUnmappedThreads_Legacy(t *testing.T) {
+	// Written before discussions were recorded
+	progressFile := filepath.Join(t.TempDir(), "progress.json")
+	if err := os.WriteFile(progressFile, []byte(`{"completed_threads":[1,2],"failed_threads":[]}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tracker, err := NewTracker(progressFile, false)
+	if err != nil {
+		t.Fatalf("Failed to load tracker: %v", err)
+	}
+	if unmapped := tracker.UnmappedThreads(); len(unmapped) != 0 {
+		t.Errorf("Expected legacy threads not to be unmapped, got %v", unmapped)
+	}
+	if err := tracker.MarkCompleted(3); err != nil {
+		t.Fatalf("Failed to mark thread 3 as completed: %v", err)
+	}
+
+	reloaded, err := NewTracker(progressFile, false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	if unmapped := reloaded.UnmappedThreads(); !reflect.DeepEqual(unmapped, []int{3}) {
+		t.Errorf("Expected only thread 3 to be unmapped, got %v", unmapped)
+	}
+}
+
+func TestForgetThread(t *testing.T) {
+	progressFile := filepath.Join(t.TempDir(), "progress.json")
+
+	tracker, err := NewTrackerWithPersistence(NewBucketedPersistence(progressFile, 100), false)
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+	for _, id := range []int{1, 2} {
+		if err := tracker.MarkCompleted(id); err != nil {
+			t.Fatalf("Failed to mark thread %d as completed: %v", id, err)
+		}
+		if err := tracker.RecordDiscussion(id, DiscussionRef{ID: fmt.Sprintf("D_%d", id), Number: id}); err != nil {
+			t.Fatalf("Failed to record discussion: %v", err)
+		}
+	}
+	if err := tracker.SaveCheckpoint(150, &ThreadCheckpoint{Discussions: []DiscussionRef{{ID: "D_3", Number: 3}}}); err != nil {
+		t.Fatalf("Failed to save checkpoint: %v", err)
+	}
+
+	for _, id := range []int{1, 150} {
+		if err := tracker.ForgetThread(id); err != nil {
+			t.Fatalf("Failed to forget thread %d: %v", id, err)
+		}
+	}
+
+	// Removals persist, including the emptied bucket of thread 150
+	reloaded, err := NewTrackerWithPersistence(NewBucketedPersistence(progressFile, 100), false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	if completed := reloaded.GetProgress().CompletedThreads; !reflect.DeepEqual(completed, []int{2}) {
+		t.Errorf("Expected only thread 2 to stay completed, got %v", completed)
+	}
+	if _, ok := reloaded.Discussion(1); ok {
+		t.Error("Expected the discussion of thread 1 to be forgotten")
+	}
+	if _, ok := reloaded.Discussion(2); !ok {
+		t.Error("Expected the discussion of thread 2 to be kept")
+	}
+	if _, ok := reloaded.Checkpoint(150); ok {
+		t.Error("Expected the checkpoint of thread 150 to be forgotten")
+	}
+}
+
+func TestFilePersistenceBackup(t *testing.T) {
+	tracker, progressFile := newTestTracker(t)
+	if err := tracker.MarkCompleted(1); err != nil {
+		t.Fatalf("Failed to mark thread as completed: %v", err)
+	}
+	if err := tracker.MarkCompleted(2); err != nil {
+		t.Fatalf("Failed to mark thread as completed: %v", err)
+	}
+
+	if _, err := os.Stat(progressFile + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected no temporary file left, got %v", err)
+	}
+	backup, err := NewPersistence(progressFile + ".bak").Load()
+	if err != nil {
+		t.Fatalf("Failed to load backup: %v", err)
+	}
+	if !reflect.DeepEqual(backup.CompletedThreads, []int{1}) {
+		t.Errorf("Expected the backup to hold the previous save, got %v", backup.CompletedThreads)
+	}
+
+	for name, damage := range map[string]func() error{
+		"corrupted": func() error { return os.WriteFile(progressFile, []byte(`{"completed_threads": [1, 2`), 0644) },
+		"missing":   func() error { return os.Remove(progressFile) },
+	} {
+		t.Run(name, func(t *testing.T) {
+			if err := damage(); err != nil {
+				t.Fatalf("Failed to damage the progress file: %v", err)
+			}
+			progress, err := NewPersistence(progressFile).Load()
+			if err != nil {
+				t.Fatalf("Expected recovery from the backup, got %v", err)
+			}
+			if !reflect.DeepEqual(progress.CompletedThreads, []int{1}) {
+				t.Errorf("Expected the backup's threads, got %v", progress.CompletedThreads)
+			}
+		})
+	}
+}
+
+func TestBucketedPersistence(t *testing.T) {
+	progressFile := filepath.Join(t.TempDir(), "progress.json")
+
+	tracker, err := NewTrackerWithPersistence(NewBucketedPersistence(progressFile, 100), false)
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+	for _, id := range []int{5, 150, 99, 1234} {
+		if err := tracker.MarkCompleted(id); err != nil {
+			t.Fatalf("Failed to mark thread %d as completed: %v", id, err)
+		}
+	}
+	if err := tracker.MarkFailed(250, "", ""); err != nil {
+		t.Fatalf("Failed to mark thread as failed: %v", err)
+	}
+	if err := tracker.RecordDiscussion(150, DiscussionRef{ID: "D_150", Number: 2}); err != nil {
+		t.Fatalf("Failed to record discussion: %v", err)
+	}
+
+	// One file per thread-ID range
+	buckets, err := filepath.Glob(progressFile + ".buckets/bucket_*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(buckets) != 4 {
+		t.Errorf("Expected 4 bucket files, got %v", buckets)
+	}
+
+	// Threads are answered from their bucket alone
+	persist := NewBucketedPersistence(progressFile, 100)
+	lazy, err := NewTrackerWithPersistence(persist, false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	if !lazy.IsCompleted(5) {
+		t.Error("Expected thread 5 to be completed")
+	}
+	if len(persist.loaded) != 1 {
+		t.Errorf("Expected only the queried bucket to be loaded, got %d", len(persist.loaded))
+	}
+	for _, tt := range []struct {
+		id        int
+		completed bool
+	}{
+		{id: 5, completed: true},
+		{id: 99, completed: true},
+		{id: 150, completed: true},
+		{id: 1234, completed: true},
+		{id: 250},
+		{id: 100},
+		{id: 5000},
+	} {
+		if completed := lazy.IsCompleted(tt.id); completed != tt.completed {
+			t.Errorf("Thread %d: expected completed=%v, got %v", tt.id, tt.completed, completed)
+		}
+	}
+
+	// Only the bucket of a changed thread is written
+	untouched := filepath.Join(progressFile+".buckets", "bucket_1200.json")
+	past := time.Now().Add(-time.Hour).Truncate(time.Second)
+	if err := os.Chtimes(untouched, past, past); err != nil {
+		t.Fatal(err)
+	}
+	if err := lazy.MarkCompleted(7); err != nil {
+		t.Fatalf("Failed to mark thread 7 as completed: %v", err)
+	}
+	if info, err := os.Stat(untouched); err != nil || !info.ModTime().Equal(past) {
+		t.Errorf("Expected the bucket of thread 1234 to be left alone, got %v (%v)", info.ModTime(), err)
+	}
+	if _, failed := lazy.FailedThreads()[250]; !failed {
+		t.Error("Expected thread 250 to stay failed")
+	}
+
+	// Round trip through a new tracker
+	reloaded, err := NewTrackerWithPersistence(NewBucketedPersistence(progressFile, 100), false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	prog := reloaded.GetProgress()
+	if !reflect.DeepEqual(prog.CompletedThreads, []int{5, 99, 7, 150, 1234}) {
+		t.Errorf("Expected completed threads ordered by bucket, got %v", prog.CompletedThreads)
+	}
+	if !reflect.DeepEqual(prog.FailedThreads, []int{250}) {
+		t.Errorf("Expected failed thread 250, got %v", prog.FailedThreads)
+	}
+	if ref, ok := reloaded.Discussion(150); !ok || ref.ID != "D_150" {
+		t.Errorf("Expected discussion D_150 for thread 150, got %+v", ref)
+	}
+	if prog.LastThreadID != 7 {
+		t.Errorf("Expected last thread ID 7, got %d", prog.LastThreadID)
+	}
+}
+
+func TestBucketedPersistenceMigratesSingleFile(t *testing.T) {
+	tracker, progressFile := newTestTracker(t)
+	if err := tracker.MarkCompleted(42); err != nil {
+		t.Fatal(err)
+	}
+
+	bucketed, err := NewTrackerWithPersistence(NewBucketedPersistence(progressFile, 10), false)
+	if err != nil {
+		t.Fatalf("Failed to create bucketed tracker: %v", err)
+	}
+	if err := bucketed.MarkCompleted(7); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(progressFile+".buckets", "bucket_40.json"))
+	if err != nil || !strings.Contains(string(data), "42") {
+		t.Errorf("Expected thread 42 to be moved into a bucket, got %q (%v)", data, err)
+	}
+	reloaded, err := NewTrackerWithPersistence(NewBucketedPersistence(progressFile, 10), false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	if !reloaded.IsCompleted(42) || !reloaded.IsCompleted(7) {
+		t.Errorf("Expected threads 42 and 7 to be completed, got %v", reloaded.GetProgress().CompletedThreads)
+	}
+}
+
+func TestSQLitePersistence(t *testing.T) {
+	if !SQLiteSupported {
+		t.Skip("SQLite needs cgo")
+	}
+	progressFile := filepath.Join(t.TempDir(), "progress.json")
+	open := func() *SQLitePersistence {
+		t.Helper()
+		persist, err := OpenSQLitePersistence(SQLitePath(progressFile), progressFile)
+		if err != nil {
+			t.Fatalf("Failed to open progress database: %v", err)
+		}
+		t.Cleanup(func() { _ = persist.Close() })
+		return persist
+	}
+
+	tracker, err := NewTrackerWithPersistence(open(), false)
+	if err != nil {
+		t.Fatalf("Failed to create tracker: %v", err)
+	}
+	for _, id := range []int{5, 150, 99} {
+		if err := tracker.MarkCompleted(id); err != nil {
+			t.Fatalf("Failed to mark thread %d as completed: %v", id, err)
+		}
+	}
+	if err := tracker.MarkFailed(250, "create", "boom"); err != nil {
+		t.Fatalf("Failed to mark thread as failed: %v", err)
+	}
+	if err := tracker.RecordDiscussion(150, DiscussionRef{ID: "D_150", Number: 2}); err != nil {
+		t.Fatalf("Failed to record discussion: %v", err)
+	}
+	if err := tracker.ForgetThread(99); err != nil {
+		t.Fatalf("Failed to forget thread 99: %v", err)
+	}
+	if _, err := os.Stat(progressFile); !os.IsNotExist(err) {
+		t.Errorf("Expected no progress file next to the database, got %v", err)
+	}
+
+	// Threads are read from the database as they are needed
+	persist := open()
+	lazy, err := NewTrackerWithPersistence(persist, false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	if !lazy.IsCompleted(5) {
+		t.Error("Expected thread 5 to be completed")
+	}
+	if len(persist.loaded) != 1 || persist.all {
+		t.Errorf("Expected only thread 5 to be loaded, got %v", persist.loaded)
+	}
+	if lazy.IsCompleted(99) {
+		t.Error("Expected forgotten thread 99 not to be completed")
+	}
+	if ref, ok := lazy.Discussion(150); !ok || ref.ID != "D_150" || ref.Number != 2 {
+		t.Errorf("Expected discussion D_150 for thread 150, got %+v", ref)
+	}
+
+	prog := lazy.GetProgress()
+	if !reflect.DeepEqual(prog.CompletedThreads, []int{5, 150}) {
+		t.Errorf("Expected completed threads 5 and 150, got %v", prog.CompletedThreads)
+	}
+	if !reflect.DeepEqual(prog.FailedThreads, []int{250}) {
+		t.Errorf("Expected failed thread 250, got %v", prog.FailedThreads)
+	}
+	if failure := prog.Failures[250]; failure.Phase != "create" || failure.Error != "boom" || failure.Attempts != 1 {
+		t.Errorf("Expected the failure of thread 250, got %+v", failure)
+	}
+	if prog.LastThreadID != 99 {
+		t.Errorf("Expected last thread ID 99, got %d", prog.LastThreadID)
+	}
+}
+
+func TestSQLitePersistenceImportsProgressFile(t *testing.T) {
+	if !SQLiteSupported {
+		t.Skip("SQLite needs cgo")
+	}
+	tracker, progressFile := newTestTracker(t)
+	if err := tracker.MarkCompleted(42); err != nil {
+		t.Fatal(err)
+	}
+	if err := tracker.RecordDiscussion(42, DiscussionRef{ID: "D_42"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, id := range []int{7, 8} {
+		persist, err := OpenPersistence(progressFile, BackendSQLite, 0)
+		if err != nil {
+			t.Fatalf("Failed to open progress database: %v", err)
+		}
+		imported, err := NewTrackerWithPersistence(persist, false)
+		if err != nil {
+			t.Fatalf("Failed to create tracker: %v", err)
+		}
+		if ref, ok := imported.Discussion(42); !ok || ref.ID != "D_42" {
+			t.Errorf("Expected the imported discussion of thread 42, got %+v", ref)
+		}
+		if err := imported.MarkCompleted(id); err != nil {
+			t.Fatal(err)
+		}
+		_ = persist.(*SQLitePersistence).Close()
+	}
+
+	persist, err := OpenPersistence(progressFile, BackendSQLite, 0)
+	if err != nil {
+		t.Fatalf("Failed to open progress database: %v", err)
+	}
+	defer func() { _ = persist.(*SQLitePersistence).Close() }()
+	reloaded, err := NewTrackerWithPersistence(persist, false)
+	if err != nil {
+		t.Fatalf("Failed to reload tracker: %v", err)
+	}
+	if got := reloaded.GetProgress().CompletedThreads; !reflect.DeepEqual(got, []int{7, 8, 42}) {
+		t.Errorf("Expected threads 7, 8 and 42 to be completed, got %v", got)
+	}
+}
@@ -0,0 +1,375 @@
+package progress
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3" // SQLite driver for OpenSQLitePersistence
+)
+
+// Thread statuses stored in the threads table.
+const (
+	statusCompleted = "completed"
+	statusFailed    = "failed"
+)
+
+// sqliteSchema creates the tables of a progress database: a row per thread
+// with its status, discussion and failed attempts, and the rest of the
+// progress as a JSON document.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS threads (
+	thread_id         INTEGER PRIMARY KEY,
+	status            TEXT NOT NULL DEFAULT '',
+	discussion_id     TEXT,
+	discussion_number INTEGER,
+	discussion        TEXT,
+	phase             TEXT NOT NULL DEFAULT '',
+	error             TEXT NOT NULL DEFAULT '',
+	attempts          INTEGER NOT NULL DEFAULT 0,
+	last_tried        INTEGER NOT NULL DEFAULT 0,
+	updated_at        INTEGER NOT NULL
+);
+CREATE TABLE IF NOT EXISTS state (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);`
+
+// progressStateKey is the state row holding the progress document.
+const progressStateKey = "progress"
+
+// SQLitePath returns the database of the SQLite backend for a progress file:
+// the file with its extension replaced by ".db".
+func SQLitePath(filePath string) string {
+	return strings.TrimSuffix(filePath, filepath.Ext(filePath)) + ".db"
+}
+
+// SQLitePersistence stores the progress in a SQLite database, with a row per
+// thread, so saving a thread writes that thread's row only. It is a
+// ThreadStore: Load reads the progress document only, and a thread's row is
+// loaded the first time the thread is needed.
+//
+// An empty database imports the single-file progress file it replaces, and
+// writes its threads on the first save. Threads are ordered by ID after a
+// reload.
+type SQLitePersistence struct {
+	mu       sync.Mutex
+	db       *sql.DB
+	jsonPath string // Single-file progress imported into an empty database
+
+	loaded map[int]bool // Threads loaded into the progress
+	all    bool         // Every thread is loaded
+	inline bool         // Threads imported from jsonPath, written on the next save
+}
+
+// OpenSQLitePersistence opens or creates the progress database at path.
+// Into an empty database, the progress file at jsonPath is imported.
+func OpenSQLitePersistence(path, jsonPath string) (*SQLitePersistence, error) {
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL")
+	if err != nil {
+		return nil, fmt.Errorf("failed to open progress database %s: %w", path, err)
+	}
+	// The tracker serializes access; one connection keeps writes in order
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		_ = db.Close()
+		return nil, fmt.Errorf("failed to create progress database %s: %w", path, err)
+	}
+
+	return &SQLitePersistence{
+		db:       db,
+		jsonPath: jsonPath,
+		loaded:   make(map[int]bool),
+	}, nil
+}
+
+// Close closes the database.
+func (p *SQLitePersistence) Close() error {
+	return p.db.Close()
+}
+
+// Load reads the progress document. An empty database returns the imported
+// progress file with every thread, or an empty progress.
+func (p *SQLitePersistence) Load() (*MigrationProgress, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	progress := &MigrationProgress{
+		CompletedThreads: []int{},
+		FailedThreads:    []int{},
+	}
+
+	var document string
+	err := p.db.QueryRow("SELECT value FROM state WHERE key = ?", progressStateKey).Scan(&document)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return p.importFile(progress)
+	case err != nil:
+		return progress, fmt.Errorf("failed to read progress: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(document), progress); err != nil {
+		return progress, fmt.Errorf("failed to parse progress: %w", err)
+	}
+	return progress, nil
+}
+
+// importFile loads the single-file progress file into an empty database.
+func (p *SQLitePersistence) importFile(empty *MigrationProgress) (*MigrationProgress, error) {
+	var threads int
+	if err := p.db.QueryRow("SELECT COUNT(*) FROM threads").Scan(&threads); err != nil {
+		return empty, fmt.Errorf("failed to read progress: %w", err)
+	}
+	if threads > 0 || p.jsonPath == "" {
+		return empty, nil
+	}
+	if _, err := os.Stat(p.jsonPath); err != nil {
+		return empty, nil
+	}
+
+	progress, err := NewPersistence(p.jsonPath).Load()
+	if err != nil {
+		return empty, fmt.Errorf("failed to import progress file %s: %w", p.jsonPath, err)
+	}
+	p.all = true
+	p.inline = true
+	return progress, nil
+}
+
+// LoadThread loads the row of a thread into progress, unless it is loaded
+// already.
+func (p *SQLitePersistence) LoadThread(progress *MigrationProgress, threadID int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.all || p.loaded[threadID] {
+		return nil
+	}
+	rows, err := p.db.Query(selectThreads+" WHERE thread_id = ?", threadID)
+	if err != nil {
+		return fmt.Errorf("failed to read progress of thread %d: %w", threadID, err)
+	}
+	if err := p.loadRows(progress, rows); err != nil {
+		return err
+	}
+	p.loaded[threadID] = true
+	return nil
+}
+
+// LoadThreads loads every row not loaded yet into progress.
+func (p *SQLitePersistence) LoadThreads(progress *MigrationProgress) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.loadThreads(progress)
+}
+
+func (p *SQLitePersistence) loadThreads(progress *MigrationProgress) error {
+	if p.all {
+		return nil
+	}
+	rows, err := p.db.Query(selectThreads + " ORDER BY thread_id")
+	if err != nil {
+		return fmt.Errorf("failed to read progress of every thread: %w", err)
+	}
+	if err := p.loadRows(progress, rows); err != nil {
+		return err
+	}
+	p.all = true
+	return nil
+}
+
+const selectThreads = "SELECT thread_id, status, discussion, phase, error, attempts, last_tried FROM threads"
+
+// loadRows adds the threads of rows not loaded yet to progress.
+func (p *SQLitePersistence) loadRows(progress *MigrationProgress, rows *sql.Rows) error {
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var (
+			threadID   int
+			status     string
+			discussion sql.NullString
+			failure    ThreadFailure
+		)
+		if err := rows.Scan(&threadID, &status, &discussion, &failure.Phase, &failure.Error, &failure.Attempts, &failure.LastTried); err != nil {
+			return fmt.Errorf("failed to read progress: %w", err)
+		}
+		if p.loaded[threadID] {
+			continue
+		}
+		p.loaded[threadID] = true
+
+		switch status {
+		case statusCompleted:
+			progress.CompletedThreads = append(progress.CompletedThreads, threadID)
+		case statusFailed:
+			progress.FailedThreads = append(progress.FailedThreads, threadID)
+		}
+		if discussion.Valid {
+			var ref DiscussionRef
+			if err := json.Unmarshal([]byte(discussion.String), &ref); err != nil {
+				return fmt.Errorf("failed to parse discussion of thread %d: %w", threadID, err)
+			}
+			if progress.Discussions == nil {
+				progress.Discussions = make(map[int]DiscussionRef)
+			}
+			progress.Discussions[threadID] = ref
+		}
+		if failure != (ThreadFailure{}) {
+			if progress.Failures == nil {
+				progress.Failures = make(map[int]ThreadFailure)
+			}
+			progress.Failures[threadID] = failure
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read progress: %w", err)
+	}
+	return nil
+}
+
+// Save loads the threads not loaded yet and rewrites every row.
+func (p *SQLitePersistence) Save(progress *MigrationProgress) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.saveAll(progress)
+}
+
+// SaveThreads writes the rows of the given threads, whose state progress
+// holds since they were loaded, and the progress document. The first save
+// after importing a progress file writes every thread.
+func (p *SQLitePersistence) SaveThreads(progress *MigrationProgress, threadIDs ...int) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.inline {
+		return p.saveAll(progress)
+	}
+	return p.write(progress, false, threadIDs)
+}
+
+func (p *SQLitePersistence) saveAll(progress *MigrationProgress) error {
+	if err := p.loadThreads(progress); err != nil {
+		return err
+	}
+
+	seen := make(map[int]bool)
+	var threadIDs []int
+	add := func(threadID int) {
+		if !seen[threadID] {
+			seen[threadID] = true
+			threadIDs = append(threadIDs, threadID)
+		}
+	}
+	for _, threadID := range progress.CompletedThreads {
+		add(threadID)
+	}
+	for _, threadID := range progress.FailedThreads {
+		add(threadID)
+	}
+	for threadID := range progress.Discussions {
+		add(threadID)
+	}
+	for threadID := range progress.Failures {
+		add(threadID)
+	}
+
+	if err := p.write(progress, true, threadIDs); err != nil {
+		return err
+	}
+	p.inline = false
+	return nil
+}
+
+// write saves the rows of threadIDs and the progress document in one
+// transaction, replacing every row when replace is set. Threads with no
+// state left are deleted.
+func (p *SQLitePersistence) write(progress *MigrationProgress, replace bool, threadIDs []int) error {
+	remaining := *progress
+	remaining.CompletedThreads = []int{}
+	remaining.FailedThreads = []int{}
+	remaining.Discussions = nil
+	remaining.Failures = nil
+	document, err := json.Marshal(&remaining)
+	if err != nil {
+		return fmt.Errorf("failed to marshal progress: %w", err)
+	}
+
+	tx, err := p.db.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to save progress: %w", err)
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	if replace {
+		if _, err := tx.Exec("DELETE FROM threads"); err != nil {
+			return fmt.Errorf("failed to save progress: %w", err)
+		}
+	}
+	now := time.Now().Unix()
+	for _, threadID := range threadIDs {
+		if err := saveThreadRow(tx, progress, threadID, now); err != nil {
+			return err
+		}
+	}
+	if _, err := tx.Exec("INSERT OR REPLACE INTO state (key, value) VALUES (?, ?)", progressStateKey, string(document)); err != nil {
+		return fmt.Errorf("failed to save progress: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to save progress: %w", err)
+	}
+	return nil
+}
+
+// saveThreadRow writes the row of a thread, or deletes it when progress
+// holds nothing about the thread.
+func saveThreadRow(tx *sql.Tx, progress *MigrationProgress, threadID int, now int64) error {
+	status := ""
+	switch {
+	case containsID(progress.CompletedThreads, threadID):
+		status = statusCompleted
+	case containsID(progress.FailedThreads, threadID):
+		status = statusFailed
+	}
+	ref, mapped := progress.Discussions[threadID]
+	failure := progress.Failures[threadID]
+
+	if status == "" && !mapped && failure == (ThreadFailure{}) {
+		if _, err := tx.Exec("DELETE FROM threads WHERE thread_id = ?", threadID); err != nil {
+			return fmt.Errorf("failed to save progress of thread %d: %w", threadID, err)
+		}
+		return nil
+	}
+
+	var discussionID, discussion sql.NullString
+	var discussionNumber sql.NullInt64
+	if mapped {
+		data, err := json.Marshal(ref)
+		if err != nil {
+			return fmt.Errorf("failed to marshal discussion of thread %d: %w", threadID, err)
+		}
+		discussionID = sql.NullString{String: ref.ID, Valid: true}
+		discussionNumber = sql.NullInt64{Int64: int64(ref.Number), Valid: true}
+		discussion = sql.NullString{String: string(data), Valid: true}
+	}
+
+	_, err := tx.Exec(`INSERT OR REPLACE INTO threads
+		(thread_id, status, discussion_id, discussion_number, discussion, phase, error, attempts, last_tried, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		threadID, status, discussionID, discussionNumber, discussion,
+		failure.Phase, failure.Error, failure.Attempts, failure.LastTried, now)
+	if err != nil {
+		return fmt.Errorf("failed to save progress of thread %d: %w", threadID, err)
+	}
+	return nil
+}
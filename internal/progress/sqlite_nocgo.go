@@ -0,0 +1,7 @@
+//go:build !cgo
+
+package progress
+
+// SQLiteSupported reports whether the binary can open SQLite databases. The
+// driver needs cgo, which this build has disabled.
+const SQLiteSupported = false
@@ -4,28 +4,140 @@
 package progress
 
 import (
+	"context"
 	"fmt"
+	"slices"
 	"strings"
+	"sync"
 	"time"
 
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
 	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
 )
 
 type MigrationProgress struct {
-	LastThreadID     int   `json:"last_thread_id"`
-	CompletedThreads []int `json:"completed_threads"`
-	FailedThreads    []int `json:"failed_threads"`
-	LastUpdated      int64 `json:"last_updated"`
+	LastThreadID     int              `json:"last_thread_id"`
+	CompletedThreads []int            `json:"completed_threads"`
+	FailedThreads    []int            `json:"failed_threads"`
+	LastUpdated      int64            `json:"last_updated"`
+	Metadata         ProgressMetadata `json:"metadata"`
+
+	// Checkpoints records threads interrupted part-way, keyed by thread ID.
+	Checkpoints map[int]*ThreadCheckpoint `json:"checkpoints,omitempty"`
+
+	// Failures details the failed attempts of each failed thread, keyed by
+	// thread ID. FailedThreads still lists the failed threads.
+	Failures map[int]ThreadFailure `json:"failures,omitempty"`
+
+	// Discussions maps migrated thread IDs to their (first) discussion.
+	Discussions map[int]DiscussionRef `json:"discussions,omitempty"`
+
+	// LegacyThreads lists the threads completed before discussions were
+	// recorded. They are not reported as unmapped.
+	LegacyThreads []int `json:"legacy_threads,omitempty"`
+
+	// Listing holds a thread listing interrupted part-way through pagination.
+	Listing *ThreadListing `json:"listing,omitempty"`
+}
+
+// ThreadListing records the threads collected by an interrupted listing so
+// the next run continues after the last fetched page.
+type ThreadListing struct {
+	NodeID  int              `json:"node_id"`
+	Page    int              `json:"page"` // Last page fetched successfully
+	Threads []xenforo.Thread `json:"threads"`
+}
+
+// ThreadCheckpoint records how far an interrupted thread was migrated so the
+// next run continues at the following post instead of starting over.
+type ThreadCheckpoint struct {
+	Discussions []DiscussionRef `json:"discussions"`           // Discussions created for the thread, one per part
+	LinkedParts int             `json:"linked_parts"`          // Parts already linked to their successor
+	LastPostID  int             `json:"last_post_id"`          // Last post written to GitHub
+	CommentIDs  map[int]string  `json:"comment_ids,omitempty"` // Post ID -> top-level comment in the last discussion, added with AddComment
+
+	added []int // Posts added to CommentIDs since the checkpoint was saved
+	reset bool  // CommentIDs was replaced since the checkpoint was saved
+}
+
+// AddComment records the top-level comment a post was written as.
+func (c *ThreadCheckpoint) AddComment(postID int, commentID string) {
+	if c.CommentIDs == nil {
+		c.CommentIDs = make(map[int]string)
+	}
+	c.CommentIDs[postID] = commentID
+	c.added = append(c.added, postID)
+}
+
+// ResetComments forgets the comments recorded so far when a new discussion
+// is started, as comment threading is scoped to the discussion.
+func (c *ThreadCheckpoint) ResetComments() {
+	c.CommentIDs = make(map[int]string)
+	c.added = nil
+	c.reset = true
+}
+
+// ThreadFailure describes the failed attempts to migrate a thread.
+type ThreadFailure struct {
+	Phase     string `json:"phase,omitempty"` // Migration phase of the last attempt that failed, e.g. "fetch" or "posts"
+	Error     string `json:"error"`           // Error of the last failed attempt
+	Attempts  int    `json:"attempts"`        // Failed attempts since the thread last succeeded
+	LastTried int64  `json:"last_tried"`      // Time (Unix) of the last failed attempt
+}
+
+// String describes the failure for the summary, e.g. "phase posts, 2
+// attempts, last 2025-01-02T15:04:05Z: server error".
+func (f ThreadFailure) String() string {
+	var details []string
+	if f.Phase != "" {
+		details = append(details, "phase "+f.Phase)
+	}
+	if f.Attempts == 1 {
+		details = append(details, "1 attempt")
+	} else if f.Attempts > 1 {
+		details = append(details, fmt.Sprintf("%d attempts", f.Attempts))
+	}
+	if f.LastTried > 0 {
+		details = append(details, "last "+time.Unix(f.LastTried, 0).UTC().Format(time.RFC3339))
+	}
+	summary := strings.Join(details, ", ")
+	if f.Error != "" && summary != "" {
+		return summary + ": " + f.Error
+	}
+	return summary + f.Error
+}
+
+// DiscussionRef identifies a created GitHub discussion.
+type DiscussionRef struct {
+	ID     string `json:"id"`
+	Number int    `json:"number"`
+	URL    string `json:"url,omitempty"`
+
+	Parts []DiscussionRef `json:"parts,omitempty"` // Later parts of a split thread
+
+	LastPostID int `json:"last_post_id,omitempty"` // Last post written to the thread's (last) discussion
+}
+
+// ProgressMetadata holds bookkeeping that is not tied to a single thread.
+type ProgressMetadata struct {
+	LastRuns  map[string]int64 `json:"last_runs,omitempty"`  // Start time (Unix) of the last successful run, keyed by repo and node
+	LastSyncs map[string]int64 `json:"last_syncs,omitempty"` // Start time (Unix) of the last successful sync pass, keyed like LastRuns
 }
 
 type Tracker struct {
+	mu       sync.Mutex
 	progress *MigrationProgress
-	persist  *Persistence
+	persist  Persistence
 	dryRun   bool
 }
 
 func NewTracker(progressFile string, dryRun bool) (*Tracker, error) {
-	persist := NewPersistence(progressFile)
+	return NewTrackerWithPersistence(NewPersistence(progressFile), dryRun)
+}
+
+// NewTrackerWithPersistence creates a tracker loading and saving its progress
+// through persist.
+func NewTrackerWithPersistence(persist Persistence, dryRun bool) (*Tracker, error) {
 	progress, err := persist.Load()
 	if err != nil {
 		// Return default progress on load error
@@ -34,6 +146,7 @@ func NewTracker(progressFile string, dryRun bool) (*Tracker, error) {
 			FailedThreads:    []int{},
 		}
 	}
+	markLegacyThreads(progress)
 
 	return &Tracker{
 		progress: progress,
@@ -42,15 +155,28 @@ func NewTracker(progressFile string, dryRun bool) (*Tracker, error) {
 	}, nil
 }
 
+// GetProgress returns the progress of every thread.
 func (t *Tracker) GetProgress() *MigrationProgress {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.loadAll()
 	return t.progress
 }
 
 func (t *Tracker) SetResumeFrom(threadID int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
 	t.progress.LastThreadID = threadID
 }
 
+// MarkCompleted records a thread as migrated. A thread that failed before is
+// no longer failed.
 func (t *Tracker) MarkCompleted(threadID int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadThread(threadID)
+
 	// Check if threadID already exists in CompletedThreads
 	for _, id := range t.progress.CompletedThreads {
 		if id == threadID {
@@ -60,22 +186,314 @@ func (t *Tracker) MarkCompleted(threadID int) error {
 
 	t.progress.CompletedThreads = append(t.progress.CompletedThreads, threadID)
 	t.progress.LastThreadID = threadID
-	return t.save()
+	t.progress.FailedThreads = removeThreadID(t.progress.FailedThreads, threadID)
+	delete(t.progress.Failures, threadID)
+	delete(t.progress.Checkpoints, threadID)
+	return t.save(threadID)
 }
 
-func (t *Tracker) MarkFailed(threadID int) error {
+// MarkFailed records a failed attempt to migrate a thread with the phase it
+// failed in and its error message, either of which may be empty, and counts
+// the attempt.
+func (t *Tracker) MarkFailed(threadID int, phase, message string) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadThread(threadID)
+
+	if t.progress.Failures == nil {
+		t.progress.Failures = make(map[int]ThreadFailure)
+	}
+	failure := t.progress.Failures[threadID]
+	failure.Phase = phase
+	failure.Error = message
+	failure.Attempts++
+	failure.LastTried = time.Now().Unix()
+	t.progress.Failures[threadID] = failure
+
 	// Check if threadID already exists in FailedThreads
-	for _, id := range t.progress.FailedThreads {
+	if !slices.Contains(t.progress.FailedThreads, threadID) {
+		t.progress.FailedThreads = append(t.progress.FailedThreads, threadID)
+	}
+	return t.save(threadID)
+}
+
+// ClearFailed removes a thread from the failed threads, e.g. after a
+// successful retry.
+func (t *Tracker) ClearFailed(threadID int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadThread(threadID)
+
+	if !slices.Contains(t.progress.FailedThreads, threadID) {
+		return nil
+	}
+	t.progress.FailedThreads = removeThreadID(t.progress.FailedThreads, threadID)
+	delete(t.progress.Failures, threadID)
+	return t.save(threadID)
+}
+
+// FailedThreads returns the threads recorded as failed with the details of
+// their failed attempts, which are empty for threads failed before those
+// were recorded.
+func (t *Tracker) FailedThreads() map[int]ThreadFailure {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadAll()
+
+	failed := make(map[int]ThreadFailure, len(t.progress.FailedThreads))
+	for _, threadID := range t.progress.FailedThreads {
+		failed[threadID] = t.progress.Failures[threadID]
+	}
+	return failed
+}
+
+// IsCompleted reports whether a thread is marked as completed.
+func (t *Tracker) IsCompleted(threadID int) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadThread(threadID)
+
+	for _, id := range t.progress.CompletedThreads {
 		if id == threadID {
-			return nil // Already marked as failed, no need to add again
+			return true
 		}
 	}
+	return false
+}
 
-	t.progress.FailedThreads = append(t.progress.FailedThreads, threadID)
+// Checkpoint returns a copy of the checkpoint saved for an interrupted thread.
+func (t *Tracker) Checkpoint(threadID int) (*ThreadCheckpoint, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	checkpoint, ok := t.progress.Checkpoints[threadID]
+	if !ok {
+		return nil, false
+	}
+	return checkpoint.clone(), true
+}
+
+// SaveCheckpoint persists how far an interrupted thread was migrated. Of the
+// comment IDs, only those added since the checkpoint was last saved are
+// copied. The checkpoint is removed once the thread is marked completed.
+func (t *Tracker) SaveCheckpoint(threadID int, checkpoint *ThreadCheckpoint) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.progress.Checkpoints == nil {
+		t.progress.Checkpoints = make(map[int]*ThreadCheckpoint)
+	}
+	stored, ok := t.progress.Checkpoints[threadID]
+	if !ok || checkpoint.reset {
+		t.progress.Checkpoints[threadID] = checkpoint.clone()
+	} else {
+		stored.Discussions = append(stored.Discussions[:0], checkpoint.Discussions...)
+		stored.LinkedParts = checkpoint.LinkedParts
+		stored.LastPostID = checkpoint.LastPostID
+		if stored.CommentIDs == nil {
+			stored.CommentIDs = make(map[int]string, len(checkpoint.added))
+		}
+		for _, postID := range checkpoint.added {
+			stored.CommentIDs[postID] = checkpoint.CommentIDs[postID]
+		}
+	}
+	checkpoint.added = checkpoint.added[:0]
+	checkpoint.reset = false
+	return t.save()
+}
+
+func (c *ThreadCheckpoint) clone() *ThreadCheckpoint {
+	clone := *c
+	clone.added, clone.reset = nil, false
+	clone.Discussions = append([]DiscussionRef(nil), c.Discussions...)
+	clone.CommentIDs = make(map[int]string, len(c.CommentIDs))
+	for postID, commentID := range c.CommentIDs {
+		clone.CommentIDs[postID] = commentID
+	}
+	return &clone
+}
+
+// Listing returns the interrupted thread listing saved for a node.
+func (t *Tracker) Listing(nodeID int) (*ThreadListing, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	listing := t.progress.Listing
+	if listing == nil || listing.NodeID != nodeID {
+		return nil, false
+	}
+	return &ThreadListing{NodeID: listing.NodeID, Page: listing.Page, Threads: append([]xenforo.Thread(nil), listing.Threads...)}, true
+}
+
+// SaveListing persists the threads collected up to and including page.
+func (t *Tracker) SaveListing(nodeID, page int, threads []xenforo.Thread) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.progress.Listing = &ThreadListing{NodeID: nodeID, Page: page, Threads: append([]xenforo.Thread(nil), threads...)}
+	return t.save()
+}
+
+// ClearListing removes the saved listing once the listing has completed.
+func (t *Tracker) ClearListing() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.progress.Listing == nil {
+		return nil
+	}
+	t.progress.Listing = nil
+	return t.save()
+}
+
+// RecordDiscussion stores the discussion a thread was migrated to.
+func (t *Tracker) RecordDiscussion(threadID int, ref DiscussionRef) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadThread(threadID)
+
+	if t.progress.Discussions == nil {
+		t.progress.Discussions = make(map[int]DiscussionRef)
+	}
+	t.progress.Discussions[threadID] = ref
+	return t.save(threadID)
+}
+
+// Discussion returns the discussion recorded for a thread.
+func (t *Tracker) Discussion(threadID int) (DiscussionRef, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadThread(threadID)
+
+	ref, ok := t.progress.Discussions[threadID]
+	return ref, ok
+}
+
+// Discussions returns a copy of the recorded discussions keyed by thread ID.
+func (t *Tracker) Discussions() map[int]DiscussionRef {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadAll()
+
+	discussions := make(map[int]DiscussionRef, len(t.progress.Discussions))
+	for threadID, ref := range t.progress.Discussions {
+		discussions[threadID] = ref
+	}
+	return discussions
+}
+
+// ForgetThread removes every record of a thread (completion, failure,
+// checkpoint and discussion), so the next run migrates it again.
+func (t *Tracker) ForgetThread(threadID int) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadThread(threadID)
+
+	t.progress.CompletedThreads = removeThreadID(t.progress.CompletedThreads, threadID)
+	t.progress.FailedThreads = removeThreadID(t.progress.FailedThreads, threadID)
+	delete(t.progress.Failures, threadID)
+	delete(t.progress.Checkpoints, threadID)
+	delete(t.progress.Discussions, threadID)
+	t.progress.LegacyThreads = removeThreadID(t.progress.LegacyThreads, threadID)
+	return t.save(threadID)
+}
+
+func removeThreadID(ids []int, threadID int) []int {
+	kept := ids[:0:0]
+	for _, id := range ids {
+		if id != threadID {
+			kept = append(kept, id)
+		}
+	}
+	return kept
+}
+
+// markLegacyThreads records the completed threads of a progress file written
+// before discussions were recorded, which has completed threads but no
+// discussions at all, as legacy threads.
+func markLegacyThreads(progress *MigrationProgress) {
+	if progress.Discussions != nil || progress.LegacyThreads != nil || len(progress.CompletedThreads) == 0 {
+		return
+	}
+	progress.LegacyThreads = append([]int(nil), progress.CompletedThreads...)
+}
+
+// UnmappedThreads returns the completed threads without a recorded
+// discussion, in completion order. A thread created on GitHub but never
+// recorded shows up here; legacy threads do not.
+func (t *Tracker) UnmappedThreads() []int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadAll()
+
+	return t.unmappedThreads()
+}
+
+func (t *Tracker) unmappedThreads() []int {
+	legacy := make(map[int]bool, len(t.progress.LegacyThreads))
+	for _, id := range t.progress.LegacyThreads {
+		legacy[id] = true
+	}
+
+	var unmapped []int
+	for _, id := range t.progress.CompletedThreads {
+		if _, ok := t.progress.Discussions[id]; !ok && !legacy[id] {
+			unmapped = append(unmapped, id)
+		}
+	}
+	return unmapped
+}
+
+// LastRunAt returns the start time of the last successful run recorded under key.
+func (t *Tracker) LastRunAt(key string) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at, ok := t.progress.Metadata.LastRuns[key]
+	return at, ok
+}
+
+// RecordRun stores the start time of a successful run under key.
+func (t *Tracker) RecordRun(key string, startedAt int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.progress.Metadata.LastRuns == nil {
+		t.progress.Metadata.LastRuns = make(map[string]int64)
+	}
+	t.progress.Metadata.LastRuns[key] = startedAt
+	return t.save()
+}
+
+// LastSyncAt returns the start time of the last successful sync pass
+// recorded under key.
+func (t *Tracker) LastSyncAt(key string) (int64, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at, ok := t.progress.Metadata.LastSyncs[key]
+	return at, ok
+}
+
+// RecordSync stores the start time of a successful sync pass under key.
+func (t *Tracker) RecordSync(key string, startedAt int64) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.progress.Metadata.LastSyncs == nil {
+		t.progress.Metadata.LastSyncs = make(map[string]int64)
+	}
+	t.progress.Metadata.LastSyncs[key] = startedAt
 	return t.save()
 }
 
 func (t *Tracker) FilterCompletedThreads(threads []xenforo.Thread) []xenforo.Thread {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, thread := range threads {
+		t.loadThread(thread.ThreadID)
+	}
+
 	completed := make(map[int]bool)
 	for _, id := range t.progress.CompletedThreads {
 		completed[id] = true
@@ -92,6 +510,10 @@ func (t *Tracker) FilterCompletedThreads(threads []xenforo.Thread) []xenforo.Thr
 }
 
 func (t *Tracker) PrintSummary() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.loadAll()
+
 	fmt.Println("\n" + strings.Repeat("=", 50))
 	fmt.Println("Migration Summary")
 	fmt.Println(strings.Repeat("=", 50))
@@ -101,6 +523,17 @@ func (t *Tracker) PrintSummary() {
 	if len(t.progress.FailedThreads) > 0 {
 		fmt.Println("\nFailed thread IDs:")
 		for _, id := range t.progress.FailedThreads {
+			if failure := t.progress.Failures[id].String(); failure != "" {
+				fmt.Printf("  - %d: %s\n", id, failure)
+			} else {
+				fmt.Printf("  - %d\n", id)
+			}
+		}
+	}
+
+	if unmapped := t.unmappedThreads(); len(unmapped) > 0 {
+		fmt.Println("\nCompleted threads without a recorded discussion:")
+		for _, id := range unmapped {
 			fmt.Printf("  - %d\n", id)
 		}
 	}
@@ -110,7 +543,30 @@ func (t *Tracker) PrintSummary() {
 	}
 }
 
-func (t *Tracker) save() error {
+// save persists the progress after a change to the given threads, or to no
+// thread in particular.
+func (t *Tracker) save(threadIDs ...int) error {
 	t.progress.LastUpdated = time.Now().Unix()
+	if store, ok := t.persist.(ThreadStore); ok {
+		return store.SaveThreads(t.progress, threadIDs...)
+	}
 	return t.persist.Save(t.progress)
 }
+
+// loadThread loads the state of a thread kept in a thread store.
+func (t *Tracker) loadThread(threadID int) {
+	if store, ok := t.persist.(ThreadStore); ok {
+		if err := store.LoadThread(t.progress, threadID); err != nil {
+			logging.Errorf(context.Background(), "Failed to load the progress of thread %d: %v", threadID, err)
+		}
+	}
+}
+
+// loadAll loads the state of every thread kept in a thread store.
+func (t *Tracker) loadAll() {
+	if store, ok := t.persist.(ThreadStore); ok {
+		if err := store.LoadThreads(t.progress); err != nil {
+			logging.Errorf(context.Background(), "Failed to load the progress of every thread: %v", err)
+		}
+	}
+}
@@ -0,0 +1,161 @@
+// Package retry runs operations again after transient failures. A Policy
+// waits between attempts with capped backoff and jitter, honors delays the
+// server asks for, and stops as soon as the context is cancelled. It is shared
+// by the XenForo and GitHub clients.
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff returns the delay before retry n (1 = the first retry).
+type Backoff func(n int) time.Duration
+
+// Exponential doubles the delay with every retry: base, 2*base, 4*base...
+// The delay saturates at the longest duration instead of overflowing, for
+// MaxDelay to cap.
+func Exponential(base time.Duration) Backoff {
+	return func(n int) time.Duration {
+		shift := min(max(n-1, 0), 62)
+		if base > math.MaxInt64>>shift {
+			return math.MaxInt64
+		}
+		return base << shift
+	}
+}
+
+// Linear grows the delay by step with every retry: step, 2*step, 3*step...
+func Linear(step time.Duration) Backoff {
+	return func(n int) time.Duration {
+		return time.Duration(n) * step
+	}
+}
+
+// Classifier decides whether a failed attempt is retried. A positive wait is
+// the delay the server asked for, which replaces the backoff delay.
+type Classifier func(err error) (retry bool, wait time.Duration)
+
+// Policy describes how an operation is retried.
+type Policy struct {
+	MaxAttempts int           // Attempts including the first (values below 1 mean 1)
+	Backoff     Backoff       // Delay before each retry (nil = no delay)
+	MaxDelay    time.Duration // Cap on the backoff delay (0 = uncapped); server waits are not capped
+	Jitter      float64       // Fraction of the delay added at random, e.g. 0.1
+	Classify    Classifier    // Errors retried (nil = all but Permanent ones)
+
+	// OnRetry is called before waiting for retry n (optional).
+	OnRetry func(n int, err error, delay time.Duration)
+}
+
+// ExhaustedError is returned when an operation still fails after all its
+// attempts. It wraps the error of the last attempt.
+type ExhaustedError struct {
+	Attempts int
+	Err      error
+}
+
+func (e *ExhaustedError) Error() string {
+	return fmt.Sprintf("giving up after %d attempts: %v", e.Attempts, e.Err)
+}
+
+func (e *ExhaustedError) Unwrap() error {
+	return e.Err
+}
+
+// permanentError marks an error that is never retried.
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not retryable whatever the policy's classifier says.
+// Do returns the unwrapped err.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// Do runs op until it succeeds, fails with an error the policy does not
+// retry, runs out of attempts (returning an ExhaustedError), or ctx is done
+// (returning ctx's error). op receives the attempt number, starting at 0.
+// Errors of attempts made after ctx is done are returned unchanged.
+func (p Policy) Do(ctx context.Context, op func(attempt int) error) error {
+	attempts := max(p.MaxAttempts, 1)
+	for attempt := 0; ; attempt++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		err := op(attempt)
+		if err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+
+		var permanent *permanentError
+		if errors.As(err, &permanent) {
+			return permanent.err
+		}
+		retry, wait := true, time.Duration(0)
+		if p.Classify != nil {
+			retry, wait = p.Classify(err)
+		}
+		if !retry {
+			return err
+		}
+		if attempt+1 >= attempts {
+			return &ExhaustedError{Attempts: attempts, Err: err}
+		}
+
+		delay := p.delay(attempt+1, wait)
+		if p.OnRetry != nil {
+			p.OnRetry(attempt+1, err, delay)
+		}
+		if err := Wait(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+// delay returns how long to wait before retry n: the server's wait when it
+// gave one, else the capped backoff, plus jitter.
+func (p Policy) delay(n int, wait time.Duration) time.Duration {
+	delay := wait
+	if delay <= 0 && p.Backoff != nil {
+		delay = p.Backoff(n)
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			delay = p.MaxDelay
+		}
+	}
+	if limit := min(time.Duration(float64(delay)*p.Jitter), math.MaxInt64-delay); limit > 0 {
+		delay += rand.N(limit)
+	}
+	return delay
+}
+
+// Wait pauses for d, returning early with ctx's error when it is cancelled.
+func Wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,248 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var errTransient = errors.New("transient failure")
+
+func TestBackoff(t *testing.T) {
+	exponential := Exponential(time.Second)
+	linear := Linear(2 * time.Second)
+	for n, expected := range map[int][2]time.Duration{
+		1: {1 * time.Second, 2 * time.Second},
+		2: {2 * time.Second, 4 * time.Second},
+		3: {4 * time.Second, 6 * time.Second},
+		4: {8 * time.Second, 8 * time.Second},
+	} {
+		if got := exponential(n); got != expected[0] {
+			t.Errorf("Expected exponential retry %d to wait %v, got %v", n, expected[0], got)
+		}
+		if got := linear(n); got != expected[1] {
+			t.Errorf("Expected linear retry %d to wait %v, got %v", n, expected[1], got)
+		}
+	}
+
+	// Long retry sequences saturate instead of overflowing
+	for _, n := range []int{35, 64, 1000} {
+		if got := exponential(n); got != math.MaxInt64 {
+			t.Errorf("Expected exponential retry %d to saturate, got %v", n, got)
+		}
+	}
+	if got := (Policy{Backoff: exponential, MaxDelay: time.Minute}).delay(1000, 0); got != time.Minute {
+		t.Errorf("Expected the saturated delay capped at a minute, got %v", got)
+	}
+	if got := (Policy{Backoff: exponential, Jitter: 0.1}).delay(1000, 0); got != math.MaxInt64 {
+		t.Errorf("Expected jitter not to overflow the saturated delay, got %v", got)
+	}
+}
+
+func TestPolicy_Do(t *testing.T) {
+	tests := []struct {
+		name        string
+		policy      Policy
+		failures    int
+		err         error
+		expectCalls int
+		expectErr   func(error) bool
+	}{
+		{
+			name:        "Success on first attempt",
+			policy:      Policy{MaxAttempts: 3},
+			expectCalls: 1,
+		},
+		{
+			name:        "Success after retries",
+			policy:      Policy{MaxAttempts: 3},
+			failures:    2,
+			err:         errTransient,
+			expectCalls: 3,
+		},
+		{
+			name:        "Exhausted attempts",
+			policy:      Policy{MaxAttempts: 3},
+			failures:    10,
+			err:         errTransient,
+			expectCalls: 3,
+			expectErr: func(err error) bool {
+				var exhausted *ExhaustedError
+				return errors.As(err, &exhausted) && exhausted.Attempts == 3 && errors.Is(err, errTransient)
+			},
+		},
+		{
+			name:        "Zero attempts still runs once",
+			policy:      Policy{},
+			failures:    10,
+			err:         errTransient,
+			expectCalls: 1,
+			expectErr:   func(err error) bool { return errors.Is(err, errTransient) },
+		},
+		{
+			name: "Classifier stops retries",
+			policy: Policy{MaxAttempts: 3, Classify: func(err error) (bool, time.Duration) {
+				return false, 0
+			}},
+			failures:    10,
+			err:         errTransient,
+			expectCalls: 1,
+			expectErr:   func(err error) bool { return err == errTransient },
+		},
+		{
+			name:        "Permanent error is unwrapped",
+			policy:      Policy{MaxAttempts: 3},
+			failures:    10,
+			err:         Permanent(errTransient),
+			expectCalls: 1,
+			expectErr:   func(err error) bool { return err == errTransient },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls := 0
+			err := tt.policy.Do(context.Background(), func(attempt int) error {
+				if attempt != calls {
+					t.Errorf("Expected attempt %d, got %d", calls, attempt)
+				}
+				calls++
+				if calls <= tt.failures {
+					return tt.err
+				}
+				return nil
+			})
+
+			if calls != tt.expectCalls {
+				t.Errorf("Expected %d calls, got %d", tt.expectCalls, calls)
+			}
+			if tt.expectErr == nil && err != nil {
+				t.Errorf("Expected success, got %v", err)
+			}
+			if tt.expectErr != nil && !tt.expectErr(err) {
+				t.Errorf("Unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestPolicy_DoOnRetry(t *testing.T) {
+	var delays []time.Duration
+	policy := Policy{
+		MaxAttempts: 4,
+		Backoff:     Exponential(time.Millisecond),
+		Classify: func(err error) (bool, time.Duration) {
+			if err.Error() == "wait" {
+				return true, 5 * time.Millisecond
+			}
+			return true, 0
+		},
+		OnRetry: func(n int, err error, delay time.Duration) {
+			if n != len(delays)+1 {
+				t.Errorf("Expected retry %d, got %d", len(delays)+1, n)
+			}
+			delays = append(delays, delay)
+		},
+	}
+
+	errs := []error{errTransient, errors.New("wait"), errTransient, nil}
+	if err := policy.Do(context.Background(), func(attempt int) error { return errs[attempt] }); err != nil {
+		t.Fatalf("Expected success, got %v", err)
+	}
+
+	expected := []time.Duration{time.Millisecond, 5 * time.Millisecond, 4 * time.Millisecond}
+	if len(delays) != len(expected) {
+		t.Fatalf("Expected delays %v, got %v", expected, delays)
+	}
+	for i := range expected {
+		if delays[i] != expected[i] {
+			t.Errorf("Expected delays %v, got %v", expected, delays)
+			break
+		}
+	}
+}
+
+func TestPolicy_Delay(t *testing.T) {
+	policy := Policy{Backoff: Linear(time.Minute), MaxDelay: 3 * time.Minute}
+	if got := policy.delay(2, 0); got != 2*time.Minute {
+		t.Errorf("Expected the backoff delay, got %v", got)
+	}
+	if got := policy.delay(10, 0); got != 3*time.Minute {
+		t.Errorf("Expected the backoff to be capped, got %v", got)
+	}
+	if got := policy.delay(1, time.Hour); got != time.Hour {
+		t.Errorf("Expected the server's wait to replace the backoff uncapped, got %v", got)
+	}
+	if got := (Policy{}).delay(3, 0); got != 0 {
+		t.Errorf("Expected no delay without a backoff, got %v", got)
+	}
+
+	policy.Jitter = 0.1
+	for i := 0; i < 100; i++ {
+		if got := policy.delay(1, 10*time.Second); got < 10*time.Second || got >= 11*time.Second {
+			t.Fatalf("Expected 10s plus under 1s of jitter, got %v", got)
+		}
+	}
+}
+
+func TestPolicy_DoCancellation(t *testing.T) {
+	t.Run("Cancelled before the first attempt", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		calls := 0
+		err := Policy{MaxAttempts: 3}.Do(ctx, func(int) error {
+			calls++
+			return nil
+		})
+		if !errors.Is(err, context.Canceled) || calls != 0 {
+			t.Errorf("Expected no attempt and the context's error, got %v after %d calls", err, calls)
+		}
+	})
+
+	t.Run("Cancelled during the backoff", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		calls := 0
+		start := time.Now()
+		err := Policy{MaxAttempts: 3, Backoff: Linear(time.Hour)}.Do(ctx, func(int) error {
+			calls++
+			return errTransient
+		})
+		if err != context.DeadlineExceeded || calls != 1 {
+			t.Errorf("Expected the deadline to stop the backoff, got %v after %d calls", err, calls)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Errorf("Expected the backoff to be cut short, took %v", elapsed)
+		}
+	})
+
+	t.Run("Attempt failing after cancellation", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		err := Policy{MaxAttempts: 3}.Do(ctx, func(int) error {
+			cancel()
+			return errTransient
+		})
+		if err != errTransient {
+			t.Errorf("Expected the attempt's error unchanged, got %v", err)
+		}
+	})
+}
+
+func TestWait(t *testing.T) {
+	if err := Wait(context.Background(), 0); err != nil {
+		t.Errorf("Expected no error for a zero wait, got %v", err)
+	}
+	if err := Wait(context.Background(), time.Millisecond); err != nil {
+		t.Errorf("Expected no error, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := Wait(ctx, 0); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected the context's error, got %v", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 type GitHubClient struct {
 	CreateDiscussionFunc  func(title, body, categoryID string) (*github.DiscussionResult, error)
 	AddCommentFunc        func(discussionID, body string) error
+	AddCommentReplyFunc   func(discussionID, replyToID, body string) (string, error)
 	GetRepositoryInfoFunc func(repo string) (*github.RepositoryInfo, error)
 }
 
@@ -27,6 +28,13 @@ func (m *GitHubClient) AddComment(discussionID, body string) error {
 	return nil
 }
 
+func (m *GitHubClient) AddCommentReply(discussionID, replyToID, body string) (string, error) {
+	if m.AddCommentReplyFunc != nil {
+		return m.AddCommentReplyFunc(discussionID, replyToID, body)
+	}
+	return "test_comment_id", nil
+}
+
 func (m *GitHubClient) GetRepositoryInfo(repo string) (*github.RepositoryInfo, error) {
 	if m.GetRepositoryInfoFunc != nil {
 		return m.GetRepositoryInfoFunc(repo)
@@ -0,0 +1,28 @@
+package testutil
+
+import "sync/atomic"
+
+// ConcurrencyProbe records the highest number of callers inside a section at
+// the same time, for tests asserting a concurrency limit.
+type ConcurrencyProbe struct {
+	active int32
+	max    int32
+}
+
+// Enter marks a caller inside the section and returns the function marking
+// it out again.
+func (p *ConcurrencyProbe) Enter() (leave func()) {
+	current := atomic.AddInt32(&p.active, 1)
+	for {
+		old := atomic.LoadInt32(&p.max)
+		if current <= old || atomic.CompareAndSwapInt32(&p.max, old, current) {
+			break
+		}
+	}
+	return func() { atomic.AddInt32(&p.active, -1) }
+}
+
+// Max returns the highest number of callers seen inside the section at once.
+func (p *ConcurrencyProbe) Max() int32 {
+	return atomic.LoadInt32(&p.max)
+}
@@ -1,6 +1,7 @@
 package testutil
 
 import (
+	"context"
 	"errors"
 
 	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
@@ -13,28 +14,28 @@ type XenForoClient struct {
 	DownloadAttachmentFunc func(url, filepath string) error
 }
 
-func (m *XenForoClient) TestConnection() error {
+func (m *XenForoClient) TestConnection(ctx context.Context) error {
 	if m.TestConnectionFunc != nil {
 		return m.TestConnectionFunc()
 	}
 	return errors.New("TestConnectionFunc not set - test must explicitly set mock behavior")
 }
 
-func (m *XenForoClient) GetThreads(nodeID int) ([]xenforo.Thread, error) {
+func (m *XenForoClient) GetThreads(ctx context.Context, nodeID int) ([]xenforo.Thread, error) {
 	if m.GetThreadsFunc != nil {
 		return m.GetThreadsFunc(nodeID)
 	}
 	return nil, errors.New("GetThreadsFunc not set - test must explicitly set mock behavior")
 }
 
-func (m *XenForoClient) GetPosts(thread xenforo.Thread) ([]xenforo.Post, error) {
+func (m *XenForoClient) GetPosts(ctx context.Context, thread xenforo.Thread) ([]xenforo.Post, error) {
 	if m.GetPostsFunc != nil {
 		return m.GetPostsFunc(thread)
 	}
 	return nil, errors.New("GetPostsFunc not set - test must explicitly set mock behavior")
 }
 
-func (m *XenForoClient) DownloadAttachment(url, filepath string) error {
+func (m *XenForoClient) DownloadAttachment(ctx context.Context, url, filepath string) error {
 	if m.DownloadAttachmentFunc != nil {
 		return m.DownloadAttachmentFunc(url, filepath)
 	}
@@ -1,16 +1,26 @@
 package xenforo
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
 	"time"
 
+	"github.com/exileum/xenforo-to-gh-discussions/internal/retry"
 	"github.com/go-resty/resty/v2"
 )
 
-func (c *Client) TestConnection() error {
-	resp, err := c.retryableRequest(func() (*resty.Response, error) {
-		return c.addHeaders(c.client.R()).Get(c.baseURL + "/")
+// TestConnection checks the API is reachable with the configured key and
+// records the XenForo version the index reports, see Version.
+func (c *Client) TestConnection(ctx context.Context) error {
+	resp, err := c.retryableRequest(ctx, func() (*resty.Response, error) {
+		return c.request(ctx).Get(c.baseURL + "/")
 	})
 
 	if err != nil {
@@ -25,58 +35,116 @@ func (c *Client) TestConnection() error {
 		return fmt.Errorf("API error: %s", resp.String())
 	}
 
+	var index IndexResponse
+	if err := json.Unmarshal(resp.Body(), &index); err != nil {
+		return fmt.Errorf("unexpected API index response: %w", err)
+	}
+	c.version = Version(index.VersionID)
+
 	return nil
 }
 
-func (c *Client) GetThreads(nodeID int) ([]Thread, error) {
-	var threads []Thread
-	page := 1
+// Version returns the XenForo version detected by TestConnection, or the
+// zero Version before a successful connection test.
+func (c *Client) Version() Version {
+	return c.version
+}
 
-	for {
-		resp, err := c.retryableRequest(func() (*resty.Response, error) {
-			return c.addHeaders(c.client.R()).
-				SetQueryParam("page", fmt.Sprintf("%d", page)).
-				Get(fmt.Sprintf("%s/forums/%d/threads", c.baseURL, nodeID))
-		})
+func (c *Client) GetThreads(ctx context.Context, nodeID int) ([]Thread, error) {
+	return c.GetThreadsFrom(ctx, nodeID, 1, nil, nil)
+}
+
+// GetThreadsFrom lists the threads of a node starting at startPage, appending
+// them to threads collected by an earlier, interrupted listing. Threads that
+// were already collected (e.g., shifted to a later page by new threads) are
+// skipped. onPage, when set, is called after every fetched page with the page
+// number and all threads collected so far; an error from it aborts the
+// listing. Pages failing with a server error are retried.
+func (c *Client) GetThreadsFrom(ctx context.Context, nodeID, startPage int, threads []Thread, onPage func(page int, threads []Thread) error) ([]Thread, error) {
+	seen := make(map[int]bool, len(threads))
+	for _, thread := range threads {
+		seen[thread.ThreadID] = true
+	}
 
+	page := max(startPage, 1)
+	for {
+		result, cached, err := c.getThreadsPage(ctx, nodeID, page)
 		if err != nil {
-			return nil, err
+			return nil, fmt.Errorf("failed to list threads (page %d): %w", page, err)
 		}
 
-		if resp.StatusCode() != 200 {
-			return nil, fmt.Errorf("API error: %s", resp.String())
+		for _, thread := range result.Threads {
+			if !seen[thread.ThreadID] {
+				seen[thread.ThreadID] = true
+				threads = append(threads, thread)
+			}
 		}
 
-		var result ThreadsResponse
-		if err := json.Unmarshal(resp.Body(), &result); err != nil {
-			return nil, err
+		if onPage != nil {
+			if err := onPage(page, threads); err != nil {
+				return nil, err
+			}
 		}
 
-		threads = append(threads, result.Threads...)
-
-		if result.Pagination.CurrentPage >= result.Pagination.TotalPages {
+		if result.Pagination.CurrentPage >= result.Pagination.Pages() {
 			break
 		}
 
 		page++
-		time.Sleep(1 * time.Second)
+		if cached {
+			continue
+		}
+		if err := retry.Wait(ctx, c.pageDelay); err != nil {
+			return nil, err
+		}
 	}
 
 	return threads, nil
 }
 
-func (c *Client) GetPosts(thread Thread) ([]Post, error) {
+// getThreadsPage fetches a page of a forum's threads, retrying failed
+// requests and server errors with exponential backoff. cached reports a page
+// served from the response cache.
+func (c *Client) getThreadsPage(ctx context.Context, nodeID, page int) (*ThreadsResponse, bool, error) {
+	var result ThreadsResponse
+	var cached bool
+	policy := retry.Policy{
+		MaxAttempts: c.maxRetries,
+		Backoff:     retry.Exponential(time.Second),
+		Jitter:      0.1,
+	}
+	err := policy.Do(ctx, func(int) error {
+		resp, fromCache, err := c.getListing(ctx, fmt.Sprintf("%s/forums/%d/threads", c.baseURL, nodeID), page)
+		if err != nil {
+			return err
+		}
+
+		if resp.StatusCode() >= 500 {
+			return fmt.Errorf("API error: %s", resp.String())
+		}
+
+		if resp.StatusCode() != 200 {
+			return retry.Permanent(fmt.Errorf("API error: %s", resp.String()))
+		}
+
+		cached = fromCache
+		return retry.Permanent(json.Unmarshal(resp.Body(), &result))
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &result, cached, nil
+}
+
+func (c *Client) GetPosts(ctx context.Context, thread Thread) ([]Post, error) {
 	var posts []Post
 
 	// Calculate total posts: reply_count + 1 (original post)
 	totalPosts := thread.ReplyCount + 1
 
 	// Start with first page to determine posts per page
-	firstPageResp, err := c.retryableRequest(func() (*resty.Response, error) {
-		return c.addHeaders(c.client.R()).
-			SetQueryParam("page", "1").
-			Get(fmt.Sprintf("%s/threads/%d/posts", c.baseURL, thread.ThreadID))
-	})
+	postsURL := fmt.Sprintf("%s/threads/%d/posts", c.baseURL, thread.ThreadID)
+	firstPageResp, _, err := c.getListing(ctx, postsURL, 1)
 
 	if err != nil {
 		return nil, err
@@ -94,22 +162,20 @@ func (c *Client) GetPosts(thread Thread) ([]Post, error) {
 	posts = append(posts, firstResult.Posts...)
 	postsPerPage := len(firstResult.Posts)
 
-	// If we got all posts on the first page, we're done
-	if len(posts) >= totalPosts {
-		return posts, nil
+	// Use the reported page count; estimate it from the reply count when the
+	// response has none
+	totalPages := firstResult.Pagination.Pages()
+	if totalPages == 0 {
+		// If we got all posts on the first page, we're done
+		if len(posts) >= totalPosts || postsPerPage == 0 {
+			return posts, nil
+		}
+		totalPages = (totalPosts + postsPerPage - 1) / postsPerPage // Ceiling division
 	}
 
-	// Calculate how many more pages we need
-	totalPages := (totalPosts + postsPerPage - 1) / postsPerPage // Ceiling division
-
 	// Fetch remaining pages
 	for page := 2; page <= totalPages; page++ {
-		resp, err := c.retryableRequest(func() (*resty.Response, error) {
-			return c.addHeaders(c.client.R()).
-				SetQueryParam("page", fmt.Sprintf("%d", page)).
-				Get(fmt.Sprintf("%s/threads/%d/posts", c.baseURL, thread.ThreadID))
-		})
-
+		resp, cached, err := c.getListing(ctx, postsURL, page)
 		if err != nil {
 			return nil, err
 		}
@@ -130,34 +196,109 @@ func (c *Client) GetPosts(thread Thread) ([]Post, error) {
 			break
 		}
 
-		time.Sleep(1 * time.Second)
+		if cached {
+			continue
+		}
+		if err := retry.Wait(ctx, 1*time.Second); err != nil {
+			return nil, err
+		}
 	}
 
 	return posts, nil
 }
 
-func (c *Client) DownloadAttachment(url, filepath string) error {
-	resp, err := c.retryableRequest(func() (*resty.Response, error) {
-		return c.addHeaders(c.client.R()).
-			SetOutput(filepath).
-			Get(url)
+// ErrIncompleteDownload reports a download shorter than the Content-Length
+// of its response.
+var ErrIncompleteDownload = errors.New("incomplete download")
+
+// partialSuffix marks the file of a download in progress. A partial file left
+// by an interrupted download is resumed with a range request.
+const partialSuffix = ".part"
+
+// DownloadAttachment downloads url to filepath through a partial file, which
+// a failed download keeps for the next attempt to resume where it stopped.
+// Downloads are throttled by the bandwidth limiter when set. A download
+// shorter than its Content-Length fails with ErrIncompleteDownload.
+func (c *Client) DownloadAttachment(ctx context.Context, url, filePath string) error {
+	if err := os.MkdirAll(filepath.Dir(filePath), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	partPath := filePath + partialSuffix
+	var offset int64
+	if info, err := os.Stat(partPath); err == nil {
+		offset = info.Size()
+	}
+
+	// Throttled downloads are not bound by the request timeout
+	client := c.client
+	if c.bandwidth != nil {
+		client = c.downloadClient
+	}
+	resp, err := c.retryableRequest(ctx, func() (*resty.Response, error) {
+		req := c.addHeaders(client.R().SetContext(ctx)).SetDoNotParseResponse(true)
+		if offset > 0 {
+			req.SetHeader("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+		resp, err := req.Get(url)
+		if err == nil && resp.StatusCode() == 429 {
+			_ = resp.RawBody().Close()
+		}
+		return resp, err
 	})
 
 	if err != nil {
 		return err
 	}
-
-	if resp.StatusCode() != 200 {
+	body := resp.RawBody()
+	defer func() { _ = body.Close() }()
+
+	flags := os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	switch {
+	case resp.StatusCode() == http.StatusPartialContent && offset > 0:
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
+	case resp.StatusCode() == http.StatusRequestedRangeNotSatisfiable && offset > 0:
+		// The partial file does not fit the attachment anymore: start over
+		if err := os.Remove(partPath); err != nil {
+			return fmt.Errorf("failed to remove partial download: %w", err)
+		}
+		return c.DownloadAttachment(ctx, url, filePath)
+	case resp.StatusCode() != 200:
 		return fmt.Errorf("download failed: status %d", resp.StatusCode())
 	}
 
+	file, err := os.OpenFile(partPath, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+
+	written, err := io.Copy(file, c.bandwidth.Reader(body))
+	if err != nil {
+		_ = file.Close()
+		return fmt.Errorf("download failed: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	if err := checkDownloadLength(written, resp.RawResponse.ContentLength); err != nil {
+		return err
+	}
+	return os.Rename(partPath, filePath)
+}
+
+// checkDownloadLength compares the bytes written with the Content-Length of
+// the response, which is -1 when unknown.
+func checkDownloadLength(written, contentLength int64) error {
+	if contentLength >= 0 && written != contentLength {
+		return fmt.Errorf("%w: got %d of %d bytes", ErrIncompleteDownload, written, contentLength)
+	}
 	return nil
 }
 
 // GetDryRunStats returns statistics for a node by fetching actual data
-func (c *Client) GetDryRunStats(nodeID int) (threadCount, postCount, attachmentCount, userCount int, err error) {
+func (c *Client) GetDryRunStats(ctx context.Context, nodeID int) (threadCount, postCount, attachmentCount, userCount int, err error) {
 	// Get all threads from the node using our working GetThreads method
-	threads, err := c.GetThreads(nodeID)
+	threads, err := c.GetThreads(ctx, nodeID)
 	if err != nil {
 		return 0, 0, 0, 0, fmt.Errorf("failed to get threads: %w", err)
 	}
@@ -184,10 +325,77 @@ func (c *Client) GetDryRunStats(nodeID int) (threadCount, postCount, attachmentC
 	return threadCount, postCount, attachmentCount, userCount, nil
 }
 
+// NodeStats counts the content of a node from its posts, unlike the estimates
+// of GetDryRunStats.
+type NodeStats struct {
+	Threads         int
+	Posts           int
+	Attachments     int
+	AttachmentBytes int64
+	Users           int // Distinct authors of the thread starters and of the posts fetched
+
+	SampledThreads int  // Threads whose posts were fetched
+	Extrapolated   bool // Attachments and bytes are extrapolated from the sampled threads
+}
+
+// GetNodeStats counts the attachments and their total size by fetching the
+// posts of sampleThreads threads spread across the node, or of every thread
+// when sampleThreads is 0. Sampled counts are extrapolated to the whole node by
+// its post count.
+func (c *Client) GetNodeStats(ctx context.Context, nodeID, sampleThreads int) (*NodeStats, error) {
+	threads, err := c.GetThreads(ctx, nodeID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get threads: %w", err)
+	}
+
+	stats := &NodeStats{Threads: len(threads)}
+	users := make(map[string]bool)
+	for _, thread := range threads {
+		stats.Posts += thread.ReplyCount + 1
+		users[thread.Username] = true
+	}
+
+	sample := threads
+	if sampleThreads > 0 && sampleThreads < len(threads) {
+		// Evenly spaced, so old and recent threads are both represented
+		sample = make([]Thread, sampleThreads)
+		for i := range sample {
+			sample[i] = threads[i*len(threads)/sampleThreads]
+		}
+		stats.Extrapolated = true
+	}
+
+	sampledPosts := 0
+	for _, thread := range sample {
+		posts, err := c.GetPosts(ctx, thread)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get posts of thread %d: %w", thread.ThreadID, err)
+		}
+		sampledPosts += len(posts)
+		for _, post := range posts {
+			users[post.Username] = true
+			stats.Attachments += len(post.Attachments)
+			for _, attachment := range post.Attachments {
+				stats.AttachmentBytes += attachment.FileSize
+			}
+		}
+	}
+	stats.SampledThreads = len(sample)
+	stats.Users = len(users)
+
+	if stats.Extrapolated && sampledPosts > 0 {
+		scale := float64(stats.Posts) / float64(sampledPosts)
+		stats.Attachments = int(math.Round(float64(stats.Attachments) * scale))
+		stats.AttachmentBytes = int64(math.Round(float64(stats.AttachmentBytes) * scale))
+	}
+
+	return stats, nil
+}
+
 // GetNodes fetches available forum nodes/categories from XenForo
-func (c *Client) GetNodes() ([]Node, error) {
-	resp, err := c.retryableRequest(func() (*resty.Response, error) {
-		return c.addHeaders(c.client.R()).Get(c.baseURL + "/nodes")
+func (c *Client) GetNodes(ctx context.Context) ([]Node, error) {
+	resp, err := c.retryableRequest(ctx, func() (*resty.Response, error) {
+		return c.request(ctx).Get(c.baseURL + "/nodes")
 	})
 
 	if err != nil {
@@ -205,3 +413,47 @@ func (c *Client) GetNodes() ([]Node, error) {
 
 	return result.Nodes, nil
 }
+
+// GetUser returns the public profile of a forum member.
+func (c *Client) GetUser(ctx context.Context, userID int) (*User, error) {
+	resp, err := c.retryableRequest(ctx, func() (*resty.Response, error) {
+		return c.request(ctx).Get(fmt.Sprintf("%s/users/%d", c.baseURL, userID))
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %d: %w", userID, err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("API error: %s", resp.String())
+	}
+
+	var result UserResponse
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse user response: %w", err)
+	}
+
+	return &result.User, nil
+}
+
+// GetThread returns a single thread by ID.
+func (c *Client) GetThread(ctx context.Context, threadID int) (*Thread, error) {
+	resp, err := c.retryableRequest(ctx, func() (*resty.Response, error) {
+		return c.request(ctx).Get(fmt.Sprintf("%s/threads/%d", c.baseURL, threadID))
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to get thread %d: %w", threadID, err)
+	}
+
+	if resp.StatusCode() != 200 {
+		return nil, fmt.Errorf("API error: %s", resp.String())
+	}
+
+	var result ThreadResponse
+	if err := json.Unmarshal(resp.Body(), &result); err != nil {
+		return nil, fmt.Errorf("failed to parse thread response: %w", err)
+	}
+
+	return &result.Thread, nil
+}
@@ -0,0 +1,159 @@
+package xenforo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/logging"
+	"github.com/go-resty/resty/v2"
+)
+
+// responseCache keeps thread and post listing responses on disk, so repeated
+// dry runs and resumed migrations do not fetch the whole forum again. Entries
+// younger than ttl are used without a request; older ones are revalidated with
+// their ETag or Last-Modified validators when the forum sent any, and fetched
+// again otherwise.
+type responseCache struct {
+	dir string
+	ttl time.Duration
+}
+
+// cacheEntry is a cached listing response.
+type cacheEntry struct {
+	URL          string          `json:"url"`
+	FetchedAt    time.Time       `json:"fetched_at"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+	Body         json.RawMessage `json:"body"`
+}
+
+// SetCache caches thread and post listing responses in dir (empty = no
+// cache). Cached responses younger than ttl are used without asking the forum
+// (0 = always ask, relying on ETag and Last-Modified). Attachment downloads
+// are never cached.
+func (c *Client) SetCache(dir string, ttl time.Duration) *Client {
+	c.cache = nil
+	if dir != "" {
+		c.cache = &responseCache{dir: dir, ttl: ttl}
+	}
+	return c
+}
+
+// getListing GETs a page of a listing, through the response cache when one
+// is set. cached reports a response served from the cache without a request,
+// after which callers need not pause before the next page.
+func (c *Client) getListing(ctx context.Context, url string, page int) (resp *resty.Response, cached bool, err error) {
+	key := fmt.Sprintf("%s?page=%d", url, page)
+	var entry *cacheEntry
+	if c.cache != nil {
+		entry = c.cache.load(c.apiUser, key)
+		if entry != nil && c.cache.fresh(entry, time.Now()) {
+			return entry.response(), true, nil
+		}
+	}
+
+	resp, err = c.retryableRequest(ctx, func() (*resty.Response, error) {
+		req := c.request(ctx).SetQueryParam("page", strconv.Itoa(page))
+		if entry != nil && entry.ETag != "" {
+			req.SetHeader("If-None-Match", entry.ETag)
+		}
+		if entry != nil && entry.LastModified != "" {
+			req.SetHeader("If-Modified-Since", entry.LastModified)
+		}
+		return req.Get(url)
+	})
+	if err != nil || c.cache == nil {
+		return resp, false, err
+	}
+
+	switch {
+	case resp.StatusCode() == http.StatusNotModified && entry != nil:
+		entry.FetchedAt = time.Now()
+		resp = entry.response()
+	case resp.StatusCode() == http.StatusOK && json.Valid(resp.Body()):
+		entry = &cacheEntry{
+			URL:          key,
+			FetchedAt:    time.Now(),
+			ETag:         resp.Header().Get("ETag"),
+			LastModified: resp.Header().Get("Last-Modified"),
+			Body:         resp.Body(),
+		}
+	default:
+		return resp, false, nil
+	}
+
+	if err := c.cache.store(c.apiUser, key, entry); err != nil {
+		logging.Warnf(ctx, "    ⚠ Failed to cache %s: %v", key, err)
+	}
+	return resp, false, nil
+}
+
+// fresh reports whether an entry is young enough to use without a request.
+func (rc *responseCache) fresh(entry *cacheEntry, now time.Time) bool {
+	return rc.ttl > 0 && now.Sub(entry.FetchedAt) < rc.ttl
+}
+
+// path returns the cache file of a listing page. Keys include the API user,
+// whose permissions decide what the listing contains.
+func (rc *responseCache) path(apiUser, key string) string {
+	sum := sha256.Sum256([]byte(apiUser + "\n" + key))
+	return filepath.Join(rc.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// load returns the cached entry of a listing page, or nil when there is none
+// or it is unreadable.
+func (rc *responseCache) load(apiUser, key string) *cacheEntry {
+	data, err := os.ReadFile(rc.path(apiUser, key))
+	if err != nil {
+		return nil
+	}
+	var entry cacheEntry
+	if json.Unmarshal(data, &entry) != nil || entry.URL != key {
+		return nil
+	}
+	return &entry
+}
+
+// store writes an entry through a temporary file, so concurrent readers and
+// interrupted writes never see a partial entry.
+func (rc *responseCache) store(apiUser, key string, entry *cacheEntry) error {
+	if err := os.MkdirAll(rc.dir, 0755); err != nil {
+		return err
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	path := rc.path(apiUser, key)
+	tmp, err := os.CreateTemp(rc.dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		return errors.Join(err, os.Remove(tmp.Name()))
+	}
+	return nil
+}
+
+// response returns the entry as a successful response.
+func (e *cacheEntry) response() *resty.Response {
+	resp := &resty.Response{RawResponse: &http.Response{StatusCode: http.StatusOK, Status: "200 OK", Header: http.Header{}}}
+	return resp.SetBody(e.Body)
+}
@@ -4,10 +4,16 @@
 package xenforo
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
-	"math"
+	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/exileum/xenforo-to-gh-discussions/internal/retry"
 	"github.com/go-resty/resty/v2"
 )
 
@@ -16,7 +22,13 @@ type Client struct {
 	apiKey     string
 	apiUser    string
 	maxRetries int
+	pageDelay  time.Duration // Pause between listing pages
+	version    Version       // Detected by TestConnection (0 = unknown)
 	client     *resty.Client
+	cache      *responseCache // Listing responses cached on disk (nil = disabled)
+
+	bandwidth      *BandwidthLimiter // Throttles attachment downloads (nil = unlimited)
+	downloadClient *resty.Client     // Client for throttled downloads
 }
 
 func NewClient(baseURL, apiKey, apiUser string, maxRetries int) *Client {
@@ -34,29 +46,99 @@ func NewClient(baseURL, apiKey, apiUser string, maxRetries int) *Client {
 		apiKey:     apiKey,
 		apiUser:    apiUser,
 		maxRetries: maxRetries,
+		pageDelay:  1 * time.Second,
 		client:     restyClient,
 	}
 }
 
-func (c *Client) retryableRequest(req func() (*resty.Response, error)) (*resty.Response, error) {
-	for i := 0; i < c.maxRetries; i++ {
-		resp, err := req()
-
-		if err != nil {
-			return nil, err
+// retryableRequest runs req, retrying rate-limited (429) responses after the
+// delay the server asks for (see serverDelay), or with exponential backoff.
+// A cancelled ctx stops the retries.
+func (c *Client) retryableRequest(ctx context.Context, req func() (*resty.Response, error)) (*resty.Response, error) {
+	var resp *resty.Response
+	policy := retry.Policy{
+		MaxAttempts: c.maxRetries,
+		Backoff:     retry.Exponential(time.Second),
+		Jitter:      0.1,
+		Classify: func(err error) (bool, time.Duration) {
+			var limited *rateLimitedError
+			if !errors.As(err, &limited) {
+				return false, 0
+			}
+			wait, _ := serverDelay(limited.resp, time.Now())
+			return true, wait
+		},
+	}
+	err := policy.Do(ctx, func(int) error {
+		var err error
+		if resp, err = req(); err != nil {
+			return err
 		}
-
-		if resp.StatusCode() != 429 {
-			return resp, nil
+		if resp.StatusCode() == http.StatusTooManyRequests {
+			return &rateLimitedError{resp: resp}
 		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// rateLimitedError is a rate-limited (429) response.
+type rateLimitedError struct {
+	resp *resty.Response
+}
 
-		if i < c.maxRetries-1 {
-			delay := time.Duration(math.Pow(2, float64(i))) * time.Second
-			time.Sleep(delay)
+func (e *rateLimitedError) Error() string {
+	return fmt.Sprintf("rate limited: %s", e.resp.Status())
+}
+
+// serverDelay returns the wait a rate-limited response asks for, if any: the
+// Retry-After header (seconds or an HTTP date), the X-RateLimit-Reset header
+// (Unix time), or the wait of a XenForo flood control error.
+func serverDelay(resp *resty.Response, now time.Time) (time.Duration, bool) {
+	header := resp.Header()
+	if retryAfter := strings.TrimSpace(header.Get("Retry-After")); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return max(time.Duration(seconds)*time.Second, 0), true
+		}
+		if at, err := http.ParseTime(retryAfter); err == nil {
+			return max(at.Sub(now), 0), true
 		}
 	}
 
-	return nil, fmt.Errorf("max retries (%d) exceeded", c.maxRetries)
+	if reset, err := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		return max(time.Unix(reset, 0).Sub(now), 0), true
+	}
+
+	// XenForo reports flood control as an error whose "count" parameter is the
+	// number of seconds to wait, e.g.
+	// {"errors":[{"code":"must_wait_x_seconds_before_performing_this_action","params":{"count":30}}]}
+	var payload struct {
+		Errors []struct {
+			Code   string          `json:"code"`
+			Params json.RawMessage `json:"params"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(resp.Body(), &payload) != nil {
+		return 0, false
+	}
+	for _, apiErr := range payload.Errors {
+		if !strings.Contains(apiErr.Code, "wait") {
+			continue
+		}
+		var params struct {
+			Count json.Number `json:"count"`
+		}
+		if json.Unmarshal(apiErr.Params, &params) != nil {
+			continue
+		}
+		if seconds, err := params.Count.Int64(); err == nil && seconds >= 0 {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+	return 0, false
 }
 
 // SetTimeout allows customizing the HTTP timeout after client creation
@@ -65,6 +147,33 @@ func (c *Client) SetTimeout(timeout time.Duration) *Client {
 	return c
 }
 
+// SetBandwidthLimiter throttles attachment downloads through limiter, which
+// may be shared with other clients. Throttled downloads are not bound by the
+// overall request timeout, which would cut off large files; waiting for the
+// response headers still is.
+func (c *Client) SetBandwidthLimiter(limiter *BandwidthLimiter) *Client {
+	c.bandwidth = limiter
+	if limiter != nil && c.downloadClient == nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.ResponseHeaderTimeout = 30 * time.Second
+		c.downloadClient = resty.New().
+			SetTransport(transport).
+			SetHeader("User-Agent", "XenForo-to-GH-Discussions/1.0")
+	}
+	return c
+}
+
+// SetPageDelay sets the pause between requests for consecutive listing pages.
+func (c *Client) SetPageDelay(delay time.Duration) *Client {
+	c.pageDelay = delay
+	return c
+}
+
+// request returns an authenticated API request bound to ctx.
+func (c *Client) request(ctx context.Context) *resty.Request {
+	return c.addHeaders(c.client.R().SetContext(ctx))
+}
+
 func (c *Client) addHeaders(req *resty.Request) *resty.Request {
 	return req.
 		SetHeader("XF-Api-Key", c.apiKey).
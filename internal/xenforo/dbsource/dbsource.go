@@ -0,0 +1,399 @@
+// Package dbsource reads XenForo forum content directly from the forum's
+// MySQL database (xf_thread, xf_post, xf_attachment, ...), for self-hosted
+// forums where the REST API is slow or restricted. Source returns the same
+// models as the API client.
+package dbsource
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	_ "github.com/go-sql-driver/mysql" // MySQL driver for Open
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+// threadsPageSize is the number of threads read per listing page.
+const threadsPageSize = 100
+
+// attachmentBatchSize is the most post IDs looked up in one attachment query.
+const attachmentBatchSize = 500
+
+// visibleThreads restricts a query on xf_thread t to the node's migrated
+// threads.
+const visibleThreads = "t.node_id = ? AND t.discussion_state = 'visible' AND t.discussion_type <> 'redirect'"
+
+// Downloader fetches attachment files over HTTP. *xenforo.Client satisfies
+// this interface.
+type Downloader interface {
+	DownloadAttachment(ctx context.Context, url, filepath string) error
+}
+
+// Source reads threads, posts, attachments and members from a XenForo
+// database.
+type Source struct {
+	db         *sql.DB
+	webURL     string     // Public forum URL attachment and avatar URLs are built from
+	dataDir    string     // internal_data directory attachment files are copied from ("" = download)
+	downloader Downloader // Downloads attachments not read from dataDir
+	version    xenforo.Version
+
+	files sync.Map // Attachment URL -> file below dataDir
+}
+
+// Open connects to the XenForo database with a MySQL DSN, e.g.
+// "user:password@tcp(localhost:3306)/xenforo".
+func Open(dsn string) (*Source, error) {
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open XenForo database: %w", err)
+	}
+	return New(db), nil
+}
+
+// New creates a source reading from db.
+func New(db *sql.DB) *Source {
+	return &Source{db: db}
+}
+
+// SetWebURL sets the public forum URL (e.g., "https://forum.example.com")
+// attachment and avatar URLs are built from.
+func (s *Source) SetWebURL(url string) *Source {
+	s.webURL = strings.TrimRight(url, "/")
+	return s
+}
+
+// SetDataDir reads attachment files from XenForo's internal_data directory
+// instead of downloading them.
+func (s *Source) SetDataDir(dir string) *Source {
+	s.dataDir = dir
+	return s
+}
+
+// SetDownloader sets the client attachments are downloaded with when they
+// are not read from the data directory.
+func (s *Source) SetDownloader(downloader Downloader) *Source {
+	s.downloader = downloader
+	return s
+}
+
+// Close closes the database connection.
+func (s *Source) Close() error {
+	return s.db.Close()
+}
+
+// TestConnection checks the database is reachable and records the XenForo
+// version of its schema, see Version.
+func (s *Source) TestConnection(ctx context.Context) error {
+	if err := s.db.PingContext(ctx); err != nil {
+		return fmt.Errorf("connection failed: %w", err)
+	}
+
+	var versionID int
+	err := s.db.QueryRowContext(ctx, "SELECT version_id FROM xf_addon WHERE addon_id = 'XF'").Scan(&versionID)
+	if err != nil {
+		return fmt.Errorf("failed to read XenForo version (is this a XenForo database?): %w", err)
+	}
+	s.version = xenforo.Version(versionID)
+
+	return nil
+}
+
+// Version returns the XenForo version detected by TestConnection, or the
+// zero Version before a successful connection test.
+func (s *Source) Version() xenforo.Version {
+	return s.version
+}
+
+// GetNodes returns the forum nodes in display order.
+func (s *Source) GetNodes(ctx context.Context) ([]xenforo.Node, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT n.node_id, n.title, n.node_type_id, n.description, n.parent_node_id,
+		n.display_order, n.display_in_list, f.discussion_count
+		FROM xf_node n LEFT JOIN xf_forum f ON f.node_id = n.node_id
+		ORDER BY n.lft`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var nodes []xenforo.Node
+	for rows.Next() {
+		var node xenforo.Node
+		var description string
+		var threadCount sql.NullInt64
+		if err := rows.Scan(&node.NodeID, &node.Title, &node.NodeTypeID, &description, &node.ParentNodeID,
+			&node.DisplayOrder, &node.DisplayInList, &threadCount); err != nil {
+			return nil, fmt.Errorf("failed to read node: %w", err)
+		}
+		if description != "" {
+			node.Description = &description
+		}
+		if threadCount.Valid {
+			count := int(threadCount.Int64)
+			node.ThreadCount = &count
+		}
+		nodes = append(nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get nodes: %w", err)
+	}
+
+	return nodes, nil
+}
+
+func (s *Source) GetThreads(ctx context.Context, nodeID int) ([]xenforo.Thread, error) {
+	return s.GetThreadsFrom(ctx, nodeID, 1, nil, nil)
+}
+
+// GetThreadsFrom lists the visible threads of a node in pages of
+// threadsPageSize, by thread ID so that new threads only add pages at the
+// end. Like the API client, it starts at startPage, skips threads already
+// collected and calls onPage after every page.
+func (s *Source) GetThreadsFrom(ctx context.Context, nodeID, startPage int, threads []xenforo.Thread, onPage func(page int, threads []xenforo.Thread) error) ([]xenforo.Thread, error) {
+	seen := make(map[int]bool, len(threads))
+	for _, thread := range threads {
+		seen[thread.ThreadID] = true
+	}
+
+	for page := max(startPage, 1); ; page++ {
+		result, err := s.getThreadsPage(ctx, nodeID, page)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list threads (page %d): %w", page, err)
+		}
+
+		for _, thread := range result {
+			if !seen[thread.ThreadID] {
+				seen[thread.ThreadID] = true
+				threads = append(threads, thread)
+			}
+		}
+
+		if onPage != nil {
+			if err := onPage(page, threads); err != nil {
+				return nil, err
+			}
+		}
+
+		if len(result) < threadsPageSize {
+			return threads, nil
+		}
+	}
+}
+
+func (s *Source) getThreadsPage(ctx context.Context, nodeID, page int) ([]xenforo.Thread, error) {
+	// Question threads and their solutions arrived with thread types in 2.2
+	solution, questionJoin := "0", ""
+	if s.version.AtLeast(2, 2) {
+		solution = "COALESCE(q.solution_post_id, 0)"
+		questionJoin = "LEFT JOIN xf_thread_question q ON q.thread_id = t.thread_id"
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT t.thread_id, t.title, t.node_id, t.username, t.post_date, t.last_post_date, t.first_post_id,
+		t.reply_count, t.view_count, t.prefix_id, COALESCE(p.phrase_text, ''), `+solution+`, t.discussion_open
+		FROM xf_thread t
+		LEFT JOIN xf_phrase p ON p.language_id = 0 AND p.title = CONCAT('thread_prefix.', t.prefix_id)
+		`+questionJoin+`
+		WHERE `+visibleThreads+`
+		ORDER BY t.thread_id
+		LIMIT ? OFFSET ?`, nodeID, threadsPageSize, (page-1)*threadsPageSize)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	var threads []xenforo.Thread
+	for rows.Next() {
+		var thread xenforo.Thread
+		var open bool
+		if err := rows.Scan(&thread.ThreadID, &thread.Title, &thread.NodeID, &thread.Username, &thread.PostDate,
+			&thread.LastPostDate, &thread.FirstPostID, &thread.ReplyCount, &thread.ViewCount, &thread.PrefixID, &thread.Prefix,
+			&thread.TypeData.SolutionPostID, &open); err != nil {
+			return nil, fmt.Errorf("failed to read thread: %w", err)
+		}
+		thread.DiscussionOpen = &open
+		threads = append(threads, thread)
+	}
+	return threads, rows.Err()
+}
+
+// GetPosts returns the visible posts of a thread in thread order, with their
+// attachments.
+func (s *Source) GetPosts(ctx context.Context, thread xenforo.Thread) ([]xenforo.Post, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT post_id, thread_id, user_id, username, post_date, message, reaction_score, reactions
+		FROM xf_post
+		WHERE thread_id = ? AND message_state = 'visible'
+		ORDER BY position, post_id`, thread.ThreadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get posts of thread %d: %w", thread.ThreadID, err)
+	}
+	defer func() { _ = rows.Close() }()
+
+	var posts []xenforo.Post
+	index := make(map[int]int)
+	for rows.Next() {
+		var post xenforo.Post
+		var reactions []byte
+		if err := rows.Scan(&post.PostID, &post.ThreadID, &post.UserID, &post.Username, &post.PostDate,
+			&post.Message, &post.ReactionScore, &reactions); err != nil {
+			return nil, fmt.Errorf("failed to read post: %w", err)
+		}
+		if err := post.Reactions.UnmarshalJSON(reactions); err != nil {
+			return nil, fmt.Errorf("failed to read reactions of post %d: %w", post.PostID, err)
+		}
+		index[post.PostID] = len(posts)
+		posts = append(posts, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get posts of thread %d: %w", thread.ThreadID, err)
+	}
+
+	for start := 0; start < len(posts); start += attachmentBatchSize {
+		batch := posts[start:min(start+attachmentBatchSize, len(posts))]
+		if err := s.addAttachments(ctx, posts, batch, index); err != nil {
+			return nil, fmt.Errorf("failed to get attachments of thread %d: %w", thread.ThreadID, err)
+		}
+	}
+
+	return posts, nil
+}
+
+// addAttachments adds the attachments of batch to posts.
+func (s *Source) addAttachments(ctx context.Context, posts, batch []xenforo.Post, index map[int]int) error {
+	placeholders := make([]string, len(batch))
+	args := make([]interface{}, len(batch))
+	for i, post := range batch {
+		placeholders[i] = "?"
+		args[i] = post.PostID
+	}
+
+	rows, err := s.db.QueryContext(ctx, `SELECT a.attachment_id, a.content_id, d.data_id, d.filename, d.file_size, d.file_hash
+		FROM xf_attachment a JOIN xf_attachment_data d ON d.data_id = a.data_id
+		WHERE a.content_type = 'post' AND a.content_id IN (`+strings.Join(placeholders, ", ")+`)
+		ORDER BY a.attachment_id`, args...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var attachment xenforo.Attachment
+		var postID, dataID int
+		var fileHash string
+		if err := rows.Scan(&attachment.AttachmentID, &postID, &dataID, &attachment.Filename, &attachment.FileSize, &fileHash); err != nil {
+			return fmt.Errorf("failed to read attachment: %w", err)
+		}
+		attachment.DirectURL = fmt.Sprintf("%s/attachments/%d/", s.webURL, attachment.AttachmentID)
+		if s.dataDir != "" {
+			s.files.Store(attachment.DirectURL, s.attachmentFile(dataID, fileHash))
+		}
+
+		if i, ok := index[postID]; ok {
+			posts[i].Attachments = append(posts[i].Attachments, attachment)
+		}
+	}
+	return rows.Err()
+}
+
+// attachmentFile returns where XenForo stores the file of an attachment
+// below internal_data: attachments/<data ID / 1000>/<data ID>-<hash>.data.
+func (s *Source) attachmentFile(dataID int, fileHash string) string {
+	return filepath.Join(s.dataDir, "attachments", strconv.Itoa(dataID/1000), fmt.Sprintf("%d-%s.data", dataID, fileHash))
+}
+
+// DownloadAttachment copies an attachment from the data directory when one
+// is set, or downloads it otherwise.
+func (s *Source) DownloadAttachment(ctx context.Context, url, filePath string) error {
+	if source, ok := s.files.Load(url); ok {
+		return copyFile(source.(string), filePath)
+	}
+	if s.downloader == nil {
+		return fmt.Errorf("cannot download %s: no XenForo data directory or downloader configured", url)
+	}
+	return s.downloader.DownloadAttachment(ctx, url, filePath)
+}
+
+func copyFile(source, target string) error {
+	in, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to open attachment file: %w", err)
+	}
+	defer func() { _ = in.Close() }()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+	out, err := os.Create(target)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return fmt.Errorf("failed to copy attachment file: %w", err)
+	}
+	return out.Close()
+}
+
+// GetUser returns the public profile of a forum member. Custom avatars are
+// served from data/avatars/<size>/<user ID / 1000>/<user ID>.jpg.
+func (s *Source) GetUser(ctx context.Context, userID int) (*xenforo.User, error) {
+	user := &xenforo.User{}
+	var avatarDate int64
+	err := s.db.QueryRowContext(ctx, "SELECT user_id, username, avatar_date FROM xf_user WHERE user_id = ?", userID).
+		Scan(&user.UserID, &user.Username, &avatarDate)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user %d: %w", userID, err)
+	}
+
+	if avatarDate > 0 && s.webURL != "" {
+		user.AvatarURLs = make(map[string]string)
+		for _, size := range []string{"o", "h", "l", "m", "s"} {
+			user.AvatarURLs[size] = fmt.Sprintf("%s/data/avatars/%s/%d/%d.jpg?%d", s.webURL, size, userID/1000, userID, avatarDate)
+		}
+	}
+
+	return user, nil
+}
+
+// GetNodeStats counts the content of a node. The database answers exactly,
+// so no threads are sampled whatever sampleThreads asks for.
+func (s *Source) GetNodeStats(ctx context.Context, nodeID, sampleThreads int) (*xenforo.NodeStats, error) {
+	stats := &xenforo.NodeStats{}
+
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*), COUNT(DISTINCT p.user_id), COUNT(DISTINCT t.thread_id)
+		FROM xf_post p JOIN xf_thread t ON t.thread_id = p.thread_id
+		WHERE `+visibleThreads+` AND p.message_state = 'visible'`, nodeID).
+		Scan(&stats.Posts, &stats.Users, &stats.Threads)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count posts: %w", err)
+	}
+
+	err = s.db.QueryRowContext(ctx, `SELECT COUNT(*), COALESCE(SUM(d.file_size), 0)
+		FROM xf_attachment a
+		JOIN xf_attachment_data d ON d.data_id = a.data_id
+		JOIN xf_post p ON p.post_id = a.content_id
+		JOIN xf_thread t ON t.thread_id = p.thread_id
+		WHERE a.content_type = 'post' AND `+visibleThreads+` AND p.message_state = 'visible'`, nodeID).
+		Scan(&stats.Attachments, &stats.AttachmentBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count attachments: %w", err)
+	}
+
+	stats.SampledThreads = stats.Threads
+	return stats, nil
+}
+
+// GetDryRunStats counts the content of a node exactly, see GetNodeStats.
+func (s *Source) GetDryRunStats(ctx context.Context, nodeID int) (threadCount, postCount, attachmentCount, userCount int, err error) {
+	stats, err := s.GetNodeStats(ctx, nodeID, 0)
+	if err != nil {
+		return 0, 0, 0, 0, err
+	}
+	return stats.Threads, stats.Posts, stats.Attachments, stats.Users, nil
+}
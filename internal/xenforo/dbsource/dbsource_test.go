@@ -0,0 +1,151 @@
+package dbsource
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+
+	"github.com/exileum/xenforo-to-gh-discussions/internal/xenforo"
+)
+
+func newMockSource(t *testing.T) (*Source, sqlmock.Sqlmock) {
+	t.Helper()
+
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("Failed to create mock database: %v", err)
+	}
+	t.Cleanup(func() {
+		if err := mock.ExpectationsWereMet(); err != nil {
+			t.Error(err)
+		}
+		_ = db.Close()
+	})
+
+	return New(db).SetWebURL("https://forum.example.com/"), mock
+}
+
+var threadColumns = []string{"thread_id", "title", "node_id", "username", "post_date", "last_post_date", "first_post_id",
+	"reply_count", "view_count", "prefix_id", "prefix", "solution_post_id", "discussion_open"}
+
+func TestSource_TestConnection(t *testing.T) {
+	source, mock := newMockSource(t)
+	mock.ExpectPing()
+	mock.ExpectQuery("SELECT version_id FROM xf_addon").
+		WillReturnRows(sqlmock.NewRows([]string{"version_id"}).AddRow(2030470))
+
+	if err := source.TestConnection(context.Background()); err != nil {
+		t.Fatalf("TestConnection returned error: %v", err)
+	}
+	if source.Version().String() != "2.3.4" {
+		t.Errorf("Expected version 2.3.4, got %s", source.Version())
+	}
+}
+
+func TestSource_GetThreadsFrom(t *testing.T) {
+	source, mock := newMockSource(t)
+
+	firstPage := sqlmock.NewRows(threadColumns)
+	for id := 1; id <= threadsPageSize; id++ {
+		firstPage.AddRow(id, "Thread", 2, "alice", 1700000000, 1700000000, id*10, 1, 5, 0, "", 0, true)
+	}
+	mock.ExpectQuery("FROM xf_thread t").WithArgs(2, threadsPageSize, 0).WillReturnRows(firstPage)
+	mock.ExpectQuery("LEFT JOIN xf_thread_question").WithArgs(2, threadsPageSize, threadsPageSize).
+		WillReturnRows(sqlmock.NewRows(threadColumns).AddRow(101, "Question", 2, "bob", 1700000100, 1700000500, 1010, 3, 9, 4, "Solved", 1012, false))
+
+	var pages []int
+	threads, err := source.GetThreadsFrom(context.Background(), 2, 1, nil, func(page int, threads []xenforo.Thread) error {
+		pages = append(pages, page)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GetThreadsFrom returned error: %v", err)
+	}
+
+	if len(threads) != threadsPageSize+1 || len(pages) != 2 {
+		t.Fatalf("Expected %d threads on 2 pages, got %d on %v", threadsPageSize+1, len(threads), pages)
+	}
+	question := threads[threadsPageSize]
+	if question.Prefix != "Solved" || question.TypeData.SolutionPostID != 1012 || !question.IsLocked() || question.LastPostDate != 1700000500 {
+		t.Errorf("Unexpected question thread: %+v", question)
+	}
+}
+
+func TestSource_GetPosts(t *testing.T) {
+	dataDir := t.TempDir()
+	stored := filepath.Join(dataDir, "attachments", "1", "1234-abcdef.data")
+	if err := os.MkdirAll(filepath.Dir(stored), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(stored, []byte("image"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	source, mock := newMockSource(t)
+	source.SetDataDir(dataDir)
+
+	mock.ExpectQuery("FROM xf_post").WithArgs(7).WillReturnRows(
+		sqlmock.NewRows([]string{"post_id", "thread_id", "user_id", "username", "post_date", "message", "reaction_score", "reactions"}).
+			AddRow(70, 7, 1, "alice", 1700000000, "Hello", 2, []byte(`{"1":2}`)).
+			AddRow(71, 7, 2, "bob", 1700000100, "[ATTACH]55[/ATTACH]", 0, []byte(`[]`)))
+	mock.ExpectQuery("FROM xf_attachment a").WithArgs(70, 71).WillReturnRows(
+		sqlmock.NewRows([]string{"attachment_id", "content_id", "data_id", "filename", "file_size", "file_hash"}).
+			AddRow(55, 71, 1234, "photo.png", 5, "abcdef"))
+
+	posts, err := source.GetPosts(context.Background(), xenforo.Thread{ThreadID: 7})
+	if err != nil {
+		t.Fatalf("GetPosts returned error: %v", err)
+	}
+
+	if len(posts) != 2 || posts[0].Reactions[1] != 2 || posts[1].Reactions != nil {
+		t.Fatalf("Unexpected posts: %+v", posts)
+	}
+	if len(posts[1].Attachments) != 1 {
+		t.Fatalf("Expected the attachment on the second post, got %+v", posts[1].Attachments)
+	}
+	attachment := posts[1].Attachments[0]
+	if attachment.DirectURL != "https://forum.example.com/attachments/55/" || !attachment.IsValid() {
+		t.Errorf("Unexpected attachment: %+v", attachment)
+	}
+
+	target := filepath.Join(t.TempDir(), "photo.png")
+	if err := source.DownloadAttachment(context.Background(), attachment.DirectURL, target); err != nil {
+		t.Fatalf("DownloadAttachment returned error: %v", err)
+	}
+	if data, err := os.ReadFile(target); err != nil || string(data) != "image" {
+		t.Errorf("Expected the file from the data directory, got %q (err: %v)", data, err)
+	}
+}
+
+func TestSource_GetUser(t *testing.T) {
+	source, mock := newMockSource(t)
+	mock.ExpectQuery("FROM xf_user").WithArgs(1234).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "username", "avatar_date"}).AddRow(1234, "alice", 1700000000))
+
+	user, err := source.GetUser(context.Background(), 1234)
+	if err != nil {
+		t.Fatalf("GetUser returned error: %v", err)
+	}
+	if expected := "https://forum.example.com/data/avatars/s/1/1234.jpg?1700000000"; user.AvatarURL() != expected {
+		t.Errorf("Expected avatar %q, got %q", expected, user.AvatarURL())
+	}
+}
+
+func TestSource_GetNodeStats(t *testing.T) {
+	source, mock := newMockSource(t)
+	mock.ExpectQuery("COUNT\\(DISTINCT p.user_id\\)").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"posts", "users", "threads"}).AddRow(40, 12, 8))
+	mock.ExpectQuery("SUM\\(d.file_size\\)").WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"attachments", "bytes"}).AddRow(5, 1<<20))
+
+	stats, err := source.GetNodeStats(context.Background(), 2, 3)
+	if err != nil {
+		t.Fatalf("GetNodeStats returned error: %v", err)
+	}
+	if stats.Threads != 8 || stats.Posts != 40 || stats.Users != 12 || stats.Attachments != 5 || stats.AttachmentBytes != 1<<20 || stats.Extrapolated {
+		t.Errorf("Unexpected stats: %+v", stats)
+	}
+}
@@ -1,19 +1,52 @@
 package xenforo
 
 import (
+	"encoding/json"
+	"fmt"
 	"strings"
 )
 
 // Thread represents a XenForo forum thread with metadata.
 // Contains thread identification, authoring information, and reply statistics.
 type Thread struct {
-	ThreadID    int    `json:"thread_id"`     // Unique thread identifier
-	Title       string `json:"title"`         // Thread title
-	NodeID      int    `json:"node_id"`       // Parent forum/category ID
-	Username    string `json:"username"`      // Thread author username
-	PostDate    int64  `json:"post_date"`     // Creation timestamp (Unix)
-	FirstPostID int    `json:"first_post_id"` // ID of the opening post
-	ReplyCount  int    `json:"reply_count"`   // Number of replies
+	ThreadID     int    `json:"thread_id"`      // Unique thread identifier
+	Title        string `json:"title"`          // Thread title
+	NodeID       int    `json:"node_id"`        // Parent forum/category ID
+	Username     string `json:"username"`       // Thread author username
+	PostDate     int64  `json:"post_date"`      // Creation timestamp (Unix)
+	LastPostDate int64  `json:"last_post_date"` // Timestamp of the latest post (Unix, 0 when not provided)
+	FirstPostID  int    `json:"first_post_id"`  // ID of the opening post
+	ReplyCount   int    `json:"reply_count"`    // Number of replies
+	ViewCount    int    `json:"view_count"`     // Number of views
+	PrefixID     int    `json:"prefix_id"`      // Thread prefix ID (0 = none)
+	Prefix       string `json:"prefix"`         // Thread prefix title, when provided by the API
+
+	TypeData ThreadTypeData `json:"type_data"` // Data of the thread type, e.g. the solution of a question
+
+	DiscussionOpen *bool `json:"discussion_open,omitempty"` // False for threads closed to new replies
+}
+
+// IsLocked reports whether the thread is closed to new replies. Threads
+// without the field are open.
+func (t *Thread) IsLocked() bool {
+	return t.DiscussionOpen != nil && !*t.DiscussionOpen
+}
+
+// ThreadTypeData holds the type-specific data of a thread. Question threads
+// record the post marked as their solution.
+type ThreadTypeData struct {
+	SolutionPostID int `json:"solution_post_id"` // Post marked as the solution (0 = none)
+}
+
+// UnmarshalJSON accepts the empty array XenForo returns for threads without
+// type data.
+func (d *ThreadTypeData) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '{' {
+		*d = ThreadTypeData{}
+		return nil
+	}
+	type plain ThreadTypeData
+	return json.Unmarshal(data, (*plain)(d))
 }
 
 // IsValid validates the Thread struct and returns true if all required fields are valid.
@@ -27,12 +60,35 @@ func (t *Thread) IsValid() bool {
 // Post represents an individual forum post within a thread.
 // Includes content, authoring information, and optional file attachments.
 type Post struct {
-	PostID      int          `json:"post_id"`               // Unique post identifier
-	ThreadID    int          `json:"thread_id"`             // Parent thread ID
-	Username    string       `json:"username"`              // Post author username
-	PostDate    int64        `json:"post_date"`             // Creation timestamp (Unix)
-	Message     string       `json:"message"`               // Post content (BB-code formatted)
-	Attachments []Attachment `json:"Attachments,omitempty"` // File attachments
+	PostID        int          `json:"post_id"`               // Unique post identifier
+	ThreadID      int          `json:"thread_id"`             // Parent thread ID
+	UserID        int          `json:"user_id"`               // Post author user ID (0 for guests)
+	Username      string       `json:"username"`              // Post author username
+	PostDate      int64        `json:"post_date"`             // Creation timestamp (Unix)
+	Message       string       `json:"message"`               // Post content (BB-code formatted)
+	ReactionScore int          `json:"reaction_score"`        // Net reaction score
+	Attachments   []Attachment `json:"Attachments,omitempty"` // File attachments
+
+	Reactions ReactionCounts `json:"reactions,omitempty"` // Reaction ID -> number of users who reacted; only the database source reads them
+}
+
+// ReactionCounts maps XenForo reaction IDs (1 = Like, 2 = Love, ...) to the
+// number of users who reacted with them.
+type ReactionCounts map[int]int
+
+// UnmarshalJSON accepts the empty array XenForo returns for posts without
+// reactions.
+func (r *ReactionCounts) UnmarshalJSON(data []byte) error {
+	if len(data) == 0 || data[0] != '{' {
+		*r = nil
+		return nil
+	}
+	counts := make(map[int]int)
+	if err := json.Unmarshal(data, &counts); err != nil {
+		return err
+	}
+	*r = counts
+	return nil
 }
 
 // IsValid validates the Post struct and returns true if all required fields are valid.
@@ -50,6 +106,7 @@ type Attachment struct {
 	AttachmentID int    `json:"attachment_id"` // Unique attachment identifier
 	Filename     string `json:"filename"`      // Original filename
 	DirectURL    string `json:"direct_url"`    // Download URL
+	FileSize     int64  `json:"file_size"`     // Size in bytes (0 when not reported)
 }
 
 // IsValid validates the Attachment struct and returns true if all required fields are valid.
@@ -65,19 +122,29 @@ func (a *Attachment) IsValid() bool {
 }
 
 type ThreadsResponse struct {
-	Threads    []Thread `json:"threads"`
-	Pagination struct {
-		CurrentPage int `json:"current_page"`
-		TotalPages  int `json:"total_pages"`
-	} `json:"pagination"`
+	Threads    []Thread   `json:"threads"`
+	Pagination Pagination `json:"pagination"`
 }
 
 type PostsResponse struct {
-	Posts      []Post `json:"posts"`
-	Pagination struct {
-		CurrentPage int `json:"current_page"`
-		TotalPages  int `json:"total_pages"`
-	} `json:"pagination"`
+	Posts      []Post     `json:"posts"`
+	Pagination Pagination `json:"pagination"`
+}
+
+// Pagination describes the page of a listing. XenForo reports the number of
+// pages as last_page; total_pages is accepted as well.
+type Pagination struct {
+	CurrentPage int `json:"current_page"`
+	LastPage    int `json:"last_page"`
+	TotalPages  int `json:"total_pages"`
+}
+
+// Pages returns the number of pages of the listing, or 0 when not reported.
+func (p Pagination) Pages() int {
+	if p.LastPage > 0 {
+		return p.LastPage
+	}
+	return p.TotalPages
 }
 
 // Node represents a XenForo forum node (category or forum).
@@ -103,3 +170,61 @@ func (n *Node) IsValid() bool {
 type NodesResponse struct {
 	Nodes []Node `json:"nodes"`
 }
+
+// User represents a forum member's public profile.
+type User struct {
+	UserID     int               `json:"user_id"`     // Unique user identifier
+	Username   string            `json:"username"`    // Display name
+	AvatarURLs map[string]string `json:"avatar_urls"` // Avatar URLs keyed by size ("o", "h", "l", "m", "s")
+}
+
+// AvatarURL returns the smallest available avatar URL, or an empty string
+// when the user has no custom avatar.
+func (u *User) AvatarURL() string {
+	for _, size := range []string{"s", "m", "l", "h", "o"} {
+		if url := u.AvatarURLs[size]; url != "" {
+			return url
+		}
+	}
+	return ""
+}
+
+type UserResponse struct {
+	User User `json:"user"`
+}
+
+// IndexResponse is the response of the API index ("/"), which identifies the
+// XenForo installation.
+type IndexResponse struct {
+	VersionID int    `json:"version_id"` // XenForo version, e.g. 2030470 for 2.3.4
+	SiteTitle string `json:"site_title"`
+}
+
+// Version is a XenForo version ID as reported by the API index. The ID
+// encodes major, minor and patch version followed by the release state, e.g.
+// 2021370 for 2.2.13 and 2030470 for 2.3.4. The zero Version is unknown.
+type Version int
+
+func (v Version) Major() int { return int(v) / 1000000 }
+func (v Version) Minor() int { return int(v) / 10000 % 100 }
+func (v Version) Patch() int { return int(v) / 100 % 100 }
+
+// AtLeast reports whether the version is major.minor or later. Unknown
+// versions are assumed to be current.
+func (v Version) AtLeast(major, minor int) bool {
+	if v == 0 {
+		return true
+	}
+	return v.Major() > major || (v.Major() == major && v.Minor() >= minor)
+}
+
+func (v Version) String() string {
+	if v == 0 {
+		return "unknown"
+	}
+	return fmt.Sprintf("%d.%d.%d", v.Major(), v.Minor(), v.Patch())
+}
+
+type ThreadResponse struct {
+	Thread Thread `json:"thread"`
+}
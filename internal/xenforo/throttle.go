@@ -0,0 +1,75 @@
+package xenforo
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// maxThrottledRead bounds the bytes read at once from a throttled reader so
+// waits stay short and concurrent downloads interleave smoothly.
+const maxThrottledRead = 32 << 10
+
+// BandwidthLimiter caps the combined throughput of every reader it wraps.
+// Concurrent downloads share the budget.
+type BandwidthLimiter struct {
+	mu          sync.Mutex
+	bytesPerSec int64
+	next        time.Time // When the bytes reserved so far have been paid for
+}
+
+// NewBandwidthLimiter creates a limiter allowing bytesPerSec bytes per second
+// across all wrapped readers. Returns nil (unlimited) when bytesPerSec is not
+// positive.
+func NewBandwidthLimiter(bytesPerSec int64) *BandwidthLimiter {
+	if bytesPerSec <= 0 {
+		return nil
+	}
+	return &BandwidthLimiter{bytesPerSec: bytesPerSec}
+}
+
+// Reader wraps r so reads are throttled by the limiter. A nil limiter returns
+// r unchanged.
+func (l *BandwidthLimiter) Reader(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &throttledReader{reader: r, limiter: l}
+}
+
+// wait blocks until n more bytes fit in the budget. Time spent idle is not
+// saved up, so throughput never bursts above the limit.
+func (l *BandwidthLimiter) wait(n int) {
+	l.mu.Lock()
+	now := time.Now()
+	if l.next.Before(now) {
+		l.next = now
+	}
+	l.next = l.next.Add(time.Duration(int64(n) * int64(time.Second) / l.bytesPerSec))
+	until := l.next
+	l.mu.Unlock()
+
+	time.Sleep(time.Until(until))
+}
+
+// chunkSize returns how many bytes to read at once: at most a tenth of a
+// second's budget.
+func (l *BandwidthLimiter) chunkSize() int {
+	return int(max(1, min(l.bytesPerSec/10, maxThrottledRead)))
+}
+
+type throttledReader struct {
+	reader  io.Reader
+	limiter *BandwidthLimiter
+}
+
+func (r *throttledReader) Read(p []byte) (int, error) {
+	if len(p) > r.limiter.chunkSize() {
+		p = p[:r.limiter.chunkSize()]
+	}
+	n, err := r.reader.Read(p)
+	if n > 0 {
+		r.limiter.wait(n)
+	}
+	return n, err
+}
@@ -1,8 +1,22 @@
 package xenforo
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"github.com/go-resty/resty/v2"
 )
 
 func TestNewXenForoClient(t *testing.T) {
@@ -180,6 +194,70 @@ func TestThread_Validation(t *testing.T) {
 	}
 }
 
+func TestThread_TypeData(t *testing.T) {
+	tests := []struct {
+		name     string
+		json     string
+		solution int
+	}{
+		{"Question with solution", `{"thread_id":1,"type_data":{"solution_post_id":42,"allow_answer_voting":true}}`, 42},
+		{"Empty type data array", `{"thread_id":1,"type_data":[]}`, 0},
+		{"Missing type data", `{"thread_id":1}`, 0},
+		{"Null type data", `{"thread_id":1,"type_data":null}`, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var thread Thread
+			if err := json.Unmarshal([]byte(tt.json), &thread); err != nil {
+				t.Fatalf("Unmarshal failed: %v", err)
+			}
+			if thread.TypeData.SolutionPostID != tt.solution {
+				t.Errorf("Expected solution post %d, got %d", tt.solution, thread.TypeData.SolutionPostID)
+			}
+		})
+	}
+}
+
+func TestThread_IsLocked(t *testing.T) {
+	tests := []struct {
+		json   string
+		locked bool
+	}{
+		{`{"thread_id":1,"discussion_open":false}`, true},
+		{`{"thread_id":1,"discussion_open":true}`, false},
+		{`{"thread_id":1}`, false},
+	}
+
+	for _, tt := range tests {
+		var thread Thread
+		if err := json.Unmarshal([]byte(tt.json), &thread); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if thread.IsLocked() != tt.locked {
+			t.Errorf("%s: expected locked %v, got %v", tt.json, tt.locked, thread.IsLocked())
+		}
+	}
+}
+
+func TestPost_Reactions(t *testing.T) {
+	var post Post
+	if err := json.Unmarshal([]byte(`{"post_id":1,"reactions":{"1":12,"2":3}}`), &post); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if post.Reactions[1] != 12 || post.Reactions[2] != 3 {
+		t.Errorf("Expected 12 likes and 3 loves, got %v", post.Reactions)
+	}
+
+	post = Post{}
+	if err := json.Unmarshal([]byte(`{"post_id":1,"reactions":[]}`), &post); err != nil {
+		t.Fatalf("Unmarshal of empty reactions failed: %v", err)
+	}
+	if len(post.Reactions) != 0 {
+		t.Errorf("Expected no reactions, got %v", post.Reactions)
+	}
+}
+
 func TestPost_Validation(t *testing.T) {
 	tests := []struct {
 		name  string
@@ -447,10 +525,7 @@ func TestThreadsResponse_Validation(t *testing.T) {
 			{ThreadID: 1, Title: "Thread 1", NodeID: 1, Username: "user1", PostDate: time.Now().Unix()},
 			{ThreadID: 2, Title: "Thread 2", NodeID: 1, Username: "user2", PostDate: time.Now().Unix()},
 		},
-		Pagination: struct {
-			CurrentPage int `json:"current_page"`
-			TotalPages  int `json:"total_pages"`
-		}{
+		Pagination: Pagination{
 			CurrentPage: 1,
 			TotalPages:  5,
 		},
@@ -475,10 +550,7 @@ func TestPostsResponse_Validation(t *testing.T) {
 			{PostID: 1, ThreadID: 1, Username: "user1", PostDate: time.Now().Unix(), Message: "First post"},
 			{PostID: 2, ThreadID: 1, Username: "user2", PostDate: time.Now().Unix(), Message: "Reply post"},
 		},
-		Pagination: struct {
-			CurrentPage int `json:"current_page"`
-			TotalPages  int `json:"total_pages"`
-		}{
+		Pagination: Pagination{
 			CurrentPage: 1,
 			TotalPages:  3,
 		},
@@ -596,3 +668,321 @@ func stringPtr(s string) *string {
 func intPtr(i int) *int {
 	return &i
 }
+
+func TestThrottledDownload(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 8000)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(content)
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	limiter := NewBandwidthLimiter(16000)
+	client := NewClient(server.URL, "key", "1", 1).SetBandwidthLimiter(limiter)
+
+	// 8000 bytes at 16000 bytes per second take at least half a second
+	start := time.Now()
+	target := filepath.Join(dir, "nested", "file.bin")
+	if err := client.DownloadAttachment(context.Background(), server.URL+"/file.bin", target); err != nil {
+		t.Fatalf("DownloadAttachment returned error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("Expected the capped download to take at least 500ms, took %v", elapsed)
+	}
+	if data, err := os.ReadFile(target); err != nil || !bytes.Equal(data, content) {
+		t.Errorf("Downloaded file does not match (err: %v)", err)
+	}
+
+	// Concurrent downloads share the limit: 2 x 4000 bytes also take half a second
+	other := NewClient(server.URL, "key", "1", 1).SetBandwidthLimiter(limiter)
+	content = content[:4000]
+	start = time.Now()
+	var wg sync.WaitGroup
+	for i, c := range []*Client{client, other} {
+		wg.Add(1)
+		go func(i int, c *Client) {
+			defer wg.Done()
+			if err := c.DownloadAttachment(context.Background(), server.URL+"/file.bin", filepath.Join(dir, "concurrent", fmt.Sprintf("file%d.bin", i))); err != nil {
+				t.Errorf("DownloadAttachment returned error: %v", err)
+			}
+		}(i, c)
+	}
+	wg.Wait()
+	if elapsed := time.Since(start); elapsed < 450*time.Millisecond {
+		t.Errorf("Expected concurrent capped downloads to take at least 500ms, took %v", elapsed)
+	}
+}
+
+func TestDownloadAttachmentResume(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 800)
+	var interrupt atomic.Bool
+	var mu sync.Mutex
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		ranges = append(ranges, r.Header.Get("Range"))
+		mu.Unlock()
+		if interrupt.Swap(false) {
+			// Announce the whole file but drop the connection halfway
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)))
+			_, _ = w.Write(content[:len(content)/2])
+			return
+		}
+		http.ServeContent(w, r, "file.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer server.Close()
+
+	target := filepath.Join(t.TempDir(), "file.bin")
+	client := NewClient(server.URL, "key", "1", 1)
+
+	interrupt.Store(true)
+	if err := client.DownloadAttachment(context.Background(), server.URL+"/file.bin", target); err == nil {
+		t.Fatal("Expected the interrupted download to fail")
+	}
+	if _, err := os.Stat(target); !os.IsNotExist(err) {
+		t.Errorf("Expected no file for the interrupted download, got %v", err)
+	}
+	if info, err := os.Stat(target + partialSuffix); err != nil || info.Size() != int64(len(content)/2) {
+		t.Fatalf("Expected the partial download to be kept, got %v", err)
+	}
+
+	if err := client.DownloadAttachment(context.Background(), server.URL+"/file.bin", target); err != nil {
+		t.Fatalf("DownloadAttachment returned error: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if expected := fmt.Sprintf("bytes=%d-", len(content)/2); ranges[1] != expected {
+		t.Errorf("Expected the download to resume with range %q, got %q", expected, ranges[1])
+	}
+	if data, err := os.ReadFile(target); err != nil || !bytes.Equal(data, content) {
+		t.Errorf("Resumed file does not match (err: %v)", err)
+	}
+	if _, err := os.Stat(target + partialSuffix); !os.IsNotExist(err) {
+		t.Errorf("Expected the partial file to be removed, got %v", err)
+	}
+}
+
+func TestCheckDownloadLength(t *testing.T) {
+	tests := []struct {
+		name          string
+		written       int64
+		contentLength int64
+		incomplete    bool
+	}{
+		{name: "Complete download", written: 100, contentLength: 100},
+		{name: "Unknown length", written: 100, contentLength: -1},
+		{name: "Truncated download", written: 60, contentLength: 100, incomplete: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkDownloadLength(tt.written, tt.contentLength)
+			if errors.Is(err, ErrIncompleteDownload) != tt.incomplete {
+				t.Errorf("Expected incomplete=%v, got %v", tt.incomplete, err)
+			}
+		})
+	}
+}
+
+func TestNewBandwidthLimiterUnlimited(t *testing.T) {
+	if NewBandwidthLimiter(0) != nil {
+		t.Error("Expected no limiter for a zero limit")
+	}
+
+	reader := bytes.NewReader([]byte("data"))
+	if NewBandwidthLimiter(0).Reader(reader) != reader {
+		t.Error("Expected a nil limiter to return the reader unchanged")
+	}
+}
+
+func TestVersion(t *testing.T) {
+	tests := []struct {
+		id       Version
+		expected string
+		is22     bool
+	}{
+		{id: 0, expected: "unknown", is22: true},
+		{id: 2010870, expected: "2.1.8", is22: false},
+		{id: 2021370, expected: "2.2.13", is22: true},
+		{id: 2030470, expected: "2.3.4", is22: true},
+	}
+
+	for _, tt := range tests {
+		if tt.id.String() != tt.expected {
+			t.Errorf("Expected %d to be %s, got %s", tt.id, tt.expected, tt.id)
+		}
+		if tt.id.AtLeast(2, 2) != tt.is22 {
+			t.Errorf("Expected %s AtLeast(2, 2) = %v", tt.id, tt.is22)
+		}
+	}
+}
+
+func TestClient_TestConnectionVersion(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = fmt.Fprint(w, `{"version_id":2030470,"site_title":"Forum"}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "key", "1", 1)
+	if client.Version() != 0 {
+		t.Errorf("Expected an unknown version before connecting, got %s", client.Version())
+	}
+	if err := client.TestConnection(context.Background()); err != nil {
+		t.Fatalf("TestConnection returned error: %v", err)
+	}
+	if client.Version().String() != "2.3.4" {
+		t.Errorf("Expected version 2.3.4, got %s", client.Version())
+	}
+}
+
+func TestClient_GetPostsReportedPages(t *testing.T) {
+	// The reply count is stale: the thread has a third page it does not account for
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		page := r.URL.Query().Get("page")
+		_, _ = fmt.Fprintf(w, `{"posts":[{"post_id":%s,"thread_id":1}],"pagination":{"current_page":%s,"last_page":3}}`, page, page)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL, "key", "1", 1)
+	posts, err := client.GetPosts(context.Background(), Thread{ThreadID: 1, ReplyCount: 1})
+	if err != nil {
+		t.Fatalf("GetPosts returned error: %v", err)
+	}
+	if len(posts) != 3 {
+		t.Errorf("Expected the 3 reported pages to be fetched, got %d posts", len(posts))
+	}
+}
+
+func TestClient_CancelledRetries(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	client := NewClient(server.URL, "key", "1", 5)
+	start := time.Now()
+	if _, err := client.GetUser(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected the deadline to stop the retries, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Expected the backoff to be cut short, took %v", elapsed)
+	}
+	if got := atomic.LoadInt32(&requests); got != 1 {
+		t.Errorf("Expected 1 request before the deadline, got %d", got)
+	}
+}
+
+func TestServerDelay(t *testing.T) {
+	now := time.Date(2024, 1, 2, 15, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name     string
+		header   http.Header
+		body     string
+		expected time.Duration
+		ok       bool
+	}{
+		{"Retry-After seconds", http.Header{"Retry-After": {"7"}}, "", 7 * time.Second, true},
+		{"Retry-After date", http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}, "", 90 * time.Second, true},
+		{"Retry-After in the past", http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}}, "", 0, true},
+		{"Rate limit reset", http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Add(20*time.Second).Unix(), 10)}}, "", 20 * time.Second, true},
+		{"Flood control error", nil, `{"errors":[{"code":"must_wait_x_seconds_before_performing_this_action","message":"Wait","params":{"count":12}}]}`, 12 * time.Second, true},
+		{"Other error", nil, `{"errors":[{"code":"no_permission","message":"Denied","params":[]}]}`, 0, false},
+		{"No hint", nil, "", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &resty.Response{RawResponse: &http.Response{Header: tt.header}}
+			resp.SetBody([]byte(tt.body))
+			if got, ok := serverDelay(resp, now); got != tt.expected || ok != tt.ok {
+				t.Errorf("Expected a delay of %v (%v), got %v (%v)", tt.expected, tt.ok, got, ok)
+			}
+		})
+	}
+}
+
+func TestClient_RetryAfter(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&requests, 1) == 1 {
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = fmt.Fprint(w, `{"user":{"user_id":1,"username":"alice"}}`)
+	}))
+	defer server.Close()
+
+	start := time.Now()
+	user, err := NewClient(server.URL, "key", "1", 3).GetUser(context.Background(), 1)
+	if err != nil {
+		t.Fatalf("GetUser returned error: %v", err)
+	}
+	if user.Username != "alice" || atomic.LoadInt32(&requests) != 2 {
+		t.Errorf("Expected the user after one retry, got %+v after %d requests", user, requests)
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Expected the retry to wait the requested second, took %v", elapsed)
+	}
+}
+
+func TestClient_ListingCache(t *testing.T) {
+	var requests, revalidated int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			atomic.AddInt32(&revalidated, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		page := r.URL.Query().Get("page")
+		_, _ = fmt.Fprintf(w, `{"threads":[{"thread_id":%s,"node_id":1,"title":"Thread","username":"alice"}],"pagination":{"current_page":%s,"last_page":2}}`, page, page)
+	}))
+	defer server.Close()
+
+	list := func(client *Client) []Thread {
+		t.Helper()
+		threads, err := client.GetThreads(context.Background(), 1)
+		if err != nil {
+			t.Fatalf("GetThreads returned error: %v", err)
+		}
+		if len(threads) != 2 {
+			t.Fatalf("Expected 2 threads, got %d", len(threads))
+		}
+		return threads
+	}
+
+	t.Run("Fresh entries skip requests", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		dir := t.TempDir()
+		list(NewClient(server.URL, "key", "1", 1).SetPageDelay(0).SetCache(dir, time.Hour))
+		list(NewClient(server.URL, "key", "1", 1).SetPageDelay(time.Hour).SetCache(dir, time.Hour))
+		if got := atomic.LoadInt32(&requests); got != 2 {
+			t.Errorf("Expected only the first listing to make requests, got %d", got)
+		}
+
+		list(NewClient(server.URL, "key", "2", 1).SetPageDelay(0).SetCache(dir, time.Hour))
+		if got := atomic.LoadInt32(&requests); got != 4 {
+			t.Errorf("Expected another API user not to share the cache, got %d requests", got)
+		}
+	})
+
+	t.Run("Stale entries are revalidated", func(t *testing.T) {
+		atomic.StoreInt32(&requests, 0)
+		client := NewClient(server.URL, "key", "1", 1).SetPageDelay(0).SetCache(t.TempDir(), 0)
+		first := list(client)
+		second := list(client)
+		if got := atomic.LoadInt32(&revalidated); got != 2 {
+			t.Errorf("Expected both pages to be revalidated, got %d", got)
+		}
+		if first[1].ThreadID != second[1].ThreadID {
+			t.Errorf("Expected the cached threads, got %+v", second)
+		}
+	})
+}